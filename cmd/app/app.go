@@ -7,15 +7,23 @@ import (
 	"ivanjabrony/test_lo/internal/config"
 	"ivanjabrony/test_lo/internal/server"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 type Application struct {
 	cfg  *config.Config
 	http *http.Server
-	ctx  context.Context
+	// grpc is nil when the gRPC api is disabled
+	grpc    *server.GRPCServer
+	workers *Workers
+	// storages are closed after the workers writing to them have stopped
+	storages *Storages
+	ctx      context.Context
 }
 
 func NewApplication(cfg *config.Config) (*Application, error) {
@@ -29,20 +37,44 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 		return nil, err
 	}
 
-	handlers, err := InitializeAdapters(cfg, logger)
+	handlers, workers, storages, err := InitializeAdapters(cfg, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	http, err := server.NewHTTP(cfg, logger, handlers.Task)
+	http, err := server.NewHTTP(cfg, logger, server.Handlers{
+		Task:         handlers.Task,
+		User:         handlers.User,
+		Project:      handlers.Project,
+		Tag:          handlers.Tag,
+		Comment:      handlers.Comment,
+		Audit:        handlers.Audit,
+		Socket:       handlers.Socket,
+		Webhook:      handlers.Webhook,
+		GraphQL:      handlers.GraphQL,
+		Notification: handlers.Notification,
+	})
 	if err != nil {
+		storages.Close()
 		return nil, err
 	}
 
+	var grpcServer *server.GRPCServer
+	if cfg.GRPCPort != "" {
+		grpcServer, err = server.NewGRPC(cfg, logger, server.Services{Task: handlers.TaskService})
+		if err != nil {
+			storages.Close()
+			return nil, err
+		}
+	}
+
 	app := Application{
-		cfg:  cfg,
-		http: http,
-		ctx:  ctx,
+		cfg:      cfg,
+		http:     http,
+		grpc:     grpcServer,
+		workers:  workers,
+		storages: storages,
+		ctx:      ctx,
 	}
 
 	return &app, nil
@@ -54,23 +86,44 @@ func (app *Application) GetAddr() string {
 
 func (app *Application) Run() error {
 	log.Print("Running application")
+	if !app.cfg.AuthEnabled {
+		log.Print("WARNING: authentication is disabled, anyone reaching the server may read and change every task")
+	}
 
 	ctx, cancel := context.WithCancel(app.ctx)
 	defer cancel()
 
+	app.workers.Webhooks.Start()
+	app.workers.Recurrence.Start()
+	app.workers.Reminders.Start()
+
 	log.Printf("Starting HTTP server at port: %s", app.cfg.HttpPort)
 
-	serverErr := make(chan error, 1)
+	serverErr := make(chan error, 2)
 	go func() {
 		if err := app.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			serverErr <- err
+			serverErr <- fmt.Errorf("HTTP server error: %w", err)
 			cancel()
 		}
 	}()
 
+	if app.grpc != nil {
+		log.Printf("Starting gRPC server at port: %s", app.cfg.GRPCPort)
+		listener, err := net.Listen("tcp", ":"+app.cfg.GRPCPort)
+		if err != nil {
+			return fmt.Errorf("gRPC server error: %w", err)
+		}
+		go func() {
+			if err := app.grpc.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				serverErr <- fmt.Errorf("gRPC server error: %w", err)
+				cancel()
+			}
+		}()
+	}
+
 	select {
 	case err := <-serverErr:
-		return fmt.Errorf("HTTP server error: %w", err)
+		return err
 	case <-ctx.Done():
 		return nil
 	}
@@ -85,6 +138,24 @@ func (app *Application) Stop() {
 	if err := app.http.Shutdown(ctx); err != nil {
 		log.Printf("HTTP server shutdown error: %v", err)
 	}
+	if app.grpc != nil {
+		if err := app.grpc.Shutdown(ctx); err != nil {
+			log.Printf("gRPC server shutdown error: %v", err)
+		}
+	}
+	if err := app.workers.Recurrence.Stop(ctx); err != nil {
+		log.Printf("Recurrence scheduler shutdown error: %v", err)
+	}
+	if err := app.workers.Reminders.Stop(ctx); err != nil {
+		log.Printf("Reminder engine shutdown error: %v", err)
+	}
+	// deliveries waiting for a retry are dropped, the ones being sent are finished
+	if err := app.workers.Webhooks.Stop(ctx); err != nil {
+		log.Printf("Webhook dispatcher shutdown error: %v", err)
+	}
+	if err := app.storages.Close(); err != nil {
+		log.Printf("Storage close error: %v", err)
+	}
 
 	log.Print("Application stopped gracefully")
 }
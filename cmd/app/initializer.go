@@ -3,13 +3,27 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/broker"
+	"ivanjabrony/test_lo/internal/clock"
 	"ivanjabrony/test_lo/internal/config"
 	"ivanjabrony/test_lo/internal/handler"
+	"ivanjabrony/test_lo/internal/idgen"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/reminder"
+	"ivanjabrony/test_lo/internal/scheduler"
 	"ivanjabrony/test_lo/internal/storage"
 	"ivanjabrony/test_lo/internal/usecase"
+	"ivanjabrony/test_lo/internal/webhook"
 	"ivanjabrony/test_lo/pkg/logger"
+	"net"
+	"net/http"
+	"net/smtp"
 	"os"
+	"strings"
+	"time"
 )
 
 type Logger interface {
@@ -24,67 +38,476 @@ func InitializeLogger(ctx context.Context, cfg *config.Config, w io.Writer) (Log
 	return logger, nil
 }
 
-func InitializeAdapters(cfg *config.Config, logger Logger) (*Handlers, error) {
+// InitializeAdapters builds handlers and the background workers they rely on,
+// workers have to be started by the caller, and the storages closed after the workers have stopped
+func InitializeAdapters(cfg *config.Config, logger Logger) (*Handlers, *Workers, *Storages, error) {
 	if cfg == nil {
-		return nil, errors.New("nil values in constructor")
+		return nil, nil, nil, errors.New("nil values in constructor")
 	}
 
-	storages, err := initStorages(logger)
+	storages, err := initStorages(cfg, logger)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
-	usecases, err := initUsecases(storages, logger)
+	workers, err := initWorkers(cfg, storages, logger)
 	if err != nil {
-		return nil, err
+		storages.Close()
+		return nil, nil, nil, err
 	}
 
-	handlers, err := initHandlers(usecases, logger)
+	usecases, err := initUsecases(cfg, storages, workers, logger)
 	if err != nil {
-		return nil, err
+		storages.Close()
+		return nil, nil, nil, err
+	}
+
+	// the scheduler works through the usecases, so it's built after them
+	workers.Recurrence, err = scheduler.NewScheduler(logger, usecases.Task, usecases.Project,
+		scheduler.WithInterval(time.Duration(cfg.RecurrenceIntervalSeconds)*time.Second))
+	if err != nil {
+		storages.Close()
+		return nil, nil, nil, err
+	}
+
+	handlers, err := initHandlers(cfg, usecases, storages.Clock, logger)
+	if err != nil {
+		storages.Close()
+		return nil, nil, nil, err
+	}
+
+	return handlers, workers, storages, nil
+}
+
+// InitializeUsecases builds usecases over the configured storages for commands working without the api,
+// the caller closes the storages when it's done
+func InitializeUsecases(cfg *config.Config, logger Logger) (*Usecases, *Storages, error) {
+	if cfg == nil {
+		return nil, nil, errors.New("nil values in constructor")
+	}
+
+	storages, err := initStorages(cfg, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workers, err := initWorkers(cfg, storages, logger)
+	if err != nil {
+		storages.Close()
+		return nil, nil, err
+	}
+
+	usecases, err := initUsecases(cfg, storages, workers, logger)
+	if err != nil {
+		storages.Close()
+		return nil, nil, err
 	}
 
-	return handlers, nil
+	return usecases, storages, nil
+}
+
+// TaskStorage is provided by both task storages, tags are kept together with tasks
+type TaskStorage interface {
+	usecase.TaskStorage
+	usecase.TagStorage
 }
 
 type Storages struct {
-	Task *storage.TaskStorage
+	Task    TaskStorage
+	User    *storage.UserStorage
+	Project *storage.ProjectStorage
+	Comment *storage.CommentStorage
+	Audit   *storage.AuditStorage
+	Webhook *storage.WebhookStorage
+	// Notification keeps notification preferences and sent reminders
+	Notification *storage.NotificationStorage
+	// Changes delivers committed task changes to event stream subscribers and webhooks
+	Changes *broker.Broker
+	// Clock is read by the storages, the usecases and the workers, so all of them see the same time
+	Clock clock.Clock
+}
+
+// Close releases files of the storages keeping them
+func (s *Storages) Close() error {
+	if closer, ok := s.Task.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Workers run in the background for the lifetime of the application
+type Workers struct {
+	Webhooks *webhook.Dispatcher
+	// Recurrence creates the next occurrences of recurring tasks
+	Recurrence *scheduler.Scheduler
+	// Reminders notifies users of due, overdue and stale tasks
+	Reminders *reminder.Engine
 }
 
 type Usecases struct {
-	Task *usecase.TaskUsecase
+	Task    *usecase.TaskUsecase
+	User    *usecase.UserUsecase
+	Project *usecase.ProjectUsecase
+	Tag     *usecase.TagUsecase
+	Comment *usecase.CommentUsecase
+	Audit   *usecase.AuditUsecase
+	Webhook *usecase.WebhookUsecase
+	// Notification manages notification preferences of users
+	Notification *usecase.NotificationUsecase
 }
 
 type Handlers struct {
-	Task *handler.TaskHandler
+	Task    *handler.TaskHandler
+	User    *handler.UserHandler
+	Project *handler.ProjectHandler
+	Tag     *handler.TagHandler
+	Comment *handler.CommentHandler
+	Audit   *handler.AuditHandler
+	Socket  *handler.SocketHandler
+	Webhook *handler.WebhookHandler
+	GraphQL *handler.GraphQLHandler
+	// Notification serves notification preferences of users
+	Notification *handler.NotificationHandler
+	// TaskService serves the gRPC api
+	TaskService *handler.TaskService
 }
 
-func initStorages(logger Logger) (*Storages, error) {
-	taslRepository, err := storage.NewTaskStorage(logger)
+func initStorages(cfg *config.Config, logger Logger) (*Storages, error) {
+	clk := clock.System{}
+	taslRepository, err := initTaskStorage(cfg, logger, clk)
+	if err != nil {
+		return nil, err
+	}
+
+	userRepository, err := storage.NewUserStorage(logger, storage.WithUserClock(clk))
+	if err != nil {
+		return nil, err
+	}
+
+	projectRepository, err := storage.NewProjectStorage(logger, cfg.DefaultTaskQuota, storage.WithProjectClock(clk))
+	if err != nil {
+		return nil, err
+	}
+
+	commentRepository, err := storage.NewCommentStorage(logger, storage.WithCommentClock(clk))
+	if err != nil {
+		return nil, err
+	}
+
+	auditRepository, err := storage.NewAuditStorage(logger, storage.WithAuditClock(clk))
+	if err != nil {
+		return nil, err
+	}
+
+	webhookRepository, err := storage.NewWebhookStorage(logger, storage.WithWebhookClock(clk))
+	if err != nil {
+		return nil, err
+	}
+
+	notificationRepository, err := storage.NewNotificationStorage(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	changeBroker, err := broker.NewBroker(logger,
+		broker.WithReplaySize(cfg.StreamReplayBuffer),
+		broker.WithClientBuffer(cfg.StreamClientBuffer),
+	)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Storages{
-		Task: taslRepository,
+		Task:         taslRepository,
+		User:         userRepository,
+		Project:      projectRepository,
+		Comment:      commentRepository,
+		Audit:        auditRepository,
+		Webhook:      webhookRepository,
+		Changes:      changeBroker,
+		Notification: notificationRepository,
+		Clock:        clk,
 	}, nil
 }
 
-func initUsecases(storages *Storages, logger Logger) (*Usecases, error) {
-	taskUsecase, err := usecase.NewTaskUsecase(logger, storages.Task)
+func initTaskStorage(cfg *config.Config, logger Logger, clk clock.Clock) (TaskStorage, error) {
+	ids, err := initIDGenerator(cfg, clk)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.TaskStorage {
+	case "", "memory":
+		return storage.NewTaskStorage(logger,
+			storage.WithStrictCompletion(cfg.StrictTaskCompletion),
+			storage.WithClock(clk),
+			storage.WithIDGenerator(ids),
+		)
+	case "sharded":
+		return storage.NewShardedTaskStorage(logger,
+			storage.WithShards(cfg.TaskShards),
+			storage.WithShardedStrictCompletion(cfg.StrictTaskCompletion),
+			storage.WithShardedClock(clk),
+			storage.WithShardedIDGenerator(ids),
+		)
+	case "events":
+		return storage.NewEventTaskStorage(logger,
+			storage.WithEventStrictCompletion(cfg.StrictTaskCompletion),
+			storage.WithEventClock(clk),
+			storage.WithEventIDGenerator(ids),
+			storage.WithEventLog(cfg.EventLogFile),
+			storage.WithSnapshotInterval(cfg.SnapshotInterval),
+		)
+	default:
+		return nil, fmt.Errorf("unknown task storage %q, expected memory, sharded or events", cfg.TaskStorage)
+	}
+}
+
+// initIDGenerator returns the generator of public ids of tasks, nil when clients see their ids
+func initIDGenerator(cfg *config.Config, clk clock.Clock) (idgen.Generator, error) {
+	if !cfg.PublicIds() {
+		return nil, nil
+	}
+	return idgen.New(cfg.IDStrategy, clk, cfg.IDNode)
+}
+
+func initWorkers(cfg *config.Config, storages *Storages, logger Logger) (*Workers, error) {
+	dispatcher, err := webhook.NewDispatcher(logger, storages.Changes, storages.Webhook,
+		webhook.WithWorkers(cfg.WebhookWorkers),
+		webhook.WithMaxAttempts(cfg.WebhookMaxAttempts),
+		webhook.WithBackoff(time.Duration(cfg.WebhookBackoffSeconds)*time.Second, time.Duration(cfg.WebhookMaxBackoffSeconds)*time.Second),
+		webhook.WithHTTPClient(&http.Client{Timeout: time.Duration(cfg.WebhookTimeoutSeconds) * time.Second}),
+		webhook.WithClock(storages.Clock),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	notifiers, err := initNotifiers(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	reminders, err := reminder.NewEngine(logger, storages.Project, storages.Task, storages.User, storages.Notification,
+		reminder.WithNotifiers(notifiers...),
+		reminder.WithDefaultChannels(channels(cfg.DefaultChannels())...),
+		reminder.WithInterval(time.Duration(cfg.ReminderIntervalSeconds)*time.Second),
+		reminder.WithClock(storages.Clock.Now),
+		reminder.WithRules(
+			reminder.DueSoon(time.Duration(cfg.ReminderDueSoonHours)*time.Hour),
+			reminder.Overdue(),
+			reminder.Stale(time.Duration(cfg.ReminderStaleHours)*time.Hour),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Workers{Webhooks: dispatcher, Reminders: reminders}, nil
+}
+
+// initNotifiers builds a notifier of every available channel, email needs a mail server
+func initNotifiers(cfg *config.Config, logger Logger) ([]reminder.Notifier, error) {
+	logNotifier, err := reminder.NewLogNotifier(logger)
+	if err != nil {
+		return nil, err
+	}
+	webhookNotifier, err := reminder.NewWebhookNotifier(
+		&http.Client{Timeout: time.Duration(cfg.WebhookTimeoutSeconds) * time.Second}, cfg.NotifyWebhookSecret)
+	if err != nil {
+		return nil, err
+	}
+	notifiers := []reminder.Notifier{logNotifier, webhookNotifier}
+	if cfg.SMTPAddr == "" {
+		return notifiers, nil
+	}
+
+	var smtpAuth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		host, _, _ := net.SplitHostPort(cfg.SMTPAddr)
+		smtpAuth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+	}
+	smtpNotifier, err := reminder.NewSMTPNotifier(cfg.SMTPAddr, cfg.SMTPFrom, smtpAuth)
+	if err != nil {
+		return nil, err
+	}
+	return append(notifiers, smtpNotifier), nil
+}
+
+func channels(names []string) []model.NotificationChannel {
+	ans := make([]model.NotificationChannel, 0, len(names))
+	for _, name := range names {
+		ans = append(ans, model.NotificationChannel(name))
+	}
+	return ans
+}
+
+func initUsecases(cfg *config.Config, storages *Storages, workers *Workers, logger Logger) (*Usecases, error) {
+	taskOpts := []usecase.TaskUsecaseOption{
+		usecase.WithUserStorage(storages.User),
+		usecase.WithCommentStorage(storages.Comment),
+		usecase.WithAuditStorage(storages.Audit),
+		usecase.WithChangeBroker(storages.Changes),
+		usecase.WithClock(storages.Clock),
+	}
+	userOpts := []usecase.UserUsecaseOption{}
+	projectOpts := []usecase.ProjectUsecaseOption{usecase.WithDefaultTaskQuota(cfg.DefaultTaskQuota)}
+	tagOpts := []usecase.TagUsecaseOption{usecase.WithTagClock(storages.Clock)}
+	commentOpts := []usecase.CommentUsecaseOption{}
+	auditOpts := []usecase.AuditUsecaseOption{}
+	if cfg.PublicIds() {
+		taskOpts = append(taskOpts, usecase.WithPublicIds())
+	}
+	webhookOpts := []usecase.WebhookUsecaseOption{usecase.WithRedeliverer(workers.Webhooks)}
+	notificationOpts := []usecase.NotificationUsecaseOption{
+		usecase.WithNotificationChannels(channels(cfg.DefaultChannels()), channels(cfg.AvailableChannels())),
+	}
+	if cfg.AuthEnabled {
+		policy := auth.NewPolicy(auth.DefaultRules, cfg.HideForbiddenTasks)
+		taskOpts = append(taskOpts, usecase.WithPolicy(policy))
+		userOpts = append(userOpts, usecase.WithUserPolicy(policy))
+		projectOpts = append(projectOpts, usecase.WithProjectPolicy(policy))
+		tagOpts = append(tagOpts, usecase.WithTagPolicy(policy))
+		commentOpts = append(commentOpts, usecase.WithCommentPolicy(policy))
+		auditOpts = append(auditOpts, usecase.WithAuditPolicy(policy))
+		webhookOpts = append(webhookOpts, usecase.WithWebhookPolicy(policy))
+		notificationOpts = append(notificationOpts, usecase.WithNotificationPolicy(policy))
+	}
+
+	taskUsecase, err := usecase.NewTaskUsecase(logger, storages.Task, taskOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	userUsecase, err := usecase.NewUserUsecase(logger, storages.User, storages.Task, userOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	projectUsecase, err := usecase.NewProjectUsecase(logger, storages.Project, projectOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tagUsecase, err := usecase.NewTagUsecase(logger, storages.Task, storages.Task, tagOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	commentUsecase, err := usecase.NewCommentUsecase(logger, storages.Comment, storages.Task, commentOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	auditUsecase, err := usecase.NewAuditUsecase(logger, storages.Audit, storages.Task, auditOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookUsecase, err := usecase.NewWebhookUsecase(logger, storages.Webhook, webhookOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	notificationUsecase, err := usecase.NewNotificationUsecase(logger, storages.Notification, storages.User, notificationOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Usecases{
-		Task: taskUsecase,
+		Task:         taskUsecase,
+		User:         userUsecase,
+		Project:      projectUsecase,
+		Tag:          tagUsecase,
+		Comment:      commentUsecase,
+		Audit:        auditUsecase,
+		Webhook:      webhookUsecase,
+		Notification: notificationUsecase,
 	}, nil
 }
 
-func initHandlers(usecases *Usecases, logger Logger) (*Handlers, error) {
-	taskHandler, err := handler.NewTaskHandler(logger, usecases.Task)
+func initHandlers(cfg *config.Config, usecases *Usecases, clk clock.Clock, logger Logger) (*Handlers, error) {
+	taskOpts := []handler.TaskHandlerOption{handler.WithHeartbeatInterval(time.Duration(cfg.StreamHeartbeatSeconds) * time.Second)}
+	auditOpts := []handler.AuditHandlerOption{}
+	socketOpts := []handler.SocketHandlerOption{
+		handler.WithMaxMessageSize(int64(cfg.WSMaxMessageSize)),
+		handler.WithPingInterval(time.Duration(cfg.WSPingIntervalSeconds) * time.Second),
+		handler.WithRateLimit(float64(cfg.WSRateLimit), cfg.WSRateBurst),
+	}
+	graphqlOpts := []handler.GraphQLHandlerOption{
+		handler.WithQueryLimits(cfg.GraphQLMaxDepth, cfg.GraphQLMaxComplexity),
+		handler.WithGraphQLClock(clk),
+	}
+	serviceOpts := []handler.TaskServiceOption{handler.WithServiceClock(clk)}
+	// every transport refers to tasks by the ids responses show
+	if cfg.PublicIds() {
+		taskOpts = append(taskOpts, handler.WithPublicIds(usecases.Task))
+		auditOpts = append(auditOpts, handler.WithAuditPublicIds())
+		socketOpts = append(socketOpts, handler.WithSocketPublicIds(usecases.Task))
+		graphqlOpts = append(graphqlOpts, handler.WithGraphQLPublicIds(usecases.Task))
+		serviceOpts = append(serviceOpts, handler.WithServicePublicIds(usecases.Task))
+	}
+	taskHandler, err := handler.NewTaskHandler(logger, usecases.Task, taskOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	userHandler, err := handler.NewUserHandler(logger, usecases.User)
+	if err != nil {
+		return nil, err
+	}
+
+	projectHandler, err := handler.NewProjectHandler(logger, usecases.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	tagHandler, err := handler.NewTagHandler(logger, usecases.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	commentHandler, err := handler.NewCommentHandler(logger, usecases.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	auditHandler, err := handler.NewAuditHandler(logger, usecases.Audit, auditOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.WSAllowedOrigins != "" {
+		origins := make([]string, 0)
+		for _, origin := range strings.Split(cfg.WSAllowedOrigins, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				origins = append(origins, origin)
+			}
+		}
+		socketOpts = append(socketOpts, handler.WithAllowedOrigins(origins...))
+	}
+	socketHandler, err := handler.NewSocketHandler(logger, usecases.Task, socketOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookHandler, err := handler.NewWebhookHandler(logger, usecases.Webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	graphqlHandler, err := handler.NewGraphQLHandler(logger, usecases.Task, graphqlOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	notificationHandler, err := handler.NewNotificationHandler(logger, usecases.Notification)
+	if err != nil {
+		return nil, err
+	}
+
+	taskService, err := handler.NewTaskService(logger, usecases.Task, usecases.Project, serviceOpts...)
 	if err != nil {
 		return nil, err
 	}
-	return &Handlers{taskHandler}, nil
+	return &Handlers{taskHandler, userHandler, projectHandler, tagHandler, commentHandler, auditHandler, socketHandler, webhookHandler, graphqlHandler, notificationHandler, taskService}, nil
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"ivanjabrony/test_lo/internal/config"
+	"ivanjabrony/test_lo/internal/server"
+	"ivanjabrony/test_lo/internal/storage"
+)
+
+// checkConfigCommand reports every problem of the config serve would fail or misbehave with, without starting anything
+func (c *cli) checkConfigCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+
+		problems := make([]error, 0)
+		cfg, err := config.Load()
+		problems = appendErrors(problems, err)
+		problems = appendErrors(problems, cfg.Validate())
+		if cfg.AuthEnabled {
+			if _, err := server.NewAuthenticator(&cfg); err != nil {
+				problems = append(problems, fmt.Errorf("authentication: %w", err))
+			}
+		}
+		if cfg.TaskStorage == "events" && cfg.EventLogFile != "" {
+			if err := storage.CheckEventLogVersion(cfg.EventLogFile); err != nil {
+				problems = append(problems, fmt.Errorf("EVENT_LOG_FILE: %w", err))
+			}
+		}
+
+		if len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Fprintf(c.stdout, "- %v\n", problem)
+			}
+			return errors.New("invalid config")
+		}
+		fmt.Fprintln(c.stdout, "Config is valid")
+		return nil
+	}
+}
+
+// appendErrors appends the errors joined in err one by one
+func appendErrors(errs []error, err error) []error {
+	if err == nil {
+		return errs
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return append(errs, joined.Unwrap()...)
+	}
+	return append(errs, err)
+}
@@ -1,34 +1,187 @@
+// Command task_lo runs the task server and the tools maintaining its storage.
+//
+// The config is read from the environment by every command, running the binary without arguments serves the api.
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"ivanjabrony/test_lo/cmd/app"
 	"ivanjabrony/test_lo/internal/config"
-	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	// recurrence rules name time zones, the alpine image has no zone database
+	_ "time/tzdata"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
 )
 
 func main() {
-	cfg := config.MustLoad()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// command is a subcommand of task_lo
+type command struct {
+	name string
+	// args describes the positional arguments in the usage
+	args    string
+	summary string
+	// setup registers the flags of the command and returns the function running it with the positional arguments
+	setup func(c *cli, fs *flag.FlagSet) func(ctx context.Context, args []string) error
+}
+
+// cli runs a single command, the streams are injected so the commands can be tested
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// usageError is a malformed command line, it's reported with a hint to the usage
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
 
-	app, err := app.NewApplication(&cfg)
+func usageErrorf(format string, args ...any) error {
+	return &usageError{fmt.Sprintf(format, args...)}
+}
+
+// commands are listed in the order of the usage
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "serve", summary: "Serve the api until interrupted, the default command", setup: (*cli).serveCommand},
+		{name: "migrate", args: "up|down|status", summary: "Migrate files of the persistent task storage", setup: (*cli).migrateCommand},
+		{name: "seed", summary: "Generate fake tasks for load testing", setup: (*cli).seedCommand},
+		{name: "check-config", summary: "Check the config read from the environment", setup: (*cli).checkConfigCommand},
+		{name: "version", summary: "Print the version and build info", setup: (*cli).versionCommand},
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		// the binary only served before it had commands
+		args = []string{"serve"}
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage(stdout)
+		return exitOK
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(stderr, "task_lo: unknown command %q\n\n", args[0])
+		c.usage(stderr)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("task_lo "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: task_lo %v [flags]\n\n%v.\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprint(fs.Output(), "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	runCommand := cmd.setup(c, fs)
+	positional, err := parseInterspersed(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
 	if err != nil {
-		log.Fatalf("Error while starting app: %v", err)
+		// flag has reported the error with the usage
+		return exitUsage
+	}
+
+	if err = runCommand(ctx, positional); err != nil {
+		fmt.Fprintf(stderr, "task_lo %v: %v\n", cmd.name, err)
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(stderr, "Run 'task_lo %v -h' for usage.\n", cmd.name)
+			return exitUsage
+		}
+		return exitError
+	}
+	return exitOK
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// parseInterspersed parses flags given before, between and after the positional arguments, "--" ends the flags
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		// flag drops the terminator, so it's found as the last of the parsed arguments
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
 	}
+}
 
-	done := make(chan os.Signal, 2)
-	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
+func (c *cli) usage(w io.Writer) {
+	fmt.Fprint(w, "task_lo serves the task api, without a command it serves.\n\nUsage: task_lo [COMMAND] [ARGS] [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-13v %v\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprint(w, "\nThe config is read from the environment, see check-config.\nRun 'task_lo COMMAND -h' for the flags of a command.\n")
+}
 
-	appErr := make(chan error, 2)
-	go func() {
-		appErr <- app.Run()
-	}()
+// loadConfig reads the config and checks the values depending on each other, every command runs with it
+func loadConfig() (config.Config, error) {
+	cfg, err := config.Load()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		return config.Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
 
-	select {
-	case err := <-appErr:
-		log.Fatalf("Application error: %v", err)
-	case <-done:
-		app.Stop()
+// toolLogger logs the work of storages and usecases to stderr when verbose, otherwise it's dropped
+func (c *cli) toolLogger(ctx context.Context, cfg *config.Config, verbose bool) (app.Logger, error) {
+	if verbose {
+		return app.InitializeLogger(ctx, cfg, c.stderr)
 	}
+	return discardLogger{}, nil
 }
+
+type discardLogger struct{}
+
+func (discardLogger) Log(format string, info ...any) {}
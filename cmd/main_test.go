@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/cmd/app"
+	"ivanjabrony/test_lo/internal/config"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/server"
+	"ivanjabrony/test_lo/internal/storage"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type MockLogger struct{}
+
+func (m *MockLogger) Log(format string, info ...any) {}
+
+// syncBuffer is written by a running command while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func runCommand(ctx context.Context, args ...string) (string, string, int) {
+	var stdout, stderr syncBuffer
+	code := run(ctx, args, &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+// setEnv sets the environment of the config for the test
+func setEnv(t *testing.T, env map[string]string) {
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+		stdout   string
+		stderr   string
+	}{
+		{name: "help", args: []string{"help"}, expected: exitOK, stdout: "Commands:\n  serve"},
+		{name: "command help", args: []string{"seed", "-h"}, expected: exitOK, stderr: "Usage: task_lo seed [flags]"},
+		{name: "unknown command", args: []string{"deploy"}, expected: exitUsage, stderr: "unknown command \"deploy\""},
+		{name: "unknown flag", args: []string{"version", "-x"}, expected: exitUsage, stderr: "flag provided but not defined: -x"},
+		{name: "unexpected argument", args: []string{"version", "now"}, expected: exitUsage, stderr: "Run 'task_lo version -h' for usage."},
+		{name: "version", args: []string{"version"}, expected: exitOK, stdout: "task_lo "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runCommand(ctx, tt.args...)
+			if code != tt.expected {
+				t.Errorf("Expected exit code %v, got %v, stderr: %v", tt.expected, code, stderr)
+			}
+			if !strings.Contains(stdout, tt.stdout) || !strings.Contains(stderr, tt.stderr) {
+				t.Errorf("Expected %q in stdout and %q in stderr, got\n%v\n%v", tt.stdout, tt.stderr, stdout, stderr)
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+	setEnv(t, map[string]string{"HTTP_PORT": port, "GRPC_PORT": "", "AUTH_ENABLED": "false", "TASK_STORAGE": "memory"})
+
+	// no arguments serve, the way the binary worked before it had commands
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	go func() {
+		_, _, code := runCommand(ctx)
+		done <- code
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		response, err := http.Get("http://127.0.0.1:" + port + "/health")
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Errorf("Expected 200 from /health, got %v", response.StatusCode)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Server didn't start: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case code := <-done:
+		if code != exitOK {
+			t.Errorf("Expected exit code 0 after the interrupt, got %v", code)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatalf("Server didn't stop")
+	}
+}
+
+func TestServeInvalidConfig(t *testing.T) {
+	// the config is read, but check-config rejects it
+	setEnv(t, map[string]string{"HTTP_PORT": "0", "GRPC_PORT": "", "AUTH_ENABLED": "false", "WS_RATE_LIMIT": "0"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, stderr, code := runCommand(ctx, "serve")
+	if code != exitError || !strings.Contains(stderr, "WS_RATE_LIMIT must be positive") {
+		t.Errorf("Expected serve to refuse the config, got %v: %v", code, stderr)
+	}
+	if ctx.Err() != nil {
+		t.Error("Expected serve to exit without starting the server")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("memory storage", func(t *testing.T) {
+		setEnv(t, map[string]string{"TASK_STORAGE": "memory"})
+		stdout, _, code := runCommand(ctx, "migrate", "up")
+		if code != exitOK || !strings.Contains(stdout, "Nothing to migrate") {
+			t.Errorf("Expected nothing to migrate, got %v: %v", code, stdout)
+		}
+	})
+
+	t.Run("event log", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		os.WriteFile(path, nil, 0o644)
+		setEnv(t, map[string]string{"TASK_STORAGE": "events", "EVENT_LOG_FILE": path})
+
+		steps := []struct {
+			args     []string
+			expected int
+			stdout   string
+			version  int
+		}{
+			{args: []string{"status"}, stdout: "is of version 0, the latest is 1\n\nVERSION  STATE    DESCRIPTION\n1        pending", version: 0},
+			{args: []string{"up"}, stdout: "Migrated up: 1 ", version: 1},
+			{args: []string{"up"}, stdout: "is already of version 1", version: 1},
+			{args: []string{"status"}, stdout: "1        applied", version: 1},
+			{args: []string{"down", "-to", "2"}, expected: exitUsage, version: 1},
+			{args: []string{"down"}, stdout: "Migrated down: 1 ", version: 0},
+			{args: []string{"up", "-to", "1"}, stdout: "Migrated up: 1 ", version: 1},
+			{args: []string{"status", "-to", "1"}, expected: exitUsage, version: 1},
+			{args: nil, expected: exitUsage, version: 1},
+		}
+		for _, step := range steps {
+			stdout, stderr, code := runCommand(ctx, append([]string{"migrate"}, step.args...)...)
+			if code != step.expected || !strings.Contains(stdout, step.stdout) {
+				t.Errorf("migrate %v: expected %v and %q, got %v:\n%v%v", step.args, step.expected, step.stdout, code, stdout, stderr)
+			}
+			if version, err := storage.ReadEventLogVersion(path); err != nil || version != step.version {
+				t.Errorf("migrate %v: expected version %v, got %v, %v", step.args, step.version, version, err)
+			}
+		}
+	})
+}
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("event log", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		setEnv(t, map[string]string{"TASK_STORAGE": "events", "EVENT_LOG_FILE": path})
+		stdout, stderr, code := runCommand(ctx, "seed", "-count", "200", "-seed", "3")
+		if code != exitOK || !strings.HasPrefix(stdout, "Seeded 200 tasks") {
+			t.Fatalf("Expected 200 seeded tasks, got %v:\n%v%v", code, stdout, stderr)
+		}
+
+		es, err := storage.NewEventTaskStorage(&MockLogger{}, storage.WithEventLog(path))
+		if err != nil {
+			t.Fatalf("Failed to open the seeded log: %v", err)
+		}
+		defer es.Close()
+		tasks, _, err := es.GetAll(ctx, model.EmptyFilter)
+		if err != nil || len(tasks) != 200 {
+			t.Fatalf("Expected 200 tasks, got %v, %v", len(tasks), err)
+		}
+		statuses := make(map[model.TaskStatus]int)
+		subtasks := 0
+		for _, task := range tasks {
+			statuses[task.Status]++
+			if task.ParentID != nil {
+				subtasks++
+			}
+		}
+		if len(statuses) != 3 || subtasks == 0 {
+			t.Errorf("Expected every status and some subtasks, got %v and %v subtasks", statuses, subtasks)
+		}
+	})
+
+	t.Run("memory storage", func(t *testing.T) {
+		setEnv(t, map[string]string{"TASK_STORAGE": "memory"})
+		if _, stderr, code := runCommand(ctx, "seed"); code != exitError || !strings.Contains(stderr, "use -server") {
+			t.Errorf("Expected a hint to -server, got %v: %v", code, stderr)
+		}
+	})
+
+	t.Run("server", func(t *testing.T) {
+		cfg := &config.Config{
+			TaskStorage:               "memory",
+			StreamReplayBuffer:        16,
+			StreamClientBuffer:        16,
+			StreamHeartbeatSeconds:    1,
+			WSMaxMessageSize:          65536,
+			WSPingIntervalSeconds:     30,
+			WSRateLimit:               20,
+			WSRateBurst:               40,
+			WebhookWorkers:            1,
+			WebhookMaxAttempts:        1,
+			WebhookBackoffSeconds:     1,
+			WebhookMaxBackoffSeconds:  1,
+			WebhookTimeoutSeconds:     1,
+			RecurrenceIntervalSeconds: 30,
+			ReminderIntervalSeconds:   60,
+			ReminderDueSoonHours:      24,
+			ReminderStaleHours:        72,
+			NotifyDefaultChannels:     "log",
+		}
+		handlers, _, _, err := app.InitializeAdapters(cfg, &MockLogger{})
+		if err != nil {
+			t.Fatalf("Failed to initialize adapters: %v", err)
+		}
+		srv, err := server.NewHTTP(cfg, &MockLogger{}, server.Handlers{
+			Task:         handlers.Task,
+			User:         handlers.User,
+			Project:      handlers.Project,
+			Tag:          handlers.Tag,
+			Comment:      handlers.Comment,
+			Audit:        handlers.Audit,
+			Socket:       handlers.Socket,
+			Webhook:      handlers.Webhook,
+			GraphQL:      handlers.GraphQL,
+			Notification: handlers.Notification,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		ts := httptest.NewServer(srv.Handler)
+		defer ts.Close()
+		if response, err := http.Post(ts.URL+"/users", "application/json", strings.NewReader(`{"name":"alice","email":"alice@example.com"}`)); err != nil {
+			t.Fatalf("Failed to create a user: %v", err)
+		} else {
+			response.Body.Close()
+		}
+
+		stdout, stderr, code := runCommand(ctx, "seed", "-server", ts.URL, "-count", "30")
+		if code != exitOK || !strings.HasPrefix(stdout, "Seeded 30 tasks") {
+			t.Fatalf("Expected 30 seeded tasks, got %v:\n%v%v", code, stdout, stderr)
+		}
+		response, err := http.Get(ts.URL + "/tasks?limit=1")
+		if err != nil {
+			t.Fatalf("Failed to list tasks: %v", err)
+		}
+		defer response.Body.Close()
+		var body bytes.Buffer
+		body.ReadFrom(response.Body)
+		if !strings.Contains(body.String(), `"total":30`) {
+			t.Errorf("Expected 30 tasks on the server, got %v", body.String())
+		}
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		if _, _, code := runCommand(ctx, "seed", "-count", "0"); code != exitUsage {
+			t.Errorf("Expected exit code %v for a zero count, got %v", exitUsage, code)
+		}
+		if _, _, code := runCommand(ctx, "seed", "-project", "2"); code != exitUsage {
+			t.Errorf("Expected exit code %v for -project without -server, got %v", exitUsage, code)
+		}
+	})
+}
+
+func TestTaskGenerator(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 30, 0, 0, time.UTC)
+	generate := func(seed uint64) []string {
+		g := newTaskGenerator(seed, now)
+		g.assignees = []int{1, 2}
+		tasks := make([]string, 0)
+		for i := range 100 {
+			task := g.next()
+			if task.Name == "" || !task.Priority.IsValid() {
+				t.Fatalf("Malformed task %+v", task)
+			}
+			if !task.DueAt.IsZero() && (task.DueAt.Before(now.Add(-15*24*time.Hour)) || task.DueAt.After(now.Add(32*24*time.Hour))) {
+				t.Errorf("Due date %v is too far from now", task.DueAt)
+			}
+			if task.ParentID != nil && task.ParentID.Id >= i {
+				t.Errorf("Parent %v of task %v isn't created before it", task.ParentID.Id, i)
+			}
+			g.created(dto.TaskRef{Id: i}, task)
+			parent := -1
+			if task.ParentID != nil {
+				parent, task.ParentID = task.ParentID.Id, nil
+			}
+			tasks = append(tasks, fmt.Sprintf("%+v parent %v", task, parent))
+		}
+		return tasks
+	}
+
+	if first, second := generate(1), generate(1); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same tasks for the same seed")
+	}
+	if first, second := generate(1), generate(2); reflect.DeepEqual(first, second) {
+		t.Errorf("Expected different tasks for different seeds")
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected int
+		stdout   []string
+	}{
+		{
+			name:     "valid",
+			env:      map[string]string{"AUTH_ENABLED": "false"},
+			expected: exitOK,
+			stdout:   []string{"Config is valid"},
+		},
+		{
+			name:     "every problem is reported",
+			env:      map[string]string{"AUTH_ENABLED": "true", "WS_RATE_LIMIT": "fast", "TASK_STORAGE": "disk", "HTTP_PORT": "http"},
+			expected: exitError,
+			stdout: []string{
+				"- invalid value of WS_RATE_LIMIT",
+				"- HTTP_PORT must be a port number",
+				"- TASK_STORAGE must be memory, sharded or events",
+				"- authentication: authentication is enabled but neither API_KEYS nor JWT keys are configured",
+			},
+		},
+		{
+			name:     "event log of a newer version",
+			env:      map[string]string{"AUTH_ENABLED": "false", "TASK_STORAGE": "events"},
+			expected: exitError,
+			stdout:   []string{"- EVENT_LOG_FILE: ", "is of version 7, newer than 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			if tt.env["TASK_STORAGE"] == "events" {
+				path := filepath.Join(t.TempDir(), "events.jsonl")
+				os.WriteFile(path+".version", []byte("7\n"), 0o644)
+				t.Setenv("EVENT_LOG_FILE", path)
+			}
+			stdout, stderr, code := runCommand(ctx, "check-config")
+			if code != tt.expected {
+				t.Errorf("Expected exit code %v, got %v: %v", tt.expected, code, stderr)
+			}
+			for _, line := range tt.stdout {
+				if !strings.Contains(stdout, line) {
+					t.Errorf("Expected %q in\n%v", line, stdout)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteVersion(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.24.3",
+		Main:      debug.Module{Path: "ivanjabrony/test_lo", Version: "v1.2.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "GOOS", Value: "linux"},
+			{Key: "GOARCH", Value: "amd64"},
+			{Key: "vcs.revision", Value: "0e5b6b2"},
+			{Key: "vcs.time", Value: "2025-06-01T10:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	var b strings.Builder
+	if err := writeVersion(&b, info); err != nil {
+		t.Fatalf("writeVersion failed: %v", err)
+	}
+	expected := "task_lo v1.2.0\n" +
+		"module:   ivanjabrony/test_lo\n" +
+		"go:       go1.24.3 linux/amd64\n" +
+		"revision: 0e5b6b2 (modified)\n" +
+		"time:     2025-06-01T10:00:00Z\n"
+	if b.String() != expected {
+		t.Errorf("Expected\n%v\ngot\n%v", expected, b.String())
+	}
+}
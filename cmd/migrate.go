@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"ivanjabrony/test_lo/internal/storage"
+	"text/tabwriter"
+)
+
+// migrateCommand migrates the event log of the "events" storage, the only storage keeping files
+func (c *cli) migrateCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	to := fs.Int("to", -1, "version to migrate to, by default up migrates to the latest one and down reverts the last one")
+	verbose := fs.Bool("v", false, "log the work of the storage to stderr")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("expected up, down or status")
+		}
+		action := args[0]
+		switch action {
+		case "up", "down", "status":
+		default:
+			return usageErrorf("unknown action %q, expected up, down or status", action)
+		}
+		if action == "status" && *to != -1 {
+			return usageErrorf("-to is used only by up and down")
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if cfg.TaskStorage != "events" || cfg.EventLogFile == "" {
+			fmt.Fprintln(c.stdout, "Nothing to migrate: only the events task storage with EVENT_LOG_FILE keeps files")
+			return nil
+		}
+		path := cfg.EventLogFile
+
+		version, err := storage.ReadEventLogVersion(path)
+		if err != nil {
+			return err
+		}
+		if action == "status" {
+			return c.printMigrations(path, version)
+		}
+
+		target := *to
+		switch {
+		case target == -1 && action == "up":
+			target = storage.EventLogVersion
+		case target == -1:
+			target = max(version-1, 0)
+		case action == "up" && target < version:
+			return usageErrorf("version %v is below the current %v, use down", target, version)
+		case action == "down" && target > version:
+			return usageErrorf("version %v is above the current %v, use up", target, version)
+		}
+
+		logger, err := c.toolLogger(ctx, &cfg, *verbose)
+		if err != nil {
+			return err
+		}
+		applied, err := storage.MigrateEventLog(logger, path, target)
+		for _, m := range applied {
+			fmt.Fprintf(c.stdout, "Migrated %v: %v %v\n", action, m.Version, m.Description)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintf(c.stdout, "Event log %v is already of version %v\n", path, version)
+		}
+		return nil
+	}
+}
+
+func (c *cli) printMigrations(path string, version int) error {
+	fmt.Fprintf(c.stdout, "Event log %v is of version %v, the latest is %v\n\n", path, version, storage.EventLogVersion)
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATE\tDESCRIPTION")
+	for _, m := range storage.EventLogMigrations() {
+		state := "pending"
+		if m.Version <= version {
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", m.Version, state, m.Description)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"ivanjabrony/test_lo/cmd/app"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/pkg/client"
+	"math/rand/v2"
+	"time"
+)
+
+var (
+	seedVerbs = []string{"Fix", "Implement", "Review", "Refactor", "Document", "Test", "Investigate",
+		"Migrate", "Speed up", "Design", "Clean up", "Monitor"}
+	seedSubjects = []string{"login timeout", "billing export", "search indexing", "onboarding flow", "rate limiter",
+		"audit log retention", "push notifications", "CSV import", "dashboard charts", "password reset email",
+		"invoice PDF rendering", "session storage", "API pagination", "dark mode", "release pipeline",
+		"database backups", "webhook retries", "user avatars", "SSO integration", "error pages"}
+	seedDetails = []string{
+		"Reported by several customers this week.",
+		"Blocks the next release.",
+		"Follow-up from the last retrospective.",
+		"Needs a short design review before the work starts.",
+		"Check the dashboards after the change is deployed.",
+		"Keep the old behaviour behind a flag until the migration is done.",
+		"",
+	}
+)
+
+// taskCreator stores a task and returns a ref to it, it's either the task usecase or the api client
+type taskCreator func(ctx context.Context, task dto.PostTaskRequest) (dto.TaskRef, error)
+
+// seedCommand creates fake tasks either in the configured persistent storage or, with -server, through the api
+func (c *cli) seedCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	count := fs.Int("count", 100, "amount of tasks to create")
+	seed := fs.Uint64("seed", 0, "seed of the generator, 0 picks a random one")
+	server := fs.String("server", "", "base url of a running server to seed through the api instead of the storage")
+	apiKey := fs.String("api-key", "", "API key for -server")
+	project := fs.Int("project", 0, "project to seed through -server, 0 seeds the default one")
+	verbose := fs.Bool("v", false, "log the work of the storage to stderr")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		if *count <= 0 {
+			return usageErrorf("-count must be positive, got %v", *count)
+		}
+		if *server == "" && (*apiKey != "" || *project != 0) {
+			return usageErrorf("-api-key and -project are used only with -server")
+		}
+		if *seed == 0 {
+			*seed = rand.Uint64()
+		}
+		generator := newTaskGenerator(*seed, time.Now())
+
+		var create taskCreator
+		if *server != "" {
+			opts := []client.Option{}
+			if *apiKey != "" {
+				opts = append(opts, client.WithAuth(client.APIKey(*apiKey)))
+			}
+			if *project != 0 {
+				opts = append(opts, client.WithProject(*project))
+			}
+			api, err := client.New(*server, opts...)
+			if err != nil {
+				return err
+			}
+			users, err := api.ListUsers(ctx)
+			if err != nil {
+				return fmt.Errorf("couldn't list assignees: %w", err)
+			}
+			for _, user := range users.Users {
+				generator.assignees = append(generator.assignees, user.Id)
+			}
+			create = func(ctx context.Context, task dto.PostTaskRequest) (dto.TaskRef, error) {
+				id, err := api.CreateTask(ctx, task)
+				return *id.Ref(), err
+			}
+		} else {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if cfg.TaskStorage != "events" || cfg.EventLogFile == "" {
+				return errors.New("only the events task storage with EVENT_LOG_FILE outlives the command, use -server to seed a running server")
+			}
+			// the command runs on behalf of the operator, there is no caller to authorize
+			cfg.AuthEnabled = false
+			logger, err := c.toolLogger(ctx, &cfg, *verbose)
+			if err != nil {
+				return err
+			}
+			usecases, storages, err := app.InitializeUsecases(&cfg, logger)
+			if err != nil {
+				return err
+			}
+			defer storages.Close()
+			create = func(ctx context.Context, task dto.PostTaskRequest) (dto.TaskRef, error) {
+				id, err := usecases.Task.Store(ctx, task)
+				if err != nil {
+					return dto.TaskRef{}, err
+				}
+				// parents are referred to by public ids when the storage gives them
+				stored, err := usecases.Task.GetByTaskId(ctx, id)
+				return dto.TaskRef{Id: id, PublicID: stored.PublicID}, err
+			}
+		}
+
+		started := time.Now()
+		for i := range *count {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("interrupted after %v tasks: %w", i, err)
+			}
+			task := generator.next()
+			ref, err := create(ctx, task)
+			if err != nil {
+				return fmt.Errorf("couldn't create task %v: %w", i+1, err)
+			}
+			generator.created(ref, task)
+		}
+		elapsed := time.Since(started)
+		fmt.Fprintf(c.stdout, "Seeded %v tasks in %v (%.0f tasks/s), seed %v\n",
+			*count, elapsed.Round(time.Millisecond), float64(*count)/elapsed.Seconds(), *seed)
+		return nil
+	}
+}
+
+// taskGenerator makes tasks looking like the ones of a product team: mostly top level tasks
+// with a few subtasks, a mix of statuses and priorities, and due dates around now, some of them missed
+type taskGenerator struct {
+	rand *rand.Rand
+	now  time.Time
+	// assignees are ids of existing users, tasks are left unassigned without them
+	assignees []int
+	// parents are top level tasks subtasks are added to, with their statuses
+	parents []seededTask
+}
+
+type seededTask struct {
+	ref    dto.TaskRef
+	status model.TaskStatus
+}
+
+func newTaskGenerator(seed uint64, now time.Time) *taskGenerator {
+	return &taskGenerator{
+		rand: rand.New(rand.NewPCG(seed, seed)),
+		now:  now,
+	}
+}
+
+func (g *taskGenerator) next() dto.PostTaskRequest {
+	task := dto.PostTaskRequest{
+		Name:        g.pick(seedVerbs) + " " + g.pick(seedSubjects),
+		Description: g.pick(seedDetails),
+		Status:      weighted(g.rand, []model.TaskStatus{model.Created, model.InProgress, model.Done}, []int{40, 35, 25}),
+		Priority: weighted(g.rand, []model.TaskPriority{model.PriorityLow, model.PriorityMedium, model.PriorityHigh, model.PriorityUrgent},
+			[]int{25, 45, 20, 10}),
+	}
+
+	if len(g.parents) > 0 && g.rand.IntN(100) < 25 {
+		parent := g.parents[g.rand.IntN(len(g.parents))]
+		task.ParentID = &parent.ref
+		if parent.status == model.Done {
+			// a done task has every subtask done
+			task.Status = model.Done
+		}
+	}
+	if len(g.assignees) > 0 && g.rand.IntN(100) < 70 {
+		task.AssigneeID = g.assignees[g.rand.IntN(len(g.assignees))]
+	}
+	if g.rand.IntN(100) < 60 {
+		// due dates fall on whole hours from two weeks ago to a month ahead
+		hours := g.rand.IntN(46*24) - 14*24
+		task.DueAt = g.now.Truncate(time.Hour).Add(time.Duration(hours) * time.Hour).UTC()
+		task.AllowPastDue = true
+	}
+	return task
+}
+
+// created remembers the stored task as a parent of later subtasks
+func (g *taskGenerator) created(ref dto.TaskRef, task dto.PostTaskRequest) {
+	if task.ParentID == nil {
+		g.parents = append(g.parents, seededTask{ref: ref, status: task.Status})
+	}
+}
+
+func (g *taskGenerator) pick(values []string) string {
+	return values[g.rand.IntN(len(values))]
+}
+
+// weighted picks one of the values with probability proportional to its weight
+func weighted[T any](r *rand.Rand, values []T, weights []int) T {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	n := r.IntN(total)
+	for i, w := range weights {
+		if n < w {
+			return values[i]
+		}
+		n -= w
+	}
+	return values[len(values)-1]
+}
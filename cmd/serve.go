@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"ivanjabrony/test_lo/cmd/app"
+)
+
+// serveCommand runs the application until the context is canceled by a signal
+func (c *cli) serveCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		application, err := app.NewApplication(&cfg)
+		if err != nil {
+			return fmt.Errorf("error while starting app: %w", err)
+		}
+
+		appErr := make(chan error, 1)
+		go func() {
+			appErr <- application.Run()
+		}()
+
+		select {
+		case err := <-appErr:
+			return fmt.Errorf("application error: %w", err)
+		case <-ctx.Done():
+			application.Stop()
+			return nil
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// flagValues are completed for the flags of every command having them
+var flagValues = map[string][]string{
+	"status":    {"created", "inProgress", "done"},
+	"priority":  {"low", "medium", "high", "urgent"},
+	"tag-match": {"any", "all"},
+	"sort": {"id", "-id", "due_at", "-due_at", "priority", "-priority",
+		"created_at", "-created_at", "started_at", "-started_at", "completed_at", "-completed_at"},
+	"output": {outputTable, outputJSON, outputYAML},
+	"o":      {outputTable, outputJSON, outputYAML},
+	"format": {"json", "csv", "ndjson"},
+}
+
+// subcommandValues are completed as the first argument of the commands
+var subcommandValues = map[string][]string{
+	"config":     configSubcommands,
+	"completion": {"bash", "zsh", "fish"},
+}
+
+// completionCommand prints a completion script built from the commands and their flags, so it can't fall behind them
+func (c *cli) completionCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("expected a shell: bash, zsh or fish")
+		}
+		switch args[0] {
+		case "bash":
+			return writeBashCompletion(c.stdout, false)
+		case "zsh":
+			return writeBashCompletion(c.stdout, true)
+		case "fish":
+			return writeFishCompletion(c.stdout)
+		}
+		return usageErrorf("unsupported shell %q, expected bash, zsh or fish", args[0])
+	}
+}
+
+// commandFlags returns the flags of the command with the global ones, sorted by name
+func commandFlags(cmd command) []*flag.Flag {
+	c := &cli{stdout: io.Discard, stderr: io.Discard}
+	fs := c.newFlagSet(cmd)
+	cmd.setup(c, fs)
+	flags := make([]*flag.Flag, 0)
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	return flags
+}
+
+// writeBashCompletion writes a bash script, zsh runs it through bashcompinit
+func writeBashCompletion(w io.Writer, zsh bool) error {
+	var b strings.Builder
+	if zsh {
+		b.WriteString("#compdef taskctl\nautoload -U +X bashcompinit && bashcompinit\n\n")
+	}
+	names := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	b.WriteString("_taskctl() {\n")
+	b.WriteString("    local cur prev\n")
+	b.WriteString("    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(&b, "    if [ \"$COMP_CWORD\" -eq 1 ]; then\n        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n        return\n    fi\n",
+		strings.Join(append(names, "help"), " "))
+
+	b.WriteString("    case \"$prev\" in\n")
+	valueFlags := make([]string, 0, len(flagValues))
+	for name := range flagValues {
+		valueFlags = append(valueFlags, name)
+	}
+	slices.Sort(valueFlags)
+	for _, name := range valueFlags {
+		fmt.Fprintf(&b, "        -%v|--%v)\n            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n            return\n            ;;\n",
+			name, name, strings.Join(flagValues[name], " "))
+	}
+	b.WriteString("    esac\n")
+
+	b.WriteString("    case \"${COMP_WORDS[1]}\" in\n")
+	for _, cmd := range commands {
+		words := make([]string, 0)
+		for _, f := range commandFlags(cmd) {
+			words = append(words, "--"+f.Name)
+		}
+		fmt.Fprintf(&b, "        %v)\n", cmd.name)
+		if values, ok := subcommandValues[cmd.name]; ok {
+			fmt.Fprintf(&b, "            if [ \"$COMP_CWORD\" -eq 2 ]; then\n                COMPREPLY=($(compgen -W %q -- \"$cur\"))\n                return\n            fi\n",
+				strings.Join(values, " "))
+		}
+		fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n            ;;\n", strings.Join(words, " "))
+	}
+	b.WriteString("    esac\n}\n\ncomplete -F _taskctl taskctl\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFishCompletion(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("complete -c taskctl -f\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "complete -c taskctl -n __fish_use_subcommand -a %v -d %q\n", cmd.name, cmd.summary)
+	}
+	for _, cmd := range commands {
+		condition := "__fish_seen_subcommand_from " + cmd.name
+		if values, ok := subcommandValues[cmd.name]; ok {
+			fmt.Fprintf(&b, "complete -c taskctl -n %q -a %q\n", condition, strings.Join(values, " "))
+		}
+		for _, f := range commandFlags(cmd) {
+			line := fmt.Sprintf("complete -c taskctl -n %q -o %v", condition, f.Name)
+			boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+			if values, found := flagValues[f.Name]; found {
+				line += fmt.Sprintf(" -x -a %q", strings.Join(values, " "))
+			} else if !ok || !boolFlag.IsBoolFlag() {
+				line += " -r"
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
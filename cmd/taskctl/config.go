@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"ivanjabrony/test_lo/pkg/client"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+)
+
+const defaultServer = "http://localhost:8080"
+
+// Environment variables override the current context and are overridden by the flags
+const (
+	envConfig  = "TASKCTL_CONFIG"
+	envContext = "TASKCTL_CONTEXT"
+	envServer  = "TASKCTL_SERVER"
+	envAPIKey  = "TASKCTL_API_KEY"
+	envToken   = "TASKCTL_TOKEN"
+)
+
+// globalFlags are accepted by every command
+type globalFlags struct {
+	configPath string
+	context    string
+	server     string
+	apiKey     string
+	token      string
+	project    int
+	output     string
+}
+
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.configPath, "config", "", "config file, $"+envConfig+" or taskctl/config.json in the user config dir by default")
+	fs.StringVar(&g.context, "context", "", "context of the config file to use instead of the current one")
+	fs.StringVar(&g.server, "server", "", "base url of the API, e.g. "+defaultServer)
+	fs.StringVar(&g.apiKey, "api-key", "", "api key")
+	fs.StringVar(&g.token, "token", "", "bearer token")
+	fs.IntVar(&g.project, "project", 0, "id of the project, the default project when 0")
+	fs.StringVar(&g.output, "output", outputTable, "output format: table, json or yaml")
+	fs.StringVar(&g.output, "o", outputTable, "shorthand for -output")
+}
+
+// Config is the config file of taskctl
+type Config struct {
+	CurrentContext string              `json:"current_context,omitempty"`
+	Contexts       map[string]*Context `json:"contexts,omitempty"`
+}
+
+// Context is a server with the credentials used for it
+type Context struct {
+	Server  string `json:"server"`
+	APIKey  string `json:"api_key,omitempty"`
+	Token   string `json:"token,omitempty"`
+	Project int    `json:"project,omitempty"`
+}
+
+func (c *cli) configPath() (string, error) {
+	if c.global.configPath != "" {
+		return c.global.configPath, nil
+	}
+	if path := c.getenv(envConfig); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("finding the config file: %w, set $%v", err, envConfig)
+	}
+	return filepath.Join(dir, "taskctl", "config.json"), nil
+}
+
+// loadConfig reads the config file, a missing file is an empty config
+func (c *cli) loadConfig() (*Config, string, error) {
+	path, err := c.configPath()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg := &Config{Contexts: map[string]*Context{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, path, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("reading config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, "", fmt.Errorf("parsing config %v: %w", path, err)
+	}
+	if cfg.Contexts == nil {
+		cfg.Contexts = map[string]*Context{}
+	}
+	return cfg, path, nil
+}
+
+// saveConfig writes the config file readable only by the user, since it holds credentials
+func saveConfig(cfg *Config, path string) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+// resolveContext merges the context of the config file with the environment and the flags, in that order
+func (c *cli) resolveContext() (Context, error) {
+	cfg, _, err := c.loadConfig()
+	if err != nil {
+		return Context{}, err
+	}
+
+	name := c.global.context
+	if name == "" {
+		name = c.getenv(envContext)
+	}
+	explicit := name != ""
+	if !explicit {
+		name = cfg.CurrentContext
+	}
+	resolved := Context{Server: defaultServer}
+	if stored, ok := cfg.Contexts[name]; ok {
+		resolved = *stored
+	} else if explicit {
+		return Context{}, usageErrorf("context %q isn't in the config", name)
+	}
+
+	overrides := []struct {
+		env, flag string
+		target    *string
+	}{
+		{envServer, c.global.server, &resolved.Server},
+		{envAPIKey, c.global.apiKey, &resolved.APIKey},
+		{envToken, c.global.token, &resolved.Token},
+	}
+	for _, override := range overrides {
+		if value := c.getenv(override.env); value != "" {
+			*override.target = value
+		}
+		if override.flag != "" {
+			*override.target = override.flag
+		}
+	}
+	if c.global.project != 0 {
+		resolved.Project = c.global.project
+	}
+	return resolved, nil
+}
+
+// client creates the api client of the resolved context
+func (c *cli) client() (*client.Client, error) {
+	if err := validateOutput(c.global.output); err != nil {
+		return nil, err
+	}
+	resolved, err := c.resolveContext()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.Option{client.WithUserAgent("taskctl")}
+	switch {
+	case resolved.Token != "":
+		opts = append(opts, client.WithAuth(client.BearerToken(resolved.Token)))
+	case resolved.APIKey != "":
+		opts = append(opts, client.WithAuth(client.APIKey(resolved.APIKey)))
+	}
+	if resolved.Project != 0 {
+		opts = append(opts, client.WithProject(resolved.Project))
+	}
+	api, err := client.New(resolved.Server, opts...)
+	if err != nil {
+		return nil, usageErrorf("%v", err)
+	}
+	return api, nil
+}
+
+var configSubcommands = []string{"view", "current-context", "get-contexts", "use-context", "set-context", "delete-context"}
+
+// configCommand manages the config file, set-context takes the values of -server, -api-key, -token and -project
+func (c *cli) configCommand(flags *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) == 0 || !slices.Contains(configSubcommands, args[0]) {
+			return usageErrorf("expected one of %v", configSubcommands)
+		}
+		subcommand, args := args[0], args[1:]
+		wantArgs := 0
+		if subcommand == "use-context" || subcommand == "set-context" || subcommand == "delete-context" {
+			wantArgs = 1
+		}
+		if len(args) != wantArgs {
+			return usageErrorf("%v expects %d arguments, got %d", subcommand, wantArgs, len(args))
+		}
+		if err := validateOutput(c.global.output); err != nil {
+			return err
+		}
+
+		cfg, path, err := c.loadConfig()
+		if err != nil {
+			return err
+		}
+		switch subcommand {
+		case "view":
+			// credentials aren't shown
+			redacted := Config{CurrentContext: cfg.CurrentContext, Contexts: map[string]*Context{}}
+			for name, stored := range cfg.Contexts {
+				shown := *stored
+				shown.APIKey, shown.Token = redact(shown.APIKey), redact(shown.Token)
+				redacted.Contexts[name] = &shown
+			}
+			// the config has no table, yaml is the default
+			if c.global.output == outputTable {
+				return writeYAML(c.stdout, redacted)
+			}
+			return c.print(redacted, nil)
+		case "current-context":
+			if cfg.CurrentContext == "" {
+				return fmt.Errorf("no current context: %w", client.ErrNotFound)
+			}
+			fmt.Fprintln(c.stdout, cfg.CurrentContext)
+			return nil
+		case "get-contexts":
+			return c.printContexts(cfg)
+		}
+
+		name := args[0]
+		switch subcommand {
+		case "use-context":
+			if _, ok := cfg.Contexts[name]; !ok {
+				return fmt.Errorf("context %q: %w", name, client.ErrNotFound)
+			}
+			cfg.CurrentContext = name
+		case "set-context":
+			stored, ok := cfg.Contexts[name]
+			if !ok {
+				stored = &Context{Server: defaultServer}
+				cfg.Contexts[name] = stored
+			}
+			flags.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "server":
+					stored.Server = c.global.server
+				case "api-key":
+					stored.APIKey = c.global.apiKey
+				case "token":
+					stored.Token = c.global.token
+				case "project":
+					stored.Project = c.global.project
+				}
+			})
+			if _, err := client.New(stored.Server); err != nil {
+				return usageErrorf("%v", err)
+			}
+			if cfg.CurrentContext == "" {
+				cfg.CurrentContext = name
+			}
+		case "delete-context":
+			if _, ok := cfg.Contexts[name]; !ok {
+				return fmt.Errorf("context %q: %w", name, client.ErrNotFound)
+			}
+			delete(cfg.Contexts, name)
+			if cfg.CurrentContext == name {
+				cfg.CurrentContext = ""
+			}
+		}
+		return saveConfig(cfg, path)
+	}
+}
+
+func (c *cli) printContexts(cfg *Config) error {
+	names := make([]string, 0, len(cfg.Contexts))
+	for name := range cfg.Contexts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	if c.global.output != outputTable {
+		type contextRow struct {
+			Name    string `json:"name"`
+			Current bool   `json:"current"`
+			Server  string `json:"server"`
+			Project int    `json:"project,omitempty"`
+		}
+		rows := make([]contextRow, 0, len(names))
+		for _, name := range names {
+			stored := cfg.Contexts[name]
+			rows = append(rows, contextRow{Name: name, Current: name == cfg.CurrentContext, Server: stored.Server, Project: stored.Project})
+		}
+		return c.print(rows, nil)
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CURRENT\tNAME\tSERVER\tPROJECT")
+	for _, name := range names {
+		current := ""
+		if name == cfg.CurrentContext {
+			current = "*"
+		}
+		project := "default"
+		if stored := cfg.Contexts[name]; stored.Project != 0 {
+			project = strconv.Itoa(stored.Project)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", current, name, cfg.Contexts[name].Server, project)
+	}
+	return tw.Flush()
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "REDACTED"
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/pkg/client"
+	"net"
+)
+
+// Exit codes, scripts tell the classes of errors apart by them
+const (
+	exitOK = 0
+	// exitError is any error without a class of its own
+	exitError = 1
+	// exitUsage is an invalid command line or config
+	exitUsage    = 2
+	exitNotFound = 3
+	// exitAuth is a missing or invalid credential, or a forbidden action
+	exitAuth = 4
+	// exitConflict is a duplicate, an exceeded quota, a dependency cycle or unfinished work
+	exitConflict = 5
+	// exitInvalid is a request the server rejected as invalid
+	exitInvalid = 6
+	// exitUnavailable is a network error or an error of the server worth retrying later
+	exitUnavailable = 7
+	// exitInterrupted follows the shell convention for SIGINT
+	exitInterrupted = 130
+)
+
+// usageError is an invalid command line, it's reported before any request is sent
+type usageError struct {
+	err error
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+func exitCode(err error) int {
+	var usageErr *usageError
+	var apiErr *client.APIError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrUnauthenticated), errors.Is(err, client.ErrForbidden):
+		return exitAuth
+	case errors.Is(err, client.ErrAlreadyExists), errors.Is(err, client.ErrQuotaExceeded),
+		errors.Is(err, client.ErrCycle), errors.Is(err, client.ErrIncomplete):
+		return exitConflict
+	case errors.Is(err, client.ErrInvalid):
+		return exitInvalid
+	case errors.As(err, &apiErr) && apiErr.Temporary(), errors.As(err, &netErr), errors.Is(err, context.DeadlineExceeded):
+		return exitUnavailable
+	}
+	return exitError
+}
@@ -0,0 +1,147 @@
+// Command taskctl manages tasks of the task API from the terminal.
+//
+// Servers and credentials are kept as named contexts in a config file, see "taskctl config".
+// The exit code tells the class of an error apart, see exitCode.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.Getenv)
+	stop()
+	os.Exit(code)
+}
+
+// cli runs a single command, the streams and the environment are injected so the commands can be tested
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+	global globalFlags
+}
+
+// command is a subcommand of taskctl
+type command struct {
+	name string
+	// args describes the positional arguments in the usage
+	args    string
+	summary string
+	// setup registers the flags of the command and returns the function running it with the positional arguments,
+	// the global flags are registered before it
+	setup func(c *cli, fs *flag.FlagSet) func(ctx context.Context, args []string) error
+}
+
+// commands are listed in the order of the usage
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "create", args: "NAME", summary: "Create a task", setup: (*cli).createCommand},
+		{name: "list", summary: "List tasks matching the filter", setup: (*cli).listCommand},
+		{name: "get", args: "ID", summary: "Show a task", setup: (*cli).getCommand},
+		{name: "update", args: "ID", summary: "Change the status or other fields of a task", setup: (*cli).updateCommand},
+		{name: "delete", args: "ID...", summary: "Delete tasks", setup: (*cli).deleteCommand},
+		{name: "export", summary: "Write tasks matching the filter as json, csv or ndjson", setup: (*cli).exportCommand},
+		{name: "watch", summary: "Print changes of tasks matching the filter until interrupted", setup: (*cli).watchCommand},
+		{name: "config", args: "SUBCOMMAND", summary: "Manage server contexts: " + strings.Join(configSubcommands, ", "), setup: (*cli).configCommand},
+		{name: "completion", args: "bash|zsh|fish", summary: "Print a shell completion script", setup: (*cli).completionCommand},
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer, getenv func(string) string) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr, getenv: getenv}
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage(stdout)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(stderr, "taskctl: unknown command %q\n\n", args[0])
+		c.usage(stderr)
+		return exitUsage
+	}
+
+	fs := c.newFlagSet(cmd)
+	runCommand := cmd.setup(c, fs)
+	positional, err := parseInterspersed(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		// flag has reported the error with the usage
+		return exitUsage
+	}
+	if err = runCommand(ctx, positional); err != nil {
+		fmt.Fprintf(stderr, "taskctl %v: %v\n", cmd.name, err)
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(stderr, "Run 'taskctl %v -h' for usage.\n", cmd.name)
+		}
+	}
+	return exitCode(err)
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// newFlagSet creates the flag set of the command with the global flags, parse errors are reported by run
+func (c *cli) newFlagSet(cmd command) *flag.FlagSet {
+	fs := flag.NewFlagSet("taskctl "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: taskctl %v [flags]\n\n%v.\n\nFlags:\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+		fs.PrintDefaults()
+	}
+	c.global.register(fs)
+	return fs
+}
+
+func (c *cli) usage(w io.Writer) {
+	fmt.Fprint(w, "taskctl manages tasks of the task API.\n\nUsage: taskctl COMMAND [ARGS] [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-11v %v\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprint(w, "\nRun 'taskctl COMMAND -h' for the flags of a command.\n")
+}
+
+// parseInterspersed parses flags given before, between and after the positional arguments, "--" ends the flags
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		// flag drops the terminator, so it's found as the last of the parsed arguments
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
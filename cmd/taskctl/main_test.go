@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/cmd/app"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/config"
+	"ivanjabrony/test_lo/internal/server"
+	"ivanjabrony/test_lo/pkg/client"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type MockLogger struct{}
+
+func (m *MockLogger) Log(format string, info ...any) {}
+
+const testAPIKey = "secret"
+
+// newTestAPI serves the application in process, the way the binary builds it
+func newTestAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	cfg := &config.Config{
+		AuthEnabled:               true,
+		APIKeys:                   "ci:0:admin:" + auth.HashAPIKey(testAPIKey),
+		StrictTaskCompletion:      true,
+		TaskStorage:               "memory",
+		StreamReplayBuffer:        16,
+		StreamClientBuffer:        16,
+		StreamHeartbeatSeconds:    1,
+		WSMaxMessageSize:          65536,
+		WSPingIntervalSeconds:     30,
+		WSRateLimit:               20,
+		WSRateBurst:               40,
+		WebhookWorkers:            1,
+		WebhookMaxAttempts:        1,
+		WebhookBackoffSeconds:     1,
+		WebhookMaxBackoffSeconds:  1,
+		WebhookTimeoutSeconds:     1,
+		RecurrenceIntervalSeconds: 30,
+		ReminderIntervalSeconds:   60,
+		ReminderDueSoonHours:      24,
+		ReminderStaleHours:        72,
+		NotifyDefaultChannels:     "log",
+		GraphQLMaxDepth:           10,
+		GraphQLMaxComplexity:      1000,
+	}
+	handlers, workers, _, err := app.InitializeAdapters(cfg, &MockLogger{})
+	if err != nil {
+		t.Fatalf("Failed to initialize adapters: %v", err)
+	}
+	workers.Webhooks.Start()
+	t.Cleanup(func() { workers.Webhooks.Stop(context.Background()) })
+
+	srv, err := server.NewHTTP(cfg, &MockLogger{}, server.Handlers{
+		Task:         handlers.Task,
+		User:         handlers.User,
+		Project:      handlers.Project,
+		Tag:          handlers.Tag,
+		Comment:      handlers.Comment,
+		Audit:        handlers.Audit,
+		Socket:       handlers.Socket,
+		Webhook:      handlers.Webhook,
+		GraphQL:      handlers.GraphQL,
+		Notification: handlers.Notification,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// syncBuffer is written by a running command while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// runCLI runs taskctl with the environment, unset variables are empty
+func runCLI(ctx context.Context, env map[string]string, args ...string) (string, string, int) {
+	var stdout, stderr syncBuffer
+	code := run(ctx, args, strings.NewReader(""), &stdout, &stderr, func(key string) string { return env[key] })
+	return stdout.String(), stderr.String(), code
+}
+
+func TestTaskCommands(t *testing.T) {
+	ts := newTestAPI(t)
+	env := map[string]string{
+		envConfig: filepath.Join(t.TempDir(), "config.json"),
+		envServer: ts.URL,
+		envAPIKey: testAPIKey,
+	}
+
+	// steps run in order against the same server
+	steps := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  []string
+		expectedErr  string
+	}{
+		{name: "create", args: []string{"create", "Write docs", "--priority", "high", "--description", "api docs"},
+			expectedOut: []string{"task 0 created"}},
+		{name: "create with flags first", args: []string{"create", "-o", "json", "--due", "2099-01-02", "Fix bug"},
+			expectedOut: []string{`"id": "1"`}},
+		{name: "create invalid", args: []string{"create", "Broken", "--status", "unknown"},
+			expectedCode: exitInvalid, expectedErr: "invalid"},
+		{name: "list", args: []string{"list"},
+			expectedOut: []string{"ID  NAME", "0   Write docs  created  high", "1   Fix bug"}},
+		{name: "list filtered", args: []string{"list", "--priority", "high", "-o", "json"},
+			expectedOut: []string{`"amount": 1`, `"name": "Write docs"`}},
+		{name: "list page", args: []string{"list", "--limit", "1", "--offset", "1", "--sort", "-id", "-o", "yaml"},
+			expectedOut: []string{"amount: 1\n", "limit: 1\n", "total: 2\n", "  - created_at", "    name: Write docs\n"}},
+		{name: "list invalid sort", args: []string{"list", "--sort", "name"}, expectedCode: exitUsage, expectedErr: "sort"},
+		{name: "get", args: []string{"get", "0"}, expectedOut: []string{"Name:         Write docs", "Description:  api docs"}},
+		{name: "get missing", args: []string{"get", "404"}, expectedCode: exitNotFound, expectedErr: "404 task not found"},
+		{name: "update status", args: []string{"update", "0", "--status", "inProgress", "-o", "json"},
+			expectedOut: []string{`"status": "inProgress"`, `"started_at"`}},
+		{name: "update nothing", args: []string{"update", "0"}, expectedCode: exitUsage, expectedErr: "nothing to update"},
+		{name: "export", args: []string{"export", "--format", "csv", "--status", "inProgress"},
+			expectedOut: []string{"Write docs"}},
+		{name: "delete", args: []string{"delete", "1", "0"}, expectedOut: []string{"task 1 deleted\ntask 0 deleted\n"}},
+		{name: "delete missing", args: []string{"delete", "1"}, expectedCode: exitNotFound, expectedErr: "deleting task 1"},
+		{name: "unknown output", args: []string{"list", "-o", "xml"}, expectedCode: exitUsage, expectedErr: "unknown output format"},
+		{name: "unknown flag", args: []string{"list", "--colour"}, expectedCode: exitUsage},
+		{name: "unknown command", args: []string{"remove"}, expectedCode: exitUsage, expectedErr: `unknown command "remove"`},
+		{name: "help", args: []string{"help"}, expectedOut: []string{"Commands:", "watch"}},
+		{name: "command help", args: []string{"list", "-h"}, expectedCode: exitOK},
+		{name: "wrong api key", args: []string{"list", "--api-key", "wrong"}, expectedCode: exitAuth, expectedErr: "401"},
+	}
+
+	for _, step := range steps {
+		stdout, stderr, code := runCLI(context.Background(), env, step.args...)
+		if code != step.expectedCode {
+			t.Errorf("%v: expected exit code %d, got %d, stderr %q", step.name, step.expectedCode, code, stderr)
+		}
+		for _, expected := range step.expectedOut {
+			if !strings.Contains(stdout, expected) {
+				t.Errorf("%v: expected %q in the output, got %q", step.name, expected, stdout)
+			}
+		}
+		if !strings.Contains(stderr, step.expectedErr) {
+			t.Errorf("%v: expected %q in the errors, got %q", step.name, step.expectedErr, stderr)
+		}
+	}
+}
+
+func TestConfigContexts(t *testing.T) {
+	ts := newTestAPI(t)
+	path := filepath.Join(t.TempDir(), "taskctl", "config.json")
+	env := map[string]string{envConfig: path}
+
+	steps := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedOut  string
+	}{
+		{name: "no current context", args: []string{"config", "current-context"}, expectedCode: exitNotFound},
+		{name: "server flag without a config", args: []string{"list", "--server", "http://127.0.0.1:1"}, expectedCode: exitUnavailable},
+		{name: "add a broken context", args: []string{"config", "set-context", "broken", "--server", "http://127.0.0.1:1"}},
+		{name: "add a context", args: []string{"config", "set-context", "test", "--server", ts.URL, "--api-key", testAPIKey}},
+		{name: "first context is current", args: []string{"config", "current-context"}, expectedOut: "broken\n"},
+		{name: "current context is used", args: []string{"list"}, expectedCode: exitUnavailable},
+		{name: "context flag", args: []string{"list", "--context", "test"}, expectedOut: "ID  NAME"},
+		{name: "switch context", args: []string{"config", "use-context", "test"}},
+		{name: "switched context is used", args: []string{"list"}, expectedOut: "ID  NAME"},
+		{name: "update a context", args: []string{"config", "set-context", "test", "--project", "404"}},
+		{name: "project of the context", args: []string{"list"}, expectedCode: exitNotFound},
+		{name: "project flag", args: []string{"list", "--project", "1"}, expectedOut: "ID  NAME"},
+		{name: "list contexts", args: []string{"config", "get-contexts"},
+			expectedOut: "*        test    " + ts.URL + "  404\n"},
+		{name: "view hides credentials", args: []string{"config", "view"}, expectedOut: "api_key: REDACTED"},
+		{name: "unknown context", args: []string{"list", "--context", "missing"}, expectedCode: exitUsage},
+		{name: "use unknown context", args: []string{"config", "use-context", "missing"}, expectedCode: exitNotFound},
+		{name: "invalid server", args: []string{"config", "set-context", "bad", "--server", "localhost"}, expectedCode: exitUsage},
+		{name: "delete context", args: []string{"config", "delete-context", "test"}},
+		{name: "current context is deleted", args: []string{"config", "current-context"}, expectedCode: exitNotFound},
+		{name: "unknown subcommand", args: []string{"config", "rename"}, expectedCode: exitUsage},
+	}
+
+	for _, step := range steps {
+		stdout, stderr, code := runCLI(context.Background(), env, step.args...)
+		if code != step.expectedCode {
+			t.Errorf("%v: expected exit code %d, got %d, stderr %q", step.name, step.expectedCode, code, stderr)
+		}
+		if !strings.Contains(stdout, step.expectedOut) {
+			t.Errorf("%v: expected %q in the output, got %q", step.name, step.expectedOut, stdout)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the config to be readable only by the user, got %v, %v", info, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ts := newTestAPI(t)
+	env := map[string]string{envConfig: filepath.Join(t.TempDir(), "config.json"), envServer: ts.URL, envAPIKey: testAPIKey}
+	api, _ := client.New(ts.URL, client.WithAuth(client.APIKey(testAPIKey)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var stdout, stderr syncBuffer
+	code := make(chan int, 1)
+	go func() {
+		code <- run(ctx, []string{"watch", "--status", "created", "-o", "json"}, strings.NewReader(""), &stdout, &stderr,
+			func(key string) string { return env[key] })
+	}()
+
+	// the task is created until the stream is open and sees it
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(stdout.String(), `"name":"watched"`) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the change to be printed, got %q, errors %q", stdout.String(), stderr.String())
+		}
+		if _, err := api.CreateTask(context.Background(), client.PostTaskRequest{Name: "watched", Status: client.StatusCreated}); err != nil {
+			t.Fatalf("CreateTask failed: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case got := <-code:
+		if got != exitOK {
+			t.Errorf("Expected an interrupted watch to exit with %d, got %d, errors %q", exitOK, got, stderr.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected watch to stop when interrupted")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			stdout, stderr, code := runCLI(context.Background(), nil, "completion", shell)
+			if code != exitOK {
+				t.Fatalf("Expected exit code %d, got %d, errors %q", exitOK, code, stderr)
+			}
+			for _, expected := range []string{"create", "watch", "due-before", "inProgress", "use-context"} {
+				if !strings.Contains(stdout, expected) {
+					t.Errorf("Expected %q in the script", expected)
+				}
+			}
+
+			if shell != "bash" {
+				return
+			}
+			bash, err := exec.LookPath("bash")
+			if err != nil {
+				t.Skip("bash isn't installed")
+			}
+			cmd := exec.Command(bash, "-n")
+			cmd.Stdin = strings.NewReader(stdout)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("Expected a valid bash script, got %v: %s", err, output)
+			}
+		})
+	}
+
+	if _, _, code := runCLI(context.Background(), nil, "completion", "powershell"); code != exitUsage {
+		t.Errorf("Expected exit code %d for an unsupported shell, got %d", exitUsage, code)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{err: nil, expected: exitOK},
+		{err: usageErrorf("bad flag"), expected: exitUsage},
+		{err: &client.APIError{StatusCode: http.StatusNotFound}, expected: exitNotFound},
+		{err: &client.APIError{StatusCode: http.StatusForbidden}, expected: exitAuth},
+		{err: &client.APIError{StatusCode: http.StatusConflict, Message: "task quota exceeded"}, expected: exitConflict},
+		{err: &client.APIError{StatusCode: http.StatusBadRequest}, expected: exitInvalid},
+		{err: &client.APIError{StatusCode: http.StatusServiceUnavailable}, expected: exitUnavailable},
+		{err: &client.APIError{StatusCode: http.StatusInternalServerError}, expected: exitUnavailable},
+		{err: fmt.Errorf("deleting task 1: %w", context.DeadlineExceeded), expected: exitUnavailable},
+		{err: context.Canceled, expected: exitInterrupted},
+		{err: errors.New("disk full"), expected: exitError},
+		{err: io.ErrUnexpectedEOF, expected: exitError},
+	}
+
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.expected {
+			t.Errorf("Expected exit code %d for %v, got %d", tt.expected, tt.err, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Output formats of -output
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func validateOutput(output string) error {
+	switch output {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return usageErrorf("unknown output format %q, expected table, json or yaml", output)
+}
+
+// print writes the value in the output format, table writes the table form of it
+func (c *cli) print(value any, table func(w io.Writer) error) error {
+	switch c.global.output {
+	case outputJSON:
+		return writeJSON(c.stdout, value)
+	case outputYAML:
+		return writeYAML(c.stdout, value)
+	}
+	if table == nil {
+		return writeYAML(c.stdout, value)
+	}
+	return table(c.stdout)
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// writeYAML writes the value as block style yaml. The value is encoded as json first, so it's written with
+// the json names of the fields, keys are sorted.
+func writeYAML(w io.Writer, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	switch decoded.(type) {
+	case map[string]any, []any:
+		if isEmptyCollection(decoded) {
+			b.WriteString(yamlScalar(decoded) + "\n")
+		} else {
+			writeYAMLNode(&b, decoded, 0)
+		}
+	default:
+		b.WriteString(yamlScalar(decoded) + "\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// writeYAMLNode writes a non empty mapping or sequence, every line starts with indent spaces
+func writeYAMLNode(b *strings.Builder, node any, indent int) {
+	prefix := strings.Repeat(" ", indent)
+	switch node := node.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			b.WriteString(prefix + yamlString(key) + ":")
+			writeYAMLValue(b, node[key], indent)
+		}
+	case []any:
+		for _, item := range node {
+			if isCollection(item) && !isEmptyCollection(item) {
+				// the first line of the item goes after the dash, the other ones are indented under it
+				var nested strings.Builder
+				writeYAMLNode(&nested, item, indent+2)
+				b.WriteString(prefix + "- " + nested.String()[indent+2:])
+				continue
+			}
+			b.WriteString(prefix + "- " + yamlScalar(item) + "\n")
+		}
+	}
+}
+
+// writeYAMLValue writes the value of a mapping key, collections go on the next lines
+func writeYAMLValue(b *strings.Builder, value any, indent int) {
+	if isCollection(value) && !isEmptyCollection(value) {
+		b.WriteString("\n")
+		writeYAMLNode(b, value, indent+2)
+		return
+	}
+	b.WriteString(" " + yamlScalar(value) + "\n")
+}
+
+func isCollection(value any) bool {
+	switch value.(type) {
+	case map[string]any, []any:
+		return true
+	}
+	return false
+}
+
+func isEmptyCollection(value any) bool {
+	switch value := value.(type) {
+	case map[string]any:
+		return len(value) == 0
+	case []any:
+		return len(value) == 0
+	}
+	return false
+}
+
+func yamlScalar(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(value)
+	case json.Number:
+		return value.String()
+	case string:
+		return yamlString(value)
+	case map[string]any:
+		return "{}"
+	case []any:
+		return "[]"
+	}
+	return fmt.Sprint(value)
+}
+
+// yamlString quotes strings a yaml parser would read as something else, json quoting is valid yaml
+func yamlString(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\t\\") ||
+		strings.HasPrefix(s, "-") || strings.HasPrefix(s, "?") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteYAML(t *testing.T) {
+	type nested struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Empty []int    `json:"empty"`
+	}
+
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{name: "scalar", value: 5, expected: "5\n"},
+		{name: "empty list", value: []int{}, expected: "[]\n"},
+		{
+			name:     "mapping",
+			value:    map[string]any{"b": true, "a": "text", "c": nil, "d": 1.5},
+			expected: "a: text\nb: true\nc: null\nd: 1.5\n",
+		},
+		{
+			name:     "quoted strings",
+			value:    map[string]string{"colon": "a: b", "number": "12", "bool": "yes", "empty": "", "dash": "-id", "line": "a\nb"},
+			expected: "bool: \"yes\"\ncolon: \"a: b\"\ndash: \"-id\"\nempty: \"\"\nline: \"a\\nb\"\nnumber: \"12\"\n",
+		},
+		{
+			name:     "time",
+			value:    map[string]time.Time{"at": time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+			expected: "at: \"2025-01-02T03:04:05Z\"\n",
+		},
+		{
+			name:  "nested",
+			value: map[string]any{"items": []nested{{Name: "a", Tags: []string{"x", "y"}}, {Name: "b"}}, "amount": 2},
+			expected: "amount: 2\nitems:\n" +
+				"  - empty: null\n    name: a\n    tags:\n      - x\n      - \"y\"\n" +
+				"  - empty: null\n    name: b\n    tags: null\n",
+		},
+		{name: "list of lists", value: [][]int{{1, 2}, {}}, expected: "- - 1\n  - 2\n- []\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := writeYAML(&b, tt.value); err != nil {
+				t.Fatalf("writeYAML failed: %v", err)
+			}
+			if b.String() != tt.expected {
+				t.Errorf("Expected\n%v\ngot\n%v", tt.expected, b.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/pkg/client"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// filterFlags are the flags of the commands reading tasks, they match the query parameters of GET /tasks
+type filterFlags struct {
+	status    string
+	assignee  int
+	priority  string
+	overdue   bool
+	dueBefore string
+	dueAfter  string
+	tags      string
+	tagMatch  string
+	sort      string
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.status, "status", "", "created, inProgress or done")
+	fs.IntVar(&f.assignee, "assignee", 0, "id of the assignee")
+	fs.StringVar(&f.priority, "priority", "", "low, medium, high or urgent")
+	fs.BoolVar(&f.overdue, "overdue", false, "only tasks past their due date that aren't done")
+	fs.StringVar(&f.dueBefore, "due-before", "", "only tasks due before the time, RFC 3339 or a date")
+	fs.StringVar(&f.dueAfter, "due-after", "", "only tasks due after the time, RFC 3339 or a date")
+	fs.StringVar(&f.tags, "tags", "", "comma separated tag names")
+	fs.StringVar(&f.tagMatch, "tag-match", "", "whether tasks need any or all of the tags, any by default")
+	fs.StringVar(&f.sort, "sort", "", "id, due_at, priority, created_at, started_at or completed_at, a leading - sorts descending")
+}
+
+func (f *filterFlags) filter() (model.Filter, error) {
+	filter := model.Filter{
+		Status:     model.TaskStatus(f.status),
+		AssigneeID: f.assignee,
+		Priority:   model.TaskPriority(f.priority),
+		Overdue:    f.overdue,
+		TagMatch:   model.TagMatch(f.tagMatch),
+	}
+	var err error
+	if filter.DueBefore, err = parseTime("due-before", f.dueBefore); err != nil {
+		return model.EmptyFilter, err
+	}
+	if filter.DueAfter, err = parseTime("due-after", f.dueAfter); err != nil {
+		return model.EmptyFilter, err
+	}
+	for _, tag := range strings.Split(f.tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+	if f.sort != "" {
+		field, descending := strings.CutPrefix(f.sort, "-")
+		filter.SortBy, filter.Descending = model.SortField(field), descending
+	}
+	if err := model.ValidateFilter(filter); err != nil {
+		return model.EmptyFilter, usageErrorf("%v", err)
+	}
+	return filter, nil
+}
+
+// parseTime parses an RFC 3339 time or a date, an empty value is the zero time
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	if parsed, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return parsed, nil
+	}
+	return time.Time{}, usageErrorf("invalid -%v %q, expected RFC 3339 time or YYYY-MM-DD date", name, value)
+}
+
+// parseTaskId takes a public id or an id of the task, servers without public ids reject anything but numbers
+func parseTaskId(arg string) (client.TaskID, error) {
+	if strings.TrimSpace(arg) == "" {
+		return "", usageErrorf("invalid task id %q", arg)
+	}
+	return client.TaskID(arg), nil
+}
+
+func (c *cli) createCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	var task dto.PostTaskRequest
+	var status, priority, due string
+	var parent string
+	fs.StringVar(&task.Description, "description", "", "description of the task")
+	fs.StringVar(&status, "status", string(model.Created), "created, inProgress or done")
+	fs.StringVar(&priority, "priority", "", "low, medium, high or urgent, medium by default")
+	fs.IntVar(&task.AssigneeID, "assignee", 0, "id of the assignee")
+	fs.StringVar(&parent, "parent", "", "id of the parent task")
+	fs.StringVar(&due, "due", "", "due date, RFC 3339 or a date")
+	fs.BoolVar(&task.AllowPastDue, "allow-past-due", false, "allow a due date in the past")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("expected the name of the task")
+		}
+		task.Name = args[0]
+		task.Status, task.Priority = model.TaskStatus(status), model.TaskPriority(priority)
+		if parent != "" {
+			parentId, err := parseTaskId(parent)
+			if err != nil {
+				return err
+			}
+			task.ParentID = parentId.Ref()
+		}
+		var err error
+		if task.DueAt, err = parseTime("due", due); err != nil {
+			return err
+		}
+		api, err := c.client()
+		if err != nil {
+			return err
+		}
+
+		id, err := api.CreateTask(ctx, task)
+		if err != nil {
+			return err
+		}
+		return c.print(map[string]client.TaskID{"id": id}, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "task %v created\n", id)
+			return err
+		})
+	}
+}
+
+func (c *cli) listCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	var filterArgs filterFlags
+	filterArgs.register(fs)
+	var limit, offset int
+	fs.IntVar(&limit, "limit", 0, "page size, every task is listed when neither -limit nor -offset is given")
+	fs.IntVar(&offset, "offset", 0, "number of skipped tasks")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		filter, err := filterArgs.filter()
+		if err != nil {
+			return err
+		}
+		api, err := c.client()
+		if err != nil {
+			return err
+		}
+
+		var response client.TaskList
+		if limit != 0 || offset != 0 {
+			response, err = api.ListTasksPage(ctx, filter, model.Page{Limit: limit, Offset: offset})
+		} else {
+			response, err = api.ListTasks(ctx, filter)
+		}
+		if err != nil {
+			return err
+		}
+		return c.print(response, func(w io.Writer) error {
+			return writeTaskTable(w, response.Tasks)
+		})
+	}
+}
+
+func (c *cli) getCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	var asOf string
+	fs.StringVar(&asOf, "as-of", "", "show the task as it was at the time, RFC 3339 or a date")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("expected the id of the task")
+		}
+		id, err := parseTaskId(args[0])
+		if err != nil {
+			return err
+		}
+		at, err := parseTime("as-of", asOf)
+		if err != nil {
+			return err
+		}
+		api, err := c.client()
+		if err != nil {
+			return err
+		}
+
+		var task client.Task
+		if at.IsZero() {
+			task, err = api.GetTask(ctx, id)
+		} else {
+			task, err = api.GetTaskAsOf(ctx, id, at)
+		}
+		if err != nil {
+			return err
+		}
+		return c.print(task, func(w io.Writer) error {
+			return writeTaskDetails(w, task)
+		})
+	}
+}
+
+func (c *cli) updateCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	var status, name, description, priority, due string
+	var assignee int
+	var allowPastDue bool
+	fs.StringVar(&status, "status", "", "created, inProgress or done")
+	fs.StringVar(&name, "name", "", "new name")
+	fs.StringVar(&description, "description", "", "new description")
+	fs.StringVar(&priority, "priority", "", "low, medium, high or urgent")
+	fs.IntVar(&assignee, "assignee", 0, "id of the assignee, 0 unassigns the task")
+	fs.StringVar(&due, "due", "", "due date, RFC 3339 or a date")
+	fs.BoolVar(&allowPastDue, "allow-past-due", false, "allow a due date in the past")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return usageErrorf("expected the id of the task")
+		}
+		id, err := parseTaskId(args[0])
+		if err != nil {
+			return err
+		}
+
+		// only the given flags are changed, so an empty description can still be set
+		patch := dto.PatchTaskRequest{AllowPastDue: allowPastDue}
+		var parseErr error
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "status":
+				taskStatus := model.TaskStatus(status)
+				patch.Status = &taskStatus
+			case "name":
+				patch.Name = &name
+			case "description":
+				patch.Description = &description
+			case "priority":
+				taskPriority := model.TaskPriority(priority)
+				patch.Priority = &taskPriority
+			case "assignee":
+				patch.AssigneeID = &assignee
+			case "due":
+				dueAt, err := parseTime("due", due)
+				if err != nil {
+					parseErr = err
+				}
+				patch.DueAt = &dueAt
+			}
+		})
+		if parseErr != nil {
+			return parseErr
+		}
+		if patch == (dto.PatchTaskRequest{AllowPastDue: allowPastDue}) {
+			return usageErrorf("nothing to update, give -status or another field")
+		}
+		api, err := c.client()
+		if err != nil {
+			return err
+		}
+
+		task, err := api.UpdateTask(ctx, id, patch)
+		if err != nil {
+			return err
+		}
+		return c.print(task, func(w io.Writer) error {
+			return writeTaskDetails(w, task)
+		})
+	}
+}
+
+func (c *cli) deleteCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) == 0 {
+			return usageErrorf("expected ids of the tasks")
+		}
+		ids := make([]client.TaskID, 0, len(args))
+		for _, arg := range args {
+			id, err := parseTaskId(arg)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		api, err := c.client()
+		if err != nil {
+			return err
+		}
+
+		// tasks are deleted one by one, the ones before a failure stay deleted
+		for _, id := range ids {
+			if err := api.DeleteTask(ctx, id); err != nil {
+				return fmt.Errorf("deleting task %v: %w", id, err)
+			}
+			if c.global.output == outputTable {
+				fmt.Fprintf(c.stdout, "task %v deleted\n", id)
+			}
+		}
+		if c.global.output == outputTable {
+			return nil
+		}
+		return c.print(map[string][]client.TaskID{"deleted": ids}, nil)
+	}
+}
+
+func (c *cli) exportCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	var filterArgs filterFlags
+	filterArgs.register(fs)
+	var format, file string
+	fs.StringVar(&format, "format", client.FormatJSON, "json, csv or ndjson")
+	fs.StringVar(&file, "file", "", "file to write, stdout by default")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		if format != client.FormatJSON && format != client.FormatCSV && format != client.FormatNDJSON {
+			return usageErrorf("unknown export format %q, expected json, csv or ndjson", format)
+		}
+		filter, err := filterArgs.filter()
+		if err != nil {
+			return err
+		}
+		api, err := c.client()
+		if err != nil {
+			return err
+		}
+
+		body, err := api.ExportTasks(ctx, format, filter)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		if file == "" {
+			if _, err := io.Copy(c.stdout, body); err != nil {
+				return fmt.Errorf("writing export: %w", err)
+			}
+			return nil
+		}
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, body); err != nil {
+			f.Close()
+			return fmt.Errorf("writing export: %w", err)
+		}
+		return f.Close()
+	}
+}
+
+// watchCommand prints changes until it's interrupted, streams closed by the server are resumed after the last change
+func (c *cli) watchCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	var filterArgs filterFlags
+	filterArgs.register(fs)
+	var lastEventID int
+	fs.IntVar(&lastEventID, "last-event-id", 0, "print the changes after the one with the id first")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		filter, err := filterArgs.filter()
+		if err != nil {
+			return err
+		}
+		api, err := c.client()
+		if err != nil {
+			return err
+		}
+
+		for {
+			stream, err := api.WatchTasks(ctx, filter, lastEventID)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = c.printEvents(stream)
+			lastEventID = stream.LastEventID()
+			stream.Close()
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !errors.Is(err, io.EOF) {
+				return err
+			}
+		}
+	}
+}
+
+func (c *cli) printEvents(stream *client.EventStream) error {
+	for {
+		event, err := stream.Next()
+		if errors.Is(err, client.ErrStreamReset) {
+			fmt.Fprintln(c.stderr, "taskctl watch: some changes were missed, reload the tasks")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch c.global.output {
+		case outputJSON:
+			// a change per line, so the output can be piped line by line
+			err = json.NewEncoder(c.stdout).Encode(event)
+		case outputYAML:
+			if _, err = io.WriteString(c.stdout, "---\n"); err == nil {
+				err = writeYAML(c.stdout, event)
+			}
+		default:
+			_, err = fmt.Fprintf(c.stdout, "%v\t%v\t%v\ttask %v\t%v\t%v\n",
+				event.ID, event.At.Local().Format(time.DateTime), event.Type, event.Task.ID, event.Task.Status, event.Task.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeTaskTable(w io.Writer, tasks []client.Task) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tPRIORITY\tASSIGNEE\tDUE")
+	for _, task := range tasks {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\n",
+			task.ID, task.Name, task.Status, task.Priority, optionalId(task.AssigneeID), optionalTime(task.DueAt))
+	}
+	return tw.Flush()
+}
+
+func writeTaskDetails(w io.Writer, task client.Task) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	parent := "-"
+	if task.ParentID != nil {
+		parent = string(*task.ParentID)
+	}
+	rows := [][2]string{
+		{"ID", string(task.ID)},
+		{"Name", task.Name},
+		{"Description", task.Description},
+		{"Status", string(task.Status)},
+		{"Priority", string(task.Priority)},
+		{"Assignee", optionalId(task.AssigneeID)},
+		{"Reporter", optionalId(task.ReporterID)},
+		{"Parent", parent},
+		{"Blocked by", joinIds(task.BlockedBy)},
+		{"Tags", joinIds(task.TagIDs)},
+		{"Comments", strconv.Itoa(task.CommentCount)},
+		{"Due", optionalTime(task.DueAt)},
+		{"Overdue", strconv.FormatBool(task.Overdue)},
+		{"Created", optionalTime(task.CreatedAt)},
+		{"Started", optionalTime(task.StartedAt)},
+		{"Completed", optionalTime(task.CompletedAt)},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%v:\t%v\n", row[0], row[1])
+	}
+	return tw.Flush()
+}
+
+func optionalId(id int) string {
+	if id == model.NoUser {
+		return "-"
+	}
+	return strconv.Itoa(id)
+}
+
+func optionalTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+func joinIds[T any](ids []T) string {
+	if len(ids) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprint(id))
+	}
+	return strings.Join(parts, ",")
+}
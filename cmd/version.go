@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+)
+
+// versionCommand prints the build info embedded by the go toolchain
+func (c *cli) versionCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return errors.New("the binary has no build info")
+		}
+		return writeVersion(c.stdout, info)
+	}
+}
+
+// writeVersion writes the version of the main module, the go version and the commit the binary was built from
+func writeVersion(w io.Writer, info *debug.BuildInfo) error {
+	settings := make(map[string]string)
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+
+	var b strings.Builder
+	version := info.Main.Version
+	if version == "" {
+		version = "(devel)"
+	}
+	fmt.Fprintf(&b, "task_lo %v\n", version)
+	fmt.Fprintf(&b, "module:   %v\n", info.Main.Path)
+	fmt.Fprintf(&b, "go:       %v %v/%v\n", info.GoVersion, settings["GOOS"], settings["GOARCH"])
+	if revision, ok := settings["vcs.revision"]; ok {
+		if settings["vcs.modified"] == "true" {
+			revision += " (modified)"
+		}
+		fmt.Fprintf(&b, "revision: %v\n", revision)
+	}
+	if committed, ok := settings["vcs.time"]; ok {
+		fmt.Fprintf(&b, "time:     %v\n", committed)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APIKey is a static key, only sha256 hash of the key is kept in memory and config
+type APIKey struct {
+	Subject string
+	UserID  int
+	Roles   []string
+	Hash    [sha256.Size]byte
+}
+
+// HashAPIKey returns hex encoded sha256 of the key in the form used in API_KEYS
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseAPIKeys parses comma separated "subject:user_id:role1|role2:sha256hex" entries.
+// user_id and roles may be empty.
+func ParseAPIKeys(spec string) ([]APIKey, error) {
+	keys := make([]APIKey, 0)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("invalid api key entry %q: expected subject:user_id:roles:hash", entry)
+		}
+		key := APIKey{Subject: parts[0]}
+		if key.Subject == "" {
+			return nil, fmt.Errorf("invalid api key entry %q: empty subject", entry)
+		}
+
+		if parts[1] != "" {
+			userId, err := strconv.Atoi(parts[1])
+			if err != nil || userId < 0 {
+				return nil, fmt.Errorf("invalid api key entry %q: invalid user id", entry)
+			}
+			key.UserID = userId
+		}
+
+		for _, role := range strings.Split(parts[2], "|") {
+			if role != "" {
+				key.Roles = append(key.Roles, role)
+			}
+		}
+
+		hash, err := hex.DecodeString(parts[3])
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid api key entry %q: hash must be hex encoded sha256", entry)
+		}
+		copy(key.Hash[:], hash)
+
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// matchAPIKey compares the key against every configured hash in constant time
+func matchAPIKey(keys []APIKey, key string) (APIKey, bool) {
+	sum := sha256.Sum256([]byte(key))
+	found := -1
+	for i := range keys {
+		if subtle.ConstantTimeCompare(keys[i].Hash[:], sum[:]) == 1 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return APIKey{}, false
+	}
+	return keys[found], true
+}
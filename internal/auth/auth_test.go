@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(t *testing.T, secret []byte, claims map[string]any) string {
+	t.Helper()
+	unsigned := encodeSegment(t, jwtHeader{Alg: AlgHS256, Typ: "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	unsigned := encodeSegment(t, jwtHeader{Alg: AlgRS256, Typ: "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate rsa key: %v", err)
+	}
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)})
+	publicKey, err := ParseRSAPublicKey(pemKey)
+	if err != nil {
+		t.Fatalf("Failed to parse public key: %v", err)
+	}
+
+	verifier, err := NewJWTVerifier(JWTConfig{
+		HMACSecret: testSecret,
+		PublicKey:  publicKey,
+		Issuer:     "tasks-idp",
+		Audience:   "tasks-api",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	rsaOnly, _ := NewJWTVerifier(JWTConfig{PublicKey: publicKey})
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":   "john",
+			"iss":   "tasks-idp",
+			"aud":   []string{"other", "tasks-api"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"uid":   7,
+			"roles": []string{"member"},
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		verifier *JWTVerifier
+		token    string
+		wantErr  error
+	}{
+		{
+			name:     "valid hs256",
+			verifier: verifier,
+			token:    signHS256(t, testSecret, claims(nil)),
+		},
+		{
+			name:     "valid rs256 with string audience",
+			verifier: verifier,
+			token:    signRS256(t, rsaKey, claims(map[string]any{"aud": "tasks-api"})),
+		},
+		{
+			name:     "expired within leeway",
+			verifier: verifier,
+			token:    signHS256(t, testSecret, claims(map[string]any{"exp": now.Add(-10 * time.Second).Unix()})),
+		},
+		{
+			name:     "expired",
+			verifier: verifier,
+			token:    signHS256(t, testSecret, claims(map[string]any{"exp": now.Add(-time.Hour).Unix()})),
+			wantErr:  ErrTokenExpired,
+		},
+		{
+			name:     "missing exp",
+			verifier: verifier,
+			token:    signHS256(t, testSecret, claims(map[string]any{"exp": nil})),
+			wantErr:  ErrTokenExpired,
+		},
+		{
+			name:     "not valid yet",
+			verifier: verifier,
+			token:    signHS256(t, testSecret, claims(map[string]any{"nbf": now.Add(time.Hour).Unix()})),
+			wantErr:  ErrTokenNotYetValid,
+		},
+		{
+			name:     "wrong issuer",
+			verifier: verifier,
+			token:    signHS256(t, testSecret, claims(map[string]any{"iss": "evil"})),
+			wantErr:  ErrInvalidIssuer,
+		},
+		{
+			name:     "wrong audience",
+			verifier: verifier,
+			token:    signHS256(t, testSecret, claims(map[string]any{"aud": "other"})),
+			wantErr:  ErrInvalidAudience,
+		},
+		{
+			name:     "wrong hmac secret",
+			verifier: verifier,
+			token:    signHS256(t, []byte("ffffffffffffffffffffffffffffffff"), claims(nil)),
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "wrong rsa key",
+			verifier: verifier,
+			token:    signRS256(t, otherKey, claims(nil)),
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "hs256 signed with public key is rejected",
+			verifier: rsaOnly,
+			token:    signHS256(t, pemKey, claims(nil)),
+			wantErr:  ErrUnsupportedAlg,
+		},
+		{
+			name:     "alg none",
+			verifier: verifier,
+			token:    encodeSegment(t, jwtHeader{Alg: "none"}) + "." + encodeSegment(t, claims(nil)) + ".",
+			wantErr:  ErrUnsupportedAlg,
+		},
+		{
+			name:     "malformed",
+			verifier: verifier,
+			token:    "not-a-token",
+			wantErr:  ErrMalformedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.verifier.Verify(tt.token, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && (got.Subject != "john" || got.UserID != 7 || len(got.Roles) != 1) {
+				t.Errorf("Unexpected claims %+v", got)
+			}
+		})
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	hash := HashAPIKey("secret")
+
+	keys, err := ParseAPIKeys("ci::admin:" + hash + ", bot:3:member|viewer:" + HashAPIKey("other"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(keys))
+	}
+	if keys[0].Subject != "ci" || keys[0].UserID != 0 || len(keys[0].Roles) != 1 {
+		t.Errorf("Unexpected first key %+v", keys[0])
+	}
+	if keys[1].UserID != 3 || len(keys[1].Roles) != 2 {
+		t.Errorf("Unexpected second key %+v", keys[1])
+	}
+
+	for _, spec := range []string{"ci:admin:" + hash, "ci:x:admin:" + hash, ":1:admin:" + hash, "ci:1:admin:zz"} {
+		if _, err := ParseAPIKeys(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	keys, _ := ParseAPIKeys("ci:5:admin:" + HashAPIKey("secret"))
+	verifier, _ := NewJWTVerifier(JWTConfig{HMACSecret: testSecret})
+	authenticator, err := NewAuthenticator(keys, verifier)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	token := signHS256(t, testSecret, map[string]any{"sub": "john", "exp": time.Now().Add(time.Hour).Unix()})
+
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		wantSubject string
+		wantErr     bool
+	}{
+		{name: "api key header", headers: map[string]string{APIKeyHeader: "secret"}, wantSubject: "ci"},
+		{name: "api key scheme", headers: map[string]string{"Authorization": "ApiKey secret"}, wantSubject: "ci"},
+		{name: "bearer", headers: map[string]string{"Authorization": "Bearer " + token}, wantSubject: "john"},
+		{name: "wrong api key", headers: map[string]string{APIKeyHeader: "wrong"}, wantErr: true},
+		{name: "no credentials", headers: map[string]string{}, wantErr: true},
+		{name: "basic auth", headers: map[string]string{"Authorization": "Basic Y2k6c2VjcmV0"}, wantErr: true},
+		{
+			name:    "both schemes",
+			headers: map[string]string{APIKeyHeader: "secret", "Authorization": "Bearer " + token},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/tasks", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			principal, err := authenticator.Authenticate(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if principal.Subject != tt.wantSubject {
+				t.Errorf("Expected subject %q, got %q", tt.wantSubject, principal.Subject)
+			}
+		})
+	}
+
+	if _, err := NewAuthenticator(nil, nil); err == nil {
+		t.Error("Expected error for authenticator without methods")
+	}
+}
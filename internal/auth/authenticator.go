@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+var (
+	ErrNoCredentials  = errors.New("no credentials provided")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrJWTNotEnabled  = errors.New("bearer tokens are not accepted")
+	errNoAuthMethods  = errors.New("authenticator needs at least one api key or jwt verifier")
+	errUnknownScheme  = errors.New("unknown authorization scheme")
+	errEmptyBearer    = errors.New("empty bearer token")
+	errEmptyAPIKey    = errors.New("empty api key")
+	errMultipleScheme = errors.New("multiple credentials provided")
+)
+
+// Authenticator resolves a Principal from request credentials.
+//
+// API keys are accepted in the X-API-Key header or as "Authorization: ApiKey <key>",
+// JWTs as "Authorization: Bearer <token>".
+type Authenticator struct {
+	keys []APIKey
+	jwt  *JWTVerifier
+	now  func() time.Time
+}
+
+// NewAuthenticator creates an Authenticator, jwt may be nil if bearer tokens aren't used
+func NewAuthenticator(keys []APIKey, jwt *JWTVerifier) (*Authenticator, error) {
+	if len(keys) == 0 && jwt == nil {
+		return nil, errNoAuthMethods
+	}
+	return &Authenticator{keys: keys, jwt: jwt, now: time.Now}, nil
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	return a.AuthenticateCredentials(r.Header.Get(APIKeyHeader), r.Header.Get("Authorization"))
+}
+
+// AuthenticateCredentials resolves a Principal from the values of the X-API-Key and Authorization headers,
+// it's used by transports that carry them outside of http headers
+func (a *Authenticator) AuthenticateCredentials(apiKey, authorization string) (Principal, error) {
+	if apiKey != "" && authorization != "" {
+		return Principal{}, errMultipleScheme
+	}
+
+	if authorization != "" {
+		scheme, credentials, _ := strings.Cut(authorization, " ")
+		credentials = strings.TrimSpace(credentials)
+		switch {
+		case strings.EqualFold(scheme, "Bearer"):
+			if credentials == "" {
+				return Principal{}, errEmptyBearer
+			}
+			return a.authenticateJWT(credentials)
+		case strings.EqualFold(scheme, "ApiKey"):
+			apiKey = credentials
+			if apiKey == "" {
+				return Principal{}, errEmptyAPIKey
+			}
+		default:
+			return Principal{}, errUnknownScheme
+		}
+	}
+
+	if apiKey == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	return a.authenticateAPIKey(apiKey)
+}
+
+func (a *Authenticator) authenticateAPIKey(apiKey string) (Principal, error) {
+	key, ok := matchAPIKey(a.keys, apiKey)
+	if !ok {
+		return Principal{}, ErrInvalidAPIKey
+	}
+	return Principal{
+		Subject: key.Subject,
+		UserID:  key.UserID,
+		Roles:   key.Roles,
+		Method:  MethodAPIKey,
+	}, nil
+}
+
+func (a *Authenticator) authenticateJWT(token string) (Principal, error) {
+	if a.jwt == nil {
+		return Principal{}, ErrJWTNotEnabled
+	}
+	claims, err := a.jwt.Verify(token, a.now())
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{
+		Subject: claims.Subject,
+		UserID:  claims.UserID,
+		Roles:   claims.Roles,
+		Method:  MethodJWT,
+	}, nil
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+// DefaultLeeway is an allowed clock skew for exp and nbf checks
+const DefaultLeeway = 30 * time.Second
+
+var (
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrTokenExpired     = errors.New("token is expired")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("invalid token issuer")
+	ErrInvalidAudience  = errors.New("invalid token audience")
+)
+
+// Claims are the registered jwt claims plus the ones used to build a Principal
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	UserID    int      `json:"uid,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+}
+
+// Audience is an "aud" claim, which can be either a string or an array of strings
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// JWTVerifier checks signature and registered claims of bearer tokens.
+//
+// Only algorithms with a configured key are accepted,
+// so a token can't pick an algorithm the server doesn't expect.
+type JWTVerifier struct {
+	hmacSecret []byte
+	publicKey  *rsa.PublicKey
+	issuer     string
+	audience   string
+	leeway     time.Duration
+}
+
+type JWTConfig struct {
+	// HMACSecret enables HS256 tokens
+	HMACSecret []byte
+	// PublicKey enables RS256 tokens
+	PublicKey *rsa.PublicKey
+	// Issuer and Audience are checked only if they are not empty
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	if len(cfg.HMACSecret) == 0 && cfg.PublicKey == nil {
+		return nil, errors.New("jwt verifier needs either hmac secret or rsa public key")
+	}
+	if len(cfg.HMACSecret) > 0 && len(cfg.HMACSecret) < sha256.Size {
+		return nil, fmt.Errorf("hmac secret must be at least %d bytes long", sha256.Size)
+	}
+	leeway := cfg.Leeway
+	if leeway == 0 {
+		leeway = DefaultLeeway
+	}
+
+	return &JWTVerifier{
+		hmacSecret: cfg.HMACSecret,
+		publicKey:  cfg.PublicKey,
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		leeway:     leeway,
+	}, nil
+}
+
+// ParseRSAPublicKey parses PEM encoded PKIX or PKCS1 rsa public key
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an rsa key")
+	}
+	return rsaKey, nil
+}
+
+// Verify checks the token and returns its claims
+func (v *JWTVerifier) Verify(token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformedToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch {
+	case header.Alg == AlgHS256 && len(v.hmacSecret) > 0:
+		mac := hmac.New(sha256.New, v.hmacSecret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return Claims{}, ErrInvalidSignature
+		}
+	case header.Alg == AlgRS256 && v.publicKey != nil:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(v.publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return Claims{}, ErrInvalidSignature
+		}
+	default:
+		return Claims{}, ErrUnsupportedAlg
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+	if err := v.validateClaims(claims, now); err != nil {
+		return Claims{}, err
+	}
+	return claims, nil
+}
+
+func (v *JWTVerifier) validateClaims(claims Claims, now time.Time) error {
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(v.leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrTokenNotYetValid
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return ErrInvalidIssuer
+	}
+	if v.audience != "" && !slices.Contains(claims.Audience, v.audience) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
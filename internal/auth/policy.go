@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model"
+	"slices"
+)
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleMember Role = "member"
+	RoleAdmin  Role = "admin"
+)
+
+type Permission string
+
+const (
+	TaskRead   Permission = "task:read"
+	TaskWrite  Permission = "task:write"
+	TaskDelete Permission = "task:delete"
+	UserRead   Permission = "user:read"
+	UserWrite  Permission = "user:write"
+	// ProjectRead and ProjectWrite guard projects themselves, tasks inside a project use task permissions
+	ProjectRead  Permission = "project:read"
+	ProjectWrite Permission = "project:write"
+	// TagRead and TagWrite guard tags of a project, attaching a tag needs task:write on the task
+	TagRead  Permission = "tag:read"
+	TagWrite Permission = "tag:write"
+	// CommentWrite allows posting comments on readable tasks, authors edit and delete their own comments.
+	// CommentModerate allows deleting comments of others.
+	CommentWrite    Permission = "comment:write"
+	CommentModerate Permission = "comment:moderate"
+	// AuditRead allows reading the audit log of every project and history of deleted tasks
+	AuditRead Permission = "audit:read"
+	// WebhookManage allows managing webhooks of a project, reading them included, since they hold secrets
+	WebhookManage Permission = "webhook:manage"
+)
+
+// Scope limits a permission to a subset of tasks
+type Scope int
+
+const (
+	// ScopeAny grants the permission on every task
+	ScopeAny Scope = iota
+	// ScopeOwn grants the permission only on tasks the principal reported or is assigned to
+	ScopeOwn
+)
+
+// Rule grants a permission to a role
+type Rule struct {
+	Role       Role
+	Permission Permission
+	Scope      Scope
+}
+
+// DefaultRules: viewers only read, members read everything and modify their own tasks,
+// admins can do anything
+var DefaultRules = []Rule{
+	{RoleViewer, TaskRead, ScopeAny},
+	{RoleViewer, UserRead, ScopeAny},
+	{RoleViewer, ProjectRead, ScopeAny},
+	{RoleViewer, TagRead, ScopeAny},
+
+	{RoleMember, TaskRead, ScopeAny},
+	{RoleMember, TaskWrite, ScopeOwn},
+	{RoleMember, TaskDelete, ScopeOwn},
+	{RoleMember, UserRead, ScopeAny},
+	{RoleMember, ProjectRead, ScopeAny},
+	{RoleMember, TagRead, ScopeAny},
+	{RoleMember, TagWrite, ScopeAny},
+	{RoleMember, CommentWrite, ScopeAny},
+
+	{RoleAdmin, TaskRead, ScopeAny},
+	{RoleAdmin, TaskWrite, ScopeAny},
+	{RoleAdmin, TaskDelete, ScopeAny},
+	{RoleAdmin, UserRead, ScopeAny},
+	{RoleAdmin, UserWrite, ScopeAny},
+	{RoleAdmin, ProjectRead, ScopeAny},
+	{RoleAdmin, ProjectWrite, ScopeAny},
+	{RoleAdmin, TagRead, ScopeAny},
+	{RoleAdmin, TagWrite, ScopeAny},
+	{RoleAdmin, CommentWrite, ScopeAny},
+	{RoleAdmin, CommentModerate, ScopeAny},
+	{RoleAdmin, AuditRead, ScopeAny},
+	{RoleAdmin, WebhookManage, ScopeAny},
+}
+
+// Policy decides whether a principal may perform an action
+type Policy struct {
+	rules []Rule
+	// hideExistence makes denied access to a task the principal can't read
+	// look like the task doesn't exist
+	hideExistence bool
+}
+
+func NewPolicy(rules []Rule, hideExistence bool) *Policy {
+	return &Policy{slices.Clone(rules), hideExistence}
+}
+
+// Authorize checks the principal from the context.
+//
+// task may be nil for actions that don't target a specific task, such as listing.
+// The returned error is model.ErrUnauthenticated, model.ErrForbidden or,
+// when existence is hidden, model.ErrNotFound.
+func (p *Policy) Authorize(ctx context.Context, permission Permission, task *model.Task) error {
+	principal, ok := FromContext(ctx)
+	if !ok {
+		return model.ErrUnauthenticated
+	}
+	if p.Allowed(principal, permission, task) {
+		return nil
+	}
+	if task != nil && p.hideExistence && !p.Allowed(principal, TaskRead, task) {
+		return model.ErrNotFound
+	}
+	return model.ErrForbidden
+}
+
+// Allowed reports whether any role of the principal grants the permission on the task
+func (p *Policy) Allowed(principal Principal, permission Permission, task *model.Task) bool {
+	for _, rule := range p.rules {
+		if rule.Permission != permission || !slices.Contains(principal.Roles, string(rule.Role)) {
+			continue
+		}
+		if rule.Scope == ScopeAny || task == nil || owns(principal, task) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedOnEveryTask reports whether a role of the principal grants the permission on any task, not only on own ones
+func (p *Policy) AllowedOnEveryTask(principal Principal, permission Permission) bool {
+	for _, rule := range p.rules {
+		if rule.Permission == permission && rule.Scope == ScopeAny && slices.Contains(principal.Roles, string(rule.Role)) {
+			return true
+		}
+	}
+	return false
+}
+
+func owns(principal Principal, task *model.Task) bool {
+	if principal.UserID == model.NoUser {
+		return false
+	}
+	return task.ReporterID == principal.UserID || task.AssigneeID == principal.UserID
+}
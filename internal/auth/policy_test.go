@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"testing"
+)
+
+func TestPolicyAuthorize(t *testing.T) {
+	ownTask := &model.Task{Id: 1, ReporterID: 7}
+	assignedTask := &model.Task{Id: 2, ReporterID: 1, AssigneeID: 7}
+	foreignTask := &model.Task{Id: 3, ReporterID: 1, AssigneeID: 2}
+
+	viewer := Principal{Subject: "viewer", UserID: 7, Roles: []string{"viewer"}}
+	member := Principal{Subject: "member", UserID: 7, Roles: []string{"member"}}
+	anonymousMember := Principal{Subject: "service", Roles: []string{"member"}}
+	admin := Principal{Subject: "admin", Roles: []string{"admin"}}
+	nobody := Principal{Subject: "nobody", UserID: 7, Roles: []string{"unknown"}}
+
+	ownReadRules := []Rule{
+		{RoleMember, TaskRead, ScopeOwn},
+		{RoleMember, TaskWrite, ScopeOwn},
+	}
+
+	tests := []struct {
+		name       string
+		rules      []Rule
+		hide       bool
+		principal  *Principal
+		permission Permission
+		task       *model.Task
+		wantErr    error
+	}{
+		{"no principal", DefaultRules, false, nil, TaskRead, nil, model.ErrUnauthenticated},
+		{"viewer lists tasks", DefaultRules, false, &viewer, TaskRead, nil, nil},
+		{"viewer reads foreign task", DefaultRules, false, &viewer, TaskRead, foreignTask, nil},
+		{"viewer can't write own task", DefaultRules, false, &viewer, TaskWrite, ownTask, model.ErrForbidden},
+		{"viewer can't delete", DefaultRules, false, &viewer, TaskDelete, ownTask, model.ErrForbidden},
+		{"member writes reported task", DefaultRules, false, &member, TaskWrite, ownTask, nil},
+		{"member writes assigned task", DefaultRules, false, &member, TaskWrite, assignedTask, nil},
+		{"member deletes reported task", DefaultRules, false, &member, TaskDelete, ownTask, nil},
+		{"member can't write foreign task", DefaultRules, false, &member, TaskWrite, foreignTask, model.ErrForbidden},
+		{"member can't delete foreign task", DefaultRules, false, &member, TaskDelete, foreignTask, model.ErrForbidden},
+		{"member without user owns nothing", DefaultRules, false, &anonymousMember, TaskWrite, &model.Task{}, model.ErrForbidden},
+		{"member can't manage users", DefaultRules, false, &member, UserWrite, nil, model.ErrForbidden},
+		{"admin writes foreign task", DefaultRules, false, &admin, TaskWrite, foreignTask, nil},
+		{"admin deletes foreign task", DefaultRules, false, &admin, TaskDelete, foreignTask, nil},
+		{"admin manages users", DefaultRules, false, &admin, UserWrite, nil, nil},
+		{"unknown role", DefaultRules, false, &nobody, TaskRead, nil, model.ErrForbidden},
+		{"readable task isn't hidden", DefaultRules, true, &member, TaskWrite, foreignTask, model.ErrForbidden},
+		{"unreadable task is hidden", ownReadRules, true, &member, TaskWrite, foreignTask, model.ErrNotFound},
+		{"unreadable task without hiding", ownReadRules, false, &member, TaskRead, foreignTask, model.ErrForbidden},
+		{"own task with own read scope", ownReadRules, true, &member, TaskRead, assignedTask, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, *tt.principal)
+			}
+
+			err := NewPolicy(tt.rules, tt.hide).Authorize(ctx, tt.permission, tt.task)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "context"
+
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is an authenticated caller of the api
+type Principal struct {
+	Subject string
+	// UserID links the principal to model.User, zero if the principal isn't a user
+	UserID int
+	Roles  []string
+	Method string
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal stored in the context by the auth middleware
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,159 @@
+package broker
+
+import (
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"sync"
+)
+
+const brokerName = "Broker"
+
+const (
+	// DefaultReplaySize is an amount of the latest changes kept for resuming subscribers
+	DefaultReplaySize = 1024
+	// DefaultClientBuffer is an amount of changes a subscriber may fall behind before it's dropped
+	DefaultClientBuffer = 64
+)
+
+type Logger interface {
+	Log(format string, info ...any)
+}
+
+// Broker delivers committed task changes from publishers to subscribers in process.
+//
+// Publishing never waits for subscribers: every subscriber has a buffer of changes,
+// and a subscriber whose buffer is full is dropped, its channel is closed. The latest changes
+// are kept in a bounded replay buffer, so a subscriber reconnecting with the id of the last
+// change it saw gets everything it missed if it wasn't away for too long.
+type Broker struct {
+	// replay holds the latest changes ordered by id, the last one has id lastID
+	replay       []model.TaskChange
+	replaySize   int
+	clientBuffer int
+	lastID       int
+	subscribers  map[*Subscription]struct{}
+	logger       Logger
+	m            sync.Mutex
+}
+
+// BrokerOption configures optional behaviour of Broker
+type BrokerOption func(*Broker)
+
+// WithReplaySize sets an amount of the latest changes kept for resuming subscribers
+func WithReplaySize(size int) BrokerOption {
+	return func(b *Broker) {
+		b.replaySize = size
+	}
+}
+
+// WithClientBuffer sets an amount of changes a subscriber may fall behind before it's dropped
+func WithClientBuffer(size int) BrokerOption {
+	return func(b *Broker) {
+		b.clientBuffer = size
+	}
+}
+
+func NewBroker(logger Logger, opts ...BrokerOption) (*Broker, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", brokerName)
+	}
+	b := &Broker{
+		replaySize:   DefaultReplaySize,
+		clientBuffer: DefaultClientBuffer,
+		subscribers:  make(map[*Subscription]struct{}),
+		logger:       logger,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.replaySize < 0 || b.clientBuffer < 1 {
+		return nil, fmt.Errorf("%v: invalid replay size %v or client buffer %v", brokerName, b.replaySize, b.clientBuffer)
+	}
+	b.replay = make([]model.TaskChange, 0, b.replaySize)
+
+	logger.Log("Created %s successfully", brokerName)
+	return b, nil
+}
+
+// Publish assigns the next id to the change and delivers it to every subscriber
+func (b *Broker) Publish(change model.TaskChange) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.lastID++
+	change.ID = b.lastID
+	if b.replaySize > 0 {
+		if len(b.replay) == b.replaySize {
+			// the oldest change is dropped, copying keeps the backing array from growing
+			copy(b.replay, b.replay[1:])
+			b.replay = b.replay[:len(b.replay)-1]
+		}
+		b.replay = append(b.replay, change)
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.changes <- change:
+		default:
+			b.remove(sub)
+			b.logger.Log("%v: dropped a subscriber that fell %v changes behind", brokerName, b.clientBuffer)
+		}
+	}
+}
+
+// Subscribe returns a subscription to changes published from now on and the changes published
+// after the one with lastID. lastID 0 means the subscriber saw nothing and needs no replay.
+//
+// complete is false when some changes after lastID aren't in the replay buffer anymore,
+// or lastID is unknown, so the subscriber has to reload the state it keeps.
+func (b *Broker) Subscribe(lastID int) (sub *Subscription, replay []model.TaskChange, complete bool) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	sub = &Subscription{changes: make(chan model.TaskChange, b.clientBuffer), broker: b}
+	b.subscribers[sub] = struct{}{}
+
+	if lastID == 0 {
+		return sub, nil, true
+	}
+	if lastID < 0 || lastID > b.lastID {
+		return sub, nil, false
+	}
+	oldest := b.lastID - len(b.replay) + 1
+	start := max(lastID+1-oldest, 0)
+	replay = make([]model.TaskChange, len(b.replay)-start)
+	copy(replay, b.replay[start:])
+	return sub, replay, lastID+1 >= oldest
+}
+
+// Subscribers returns an amount of active subscriptions
+func (b *Broker) Subscribers() int {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return len(b.subscribers)
+}
+
+// remove closes the subscription, must be called under lock
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.changes)
+	}
+}
+
+// Subscription is a stream of changes published after it was created
+type Subscription struct {
+	changes chan model.TaskChange
+	broker  *Broker
+}
+
+// Changes returns the channel of changes, it's closed when the subscription is closed
+// or dropped for falling behind
+func (s *Subscription) Changes() <-chan model.TaskChange {
+	return s.changes
+}
+
+// Close stops the subscription, closing it again does nothing
+func (s *Subscription) Close() {
+	s.broker.m.Lock()
+	defer s.broker.m.Unlock()
+	s.broker.remove(s)
+}
@@ -0,0 +1,99 @@
+package broker
+
+import (
+	"ivanjabrony/test_lo/internal/model"
+	"testing"
+)
+
+type MockLogger struct{}
+
+func (m *MockLogger) Log(format string, info ...any) {}
+
+func changeIds(changes []model.TaskChange) []int {
+	ids := make([]int, 0, len(changes))
+	for _, change := range changes {
+		ids = append(ids, change.ID)
+	}
+	return ids
+}
+
+func TestBroker(t *testing.T) {
+	t.Run("delivery", func(t *testing.T) {
+		b, _ := NewBroker(&MockLogger{})
+		first, _, _ := b.Subscribe(0)
+		second, _, _ := b.Subscribe(0)
+		b.Publish(model.TaskChange{Type: model.TaskChangeCreated, Task: model.Task{Id: 3}})
+
+		for _, sub := range []*Subscription{first, second} {
+			change := <-sub.Changes()
+			if change.ID != 1 || change.Task.Id != 3 {
+				t.Errorf("Unexpected change %+v", change)
+			}
+		}
+		first.Close()
+		first.Close()
+		if _, ok := <-first.Changes(); ok {
+			t.Errorf("Expected a closed channel")
+		}
+		if b.Subscribers() != 1 {
+			t.Errorf("Expected 1 subscriber, got %v", b.Subscribers())
+		}
+	})
+
+	t.Run("slow subscriber", func(t *testing.T) {
+		b, _ := NewBroker(&MockLogger{}, WithClientBuffer(2))
+		slow, _, _ := b.Subscribe(0)
+		fast, _, _ := b.Subscribe(0)
+		for i := 0; i < 3; i++ {
+			b.Publish(model.TaskChange{})
+			<-fast.Changes()
+		}
+
+		got := make([]int, 0)
+		for change := range slow.Changes() {
+			got = append(got, change.ID)
+		}
+		if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+			t.Errorf("Expected the buffered changes before closing, got %v", got)
+		}
+		if b.Subscribers() != 1 {
+			t.Errorf("Expected the slow subscriber to be dropped, got %v subscribers", b.Subscribers())
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		b, _ := NewBroker(&MockLogger{}, WithReplaySize(3))
+		for i := 0; i < 5; i++ {
+			b.Publish(model.TaskChange{})
+		}
+
+		tests := []struct {
+			name         string
+			lastID       int
+			wantIds      []int
+			wantComplete bool
+		}{
+			{name: "new subscriber", lastID: 0, wantIds: []int{}, wantComplete: true},
+			{name: "up to date", lastID: 5, wantIds: []int{}, wantComplete: true},
+			{name: "missed some", lastID: 3, wantIds: []int{4, 5}, wantComplete: true},
+			{name: "oldest kept", lastID: 2, wantIds: []int{3, 4, 5}, wantComplete: true},
+			{name: "missed too many", lastID: 1, wantIds: []int{3, 4, 5}, wantComplete: false},
+			{name: "unknown id", lastID: 9, wantIds: []int{}, wantComplete: false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				sub, replay, complete := b.Subscribe(tt.lastID)
+				defer sub.Close()
+				ids := changeIds(replay)
+				if complete != tt.wantComplete || len(ids) != len(tt.wantIds) {
+					t.Fatalf("Expected %v complete %v, got %v complete %v", tt.wantIds, tt.wantComplete, ids, complete)
+				}
+				for i := range ids {
+					if ids[i] != tt.wantIds[i] {
+						t.Errorf("Expected %v, got %v", tt.wantIds, ids)
+					}
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,267 @@
+package btree
+
+import "slices"
+
+// DefaultDegree is the degree of trees created with New, nodes hold from 31 to 63 items
+const DefaultDegree = 32
+
+// BTree is an ordered set of items kept in a B-tree, items equal by less are the same item.
+// It isn't safe for concurrent use, storages guard it with their locks.
+type BTree[T any] struct {
+	less   func(a, b T) bool
+	degree int
+	root   *node[T]
+	length int
+}
+
+type node[T any] struct {
+	items []T
+	// children is empty in leaves and has len(items)+1 subtrees otherwise
+	children []*node[T]
+}
+
+// New creates an empty tree ordered by less
+func New[T any](less func(a, b T) bool) *BTree[T] {
+	return NewWithDegree(DefaultDegree, less)
+}
+
+// NewWithDegree creates an empty tree whose nodes hold from degree-1 to 2*degree-1 items,
+// it panics if degree is less than 2
+func NewWithDegree[T any](degree int, less func(a, b T) bool) *BTree[T] {
+	if degree < 2 {
+		panic("btree: degree must be at least 2")
+	}
+	return &BTree[T]{less: less, degree: degree}
+}
+
+// Len returns an amount of items in the tree
+func (t *BTree[T]) Len() int {
+	return t.length
+}
+
+// Insert adds the item or replaces an equal one, it's false if there was an equal item
+func (t *BTree[T]) Insert(item T) bool {
+	if t.root == nil {
+		t.root = &node[T]{items: []T{item}}
+		t.length++
+		return true
+	}
+	if len(t.root.items) >= t.maxItems() {
+		mid, right := t.root.split(t.maxItems() / 2)
+		t.root = &node[T]{items: []T{mid}, children: []*node[T]{t.root, right}}
+	}
+	added := t.insert(t.root, item)
+	if added {
+		t.length++
+	}
+	return added
+}
+
+// Delete removes the item equal to the given one, it's false if there was none
+func (t *BTree[T]) Delete(item T) bool {
+	if t.root == nil {
+		return false
+	}
+	removed := t.remove(t.root, item)
+	if len(t.root.items) == 0 {
+		if len(t.root.children) > 0 {
+			t.root = t.root.children[0]
+		} else {
+			t.root = nil
+		}
+	}
+	if removed {
+		t.length--
+	}
+	return removed
+}
+
+// Has reports whether the tree has an item equal to the given one
+func (t *BTree[T]) Has(item T) bool {
+	for n := t.root; n != nil; {
+		i, found := t.find(n.items, item)
+		if found {
+			return true
+		}
+		if len(n.children) == 0 {
+			return false
+		}
+		n = n.children[i]
+	}
+	return false
+}
+
+// Ascend calls fn for every item in order until it returns false
+func (t *BTree[T]) Ascend(fn func(T) bool) {
+	if t.root != nil {
+		t.ascend(t.root, nil, fn)
+	}
+}
+
+// AscendFrom calls fn in order for the items that aren't less than from until it returns false
+func (t *BTree[T]) AscendFrom(from T, fn func(T) bool) {
+	if t.root != nil {
+		t.ascend(t.root, &from, fn)
+	}
+}
+
+func (t *BTree[T]) maxItems() int {
+	return 2*t.degree - 1
+}
+
+func (t *BTree[T]) minItems() int {
+	return t.degree - 1
+}
+
+// find returns the position of the first item of the node that isn't less than the given one
+// and whether it's equal to it
+func (t *BTree[T]) find(items []T, item T) (int, bool) {
+	i, j := 0, len(items)
+	for i < j {
+		h := int(uint(i+j) >> 1)
+		if t.less(items[h], item) {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	return i, i < len(items) && !t.less(item, items[i])
+}
+
+// insert adds the item to the subtree of n, n isn't full, so a full child can be split into it
+func (t *BTree[T]) insert(n *node[T], item T) bool {
+	i, found := t.find(n.items, item)
+	if found {
+		n.items[i] = item
+		return false
+	}
+	if len(n.children) == 0 {
+		n.items = slices.Insert(n.items, i, item)
+		return true
+	}
+	if len(n.children[i].items) >= t.maxItems() {
+		mid, right := n.children[i].split(t.maxItems() / 2)
+		n.items = slices.Insert(n.items, i, mid)
+		n.children = slices.Insert(n.children, i+1, right)
+		switch {
+		case t.less(item, mid):
+		case t.less(mid, item):
+			i++
+		default:
+			n.items[i] = item
+			return false
+		}
+	}
+	return t.insert(n.children[i], item)
+}
+
+// split leaves the items before i in the node and returns the item i with a new node of the items after it
+func (n *node[T]) split(i int) (T, *node[T]) {
+	mid := n.items[i]
+	right := &node[T]{items: slices.Clone(n.items[i+1:])}
+	clear(n.items[i:])
+	n.items = n.items[:i]
+	if len(n.children) > 0 {
+		right.children = slices.Clone(n.children[i+1:])
+		clear(n.children[i+1:])
+		n.children = n.children[:i+1]
+	}
+	return mid, right
+}
+
+// remove deletes the item from the subtree of n, n has more than the minimum of items unless it's the root,
+// so the child the item is looked for in is grown to that before going down
+func (t *BTree[T]) remove(n *node[T], item T) bool {
+	i, found := t.find(n.items, item)
+	if len(n.children) == 0 {
+		if !found {
+			return false
+		}
+		n.items = slices.Delete(n.items, i, i+1)
+		return true
+	}
+	if len(n.children[i].items) <= t.minItems() {
+		t.grow(n, i)
+		return t.remove(n, item)
+	}
+	if found {
+		// the item is replaced by its predecessor, the largest item of the subtree before it
+		n.items[i] = t.removeMax(n.children[i])
+		return true
+	}
+	return t.remove(n.children[i], item)
+}
+
+// removeMax deletes and returns the largest item of the subtree of n, n has more than the minimum of items
+func (t *BTree[T]) removeMax(n *node[T]) T {
+	if len(n.children) == 0 {
+		last := len(n.items) - 1
+		item := n.items[last]
+		n.items = slices.Delete(n.items, last, last+1)
+		return item
+	}
+	last := len(n.children) - 1
+	if len(n.children[last].items) <= t.minItems() {
+		t.grow(n, last)
+		return t.removeMax(n)
+	}
+	return t.removeMax(n.children[last])
+}
+
+// grow gives the child i of n more than the minimum of items by moving an item from a sibling
+// through n, or by merging the child with a sibling when both have the minimum
+func (t *BTree[T]) grow(n *node[T], i int) {
+	child := n.children[i]
+	switch {
+	case i > 0 && len(n.children[i-1].items) > t.minItems():
+		left := n.children[i-1]
+		last := len(left.items) - 1
+		child.items = slices.Insert(child.items, 0, n.items[i-1])
+		n.items[i-1] = left.items[last]
+		left.items = slices.Delete(left.items, last, last+1)
+		if len(left.children) > 0 {
+			last = len(left.children) - 1
+			child.children = slices.Insert(child.children, 0, left.children[last])
+			left.children = slices.Delete(left.children, last, last+1)
+		}
+	case i < len(n.items) && len(n.children[i+1].items) > t.minItems():
+		right := n.children[i+1]
+		child.items = append(child.items, n.items[i])
+		n.items[i] = right.items[0]
+		right.items = slices.Delete(right.items, 0, 1)
+		if len(right.children) > 0 {
+			child.children = append(child.children, right.children[0])
+			right.children = slices.Delete(right.children, 0, 1)
+		}
+	default:
+		if i == len(n.items) {
+			i--
+			child = n.children[i]
+		}
+		right := n.children[i+1]
+		child.items = append(append(child.items, n.items[i]), right.items...)
+		child.children = append(child.children, right.children...)
+		n.items = slices.Delete(n.items, i, i+1)
+		n.children = slices.Delete(n.children, i+1, i+2)
+	}
+}
+
+// ascend calls fn for the items of the subtree of n that aren't less than from, every item without it,
+// it's false once fn returned false
+func (t *BTree[T]) ascend(n *node[T], from *T, fn func(T) bool) bool {
+	i := 0
+	if from != nil {
+		i, _ = t.find(n.items, *from)
+	}
+	for ; i <= len(n.items); i++ {
+		if len(n.children) > 0 && !t.ascend(n.children[i], from, fn) {
+			return false
+		}
+		// items after the first subtree are greater than from
+		from = nil
+		if i < len(n.items) && !fn(n.items[i]) {
+			return false
+		}
+	}
+	return true
+}
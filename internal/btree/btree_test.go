@@ -0,0 +1,140 @@
+package btree
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func less(a, b int) bool { return a < b }
+
+// items returns items of the tree in order and checks sizes of the nodes on the way
+func items(t *testing.T, tree *BTree[int]) []int {
+	t.Helper()
+	var check func(n *node[int], root bool) int
+	check = func(n *node[int], root bool) int {
+		if !root && (len(n.items) < tree.minItems() || len(n.items) > tree.maxItems()) {
+			t.Fatalf("node has %v items, expected from %v to %v", len(n.items), tree.minItems(), tree.maxItems())
+		}
+		if len(n.children) == 0 {
+			return 1
+		}
+		if len(n.children) != len(n.items)+1 {
+			t.Fatalf("node has %v items and %v children", len(n.items), len(n.children))
+		}
+		depth := check(n.children[0], false)
+		for _, child := range n.children[1:] {
+			if check(child, false) != depth {
+				t.Fatal("leaves are at different depths")
+			}
+		}
+		return depth + 1
+	}
+	if tree.root != nil {
+		check(tree.root, true)
+	}
+
+	ans := make([]int, 0, tree.Len())
+	tree.Ascend(func(item int) bool {
+		ans = append(ans, item)
+		return true
+	})
+	return ans
+}
+
+func TestEmpty(t *testing.T) {
+	tree := New(less)
+	if tree.Len() != 0 || tree.Has(1) || tree.Delete(1) {
+		t.Error("Expected an empty tree")
+	}
+	tree.Ascend(func(int) bool {
+		t.Error("Expected no items")
+		return true
+	})
+}
+
+func TestInsertReplaces(t *testing.T) {
+	type pair struct{ key, value int }
+	tree := NewWithDegree(2, func(a, b pair) bool { return a.key < b.key })
+	for i := range 20 {
+		tree.Insert(pair{i, 0})
+	}
+	if tree.Insert(pair{7, 1}) {
+		t.Error("Expected the equal item to be replaced")
+	}
+	tree.AscendFrom(pair{key: 7}, func(p pair) bool {
+		if p.value != 1 {
+			t.Errorf("Expected replaced value, got %v", p)
+		}
+		return false
+	})
+	if tree.Len() != 20 {
+		t.Errorf("Expected 20 items, got %v", tree.Len())
+	}
+}
+
+// TestMatchesSortedSlice applies random inserts and deletes to trees of different degrees
+// and to a sorted slice and expects the same items in them
+func TestMatchesSortedSlice(t *testing.T) {
+	for _, degree := range []int{2, 3, 5, DefaultDegree} {
+		rnd := rand.New(rand.NewPCG(uint64(degree), 50))
+		tree := NewWithDegree(degree, less)
+		expected := make([]int, 0)
+		for step := 0; step < 20000; step++ {
+			item := rnd.IntN(2000)
+			i, found := slices.BinarySearch(expected, item)
+			insert := rnd.IntN(5) < 3
+			if insert {
+				if added := tree.Insert(item); added == found {
+					t.Fatalf("degree %v step %v: insert of %v returned %v", degree, step, item, added)
+				}
+				if !found {
+					expected = slices.Insert(expected, i, item)
+				}
+			} else {
+				if removed := tree.Delete(item); removed != found {
+					t.Fatalf("degree %v step %v: delete of %v returned %v", degree, step, item, removed)
+				}
+				if found {
+					expected = slices.Delete(expected, i, i+1)
+				}
+			}
+			if tree.Has(item) != insert {
+				t.Fatalf("degree %v step %v: expected Has(%v) to be %v", degree, step, item, insert)
+			}
+			if step%500 == 0 {
+				if got := items(t, tree); !slices.Equal(got, expected) || tree.Len() != len(expected) {
+					t.Fatalf("degree %v step %v: expected %v items, got %v", degree, step, len(expected), tree.Len())
+				}
+			}
+		}
+
+		for range 200 {
+			from := rnd.IntN(2100) - 50
+			limit := rnd.IntN(20)
+			start, _ := slices.BinarySearch(expected, from)
+			want := expected[start:]
+			want = want[:min(limit, len(want))]
+			got := make([]int, 0)
+			tree.AscendFrom(from, func(item int) bool {
+				if len(got) == limit {
+					return false
+				}
+				got = append(got, item)
+				return true
+			})
+			if !slices.Equal(got, want) {
+				t.Fatalf("degree %v: ascending from %v expected %v, got %v", degree, from, want, got)
+			}
+		}
+
+		for _, item := range slices.Clone(expected) {
+			if !tree.Has(item) || !tree.Delete(item) {
+				t.Fatalf("degree %v: expected to delete %v", degree, item)
+			}
+		}
+		if tree.Len() != 0 || tree.root != nil {
+			t.Errorf("degree %v: expected an empty tree, got %v items", degree, tree.Len())
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time, storages and generators of ids read it instead of time.Now,
+// so tests can control timestamps
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the clock of the machine
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Manual is a clock that stands still until it's set or advanced, it's safe for concurrent use
+type Manual struct {
+	now time.Time
+	m   sync.Mutex
+}
+
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+func (c *Manual) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+// Set moves the clock to the moment, it may go backwards
+func (c *Manual) Set(now time.Time) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d and returns the new time
+func (c *Manual) Advance(d time.Duration) time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
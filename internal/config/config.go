@@ -1,18 +1,164 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
 	HttpPort string
+	// GRPCPort is the port of the gRPC api, empty disables it
+	GRPCPort string
+
+	// AuthEnabled turns on authentication of every endpoint except /health, /metrics, /openapi.json and /docs
+	AuthEnabled bool
+	// APIKeys is a comma separated list of "subject:user_id:role1|role2:sha256hex" entries
+	APIKeys string
+	// JWTSecret enables HS256 bearer tokens
+	JWTSecret string
+	// JWTPublicKeyFile is a path to a PEM encoded rsa public key, it enables RS256 bearer tokens
+	JWTPublicKeyFile string
+	JWTIssuer        string
+	JWTAudience      string
+	// HideForbiddenTasks makes access to tasks the caller can't read respond with 404 instead of 403
+	HideForbiddenTasks bool
+
+	// DefaultTaskQuota limits tasks of the default project and of projects created without a quota, 0 disables the limit
+	DefaultTaskQuota int
+	// StrictTaskCompletion forbids moving a task to done while it has open blockers or unfinished subtasks
+	StrictTaskCompletion bool
+
+	// TaskStorage selects the task storage: "memory" keeps only the current state,
+	// "sharded" keeps it in shards with their own locks for many concurrent writers,
+	// "events" keeps the log of domain events and supports point-in-time reads
+	TaskStorage string
+	// TaskShards is an amount of shards of every project of the "sharded" storage
+	TaskShards int
+	// EventLogFile keeps events of the "events" storage in a file, empty keeps them in memory
+	EventLogFile string
+	// SnapshotInterval is an amount of events between snapshots of the "events" storage, 0 disables them
+	SnapshotInterval int
+	// IDStrategy selects the ids clients see: "int" shows the ids of tasks in their projects,
+	// "uuidv7" and "snowflake" give tasks public ids and the api addresses tasks by them
+	IDStrategy string
+	// IDNode tells apart servers generating snowflake ids, from 0 to 1023
+	IDNode int
+
+	// StreamReplayBuffer is an amount of the latest task changes kept for resuming event streams
+	StreamReplayBuffer int
+	// StreamClientBuffer is an amount of changes an event stream client may fall behind before it's disconnected
+	StreamClientBuffer int
+	// StreamHeartbeatSeconds is the time between heartbeats of idle event streams
+	StreamHeartbeatSeconds int
+
+	// WSMaxMessageSize limits the size of a message of a websocket client in bytes
+	WSMaxMessageSize int
+	// WSPingIntervalSeconds is the time between pings, clients not answering two of them are disconnected
+	WSPingIntervalSeconds int
+	// WSRateLimit is an amount of messages a websocket client may send per second, WSRateBurst at once
+	WSRateLimit int
+	WSRateBurst int
+	// WSAllowedOrigins is a comma separated list of origins browsers may open websockets from besides the server itself
+	WSAllowedOrigins string
+
+	// WebhookWorkers is an amount of webhook deliveries sent at once
+	WebhookWorkers int
+	// WebhookMaxAttempts is an amount of attempts before a delivery goes to the dead letters of its webhook
+	WebhookMaxAttempts int
+	// WebhookBackoffSeconds is the delay before the first retry, every next one waits twice as long up to WebhookMaxBackoffSeconds
+	WebhookBackoffSeconds    int
+	WebhookMaxBackoffSeconds int
+	// WebhookTimeoutSeconds limits a single delivery attempt
+	WebhookTimeoutSeconds int
+
+	// RecurrenceIntervalSeconds is the delay between checks creating the next occurrences of recurring tasks
+	RecurrenceIntervalSeconds int
+
+	// ReminderIntervalSeconds is the delay between checks sending reminders about tasks
+	ReminderIntervalSeconds int
+	// ReminderDueSoonHours is how long before the due date a task is reminded of
+	ReminderDueSoonHours int
+	// ReminderStaleHours is how long a task may stay in progress before it's reminded of
+	ReminderStaleHours int
+	// NotifyDefaultChannels is a comma separated list of channels of users who haven't chosen any: log, email or webhook
+	NotifyDefaultChannels string
+	// NotifyWebhookSecret signs reminders sent to webhooks of users, empty leaves them unsigned
+	NotifyWebhookSecret string
+	// SMTPAddr is the host:port of the mail server, empty disables the email channel
+	SMTPAddr string
+	// SMTPFrom is the sender of reminder emails
+	SMTPFrom string
+	// SMTPUsername and SMTPPassword enable PLAIN authentication, it needs TLS unless the server is local
+	SMTPUsername string
+	SMTPPassword string
+
+	// GraphQLMaxDepth limits the nesting of fields in graphql queries, GraphQLMaxComplexity the sum of their costs, 0 disables a limit
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
 }
 
-func MustLoad() Config {
+// Load reads the config from the environment, every malformed value is reported in the error
+func Load() (Config, error) {
+	var env env
 	cfg := Config{
 		HttpPort: getEnv("HTTP_PORT", "8080"),
+		GRPCPort: getEnv("GRPC_PORT", "9090"),
+
+		AuthEnabled:      env.getBool("AUTH_ENABLED", true),
+		APIKeys:          getEnv("API_KEYS", ""),
+		JWTSecret:        getEnv("JWT_HS256_SECRET", ""),
+		JWTPublicKeyFile: getEnv("JWT_RS256_PUBLIC_KEY_FILE", ""),
+		JWTIssuer:        getEnv("JWT_ISSUER", ""),
+		JWTAudience:      getEnv("JWT_AUDIENCE", ""),
+
+		HideForbiddenTasks: env.getBool("HIDE_FORBIDDEN_TASKS", false),
+
+		DefaultTaskQuota:     env.getInt("DEFAULT_TASK_QUOTA", 0),
+		StrictTaskCompletion: env.getBool("STRICT_TASK_COMPLETION", true),
+
+		TaskStorage:      getEnv("TASK_STORAGE", "memory"),
+		TaskShards:       env.getInt("TASK_SHARDS", 32),
+		EventLogFile:     getEnv("EVENT_LOG_FILE", ""),
+		SnapshotInterval: env.getInt("SNAPSHOT_INTERVAL", 1000),
+		IDStrategy:       getEnv("ID_STRATEGY", "int"),
+		IDNode:           env.getInt("ID_NODE", 0),
+
+		StreamReplayBuffer:     env.getInt("STREAM_REPLAY_BUFFER", 1024),
+		StreamClientBuffer:     env.getInt("STREAM_CLIENT_BUFFER", 64),
+		StreamHeartbeatSeconds: env.getInt("STREAM_HEARTBEAT_SECONDS", 15),
+
+		WSMaxMessageSize:      env.getInt("WS_MAX_MESSAGE_SIZE", 65536),
+		WSPingIntervalSeconds: env.getInt("WS_PING_INTERVAL_SECONDS", 30),
+		WSRateLimit:           env.getInt("WS_RATE_LIMIT", 20),
+		WSRateBurst:           env.getInt("WS_RATE_BURST", 40),
+		WSAllowedOrigins:      getEnv("WS_ALLOWED_ORIGINS", ""),
+
+		WebhookWorkers:           env.getInt("WEBHOOK_WORKERS", 4),
+		WebhookMaxAttempts:       env.getInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookBackoffSeconds:    env.getInt("WEBHOOK_BACKOFF_SECONDS", 1),
+		WebhookMaxBackoffSeconds: env.getInt("WEBHOOK_MAX_BACKOFF_SECONDS", 300),
+		WebhookTimeoutSeconds:    env.getInt("WEBHOOK_TIMEOUT_SECONDS", 10),
+
+		RecurrenceIntervalSeconds: env.getInt("RECURRENCE_INTERVAL_SECONDS", 30),
+
+		ReminderIntervalSeconds: env.getInt("REMINDER_INTERVAL_SECONDS", 60),
+		ReminderDueSoonHours:    env.getInt("REMINDER_DUE_SOON_HOURS", 24),
+		ReminderStaleHours:      env.getInt("REMINDER_STALE_HOURS", 72),
+		NotifyDefaultChannels:   getEnv("NOTIFY_DEFAULT_CHANNELS", "log"),
+		NotifyWebhookSecret:     getEnv("NOTIFY_WEBHOOK_SECRET", ""),
+		SMTPAddr:                getEnv("SMTP_ADDR", ""),
+		SMTPFrom:                getEnv("SMTP_FROM", ""),
+		SMTPUsername:            getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:            getEnv("SMTP_PASSWORD", ""),
+
+		GraphQLMaxDepth:      env.getInt("GRAPHQL_MAX_DEPTH", 10),
+		GraphQLMaxComplexity: env.getInt("GRAPHQL_MAX_COMPLEXITY", 1000),
 	}
-	return cfg
+	return cfg, errors.Join(env.errs...)
 }
 
 func getEnv(key, defaultValue string) string {
@@ -25,3 +171,124 @@ func getEnv(key, defaultValue string) string {
 
 	return ""
 }
+
+// env collects errors of malformed values, so all of them are reported at once
+type env struct {
+	errs []error
+}
+
+func (e *env) getBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid value of %s: %w", key, err))
+		return defaultValue
+	}
+	return parsed
+}
+
+func (e *env) getInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid value of %s: %w", key, err))
+		return defaultValue
+	}
+	return parsed
+}
+
+// Validate reports every value the application can't run with, the ones checked by other packages,
+// like API keys, are left to them
+func (c Config) Validate() error {
+	errs := make([]error, 0)
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	isPort := func(port string) bool {
+		n, err := strconv.Atoi(port)
+		return err == nil && n > 0 && n <= 65535
+	}
+
+	check(isPort(c.HttpPort), "HTTP_PORT must be a port number, got %q", c.HttpPort)
+	check(c.GRPCPort == "" || isPort(c.GRPCPort), "GRPC_PORT must be a port number or empty, got %q", c.GRPCPort)
+	check(c.GRPCPort == "" || c.GRPCPort != c.HttpPort, "GRPC_PORT and HTTP_PORT must differ, both are %q", c.HttpPort)
+
+	check(c.DefaultTaskQuota >= 0, "DEFAULT_TASK_QUOTA must not be negative, got %v", c.DefaultTaskQuota)
+	switch c.TaskStorage {
+	case "", "memory", "sharded", "events":
+	default:
+		check(false, "TASK_STORAGE must be memory, sharded or events, got %q", c.TaskStorage)
+	}
+	check(c.TaskShards > 0, "TASK_SHARDS must be positive, got %v", c.TaskShards)
+	check(c.SnapshotInterval >= 0, "SNAPSHOT_INTERVAL must not be negative, got %v", c.SnapshotInterval)
+	switch c.IDStrategy {
+	case "", "int", "uuidv7", "snowflake":
+	default:
+		check(false, "ID_STRATEGY must be int, uuidv7 or snowflake, got %q", c.IDStrategy)
+	}
+	check(c.IDNode >= 0 && c.IDNode <= 1023, "ID_NODE must be from 0 to 1023, got %v", c.IDNode)
+
+	check(c.StreamReplayBuffer >= 0, "STREAM_REPLAY_BUFFER must not be negative, got %v", c.StreamReplayBuffer)
+	check(c.StreamClientBuffer > 0, "STREAM_CLIENT_BUFFER must be positive, got %v", c.StreamClientBuffer)
+	check(c.StreamHeartbeatSeconds > 0, "STREAM_HEARTBEAT_SECONDS must be positive, got %v", c.StreamHeartbeatSeconds)
+
+	check(c.WSMaxMessageSize > 0, "WS_MAX_MESSAGE_SIZE must be positive, got %v", c.WSMaxMessageSize)
+	check(c.WSPingIntervalSeconds > 0, "WS_PING_INTERVAL_SECONDS must be positive, got %v", c.WSPingIntervalSeconds)
+	check(c.WSRateLimit > 0, "WS_RATE_LIMIT must be positive, got %v", c.WSRateLimit)
+	check(c.WSRateBurst > 0, "WS_RATE_BURST must be positive, got %v", c.WSRateBurst)
+
+	check(c.WebhookWorkers > 0, "WEBHOOK_WORKERS must be positive, got %v", c.WebhookWorkers)
+	check(c.WebhookMaxAttempts > 0, "WEBHOOK_MAX_ATTEMPTS must be positive, got %v", c.WebhookMaxAttempts)
+	check(c.WebhookBackoffSeconds >= 0, "WEBHOOK_BACKOFF_SECONDS must not be negative, got %v", c.WebhookBackoffSeconds)
+	check(c.WebhookMaxBackoffSeconds >= c.WebhookBackoffSeconds,
+		"WEBHOOK_MAX_BACKOFF_SECONDS must not be less than WEBHOOK_BACKOFF_SECONDS, got %v", c.WebhookMaxBackoffSeconds)
+	check(c.WebhookTimeoutSeconds > 0, "WEBHOOK_TIMEOUT_SECONDS must be positive, got %v", c.WebhookTimeoutSeconds)
+
+	check(c.RecurrenceIntervalSeconds > 0, "RECURRENCE_INTERVAL_SECONDS must be positive, got %v", c.RecurrenceIntervalSeconds)
+
+	check(c.ReminderIntervalSeconds > 0, "REMINDER_INTERVAL_SECONDS must be positive, got %v", c.ReminderIntervalSeconds)
+	check(c.ReminderDueSoonHours > 0, "REMINDER_DUE_SOON_HOURS must be positive, got %v", c.ReminderDueSoonHours)
+	check(c.ReminderStaleHours > 0, "REMINDER_STALE_HOURS must be positive, got %v", c.ReminderStaleHours)
+	for _, channel := range c.DefaultChannels() {
+		check(slices.Contains(c.AvailableChannels(), channel),
+			"NOTIFY_DEFAULT_CHANNELS must list some of %v, got %q", strings.Join(c.AvailableChannels(), ", "), channel)
+	}
+	check(c.SMTPAddr == "" || c.SMTPFrom != "", "SMTP_FROM must be set with SMTP_ADDR")
+
+	check(c.GraphQLMaxDepth >= 0, "GRAPHQL_MAX_DEPTH must not be negative, got %v", c.GraphQLMaxDepth)
+	check(c.GraphQLMaxComplexity >= 0, "GRAPHQL_MAX_COMPLEXITY must not be negative, got %v", c.GraphQLMaxComplexity)
+
+	return errors.Join(errs...)
+}
+
+// DefaultChannels returns the channels of NotifyDefaultChannels
+func (c Config) DefaultChannels() []string {
+	channels := make([]string, 0)
+	for _, channel := range strings.Split(c.NotifyDefaultChannels, ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+// AvailableChannels returns the channels reminders can be sent through, email needs SMTPAddr
+func (c Config) AvailableChannels() []string {
+	if c.SMTPAddr == "" {
+		return []string{"log", "webhook"}
+	}
+	return []string{"log", "email", "webhook"}
+}
+
+// PublicIds reports whether tasks are given public ids, see IDStrategy
+func (c Config) PublicIds() bool {
+	return c.IDStrategy != "" && c.IDStrategy != "int"
+}
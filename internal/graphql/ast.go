@@ -0,0 +1,148 @@
+package graphql
+
+// Location is a position in the query, lines and columns start at 1
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Document is a parsed query, it holds executable definitions only
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+type OperationType string
+
+const (
+	QueryOperation    OperationType = "query"
+	MutationOperation OperationType = "mutation"
+)
+
+type Operation struct {
+	Type OperationType
+	// Name is empty for anonymous operations
+	Name       string
+	Variables  []*VariableDefinition
+	Selections []Selection
+	Loc        Location
+}
+
+type VariableDefinition struct {
+	Name string
+	Type *TypeRef
+	// Default is nil when the variable has no default value
+	Default *Value
+	Loc     Location
+}
+
+// TypeRef is a type written in the query, like [String!]
+type TypeRef struct {
+	// Name is empty for lists
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+}
+
+func (t *TypeRef) String() string {
+	var s string
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	} else {
+		s = t.Name
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+	Loc           Location
+}
+
+// Selection is a *FieldSelection, a *FragmentSpread or an *InlineFragment
+type Selection interface {
+	location() Location
+}
+
+type FieldSelection struct {
+	// Alias is empty when the field isn't aliased
+	Alias      string
+	Name       string
+	Arguments  []*ArgumentNode
+	Directives []*Directive
+	Selections []Selection
+	Loc        Location
+}
+
+// ResponseKey is the key of the field in the response
+func (f *FieldSelection) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Loc        Location
+}
+
+type InlineFragment struct {
+	// TypeCondition is empty when the fragment has no type condition
+	TypeCondition string
+	Directives    []*Directive
+	Selections    []Selection
+	Loc           Location
+}
+
+func (f *FieldSelection) location() Location { return f.Loc }
+func (f *FragmentSpread) location() Location { return f.Loc }
+func (f *InlineFragment) location() Location { return f.Loc }
+
+type ArgumentNode struct {
+	Name  string
+	Value *Value
+	Loc   Location
+}
+
+type Directive struct {
+	Name      string
+	Arguments []*ArgumentNode
+	Loc       Location
+}
+
+type ValueKind int
+
+const (
+	VariableValue ValueKind = iota
+	IntValue
+	FloatValue
+	StringValue
+	BooleanValue
+	NullValue
+	EnumValue
+	ListValue
+	ObjectValue
+)
+
+// Value is a literal or a variable in the query
+type Value struct {
+	Kind ValueKind
+	// Raw is the name of variables and enum values, the text of numbers, the unescaped string
+	// of strings and true or false for booleans
+	Raw    string
+	List   []*Value
+	Fields []*ObjectField
+	Loc    Location
+}
+
+type ObjectField struct {
+	Name  string
+	Value *Value
+}
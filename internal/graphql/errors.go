@@ -0,0 +1,70 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Error is an error in the response. Resolvers may return it to set the message and the extensions
+// shown to the client, any other error is shown with its Error() text.
+type Error struct {
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	// Path is the response path of the field that failed, it holds keys and list indexes
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Locations) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%v (%d:%d)", e.Message, e.Locations[0].Line, e.Locations[0].Column)
+}
+
+// Errorf builds an error with the code extension, clients can tell errors apart by it
+func Errorf(code string, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Extensions: map[string]any{"code": code}}
+}
+
+// Result is the response to a query, Data is absent when the query failed before the execution started
+type Result struct {
+	Data   any      `json:"data"`
+	Errors []*Error `json:"errors,omitempty"`
+	// executed is false when the query couldn't be parsed or validated
+	executed bool
+}
+
+func (r *Result) MarshalJSON() ([]byte, error) {
+	if !r.executed {
+		return json.Marshal(struct {
+			Errors []*Error `json:"errors"`
+		}{r.Errors})
+	}
+	return json.Marshal(struct {
+		Data   any      `json:"data"`
+		Errors []*Error `json:"errors,omitempty"`
+	}{r.Data, r.Errors})
+}
+
+// requestError builds the result of a query failed before the execution
+func requestError(err error) *Result {
+	var gqlErr *Error
+	if !errors.As(err, &gqlErr) {
+		gqlErr = &Error{Message: err.Error()}
+	}
+	return &Result{Errors: []*Error{gqlErr}}
+}
+
+// fieldError copies the error of a resolver with the location and the path of the field
+func fieldError(err error, loc Location, path []any) *Error {
+	ans := &Error{Message: err.Error()}
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		ans.Message, ans.Extensions = gqlErr.Message, gqlErr.Extensions
+	}
+	ans.Locations = []Location{loc}
+	ans.Path = path
+	return ans
+}
@@ -0,0 +1,433 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Params are the parameters of a query
+type Params struct {
+	Query         string
+	OperationName string
+	// Variables are decoded json, numbers should be decoded as json.Number
+	Variables map[string]any
+	// MaxDepth limits the nesting of fields and MaxComplexity the sum of the costs of the fields, 0 disables a limit
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// Execute parses, validates and executes the query.
+//
+// Fields of an object are resolved breadth first: a field is resolved for every object of a list at
+// once, so a BatchResolver loads related data of the whole list in a single call. Fields are resolved
+// one after another, mutations are executed in order.
+func (s *Schema) Execute(ctx context.Context, params Params) *Result {
+	doc, err := Parse(params.Query)
+	if err != nil {
+		return requestError(err)
+	}
+	operation, errs := s.validate(doc, params.OperationName)
+	if len(errs) > 0 {
+		return &Result{Errors: errs}
+	}
+	vars, err := s.coerceVariables(operation.Variables, params.Variables)
+	if err != nil {
+		return requestError(err)
+	}
+
+	e := &executor{doc: doc, vars: vars}
+	root := s.Query
+	if operation.Type == MutationOperation {
+		root = s.Mutation
+	}
+	depth, complexity := e.measure(root, operation.Selections)
+	if params.MaxDepth > 0 && depth > params.MaxDepth {
+		return requestError(Errorf("QUERY_TOO_DEEP", "query depth %d exceeds the limit of %d", depth, params.MaxDepth))
+	}
+	if params.MaxComplexity > 0 && complexity > params.MaxComplexity {
+		return requestError(Errorf("QUERY_TOO_COMPLEX", "query complexity %d exceeds the limit of %d", complexity, params.MaxComplexity))
+	}
+
+	result := &Result{executed: true}
+	if data := e.executeSelections(ctx, root, []any{nil}, [][]any{nil}, operation.Selections)[0]; data != nil {
+		result.Data = data
+	}
+	result.Errors = e.errors
+	return result
+}
+
+type executor struct {
+	doc    *Document
+	vars   map[string]any
+	errors []*Error
+}
+
+// fieldGroup holds the fields selected with the same response key, they are resolved together
+type fieldGroup struct {
+	key    string
+	fields []*FieldSelection
+}
+
+// collectFields groups the selected fields by response key in the order of the query, fragments are
+// inlined and fields excluded by directives are dropped
+func (e *executor) collectFields(t *Object, selections []Selection) []*fieldGroup {
+	var groups []*fieldGroup
+	index := make(map[string]*fieldGroup)
+	visited := make(map[string]bool)
+
+	var collect func(selections []Selection)
+	collect = func(selections []Selection) {
+		for _, selection := range selections {
+			switch selection := selection.(type) {
+			case *FieldSelection:
+				if !e.included(selection.Directives) {
+					continue
+				}
+				key := selection.ResponseKey()
+				if group, ok := index[key]; ok {
+					group.fields = append(group.fields, selection)
+					continue
+				}
+				group := &fieldGroup{key: key, fields: []*FieldSelection{selection}}
+				index[key] = group
+				groups = append(groups, group)
+			case *InlineFragment:
+				if e.included(selection.Directives) && (selection.TypeCondition == "" || selection.TypeCondition == t.Name) {
+					collect(selection.Selections)
+				}
+			case *FragmentSpread:
+				if !e.included(selection.Directives) || visited[selection.Name] {
+					continue
+				}
+				visited[selection.Name] = true
+				if fragment := e.doc.Fragments[selection.Name]; fragment.TypeCondition == t.Name {
+					collect(fragment.Selections)
+				}
+			}
+		}
+	}
+	collect(selections)
+	return groups
+}
+
+// included evaluates the skip and include directives
+func (e *executor) included(directives []*Directive) bool {
+	for _, directive := range directives {
+		args, err := coerceArguments(booleanIf, directive.Arguments, e.vars)
+		if err != nil {
+			continue
+		}
+		condition, _ := args["if"].(bool)
+		if directive.Name == "skip" && condition || directive.Name == "include" && !condition {
+			return false
+		}
+	}
+	return true
+}
+
+// subselections merges the selections of fields with the same response key
+func subselections(fields []*FieldSelection) []Selection {
+	if len(fields) == 1 {
+		return fields[0].Selections
+	}
+	var selections []Selection
+	for _, field := range fields {
+		selections = append(selections, field.Selections...)
+	}
+	return selections
+}
+
+// measure returns the depth and the complexity of the selections, complexity saturates at math.MaxInt32
+func (e *executor) measure(t *Object, selections []Selection) (int, int) {
+	depth, complexity := 0, 0
+	for _, group := range e.collectFields(t, selections) {
+		field := group.fields[0]
+		def := t.Field(field.Name)
+		if def == nil {
+			depth = max(depth, 1)
+			continue
+		}
+
+		childDepth, childComplexity := 0, 0
+		if child, ok := namedType(def.Type).(*Object); ok {
+			childDepth, childComplexity = e.measure(child, subselections(group.fields))
+		}
+		depth = max(depth, childDepth+1)
+
+		cost := 1 + childComplexity
+		if def.Cost != nil {
+			args, err := coerceArguments(def.Args, field.Arguments, e.vars)
+			if err != nil {
+				args = map[string]any{}
+			}
+			cost = def.Cost(args, childComplexity)
+		}
+		complexity = min(complexity+max(cost, 0), math.MaxInt32)
+	}
+	return depth, complexity
+}
+
+// executeSelections resolves the selections on every source, a nil object in the result means the
+// object is null because a non null field of it is
+func (e *executor) executeSelections(ctx context.Context, t *Object, sources []any, paths [][]any, selections []Selection) []*object {
+	objects := make([]*object, len(sources))
+	for i := range objects {
+		objects[i] = &object{}
+	}
+
+	for _, group := range e.collectFields(t, selections) {
+		field := group.fields[0]
+		if field.Name == "__typename" {
+			for _, o := range objects {
+				if o != nil {
+					o.set(group.key, t.Name)
+				}
+			}
+			continue
+		}
+
+		def := t.Field(field.Name)
+		fieldPaths := make([][]any, len(sources))
+		for i := range sources {
+			fieldPaths[i] = appendPath(paths[i], group.key)
+		}
+		values, failed := e.resolve(ctx, def, field, sources, fieldPaths)
+		values, _ = e.complete(ctx, t.Name+"."+def.Name, def.Type, values, failed, fieldPaths, group.fields)
+		for i, o := range objects {
+			if o == nil {
+				continue
+			}
+			if values[i] == nil && isNonNull(def.Type) {
+				objects[i] = nil
+				continue
+			}
+			o.set(group.key, values[i])
+		}
+	}
+	return objects
+}
+
+// resolve calls the resolver of the field, failed marks the sources the resolver failed on
+func (e *executor) resolve(ctx context.Context, def *Field, field *FieldSelection, sources []any, paths [][]any) ([]any, []bool) {
+	values := make([]any, len(sources))
+	failed := make([]bool, len(sources))
+	failAll := func(err error) ([]any, []bool) {
+		for i := range sources {
+			e.errors = append(e.errors, fieldError(err, field.Loc, paths[i]))
+			failed[i] = true
+		}
+		return values, failed
+	}
+
+	args, err := coerceArguments(def.Args, field.Arguments, e.vars)
+	if err != nil {
+		return failAll(err)
+	}
+
+	if def.Batch != nil {
+		batch, err := callBatch(ctx, def.Batch, sources, args)
+		if err == nil && len(batch) != len(sources) {
+			err = fmt.Errorf("batch resolver returned %d values for %d sources", len(batch), len(sources))
+		}
+		if err != nil {
+			return failAll(err)
+		}
+		for i, value := range batch {
+			values[i] = normalize(value)
+		}
+		return values, failed
+	}
+
+	for i, source := range sources {
+		value, err := callResolve(ctx, def, source, args)
+		if err != nil {
+			e.errors = append(e.errors, fieldError(err, field.Loc, paths[i]))
+			failed[i] = true
+			continue
+		}
+		values[i] = normalize(value)
+	}
+	return values, failed
+}
+
+func callBatch(ctx context.Context, batch BatchResolver, sources []any, args map[string]any) (values []any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error")
+		}
+	}()
+	return batch(ctx, sources, args)
+}
+
+func callResolve(ctx context.Context, def *Field, source any, args map[string]any) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error")
+		}
+	}()
+	if def.Resolve != nil {
+		return def.Resolve(ctx, source, args)
+	}
+	if m, ok := source.(map[string]any); ok {
+		return m[def.Name], nil
+	}
+	return nil, nil
+}
+
+// normalize turns typed nils into nil
+func normalize(value any) any {
+	if value == nil {
+		return nil
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	return value
+}
+
+// complete converts resolved values to the values of the response. failed marks the values that are
+// null because of an error already reported, so a null in a non null position is reported once.
+func (e *executor) complete(ctx context.Context, name string, t Type, values []any, failed []bool, paths [][]any, fields []*FieldSelection) ([]any, []bool) {
+	loc := fields[0].Loc
+	switch t := t.(type) {
+	case *NonNull:
+		out, outFailed := e.complete(ctx, name, t.OfType, values, failed, paths, fields)
+		for i := range out {
+			if out[i] == nil && !outFailed[i] {
+				e.errors = append(e.errors, &Error{Message: fmt.Sprintf("cannot return null for non-null field %v", name), Locations: []Location{loc}, Path: paths[i]})
+				outFailed[i] = true
+			}
+		}
+		return out, outFailed
+
+	case *List:
+		out := make([]any, len(values))
+		outFailed := append([]bool(nil), failed...)
+		var items []any
+		var itemPaths [][]any
+		var owners []int
+		for i, value := range values {
+			if value == nil {
+				continue
+			}
+			v := reflect.ValueOf(value)
+			if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				e.errors = append(e.errors, &Error{Message: fmt.Sprintf("expected a list for field %v", name), Locations: []Location{loc}, Path: paths[i]})
+				outFailed[i] = true
+				continue
+			}
+			out[i] = make([]any, 0, v.Len())
+			for j := range v.Len() {
+				items = append(items, normalize(v.Index(j).Interface()))
+				itemPaths = append(itemPaths, appendPath(paths[i], j))
+				owners = append(owners, i)
+			}
+		}
+
+		completed, itemFailed := e.complete(ctx, name, t.OfType, items, make([]bool, len(items)), itemPaths, fields)
+		for k, owner := range owners {
+			list, ok := out[owner].([]any)
+			if !ok {
+				continue
+			}
+			if completed[k] == nil && isNonNull(t.OfType) {
+				out[owner] = nil
+				outFailed[owner] = outFailed[owner] || itemFailed[k]
+				continue
+			}
+			out[owner] = append(list, completed[k])
+		}
+		return out, outFailed
+
+	case *Scalar:
+		out := make([]any, len(values))
+		outFailed := append([]bool(nil), failed...)
+		for i, value := range values {
+			if value == nil {
+				continue
+			}
+			serialized, err := t.Serialize(value)
+			if err != nil {
+				e.errors = append(e.errors, fieldError(err, loc, paths[i]))
+				outFailed[i] = true
+				continue
+			}
+			out[i] = serialized
+		}
+		return out, outFailed
+
+	case *Object:
+		out := make([]any, len(values))
+		outFailed := append([]bool(nil), failed...)
+		var sources []any
+		var sourcePaths [][]any
+		var owners []int
+		for i, value := range values {
+			if value != nil {
+				sources = append(sources, value)
+				sourcePaths = append(sourcePaths, paths[i])
+				owners = append(owners, i)
+			}
+		}
+		if len(sources) == 0 {
+			return out, outFailed
+		}
+		for k, o := range e.executeSelections(ctx, t, sources, sourcePaths, subselections(fields)) {
+			if o == nil {
+				// the null non null field was reported already
+				outFailed[owners[k]] = true
+				continue
+			}
+			out[owners[k]] = o
+		}
+		return out, outFailed
+
+	default:
+		panic(fmt.Sprintf("graphql: unexpected output type %v", t))
+	}
+}
+
+func appendPath(path []any, key any) []any {
+	ans := make([]any, len(path)+1)
+	copy(ans, path)
+	ans[len(path)] = key
+	return ans
+}
+
+// object is an object of the response, it keeps the order of the fields in the query
+type object struct {
+	keys   []string
+	values []any
+}
+
+func (o *object) set(key string, value any) {
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, value)
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
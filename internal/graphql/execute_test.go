@@ -0,0 +1,274 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+type testItem struct {
+	id      int
+	friends []int
+}
+
+// newTestSchema builds a schema over items 1..5, item n is friends with the items after it.
+// friendBatches counts the calls of the batch resolver of friends.
+func newTestSchema(t *testing.T, friendBatches *int, total *int) *Schema {
+	t.Helper()
+	items := make(map[int]*testItem)
+	for id := 1; id <= 5; id++ {
+		item := &testItem{id: id}
+		for friend := id + 1; friend <= 5 && friend <= id+2; friend++ {
+			item.friends = append(item.friends, friend)
+		}
+		items[id] = item
+	}
+
+	item := &Object{Name: "Item"}
+	item.Fields = []*Field{
+		{Name: "id", Type: NonNullOf(Int), Resolve: func(_ context.Context, source any, _ map[string]any) (any, error) {
+			return source.(*testItem).id, nil
+		}},
+		{Name: "name", Type: String, Args: []*Argument{{Name: "upper", Type: Boolean, Default: false}}, Resolve: func(_ context.Context, source any, args map[string]any) (any, error) {
+			if args["upper"].(bool) {
+				return fmt.Sprintf("ITEM %d", source.(*testItem).id), nil
+			}
+			return fmt.Sprintf("item %d", source.(*testItem).id), nil
+		}},
+		{Name: "friends", Type: NonNullOf(ListOf(NonNullOf(item))), Batch: func(_ context.Context, sources []any, _ map[string]any) ([]any, error) {
+			*friendBatches++
+			ans := make([]any, len(sources))
+			for i, source := range sources {
+				friends := []*testItem{}
+				for _, id := range source.(*testItem).friends {
+					friends = append(friends, items[id])
+				}
+				ans[i] = friends
+			}
+			return ans, nil
+		}},
+		{Name: "broken", Type: NonNullOf(String), Resolve: func(_ context.Context, source any, _ map[string]any) (any, error) {
+			if source.(*testItem).id%2 == 0 {
+				return nil, Errorf("BROKEN", "item %d is broken", source.(*testItem).id)
+			}
+			return "fine", nil
+		}},
+	}
+
+	query := &Object{Name: "Query", Fields: []*Field{
+		{Name: "item", Type: item, Args: []*Argument{{Name: "id", Type: NonNullOf(Int)}}, Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+			if found, ok := items[args["id"].(int)]; ok {
+				return found, nil
+			}
+			return (*testItem)(nil), nil
+		}},
+		{
+			Name: "items",
+			Type: NonNullOf(ListOf(NonNullOf(item))),
+			Args: []*Argument{{Name: "limit", Type: Int, Default: 2}},
+			Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				ans := []*testItem{}
+				for id := 1; id <= args["limit"].(int) && id <= 5; id++ {
+					ans = append(ans, items[id])
+				}
+				return ans, nil
+			},
+			Cost: func(args map[string]any, childComplexity int) int {
+				return 1 + args["limit"].(int)*childComplexity
+			},
+		},
+		{Name: "panic", Type: Int, Resolve: func(context.Context, any, map[string]any) (any, error) {
+			panic("resolver panicked")
+		}},
+	}}
+	mutation := &Object{Name: "Mutation", Fields: []*Field{
+		{Name: "add", Type: NonNullOf(Int), Args: []*Argument{{Name: "n", Type: NonNullOf(Int)}}, Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+			*total += args["n"].(int)
+			return *total, nil
+		}},
+	}}
+
+	schema, err := NewSchema(query, mutation)
+	if err != nil {
+		t.Fatalf("NewSchema failed: %v", err)
+	}
+	return schema
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name                  string
+		params                Params
+		expectedResponse      string
+		expectedFriendBatches int
+	}{
+		{
+			name:             "aliases and arguments",
+			params:           Params{Query: `{ first: item(id: 1) { id name } loud: item(id: 1) { name(upper: true) } missing: item(id: 9) { id } }`},
+			expectedResponse: `{"data":{"first":{"id":1,"name":"item 1"},"loud":{"name":"ITEM 1"},"missing":null}}`,
+		},
+		{
+			name: "variables and defaults",
+			params: Params{
+				Query:     `query Items($limit: Int = 1, $upper: Boolean!) { items(limit: $limit) { name(upper: $upper) } }`,
+				Variables: map[string]any{"upper": true},
+			},
+			expectedResponse: `{"data":{"items":[{"name":"ITEM 1"}]}}`,
+		},
+		{
+			name:             "fragments and directives",
+			params:           Params{Query: `query($skip: Boolean = true) { item(id: 2) { ...Names ... on Item { id } id @skip(if: $skip) __typename } } fragment Names on Item { name name @include(if: false) }`},
+			expectedResponse: `{"data":{"item":{"name":"item 2","id":2,"__typename":"Item"}}}`,
+		},
+		{
+			name:                  "nested lists are batched per level",
+			params:                Params{Query: `{ items(limit: 3) { id friends { id friends { id } } } }`},
+			expectedResponse:      `{"data":{"items":[{"id":1,"friends":[{"id":2,"friends":[{"id":3},{"id":4}]},{"id":3,"friends":[{"id":4},{"id":5}]}]},{"id":2,"friends":[{"id":3,"friends":[{"id":4},{"id":5}]},{"id":4,"friends":[{"id":5}]}]},{"id":3,"friends":[{"id":4,"friends":[{"id":5}]},{"id":5,"friends":[]}]}]}}`,
+			expectedFriendBatches: 2,
+		},
+		{
+			name:             "null bubbles up to the nearest nullable field",
+			params:           Params{Query: `{ odd: item(id: 1) { broken } even: item(id: 2) { id broken } }`},
+			expectedResponse: `{"data":{"odd":{"broken":"fine"},"even":null},"errors":[{"message":"item 2 is broken","locations":[{"line":1,"column":54}],"path":["even","broken"],"extensions":{"code":"BROKEN"}}]}`,
+		},
+		{
+			name:                  "null in a non null list nulls the list",
+			params:                Params{Query: `{ items(limit: 2) { broken } }`},
+			expectedResponse:      `{"data":null,"errors":[{"message":"item 2 is broken","locations":[{"line":1,"column":21}],"path":["items",1,"broken"],"extensions":{"code":"BROKEN"}}]}`,
+			expectedFriendBatches: 0,
+		},
+		{
+			name:             "panics are recovered",
+			params:           Params{Query: `{ panic item(id: 1) { id } }`},
+			expectedResponse: `{"data":{"panic":null,"item":{"id":1}},"errors":[{"message":"internal error","locations":[{"line":1,"column":3}],"path":["panic"]}]}`,
+		},
+		{
+			name:             "mutations run in order",
+			params:           Params{Query: `mutation { first: add(n: 2) second: add(n: 3) third: add(n: -1) }`},
+			expectedResponse: `{"data":{"first":2,"second":5,"third":4}}`,
+		},
+		{
+			name:             "operation name",
+			params:           Params{Query: `query A { item(id: 1) { id } } query B { item(id: 2) { id } }`, OperationName: "B"},
+			expectedResponse: `{"data":{"item":{"id":2}}}`,
+		},
+		{
+			name:             "depth limit",
+			params:           Params{Query: `{ item(id: 1) { friends { friends { id } } } }`, MaxDepth: 3},
+			expectedResponse: `{"errors":[{"message":"query depth 4 exceeds the limit of 3","extensions":{"code":"QUERY_TOO_DEEP"}}]}`,
+		},
+		{
+			name:             "complexity limit",
+			params:           Params{Query: `{ items(limit: 100) { id name } }`, MaxComplexity: 100},
+			expectedResponse: `{"errors":[{"message":"query complexity 201 exceeds the limit of 100","extensions":{"code":"QUERY_TOO_COMPLEX"}}]}`,
+		},
+		{
+			name:             "missing variable",
+			params:           Params{Query: `query($id: Int!) { item(id: $id) { id } }`},
+			expectedResponse: `{"errors":[{"message":"variable $id of type Int! is required","locations":[{"line":1,"column":7}]}]}`,
+		},
+		{
+			name:             "invalid variable",
+			params:           Params{Query: `query($id: Int!) { item(id: $id) { id } }`, Variables: map[string]any{"id": "one"}},
+			expectedResponse: `{"errors":[{"message":"variable $id got an invalid value: Int can't represent one","locations":[{"line":1,"column":7}]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			friendBatches, total := 0, 0
+			schema := newTestSchema(t, &friendBatches, &total)
+
+			response, err := json.Marshal(schema.Execute(context.Background(), tt.params))
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+
+			if string(response) != tt.expectedResponse {
+				t.Errorf("Expected response\n%s\ngot\n%s", tt.expectedResponse, response)
+			}
+			if friendBatches != tt.expectedFriendBatches {
+				t.Errorf("Expected %d batches of friends, got %d", tt.expectedFriendBatches, friendBatches)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		expectedMessages []string
+	}{
+		{name: "unknown field", query: `{ item(id: 1) { title } }`, expectedMessages: []string{`cannot query field "title" on type "Item"`}},
+		{name: "missing argument", query: `{ item { id } }`, expectedMessages: []string{`argument "id" of type Int! of field "item" is required`}},
+		{name: "unknown argument", query: `{ item(id: 1, name: "a") { id } }`, expectedMessages: []string{`unknown argument "name" of field "item"`}},
+		{name: "invalid literal", query: `{ item(id: "a") { id } }`, expectedMessages: []string{`invalid value: Int can't represent a`}},
+		{name: "missing subselection", query: `{ item(id: 1) }`, expectedMessages: []string{`field "item" of type Item must have a selection of subfields`}},
+		{name: "subselection of a leaf", query: `{ item(id: 1) { id { value } } }`, expectedMessages: []string{`field "id" of type Int! can't have a selection of subfields`}},
+		{name: "nullable variable in a non null position", query: `query($id: Int) { item(id: $id) { id } }`, expectedMessages: []string{`variable $id of type Int can't be used as Int!`}},
+		{name: "undefined variable", query: `{ item(id: $id) { id } }`, expectedMessages: []string{`variable $id is not defined`}},
+		{name: "unused variable", query: `query($id: Int) { item(id: 1) { id } }`, expectedMessages: []string{`variable $id is never used`}},
+		{name: "unknown fragment", query: `{ item(id: 1) { ...Missing } }`, expectedMessages: []string{`unknown fragment "Missing"`}},
+		{name: "unused fragment", query: `{ item(id: 1) { id } } fragment Ids on Item { id }`, expectedMessages: []string{`fragment "Ids" is never used`}},
+		{name: "fragment cycle", query: `{ item(id: 1) { ...A } } fragment A on Item { friends { ...B } } fragment B on Item { ...A }`, expectedMessages: []string{`fragment "A" spreads itself`}},
+		{name: "fragment on a wrong type", query: `{ ... on Item { id } }`, expectedMessages: []string{`fragment on "Item" can't be spread on type "Query"`}},
+		{name: "conflicting fields", query: `{ item(id: 1) { id } item(id: 2) { id } }`, expectedMessages: []string{`fields "item" conflict because they select different fields or arguments, use aliases`}},
+		{name: "unknown directive", query: `{ item(id: 1) @cached { id } }`, expectedMessages: []string{`unknown directive @cached`}},
+		{name: "ambiguous operation", query: `query A { panic } query B { panic }`, expectedMessages: []string{`operationName is required when the document has more than one operation`}},
+		{name: "anonymous operation among others", query: `{ panic } query B { panic }`, expectedMessages: []string{`an anonymous operation must be the only operation in the document`}},
+		{
+			name:             "every error is reported",
+			query:            `{ item(id: 1) { title } items(limit: true) { id } }`,
+			expectedMessages: []string{`cannot query field "title" on type "Item"`, `invalid value: Int can't represent true`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			friendBatches, total := 0, 0
+			result := newTestSchema(t, &friendBatches, &total).Execute(context.Background(), Params{Query: tt.query})
+
+			if result.executed || result.Data != nil {
+				t.Errorf("Expected the query not to be executed")
+			}
+			messages := make([]string, len(result.Errors))
+			for i, err := range result.Errors {
+				messages[i] = err.Message
+			}
+			if fmt.Sprint(messages) != fmt.Sprint(tt.expectedMessages) {
+				t.Errorf("Expected errors %q, got %q", tt.expectedMessages, messages)
+			}
+		})
+	}
+}
+
+func TestSchemaString(t *testing.T) {
+	friendBatches, total := 0, 0
+	expected := `schema {
+  query: Query
+  mutation: Mutation
+}
+
+type Item {
+  id: Int!
+  name(upper: Boolean = false): String
+  friends: [Item!]!
+  broken: String!
+}
+
+type Mutation {
+  add(n: Int!): Int!
+}
+
+type Query {
+  item(id: Int!): Item
+  items(limit: Int = 2): [Item!]!
+  panic: Int
+}
+`
+	if sdl := newTestSchema(t, &friendBatches, &total).String(); sdl != expected {
+		t.Errorf("Expected schema\n%s\ngot\n%s", expected, sdl)
+	}
+}
@@ -0,0 +1,448 @@
+package graphql
+
+import "fmt"
+
+// parser is a recursive descent parser of executable documents, it reads one token ahead
+type parser struct {
+	lexer *lexer
+	token token
+}
+
+// Parse parses a query, type system definitions aren't supported.
+// Errors are *Error with the location of the unexpected token.
+func Parse(source string) (*Document, error) {
+	p := &parser{lexer: newLexer(source)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+	if p.token.kind == tokenEOF {
+		return nil, p.lexer.errorf(p.token.loc, "the document has no operations")
+	}
+	for p.token.kind != tokenEOF {
+		if p.peekName("fragment") {
+			fragment, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.Fragments[fragment.Name]; ok {
+				return nil, &Error{Message: fmt.Sprintf("there can be only one fragment named %q", fragment.Name), Locations: []Location{fragment.Loc}}
+			}
+			doc.Fragments[fragment.Name] = fragment
+			continue
+		}
+		operation, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		doc.Operations = append(doc.Operations, operation)
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = t
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	return p.lexer.errorf(p.token.loc, "unexpected %v", p.token)
+}
+
+func (p *parser) peek(punctuator string) bool {
+	return p.token.kind == tokenPunctuator && p.token.value == punctuator
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.token.kind == tokenName && p.token.value == name
+}
+
+// skip advances past the punctuator if it's the current token and reports whether it was
+func (p *parser) skip(punctuator string) (bool, error) {
+	if !p.peek(punctuator) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return p.lexer.errorf(p.token.loc, "expected %q, found %v", punctuator, p.token)
+	}
+	return p.advance()
+}
+
+func (p *parser) parseName() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.lexer.errorf(p.token.loc, "expected a name, found %v", p.token)
+	}
+	name := p.token.value
+	return name, p.advance()
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	operation := &Operation{Type: QueryOperation, Loc: p.token.loc}
+	if p.peek("{") {
+		selections, err := p.parseSelectionSet()
+		operation.Selections = selections
+		return operation, err
+	}
+
+	if p.token.kind != tokenName {
+		return nil, p.unexpected()
+	}
+	switch p.token.value {
+	case "query", "mutation":
+		operation.Type = OperationType(p.token.value)
+	case "subscription":
+		return nil, &Error{Message: "subscriptions aren't supported", Locations: []Location{p.token.loc}}
+	default:
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.token.kind == tokenName {
+		if operation.Name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+	}
+	if operation.Variables, err = p.parseVariableDefinitions(); err != nil {
+		return nil, err
+	}
+	if p.peek("@") {
+		return nil, p.lexer.errorf(p.token.loc, "directives on operations aren't supported")
+	}
+	if operation.Selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+func (p *parser) parseVariableDefinitions() ([]*VariableDefinition, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+
+	var definitions []*VariableDefinition
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			return definitions, err
+		}
+		definition := &VariableDefinition{Loc: p.token.loc}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		var err error
+		if definition.Name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if definition.Type, err = p.parseTypeRef(); err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if definition.Default, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+		definitions = append(definitions, definition)
+	}
+}
+
+func (p *parser) parseTypeRef() (*TypeRef, error) {
+	ref := &TypeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if ref.Elem, err = p.parseTypeRef(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if ref.Name, err = p.parseName(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	ref.NonNull, err = p.skip("!")
+	return ref, err
+}
+
+func (p *parser) parseSelectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []Selection
+	for {
+		if p.peek("}") && len(selections) == 0 {
+			return nil, p.lexer.errorf(p.token.loc, "a selection set can't be empty")
+		}
+		if ok, err := p.skip("}"); ok || err != nil {
+			return selections, err
+		}
+
+		var selection Selection
+		var err error
+		if p.peek("...") {
+			selection, err = p.parseFragmentSelection()
+		} else {
+			selection, err = p.parseField()
+		}
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+}
+
+func (p *parser) parseField() (*FieldSelection, error) {
+	field := &FieldSelection{Loc: p.token.loc}
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		field.Alias = name
+		if name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+	}
+	field.Name = name
+
+	if field.Arguments, err = p.parseArguments(); err != nil {
+		return nil, err
+	}
+	if field.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if field.Selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+func (p *parser) parseArguments() ([]*ArgumentNode, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+
+	var arguments []*ArgumentNode
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			return arguments, err
+		}
+		argument := &ArgumentNode{Loc: p.token.loc}
+		var err error
+		if argument.Name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+		for _, other := range arguments {
+			if other.Name == argument.Name {
+				return nil, &Error{Message: fmt.Sprintf("there can be only one argument named %q", argument.Name), Locations: []Location{argument.Loc}}
+			}
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if argument.Value, err = p.parseValue(false); err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+}
+
+func (p *parser) parseDirectives() ([]*Directive, error) {
+	var directives []*Directive
+	for p.peek("@") {
+		directive := &Directive{Loc: p.token.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if directive.Name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+		if directive.Arguments, err = p.parseArguments(); err != nil {
+			return nil, err
+		}
+		directives = append(directives, directive)
+	}
+	return directives, nil
+}
+
+// parseFragmentSelection parses a fragment spread or an inline fragment
+func (p *parser) parseFragmentSelection() (Selection, error) {
+	loc := p.token.loc
+	if err := p.expect("..."); err != nil {
+		return nil, err
+	}
+
+	if p.token.kind == tokenName && !p.peekName("on") {
+		spread := &FragmentSpread{Loc: loc}
+		var err error
+		if spread.Name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+		spread.Directives, err = p.parseDirectives()
+		return spread, err
+	}
+
+	fragment := &InlineFragment{Loc: loc}
+	var err error
+	if p.peekName("on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if fragment.TypeCondition, err = p.parseName(); err != nil {
+			return nil, err
+		}
+	}
+	if fragment.Directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if fragment.Selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+func (p *parser) parseFragment() (*Fragment, error) {
+	fragment := &Fragment{Loc: p.token.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if fragment.Name, err = p.parseName(); err != nil {
+		return nil, err
+	}
+	if fragment.Name == "on" {
+		return nil, p.lexer.errorf(fragment.Loc, "a fragment can't be named \"on\"")
+	}
+	if !p.peekName("on") {
+		return nil, p.lexer.errorf(p.token.loc, "expected \"on\", found %v", p.token)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if fragment.TypeCondition, err = p.parseName(); err != nil {
+		return nil, err
+	}
+	if p.peek("@") {
+		return nil, p.lexer.errorf(p.token.loc, "directives on fragment definitions aren't supported")
+	}
+	if fragment.Selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+// parseValue parses a value, constant values can't hold variables
+func (p *parser) parseValue(constant bool) (*Value, error) {
+	value := &Value{Loc: p.token.loc}
+	switch p.token.kind {
+	case tokenInt:
+		value.Kind, value.Raw = IntValue, p.token.value
+	case tokenFloat:
+		value.Kind, value.Raw = FloatValue, p.token.value
+	case tokenString:
+		value.Kind, value.Raw = StringValue, p.token.value
+	case tokenName:
+		switch p.token.value {
+		case "true", "false":
+			value.Kind = BooleanValue
+		case "null":
+			value.Kind = NullValue
+		default:
+			value.Kind = EnumValue
+		}
+		value.Raw = p.token.value
+	case tokenPunctuator:
+		switch p.token.value {
+		case "$":
+			if constant {
+				return nil, p.lexer.errorf(p.token.loc, "variables aren't allowed in constant values")
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			var err error
+			value.Kind = VariableValue
+			value.Raw, err = p.parseName()
+			return value, err
+		case "[":
+			return p.parseList(value, constant)
+		case "{":
+			return p.parseObject(value, constant)
+		default:
+			return nil, p.unexpected()
+		}
+	default:
+		return nil, p.unexpected()
+	}
+	return value, p.advance()
+}
+
+func (p *parser) parseList(value *Value, constant bool) (*Value, error) {
+	value.Kind = ListValue
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for {
+		if ok, err := p.skip("]"); ok || err != nil {
+			return value, err
+		}
+		item, err := p.parseValue(constant)
+		if err != nil {
+			return nil, err
+		}
+		value.List = append(value.List, item)
+	}
+}
+
+func (p *parser) parseObject(value *Value, constant bool) (*Value, error) {
+	value.Kind = ObjectValue
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for {
+		if ok, err := p.skip("}"); ok || err != nil {
+			return value, err
+		}
+		loc := p.token.loc
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range value.Fields {
+			if other.Name == name {
+				return nil, &Error{Message: fmt.Sprintf("there can be only one input field named %q", name), Locations: []Location{loc}}
+			}
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		field, err := p.parseValue(constant)
+		if err != nil {
+			return nil, err
+		}
+		value.Fields = append(value.Fields, &ObjectField{Name: name, Value: field})
+	}
+}
@@ -0,0 +1,114 @@
+package graphql
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		# every kind of selection
+		query Tasks($status: String = "done", $ids: [Int!]!) {
+			first: task(id: 1) { ...TaskFields }
+			tasks(filter: {status: $status, tags: ["a", "b"]}, limit: 2.5e1) @include(if: true) {
+				... on TaskPage { total }
+				...  { hasMore }
+			}
+			description(text: """
+				block
+				  string
+			""", escaped: "a\"é\n", ids: $ids, empty: null, enum: RED)
+		}
+
+		fragment TaskFields on Task { id, name }
+	`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(doc.Operations) != 1 || len(doc.Fragments) != 1 {
+		t.Fatalf("Expected an operation and a fragment, got %v", doc)
+	}
+	operation := doc.Operations[0]
+	if operation.Type != QueryOperation || operation.Name != "Tasks" || len(operation.Variables) != 2 || len(operation.Selections) != 3 {
+		t.Fatalf("Unexpected operation %+v", operation)
+	}
+	if ids := operation.Variables[1]; ids.Type.String() != "[Int!]!" || ids.Default != nil {
+		t.Errorf("Unexpected variable %+v", ids)
+	}
+	if status := operation.Variables[0]; status.Default == nil || status.Default.Kind != StringValue || status.Default.Raw != "done" {
+		t.Errorf("Unexpected variable %+v", status)
+	}
+
+	first := operation.Selections[0].(*FieldSelection)
+	if first.ResponseKey() != "first" || first.Name != "task" || first.Loc != (Location{Line: 4, Column: 4}) {
+		t.Errorf("Unexpected field %+v", first)
+	}
+	if spread := first.Selections[0].(*FragmentSpread); spread.Name != "TaskFields" {
+		t.Errorf("Unexpected spread %+v", spread)
+	}
+
+	tasks := operation.Selections[1].(*FieldSelection)
+	filter := tasks.Arguments[0].Value
+	if filter.Kind != ObjectValue || filter.Fields[0].Value.Kind != VariableValue || len(filter.Fields[1].Value.List) != 2 {
+		t.Errorf("Unexpected filter %+v", filter)
+	}
+	if limit := tasks.Arguments[1].Value; limit.Kind != FloatValue || limit.Raw != "2.5e1" {
+		t.Errorf("Unexpected limit %+v", limit)
+	}
+	if len(tasks.Directives) != 1 || tasks.Directives[0].Name != "include" {
+		t.Errorf("Unexpected directives %+v", tasks.Directives)
+	}
+	if inline := tasks.Selections[0].(*InlineFragment); inline.TypeCondition != "TaskPage" {
+		t.Errorf("Unexpected fragment %+v", inline)
+	}
+	if inline := tasks.Selections[1].(*InlineFragment); inline.TypeCondition != "" {
+		t.Errorf("Unexpected fragment %+v", inline)
+	}
+
+	args := operation.Selections[2].(*FieldSelection).Arguments
+	expected := []struct {
+		kind ValueKind
+		raw  string
+	}{{StringValue, "block\n  string"}, {StringValue, "a\"é\n"}, {VariableValue, "ids"}, {NullValue, "null"}, {EnumValue, "RED"}}
+	for i, arg := range args {
+		if arg.Value.Kind != expected[i].kind || arg.Value.Raw != expected[i].raw {
+			t.Errorf("Expected argument %v to be %q, got %q", arg.Name, expected[i].raw, arg.Value.Raw)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		expectedMessage  string
+		expectedLocation Location
+	}{
+		{name: "empty document", query: "  ", expectedMessage: "Syntax Error: the document has no operations", expectedLocation: Location{1, 3}},
+		{name: "unclosed selection set", query: "{ task", expectedMessage: `Syntax Error: expected a name, found <EOF>`, expectedLocation: Location{1, 7}},
+		{name: "empty selection set", query: "{\n  task {}\n}", expectedMessage: "Syntax Error: a selection set can't be empty", expectedLocation: Location{2, 9}},
+		{name: "unknown character", query: "{ task(id: 1) ? }", expectedMessage: `Syntax Error: unexpected character '?'`, expectedLocation: Location{1, 15}},
+		{name: "leading zero", query: "{ task(id: 01) }", expectedMessage: "Syntax Error: invalid number, unexpected digit after 0", expectedLocation: Location{1, 12}},
+		{name: "unterminated string", query: `{ task(name: "abc) }`, expectedMessage: "Syntax Error: unterminated string", expectedLocation: Location{1, 14}},
+		{name: "invalid escape", query: `{ task(name: "\x") }`, expectedMessage: `Syntax Error: invalid escape \x`, expectedLocation: Location{1, 14}},
+		{name: "variable in a constant", query: "query($a: Int = $b) { task }", expectedMessage: "Syntax Error: variables aren't allowed in constant values", expectedLocation: Location{1, 17}},
+		{name: "repeated argument", query: "{ task(id: 1, id: 2) }", expectedMessage: `there can be only one argument named "id"`, expectedLocation: Location{1, 15}},
+		{name: "repeated fragment", query: "{ task } fragment A on Task { id } fragment A on Task { id }", expectedMessage: `there can be only one fragment named "A"`, expectedLocation: Location{1, 36}},
+		{name: "subscription", query: "subscription { task }", expectedMessage: "subscriptions aren't supported", expectedLocation: Location{1, 1}},
+		{name: "type definition", query: "type Task { id: Int }", expectedMessage: "Syntax Error: unexpected type", expectedLocation: Location{1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			var gqlErr *Error
+			if !errors.As(err, &gqlErr) {
+				t.Fatalf("Expected a graphql error, got %v", err)
+			}
+			if gqlErr.Message != tt.expectedMessage || len(gqlErr.Locations) != 1 || gqlErr.Locations[0] != tt.expectedLocation {
+				t.Errorf("Expected %q at %v, got %q at %v", tt.expectedMessage, tt.expectedLocation, gqlErr.Message, gqlErr.Locations)
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Type is a type of the schema: *Scalar, *Object, *InputObject, *List or *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type
+type Scalar struct {
+	Name        string
+	Description string
+	// Serialize converts a resolved value to its json value, nil stands for null
+	Serialize func(value any) (any, error)
+	// Parse converts an input value to the value passed to resolvers. Numbers come as json.Number
+	// or float64, strings as string and booleans as bool.
+	Parse func(value any) (any, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Object is an output type with fields
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+func (o *Object) String() string { return o.Name }
+
+// Field looks up the field by name, it returns nil for unknown fields
+func (o *Object) Field(name string) *Field {
+	for _, field := range o.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// Resolver returns the value of a field on source
+type Resolver func(ctx context.Context, source any, args map[string]any) (any, error)
+
+// BatchResolver returns the values of a field on every source at once, so related data of a list can be
+// loaded with a single call. The result must hold a value for every source in the same order.
+type BatchResolver func(ctx context.Context, sources []any, args map[string]any) ([]any, error)
+
+type Field struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Argument
+	// Resolve is called for every source, fields without resolvers read the key of a map[string]any source
+	Resolve Resolver
+	// Batch is called once for all the sources of the field in a list, it takes precedence over Resolve
+	Batch BatchResolver
+	// Cost is the complexity of the field given its arguments and the complexity of the selected fields,
+	// nil costs 1 plus the complexity of the selected fields
+	Cost func(args map[string]any, childComplexity int) int
+}
+
+// Argument is an argument of a field or a field of an input object, arguments missing
+// from the query aren't in the args of resolvers unless they have a default
+type Argument struct {
+	Name        string
+	Description string
+	Type        Type
+	// Default is used when the argument is missing, nil means there is no default
+	Default any
+}
+
+// InputObject is an input type with fields, resolvers get it as map[string]any
+type InputObject struct {
+	Name        string
+	Description string
+	Fields      []*Argument
+}
+
+func (o *InputObject) String() string { return o.Name }
+
+func (o *InputObject) field(name string) *Argument {
+	for _, field := range o.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+type List struct {
+	OfType Type
+}
+
+func ListOf(t Type) *List { return &List{OfType: t} }
+
+func (l *List) String() string { return "[" + l.OfType.String() + "]" }
+
+type NonNull struct {
+	OfType Type
+}
+
+func NonNullOf(t Type) *NonNull { return &NonNull{OfType: t} }
+
+func (n *NonNull) String() string { return n.OfType.String() + "!" }
+
+// namedType unwraps lists and non null types
+func namedType(t Type) Type {
+	for {
+		switch wrapper := t.(type) {
+		case *List:
+			t = wrapper.OfType
+		case *NonNull:
+			t = wrapper.OfType
+		default:
+			return t
+		}
+	}
+}
+
+func isNonNull(t Type) bool {
+	_, ok := t.(*NonNull)
+	return ok
+}
+
+// Schema holds the root types, its named types are the ones reachable from the roots
+type Schema struct {
+	Query *Object
+	// Mutation is nil when the schema has no mutations
+	Mutation *Object
+	types    map[string]Type
+}
+
+// NewSchema checks the types reachable from the roots: names are unique and valid, fields have output
+// types and arguments have input types
+func NewSchema(query, mutation *Object) (*Schema, error) {
+	if query == nil {
+		return nil, fmt.Errorf("schema: query type is required")
+	}
+	s := &Schema{Query: query, Mutation: mutation, types: make(map[string]Type)}
+	for _, scalar := range []*Scalar{Int, Float, String, Boolean, ID} {
+		s.types[scalar.Name] = scalar
+	}
+	for _, root := range []*Object{query, mutation} {
+		if root != nil {
+			if err := s.addType(root); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) addType(t Type) error {
+	named := namedType(t)
+	name := named.String()
+	if existing, ok := s.types[name]; ok {
+		if existing != named {
+			return fmt.Errorf("schema: there are different types named %q", name)
+		}
+		return nil
+	}
+	if !isName(name) || strings.HasPrefix(name, "__") {
+		return fmt.Errorf("schema: invalid type name %q", name)
+	}
+	s.types[name] = named
+
+	switch named := named.(type) {
+	case *Scalar:
+		if named.Serialize == nil || named.Parse == nil {
+			return fmt.Errorf("schema: scalar %v needs Serialize and Parse", name)
+		}
+	case *Object:
+		if len(named.Fields) == 0 {
+			return fmt.Errorf("schema: object %v has no fields", name)
+		}
+		seen := make(map[string]bool)
+		for _, field := range named.Fields {
+			if !isName(field.Name) || strings.HasPrefix(field.Name, "__") || seen[field.Name] {
+				return fmt.Errorf("schema: invalid or repeated field %v.%v", name, field.Name)
+			}
+			seen[field.Name] = true
+			if _, ok := namedType(field.Type).(*InputObject); ok || field.Type == nil {
+				return fmt.Errorf("schema: field %v.%v needs an output type", name, field.Name)
+			}
+			if err := s.addType(field.Type); err != nil {
+				return err
+			}
+			if err := s.addArguments(name+"."+field.Name, field.Args); err != nil {
+				return err
+			}
+		}
+	case *InputObject:
+		if len(named.Fields) == 0 {
+			return fmt.Errorf("schema: input object %v has no fields", name)
+		}
+		return s.addArguments(name, named.Fields)
+	default:
+		return fmt.Errorf("schema: unsupported type %v", name)
+	}
+	return nil
+}
+
+func (s *Schema) addArguments(owner string, args []*Argument) error {
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		if !isName(arg.Name) || seen[arg.Name] {
+			return fmt.Errorf("schema: invalid or repeated argument %v(%v)", owner, arg.Name)
+		}
+		seen[arg.Name] = true
+		if arg.Type == nil {
+			return fmt.Errorf("schema: argument %v(%v) has no type", owner, arg.Name)
+		}
+		if _, ok := namedType(arg.Type).(*Object); ok {
+			return fmt.Errorf("schema: argument %v(%v) needs an input type", owner, arg.Name)
+		}
+		if err := s.addType(arg.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String prints the schema in the schema definition language, types are sorted by name
+func (s *Schema) String() string {
+	var b strings.Builder
+	b.WriteString("schema {\n  query: " + s.Query.Name + "\n")
+	if s.Mutation != nil {
+		b.WriteString("  mutation: " + s.Mutation.Name + "\n")
+	}
+	b.WriteString("}\n")
+
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		switch t := s.types[name].(type) {
+		case *Scalar:
+			if !isBuiltin(t) {
+				b.WriteString("\n" + description(t.Description, ""))
+				b.WriteString("scalar " + t.Name + "\n")
+			}
+		case *Object:
+			b.WriteString("\n" + description(t.Description, ""))
+			b.WriteString("type " + t.Name + " {\n")
+			for _, field := range t.Fields {
+				b.WriteString(description(field.Description, "  "))
+				b.WriteString("  " + field.Name + arguments(field.Args) + ": " + field.Type.String() + "\n")
+			}
+			b.WriteString("}\n")
+		case *InputObject:
+			b.WriteString("\n" + description(t.Description, ""))
+			b.WriteString("input " + t.Name + " {\n")
+			for _, field := range t.Fields {
+				b.WriteString(description(field.Description, "  "))
+				b.WriteString("  " + inputValue(field) + "\n")
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
+
+func description(text, indent string) string {
+	if text == "" {
+		return ""
+	}
+	return indent + strconv.Quote(text) + "\n"
+}
+
+func arguments(args []*Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+	printed := make([]string, 0, len(args))
+	for _, arg := range args {
+		printed = append(printed, inputValue(arg))
+	}
+	return "(" + strings.Join(printed, ", ") + ")"
+}
+
+func inputValue(arg *Argument) string {
+	s := arg.Name + ": " + arg.Type.String()
+	if arg.Default != nil {
+		value, _ := json.Marshal(arg.Default)
+		s += " = " + string(value)
+	}
+	return s
+}
+
+func isName(name string) bool {
+	if name == "" || !isNameStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isNameContinue(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBuiltin(s *Scalar) bool {
+	return s == Int || s == Float || s == String || s == Boolean || s == ID
+}
+
+var (
+	Int = &Scalar{
+		Name:      "Int",
+		Serialize: serializeInt,
+		Parse: func(value any) (any, error) {
+			switch value := value.(type) {
+			case json.Number:
+				n, err := strconv.ParseInt(string(value), 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("Int can't represent %v", value)
+				}
+				return int(n), nil
+			case float64:
+				return serializeInt(value)
+			default:
+				return nil, fmt.Errorf("Int can't represent %v", value)
+			}
+		},
+	}
+	Float = &Scalar{
+		Name: "Float",
+		Serialize: func(value any) (any, error) {
+			v := reflect.ValueOf(value)
+			switch {
+			case v.CanFloat():
+				return v.Float(), nil
+			case v.CanInt():
+				return float64(v.Int()), nil
+			default:
+				return nil, fmt.Errorf("Float can't represent %v", value)
+			}
+		},
+		Parse: func(value any) (any, error) {
+			switch value := value.(type) {
+			case json.Number:
+				return value.Float64()
+			case float64:
+				return value, nil
+			default:
+				return nil, fmt.Errorf("Float can't represent %v", value)
+			}
+		},
+	}
+	String = &Scalar{
+		Name: "String",
+		Serialize: func(value any) (any, error) {
+			if v := reflect.ValueOf(value); v.Kind() == reflect.String {
+				return v.String(), nil
+			}
+			return nil, fmt.Errorf("String can't represent %v", value)
+		},
+		Parse: func(value any) (any, error) {
+			if s, ok := value.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String can't represent %v", value)
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean",
+		Serialize: func(value any) (any, error) {
+			if v := reflect.ValueOf(value); v.Kind() == reflect.Bool {
+				return v.Bool(), nil
+			}
+			return nil, fmt.Errorf("Boolean can't represent %v", value)
+		},
+		Parse: func(value any) (any, error) {
+			if b, ok := value.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean can't represent %v", value)
+		},
+	}
+	ID = &Scalar{
+		Name: "ID",
+		Serialize: func(value any) (any, error) {
+			v := reflect.ValueOf(value)
+			switch {
+			case v.Kind() == reflect.String:
+				return v.String(), nil
+			case v.CanInt():
+				return strconv.FormatInt(v.Int(), 10), nil
+			default:
+				return nil, fmt.Errorf("ID can't represent %v", value)
+			}
+		},
+		Parse: func(value any) (any, error) {
+			switch value := value.(type) {
+			case string:
+				return value, nil
+			case json.Number:
+				if _, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+					return string(value), nil
+				}
+			}
+			return nil, fmt.Errorf("ID can't represent %v", value)
+		},
+	}
+)
+
+// serializeInt accepts integers and integral floats that fit 32 bits, like the spec requires
+func serializeInt(value any) (any, error) {
+	v := reflect.ValueOf(value)
+	switch {
+	case v.CanInt() && v.Int() >= math.MinInt32 && v.Int() <= math.MaxInt32:
+		return int(v.Int()), nil
+	case v.CanUint() && v.Uint() <= math.MaxInt32:
+		return int(v.Uint()), nil
+	case v.CanFloat() && v.Float() == math.Trunc(v.Float()) && v.Float() >= math.MinInt32 && v.Float() <= math.MaxInt32:
+		return int(v.Float()), nil
+	default:
+		return nil, fmt.Errorf("Int can't represent %v", value)
+	}
+}
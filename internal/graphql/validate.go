@@ -0,0 +1,431 @@
+package graphql
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// validation collects the errors of a document, see Schema.validate
+type validation struct {
+	schema *Schema
+	doc    *Document
+	errors []*Error
+	// fragments are the fragments validated in the current operation, a fragment is validated once
+	// per operation since its type is fixed
+	fragments map[string]bool
+	// usedFragments are the fragments spread by any operation
+	usedFragments map[string]bool
+	// spreading holds the fragments on the current spread path, it detects cycles
+	spreading map[string]bool
+	// variables are the variables of the operation being validated
+	variables map[string]*VariableDefinition
+	// used are the variables used by the operation being validated
+	used map[string]bool
+}
+
+func (v *validation) errorf(loc Location, format string, args ...any) {
+	v.errors = append(v.errors, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+// validate checks the document against the schema and returns the operation to execute,
+// the operation is chosen by name when the document has more than one
+func (s *Schema) validate(doc *Document, operationName string) (*Operation, []*Error) {
+	v := &validation{schema: s, doc: doc, usedFragments: make(map[string]bool), spreading: make(map[string]bool)}
+
+	var selected *Operation
+	names := make(map[string]bool)
+	for _, operation := range doc.Operations {
+		if operation.Name == "" && len(doc.Operations) > 1 {
+			v.errorf(operation.Loc, "an anonymous operation must be the only operation in the document")
+		}
+		if operation.Name != "" && names[operation.Name] {
+			v.errorf(operation.Loc, "there can be only one operation named %q", operation.Name)
+		}
+		names[operation.Name] = true
+		if operationName == "" && len(doc.Operations) == 1 || operation.Name == operationName {
+			selected = operation
+		}
+		v.validateOperation(operation)
+	}
+	for _, name := range slices.Sorted(maps.Keys(doc.Fragments)) {
+		if !v.usedFragments[name] {
+			v.errorf(doc.Fragments[name].Loc, "fragment %q is never used", name)
+		}
+	}
+
+	if selected == nil && len(v.errors) == 0 {
+		if operationName == "" {
+			v.errors = append(v.errors, &Error{Message: "operationName is required when the document has more than one operation"})
+		} else {
+			v.errors = append(v.errors, &Error{Message: fmt.Sprintf("unknown operation %q", operationName)})
+		}
+	}
+	return selected, v.errors
+}
+
+func (v *validation) validateOperation(operation *Operation) {
+	root := v.schema.Query
+	if operation.Type == MutationOperation {
+		if root = v.schema.Mutation; root == nil {
+			v.errorf(operation.Loc, "the schema has no mutations")
+			return
+		}
+	}
+
+	v.fragments = make(map[string]bool)
+	v.variables = make(map[string]*VariableDefinition)
+	v.used = make(map[string]bool)
+	for _, def := range operation.Variables {
+		if _, ok := v.variables[def.Name]; ok {
+			v.errorf(def.Loc, "there can be only one variable named $%v", def.Name)
+			continue
+		}
+		v.variables[def.Name] = def
+		t := v.schema.typeOf(def.Type)
+		if t == nil {
+			v.errorf(def.Loc, "unknown type %v of variable $%v", def.Type, def.Name)
+			continue
+		}
+		if _, ok := namedType(t).(*Object); ok {
+			v.errorf(def.Loc, "variable $%v can't be of output type %v", def.Name, def.Type)
+			continue
+		}
+		if def.Default != nil {
+			if _, _, err := coerceLiteral(def.Default, t, nil); err != nil {
+				v.errorf(def.Default.Loc, "invalid default of variable $%v: %v", def.Name, err)
+			}
+		}
+	}
+
+	v.validateSelections(root, operation.Selections)
+	for name := range v.operationVariables(operation.Selections, make(map[string]bool)) {
+		v.used[name] = true
+	}
+	for _, def := range operation.Variables {
+		if !v.used[def.Name] {
+			v.errorf(def.Loc, "variable $%v is never used", def.Name)
+		}
+	}
+}
+
+func (v *validation) validateSelections(parent *Object, selections []Selection) {
+	for _, selection := range selections {
+		switch selection := selection.(type) {
+		case *FieldSelection:
+			v.validateField(parent, selection)
+		case *InlineFragment:
+			v.validateDirectives(selection.Directives)
+			if selection.TypeCondition != "" && selection.TypeCondition != parent.Name {
+				v.errorf(selection.Loc, "fragment on %q can't be spread on type %q", selection.TypeCondition, parent.Name)
+				continue
+			}
+			v.validateSelections(parent, selection.Selections)
+		case *FragmentSpread:
+			v.validateDirectives(selection.Directives)
+			fragment, ok := v.doc.Fragments[selection.Name]
+			if !ok {
+				v.errorf(selection.Loc, "unknown fragment %q", selection.Name)
+				continue
+			}
+			if fragment.TypeCondition != parent.Name {
+				v.errorf(selection.Loc, "fragment %q on %q can't be spread on type %q", fragment.Name, fragment.TypeCondition, parent.Name)
+				continue
+			}
+			if v.spreading[fragment.Name] {
+				v.errorf(selection.Loc, "fragment %q spreads itself", fragment.Name)
+				continue
+			}
+			if v.fragments[fragment.Name] {
+				continue
+			}
+			v.fragments[fragment.Name] = true
+			v.usedFragments[fragment.Name] = true
+			v.spreading[fragment.Name] = true
+			v.validateSelections(parent, fragment.Selections)
+			delete(v.spreading, fragment.Name)
+		}
+	}
+	v.validateMerging(parent, selections)
+}
+
+func (v *validation) validateField(parent *Object, field *FieldSelection) {
+	v.validateDirectives(field.Directives)
+	if field.Name == "__typename" {
+		if len(field.Arguments) > 0 || len(field.Selections) > 0 {
+			v.errorf(field.Loc, "__typename has no arguments and subfields")
+		}
+		return
+	}
+
+	def := parent.Field(field.Name)
+	if def == nil {
+		v.errorf(field.Loc, "cannot query field %q on type %q", field.Name, parent.Name)
+		return
+	}
+	v.validateArguments(fmt.Sprintf("field %q", field.Name), def.Args, field.Arguments, field.Loc)
+
+	switch t := namedType(def.Type).(type) {
+	case *Object:
+		if len(field.Selections) == 0 {
+			v.errorf(field.Loc, "field %q of type %v must have a selection of subfields", field.Name, def.Type)
+			return
+		}
+		v.validateSelections(t, field.Selections)
+	default:
+		if len(field.Selections) > 0 {
+			v.errorf(field.Loc, "field %q of type %v can't have a selection of subfields", field.Name, def.Type)
+		}
+	}
+}
+
+// booleanIf is the argument of the skip and include directives
+var booleanIf = []*Argument{{Name: "if", Type: NonNullOf(Boolean)}}
+
+func (v *validation) validateDirectives(directives []*Directive) {
+	seen := make(map[string]bool)
+	for _, directive := range directives {
+		if directive.Name != "skip" && directive.Name != "include" {
+			v.errorf(directive.Loc, "unknown directive @%v", directive.Name)
+			continue
+		}
+		if seen[directive.Name] {
+			v.errorf(directive.Loc, "directive @%v can't be repeated", directive.Name)
+		}
+		seen[directive.Name] = true
+		v.validateArguments("directive @"+directive.Name, booleanIf, directive.Arguments, directive.Loc)
+	}
+}
+
+func (v *validation) validateArguments(owner string, defs []*Argument, nodes []*ArgumentNode, loc Location) {
+	for _, node := range nodes {
+		var def *Argument
+		for _, candidate := range defs {
+			if candidate.Name == node.Name {
+				def = candidate
+			}
+		}
+		if def == nil {
+			v.errorf(node.Loc, "unknown argument %q of %v", node.Name, owner)
+			continue
+		}
+		v.validateValue(node.Value, def.Type, def.Default != nil)
+	}
+	for _, def := range defs {
+		if !isNonNull(def.Type) || def.Default != nil {
+			continue
+		}
+		found := false
+		for _, node := range nodes {
+			found = found || node.Name == def.Name
+		}
+		if !found {
+			v.errorf(loc, "argument %q of type %v of %v is required", def.Name, def.Type, owner)
+		}
+	}
+}
+
+// placeholder stands for the values of variables while literals are validated
+type placeholder struct{}
+
+// validateValue checks that variables in the value are defined and fit their positions, and that literals can be coerced
+func (v *validation) validateValue(value *Value, t Type, hasDefault bool) {
+	if !v.validateVariables(value, t, hasDefault) {
+		return
+	}
+	placeholders := make(map[string]any, len(v.variables))
+	for name := range v.variables {
+		placeholders[name] = placeholder{}
+	}
+	if _, _, err := coerceLiteral(value, t, placeholders); err != nil {
+		v.errorf(value.Loc, "invalid value: %v", err)
+	}
+}
+
+func (v *validation) validateVariables(value *Value, t Type, hasDefault bool) bool {
+	switch value.Kind {
+	case VariableValue:
+		def, ok := v.variables[value.Raw]
+		if !ok {
+			v.errorf(value.Loc, "variable $%v is not defined", value.Raw)
+			return false
+		}
+		if varType := v.schema.typeOf(def.Type); varType != nil && !variableAllowed(varType, def.Default != nil, t, hasDefault) {
+			v.errorf(value.Loc, "variable $%v of type %v can't be used as %v", value.Raw, def.Type, t)
+			return false
+		}
+	case ListValue:
+		elem := t
+		if nonNull, ok := elem.(*NonNull); ok {
+			elem = nonNull.OfType
+		}
+		if list, ok := elem.(*List); ok {
+			elem = list.OfType
+		}
+		for _, item := range value.List {
+			if !v.validateVariables(item, elem, false) {
+				return false
+			}
+		}
+	case ObjectValue:
+		object, ok := namedType(t).(*InputObject)
+		if !ok {
+			return true
+		}
+		for _, field := range value.Fields {
+			if def := object.field(field.Name); def != nil && !v.validateVariables(field.Value, def.Type, def.Default != nil) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// variableAllowed reports whether a variable of varType can be used where locationType is expected,
+// a nullable variable fits a non null position when one of them has a default
+func variableAllowed(varType Type, varDefault bool, locationType Type, locationDefault bool) bool {
+	if nonNull, ok := locationType.(*NonNull); ok && !isNonNull(varType) {
+		if !varDefault && !locationDefault {
+			return false
+		}
+		locationType = nonNull.OfType
+	}
+	return isSubtype(varType, locationType)
+}
+
+func isSubtype(sub, super Type) bool {
+	if superNonNull, ok := super.(*NonNull); ok {
+		subNonNull, ok := sub.(*NonNull)
+		return ok && isSubtype(subNonNull.OfType, superNonNull.OfType)
+	}
+	if subNonNull, ok := sub.(*NonNull); ok {
+		return isSubtype(subNonNull.OfType, super)
+	}
+	if superList, ok := super.(*List); ok {
+		subList, ok := sub.(*List)
+		return ok && isSubtype(subList.OfType, superList.OfType)
+	}
+	if _, ok := sub.(*List); ok {
+		return false
+	}
+	return sub == super
+}
+
+// validateMerging checks that fields with the same response key select the same field with the same arguments
+func (v *validation) validateMerging(parent *Object, selections []Selection) {
+	seen := make(map[string]*FieldSelection)
+	var check func(selections []Selection, visited map[string]bool)
+	check = func(selections []Selection, visited map[string]bool) {
+		for _, selection := range selections {
+			switch selection := selection.(type) {
+			case *FieldSelection:
+				key := selection.ResponseKey()
+				other, ok := seen[key]
+				if !ok {
+					seen[key] = selection
+					continue
+				}
+				if other.Name != selection.Name || printArguments(other.Arguments) != printArguments(selection.Arguments) {
+					v.errorf(selection.Loc, "fields %q conflict because they select different fields or arguments, use aliases", key)
+				}
+			case *InlineFragment:
+				if selection.TypeCondition == "" || selection.TypeCondition == parent.Name {
+					check(selection.Selections, visited)
+				}
+			case *FragmentSpread:
+				fragment, ok := v.doc.Fragments[selection.Name]
+				if ok && !visited[selection.Name] && fragment.TypeCondition == parent.Name {
+					visited[selection.Name] = true
+					check(fragment.Selections, visited)
+				}
+			}
+		}
+	}
+	check(selections, make(map[string]bool))
+}
+
+// operationVariables returns the names of the variables used in the selections and the fragments they spread
+func (v *validation) operationVariables(selections []Selection, visited map[string]bool) map[string]bool {
+	used := make(map[string]bool)
+	var walkValue func(value *Value)
+	walkValue = func(value *Value) {
+		switch value.Kind {
+		case VariableValue:
+			used[value.Raw] = true
+		case ListValue:
+			for _, item := range value.List {
+				walkValue(item)
+			}
+		case ObjectValue:
+			for _, field := range value.Fields {
+				walkValue(field.Value)
+			}
+		}
+	}
+	walkDirectives := func(directives []*Directive) {
+		for _, directive := range directives {
+			for _, argument := range directive.Arguments {
+				walkValue(argument.Value)
+			}
+		}
+	}
+
+	var walk func(selections []Selection)
+	walk = func(selections []Selection) {
+		for _, selection := range selections {
+			switch selection := selection.(type) {
+			case *FieldSelection:
+				for _, argument := range selection.Arguments {
+					walkValue(argument.Value)
+				}
+				walkDirectives(selection.Directives)
+				walk(selection.Selections)
+			case *InlineFragment:
+				walkDirectives(selection.Directives)
+				walk(selection.Selections)
+			case *FragmentSpread:
+				walkDirectives(selection.Directives)
+				if fragment, ok := v.doc.Fragments[selection.Name]; ok && !visited[selection.Name] {
+					visited[selection.Name] = true
+					walk(fragment.Selections)
+				}
+			}
+		}
+	}
+	walk(selections)
+	return used
+}
+
+// printArguments prints arguments in a canonical form, so equal arguments print the same
+func printArguments(arguments []*ArgumentNode) string {
+	printed := make([]string, 0, len(arguments))
+	for _, argument := range arguments {
+		printed = append(printed, argument.Name+":"+printValue(argument.Value))
+	}
+	slices.Sort(printed)
+	return strings.Join(printed, ",")
+}
+
+func printValue(value *Value) string {
+	switch value.Kind {
+	case VariableValue:
+		return "$" + value.Raw
+	case StringValue:
+		return fmt.Sprintf("%q", value.Raw)
+	case ListValue:
+		items := make([]string, 0, len(value.List))
+		for _, item := range value.List {
+			items = append(items, printValue(item))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case ObjectValue:
+		fields := make([]string, 0, len(value.Fields))
+		for _, field := range value.Fields {
+			fields = append(fields, field.Name+":"+printValue(field.Value))
+		}
+		return "{" + strings.Join(fields, ",") + "}"
+	default:
+		return value.Raw
+	}
+}
@@ -0,0 +1,245 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// coerceLiteral converts a value of the query to the input type with the coerced variables.
+// present is false when the value is a variable that wasn't provided.
+func coerceLiteral(value *Value, t Type, vars map[string]any) (any, bool, error) {
+	if value.Kind == VariableValue {
+		v, ok := vars[value.Raw]
+		if !ok {
+			return nil, false, nil
+		}
+		if v == nil && isNonNull(t) {
+			return nil, true, fmt.Errorf("variable $%v of non-null type %v must not be null", value.Raw, t)
+		}
+		return v, true, nil
+	}
+
+	if nonNull, ok := t.(*NonNull); ok {
+		if value.Kind == NullValue {
+			return nil, true, fmt.Errorf("expected a value of type %v, found null", t)
+		}
+		return coerceLiteral(value, nonNull.OfType, vars)
+	}
+	if value.Kind == NullValue {
+		return nil, true, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		if value.Kind != ListValue {
+			item, _, err := coerceLiteral(value, t.OfType, vars)
+			if err != nil {
+				return nil, true, err
+			}
+			return []any{item}, true, nil
+		}
+		list := make([]any, 0, len(value.List))
+		for i, item := range value.List {
+			coerced, _, err := coerceLiteral(item, t.OfType, vars)
+			if err != nil {
+				return nil, true, fmt.Errorf("at index %d: %w", i, err)
+			}
+			list = append(list, coerced)
+		}
+		return list, true, nil
+	case *InputObject:
+		if value.Kind != ObjectValue {
+			return nil, true, fmt.Errorf("expected an object of type %v", t)
+		}
+		for _, field := range value.Fields {
+			if t.field(field.Name) == nil {
+				return nil, true, fmt.Errorf("field %q isn't defined by type %v", field.Name, t)
+			}
+		}
+		object := make(map[string]any)
+		for _, def := range t.Fields {
+			var literal *Value
+			for _, field := range value.Fields {
+				if field.Name == def.Name {
+					literal = field.Value
+				}
+			}
+			if literal != nil {
+				coerced, present, err := coerceLiteral(literal, def.Type, vars)
+				if err != nil {
+					return nil, true, fmt.Errorf("field %q: %w", def.Name, err)
+				}
+				if present {
+					object[def.Name] = coerced
+					continue
+				}
+			}
+			if err := setDefault(object, def); err != nil {
+				return nil, true, err
+			}
+		}
+		return object, true, nil
+	case *Scalar:
+		var raw any
+		switch value.Kind {
+		case IntValue, FloatValue:
+			raw = json.Number(value.Raw)
+		case StringValue:
+			raw = value.Raw
+		case BooleanValue:
+			raw = value.Raw == "true"
+		default:
+			return nil, true, fmt.Errorf("expected a value of type %v", t)
+		}
+		parsed, err := t.Parse(raw)
+		return parsed, true, err
+	default:
+		return nil, true, fmt.Errorf("%v isn't an input type", t)
+	}
+}
+
+// coerceInput converts a json value, like the value of a variable, to the input type
+func coerceInput(value any, t Type) (any, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		if value == nil {
+			return nil, fmt.Errorf("expected a value of type %v, found null", t)
+		}
+		return coerceInput(value, nonNull.OfType)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		items, ok := value.([]any)
+		if !ok {
+			item, err := coerceInput(value, t.OfType)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+		list := make([]any, 0, len(items))
+		for i, item := range items {
+			coerced, err := coerceInput(item, t.OfType)
+			if err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+			list = append(list, coerced)
+		}
+		return list, nil
+	case *InputObject:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected an object of type %v", t)
+		}
+		for name := range fields {
+			if t.field(name) == nil {
+				return nil, fmt.Errorf("field %q isn't defined by type %v", name, t)
+			}
+		}
+		object := make(map[string]any)
+		for _, def := range t.Fields {
+			if field, ok := fields[def.Name]; ok {
+				coerced, err := coerceInput(field, def.Type)
+				if err != nil {
+					return nil, fmt.Errorf("field %q: %w", def.Name, err)
+				}
+				object[def.Name] = coerced
+				continue
+			}
+			if err := setDefault(object, def); err != nil {
+				return nil, err
+			}
+		}
+		return object, nil
+	case *Scalar:
+		return t.Parse(value)
+	default:
+		return nil, fmt.Errorf("%v isn't an input type", t)
+	}
+}
+
+// setDefault sets the default of a missing argument or input field, a required one without a default fails
+func setDefault(values map[string]any, def *Argument) error {
+	if def.Default != nil {
+		values[def.Name] = def.Default
+		return nil
+	}
+	if isNonNull(def.Type) {
+		return fmt.Errorf("argument %q of type %v is required", def.Name, def.Type)
+	}
+	return nil
+}
+
+// coerceArguments builds the args of a resolver
+func coerceArguments(defs []*Argument, nodes []*ArgumentNode, vars map[string]any) (map[string]any, error) {
+	args := make(map[string]any, len(defs))
+	for _, def := range defs {
+		for _, node := range nodes {
+			if node.Name != def.Name {
+				continue
+			}
+			value, present, err := coerceLiteral(node.Value, def.Type, vars)
+			if err != nil {
+				return nil, fmt.Errorf("argument %q: %w", def.Name, err)
+			}
+			if present {
+				args[def.Name] = value
+			}
+		}
+		if _, ok := args[def.Name]; ok {
+			continue
+		}
+		if err := setDefault(args, def); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// coerceVariables converts the provided variables to the types of their definitions
+func (s *Schema) coerceVariables(definitions []*VariableDefinition, provided map[string]any) (map[string]any, error) {
+	vars := make(map[string]any, len(definitions))
+	for _, def := range definitions {
+		t := s.typeOf(def.Type)
+		value, ok := provided[def.Name]
+		if !ok {
+			if def.Default != nil {
+				coerced, _, err := coerceLiteral(def.Default, t, nil)
+				if err != nil {
+					return nil, &Error{Message: fmt.Sprintf("variable $%v has an invalid default: %v", def.Name, err), Locations: []Location{def.Loc}}
+				}
+				vars[def.Name] = coerced
+			} else if def.Type.NonNull {
+				return nil, &Error{Message: fmt.Sprintf("variable $%v of type %v is required", def.Name, def.Type), Locations: []Location{def.Loc}}
+			}
+			continue
+		}
+		coerced, err := coerceInput(value, t)
+		if err != nil {
+			return nil, &Error{Message: fmt.Sprintf("variable $%v got an invalid value: %v", def.Name, err), Locations: []Location{def.Loc}}
+		}
+		vars[def.Name] = coerced
+	}
+	return vars, nil
+}
+
+// typeOf looks up the schema type of a type written in the query, it returns nil for unknown types
+func (s *Schema) typeOf(ref *TypeRef) Type {
+	var t Type
+	if ref.Elem != nil {
+		elem := s.typeOf(ref.Elem)
+		if elem == nil {
+			return nil
+		}
+		t = ListOf(elem)
+	} else if t = s.types[ref.Name]; t == nil {
+		return nil
+	}
+	if ref.NonNull {
+		t = NonNullOf(t)
+	}
+	return t
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strconv"
+)
+
+const auditHandlerName = "AuditHandler"
+
+var errInvalidAuditFilter = errors.New("invalid audit filter")
+
+type AuditUsecase interface {
+	History(ctx context.Context, taskId int, page model.Page) (dto.GetAuditResponse, error)
+	GetAll(ctx context.Context, filter model.AuditFilter, page model.Page) (dto.GetAuditResponse, error)
+	Verify(ctx context.Context) (dto.VerifyAuditResponse, error)
+}
+
+type AuditHandler struct {
+	auditUsecase AuditUsecase
+	logger       Logger
+	publicIds    bool
+}
+
+// AuditHandlerOption configures optional behaviour of AuditHandler
+type AuditHandlerOption func(*AuditHandler)
+
+// WithAuditPublicIds filters the audit log by public ids of tasks, the ones responses show
+func WithAuditPublicIds() AuditHandlerOption {
+	return func(ah *AuditHandler) {
+		ah.publicIds = true
+	}
+}
+
+func NewAuditHandler(logger Logger, auditUsecase AuditUsecase, opts ...AuditHandlerOption) (*AuditHandler, error) {
+	if auditUsecase == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", auditHandlerName)
+	}
+
+	ah := &AuditHandler{auditUsecase: auditUsecase, logger: logger}
+	for _, opt := range opts {
+		opt(ah)
+	}
+	return ah, nil
+}
+
+// HandleGetTaskHistory responds with a page of the changes of the task, oldest first
+func (ah *AuditHandler) HandleGetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	taskId, ok := idFromPath(ah.logger, w, r, "task_id")
+	if !ok {
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		respondWithError(ah.logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := ah.auditUsecase.History(r.Context(), taskId, page)
+	if err != nil {
+		ah.logger.Log("error in %v: %v", auditHandlerName, err)
+		respondWithUsecaseError(ah.logger, w, err, "task history", "failed to retrieve task history")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// HandleGetAudit responds with a page of the audit log of every project.
+// Entries are filtered with project_id, task_id, actor_id, action and an RFC 3339 from/to range.
+func (ah *AuditHandler) HandleGetAudit(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		respondWithError(ah.logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := parseAuditFilter(r, ah.publicIds)
+	if err != nil {
+		respondWithError(ah.logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := ah.auditUsecase.GetAll(r.Context(), filter, page)
+	if err != nil {
+		ah.logger.Log("error in %v: %v", auditHandlerName, err)
+		respondWithUsecaseError(ah.logger, w, err, "audit filter", "failed to retrieve audit log")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// HandleVerifyAudit recomputes the hash chain of the audit log
+func (ah *AuditHandler) HandleVerifyAudit(w http.ResponseWriter, r *http.Request) {
+	response, err := ah.auditUsecase.Verify(r.Context())
+	if err != nil {
+		ah.logger.Log("error in %v: %v", auditHandlerName, err)
+		respondWithUsecaseError(ah.logger, w, err, "audit log", "failed to verify audit log")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// parseAuditFilter builds model.AuditFilter from the query parameters of the request, task_id is a public id
+// with publicIds. Returned errors are safe to be shown to the client.
+func parseAuditFilter(r *http.Request, publicIds bool) (model.AuditFilter, error) {
+	queryParams := r.URL.Query()
+	filter := model.AuditFilter{Action: model.AuditAction(queryParams.Get("action"))}
+
+	ids := []struct {
+		name   string
+		target *int
+	}{
+		{"project_id", &filter.ProjectID},
+		{"actor_id", &filter.ActorID},
+	}
+	for _, id := range ids {
+		if value := queryParams.Get(id.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return model.AuditFilter{}, errInvalidAuditFilter
+			}
+			*id.target = parsed
+		}
+	}
+	if value := queryParams.Get("task_id"); value != "" && publicIds {
+		filter.TaskPublicID = value
+	} else if value != "" {
+		taskId, err := strconv.Atoi(value)
+		if err != nil {
+			return model.AuditFilter{}, errInvalidAuditFilter
+		}
+		filter.TaskID = &taskId
+	}
+
+	var err error
+	if filter.From, err = parseOptionalTime(queryParams.Get("from")); err != nil {
+		return model.AuditFilter{}, errInvalidAuditFilter
+	}
+	if filter.To, err = parseOptionalTime(queryParams.Get("to")); err != nil {
+		return model.AuditFilter{}, errInvalidAuditFilter
+	}
+	if err := model.ValidateAuditFilter(filter); err != nil {
+		return model.AuditFilter{}, errInvalidAuditFilter
+	}
+	return filter, nil
+}
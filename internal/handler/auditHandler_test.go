@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type MockAuditUsecase struct {
+	historyFunc func(ctx context.Context, taskId int, page model.Page) (dto.GetAuditResponse, error)
+	getAllFunc  func(ctx context.Context, filter model.AuditFilter, page model.Page) (dto.GetAuditResponse, error)
+	verifyFunc  func(ctx context.Context) (dto.VerifyAuditResponse, error)
+}
+
+func (m *MockAuditUsecase) History(ctx context.Context, taskId int, page model.Page) (dto.GetAuditResponse, error) {
+	return m.historyFunc(ctx, taskId, page)
+}
+
+func (m *MockAuditUsecase) GetAll(ctx context.Context, filter model.AuditFilter, page model.Page) (dto.GetAuditResponse, error) {
+	return m.getAllFunc(ctx, filter, page)
+}
+
+func (m *MockAuditUsecase) Verify(ctx context.Context) (dto.VerifyAuditResponse, error) {
+	return m.verifyFunc(ctx)
+}
+
+func TestHandleGetAudit(t *testing.T) {
+	taskId := 3
+	tests := []struct {
+		name           string
+		query          string
+		opts           []AuditHandlerOption
+		expectedFilter model.AuditFilter
+		expectedStatus int
+	}{
+		{name: "no filter", query: "", expectedStatus: http.StatusOK},
+		{
+			name:  "every filter",
+			query: "?project_id=2&task_id=3&actor_id=7&action=delete&from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z",
+			expectedFilter: model.AuditFilter{
+				ProjectID: 2,
+				TaskID:    &taskId,
+				ActorID:   7,
+				Action:    model.AuditDelete,
+				From:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:        time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "invalid task", query: "?task_id=abc", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "public task id",
+			query:          "?task_id=abc",
+			opts:           []AuditHandlerOption{WithAuditPublicIds()},
+			expectedFilter: model.AuditFilter{TaskPublicID: "abc"},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "unknown action", query: "?action=rename", expectedStatus: http.StatusBadRequest},
+		{name: "invalid time", query: "?from=yesterday", expectedStatus: http.StatusBadRequest},
+		{name: "reversed range", query: "?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter model.AuditFilter
+			mockUsecase := &MockAuditUsecase{
+				getAllFunc: func(ctx context.Context, f model.AuditFilter, page model.Page) (dto.GetAuditResponse, error) {
+					filter = f
+					return dto.GetAuditResponse{}, nil
+				},
+			}
+			handler, _ := NewAuditHandler(&MockLogger{}, mockUsecase, tt.opts...)
+
+			req := httptest.NewRequest("GET", "/audit"+tt.query, nil)
+			w := httptest.NewRecorder()
+			handler.HandleGetAudit(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && !reflect.DeepEqual(filter, tt.expectedFilter) {
+				t.Errorf("Expected filter %+v, got %+v", tt.expectedFilter, filter)
+			}
+		})
+	}
+}
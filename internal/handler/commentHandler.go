@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strconv"
+)
+
+const commentHandlerName = "CommentHandler"
+
+type CommentUsecase interface {
+	Store(ctx context.Context, taskId int, request dto.PostCommentRequest) (int, error)
+	GetByTaskId(ctx context.Context, taskId int, page model.Page) (dto.GetCommentsResponse, error)
+	Update(ctx context.Context, taskId, commentId int, request dto.PutCommentRequest) (dto.GetCommentByIdResponse, error)
+	Delete(ctx context.Context, taskId, commentId int) error
+}
+
+type CommentHandler struct {
+	commentUsecase CommentUsecase
+	logger         Logger
+}
+
+func NewCommentHandler(logger Logger, commentUsecase CommentUsecase) (*CommentHandler, error) {
+	if commentUsecase == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", commentHandlerName)
+	}
+
+	return &CommentHandler{commentUsecase, logger}, nil
+}
+
+func (ch *CommentHandler) HandlePostComment(w http.ResponseWriter, r *http.Request) {
+	taskId, ok := idFromPath(ch.logger, w, r, "task_id")
+	if !ok {
+		return
+	}
+
+	var postReq dto.PostCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&postReq); err != nil {
+		respondWithError(ch.logger, w, http.StatusBadRequest, "invalid data in comment")
+		return
+	}
+
+	id, err := ch.commentUsecase.Store(r.Context(), taskId, postReq)
+	if err != nil {
+		ch.logger.Log("error in %v: %v", commentHandlerName, err)
+		respondWithUsecaseError(ch.logger, w, err, "task or comment", "failed to store comment")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, id)
+}
+
+// HandleGetComments responds with a page of the comments of the task, oldest first.
+// The page is selected with limit and offset query parameters.
+func (ch *CommentHandler) HandleGetComments(w http.ResponseWriter, r *http.Request) {
+	taskId, ok := idFromPath(ch.logger, w, r, "task_id")
+	if !ok {
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		respondWithError(ch.logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := ch.commentUsecase.GetByTaskId(r.Context(), taskId, page)
+	if err != nil {
+		ch.logger.Log("error in %v: %v", commentHandlerName, err)
+		respondWithUsecaseError(ch.logger, w, err, "task", "failed to retrieve comments")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (ch *CommentHandler) HandlePutComment(w http.ResponseWriter, r *http.Request) {
+	taskId, commentId, ok := ch.commentIdsFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var putReq dto.PutCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&putReq); err != nil {
+		respondWithError(ch.logger, w, http.StatusBadRequest, "invalid data in comment")
+		return
+	}
+
+	response, err := ch.commentUsecase.Update(r.Context(), taskId, commentId, putReq)
+	if err != nil {
+		ch.logger.Log("error in %v: %v", commentHandlerName, err)
+		respondWithUsecaseError(ch.logger, w, err, "comment", "failed to update comment")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (ch *CommentHandler) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	taskId, commentId, ok := ch.commentIdsFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := ch.commentUsecase.Delete(r.Context(), taskId, commentId); err != nil {
+		ch.logger.Log("error in %v: %v", commentHandlerName, err)
+		respondWithUsecaseError(ch.logger, w, err, "comment", "failed to delete comment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ch *CommentHandler) commentIdsFromPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	taskId, ok := idFromPath(ch.logger, w, r, "task_id")
+	if !ok {
+		return 0, 0, false
+	}
+	commentId, ok := idFromPath(ch.logger, w, r, "comment_id")
+	if !ok {
+		return 0, 0, false
+	}
+	return taskId, commentId, true
+}
+
+// parsePage builds model.Page from limit and offset query parameters,
+// returned errors are safe to be shown to the client
+func parsePage(r *http.Request) (model.Page, error) {
+	queryParams := r.URL.Query()
+	page := model.DefaultPage
+
+	if limit := queryParams.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			return model.DefaultPage, errInvalidPage
+		}
+		page.Limit = parsed
+	}
+	if offset := queryParams.Get("offset"); offset != "" {
+		parsed, err := strconv.Atoi(offset)
+		if err != nil {
+			return model.DefaultPage, errInvalidPage
+		}
+		page.Offset = parsed
+	}
+	if err := model.ValidatePage(page); err != nil {
+		return model.DefaultPage, errInvalidPage
+	}
+	return page, nil
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockCommentUsecase struct {
+	storeFunc       func(ctx context.Context, taskId int, request dto.PostCommentRequest) (int, error)
+	getByTaskIdFunc func(ctx context.Context, taskId int, page model.Page) (dto.GetCommentsResponse, error)
+	updateFunc      func(ctx context.Context, taskId, commentId int, request dto.PutCommentRequest) (dto.GetCommentByIdResponse, error)
+	deleteFunc      func(ctx context.Context, taskId, commentId int) error
+}
+
+func (m *MockCommentUsecase) Store(ctx context.Context, taskId int, request dto.PostCommentRequest) (int, error) {
+	return m.storeFunc(ctx, taskId, request)
+}
+
+func (m *MockCommentUsecase) GetByTaskId(ctx context.Context, taskId int, page model.Page) (dto.GetCommentsResponse, error) {
+	return m.getByTaskIdFunc(ctx, taskId, page)
+}
+
+func (m *MockCommentUsecase) Update(ctx context.Context, taskId, commentId int, request dto.PutCommentRequest) (dto.GetCommentByIdResponse, error) {
+	return m.updateFunc(ctx, taskId, commentId, request)
+}
+
+func (m *MockCommentUsecase) Delete(ctx context.Context, taskId, commentId int) error {
+	return m.deleteFunc(ctx, taskId, commentId)
+}
+
+func TestHandleGetComments(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		usecaseError   error
+		expectedPage   model.Page
+		expectedStatus int
+	}{
+		{name: "default page", query: "", expectedPage: model.DefaultPage, expectedStatus: http.StatusOK},
+		{name: "explicit page", query: "?limit=5&offset=10", expectedPage: model.Page{Limit: 5, Offset: 10}, expectedStatus: http.StatusOK},
+		{name: "invalid limit", query: "?limit=abc", expectedStatus: http.StatusBadRequest},
+		{name: "too large limit", query: fmt.Sprintf("?limit=%d", model.MaxPageLimit+1), expectedStatus: http.StatusBadRequest},
+		{name: "negative offset", query: "?offset=-1", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "missing task",
+			usecaseError:   fmt.Errorf("storage: %w", model.ErrNotFound),
+			expectedPage:   model.DefaultPage,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page model.Page
+			mockUsecase := &MockCommentUsecase{
+				getByTaskIdFunc: func(ctx context.Context, taskId int, p model.Page) (dto.GetCommentsResponse, error) {
+					page = p
+					return dto.GetCommentsResponse{}, tt.usecaseError
+				},
+			}
+			handler, _ := NewCommentHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("GET", "/tasks/0/comments"+tt.query, nil)
+			req.SetPathValue("task_id", "0")
+			w := httptest.NewRecorder()
+			handler.HandleGetComments(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusBadRequest && page != tt.expectedPage {
+				t.Errorf("Expected page %v, got %v", tt.expectedPage, page)
+			}
+		})
+	}
+}
+
+func TestHandleDeleteComment(t *testing.T) {
+	tests := []struct {
+		name           string
+		commentId      string
+		usecaseError   error
+		expectedStatus int
+	}{
+		{name: "successful delete", commentId: "1", expectedStatus: http.StatusNoContent},
+		{name: "invalid comment id", commentId: "abc", expectedStatus: http.StatusBadRequest},
+		{name: "not the author", commentId: "1", usecaseError: fmt.Errorf("usecase: %w", model.ErrForbidden), expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockCommentUsecase{
+				deleteFunc: func(ctx context.Context, taskId, commentId int) error {
+					return tt.usecaseError
+				},
+			}
+			handler, _ := NewCommentHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("DELETE", "/tasks/0/comments/"+tt.commentId, nil)
+			req.SetPathValue("task_id", "0")
+			req.SetPathValue("comment_id", tt.commentId)
+			w := httptest.NewRecorder()
+			handler.HandleDeleteComment(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
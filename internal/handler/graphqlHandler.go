@@ -0,0 +1,531 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/graphql"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"net/http"
+	"time"
+)
+
+const graphqlHandlerName = "GraphQLHandler"
+
+const (
+	// DefaultMaxQueryDepth and DefaultMaxQueryComplexity limit graphql queries unless WithQueryLimits is used
+	DefaultMaxQueryDepth      = 10
+	DefaultMaxQueryComplexity = 1000
+	// relatedTasksCost is the assumed amount of blockers and subtasks of a task when the complexity of a query is counted
+	relatedTasksCost = 10
+	// maxGraphQLRequestSize limits the body of graphql requests
+	maxGraphQLRequestSize = 1 << 20
+)
+
+// graphqlErrorCodes are the codes in the extensions of graphql errors, by the http status of the error
+var graphqlErrorCodes = map[int]string{
+	http.StatusBadRequest:     "BAD_USER_INPUT",
+	http.StatusUnauthorized:   "UNAUTHENTICATED",
+	http.StatusForbidden:      "FORBIDDEN",
+	http.StatusNotFound:       "NOT_FOUND",
+	http.StatusConflict:       "CONFLICT",
+	http.StatusNotImplemented: "NOT_SUPPORTED",
+}
+
+// GraphQLHandler serves queries of tasks with their related tasks and mutations of tasks,
+// see HandleGetSchema for the schema
+type GraphQLHandler struct {
+	taskUsecase   TaskUsecase
+	logger        Logger
+	schema        *graphql.Schema
+	maxDepth      int
+	maxComplexity int
+	// publicIds is nil when tasks are referred to by their ids
+	publicIds PublicIdResolver
+	clock     clock.Clock
+}
+
+// GraphQLHandlerOption configures optional behaviour of GraphQLHandler
+type GraphQLHandlerOption func(*GraphQLHandler)
+
+// WithQueryLimits sets the maximum depth and complexity of queries, 0 disables a limit
+func WithQueryLimits(maxDepth, maxComplexity int) GraphQLHandlerOption {
+	return func(gh *GraphQLHandler) {
+		gh.maxDepth = maxDepth
+		gh.maxComplexity = maxComplexity
+	}
+}
+
+// WithGraphQLPublicIds makes ids of tasks in the schema IDs holding public ids
+func WithGraphQLPublicIds(resolver PublicIdResolver) GraphQLHandlerOption {
+	return func(gh *GraphQLHandler) {
+		gh.publicIds = resolver
+	}
+}
+
+// WithGraphQLClock replaces the system clock due dates of tasks are checked against
+func WithGraphQLClock(clock clock.Clock) GraphQLHandlerOption {
+	return func(gh *GraphQLHandler) {
+		gh.clock = clock
+	}
+}
+
+func NewGraphQLHandler(logger Logger, taskUsecase TaskUsecase, opts ...GraphQLHandlerOption) (*GraphQLHandler, error) {
+	if taskUsecase == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", graphqlHandlerName)
+	}
+
+	gh := &GraphQLHandler{taskUsecase: taskUsecase, logger: logger, maxDepth: DefaultMaxQueryDepth, maxComplexity: DefaultMaxQueryComplexity,
+		clock: clock.System{}}
+	for _, opt := range opts {
+		opt(gh)
+	}
+	if gh.maxDepth < 0 || gh.maxComplexity < 0 {
+		return nil, fmt.Errorf("%v: query limits can't be negative", graphqlHandlerName)
+	}
+	schema, err := gh.taskSchema()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", graphqlHandlerName, err)
+	}
+	gh.schema = schema
+	return gh, nil
+}
+
+// HandleGraphQL executes the query of the request. Errors of the query are in the errors of the response,
+// it's 200 unless the request itself is malformed.
+func (gh *GraphQLHandler) HandleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var request dto.GraphQLRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestSize))
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil || request.Query == "" {
+		gh.logger.Log("error in %v: invalid graphql request: %v", graphqlHandlerName, err)
+		respondWithJSON(w, http.StatusBadRequest, &graphql.Result{Errors: []*graphql.Error{{Message: "invalid graphql request"}}})
+		return
+	}
+
+	ctx := withTaskLoader(r.Context(), newTaskLoader(gh.taskUsecase, gh.clock.Now()))
+	result := gh.schema.Execute(ctx, graphql.Params{
+		Query:         request.Query,
+		OperationName: request.OperationName,
+		Variables:     request.Variables,
+		MaxDepth:      gh.maxDepth,
+		MaxComplexity: gh.maxComplexity,
+	})
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// HandleGetSchema responds with the schema in the schema definition language
+func (gh *GraphQLHandler) HandleGetSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(gh.schema.String()))
+}
+
+// usecaseError logs the error and converts it to a graphql error with the message the rest api would respond with
+func (gh *GraphQLHandler) usecaseError(err error, entity, fallback string) error {
+	gh.logger.Log("error in %v: %v", graphqlHandlerName, err)
+	status, message := usecaseErrorStatus(err, entity, fallback)
+	code, ok := graphqlErrorCodes[status]
+	if !ok {
+		code = "INTERNAL_SERVER_ERROR"
+	}
+	return graphql.Errorf(code, "%s", message)
+}
+
+// taskPage is the source of the TaskPage type
+type taskPage struct {
+	total   int
+	tasks   []dto.GetTaskByIdResponse
+	hasMore bool
+}
+
+var timeScalar = &graphql.Scalar{
+	Name:        "Time",
+	Description: "RFC 3339 timestamp",
+	Serialize: func(value any) (any, error) {
+		t, ok := value.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("Time can't represent %v", value)
+		}
+		if t.IsZero() {
+			return nil, nil
+		}
+		return t.Format(time.RFC3339Nano), nil
+	},
+	Parse: func(value any) (any, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("Time can't represent %v", value)
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("Time must be an RFC 3339 timestamp")
+		}
+		return t, nil
+	},
+}
+
+// taskSchema builds the schema, queries and mutations go through the same TaskUsecase calls as the rest api
+func (gh *GraphQLHandler) taskSchema() (*graphql.Schema, error) {
+	taskId := graphql.Int
+	if gh.publicIds != nil {
+		taskId = graphql.ID
+	}
+	task := &graphql.Object{Name: "Task"}
+	task.Fields = []*graphql.Field{
+		taskField("id", graphql.NonNullOf(taskId), gh.taskId),
+		taskField("name", graphql.NonNullOf(graphql.String), func(t dto.GetTaskByIdResponse) any { return t.Name }),
+		taskField("description", graphql.NonNullOf(graphql.String), func(t dto.GetTaskByIdResponse) any { return t.Description }),
+		taskField("status", graphql.NonNullOf(graphql.String), func(t dto.GetTaskByIdResponse) any { return t.Status }),
+		taskField("priority", graphql.NonNullOf(graphql.String), func(t dto.GetTaskByIdResponse) any { return t.Priority }),
+		taskField("assigneeId", graphql.Int, func(t dto.GetTaskByIdResponse) any { return optionalUser(t.AssigneeID) }),
+		taskField("reporterId", graphql.Int, func(t dto.GetTaskByIdResponse) any { return optionalUser(t.ReporterID) }),
+		taskField("tagIds", graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(graphql.Int))), func(t dto.GetTaskByIdResponse) any {
+			return append([]int{}, t.TagIDs...)
+		}),
+		taskField("parentId", taskId, gh.parentId),
+		taskField("dueAt", timeScalar, func(t dto.GetTaskByIdResponse) any { return t.DueAt }),
+		taskField("overdue", graphql.NonNullOf(graphql.Boolean), func(t dto.GetTaskByIdResponse) any { return t.Overdue }),
+		taskField("createdAt", graphql.NonNullOf(timeScalar), func(t dto.GetTaskByIdResponse) any { return t.CreatedAt }),
+		taskField("startedAt", timeScalar, func(t dto.GetTaskByIdResponse) any { return t.StartedAt }),
+		taskField("completedAt", timeScalar, func(t dto.GetTaskByIdResponse) any { return t.CompletedAt }),
+		{
+			Name:        "parent",
+			Description: "the task this one is a subtask of",
+			Type:        task,
+			Batch:       gh.batchParents,
+		},
+		{
+			Name:        "blockedBy",
+			Description: "the tasks blocking this one",
+			Type:        graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(task))),
+			Batch:       gh.batchBlockers,
+			Cost:        relatedTasksCostOf,
+		},
+		{
+			Name:        "subtasks",
+			Description: "the direct subtasks of this task",
+			Type:        graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(task))),
+			Batch:       gh.batchSubtasks,
+			Cost:        relatedTasksCostOf,
+		},
+	}
+
+	page := &graphql.Object{Name: "TaskPage", Fields: []*graphql.Field{
+		{Name: "total", Description: "the amount of tasks matching the filter", Type: graphql.NonNullOf(graphql.Int),
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return source.(taskPage).total, nil
+			}},
+		{Name: "hasMore", Type: graphql.NonNullOf(graphql.Boolean),
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return source.(taskPage).hasMore, nil
+			}},
+		{Name: "tasks", Type: graphql.NonNullOf(graphql.ListOf(graphql.NonNullOf(task))),
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return source.(taskPage).tasks, nil
+			}},
+	}}
+
+	filter := &graphql.InputObject{Name: "TaskFilter", Fields: []*graphql.Argument{
+		{Name: "status", Type: graphql.String},
+		{Name: "priority", Type: graphql.String},
+		{Name: "assigneeId", Type: graphql.Int},
+		{Name: "overdue", Type: graphql.Boolean},
+		{Name: "dueBefore", Type: timeScalar},
+		{Name: "dueAfter", Type: timeScalar},
+		{Name: "tags", Type: graphql.ListOf(graphql.NonNullOf(graphql.String))},
+		{Name: "tagMatch", Description: "any or all", Type: graphql.String},
+		{Name: "sortBy", Type: graphql.String},
+		{Name: "descending", Type: graphql.Boolean},
+	}}
+
+	createInput := &graphql.InputObject{Name: "CreateTaskInput", Fields: []*graphql.Argument{
+		{Name: "name", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "status", Type: graphql.NonNullOf(graphql.String)},
+		{Name: "description", Type: graphql.String},
+		{Name: "priority", Type: graphql.String},
+		{Name: "assigneeId", Type: graphql.Int},
+		{Name: "parentId", Type: taskId},
+		{Name: "dueAt", Type: timeScalar},
+		{Name: "allowPastDue", Type: graphql.Boolean},
+	}}
+
+	query := &graphql.Object{Name: "Query", Fields: []*graphql.Field{
+		{
+			Name:    "task",
+			Type:    task,
+			Args:    []*graphql.Argument{{Name: "id", Type: graphql.NonNullOf(taskId)}},
+			Resolve: gh.resolveTask,
+		},
+		{
+			Name: "tasks",
+			Type: graphql.NonNullOf(page),
+			Args: []*graphql.Argument{
+				{Name: "filter", Type: filter},
+				{Name: "limit", Type: graphql.Int, Default: model.DefaultPageLimit},
+				{Name: "offset", Type: graphql.Int, Default: 0},
+			},
+			Resolve: gh.resolveTasks,
+			Cost: func(args map[string]any, childComplexity int) int {
+				limit, _ := args["limit"].(int)
+				return 1 + max(limit, 0)*childComplexity
+			},
+		},
+	}}
+
+	mutation := &graphql.Object{Name: "Mutation", Fields: []*graphql.Field{
+		{
+			Name:    "createTask",
+			Type:    graphql.NonNullOf(task),
+			Args:    []*graphql.Argument{{Name: "input", Type: graphql.NonNullOf(createInput)}},
+			Resolve: gh.resolveCreateTask,
+		},
+		{
+			Name: "setTaskStatus",
+			Type: graphql.NonNullOf(task),
+			Args: []*graphql.Argument{
+				{Name: "id", Type: graphql.NonNullOf(taskId)},
+				{Name: "status", Type: graphql.NonNullOf(graphql.String)},
+			},
+			Resolve: gh.resolveSetTaskStatus,
+		},
+	}}
+
+	return graphql.NewSchema(query, mutation)
+}
+
+func taskField(name string, t graphql.Type, value func(dto.GetTaskByIdResponse) any) *graphql.Field {
+	return &graphql.Field{
+		Name: name,
+		Type: t,
+		Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			return value(source.(dto.GetTaskByIdResponse)), nil
+		},
+	}
+}
+
+// taskId is the id of the task as clients see it, the public one when tasks have public ids
+func (gh *GraphQLHandler) taskId(t dto.GetTaskByIdResponse) any {
+	if gh.publicIds == nil {
+		return t.Id
+	}
+	return t.PublicIds().Id
+}
+
+func (gh *GraphQLHandler) parentId(t dto.GetTaskByIdResponse) any {
+	switch {
+	case t.ParentID == nil:
+		return nil
+	case gh.publicIds == nil:
+		return *t.ParentID
+	default:
+		return *t.PublicIds().ParentID
+	}
+}
+
+// taskIdArg returns the id of the task an id argument refers to
+func (gh *GraphQLHandler) taskIdArg(ctx context.Context, arg any) (int, error) {
+	if gh.publicIds == nil {
+		return arg.(int), nil
+	}
+	taskId, err := gh.publicIds.ResolvePublicId(ctx, arg.(string))
+	if err != nil {
+		return 0, gh.usecaseError(err, "task", "failed to resolve task")
+	}
+	return taskId, nil
+}
+
+func optionalUser(userId int) any {
+	if userId == model.NoUser {
+		return nil
+	}
+	return userId
+}
+
+func relatedTasksCostOf(args map[string]any, childComplexity int) int {
+	return 1 + relatedTasksCost*childComplexity
+}
+
+func (gh *GraphQLHandler) resolveTask(ctx context.Context, source any, args map[string]any) (any, error) {
+	taskId, err := gh.taskIdArg(ctx, args["id"])
+	if err != nil {
+		return nil, err
+	}
+	task, err := gh.taskUsecase.GetByTaskId(ctx, taskId)
+	if err != nil {
+		return nil, gh.usecaseError(err, "task", "failed to retrieve task")
+	}
+	taskLoaderFrom(ctx).prime(task)
+	return task, nil
+}
+
+func (gh *GraphQLHandler) resolveTasks(ctx context.Context, source any, args map[string]any) (any, error) {
+	filter := graphqlFilter(args["filter"])
+	filter.Page = model.Page{Limit: args["limit"].(int), Offset: args["offset"].(int)}
+	if err := model.ValidatePage(filter.Page); err != nil {
+		return nil, graphql.Errorf(graphqlErrorCodes[http.StatusBadRequest], "%v", err)
+	}
+	if err := model.ValidateFilter(filter); err != nil {
+		return nil, graphql.Errorf(graphqlErrorCodes[http.StatusBadRequest], "%v", err)
+	}
+
+	tasks, err := gh.taskUsecase.GetAll(ctx, filter)
+	if err != nil {
+		return nil, gh.usecaseError(err, "task", "failed to retrieve tasks")
+	}
+
+	ans := taskPage{
+		total:   tasks.Total,
+		hasMore: filter.Page.Offset+len(tasks.Tasks) < tasks.Total,
+		tasks:   make([]dto.GetTaskByIdResponse, 0, len(tasks.Tasks)),
+	}
+	loader := taskLoaderFrom(ctx)
+	for _, task := range tasks.Tasks {
+		ans.tasks = append(ans.tasks, mapper.TaskToGetTaskByIdReponse(task, loader.now))
+	}
+	loader.prime(ans.tasks...)
+	return ans, nil
+}
+
+func (gh *GraphQLHandler) resolveCreateTask(ctx context.Context, source any, args map[string]any) (any, error) {
+	input := args["input"].(map[string]any)
+	request := dto.PostTaskRequest{
+		Name:   input["name"].(string),
+		Status: model.TaskStatus(input["status"].(string)),
+	}
+	request.Description, _ = input["description"].(string)
+	if priority, ok := input["priority"].(string); ok {
+		request.Priority = model.TaskPriority(priority)
+	}
+	request.AssigneeID, _ = input["assigneeId"].(int)
+	switch parentId := input["parentId"].(type) {
+	case int:
+		request.ParentID = &dto.TaskRef{Id: parentId}
+	case string:
+		request.ParentID = &dto.TaskRef{PublicID: parentId}
+	}
+	request.DueAt, _ = input["dueAt"].(time.Time)
+	request.AllowPastDue, _ = input["allowPastDue"].(bool)
+
+	id, err := gh.taskUsecase.Store(ctx, request)
+	if err != nil {
+		return nil, gh.usecaseError(err, "task", "failed to store task")
+	}
+	task, err := gh.taskUsecase.GetByTaskId(ctx, id)
+	if err != nil {
+		return nil, gh.usecaseError(err, "task", "failed to retrieve task")
+	}
+	taskLoaderFrom(ctx).prime(task)
+	return task, nil
+}
+
+func (gh *GraphQLHandler) resolveSetTaskStatus(ctx context.Context, source any, args map[string]any) (any, error) {
+	taskId, err := gh.taskIdArg(ctx, args["id"])
+	if err != nil {
+		return nil, err
+	}
+	status := model.TaskStatus(args["status"].(string))
+	task, err := gh.taskUsecase.Update(ctx, taskId, dto.PatchTaskRequest{Status: &status})
+	if err != nil {
+		return nil, gh.usecaseError(err, "task", "failed to update task")
+	}
+	taskLoaderFrom(ctx).prime(task)
+	return task, nil
+}
+
+func (gh *GraphQLHandler) batchParents(ctx context.Context, sources []any, args map[string]any) ([]any, error) {
+	var ids []int
+	for _, source := range sources {
+		if parentId := source.(dto.GetTaskByIdResponse).ParentID; parentId != nil {
+			ids = append(ids, *parentId)
+		}
+	}
+	parents, err := taskLoaderFrom(ctx).load(ctx, ids)
+	if err != nil {
+		return nil, gh.usecaseError(err, "task", "failed to retrieve parent tasks")
+	}
+
+	ans := make([]any, len(sources))
+	for i, source := range sources {
+		if parentId := source.(dto.GetTaskByIdResponse).ParentID; parentId != nil {
+			if parent, ok := parents[*parentId]; ok {
+				ans[i] = parent
+			}
+		}
+	}
+	return ans, nil
+}
+
+func (gh *GraphQLHandler) batchBlockers(ctx context.Context, sources []any, args map[string]any) ([]any, error) {
+	var ids []int
+	for _, source := range sources {
+		ids = append(ids, source.(dto.GetTaskByIdResponse).BlockedBy...)
+	}
+	blockers, err := taskLoaderFrom(ctx).load(ctx, ids)
+	if err != nil {
+		return nil, gh.usecaseError(err, "task", "failed to retrieve blocking tasks")
+	}
+
+	ans := make([]any, len(sources))
+	for i, source := range sources {
+		tasks := make([]dto.GetTaskByIdResponse, 0)
+		for _, id := range source.(dto.GetTaskByIdResponse).BlockedBy {
+			if blocker, ok := blockers[id]; ok {
+				tasks = append(tasks, blocker)
+			}
+		}
+		ans[i] = tasks
+	}
+	return ans, nil
+}
+
+func (gh *GraphQLHandler) batchSubtasks(ctx context.Context, sources []any, args map[string]any) ([]any, error) {
+	ids := make([]int, 0, len(sources))
+	for _, source := range sources {
+		ids = append(ids, source.(dto.GetTaskByIdResponse).Id)
+	}
+	subtasks, err := taskLoaderFrom(ctx).children(ctx, ids)
+	if err != nil {
+		return nil, gh.usecaseError(err, "task", "failed to retrieve subtasks")
+	}
+
+	ans := make([]any, len(sources))
+	for i, id := range ids {
+		ans[i] = subtasks[id]
+	}
+	return ans, nil
+}
+
+// graphqlFilter builds the filter from the TaskFilter argument the way GET /tasks parses its query params,
+// it isn't validated
+func graphqlFilter(arg any) model.Filter {
+	fields, _ := arg.(map[string]any)
+	var filter model.Filter
+	if status, ok := fields["status"].(string); ok {
+		filter.Status = model.TaskStatus(status)
+	}
+	if priority, ok := fields["priority"].(string); ok {
+		filter.Priority = model.TaskPriority(priority)
+	}
+	filter.AssigneeID, _ = fields["assigneeId"].(int)
+	filter.Overdue, _ = fields["overdue"].(bool)
+	filter.DueBefore, _ = fields["dueBefore"].(time.Time)
+	filter.DueAfter, _ = fields["dueAfter"].(time.Time)
+	tags, _ := fields["tags"].([]any)
+	for _, tag := range tags {
+		if name := model.NormalizeTagName(tag.(string)); name != "" {
+			filter.Tags = append(filter.Tags, name)
+		}
+	}
+	if tagMatch, ok := fields["tagMatch"].(string); ok {
+		filter.TagMatch = model.TagMatch(tagMatch)
+	}
+	if sortBy, ok := fields["sortBy"].(string); ok {
+		filter.SortBy = model.SortField(sortBy)
+	}
+	filter.Descending, _ = fields["descending"].(bool)
+	return filter
+}
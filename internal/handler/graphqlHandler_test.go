@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+// usecaseCalls counts the calls of the task usecase made by a query
+type usecaseCalls struct {
+	GetAll      int `json:"getAll"`
+	GetByTaskId int `json:"getByTaskId"`
+	Export      int `json:"export"`
+	Store       int `json:"store"`
+	Update      int `json:"update"`
+}
+
+// newGraphQLFixture returns a usecase over a small tree of tasks:
+// 1 has subtasks 2 and 3, 2 has subtasks 4 and 5, 4 is blocked by 3 and 5 is blocked by 3 and 4
+func newGraphQLFixture() (*MockTaskUsecase, *usecaseCalls) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	// 4 is overdue and 5 isn't
+	now := created.AddDate(1, 0, 0)
+	parent := func(id int) *int { return &id }
+	tasks := []model.Task{
+		{Id: 1, Name: "Release", Description: "ship v2", Status: model.InProgress, Priority: model.PriorityHigh, ReporterID: 1, CreatedAt: created, StartedAt: created.Add(time.Hour)},
+		{Id: 2, Name: "Backend", Status: model.InProgress, Priority: model.PriorityMedium, AssigneeID: 2, TagIDs: []int{1, 2}, ParentID: parent(1), CreatedAt: created},
+		{Id: 3, Name: "Schema", Status: model.Done, Priority: model.PriorityLow, ParentID: parent(1), CreatedAt: created, CompletedAt: created.Add(48 * time.Hour)},
+		{Id: 4, Name: "Handlers", Status: model.Created, Priority: model.PriorityMedium, ParentID: parent(2), BlockedBy: []int{3}, DueAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), CreatedAt: created},
+		{Id: 5, Name: "Tests", Status: model.Created, Priority: model.PriorityMedium, ParentID: parent(2), BlockedBy: []int{3, 4}, DueAt: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC), CreatedAt: created},
+	}
+	find := func(id int) (int, bool) {
+		for i, task := range tasks {
+			if task.Id == id {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	calls := &usecaseCalls{}
+	return &MockTaskUsecase{
+		getAllFunc: func(ctx context.Context, filter model.Filter) (dto.GetAllTasksResponse, error) {
+			calls.GetAll++
+			var ans []model.Task
+			for _, task := range tasks {
+				if filter.Matches(task, time.Now()) {
+					ans = append(ans, task)
+				}
+			}
+			return pagedResponse(ans, filter), nil
+		},
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error) {
+			calls.GetByTaskId++
+			i, ok := find(taskId)
+			if !ok {
+				return dto.GetTaskByIdResponse{}, fmt.Errorf("get task: %w", model.ErrNotFound)
+			}
+			return mapper.TaskToGetTaskByIdReponse(tasks[i], now), nil
+		},
+		exportFunc: func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+			calls.Export++
+			for _, task := range tasks {
+				if err := fn(task); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		storeFunc: func(ctx context.Context, request dto.PostTaskRequest) (int, error) {
+			calls.Store++
+			var parentId *int
+			if request.ParentID != nil {
+				if _, ok := find(request.ParentID.Id); !ok {
+					return 0, fmt.Errorf("store task: parent: %w", model.ErrNotFound)
+				}
+				parentId = &request.ParentID.Id
+			}
+			task := model.Task{Id: len(tasks) + 1, Name: request.Name, Description: request.Description, Status: request.Status,
+				Priority: request.Priority, ParentID: parentId, DueAt: request.DueAt, CreatedAt: created}
+			tasks = append(tasks, task)
+			return task.Id, nil
+		},
+		updateFunc: func(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error) {
+			calls.Update++
+			i, ok := find(taskId)
+			if !ok {
+				return dto.GetTaskByIdResponse{}, fmt.Errorf("update task: %w", model.ErrNotFound)
+			}
+			if status := *request.Status; status != model.Created && status != model.InProgress && status != model.Done {
+				return dto.GetTaskByIdResponse{}, fmt.Errorf("update task: unknown status %q: %w", *request.Status, model.ErrInvalid)
+			}
+			tasks[i].Status = *request.Status
+			return mapper.TaskToGetTaskByIdReponse(tasks[i], now), nil
+		},
+	}, calls
+}
+
+// TestGraphQLGolden runs the queries of testdata/graphql/*.graphql and compares the responses and the
+// usecase calls with the .json files next to them. A "# variables: {...}" line sets the variables of a query.
+// Run with -update to rewrite the golden files.
+func TestGraphQLGolden(t *testing.T) {
+	queries, err := filepath.Glob(filepath.Join("testdata", "graphql", "*.graphql"))
+	if err != nil || len(queries) == 0 {
+		t.Fatalf("No golden queries found: %v", err)
+	}
+
+	for _, path := range queries {
+		name := strings.TrimSuffix(filepath.Base(path), ".graphql")
+		t.Run(name, func(t *testing.T) {
+			query, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile failed: %v", err)
+			}
+			request := dto.GraphQLRequest{Query: string(query)}
+			for _, line := range strings.Split(request.Query, "\n") {
+				if variables, ok := strings.CutPrefix(line, "# variables: "); ok {
+					if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+						t.Fatalf("Invalid variables: %v", err)
+					}
+				}
+			}
+			body, _ := json.Marshal(request)
+
+			mockUsecase, calls := newGraphQLFixture()
+			handler, _ := NewGraphQLHandler(&MockLogger{}, mockUsecase, WithQueryLimits(5, 500))
+			rr := httptest.NewRecorder()
+			handler.HandleGraphQL(rr, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			golden := struct {
+				Response json.RawMessage `json:"response"`
+				Calls    *usecaseCalls   `json:"calls"`
+			}{Response: bytes.TrimSpace(rr.Body.Bytes()), Calls: calls}
+			actual, err := json.MarshalIndent(golden, "", "  ")
+			if err != nil {
+				t.Fatalf("MarshalIndent failed: %v", err)
+			}
+			actual = append(actual, '\n')
+
+			goldenPath := strings.TrimSuffix(path, ".graphql") + ".json"
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, actual, 0o644); err != nil {
+					t.Fatalf("WriteFile failed: %v", err)
+				}
+			}
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("ReadFile failed: %v", err)
+			}
+			if !bytes.Equal(expected, actual) {
+				t.Errorf("Response differs from %v\nexpected:\n%s\ngot:\n%s", goldenPath, expected, actual)
+			}
+		})
+	}
+}
+
+func TestGraphQLPublicIds(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		// the fixture has no public ids, so tasks are shown by their ids in decimal
+		{name: "task by public id", query: `{ task(id: "b") { id parentId } }`, expected: `{"data":{"task":{"id":"2","parentId":"1"}}}`},
+		{name: "ids are strings in lists", query: `{ tasks(filter: {status: "done"}) { tasks { id } } }`, expected: `{"data":{"tasks":{"tasks":[{"id":"3"}]}}}`},
+		{name: "unknown public id", query: `{ task(id: "c") { id } }`, expected: `"message":"task not found"`},
+		{name: "forbidden public id", query: `{ task(id: "secret") { id } }`, expected: `"message":"forbidden"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase, _ := newGraphQLFixture()
+			handler, _ := NewGraphQLHandler(&MockLogger{}, mockUsecase, WithGraphQLPublicIds(MockPublicIdResolver{}))
+			body, _ := json.Marshal(dto.GraphQLRequest{Query: tt.query})
+			rr := httptest.NewRecorder()
+			handler.HandleGraphQL(rr, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+			if !strings.Contains(rr.Body.String(), tt.expected) {
+				t.Errorf("Expected %v in the response, got %v", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleGraphQLInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed json", body: `{"query": `},
+		{name: "missing query", body: `{"variables": {}}`},
+		{name: "oversized body", body: `{"query": "` + strings.Repeat(" ", maxGraphQLRequestSize) + `{ task(id: 1) { id } }"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase, _ := newGraphQLFixture()
+			handler, _ := NewGraphQLHandler(&MockLogger{}, mockUsecase)
+			rr := httptest.NewRecorder()
+			handler.HandleGraphQL(rr, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.body)))
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+			if body := strings.TrimSpace(rr.Body.String()); body != `{"errors":[{"message":"invalid graphql request"}]}` {
+				t.Errorf("Unexpected body %v", body)
+			}
+		})
+	}
+}
+
+func TestNewGraphQLHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		taskUsecase TaskUsecase
+		opts        []GraphQLHandlerOption
+		expectedErr bool
+	}{
+		{name: "successful creation", taskUsecase: &MockTaskUsecase{}},
+		{name: "nil usecase", expectedErr: true},
+		{name: "negative limits", taskUsecase: &MockTaskUsecase{}, opts: []GraphQLHandlerOption{WithQueryLimits(-1, 0)}, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewGraphQLHandler(&MockLogger{}, tt.taskUsecase, tt.opts...)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if !tt.expectedErr && handler == nil {
+				t.Error("Expected handler, got nil")
+			}
+		})
+	}
+}
+
+func TestHandleGetSchema(t *testing.T) {
+	handler, _ := NewGraphQLHandler(&MockLogger{}, &MockTaskUsecase{})
+	rr := httptest.NewRecorder()
+	handler.HandleGetSchema(rr, httptest.NewRequest(http.MethodGet, "/graphql/schema", nil))
+
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("Unexpected response %d %v", rr.Code, rr.Header())
+	}
+	for _, expected := range []string{"type Query {", "tasks(filter: TaskFilter, limit: Int = 20, offset: Int = 0): TaskPage!", "type Mutation {", "input CreateTaskInput {", "scalar Time"} {
+		if !strings.Contains(rr.Body.String(), expected) {
+			t.Errorf("Expected the schema to contain %q, got\n%s", expected, rr.Body.String())
+		}
+	}
+}
+
+func TestTaskLoaderErrors(t *testing.T) {
+	storageErr := errors.New("storage is down")
+	mockUsecase := &MockTaskUsecase{
+		exportFunc: func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+			return storageErr
+		},
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error) {
+			return dto.GetTaskByIdResponse{}, storageErr
+		},
+	}
+	loader := newTaskLoader(mockUsecase, time.Now())
+
+	if _, err := loader.load(context.Background(), []int{1}); !errors.Is(err, storageErr) {
+		t.Errorf("Expected %v, got %v", storageErr, err)
+	}
+	if _, err := loader.load(context.Background(), []int{1, 2}); !errors.Is(err, storageErr) {
+		t.Errorf("Expected %v, got %v", storageErr, err)
+	}
+	if _, err := loader.children(context.Background(), []int{1}); !errors.Is(err, storageErr) {
+		t.Errorf("Expected %v, got %v", storageErr, err)
+	}
+}
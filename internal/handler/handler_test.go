@@ -11,6 +11,7 @@ import (
 	"ivanjabrony/test_lo/internal/model/dto"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -20,6 +21,19 @@ type MockTaskUsecase struct {
 	storeFunc       func(ctx context.Context, request dto.PostTaskRequest) (int, error)
 	getAllFunc      func(ctx context.Context, filter model.Filter) (dto.GetAllTasksResponse, error)
 	getByTaskIdFunc func(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error)
+	getAsOfFunc     func(ctx context.Context, taskId int, asOf time.Time) (dto.GetTaskByIdResponse, error)
+	exportFunc      func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error
+	importFunc      func(ctx context.Context, rows []dto.ImportTaskRow, dryRun bool) (dto.ImportTasksResponse, error)
+	updateFunc      func(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error)
+	deleteFunc      func(ctx context.Context, taskId int) error
+	blockerFunc     func(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error)
+	getSubtasksFunc func(ctx context.Context, taskId, depth int) (dto.GetSubtasksResponse, error)
+	dependencyFunc  func(ctx context.Context, taskId int) (dto.GetDependencyOrderResponse, error)
+	watchFunc       func(ctx context.Context, filter model.Filter, lastEventID int) (dto.TaskChangeStream, error)
+}
+
+func (m *MockTaskUsecase) Watch(ctx context.Context, filter model.Filter, lastEventID int) (dto.TaskChangeStream, error) {
+	return m.watchFunc(ctx, filter, lastEventID)
 }
 
 func (m *MockTaskUsecase) Store(ctx context.Context, request dto.PostTaskRequest) (int, error) {
@@ -30,10 +44,58 @@ func (m *MockTaskUsecase) GetAll(ctx context.Context, filter model.Filter) (dto.
 	return m.getAllFunc(ctx, filter)
 }
 
+// pagedResponse is the response of the usecase with the page of the tasks the filter asks for
+func pagedResponse(tasks []model.Task, filter model.Filter) dto.GetAllTasksResponse {
+	if !filter.Paged() {
+		return dto.GetAllTasksResponse{Amount: len(tasks), Tasks: tasks}
+	}
+	start := min(filter.Page.Offset, len(tasks))
+	end := min(start+filter.Page.Limit, len(tasks))
+	return dto.GetAllTasksResponse{
+		Amount: end - start, Tasks: tasks[start:end], Total: len(tasks), Limit: filter.Page.Limit, Offset: filter.Page.Offset,
+	}
+}
+
 func (m *MockTaskUsecase) GetByTaskId(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error) {
 	return m.getByTaskIdFunc(ctx, taskId)
 }
 
+func (m *MockTaskUsecase) GetByTaskIdAsOf(ctx context.Context, taskId int, asOf time.Time) (dto.GetTaskByIdResponse, error) {
+	return m.getAsOfFunc(ctx, taskId, asOf)
+}
+
+func (m *MockTaskUsecase) Export(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+	return m.exportFunc(ctx, filter, fn)
+}
+
+func (m *MockTaskUsecase) Import(ctx context.Context, rows []dto.ImportTaskRow, dryRun bool) (dto.ImportTasksResponse, error) {
+	return m.importFunc(ctx, rows, dryRun)
+}
+
+func (m *MockTaskUsecase) Update(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error) {
+	return m.updateFunc(ctx, taskId, request)
+}
+
+func (m *MockTaskUsecase) Delete(ctx context.Context, taskId int) error {
+	return m.deleteFunc(ctx, taskId)
+}
+
+func (m *MockTaskUsecase) AddBlocker(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error) {
+	return m.blockerFunc(ctx, taskId, blockerId)
+}
+
+func (m *MockTaskUsecase) RemoveBlocker(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error) {
+	return m.blockerFunc(ctx, taskId, blockerId)
+}
+
+func (m *MockTaskUsecase) GetSubtasks(ctx context.Context, taskId, depth int) (dto.GetSubtasksResponse, error) {
+	return m.getSubtasksFunc(ctx, taskId, depth)
+}
+
+func (m *MockTaskUsecase) DependencyOrder(ctx context.Context, taskId int) (dto.GetDependencyOrderResponse, error) {
+	return m.dependencyFunc(ctx, taskId)
+}
+
 type MockLogger struct {
 	logs []string
 }
@@ -113,6 +175,18 @@ func TestHandlePostTask(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   map[string]string{"error": "invalid data in task"},
 		},
+		{
+			name: "nonexistent assignee",
+			requestBody: dto.PostTaskRequest{
+				Name:       "Test Task",
+				Status:     model.Created,
+				AssigneeID: 7,
+			},
+			usecaseReturn:  -1,
+			usecaseError:   fmt.Errorf("usecase: %w", model.ErrInvalid),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   map[string]string{"error": "invalid data in task"},
+		},
 		{
 			name: "usecase error",
 			requestBody: dto.PostTaskRequest{
@@ -193,6 +267,8 @@ func TestHandleGetAllTasks(t *testing.T) {
 		usecaseError   error
 		expectedStatus int
 		expectedLength int
+		expectedPage   model.Page
+		expectedError  string
 	}{
 		{
 			name:           "successful get all",
@@ -217,6 +293,16 @@ func TestHandleGetAllTasks(t *testing.T) {
 			usecaseError:   nil,
 			expectedStatus: http.StatusBadRequest,
 			expectedLength: 0,
+			expectedError:  "invalid task status in filter",
+		},
+		{
+			name:           "invalid assignee filter",
+			queryParams:    map[string]string{"assignee": "-1"},
+			usecaseReturn:  dto.GetAllTasksResponse{},
+			usecaseError:   nil,
+			expectedStatus: http.StatusBadRequest,
+			expectedLength: 0,
+			expectedError:  "invalid assignee in filter",
 		},
 		{
 			name:           "usecase error",
@@ -225,6 +311,29 @@ func TestHandleGetAllTasks(t *testing.T) {
 			usecaseError:   errors.New("usecase error"),
 			expectedStatus: http.StatusInternalServerError,
 			expectedLength: 0,
+			expectedError:  "failed to retrieve tasks",
+		},
+		{
+			name:           "page",
+			queryParams:    map[string]string{"limit": "1", "offset": "1"},
+			usecaseReturn:  dto.GetAllTasksResponse{Amount: 2, Tasks: testTasks},
+			expectedStatus: http.StatusOK,
+			expectedLength: 1,
+			expectedPage:   model.Page{Limit: 1, Offset: 1},
+		},
+		{
+			name:           "page past the end",
+			queryParams:    map[string]string{"offset": "5"},
+			usecaseReturn:  dto.GetAllTasksResponse{Amount: 2, Tasks: testTasks},
+			expectedStatus: http.StatusOK,
+			expectedLength: 0,
+			expectedPage:   model.Page{Limit: model.DefaultPageLimit, Offset: 5},
+		},
+		{
+			name:           "invalid page",
+			queryParams:    map[string]string{"limit": "1000"},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid limit or offset",
 		},
 	}
 
@@ -233,7 +342,10 @@ func TestHandleGetAllTasks(t *testing.T) {
 			mockLogger := &MockLogger{}
 			mockUsecase := &MockTaskUsecase{
 				getAllFunc: func(ctx context.Context, filter model.Filter) (dto.GetAllTasksResponse, error) {
-					return tt.usecaseReturn, tt.usecaseError
+					if filter.Page != tt.expectedPage {
+						t.Errorf("Expected page %+v, got %+v", tt.expectedPage, filter.Page)
+					}
+					return pagedResponse(tt.usecaseReturn.Tasks, filter), tt.usecaseError
 				},
 			}
 
@@ -266,14 +378,14 @@ func TestHandleGetAllTasks(t *testing.T) {
 				if response.Amount != tt.expectedLength {
 					t.Errorf("Expected %d tasks, got %d", tt.expectedLength, response.Amount)
 				}
-			} else if tt.expectedStatus == http.StatusBadRequest {
+			} else {
 				var errorResponse map[string]string
 				if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
 					t.Fatalf("Failed to decode error response: %v", err)
 				}
 
-				if errorResponse["error"] != "invalid task status in filter" {
-					t.Errorf("Unexpected error message: %s", errorResponse["error"])
+				if errorResponse["error"] != tt.expectedError {
+					t.Errorf("Expected error '%s', got '%s'", tt.expectedError, errorResponse["error"])
 				}
 			}
 		})
@@ -328,6 +440,14 @@ func TestHandleGetTaskById(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedError:  "failed to retrieve task",
 		},
+		{
+			name:           "nonexistent task",
+			path:           "/tasks/99",
+			usecaseReturn:  dto.GetTaskByIdResponse{},
+			usecaseError:   fmt.Errorf("storage: %w", model.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "task not found",
+		},
 	}
 
 	for _, tt := range tests {
@@ -391,3 +511,341 @@ func TestHandleGetTaskById(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleGetTaskByIdAsOf(t *testing.T) {
+	asOf := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		usecaseError   error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "past state",
+			query:          "?as_of=2025-03-01T12:00:00Z",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid moment",
+			query:          "?as_of=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid as_of, expected RFC3339 time",
+		},
+		{
+			name:           "task didn't exist",
+			query:          "?as_of=2025-03-01T12:00:00Z",
+			usecaseError:   fmt.Errorf("storage: %w", model.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "task not found",
+		},
+		{
+			name:           "storage without history",
+			query:          "?as_of=2025-03-01T12:00:00Z",
+			usecaseError:   fmt.Errorf("usecase: %w", model.ErrUnsupported),
+			expectedStatus: http.StatusNotImplemented,
+			expectedError:  "not supported by the task storage",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockTaskUsecase{
+				getByTaskIdFunc: func(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error) {
+					t.Error("current state was requested")
+					return dto.GetTaskByIdResponse{}, nil
+				},
+				getAsOfFunc: func(ctx context.Context, taskId int, got time.Time) (dto.GetTaskByIdResponse, error) {
+					if taskId != 42 || !got.Equal(asOf) {
+						t.Errorf("Expected task 42 as of %v, got %d as of %v", asOf, taskId, got)
+					}
+					return dto.GetTaskByIdResponse{Id: 42}, tt.usecaseError
+				},
+			}
+			handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("GET", "/tasks/42"+tt.query, nil)
+			req.SetPathValue("task_id", "42")
+			w := httptest.NewRecorder()
+
+			handler.HandleGetTaskById(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedError != "" {
+				var errorResponse map[string]string
+				if err := json.Unmarshal(w.Body.Bytes(), &errorResponse); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if errorResponse["error"] != tt.expectedError {
+					t.Errorf("Expected error %q, got %q", tt.expectedError, errorResponse["error"])
+				}
+			}
+		})
+	}
+}
+
+func TestHandlePatchTask(t *testing.T) {
+	tests := []struct {
+		name           string
+		taskId         string
+		body           string
+		usecaseError   error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "successful patch",
+			taskId:         "1",
+			body:           `{"status": "done"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "malformed body",
+			taskId:         "1",
+			body:           `{"status": `,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid data in task",
+		},
+		{
+			name:           "invalid status",
+			taskId:         "1",
+			body:           `{"status": "unknown"}`,
+			usecaseError:   fmt.Errorf("usecase: %w", model.ErrInvalid),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid data in task",
+		},
+		{
+			name:           "forbidden",
+			taskId:         "1",
+			body:           `{"name": "new"}`,
+			usecaseError:   fmt.Errorf("usecase: %w", model.ErrForbidden),
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+		},
+		{
+			name:           "hidden",
+			taskId:         "1",
+			body:           `{"name": "new"}`,
+			usecaseError:   fmt.Errorf("usecase: %w", model.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "task not found",
+		},
+		{
+			name:           "unauthenticated",
+			taskId:         "1",
+			body:           `{"name": "new"}`,
+			usecaseError:   fmt.Errorf("usecase: %w", model.ErrUnauthenticated),
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockTaskUsecase{
+				updateFunc: func(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error) {
+					if tt.usecaseError != nil {
+						return dto.GetTaskByIdResponse{}, tt.usecaseError
+					}
+					return dto.GetTaskByIdResponse{Id: taskId, Status: *request.Status}, nil
+				},
+			}
+			handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("PATCH", "/tasks/"+tt.taskId, strings.NewReader(tt.body))
+			req.SetPathValue("task_id", tt.taskId)
+			w := httptest.NewRecorder()
+			handler.HandlePatchTask(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedError != "" {
+				var errorResponse map[string]string
+				if err := json.NewDecoder(w.Body).Decode(&errorResponse); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if errorResponse["error"] != tt.expectedError {
+					t.Errorf("Expected error '%s', got '%s'", tt.expectedError, errorResponse["error"])
+				}
+			}
+		})
+	}
+}
+
+func TestHandleDeleteTask(t *testing.T) {
+	mockUsecase := &MockTaskUsecase{
+		deleteFunc: func(ctx context.Context, taskId int) error {
+			switch taskId {
+			case 1:
+				return nil
+			case 2:
+				return fmt.Errorf("usecase: %w", model.ErrForbidden)
+			default:
+				return fmt.Errorf("usecase: %w", model.ErrNotFound)
+			}
+		},
+	}
+	handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+	for taskId, expectedStatus := range map[string]int{
+		"1":   http.StatusNoContent,
+		"2":   http.StatusForbidden,
+		"3":   http.StatusNotFound,
+		"abc": http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("DELETE", "/tasks/"+taskId, nil)
+		req.SetPathValue("task_id", taskId)
+		w := httptest.NewRecorder()
+		handler.HandleDeleteTask(w, req)
+
+		if w.Code != expectedStatus {
+			t.Errorf("Expected status %d for task %s, got %d", expectedStatus, taskId, w.Code)
+		}
+	}
+}
+
+// MockPublicIdResolver knows public ids "a" and "b" of tasks 1 and 2, "secret" is forbidden
+type MockPublicIdResolver struct{}
+
+func (MockPublicIdResolver) ResolvePublicId(ctx context.Context, publicId string) (int, error) {
+	switch publicId {
+	case "a":
+		return 1, nil
+	case "b":
+		return 2, nil
+	case "secret":
+		return -1, fmt.Errorf("usecase: %w", model.ErrForbidden)
+	}
+	return -1, fmt.Errorf("usecase: %w", model.ErrNotFound)
+}
+
+func TestResolvePublicIds(t *testing.T) {
+	mockUsecase := &MockTaskUsecase{
+		blockerFunc: func(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error) {
+			return dto.GetTaskByIdResponse{Id: taskId, BlockedBy: []int{blockerId}}, nil
+		},
+	}
+	plain, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+	public, _ := NewTaskHandler(&MockLogger{}, mockUsecase, WithPublicIds(MockPublicIdResolver{}))
+
+	tests := []struct {
+		name           string
+		handler        *TaskHandler
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "public ids", handler: public, path: "/tasks/a/blockers/b", expectedStatus: http.StatusOK, expectedBody: `"blocked_by":[2]`},
+		{name: "ids aren't public ids", handler: public, path: "/tasks/1/blockers/b", expectedStatus: http.StatusNotFound},
+		{name: "unknown blocker", handler: public, path: "/tasks/a/blockers/c", expectedStatus: http.StatusNotFound},
+		{name: "forbidden task", handler: public, path: "/tasks/secret/blockers/a", expectedStatus: http.StatusForbidden},
+		{name: "without public ids", handler: plain, path: "/tasks/1/blockers/2", expectedStatus: http.StatusOK, expectedBody: `"blocked_by":[2]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /tasks/{task_id}/blockers/{blocker_id}", tt.handler.ResolvePublicIds(tt.handler.HandleAddBlocker))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, tt.path, nil))
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body with %s, got %s", tt.expectedBody, w.Body)
+			}
+		})
+	}
+
+	t.Run("post responds with the public id", func(t *testing.T) {
+		mockUsecase := &MockTaskUsecase{
+			storeFunc: func(ctx context.Context, request dto.PostTaskRequest) (int, error) { return 1, nil },
+			getByTaskIdFunc: func(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error) {
+				return dto.GetTaskByIdResponse{Id: taskId, PublicID: "a"}, nil
+			},
+		}
+		handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase, WithPublicIds(MockPublicIdResolver{}))
+		body, _ := json.Marshal(dto.PostTaskRequest{Name: "Task", Status: model.Created})
+		w := httptest.NewRecorder()
+		handler.HandlePostTask(w, httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewReader(body)))
+		if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `"a"` {
+			t.Errorf("Expected the public id, got %d: %s", w.Code, w.Body)
+		}
+	})
+}
+
+func TestParseFilter(t *testing.T) {
+	dueAfter := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	dueBefore := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected model.Filter
+		wantErr  error
+	}{
+		{
+			name:     "priority and overdue",
+			query:    "priority=urgent&overdue=true",
+			expected: model.Filter{Priority: model.PriorityUrgent, Overdue: true},
+		},
+		{
+			name:     "due range",
+			query:    "due_after=2025-06-01T00:00:00Z&due_before=2025-07-01T00:00:00Z",
+			expected: model.Filter{DueAfter: dueAfter, DueBefore: dueBefore},
+		},
+		{
+			name:     "descending sort",
+			query:    "sort=-due_at",
+			expected: model.Filter{SortBy: model.SortByDueAt, Descending: true},
+		},
+		{name: "unknown priority", query: "priority=critical", wantErr: errInvalidPriorityFilter},
+		{name: "malformed overdue", query: "overdue=maybe", wantErr: errInvalidOverdueFilter},
+		{name: "recurring", query: "recurring=true", expected: model.Filter{Recurring: true}},
+		{name: "malformed recurring", query: "recurring=often", wantErr: errInvalidRecurringFilter},
+		{name: "malformed due date", query: "due_before=tomorrow", wantErr: errInvalidDueFilter},
+		{
+			name:    "inverted due range",
+			query:   "due_after=2025-07-01T00:00:00Z&due_before=2025-06-01T00:00:00Z",
+			wantErr: errInvalidDueFilter,
+		},
+		{
+			name:     "creation range",
+			query:    "created_after=2025-06-01T00:00:00Z&created_before=2025-07-01T00:00:00Z",
+			expected: model.Filter{CreatedAfter: dueAfter, CreatedBefore: dueBefore},
+		},
+		{name: "malformed creation time", query: "created_after=yesterday", wantErr: errInvalidCreatedFilter},
+		{
+			name:    "inverted creation range",
+			query:   "created_after=2025-07-01T00:00:00Z&created_before=2025-06-01T00:00:00Z",
+			wantErr: errInvalidCreatedFilter,
+		},
+		{name: "unknown sort field", query: "sort=name", wantErr: errInvalidSort},
+		{
+			name:     "tags",
+			query:    "tags=Backend,,bug&tag_match=all",
+			expected: model.Filter{Tags: []string{"backend", "bug"}, TagMatch: model.TagMatchAll},
+		},
+		{name: "empty tags", query: "tags=,", wantErr: errInvalidTagsFilter},
+		{name: "unknown tag match", query: "tags=bug&tag_match=some", wantErr: errInvalidTagsFilter},
+	}
+
+	handler, _ := NewTaskHandler(&MockLogger{}, &MockTaskUsecase{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/tasks?"+tt.query, nil)
+			filter, err := handler.parseFilter(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && !reflect.DeepEqual(filter, tt.expected) {
+				t.Errorf("Expected filter %+v, got %+v", tt.expected, filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+)
+
+const notificationHandlerName = "NotificationHandler"
+
+type NotificationUsecase interface {
+	GetPreferences(ctx context.Context, userId int) (dto.GetNotificationPreferencesResponse, error)
+	PutPreferences(ctx context.Context, userId int, request dto.PutNotificationPreferencesRequest) (dto.GetNotificationPreferencesResponse, error)
+}
+
+type NotificationHandler struct {
+	notificationUsecase NotificationUsecase
+	logger              Logger
+}
+
+func NewNotificationHandler(logger Logger, notificationUsecase NotificationUsecase) (*NotificationHandler, error) {
+	if notificationUsecase == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", notificationHandlerName)
+	}
+
+	return &NotificationHandler{notificationUsecase, logger}, nil
+}
+
+func (nh *NotificationHandler) HandleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	userId, ok := userIdFromPath(nh.logger, w, r)
+	if !ok {
+		return
+	}
+
+	response, err := nh.notificationUsecase.GetPreferences(r.Context(), userId)
+	if err != nil {
+		nh.logger.Log("error in %v: %v", notificationHandlerName, err)
+		respondWithUsecaseError(nh.logger, w, err, "notification preferences", "failed to retrieve notification preferences")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (nh *NotificationHandler) HandlePutPreferences(w http.ResponseWriter, r *http.Request) {
+	userId, ok := userIdFromPath(nh.logger, w, r)
+	if !ok {
+		return
+	}
+
+	var putReq dto.PutNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&putReq); err != nil {
+		respondWithError(nh.logger, w, http.StatusBadRequest, "invalid data in notification preferences")
+		return
+	}
+
+	response, err := nh.notificationUsecase.PutPreferences(r.Context(), userId, putReq)
+	if err != nil {
+		nh.logger.Log("error in %v: %v", notificationHandlerName, err)
+		respondWithUsecaseError(nh.logger, w, err, "notification preferences", "failed to update notification preferences")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockNotificationUsecase struct {
+	putFunc func(ctx context.Context, userId int, request dto.PutNotificationPreferencesRequest) (dto.GetNotificationPreferencesResponse, error)
+}
+
+func (m *MockNotificationUsecase) GetPreferences(ctx context.Context, userId int) (dto.GetNotificationPreferencesResponse, error) {
+	return dto.GetNotificationPreferencesResponse{UserID: userId}, nil
+}
+
+func (m *MockNotificationUsecase) PutPreferences(ctx context.Context, userId int, request dto.PutNotificationPreferencesRequest) (dto.GetNotificationPreferencesResponse, error) {
+	return m.putFunc(ctx, userId, request)
+}
+
+func TestHandlePutPreferences(t *testing.T) {
+	tests := []struct {
+		name           string
+		userId         string
+		body           string
+		usecaseError   error
+		expectedStatus int
+		wantChannels   []model.NotificationChannel
+	}{
+		{name: "updated", userId: "2", body: `{"channels":["email"],"muted":["stale"]}`, expectedStatus: http.StatusOK, wantChannels: []model.NotificationChannel{model.ChannelEmail}},
+		{name: "default channels", userId: "2", body: `{}`, expectedStatus: http.StatusOK, wantChannels: nil},
+		{name: "turned off", userId: "2", body: `{"channels":[]}`, expectedStatus: http.StatusOK, wantChannels: []model.NotificationChannel{}},
+		{name: "invalid id", userId: "abc", body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "malformed body", userId: "2", body: `{"channels":`, expectedStatus: http.StatusBadRequest},
+		{name: "unavailable channel", userId: "2", body: `{"channels":["email"]}`, usecaseError: fmt.Errorf("usecase: %w", model.Invalid(fmt.Errorf("unavailable"))), expectedStatus: http.StatusBadRequest},
+		{name: "another user", userId: "3", body: `{}`, usecaseError: model.ErrForbidden, expectedStatus: http.StatusForbidden},
+		{name: "nonexistent user", userId: "99", body: `{}`, usecaseError: fmt.Errorf("storage: %w", model.ErrNotFound), expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored dto.PutNotificationPreferencesRequest
+			mockUsecase := &MockNotificationUsecase{
+				putFunc: func(ctx context.Context, userId int, request dto.PutNotificationPreferencesRequest) (dto.GetNotificationPreferencesResponse, error) {
+					stored = request
+					return dto.GetNotificationPreferencesResponse{UserID: userId}, tt.usecaseError
+				},
+			}
+			handler, _ := NewNotificationHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("PUT", "/users/"+tt.userId+"/notifications", strings.NewReader(tt.body))
+			req.SetPathValue("user_id", tt.userId)
+			w := httptest.NewRecorder()
+			handler.HandlePutPreferences(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && (stored.Channels == nil) != (tt.wantChannels == nil) {
+				t.Errorf("Expected channels %#v, got %#v", tt.wantChannels, stored.Channels)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/tenant"
+	"net/http"
+	"strconv"
+)
+
+const projectHandlerName = "ProjectHandler"
+
+type ProjectUsecase interface {
+	Store(ctx context.Context, request dto.PostProjectRequest) (int, error)
+	GetAll(ctx context.Context) (dto.GetAllProjectsResponse, error)
+	GetByProjectId(ctx context.Context, projectId int) (dto.GetProjectByIdResponse, error)
+	Update(ctx context.Context, projectId int, request dto.PatchProjectRequest) (dto.GetProjectByIdResponse, error)
+	Scope(ctx context.Context, projectId int) (tenant.Scope, error)
+}
+
+type ProjectHandler struct {
+	projectUsecase ProjectUsecase
+	logger         Logger
+}
+
+func NewProjectHandler(logger Logger, projectUsecase ProjectUsecase) (*ProjectHandler, error) {
+	if projectUsecase == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", projectHandlerName)
+	}
+
+	return &ProjectHandler{projectUsecase, logger}, nil
+}
+
+func (ph *ProjectHandler) HandlePostProject(w http.ResponseWriter, r *http.Request) {
+	var postReq dto.PostProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&postReq); err != nil {
+		respondWithError(ph.logger, w, http.StatusBadRequest, "invalid data in project")
+		return
+	}
+
+	id, err := ph.projectUsecase.Store(r.Context(), postReq)
+	if err != nil {
+		ph.logger.Log("error in %v: %v", projectHandlerName, err)
+		respondWithUsecaseError(ph.logger, w, err, "project", "failed to store project")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, id)
+}
+
+func (ph *ProjectHandler) HandleGetAllProjects(w http.ResponseWriter, r *http.Request) {
+	response, err := ph.projectUsecase.GetAll(r.Context())
+	if err != nil {
+		ph.logger.Log("error in %v: %v", projectHandlerName, err)
+		respondWithUsecaseError(ph.logger, w, err, "project", "failed to retrieve projects")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (ph *ProjectHandler) HandleGetProjectById(w http.ResponseWriter, r *http.Request) {
+	projectId, ok := ph.projectIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := ph.projectUsecase.GetByProjectId(r.Context(), projectId)
+	if err != nil {
+		respondWithUsecaseError(ph.logger, w, err, "project", "failed to retrieve project")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (ph *ProjectHandler) HandlePatchProject(w http.ResponseWriter, r *http.Request) {
+	projectId, ok := ph.projectIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var patchReq dto.PatchProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&patchReq); err != nil {
+		respondWithError(ph.logger, w, http.StatusBadRequest, "invalid data in project")
+		return
+	}
+
+	response, err := ph.projectUsecase.Update(r.Context(), projectId, patchReq)
+	if err != nil {
+		ph.logger.Log("error in %v: %v", projectHandlerName, err)
+		respondWithUsecaseError(ph.logger, w, err, "project", "failed to update project")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// Scoped limits next to the project from the project_id path value,
+// a nonexistent project responds with 404 before next is called
+func (ph *ProjectHandler) Scoped(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectId, ok := ph.projectIdFromPath(w, r)
+		if !ok {
+			return
+		}
+		ph.serveScoped(w, r, projectId, next)
+	}
+}
+
+// DefaultScoped limits next to the default project, it's used by the routes that aren't nested under /projects
+func (ph *ProjectHandler) DefaultScoped(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ph.serveScoped(w, r, model.DefaultProjectId, next)
+	}
+}
+
+func (ph *ProjectHandler) serveScoped(w http.ResponseWriter, r *http.Request, projectId int, next http.HandlerFunc) {
+	scope, err := ph.projectUsecase.Scope(r.Context(), projectId)
+	if err != nil {
+		respondWithUsecaseError(ph.logger, w, err, "project", "failed to resolve project")
+		return
+	}
+	next(w, r.WithContext(tenant.WithScope(r.Context(), scope)))
+}
+
+// projectIdFromPath parses project_id path value and responds with an error if it's invalid
+func (ph *ProjectHandler) projectIdFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	projectIdParam := r.PathValue("project_id")
+	if projectIdParam == "" {
+		respondWithError(ph.logger, w, http.StatusBadRequest, "project_id wasn't provided")
+		return 0, false
+	}
+
+	projectId, err := strconv.Atoi(projectIdParam)
+	if err != nil {
+		respondWithError(ph.logger, w, http.StatusBadRequest, "invalid project_id parameter")
+		return 0, false
+	}
+	return projectId, true
+}
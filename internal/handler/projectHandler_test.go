@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/tenant"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockProjectUsecase struct {
+	storeFunc          func(ctx context.Context, request dto.PostProjectRequest) (int, error)
+	getAllFunc         func(ctx context.Context) (dto.GetAllProjectsResponse, error)
+	getByProjectIdFunc func(ctx context.Context, projectId int) (dto.GetProjectByIdResponse, error)
+	updateFunc         func(ctx context.Context, projectId int, request dto.PatchProjectRequest) (dto.GetProjectByIdResponse, error)
+	scopeFunc          func(ctx context.Context, projectId int) (tenant.Scope, error)
+}
+
+func (m *MockProjectUsecase) Store(ctx context.Context, request dto.PostProjectRequest) (int, error) {
+	return m.storeFunc(ctx, request)
+}
+
+func (m *MockProjectUsecase) GetAll(ctx context.Context) (dto.GetAllProjectsResponse, error) {
+	return m.getAllFunc(ctx)
+}
+
+func (m *MockProjectUsecase) GetByProjectId(ctx context.Context, projectId int) (dto.GetProjectByIdResponse, error) {
+	return m.getByProjectIdFunc(ctx, projectId)
+}
+
+func (m *MockProjectUsecase) Update(ctx context.Context, projectId int, request dto.PatchProjectRequest) (dto.GetProjectByIdResponse, error) {
+	return m.updateFunc(ctx, projectId, request)
+}
+
+func (m *MockProjectUsecase) Scope(ctx context.Context, projectId int) (tenant.Scope, error) {
+	return m.scopeFunc(ctx, projectId)
+}
+
+func TestHandlePostProject(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		usecaseError   error
+		expectedStatus int
+	}{
+		{
+			name:           "successful post",
+			body:           `{"name": "Team A", "task_quota": 10}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "malformed body",
+			body:           `{"name": `,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid project",
+			body:           `{"name": ""}`,
+			usecaseError:   fmt.Errorf("usecase: %w", model.ErrInvalid),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockProjectUsecase{
+				storeFunc: func(ctx context.Context, request dto.PostProjectRequest) (int, error) {
+					return 2, tt.usecaseError
+				},
+			}
+			handler, _ := NewProjectHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("POST", "/projects", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.HandlePostProject(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestScoped(t *testing.T) {
+	mockUsecase := &MockProjectUsecase{
+		scopeFunc: func(ctx context.Context, projectId int) (tenant.Scope, error) {
+			if projectId != 1 && projectId != 2 {
+				return tenant.Scope{}, fmt.Errorf("storage: %w", model.ErrNotFound)
+			}
+			return tenant.Scope{ProjectID: projectId, TaskQuota: 5}, nil
+		},
+	}
+	handler, _ := NewProjectHandler(&MockLogger{}, mockUsecase)
+
+	tests := []struct {
+		name           string
+		projectId      string
+		wrap           func(http.HandlerFunc) http.HandlerFunc
+		expectedStatus int
+		expectedScope  int
+	}{
+		{name: "existing project", projectId: "2", wrap: handler.Scoped, expectedStatus: http.StatusOK, expectedScope: 2},
+		{name: "nonexistent project", projectId: "3", wrap: handler.Scoped, expectedStatus: http.StatusNotFound},
+		{name: "invalid project id", projectId: "abc", wrap: handler.Scoped, expectedStatus: http.StatusBadRequest},
+		{name: "default project", wrap: handler.DefaultScoped, expectedStatus: http.StatusOK, expectedScope: model.DefaultProjectId},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got tenant.Scope
+			next := func(w http.ResponseWriter, r *http.Request) {
+				got = tenant.FromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}
+
+			req := httptest.NewRequest("GET", "/projects/"+tt.projectId+"/tasks", nil)
+			req.SetPathValue("project_id", tt.projectId)
+			w := httptest.NewRecorder()
+			tt.wrap(next)(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && (got.ProjectID != tt.expectedScope || got.TaskQuota != 5) {
+				t.Errorf("Unexpected scope %+v", got)
+			}
+		})
+	}
+}
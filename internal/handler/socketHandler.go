@@ -0,0 +1,452 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/websocket"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const socketHandlerName = "SocketHandler"
+
+const (
+	// DefaultSocketMessageSize limits the size of a message of a client
+	DefaultSocketMessageSize = 64 << 10
+	// DefaultSocketPingInterval is the time between pings, a client not answering two of them is disconnected
+	DefaultSocketPingInterval = 30 * time.Second
+	// DefaultSocketRateLimit is an amount of messages a client may send per second on average
+	DefaultSocketRateLimit = 20
+	// DefaultSocketRateBurst is an amount of messages a client may send at once
+	DefaultSocketRateBurst = 40
+
+	// maxSocketSubscriptions limits subscriptions of a connection
+	maxSocketSubscriptions = 32
+	// socketOutboxSize is an amount of messages waiting to be written to a connection
+	socketOutboxSize = 64
+	// socketWriteTimeout limits writing of a single message, clients reading slower are disconnected
+	socketWriteTimeout = 10 * time.Second
+)
+
+// SocketHandler serves the WebSocket API: clients subscribe to changes of tasks and send commands
+// over a single connection, see dto.SocketRequest and dto.SocketResponse
+type SocketHandler struct {
+	taskUsecase    TaskUsecase
+	logger         Logger
+	maxMessageSize int64
+	pingInterval   time.Duration
+	rateLimit      float64
+	rateBurst      int
+	allowedOrigins []string
+	publicIds      PublicIdResolver
+}
+
+// SocketHandlerOption configures optional behaviour of SocketHandler
+type SocketHandlerOption func(*SocketHandler)
+
+// WithMaxMessageSize limits the size of a message of a client, bigger messages close the connection
+func WithMaxMessageSize(size int64) SocketHandlerOption {
+	return func(sh *SocketHandler) {
+		sh.maxMessageSize = size
+	}
+}
+
+// WithPingInterval sets the time between pings of a connection
+func WithPingInterval(interval time.Duration) SocketHandlerOption {
+	return func(sh *SocketHandler) {
+		sh.pingInterval = interval
+	}
+}
+
+// WithRateLimit limits messages of a connection to perSecond on average and burst at once
+func WithRateLimit(perSecond float64, burst int) SocketHandlerOption {
+	return func(sh *SocketHandler) {
+		sh.rateLimit = perSecond
+		sh.rateBurst = burst
+	}
+}
+
+// WithAllowedOrigins lets browsers connect from the origins besides the origin of the server
+func WithAllowedOrigins(origins ...string) SocketHandlerOption {
+	return func(sh *SocketHandler) {
+		sh.allowedOrigins = origins
+	}
+}
+
+// WithSocketPublicIds makes requests refer to tasks by their public ids
+func WithSocketPublicIds(resolver PublicIdResolver) SocketHandlerOption {
+	return func(sh *SocketHandler) {
+		sh.publicIds = resolver
+	}
+}
+
+func NewSocketHandler(logger Logger, taskUsecase TaskUsecase, opts ...SocketHandlerOption) (*SocketHandler, error) {
+	if taskUsecase == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", socketHandlerName)
+	}
+
+	sh := &SocketHandler{
+		taskUsecase:    taskUsecase,
+		logger:         logger,
+		maxMessageSize: DefaultSocketMessageSize,
+		pingInterval:   DefaultSocketPingInterval,
+		rateLimit:      DefaultSocketRateLimit,
+		rateBurst:      DefaultSocketRateBurst,
+	}
+	for _, opt := range opts {
+		opt(sh)
+	}
+	if sh.maxMessageSize <= 0 || sh.pingInterval <= 0 || sh.rateLimit <= 0 || sh.rateBurst < 1 {
+		return nil, fmt.Errorf("%v: message size, ping interval and rate limit must be positive", socketHandlerName)
+	}
+	return sh, nil
+}
+
+// HandleWebSocket upgrades the request and serves the connection until either side closes it
+func (sh *SocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r, sh.allowedOrigins...)
+	if err != nil {
+		var handshakeErr *websocket.HandshakeError
+		if errors.As(err, &handshakeErr) {
+			respondWithError(sh.logger, w, handshakeErr.Status, handshakeErr.Message)
+			return
+		}
+		sh.logger.Log("error in %v: %v", socketHandlerName, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	session := &socketSession{
+		handler:       sh,
+		conn:          conn,
+		parent:        r.Context(),
+		ctx:           ctx,
+		cancel:        cancel,
+		outbox:        make(chan dto.SocketResponse, socketOutboxSize),
+		subscriptions: make(map[int]context.CancelFunc),
+		limiter:       newRateLimiter(sh.rateLimit, sh.rateBurst, time.Now()),
+	}
+	session.run()
+}
+
+// socketSession is a served connection. Requests are handled one by one by the reading goroutine,
+// so replies keep their order, and every message to the client goes through the writing goroutine.
+type socketSession struct {
+	handler *SocketHandler
+	conn    *websocket.Conn
+	// parent is the context of the request, it's done on server shutdown
+	parent context.Context
+	// ctx is done when the connection is closing
+	ctx    context.Context
+	cancel context.CancelFunc
+	outbox chan dto.SocketResponse
+
+	subscriptions    map[int]context.CancelFunc
+	lastSubscription int
+	sm               sync.Mutex
+
+	limiter *rateLimiter
+}
+
+func (s *socketSession) run() {
+	defer s.cancel()
+
+	pongWait := 2 * s.handler.pingInterval
+	s.conn.SetReadLimit(s.handler.maxMessageSize)
+	s.conn.SetWriteTimeout(socketWriteTimeout)
+	s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.conn.SetPongHandler(func() {
+		s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		s.write()
+	}()
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) && s.ctx.Err() == nil {
+				s.handler.logger.Log("error in %v: closing connection: %v", socketHandlerName, err)
+			}
+			break
+		}
+		s.conn.SetReadDeadline(time.Now().Add(pongWait))
+		s.handle(data)
+	}
+	s.cancel()
+	<-written
+}
+
+// write sends queued messages and pings until the session ends, then closes the connection
+func (s *socketSession) write() {
+	ping := time.NewTicker(s.handler.pingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-s.ctx.Done():
+			code := websocket.CloseNormal
+			if s.parent.Err() != nil {
+				code = websocket.CloseGoingAway
+			}
+			s.conn.Close(code, "")
+			return
+		case <-ping.C:
+			err = s.conn.Ping(nil)
+		case message := <-s.outbox:
+			var data []byte
+			if data, err = json.Marshal(message); err == nil {
+				err = s.conn.WriteMessage(websocket.TextMessage, data)
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, websocket.ErrCloseSent) {
+				s.handler.logger.Log("error in %v: closing connection: %v", socketHandlerName, err)
+			}
+			s.cancel()
+			s.conn.Close(websocket.CloseGoingAway, "")
+			return
+		}
+	}
+}
+
+// send queues the message, it waits while the outbox is full and gives up when the session ends
+func (s *socketSession) send(message dto.SocketResponse) bool {
+	select {
+	case s.outbox <- message:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *socketSession) sendError(id string, code int, message string) {
+	s.send(dto.SocketResponse{ID: id, Type: dto.SocketError, Status: code, Error: message})
+}
+
+func (s *socketSession) handle(data []byte) {
+	// invalid messages count against the limit as well
+	allowed := s.limiter.allow(time.Now())
+	var request dto.SocketRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		s.sendError("", http.StatusBadRequest, "invalid message")
+		return
+	}
+	if !allowed {
+		s.sendError(request.ID, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+
+	switch request.Type {
+	case dto.SocketSubscribe:
+		s.subscribe(request)
+	case dto.SocketUnsubscribe:
+		s.unsubscribe(request)
+	case dto.SocketCreate:
+		s.create(request)
+	case dto.SocketUpdate:
+		s.update(request)
+	default:
+		s.sendError(request.ID, http.StatusBadRequest, "unknown message type")
+	}
+}
+
+func (s *socketSession) subscribe(request dto.SocketRequest) {
+	s.sm.Lock()
+	subscriptions := len(s.subscriptions)
+	s.sm.Unlock()
+	if subscriptions >= maxSocketSubscriptions {
+		s.sendError(request.ID, http.StatusTooManyRequests, "too many subscriptions")
+		return
+	}
+
+	values := make(url.Values, len(request.Filter))
+	for key, value := range request.Filter {
+		values.Set(key, value)
+	}
+	filter, err := parseFilterValues(s.handler.logger, values)
+	if err != nil {
+		s.sendError(request.ID, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.LastEventID < 0 {
+		s.sendError(request.ID, http.StatusBadRequest, "invalid last_event_id")
+		return
+	}
+
+	taskIds := make([]int, 0, len(request.TaskIDs))
+	for _, ref := range request.TaskIDs {
+		taskId, err := s.taskId(ref)
+		if err != nil {
+			code, message := usecaseErrorStatus(err, "task", "failed to resolve task")
+			s.sendError(request.ID, code, message)
+			return
+		}
+		taskIds = append(taskIds, taskId)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	stream, err := s.handler.taskUsecase.Watch(ctx, filter, request.LastEventID)
+	if err != nil {
+		cancel()
+		code, message := usecaseErrorStatus(err, "task", "failed to watch tasks")
+		s.sendError(request.ID, code, message)
+		return
+	}
+
+	s.sm.Lock()
+	s.lastSubscription++
+	id := s.lastSubscription
+	s.subscriptions[id] = cancel
+	s.sm.Unlock()
+
+	s.send(dto.SocketResponse{ID: request.ID, Type: dto.SocketSubscribed, Subscription: id})
+	if !stream.Complete {
+		s.send(dto.SocketResponse{Type: dto.SocketReset, Subscription: id})
+	}
+	wanted := func(event dto.TaskChangeEvent) bool {
+		return len(taskIds) == 0 || slices.Contains(taskIds, event.Task.Id)
+	}
+	for _, event := range stream.Replay {
+		if wanted(event) {
+			s.send(dto.SocketResponse{Type: dto.SocketChange, Subscription: id, Event: &event})
+		}
+	}
+
+	go func() {
+		for event := range stream.Events {
+			// changes in flight when the client unsubscribed are dropped
+			if ctx.Err() != nil {
+				break
+			}
+			if wanted(event) && !s.send(dto.SocketResponse{Type: dto.SocketChange, Subscription: id, Event: &event}) {
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// the subscription fell too far behind, the client resubscribes with the last event id it saw
+		s.sm.Lock()
+		delete(s.subscriptions, id)
+		s.sm.Unlock()
+		cancel()
+		s.send(dto.SocketResponse{Type: dto.SocketUnsubscribed, Subscription: id, Error: "subscription fell too far behind"})
+	}()
+}
+
+func (s *socketSession) unsubscribe(request dto.SocketRequest) {
+	s.sm.Lock()
+	cancel, ok := s.subscriptions[request.Subscription]
+	delete(s.subscriptions, request.Subscription)
+	s.sm.Unlock()
+	if !ok {
+		s.sendError(request.ID, http.StatusNotFound, "subscription not found")
+		return
+	}
+
+	cancel()
+	s.send(dto.SocketResponse{ID: request.ID, Type: dto.SocketUnsubscribed, Subscription: request.Subscription})
+}
+
+func (s *socketSession) create(request dto.SocketRequest) {
+	var postReq dto.PostTaskRequest
+	if err := json.Unmarshal(request.Task, &postReq); err != nil {
+		s.sendError(request.ID, http.StatusBadRequest, "invalid data in task")
+		return
+	}
+
+	taskId, err := s.handler.taskUsecase.Store(s.ctx, postReq)
+	if err != nil {
+		code, message := usecaseErrorStatus(err, "task", "failed to store task")
+		s.sendError(request.ID, code, message)
+		return
+	}
+	task, err := s.handler.taskUsecase.GetByTaskId(s.ctx, taskId)
+	if err != nil {
+		code, message := usecaseErrorStatus(err, "task", "failed to retrieve task")
+		s.sendError(request.ID, code, message)
+		return
+	}
+
+	s.send(dto.SocketResponse{ID: request.ID, Type: dto.SocketTask, Task: &task})
+}
+
+func (s *socketSession) update(request dto.SocketRequest) {
+	if request.TaskID == nil {
+		s.sendError(request.ID, http.StatusBadRequest, "task_id wasn't provided")
+		return
+	}
+	var patchReq dto.PatchTaskRequest
+	if err := json.Unmarshal(request.Task, &patchReq); err != nil {
+		s.sendError(request.ID, http.StatusBadRequest, "invalid data in task")
+		return
+	}
+
+	taskId, err := s.taskId(*request.TaskID)
+	if err != nil {
+		code, message := usecaseErrorStatus(err, "task", "failed to resolve task")
+		s.sendError(request.ID, code, message)
+		return
+	}
+	task, err := s.handler.taskUsecase.Update(s.ctx, taskId, patchReq)
+	if err != nil {
+		code, message := usecaseErrorStatus(err, "task", "failed to update task")
+		s.sendError(request.ID, code, message)
+		return
+	}
+
+	s.send(dto.SocketResponse{ID: request.ID, Type: dto.SocketTask, Task: &task})
+}
+
+// taskId returns the id of the task the ref refers to. With public ids refs hold public ids, or ids of tasks
+// without them, otherwise they hold ids, strings are ids in decimal.
+func (s *socketSession) taskId(ref dto.TaskRef) (int, error) {
+	if s.handler.publicIds != nil {
+		return s.handler.publicIds.ResolvePublicId(s.ctx, cmp.Or(ref.PublicID, strconv.Itoa(ref.Id)))
+	}
+	if ref.PublicID == "" {
+		return ref.Id, nil
+	}
+	taskId, err := strconv.Atoi(ref.PublicID)
+	if err != nil {
+		return 0, model.Invalid(fmt.Errorf("task ids are numbers, got %q", ref.PublicID))
+	}
+	return taskId, nil
+}
+
+// rateLimiter is a token bucket, it's used by a single goroutine
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int, now time.Time) *rateLimiter {
+	return &rateLimiter{rate: perSecond, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// allow takes a token if there is one, tokens are refilled at the rate up to the burst
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
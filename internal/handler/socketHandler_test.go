@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialSocket starts a server of the handler and connects to it
+func dialSocket(t *testing.T, handler *SocketHandler) *websocket.Conn {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(handler.HandleWebSocket))
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close(websocket.CloseNormal, "") })
+	return conn
+}
+
+func readSocket(t *testing.T, conn *websocket.Conn) dto.SocketResponse {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	var response dto.SocketResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		t.Fatalf("Invalid message %s: %v", data, err)
+	}
+	return response
+}
+
+func writeSocket(t *testing.T, conn *websocket.Conn, message string) {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+}
+
+func TestHandleWebSocket(t *testing.T) {
+	events := make(chan dto.TaskChangeEvent)
+	unsubscribed := make(chan struct{})
+	mockUsecase := &MockTaskUsecase{
+		storeFunc: func(ctx context.Context, request dto.PostTaskRequest) (int, error) {
+			if request.Name == "" {
+				return -1, fmt.Errorf("usecase: %w", model.ErrInvalid)
+			}
+			return 5, nil
+		},
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error) {
+			return dto.GetTaskByIdResponse{Id: taskId, Name: "new"}, nil
+		},
+		updateFunc: func(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error) {
+			if taskId == 9 {
+				return dto.GetTaskByIdResponse{}, fmt.Errorf("usecase: %w", model.ErrNotFound)
+			}
+			return dto.GetTaskByIdResponse{Id: taskId, Name: *request.Name}, nil
+		},
+		watchFunc: func(ctx context.Context, filter model.Filter, lastEventID int) (dto.TaskChangeStream, error) {
+			if filter.Status != model.Done || lastEventID != 2 {
+				t.Errorf("Unexpected filter %+v or last event id %v", filter, lastEventID)
+			}
+			stream := make(chan dto.TaskChangeEvent)
+			go func() {
+				defer close(stream)
+				for {
+					select {
+					case <-ctx.Done():
+						close(unsubscribed)
+						return
+					case event := <-events:
+						stream <- event
+					}
+				}
+			}()
+			replay := []dto.TaskChangeEvent{{ID: 3, Type: model.TaskChangeUpdated, Task: dto.GetTaskByIdResponse{Id: 4}}}
+			return dto.TaskChangeStream{Replay: replay, Complete: true, Events: stream}, nil
+		},
+	}
+	handler, _ := NewSocketHandler(&MockLogger{}, mockUsecase)
+	conn := dialSocket(t, handler)
+
+	tests := []struct {
+		name           string
+		message        string
+		expectedType   dto.SocketMessageType
+		expectedStatus int
+	}{
+		{name: "create", message: `{"id": "1", "type": "create", "task": {"name": "new", "status": "created"}}`, expectedType: dto.SocketTask},
+		{name: "invalid task", message: `{"id": "2", "type": "create", "task": {"status": "created"}}`, expectedType: dto.SocketError, expectedStatus: http.StatusBadRequest},
+		{name: "update", message: `{"id": "3", "type": "update", "task_id": 1, "task": {"name": "renamed"}}`, expectedType: dto.SocketTask},
+		{name: "update missing task", message: `{"id": "4", "type": "update", "task_id": 9, "task": {"name": "x"}}`, expectedType: dto.SocketError, expectedStatus: http.StatusNotFound},
+		{name: "update without task id", message: `{"id": "5", "type": "update", "task": {}}`, expectedType: dto.SocketError, expectedStatus: http.StatusBadRequest},
+		{name: "update by decimal task id", message: `{"id": "5", "type": "update", "task_id": "1", "task": {"name": "renamed"}}`, expectedType: dto.SocketTask},
+		{name: "update by non numeric task id", message: `{"id": "5", "type": "update", "task_id": "a", "task": {}}`, expectedType: dto.SocketError, expectedStatus: http.StatusBadRequest},
+		{name: "invalid filter", message: `{"id": "6", "type": "subscribe", "filter": {"status": "unknown"}}`, expectedType: dto.SocketError, expectedStatus: http.StatusBadRequest},
+		{name: "unknown subscription", message: `{"id": "7", "type": "unsubscribe", "subscription": 99}`, expectedType: dto.SocketError, expectedStatus: http.StatusNotFound},
+		{name: "unknown type", message: `{"id": "8", "type": "delete"}`, expectedType: dto.SocketError, expectedStatus: http.StatusBadRequest},
+		{name: "invalid json", message: `{"id": `, expectedType: dto.SocketError, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeSocket(t, conn, tt.message)
+			response := readSocket(t, conn)
+			if response.Type != tt.expectedType || response.Status != tt.expectedStatus {
+				t.Errorf("Expected %v with status %d, got %+v", tt.expectedType, tt.expectedStatus, response)
+			}
+		})
+	}
+
+	t.Run("subscription", func(t *testing.T) {
+		writeSocket(t, conn, `{"id": "s", "type": "subscribe", "filter": {"status": "done"}, "task_ids": [4], "last_event_id": 2}`)
+		subscribed := readSocket(t, conn)
+		if subscribed.Type != dto.SocketSubscribed || subscribed.ID != "s" || subscribed.Subscription == 0 {
+			t.Fatalf("Expected a subscription, got %+v", subscribed)
+		}
+		if replayed := readSocket(t, conn); replayed.Type != dto.SocketChange || replayed.Event.ID != 3 {
+			t.Errorf("Expected the replayed change, got %+v", replayed)
+		}
+
+		events <- dto.TaskChangeEvent{ID: 4, Type: model.TaskChangeUpdated, Task: dto.GetTaskByIdResponse{Id: 5}}
+		events <- dto.TaskChangeEvent{ID: 5, Type: model.TaskChangeDeleted, Task: dto.GetTaskByIdResponse{Id: 4}}
+		change := readSocket(t, conn)
+		if change.Type != dto.SocketChange || change.Subscription != subscribed.Subscription || change.Event.ID != 5 {
+			t.Errorf("Expected only the change of the subscribed task, got %+v", change)
+		}
+
+		writeSocket(t, conn, fmt.Sprintf(`{"id": "u", "type": "unsubscribe", "subscription": %d}`, subscribed.Subscription))
+		if response := readSocket(t, conn); response.Type != dto.SocketUnsubscribed || response.ID != "u" {
+			t.Errorf("Expected the subscription to end, got %+v", response)
+		}
+		select {
+		case <-unsubscribed:
+		case <-time.After(time.Second):
+			t.Errorf("Expected the watch to be canceled")
+		}
+	})
+}
+
+func TestHandleWebSocketPublicIds(t *testing.T) {
+	mockUsecase := &MockTaskUsecase{
+		updateFunc: func(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error) {
+			return dto.GetTaskByIdResponse{Id: taskId}, nil
+		},
+		watchFunc: func(ctx context.Context, filter model.Filter, lastEventID int) (dto.TaskChangeStream, error) {
+			replay := []dto.TaskChangeEvent{{ID: 1, Task: dto.GetTaskByIdResponse{Id: 1}}, {ID: 2, Task: dto.GetTaskByIdResponse{Id: 2}}}
+			return dto.TaskChangeStream{Replay: replay, Complete: true, Events: make(chan dto.TaskChangeEvent)}, nil
+		},
+	}
+	handler, _ := NewSocketHandler(&MockLogger{}, mockUsecase, WithSocketPublicIds(MockPublicIdResolver{}))
+	conn := dialSocket(t, handler)
+
+	tests := []struct {
+		name           string
+		message        string
+		expectedTaskId int
+		expectedStatus int
+	}{
+		{name: "public id", message: `{"type": "update", "task_id": "b", "task": {}}`, expectedTaskId: 2},
+		{name: "unknown public id", message: `{"type": "update", "task_id": "c", "task": {}}`, expectedStatus: http.StatusNotFound},
+		{name: "forbidden public id", message: `{"type": "update", "task_id": "secret", "task": {}}`, expectedStatus: http.StatusForbidden},
+		{name: "ids aren't accepted", message: `{"type": "update", "task_id": 1, "task": {}}`, expectedStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeSocket(t, conn, tt.message)
+			response := readSocket(t, conn)
+			if response.Status != tt.expectedStatus || tt.expectedStatus == 0 && (response.Task == nil || response.Task.Id != tt.expectedTaskId) {
+				t.Errorf("Expected task %d with status %d, got %+v", tt.expectedTaskId, tt.expectedStatus, response)
+			}
+		})
+	}
+
+	t.Run("subscription", func(t *testing.T) {
+		writeSocket(t, conn, `{"type": "subscribe", "task_ids": ["b"]}`)
+		if subscribed := readSocket(t, conn); subscribed.Type != dto.SocketSubscribed {
+			t.Fatalf("Expected a subscription, got %+v", subscribed)
+		}
+		if change := readSocket(t, conn); change.Type != dto.SocketChange || change.Event.ID != 2 {
+			t.Errorf("Expected only the change of the subscribed task, got %+v", change)
+		}
+
+		writeSocket(t, conn, `{"type": "subscribe", "task_ids": ["c"]}`)
+		if response := readSocket(t, conn); response.Type != dto.SocketError || response.Status != http.StatusNotFound {
+			t.Errorf("Expected an unknown task to be rejected, got %+v", response)
+		}
+	})
+}
+
+func TestHandleWebSocketLimits(t *testing.T) {
+	mockUsecase := &MockTaskUsecase{
+		updateFunc: func(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error) {
+			return dto.GetTaskByIdResponse{Id: taskId}, nil
+		},
+	}
+
+	t.Run("rate limit", func(t *testing.T) {
+		handler, _ := NewSocketHandler(&MockLogger{}, mockUsecase, WithRateLimit(0.001, 2))
+		conn := dialSocket(t, handler)
+		for i, expectedType := range []dto.SocketMessageType{dto.SocketTask, dto.SocketTask, dto.SocketError} {
+			writeSocket(t, conn, `{"type": "update", "task_id": 1, "task": {}}`)
+			if response := readSocket(t, conn); response.Type != expectedType {
+				t.Errorf("Message %d: expected %v, got %+v", i, expectedType, response)
+			} else if expectedType == dto.SocketError && response.Status != http.StatusTooManyRequests {
+				t.Errorf("Expected status 429, got %+v", response)
+			}
+		}
+	})
+
+	t.Run("message size", func(t *testing.T) {
+		handler, _ := NewSocketHandler(&MockLogger{}, mockUsecase, WithMaxMessageSize(32))
+		conn := dialSocket(t, handler)
+		writeSocket(t, conn, `{"type": "update", "task_id": 1, "task": {"name": "too long for the limit"}}`)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		var closeErr *websocket.CloseError
+		if _, _, err := conn.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseMessageTooBig {
+			t.Errorf("Expected close with %d, got %v", websocket.CloseMessageTooBig, err)
+		}
+	})
+
+	t.Run("handshake", func(t *testing.T) {
+		handler, _ := NewSocketHandler(&MockLogger{}, mockUsecase)
+		w := httptest.NewRecorder()
+		handler.HandleWebSocket(w, httptest.NewRequest("GET", "/ws", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a plain request, got %d", w.Code)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := NewSocketHandler(&MockLogger{}, mockUsecase, WithPingInterval(0)); err == nil {
+			t.Errorf("Expected an error for zero ping interval")
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strconv"
+)
+
+const tagHandlerName = "TagHandler"
+
+type TagUsecase interface {
+	Store(ctx context.Context, request dto.PostTagRequest) (int, error)
+	GetAll(ctx context.Context) (dto.GetAllTagsResponse, error)
+	GetByTagId(ctx context.Context, tagId int) (dto.GetTagByIdResponse, error)
+	Update(ctx context.Context, tagId int, request dto.PutTagRequest) (dto.GetTagByIdResponse, error)
+	Delete(ctx context.Context, tagId int) error
+	Attach(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error)
+	Detach(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error)
+}
+
+type TagHandler struct {
+	tagUsecase TagUsecase
+	logger     Logger
+}
+
+func NewTagHandler(logger Logger, tagUsecase TagUsecase) (*TagHandler, error) {
+	if tagUsecase == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", tagHandlerName)
+	}
+
+	return &TagHandler{tagUsecase, logger}, nil
+}
+
+func (th *TagHandler) HandlePostTag(w http.ResponseWriter, r *http.Request) {
+	var postReq dto.PostTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&postReq); err != nil {
+		respondWithError(th.logger, w, http.StatusBadRequest, "invalid data in tag")
+		return
+	}
+
+	id, err := th.tagUsecase.Store(r.Context(), postReq)
+	if err != nil {
+		th.logger.Log("error in %v: %v", tagHandlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "tag", "failed to store tag")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, id)
+}
+
+func (th *TagHandler) HandleGetAllTags(w http.ResponseWriter, r *http.Request) {
+	response, err := th.tagUsecase.GetAll(r.Context())
+	if err != nil {
+		th.logger.Log("error in %v: %v", tagHandlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "tag", "failed to retrieve tags")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (th *TagHandler) HandleGetTagById(w http.ResponseWriter, r *http.Request) {
+	tagId, ok := idFromPath(th.logger, w, r, "tag_id")
+	if !ok {
+		return
+	}
+
+	response, err := th.tagUsecase.GetByTagId(r.Context(), tagId)
+	if err != nil {
+		respondWithUsecaseError(th.logger, w, err, "tag", "failed to retrieve tag")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (th *TagHandler) HandlePutTag(w http.ResponseWriter, r *http.Request) {
+	tagId, ok := idFromPath(th.logger, w, r, "tag_id")
+	if !ok {
+		return
+	}
+
+	var putReq dto.PutTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&putReq); err != nil {
+		respondWithError(th.logger, w, http.StatusBadRequest, "invalid data in tag")
+		return
+	}
+
+	response, err := th.tagUsecase.Update(r.Context(), tagId, putReq)
+	if err != nil {
+		th.logger.Log("error in %v: %v", tagHandlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "tag", "failed to update tag")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (th *TagHandler) HandleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	tagId, ok := idFromPath(th.logger, w, r, "tag_id")
+	if !ok {
+		return
+	}
+
+	if err := th.tagUsecase.Delete(r.Context(), tagId); err != nil {
+		respondWithUsecaseError(th.logger, w, err, "tag", "failed to delete tag")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleAttachTag attaches the tag to the task and responds with the updated task
+func (th *TagHandler) HandleAttachTag(w http.ResponseWriter, r *http.Request) {
+	th.handleTagging(w, r, th.tagUsecase.Attach, "failed to attach tag")
+}
+
+// HandleDetachTag detaches the tag from the task and responds with the updated task
+func (th *TagHandler) HandleDetachTag(w http.ResponseWriter, r *http.Request) {
+	th.handleTagging(w, r, th.tagUsecase.Detach, "failed to detach tag")
+}
+
+func (th *TagHandler) handleTagging(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error),
+	fallback string) {
+
+	taskId, ok := idFromPath(th.logger, w, r, "task_id")
+	if !ok {
+		return
+	}
+	tagId, ok := idFromPath(th.logger, w, r, "tag_id")
+	if !ok {
+		return
+	}
+
+	response, err := action(r.Context(), taskId, tagId)
+	if err != nil {
+		th.logger.Log("error in %v: %v", tagHandlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "task or tag", fallback)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// idFromPath parses an id path value and responds with an error if it's invalid
+func idFromPath(logger Logger, w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	param := r.PathValue(name)
+	if param == "" {
+		respondWithError(logger, w, http.StatusBadRequest, name+" wasn't provided")
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		respondWithError(logger, w, http.StatusBadRequest, "invalid "+name+" parameter")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockTagUsecase struct {
+	storeFunc      func(ctx context.Context, request dto.PostTagRequest) (int, error)
+	getAllFunc     func(ctx context.Context) (dto.GetAllTagsResponse, error)
+	getByTagIdFunc func(ctx context.Context, tagId int) (dto.GetTagByIdResponse, error)
+	updateFunc     func(ctx context.Context, tagId int, request dto.PutTagRequest) (dto.GetTagByIdResponse, error)
+	deleteFunc     func(ctx context.Context, tagId int) error
+	attachFunc     func(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error)
+	detachFunc     func(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error)
+}
+
+func (m *MockTagUsecase) Store(ctx context.Context, request dto.PostTagRequest) (int, error) {
+	return m.storeFunc(ctx, request)
+}
+
+func (m *MockTagUsecase) GetAll(ctx context.Context) (dto.GetAllTagsResponse, error) {
+	return m.getAllFunc(ctx)
+}
+
+func (m *MockTagUsecase) GetByTagId(ctx context.Context, tagId int) (dto.GetTagByIdResponse, error) {
+	return m.getByTagIdFunc(ctx, tagId)
+}
+
+func (m *MockTagUsecase) Update(ctx context.Context, tagId int, request dto.PutTagRequest) (dto.GetTagByIdResponse, error) {
+	return m.updateFunc(ctx, tagId, request)
+}
+
+func (m *MockTagUsecase) Delete(ctx context.Context, tagId int) error {
+	return m.deleteFunc(ctx, tagId)
+}
+
+func (m *MockTagUsecase) Attach(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error) {
+	return m.attachFunc(ctx, taskId, tagId)
+}
+
+func (m *MockTagUsecase) Detach(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error) {
+	return m.detachFunc(ctx, taskId, tagId)
+}
+
+func TestHandlePostTag(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		usecaseError   error
+		expectedStatus int
+	}{
+		{name: "successful post", body: `{"name": "backend"}`, expectedStatus: http.StatusOK},
+		{name: "malformed body", body: `{"name": `, expectedStatus: http.StatusBadRequest},
+		{
+			name:           "duplicate tag",
+			body:           `{"name": "backend"}`,
+			usecaseError:   fmt.Errorf("storage: %w", model.ErrAlreadyExists),
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockTagUsecase{
+				storeFunc: func(ctx context.Context, request dto.PostTagRequest) (int, error) {
+					return 1, tt.usecaseError
+				},
+			}
+			handler, _ := NewTagHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("POST", "/tags", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.HandlePostTag(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandleAttachTag(t *testing.T) {
+	tests := []struct {
+		name           string
+		taskId         string
+		tagId          string
+		usecaseError   error
+		expectedStatus int
+	}{
+		{name: "successful attach", taskId: "0", tagId: "1", expectedStatus: http.StatusOK},
+		{name: "invalid task id", taskId: "abc", tagId: "1", expectedStatus: http.StatusBadRequest},
+		{name: "invalid tag id", taskId: "0", tagId: "abc", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "missing tag",
+			taskId:         "0",
+			tagId:          "9",
+			usecaseError:   fmt.Errorf("storage: tag: %w", model.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockTagUsecase{
+				attachFunc: func(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error) {
+					return dto.GetTaskByIdResponse{Id: taskId, TagIDs: []int{tagId}}, tt.usecaseError
+				},
+			}
+			handler, _ := NewTagHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("POST", "/tasks/"+tt.taskId+"/tags/"+tt.tagId, nil)
+			req.SetPathValue("task_id", tt.taskId)
+			req.SetPathValue("tag_id", tt.tagId)
+			w := httptest.NewRecorder()
+			handler.HandleAttachTag(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultHeartbeatInterval is the time between comments keeping idle event streams open
+	DefaultHeartbeatInterval = 15 * time.Second
+	// streamWriteTimeout limits writing of a single event, clients reading slower are disconnected
+	streamWriteTimeout = 10 * time.Second
+)
+
+// HandleTaskEvents streams changes of the tasks matching the filter parameters as server-sent events.
+//
+// Every event has the change id as its id and the change type as its name. A client reconnecting
+// with Last-Event-ID gets the changes it missed first, and a "reset" event when some of them
+// can't be replayed anymore, then it has to reload the tasks.
+func (th *TaskHandler) HandleTaskEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := th.parseFilter(r)
+	if err != nil {
+		respondWithError(th.logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	lastEventID := 0
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		if lastEventID, err = strconv.Atoi(value); err != nil || lastEventID < 0 {
+			respondWithError(th.logger, w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+	}
+
+	stream, err := th.taskUsecase.Watch(ctx, filter, lastEventID)
+	if err != nil {
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to watch tasks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	send := func(message string) bool {
+		if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return false
+		}
+		if _, err := fmt.Fprint(w, message); err != nil {
+			th.logger.Log("error in %v: closing event stream: %v", handlerName, err)
+			return false
+		}
+		if err := rc.Flush(); err != nil {
+			th.logger.Log("error in %v: closing event stream: %v", handlerName, err)
+			return false
+		}
+		return true
+	}
+
+	if !send(": connected\n\n") {
+		return
+	}
+	if !stream.Complete && !send("event: reset\ndata: {}\n\n") {
+		return
+	}
+	for _, event := range stream.Replay {
+		if !send(formatTaskEvent(event)) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(th.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if !send(": heartbeat\n\n") {
+				return
+			}
+		case event, ok := <-stream.Events:
+			if !ok {
+				// the client fell too far behind, it resumes from its last event after reconnecting
+				return
+			}
+			if !send(formatTaskEvent(event)) {
+				return
+			}
+		}
+	}
+}
+
+// formatTaskEvent formats the change as a server-sent event, json has no raw newlines, so data takes one line
+func formatTaskEvent(event dto.TaskChangeEvent) string {
+	data, err := json.Marshal(event)
+	if err != nil {
+		data = []byte("{}")
+	}
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
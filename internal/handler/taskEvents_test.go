@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleTaskEvents(t *testing.T) {
+	change := func(id int, changeType model.TaskChangeType) dto.TaskChangeEvent {
+		return dto.TaskChangeEvent{ID: id, Type: changeType, ProjectID: 1, Task: dto.GetTaskByIdResponse{Id: 4, Name: "task"}}
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		lastEventID    string
+		replay         []dto.TaskChangeEvent
+		incomplete     bool
+		live           []dto.TaskChangeEvent
+		liveDelay      time.Duration
+		usecaseError   error
+		expectedStatus int
+		expectedLast   int
+		expectedBody   []string
+		unexpectedBody []string
+	}{
+		{
+			name:           "live changes",
+			query:          "?status=done",
+			live:           []dto.TaskChangeEvent{change(1, model.TaskChangeCreated), change(2, model.TaskChangeDeleted)},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{"id: 1\nevent: created\ndata: {\"id\":1,", "id: 2\nevent: deleted\n"},
+			unexpectedBody: []string{"event: reset"},
+		},
+		{
+			name:           "resumed stream",
+			lastEventID:    "6",
+			replay:         []dto.TaskChangeEvent{change(7, model.TaskChangeUpdated)},
+			live:           []dto.TaskChangeEvent{change(8, model.TaskChangeUpdated)},
+			expectedStatus: http.StatusOK,
+			expectedLast:   6,
+			expectedBody:   []string{"id: 7\nevent: updated\n", "id: 8\nevent: updated\n"},
+		},
+		{
+			name:           "replay buffer overrun",
+			lastEventID:    "1",
+			incomplete:     true,
+			expectedStatus: http.StatusOK,
+			expectedLast:   1,
+			expectedBody:   []string{"event: reset\ndata: {}\n\n"},
+		},
+		{
+			name:           "heartbeat",
+			liveDelay:      50 * time.Millisecond,
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{": heartbeat\n\n"},
+		},
+		{
+			name:           "invalid last event id",
+			lastEventID:    "abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid filter",
+			query:          "?status=unknown",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "streaming disabled",
+			usecaseError:   fmt.Errorf("usecase: %w", model.ErrUnsupported),
+			expectedStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockTaskUsecase{
+				watchFunc: func(ctx context.Context, filter model.Filter, lastEventID int) (dto.TaskChangeStream, error) {
+					if lastEventID != tt.expectedLast {
+						t.Errorf("Expected last event id %v, got %v", tt.expectedLast, lastEventID)
+					}
+					if tt.usecaseError != nil {
+						return dto.TaskChangeStream{}, tt.usecaseError
+					}
+					events := make(chan dto.TaskChangeEvent)
+					go func() {
+						defer close(events)
+						time.Sleep(tt.liveDelay)
+						for _, event := range tt.live {
+							events <- event
+						}
+					}()
+					return dto.TaskChangeStream{Replay: tt.replay, Complete: !tt.incomplete, Events: events}, nil
+				},
+			}
+			handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase, WithHeartbeatInterval(5*time.Millisecond))
+
+			req := httptest.NewRequest("GET", "/tasks/events"+tt.query, nil)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			w := httptest.NewRecorder()
+
+			handler.HandleTaskEvents(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "text/event-stream" {
+				t.Errorf("Expected event stream, got %q", contentType)
+			}
+			body := w.Body.String()
+			position := 0
+			for _, part := range tt.expectedBody {
+				index := strings.Index(body[position:], part)
+				if index < 0 {
+					t.Fatalf("Expected %q in order in body %q", part, body)
+				}
+				position += index + len(part)
+			}
+			for _, part := range tt.unexpectedBody {
+				if strings.Contains(body, part) {
+					t.Errorf("Unexpected %q in body %q", part, body)
+				}
+			}
+		})
+	}
+}
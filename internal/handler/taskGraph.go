@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// DefaultSubtaskDepth is the depth of the subtask tree when it isn't set in the query
+	DefaultSubtaskDepth = 1
+	// MaxSubtaskDepth limits the size of a single response
+	MaxSubtaskDepth = 16
+)
+
+// HandleAddBlocker makes blocker_id block task_id and responds with the updated task
+func (th *TaskHandler) HandleAddBlocker(w http.ResponseWriter, r *http.Request) {
+	th.handleBlocker(w, r, th.taskUsecase.AddBlocker, "failed to add blocker")
+}
+
+// HandleRemoveBlocker removes blocker_id from the blockers of task_id and responds with the updated task
+func (th *TaskHandler) HandleRemoveBlocker(w http.ResponseWriter, r *http.Request) {
+	th.handleBlocker(w, r, th.taskUsecase.RemoveBlocker, "failed to remove blocker")
+}
+
+// HandleGetSubtasks responds with the subtask tree of the task, the depth query parameter
+// limits how many levels are included
+func (th *TaskHandler) HandleGetSubtasks(w http.ResponseWriter, r *http.Request) {
+	taskId, ok := th.taskIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	depth := DefaultSubtaskDepth
+	if param := r.URL.Query().Get("depth"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 0 || parsed > MaxSubtaskDepth {
+			respondWithError(th.logger, w, http.StatusBadRequest, "invalid depth parameter")
+			return
+		}
+		depth = parsed
+	}
+
+	response, err := th.taskUsecase.GetSubtasks(r.Context(), taskId, depth)
+	if err != nil {
+		th.logger.Log("error in %v: %v", handlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to retrieve subtasks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// HandleGetDependencyOrder responds with the task and everything blocking it in an order they can be done in
+func (th *TaskHandler) HandleGetDependencyOrder(w http.ResponseWriter, r *http.Request) {
+	taskId, ok := th.taskIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := th.taskUsecase.DependencyOrder(r.Context(), taskId)
+	if err != nil {
+		th.logger.Log("error in %v: %v", handlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to order dependencies")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (th *TaskHandler) handleBlocker(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error),
+	fallback string) {
+
+	taskId, ok := th.taskIdFromPath(w, r)
+	if !ok {
+		return
+	}
+	blockerId, ok := idFromPath(th.logger, w, r, "blocker_id")
+	if !ok {
+		return
+	}
+
+	response, err := action(r.Context(), taskId, blockerId)
+	if err != nil {
+		th.logger.Log("error in %v: %v", handlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "task", fallback)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleAddBlocker(t *testing.T) {
+	tests := []struct {
+		name           string
+		taskId         string
+		blockerId      string
+		usecaseError   error
+		expectedStatus int
+	}{
+		{name: "successful add", taskId: "1", blockerId: "0", expectedStatus: http.StatusOK},
+		{name: "invalid blocker id", taskId: "1", blockerId: "abc", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "cycle",
+			taskId:         "0",
+			blockerId:      "1",
+			usecaseError:   fmt.Errorf("storage: %w", model.ErrCycle),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "missing blocker",
+			taskId:         "0",
+			blockerId:      "9",
+			usecaseError:   fmt.Errorf("storage: %w", model.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockTaskUsecase{
+				blockerFunc: func(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error) {
+					return dto.GetTaskByIdResponse{Id: taskId, BlockedBy: []int{blockerId}}, tt.usecaseError
+				},
+			}
+			handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("PUT", "/tasks/"+tt.taskId+"/blockers/"+tt.blockerId, nil)
+			req.SetPathValue("task_id", tt.taskId)
+			req.SetPathValue("blocker_id", tt.blockerId)
+			w := httptest.NewRecorder()
+			handler.HandleAddBlocker(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandleGetSubtasks(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedDepth  int
+		expectedStatus int
+	}{
+		{name: "default depth", query: "", expectedDepth: DefaultSubtaskDepth, expectedStatus: http.StatusOK},
+		{name: "explicit depth", query: "?depth=3", expectedDepth: 3, expectedStatus: http.StatusOK},
+		{name: "negative depth", query: "?depth=-1", expectedStatus: http.StatusBadRequest},
+		{name: "too deep", query: fmt.Sprintf("?depth=%d", MaxSubtaskDepth+1), expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depth := -1
+			mockUsecase := &MockTaskUsecase{
+				getSubtasksFunc: func(ctx context.Context, taskId, d int) (dto.GetSubtasksResponse, error) {
+					depth = d
+					return dto.GetSubtasksResponse{}, nil
+				},
+			}
+			handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("GET", "/tasks/0/subtasks"+tt.query, nil)
+			req.SetPathValue("task_id", "0")
+			w := httptest.NewRecorder()
+			handler.HandleGetSubtasks(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && depth != tt.expectedDepth {
+				t.Errorf("Expected depth %d, got %d", tt.expectedDepth, depth)
+			}
+		})
+	}
+}
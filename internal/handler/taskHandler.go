@@ -3,36 +3,96 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"ivanjabrony/test_lo/internal/model"
 	"ivanjabrony/test_lo/internal/model/dto"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const handlerName = "TaskHandler"
 
+var (
+	errInvalidStatusFilter    = errors.New("invalid task status in filter")
+	errInvalidAssigneeFilter  = errors.New("invalid assignee in filter")
+	errInvalidPriorityFilter  = errors.New("invalid priority in filter")
+	errInvalidOverdueFilter   = errors.New("invalid overdue in filter")
+	errInvalidRecurringFilter = errors.New("invalid recurring in filter")
+	errInvalidDueFilter       = errors.New("invalid due date range in filter")
+	errInvalidCreatedFilter   = errors.New("invalid creation time range in filter")
+	errInvalidSort            = errors.New("invalid sort field")
+	errInvalidTagsFilter      = errors.New("invalid tags in filter")
+	errInvalidPage            = errors.New("invalid limit or offset")
+)
+
 type TaskUsecase interface {
 	Store(ctx context.Context, request dto.PostTaskRequest) (int, error)
 	GetAll(ctx context.Context, filter model.Filter) (dto.GetAllTasksResponse, error)
 	GetByTaskId(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error)
+	GetByTaskIdAsOf(ctx context.Context, taskId int, asOf time.Time) (dto.GetTaskByIdResponse, error)
+	Export(ctx context.Context, filter model.Filter, fn func(model.Task) error) error
+	Import(ctx context.Context, rows []dto.ImportTaskRow, dryRun bool) (dto.ImportTasksResponse, error)
+	Update(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error)
+	Delete(ctx context.Context, taskId int) error
+	AddBlocker(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error)
+	RemoveBlocker(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error)
+	GetSubtasks(ctx context.Context, taskId, depth int) (dto.GetSubtasksResponse, error)
+	DependencyOrder(ctx context.Context, taskId int) (dto.GetDependencyOrderResponse, error)
+	Watch(ctx context.Context, filter model.Filter, lastEventID int) (dto.TaskChangeStream, error)
 }
 
 type Logger interface {
 	Log(format string, info ...any)
 }
 
+// PublicIdResolver maps public ids of tasks to their ids, see usecase.TaskUsecase.ResolvePublicId
+type PublicIdResolver interface {
+	ResolvePublicId(ctx context.Context, publicId string) (int, error)
+}
+
 type TaskHandler struct {
 	taskUsecase TaskUsecase
 	logger      Logger
+	// heartbeat is the time between comments keeping idle event streams open
+	heartbeat time.Duration
+	// publicIds is nil when tasks are addressed by their ids
+	publicIds PublicIdResolver
+}
+
+// TaskHandlerOption configures optional behaviour of TaskHandler
+type TaskHandlerOption func(*TaskHandler)
+
+// WithHeartbeatInterval sets the time between heartbeats of event streams
+func WithHeartbeatInterval(interval time.Duration) TaskHandlerOption {
+	return func(th *TaskHandler) {
+		th.heartbeat = interval
+	}
 }
 
-func NewTaskHandler(logger Logger, taskUsecase TaskUsecase) (*TaskHandler, error) {
+// WithPublicIds makes the api address tasks by their public ids, see ResolvePublicIds
+func WithPublicIds(resolver PublicIdResolver) TaskHandlerOption {
+	return func(th *TaskHandler) {
+		th.publicIds = resolver
+	}
+}
+
+func NewTaskHandler(logger Logger, taskUsecase TaskUsecase, opts ...TaskHandlerOption) (*TaskHandler, error) {
 	if taskUsecase == nil {
 		return nil, fmt.Errorf("nil values in %v constructor", handlerName)
 	}
 
-	return &TaskHandler{taskUsecase, logger}, nil
+	th := &TaskHandler{taskUsecase: taskUsecase, logger: logger, heartbeat: DefaultHeartbeatInterval}
+	for _, opt := range opts {
+		opt(th)
+	}
+	if th.heartbeat <= 0 {
+		return nil, fmt.Errorf("%v: heartbeat interval must be positive", handlerName)
+	}
+	return th, nil
 }
 
 func (th *TaskHandler) HandlePostTask(w http.ResponseWriter, r *http.Request) {
@@ -45,6 +105,8 @@ func (th *TaskHandler) HandlePostTask(w http.ResponseWriter, r *http.Request) {
 		Status:      postReq.Status,
 		Name:        postReq.Name,
 		Description: postReq.Description,
+		AssigneeID:  postReq.AssigneeID,
+		ReporterID:  postReq.ReporterID,
 	}
 	if err := model.ValidateTask(unvalidatedTask); err != nil {
 		th.logger.Log("error in error %v: error while task validation: %v", handlerName, err)
@@ -52,68 +114,309 @@ func (th *TaskHandler) HandlePostTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tasks, err := th.taskUsecase.Store(ctx, postReq)
+	taskId, err := th.taskUsecase.Store(ctx, postReq)
 	if err != nil {
-		respondWithError(th.logger, w, http.StatusInternalServerError, "failed to store task")
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to store task")
+		return
+	}
+	if th.publicIds == nil {
+		respondWithJSON(w, http.StatusOK, taskId)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, tasks)
+	task, err := th.taskUsecase.GetByTaskId(ctx, taskId)
+	if err != nil {
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to store task")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, task.PublicID)
 }
 
+// HandleGetAllTasks responds with the tasks matching the filter, all of them unless limit or offset ask for a page
 func (th *TaskHandler) HandleGetAllTasks(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	filter, err := th.parseFilter(r)
+	if err != nil {
+		respondWithError(th.logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
 	queryParams := r.URL.Query()
-	filter := model.EmptyFilter
-
-	if typeParam := queryParams.Get("status"); typeParam != "" {
-		unvalidatedFilter := model.Filter{Status: model.TaskStatus(typeParam)}
-		if err := model.ValidateFilter(unvalidatedFilter); err != nil {
-			th.logger.Log("error in error %v: error while filter validation: %v", handlerName, err)
-			respondWithError(th.logger, w, http.StatusBadRequest, "invalid task status in filter")
+	if queryParams.Has("limit") || queryParams.Has("offset") {
+		filter.Page, err = parsePage(r)
+		if err != nil {
+			respondWithError(th.logger, w, http.StatusBadRequest, err.Error())
 			return
 		}
-		filter = unvalidatedFilter
 	}
 
 	response, err := th.taskUsecase.GetAll(ctx, filter)
 	if err != nil {
-		respondWithError(th.logger, w, http.StatusInternalServerError, "failed to retrieve tasks")
 		th.logger.Log("error in %v: %v", handlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to retrieve tasks")
 		return
 	}
-
 	respondWithJSON(w, http.StatusOK, response)
 }
 
 func (th *TaskHandler) HandleGetTaskById(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	taskId, ok := th.taskIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var tasks dto.GetTaskByIdResponse
+	var err error
+	if value := r.URL.Query().Get("as_of"); value != "" {
+		asOf, parseErr := time.Parse(time.RFC3339, value)
+		if parseErr != nil {
+			respondWithError(th.logger, w, http.StatusBadRequest, "invalid as_of, expected RFC3339 time")
+			return
+		}
+		tasks, err = th.taskUsecase.GetByTaskIdAsOf(ctx, taskId, asOf)
+	} else {
+		tasks, err = th.taskUsecase.GetByTaskId(ctx, taskId)
+	}
+	if err != nil {
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to retrieve task")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tasks)
+}
+
+func (th *TaskHandler) HandlePatchTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskId, ok := th.taskIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var patchReq dto.PatchTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&patchReq); err != nil {
+		respondWithError(th.logger, w, http.StatusBadRequest, "invalid data in task")
+		return
+	}
+
+	response, err := th.taskUsecase.Update(ctx, taskId, patchReq)
+	if err != nil {
+		th.logger.Log("error in %v: %v", handlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to update task")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (th *TaskHandler) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	taskId, ok := th.taskIdFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := th.taskUsecase.Delete(ctx, taskId); err != nil {
+		th.logger.Log("error in %v: %v", handlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to delete task")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResolvePublicIds replaces public ids in task_id and blocker_id path values with the ids of the tasks,
+// so handlers of the routes work the same way with public ids. Unknown public ids respond with 404
+// before next is called. Without WithPublicIds next is returned as is.
+//
+// It has to run inside the project scope, since tasks are looked up in the project.
+func (th *TaskHandler) ResolvePublicIds(next http.HandlerFunc) http.HandlerFunc {
+	if th.publicIds == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"task_id", "blocker_id"} {
+			publicId := r.PathValue(name)
+			if publicId == "" {
+				continue
+			}
+			taskId, err := th.publicIds.ResolvePublicId(r.Context(), publicId)
+			if err != nil {
+				respondWithUsecaseError(th.logger, w, err, "task", "failed to resolve task")
+				return
+			}
+			r.SetPathValue(name, strconv.Itoa(taskId))
+		}
+		next(w, r)
+	}
+}
+
+// taskIdFromPath parses task_id path value and responds with an error if it's invalid
+func (th *TaskHandler) taskIdFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
 	taskIDParam := r.PathValue("task_id")
 	if taskIDParam == "" {
 		respondWithError(th.logger, w, http.StatusBadRequest, "task_id wasn't provided")
-		return
+		return 0, false
 	}
 
 	taskId, err := strconv.Atoi(taskIDParam)
 	if err != nil {
 		respondWithError(th.logger, w, http.StatusBadRequest, "invalid task_id parameter")
-		return
+		return 0, false
+	}
+	return taskId, true
+}
+
+// parseFilter builds model.Filter from the query parameters of the request.
+// Returned errors are safe to be shown to the client.
+func (th *TaskHandler) parseFilter(r *http.Request) (model.Filter, error) {
+	return parseFilterValues(th.logger, r.URL.Query())
+}
+
+// parseFilterValues builds model.Filter from query parameters of GET /tasks
+func parseFilterValues(logger Logger, queryParams url.Values) (model.Filter, error) {
+	filter := model.EmptyFilter
+
+	if typeParam := queryParams.Get("status"); typeParam != "" {
+		filter.Status = model.TaskStatus(typeParam)
+		if err := model.ValidateFilter(filter); err != nil {
+			logger.Log("error in error %v: error while filter validation: %v", handlerName, err)
+			return model.EmptyFilter, errInvalidStatusFilter
+		}
 	}
 
-	tasks, err := th.taskUsecase.GetByTaskId(ctx, taskId)
-	if err != nil {
-		respondWithError(th.logger, w, http.StatusInternalServerError, "failed to retrieve task")
-		return
+	if assigneeParam := queryParams.Get("assignee"); assigneeParam != "" {
+		assigneeId, err := strconv.Atoi(assigneeParam)
+		if err != nil || assigneeId <= model.NoUser {
+			return model.EmptyFilter, errInvalidAssigneeFilter
+		}
+		filter.AssigneeID = assigneeId
 	}
 
-	respondWithJSON(w, http.StatusOK, tasks)
+	if priorityParam := queryParams.Get("priority"); priorityParam != "" {
+		filter.Priority = model.TaskPriority(priorityParam)
+		if !filter.Priority.IsValid() {
+			return model.EmptyFilter, errInvalidPriorityFilter
+		}
+	}
+
+	if overdueParam := queryParams.Get("overdue"); overdueParam != "" {
+		overdue, err := strconv.ParseBool(overdueParam)
+		if err != nil {
+			return model.EmptyFilter, errInvalidOverdueFilter
+		}
+		filter.Overdue = overdue
+	}
+
+	if recurringParam := queryParams.Get("recurring"); recurringParam != "" {
+		recurring, err := strconv.ParseBool(recurringParam)
+		if err != nil {
+			return model.EmptyFilter, errInvalidRecurringFilter
+		}
+		filter.Recurring = recurring
+	}
+
+	var err error
+	if filter.DueBefore, err = parseOptionalTime(queryParams.Get("due_before")); err != nil {
+		return model.EmptyFilter, errInvalidDueFilter
+	}
+	if filter.DueAfter, err = parseOptionalTime(queryParams.Get("due_after")); err != nil {
+		return model.EmptyFilter, errInvalidDueFilter
+	}
+	if filter.CreatedBefore, err = parseOptionalTime(queryParams.Get("created_before")); err != nil {
+		return model.EmptyFilter, errInvalidCreatedFilter
+	}
+	if filter.CreatedAfter, err = parseOptionalTime(queryParams.Get("created_after")); err != nil {
+		return model.EmptyFilter, errInvalidCreatedFilter
+	}
+	if err := model.ValidateFilter(filter); err != nil {
+		logger.Log("error in error %v: error while filter validation: %v", handlerName, err)
+		if !filter.CreatedBefore.IsZero() && filter.CreatedBefore.Before(filter.CreatedAfter) {
+			return model.EmptyFilter, errInvalidCreatedFilter
+		}
+		return model.EmptyFilter, errInvalidDueFilter
+	}
+
+	// tags=a,b matches tasks having any of the tags, with tag_match=all tasks having all of them
+	if tagsParam := queryParams.Get("tags"); tagsParam != "" {
+		for _, name := range strings.Split(tagsParam, ",") {
+			if name = model.NormalizeTagName(name); name != "" {
+				filter.Tags = append(filter.Tags, name)
+			}
+		}
+		if len(filter.Tags) == 0 {
+			return model.EmptyFilter, errInvalidTagsFilter
+		}
+	}
+	if matchParam := queryParams.Get("tag_match"); matchParam != "" {
+		filter.TagMatch = model.TagMatch(matchParam)
+		if err := model.ValidateFilter(filter); err != nil {
+			logger.Log("error in error %v: error while filter validation: %v", handlerName, err)
+			return model.EmptyFilter, errInvalidTagsFilter
+		}
+	}
+
+	// sort=due_at orders ascending, sort=-due_at descending
+	if sortParam := queryParams.Get("sort"); sortParam != "" {
+		field, descending := strings.CutPrefix(sortParam, "-")
+		filter.SortBy = model.SortField(field)
+		filter.Descending = descending
+		if err := model.ValidateFilter(filter); err != nil {
+			logger.Log("error in error %v: error while filter validation: %v", handlerName, err)
+			return model.EmptyFilter, errInvalidSort
+		}
+	}
+
+	return filter, nil
+}
+
+// parseOptionalTime parses an RFC 3339 timestamp, an empty value means there is no timestamp
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
 
 func respondWithError(logger Logger, w http.ResponseWriter, code int, message string) {
 	logger.Log("error in %v: %v", handlerName, message)
-	respondWithJSON(w, code, map[string]string{"error": message})
+	respondWithJSON(w, code, dto.ErrorResponse{Error: message})
+}
+
+// respondWithUsecaseError maps domain errors to http statuses,
+// unknown errors are reported as internal ones with the fallback message
+func respondWithUsecaseError(logger Logger, w http.ResponseWriter, err error, entity, fallback string) {
+	code, message := usecaseErrorStatus(err, entity, fallback)
+	respondWithError(logger, w, code, message)
+}
+
+// usecaseErrorStatus returns the http status and the message shown to the client for the domain error
+func usecaseErrorStatus(err error, entity, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, model.ErrUnauthenticated):
+		return http.StatusUnauthorized, "unauthorized"
+	case errors.Is(err, model.ErrForbidden):
+		return http.StatusForbidden, "forbidden"
+	case errors.Is(err, model.ErrNotFound):
+		return http.StatusNotFound, entity + " not found"
+	case errors.Is(err, model.ErrInvalid):
+		return http.StatusBadRequest, "invalid data in " + entity
+	case errors.Is(err, model.ErrAlreadyExists):
+		return http.StatusConflict, entity + " already exists"
+	case errors.Is(err, model.ErrQuotaExceeded):
+		return http.StatusConflict, "task quota exceeded"
+	case errors.Is(err, model.ErrCycle):
+		return http.StatusConflict, "dependency cycle"
+	case errors.Is(err, model.ErrIncomplete):
+		return http.StatusConflict, "task has open blockers or unfinished subtasks"
+	case errors.Is(err, model.ErrUnsupported):
+		return http.StatusNotImplemented, "not supported by the task storage"
+	default:
+		return http.StatusInternalServerError, fallback
+	}
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload any) {
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"time"
+)
+
+// taskLoader loads tasks related to the tasks of a graphql query in batches and caches them for the query,
+// so related tasks of a list cost a single usecase call instead of one per task. Resolvers of a query run
+// one at a time, so the loader isn't guarded.
+type taskLoader struct {
+	taskUsecase TaskUsecase
+	// now is the time the query started, tasks due before it are overdue
+	now time.Time
+	// tasks caches loaded tasks by id, nil marks a task that doesn't exist or can't be read
+	tasks map[int]*dto.GetTaskByIdResponse
+	// subtasks caches subtasks by the id of their parent
+	subtasks map[int][]dto.GetTaskByIdResponse
+}
+
+func newTaskLoader(taskUsecase TaskUsecase, now time.Time) *taskLoader {
+	return &taskLoader{
+		taskUsecase: taskUsecase,
+		now:         now,
+		tasks:       make(map[int]*dto.GetTaskByIdResponse),
+		subtasks:    make(map[int][]dto.GetTaskByIdResponse),
+	}
+}
+
+type taskLoaderKey struct{}
+
+func withTaskLoader(ctx context.Context, loader *taskLoader) context.Context {
+	return context.WithValue(ctx, taskLoaderKey{}, loader)
+}
+
+func taskLoaderFrom(ctx context.Context) *taskLoader {
+	return ctx.Value(taskLoaderKey{}).(*taskLoader)
+}
+
+// prime caches tasks loaded by other calls
+func (tl *taskLoader) prime(tasks ...dto.GetTaskByIdResponse) {
+	for _, task := range tasks {
+		tl.tasks[task.Id] = &task
+	}
+}
+
+// load returns the tasks with the ids, tasks that don't exist or can't be read are left out.
+// A single missing task is read by id, more of them are collected in a single pass over the tasks.
+func (tl *taskLoader) load(ctx context.Context, ids []int) (map[int]dto.GetTaskByIdResponse, error) {
+	missing := make(map[int]bool)
+	for _, id := range ids {
+		if _, ok := tl.tasks[id]; !ok {
+			missing[id] = true
+		}
+	}
+
+	switch len(missing) {
+	case 0:
+	case 1:
+		for id := range missing {
+			task, err := tl.taskUsecase.GetByTaskId(ctx, id)
+			if err != nil && !errors.Is(err, model.ErrNotFound) && !errors.Is(err, model.ErrForbidden) {
+				return nil, err
+			}
+			if err == nil {
+				tl.prime(task)
+			} else {
+				tl.tasks[id] = nil
+			}
+		}
+	default:
+		err := tl.taskUsecase.Export(ctx, model.EmptyFilter, func(task model.Task) error {
+			if missing[task.Id] {
+				tl.prime(mapper.TaskToGetTaskByIdReponse(task, tl.now))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for id := range missing {
+			if _, ok := tl.tasks[id]; !ok {
+				tl.tasks[id] = nil
+			}
+		}
+	}
+
+	ans := make(map[int]dto.GetTaskByIdResponse, len(ids))
+	for _, id := range ids {
+		if task := tl.tasks[id]; task != nil {
+			ans[id] = *task
+		}
+	}
+	return ans, nil
+}
+
+// children returns the subtasks of every parent in a single pass over the tasks
+func (tl *taskLoader) children(ctx context.Context, parentIds []int) (map[int][]dto.GetTaskByIdResponse, error) {
+	missing := make(map[int]bool)
+	for _, id := range parentIds {
+		if _, ok := tl.subtasks[id]; !ok {
+			missing[id] = true
+		}
+	}
+
+	if len(missing) > 0 {
+		loaded := make(map[int][]dto.GetTaskByIdResponse, len(missing))
+		for id := range missing {
+			loaded[id] = []dto.GetTaskByIdResponse{}
+		}
+		err := tl.taskUsecase.Export(ctx, model.EmptyFilter, func(task model.Task) error {
+			if task.ParentID != nil && missing[*task.ParentID] {
+				subtask := mapper.TaskToGetTaskByIdReponse(task, tl.now)
+				loaded[*task.ParentID] = append(loaded[*task.ParentID], subtask)
+				tl.prime(subtask)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for id, subtasks := range loaded {
+			tl.subtasks[id] = subtasks
+		}
+	}
+
+	ans := make(map[int][]dto.GetTaskByIdResponse, len(parentIds))
+	for _, id := range parentIds {
+		ans[id] = tl.subtasks[id]
+	}
+	return ans, nil
+}
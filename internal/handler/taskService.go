@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	taskv1 "ivanjabrony/test_lo/api/task/v1"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"ivanjabrony/test_lo/internal/tenant"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const taskServiceName = "TaskService"
+
+// ProjectScoper resolves the scope of a project, see ProjectUsecase
+type ProjectScoper interface {
+	Scope(ctx context.Context, projectId int) (tenant.Scope, error)
+}
+
+// TaskService serves taskv1.TaskService with the same TaskUsecase as TaskHandler,
+// so gRPC calls go through the same validation and access checks as the rest api
+type TaskService struct {
+	taskv1.UnimplementedTaskServiceServer
+	taskUsecase TaskUsecase
+	projects    ProjectScoper
+	logger      Logger
+	// publicIds is nil when tasks are referred to by their ids
+	publicIds PublicIdResolver
+	clock     clock.Clock
+}
+
+// TaskServiceOption configures optional behaviour of TaskService
+type TaskServiceOption func(*TaskService)
+
+// WithServicePublicIds makes requests and responses refer to tasks by their public ids,
+// requests without public_id are rejected
+func WithServicePublicIds(resolver PublicIdResolver) TaskServiceOption {
+	return func(ts *TaskService) {
+		ts.publicIds = resolver
+	}
+}
+
+// WithServiceClock replaces the system clock due dates of listed tasks are checked against
+func WithServiceClock(clock clock.Clock) TaskServiceOption {
+	return func(ts *TaskService) {
+		ts.clock = clock
+	}
+}
+
+func NewTaskService(logger Logger, taskUsecase TaskUsecase, projects ProjectScoper, opts ...TaskServiceOption) (*TaskService, error) {
+	if taskUsecase == nil || projects == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", taskServiceName)
+	}
+
+	ts := &TaskService{taskUsecase: taskUsecase, projects: projects, logger: logger, clock: clock.System{}}
+	for _, opt := range opts {
+		opt(ts)
+	}
+	return ts, nil
+}
+
+// Create stores the task and responds with it
+func (ts *TaskService) Create(ctx context.Context, request *taskv1.CreateTaskRequest) (*taskv1.Task, error) {
+	ctx, err := ts.scoped(ctx, request.GetProjectId())
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := ts.taskUsecase.Store(ctx, mapper.CreateTaskRequestToPostTaskRequest(request))
+	if err != nil {
+		return nil, ts.usecaseError(err, "task", "failed to store task")
+	}
+	task, err := ts.taskUsecase.GetByTaskId(ctx, id)
+	if err != nil {
+		return nil, ts.usecaseError(err, "task", "failed to retrieve task")
+	}
+
+	return ts.protoTask(task), nil
+}
+
+// Get responds with the task, or with the task as it was at as_of when it's set
+func (ts *TaskService) Get(ctx context.Context, request *taskv1.GetTaskRequest) (*taskv1.Task, error) {
+	ctx, err := ts.scoped(ctx, request.GetProjectId())
+	if err != nil {
+		return nil, err
+	}
+
+	taskId, err := ts.taskId(ctx, request.GetId(), request.GetPublicId())
+	if err != nil {
+		return nil, err
+	}
+	var task dto.GetTaskByIdResponse
+	if request.AsOf != nil {
+		task, err = ts.taskUsecase.GetByTaskIdAsOf(ctx, taskId, request.AsOf.AsTime())
+	} else {
+		task, err = ts.taskUsecase.GetByTaskId(ctx, taskId)
+	}
+	if err != nil {
+		return nil, ts.usecaseError(err, "task", "failed to retrieve task")
+	}
+
+	return ts.protoTask(task), nil
+}
+
+// List responds with a page of the tasks matching the filter, the page token is an offset
+func (ts *TaskService) List(ctx context.Context, request *taskv1.ListTasksRequest) (*taskv1.ListTasksResponse, error) {
+	filter := mapper.TaskFilterToFilter(request.GetFilter())
+	if err := model.ValidateFilter(filter); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	page, err := pageFromToken(request.GetPageSize(), request.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ctx, err = ts.scoped(ctx, request.GetProjectId())
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Page = page
+	tasks, err := ts.taskUsecase.GetAll(ctx, filter)
+	if err != nil {
+		return nil, ts.usecaseError(err, "task", "failed to retrieve tasks")
+	}
+
+	response := &taskv1.ListTasksResponse{Total: int32(tasks.Total), Tasks: make([]*taskv1.Task, 0, len(tasks.Tasks))}
+	now := ts.clock.Now()
+	for _, task := range tasks.Tasks {
+		response.Tasks = append(response.Tasks, ts.protoTask(mapper.TaskToGetTaskByIdReponse(task, now)))
+	}
+	if end := page.Offset + len(tasks.Tasks); end < tasks.Total {
+		response.NextPageToken = strconv.Itoa(end)
+	}
+	return response, nil
+}
+
+// Update changes the fields set in the request and responds with the task
+func (ts *TaskService) Update(ctx context.Context, request *taskv1.UpdateTaskRequest) (*taskv1.Task, error) {
+	ctx, err := ts.scoped(ctx, request.GetProjectId())
+	if err != nil {
+		return nil, err
+	}
+
+	taskId, err := ts.taskId(ctx, request.GetId(), request.GetPublicId())
+	if err != nil {
+		return nil, err
+	}
+	task, err := ts.taskUsecase.Update(ctx, taskId, mapper.UpdateTaskRequestToPatchTaskRequest(request))
+	if err != nil {
+		return nil, ts.usecaseError(err, "task", "failed to update task")
+	}
+
+	return ts.protoTask(task), nil
+}
+
+func (ts *TaskService) Delete(ctx context.Context, request *taskv1.DeleteTaskRequest) (*taskv1.DeleteTaskResponse, error) {
+	ctx, err := ts.scoped(ctx, request.GetProjectId())
+	if err != nil {
+		return nil, err
+	}
+
+	taskId, err := ts.taskId(ctx, request.GetId(), request.GetPublicId())
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.taskUsecase.Delete(ctx, taskId); err != nil {
+		return nil, ts.usecaseError(err, "task", "failed to delete task")
+	}
+
+	return &taskv1.DeleteTaskResponse{}, nil
+}
+
+// Watch streams changes of the tasks matching the filter like HandleTaskEvents does, a resumed stream
+// starts with a reset event when some of the missed changes can't be replayed. A client falling too far
+// behind gets Unavailable and resumes with the id of its last event.
+func (ts *TaskService) Watch(request *taskv1.WatchTasksRequest, stream grpc.ServerStreamingServer[taskv1.TaskEvent]) error {
+	filter := mapper.TaskFilterToFilter(request.GetFilter())
+	if err := model.ValidateFilter(filter); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if request.GetLastEventId() < 0 {
+		return status.Error(codes.InvalidArgument, "invalid last_event_id")
+	}
+	ctx, err := ts.scoped(stream.Context(), request.GetProjectId())
+	if err != nil {
+		return err
+	}
+
+	changes, err := ts.taskUsecase.Watch(ctx, filter, int(request.GetLastEventId()))
+	if err != nil {
+		return ts.usecaseError(err, "task", "failed to watch tasks")
+	}
+
+	if !changes.Complete {
+		if err := stream.Send(&taskv1.TaskEvent{Type: "reset", ProjectId: request.GetProjectId()}); err != nil {
+			return err
+		}
+	}
+	for _, event := range changes.Replay {
+		if err := stream.Send(ts.protoEvent(event)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-changes.Events:
+			if !ok {
+				if ctx.Err() != nil {
+					return status.FromContextError(ctx.Err()).Err()
+				}
+				return status.Error(codes.Unavailable, "stream fell too far behind, resume with last_event_id")
+			}
+			if err := stream.Send(ts.protoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// taskId returns the id of the task the request refers to, by public_id when tasks have public ids
+func (ts *TaskService) taskId(ctx context.Context, id int64, publicId string) (int, error) {
+	if ts.publicIds == nil {
+		return int(id), nil
+	}
+	if publicId == "" {
+		return 0, status.Error(codes.InvalidArgument, "public_id wasn't provided")
+	}
+	taskId, err := ts.publicIds.ResolvePublicId(ctx, publicId)
+	if err != nil {
+		return 0, ts.usecaseError(err, "task", "failed to resolve task")
+	}
+	return taskId, nil
+}
+
+// protoTask maps the task with public ids in place of its ids when tasks have public ids
+func (ts *TaskService) protoTask(task dto.GetTaskByIdResponse) *taskv1.Task {
+	if ts.publicIds == nil {
+		return mapper.GetTaskByIdResponseToProtoTask(task)
+	}
+	return mapper.GetTaskByIdResponseToPublicProtoTask(task)
+}
+
+func (ts *TaskService) protoEvent(event dto.TaskChangeEvent) *taskv1.TaskEvent {
+	ans := mapper.TaskChangeEventToProtoTaskEvent(event)
+	ans.Task = ts.protoTask(event.Task)
+	return ans
+}
+
+// scoped limits the context to the project, 0 selects the default project
+func (ts *TaskService) scoped(ctx context.Context, projectId int64) (context.Context, error) {
+	if projectId == 0 {
+		projectId = model.DefaultProjectId
+	}
+	scope, err := ts.projects.Scope(ctx, int(projectId))
+	if err != nil {
+		return nil, ts.usecaseError(err, "project", "failed to resolve project")
+	}
+	return tenant.WithScope(ctx, scope), nil
+}
+
+// usecaseError logs the error and converts it to a gRPC status with the message the rest api would respond with
+func (ts *TaskService) usecaseError(err error, entity, fallback string) error {
+	ts.logger.Log("error in %v: %v", taskServiceName, err)
+	_, message := usecaseErrorStatus(err, entity, fallback)
+	return status.Error(usecaseErrorCode(err), message)
+}
+
+// usecaseErrorCode is the gRPC counterpart of usecaseErrorStatus
+func usecaseErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, model.ErrUnauthenticated):
+		return codes.Unauthenticated
+	case errors.Is(err, model.ErrForbidden):
+		return codes.PermissionDenied
+	case errors.Is(err, model.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, model.ErrInvalid):
+		return codes.InvalidArgument
+	case errors.Is(err, model.ErrAlreadyExists):
+		return codes.AlreadyExists
+	case errors.Is(err, model.ErrQuotaExceeded):
+		return codes.ResourceExhausted
+	case errors.Is(err, model.ErrCycle), errors.Is(err, model.ErrIncomplete):
+		return codes.FailedPrecondition
+	case errors.Is(err, model.ErrUnsupported):
+		return codes.Unimplemented
+	default:
+		return codes.Internal
+	}
+}
+
+// pageFromToken builds the page of List, the token is the offset of the page
+func pageFromToken(size int32, token string) (model.Page, error) {
+	page := model.DefaultPage
+	if size != 0 {
+		page.Limit = int(size)
+	}
+	if token != "" {
+		offset, err := strconv.Atoi(token)
+		if err != nil {
+			return model.DefaultPage, errors.New("invalid page_token")
+		}
+		page.Offset = offset
+	}
+	if err := model.ValidatePage(page); err != nil {
+		return model.DefaultPage, err
+	}
+	return page, nil
+}
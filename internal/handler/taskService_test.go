@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	taskv1 "ivanjabrony/test_lo/api/task/v1"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/tenant"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestTaskService(t *testing.T, taskUsecase *MockTaskUsecase, opts ...TaskServiceOption) *TaskService {
+	t.Helper()
+	projects := &MockProjectUsecase{
+		scopeFunc: func(ctx context.Context, projectId int) (tenant.Scope, error) {
+			if projectId > 2 {
+				return tenant.Scope{}, fmt.Errorf("storage: %w", model.ErrNotFound)
+			}
+			return tenant.Scope{ProjectID: projectId}, nil
+		},
+	}
+	service, err := NewTaskService(&MockLogger{}, taskUsecase, projects, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	return service
+}
+
+func TestTaskServiceErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		usecaseError error
+		expectedCode codes.Code
+	}{
+		{name: "unauthenticated", usecaseError: model.ErrUnauthenticated, expectedCode: codes.Unauthenticated},
+		{name: "forbidden", usecaseError: model.ErrForbidden, expectedCode: codes.PermissionDenied},
+		{name: "not found", usecaseError: fmt.Errorf("storage: %w", model.ErrNotFound), expectedCode: codes.NotFound},
+		{name: "invalid", usecaseError: model.Invalid(fmt.Errorf("invalid name")), expectedCode: codes.InvalidArgument},
+		{name: "quota", usecaseError: model.ErrQuotaExceeded, expectedCode: codes.ResourceExhausted},
+		{name: "incomplete", usecaseError: model.ErrIncomplete, expectedCode: codes.FailedPrecondition},
+		{name: "unsupported", usecaseError: model.ErrUnsupported, expectedCode: codes.Unimplemented},
+		{name: "unknown", usecaseError: fmt.Errorf("disk is full"), expectedCode: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestTaskService(t, &MockTaskUsecase{
+				updateFunc: func(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error) {
+					return dto.GetTaskByIdResponse{}, tt.usecaseError
+				},
+			})
+
+			_, err := service.Update(context.Background(), &taskv1.UpdateTaskRequest{Id: 1})
+			if code := status.Code(err); code != tt.expectedCode {
+				t.Errorf("Expected code %v, got %v", tt.expectedCode, code)
+			}
+			if tt.expectedCode == codes.Internal && status.Convert(err).Message() == tt.usecaseError.Error() {
+				t.Errorf("Expected internal errors to be hidden, got %v", err)
+			}
+		})
+	}
+
+	t.Run("unknown project", func(t *testing.T) {
+		service := newTestTaskService(t, &MockTaskUsecase{})
+		if _, err := service.Delete(context.Background(), &taskv1.DeleteTaskRequest{ProjectId: 9, Id: 1}); status.Code(err) != codes.NotFound {
+			t.Errorf("Expected NotFound, got %v", err)
+		}
+	})
+}
+
+func TestTaskServiceList(t *testing.T) {
+	tasks := make([]model.Task, 0)
+	for id := range 5 {
+		tasks = append(tasks, model.Task{Id: id, Name: "task", Status: model.Created})
+	}
+
+	tests := []struct {
+		name          string
+		request       *taskv1.ListTasksRequest
+		expectedIds   []int64
+		expectedToken string
+		expectedCode  codes.Code
+	}{
+		{name: "single page", request: &taskv1.ListTasksRequest{}, expectedIds: []int64{0, 1, 2, 3, 4}},
+		{name: "first page", request: &taskv1.ListTasksRequest{PageSize: 2}, expectedIds: []int64{0, 1}, expectedToken: "2"},
+		{name: "last page", request: &taskv1.ListTasksRequest{PageSize: 2, PageToken: "4"}, expectedIds: []int64{4}},
+		{name: "past the end", request: &taskv1.ListTasksRequest{PageToken: "9"}, expectedIds: []int64{}},
+		{name: "too large page", request: &taskv1.ListTasksRequest{PageSize: model.MaxPageLimit + 1}, expectedCode: codes.InvalidArgument},
+		{name: "invalid token", request: &taskv1.ListTasksRequest{PageToken: "abc"}, expectedCode: codes.InvalidArgument},
+		{name: "invalid filter", request: &taskv1.ListTasksRequest{Filter: &taskv1.TaskFilter{Status: "archived"}}, expectedCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var projectId int
+			service := newTestTaskService(t, &MockTaskUsecase{
+				getAllFunc: func(ctx context.Context, filter model.Filter) (dto.GetAllTasksResponse, error) {
+					projectId = tenant.FromContext(ctx).ProjectID
+					return pagedResponse(tasks, filter), nil
+				},
+			})
+
+			response, err := service.List(context.Background(), tt.request)
+			if code := status.Code(err); code != tt.expectedCode {
+				t.Fatalf("Expected code %v, got %v", tt.expectedCode, err)
+			}
+			if err != nil {
+				return
+			}
+			if projectId != model.DefaultProjectId {
+				t.Errorf("Expected the default project, got %d", projectId)
+			}
+			if len(response.Tasks) != len(tt.expectedIds) || response.NextPageToken != tt.expectedToken || response.Total != 5 {
+				t.Fatalf("Expected tasks %v and token %q, got %v", tt.expectedIds, tt.expectedToken, response)
+			}
+			for i, task := range response.Tasks {
+				if task.Id != tt.expectedIds[i] {
+					t.Errorf("Expected task %d, got %d", tt.expectedIds[i], task.Id)
+				}
+			}
+		})
+	}
+}
+
+func TestTaskServicePublicIds(t *testing.T) {
+	parentId := 1
+	var parent *dto.TaskRef
+	service := newTestTaskService(t, &MockTaskUsecase{
+		storeFunc: func(ctx context.Context, request dto.PostTaskRequest) (int, error) {
+			parent = request.ParentID
+			return 2, nil
+		},
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error) {
+			return dto.GetTaskByIdResponse{Id: taskId, PublicID: "b", ParentID: &parentId, PublicParentID: "a"}, nil
+		},
+	}, WithServicePublicIds(MockPublicIdResolver{}))
+	ctx := context.Background()
+
+	parentPublicId := "a"
+	created, err := service.Create(ctx, &taskv1.CreateTaskRequest{Name: "task", Status: "created", ParentPublicId: &parentPublicId})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if parent == nil || parent.PublicID != "a" {
+		t.Errorf("Expected the parent to be referred to by its public id, got %+v", parent)
+	}
+	if created.Id != 0 || created.ParentId != nil || created.PublicId != "b" || created.GetPublicParentId() != "a" {
+		t.Errorf("Expected only public ids, got %v", created)
+	}
+
+	tests := []struct {
+		name         string
+		request      *taskv1.GetTaskRequest
+		expectedCode codes.Code
+	}{
+		{name: "public id", request: &taskv1.GetTaskRequest{PublicId: "b"}},
+		{name: "id", request: &taskv1.GetTaskRequest{Id: 2}, expectedCode: codes.InvalidArgument},
+		{name: "unknown public id", request: &taskv1.GetTaskRequest{PublicId: "c"}, expectedCode: codes.NotFound},
+		{name: "forbidden public id", request: &taskv1.GetTaskRequest{PublicId: "secret"}, expectedCode: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, err := service.Get(ctx, tt.request)
+			if code := status.Code(err); code != tt.expectedCode {
+				t.Fatalf("Expected code %v, got %v", tt.expectedCode, err)
+			}
+			if err == nil && task.PublicId != "b" {
+				t.Errorf("Expected task b, got %v", task)
+			}
+		})
+	}
+}
@@ -0,0 +1,545 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+)
+
+// maxImportBodySize limits the size of an uploaded import file
+const maxImportBodySize = 10 << 20
+
+// exportFlushEvery is an amount of exported tasks after which the response is flushed
+const exportFlushEvery = 100
+
+var csvHeader = []string{
+	"id", "status", "name", "description", "priority", "assignee_id", "reporter_id",
+	"due_at", "created_at", "started_at", "completed_at",
+}
+
+var errUnsupportedFormat = errors.New("unsupported format")
+
+// HandleExportTasks streams all the tasks matching the filter in csv, ndjson or json format.
+//
+// Tasks are written one by one as they are read from the storage,
+// so the whole export is never held in memory.
+// An error after the first task ends the stream with an error record, so a cut export doesn't look complete.
+func (th *TaskHandler) HandleExportTasks(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = FormatJSON
+	}
+
+	filter, err := th.parseFilter(r)
+	if err != nil {
+		respondWithError(th.logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var enc taskEncoder
+	switch format {
+	case FormatCSV:
+		enc = newCSVTaskEncoder(w)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="tasks.csv"`)
+	case FormatNDJSON:
+		enc = newNDJSONTaskEncoder(w)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	case FormatJSON:
+		enc = newJSONTaskEncoder(w)
+		w.Header().Set("Content-Type", "application/json")
+	default:
+		respondWithError(th.logger, w, http.StatusBadRequest, "unsupported export format")
+		return
+	}
+
+	th.exportTasks(w, r, filter, enc)
+}
+
+// exportTasks streams the tasks of the filter with the encoder
+func (th *TaskHandler) exportTasks(w http.ResponseWriter, r *http.Request, filter model.Filter, enc taskEncoder) {
+	flusher, _ := w.(http.Flusher)
+	written := 0
+	started := false
+
+	err := th.taskUsecase.Export(r.Context(), filter, func(task model.Task) error {
+		if !started {
+			started = true
+			w.WriteHeader(http.StatusOK)
+			if err := enc.Begin(); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(task); err != nil {
+			return err
+		}
+		written++
+		if flusher != nil && written%exportFlushEvery == 0 {
+			if err := enc.Flush(); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		th.logger.Log("error in %v: export failed after %d tasks: %v", handlerName, written, err)
+		if !started {
+			w.Header().Del("Content-Disposition")
+			respondWithUsecaseError(th.logger, w, err, "task", "failed to export tasks")
+			return
+		}
+		// the status is already sent, so the failure is reported by the last record of the stream
+		_, message := usecaseErrorStatus(err, "task", "failed to export tasks")
+		if err := enc.Fail(message); err != nil {
+			th.logger.Log("error in %v: %v", handlerName, err)
+		}
+		return
+	}
+
+	if !started {
+		w.WriteHeader(http.StatusOK)
+		if err := enc.Begin(); err != nil {
+			th.logger.Log("error in %v: %v", handlerName, err)
+			return
+		}
+	}
+	if err := enc.End(); err != nil {
+		th.logger.Log("error in %v: %v", handlerName, err)
+	}
+}
+
+// HandleImportTasks ingests tasks from a csv or ndjson body.
+//
+// The format is taken from the "format" query parameter or from the Content-Type header.
+// Source columns (or ndjson keys) can be renamed with repeated map=source:target parameters.
+// With dry_run=true tasks are only validated and nothing is stored.
+func (th *TaskHandler) HandleImportTasks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	queryParams := r.URL.Query()
+
+	format, err := importFormat(queryParams.Get("format"), r.Header.Get("Content-Type"))
+	if err != nil {
+		respondWithError(th.logger, w, http.StatusBadRequest, "unsupported import format")
+		return
+	}
+
+	dryRun := false
+	if dryRunParam := queryParams.Get("dry_run"); dryRunParam != "" {
+		dryRun, err = strconv.ParseBool(dryRunParam)
+		if err != nil {
+			respondWithError(th.logger, w, http.StatusBadRequest, "invalid dry_run parameter")
+			return
+		}
+	}
+
+	mapping, err := parseHeaderMapping(queryParams["map"])
+	if err != nil {
+		respondWithError(th.logger, w, http.StatusBadRequest, "invalid map parameter")
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBodySize)
+
+	var rows []dto.ImportTaskRow
+	var rowErrors []dto.ImportRowError
+	switch format {
+	case FormatCSV:
+		rows, rowErrors, err = decodeCSVRows(body, mapping)
+	case FormatNDJSON:
+		rows, rowErrors, err = decodeNDJSONRows(body, mapping)
+	}
+	if err != nil {
+		th.logger.Log("error in %v: error while reading import body: %v", handlerName, err)
+		respondWithError(th.logger, w, http.StatusBadRequest, "failed to read import body")
+		return
+	}
+
+	response, err := th.taskUsecase.Import(ctx, rows, dryRun)
+	if err != nil {
+		th.logger.Log("error in %v: %v", handlerName, err)
+		respondWithUsecaseError(th.logger, w, err, "task", "failed to import tasks")
+		return
+	}
+
+	response.Total += len(rowErrors)
+	response.Failed += len(rowErrors)
+	response.Errors = mergeRowErrors(rowErrors, response.Errors)
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func importFormat(formatParam, contentType string) (string, error) {
+	switch formatParam {
+	case FormatCSV, FormatNDJSON:
+		return formatParam, nil
+	case "":
+	default:
+		return "", errUnsupportedFormat
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, nil
+	}
+	return "", errUnsupportedFormat
+}
+
+// parseHeaderMapping parses source:target pairs, targets must be known task fields
+func parseHeaderMapping(pairs []string) (map[string]string, error) {
+	mapping := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		for _, item := range strings.Split(pair, ",") {
+			source, target, ok := strings.Cut(item, ":")
+			source, target = normalizeColumn(source), normalizeColumn(target)
+			if !ok || source == "" || !isImportField(target) {
+				return nil, fmt.Errorf("invalid mapping %q", item)
+			}
+			mapping[source] = target
+		}
+	}
+	return mapping, nil
+}
+
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func isImportField(field string) bool {
+	switch field {
+	case "status", "name", "description", "priority", "assignee_id", "reporter_id", "due_at":
+		return true
+	}
+	return false
+}
+
+// resolveColumn returns the task field a source column is mapped to, or an empty string
+func resolveColumn(mapping map[string]string, column string) string {
+	column = normalizeColumn(column)
+	if target, ok := mapping[column]; ok {
+		return target
+	}
+	if isImportField(column) {
+		return column
+	}
+	return ""
+}
+
+func recordToPostTaskRequest(record map[string]string) (dto.PostTaskRequest, error) {
+	request := dto.PostTaskRequest{
+		Status:      model.TaskStatus(record["status"]),
+		Name:        record["name"],
+		Description: record["description"],
+		Priority:    model.TaskPriority(strings.TrimSpace(record["priority"])),
+	}
+
+	var err error
+	if request.AssigneeID, err = parseOptionalId(record["assignee_id"]); err != nil {
+		return request, fmt.Errorf("invalid assignee_id: %w", err)
+	}
+	if request.ReporterID, err = parseOptionalId(record["reporter_id"]); err != nil {
+		return request, fmt.Errorf("invalid reporter_id: %w", err)
+	}
+	if request.DueAt, err = parseOptionalTime(strings.TrimSpace(record["due_at"])); err != nil {
+		return request, fmt.Errorf("invalid due_at: %w", err)
+	}
+	return request, nil
+}
+
+// parseOptionalId parses an id column, an empty value means there is no id
+func parseOptionalId(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func formatOptionalId(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func decodeCSVRows(r io.Reader, mapping map[string]string) ([]dto.ImportTaskRow, []dto.ImportRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't read csv header: %w", err)
+	}
+	fields := make([]string, len(header))
+	for i, column := range header {
+		fields[i] = resolveColumn(mapping, strings.TrimPrefix(column, "\ufeff"))
+	}
+
+	rows := make([]dto.ImportTaskRow, 0)
+	rowErrors := make([]dto.ImportRowError, 0)
+	for {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			rowErrors = append(rowErrors, dto.ImportRowError{Row: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if len(values) != len(header) {
+			rowErrors = append(rowErrors, dto.ImportRowError{
+				Row:   line,
+				Error: fmt.Sprintf("expected %d fields, got %d", len(header), len(values)),
+			})
+			continue
+		}
+
+		record := make(map[string]string, len(values))
+		for i, value := range values {
+			if fields[i] != "" {
+				record[fields[i]] = value
+			}
+		}
+		request, err := recordToPostTaskRequest(record)
+		if err != nil {
+			rowErrors = append(rowErrors, dto.ImportRowError{Row: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, dto.ImportTaskRow{Row: line, Request: request})
+	}
+
+	return rows, rowErrors, nil
+}
+
+func decodeNDJSONRows(r io.Reader, mapping map[string]string) ([]dto.ImportTaskRow, []dto.ImportRowError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportBodySize)
+
+	rows := make([]dto.ImportTaskRow, 0)
+	rowErrors := make([]dto.ImportRowError, 0)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var object map[string]any
+		if err := json.Unmarshal(data, &object); err != nil {
+			rowErrors = append(rowErrors, dto.ImportRowError{Row: line, Error: "invalid json: " + err.Error()})
+			continue
+		}
+
+		record := make(map[string]string, len(object))
+		for key, value := range object {
+			field := resolveColumn(mapping, key)
+			if field == "" || value == nil {
+				continue
+			}
+			switch v := value.(type) {
+			case string:
+				record[field] = v
+			default:
+				record[field] = fmt.Sprint(v)
+			}
+		}
+		request, err := recordToPostTaskRequest(record)
+		if err != nil {
+			rowErrors = append(rowErrors, dto.ImportRowError{Row: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, dto.ImportTaskRow{Row: line, Request: request})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return rows, rowErrors, nil
+}
+
+// mergeRowErrors merges two lists of errors sorted by row into a single sorted list
+func mergeRowErrors(a, b []dto.ImportRowError) []dto.ImportRowError {
+	merged := make([]dto.ImportRowError, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0].Row <= b[0].Row {
+			merged, a = append(merged, a[0]), a[1:]
+		} else {
+			merged, b = append(merged, b[0]), b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+type taskEncoder interface {
+	Begin() error
+	Encode(task model.Task) error
+	// Flush writes buffered tasks to the underlying writer
+	Flush() error
+	End() error
+	// Fail ends the stream with an error record instead of End
+	Fail(message string) error
+}
+
+type csvTaskEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVTaskEncoder(w io.Writer) *csvTaskEncoder {
+	return &csvTaskEncoder{csv.NewWriter(w)}
+}
+
+func (e *csvTaskEncoder) Begin() error {
+	return e.w.Write(csvHeader)
+}
+
+// Encode writes the public id of the task in the id column once it has one, like responses do
+func (e *csvTaskEncoder) Encode(task model.Task) error {
+	ids, _ := task.PublicIds()
+	return e.w.Write([]string{
+		ids.Id,
+		string(task.Status),
+		task.Name,
+		task.Description,
+		string(task.Priority),
+		formatOptionalId(task.AssigneeID),
+		formatOptionalId(task.ReporterID),
+		formatOptionalTime(task.DueAt),
+		task.CreatedAt.Format(time.RFC3339Nano),
+		formatOptionalTime(task.StartedAt),
+		formatOptionalTime(task.CompletedAt),
+	})
+}
+
+func (e *csvTaskEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvTaskEncoder) End() error {
+	return e.Flush()
+}
+
+// Fail writes an "error" row of two fields, so readers expecting every row to have the header's fields stop at it
+func (e *csvTaskEncoder) Fail(message string) error {
+	if err := e.w.Write([]string{"error", message}); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+type ndjsonTaskEncoder struct {
+	enc *json.Encoder
+}
+
+func newNDJSONTaskEncoder(w io.Writer) *ndjsonTaskEncoder {
+	return &ndjsonTaskEncoder{json.NewEncoder(w)}
+}
+
+func (e *ndjsonTaskEncoder) Begin() error {
+	return nil
+}
+
+func (e *ndjsonTaskEncoder) Encode(task model.Task) error {
+	return e.enc.Encode(dto.TaskResponse(task))
+}
+
+func (e *ndjsonTaskEncoder) Flush() error {
+	return nil
+}
+
+func (e *ndjsonTaskEncoder) End() error {
+	return nil
+}
+
+func (e *ndjsonTaskEncoder) Fail(message string) error {
+	return e.enc.Encode(dto.ErrorResponse{Error: message})
+}
+
+// jsonTaskEncoder writes tasks as a single json array
+type jsonTaskEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONTaskEncoder(w io.Writer) *jsonTaskEncoder {
+	return &jsonTaskEncoder{w: w}
+}
+
+func (e *jsonTaskEncoder) Begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonTaskEncoder) Encode(task model.Task) error {
+	data, err := json.Marshal(dto.TaskResponse(task))
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonTaskEncoder) Flush() error {
+	return nil
+}
+
+func (e *jsonTaskEncoder) End() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// Fail closes the array with an error object as its last element
+func (e *jsonTaskEncoder) Fail(message string) error {
+	data, err := json.Marshal(dto.ErrorResponse{Error: message})
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	if _, err := e.w.Write(data); err != nil {
+		return err
+	}
+	return e.End()
+}
@@ -0,0 +1,395 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleExportTasks(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	testTasks := []model.Task{
+		{Id: 0, Name: "Task 1", Description: "Desc, with comma", Status: model.Done, CreatedAt: now},
+		{Id: 1, Name: "Task 2", Description: "Desc 2", Status: model.Done, CreatedAt: now},
+	}
+
+	newHandler := func(t *testing.T, wantStatus model.TaskStatus) *TaskHandler {
+		mockUsecase := &MockTaskUsecase{
+			exportFunc: func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+				if filter.Status != wantStatus {
+					t.Errorf("Filter mismatch. Expected %q, got %q", wantStatus, filter.Status)
+				}
+				for _, task := range testTasks {
+					if err := fn(task); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+		handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+		return handler
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/tasks/export?format=csv&status=done", nil)
+		w := httptest.NewRecorder()
+		newHandler(t, model.Done).HandleExportTasks(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to parse csv: %v", err)
+		}
+		if len(records) != 3 {
+			t.Fatalf("Expected header and 2 rows, got %d records", len(records))
+		}
+		if strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+			t.Errorf("Unexpected header %v", records[0])
+		}
+		if records[1][3] != "Desc, with comma" {
+			t.Errorf("Unexpected description %q", records[1][3])
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/tasks/export?format=ndjson", nil)
+		w := httptest.NewRecorder()
+		newHandler(t, "").HandleExportTasks(w, req)
+
+		scanner := bufio.NewScanner(w.Body)
+		lines := 0
+		for scanner.Scan() {
+			var task model.Task
+			if err := json.Unmarshal(scanner.Bytes(), &task); err != nil {
+				t.Fatalf("Failed to decode line %d: %v", lines, err)
+			}
+			if task.Id != testTasks[lines].Id {
+				t.Errorf("Expected task %d, got %d", testTasks[lines].Id, task.Id)
+			}
+			lines++
+		}
+		if lines != len(testTasks) {
+			t.Errorf("Expected %d lines, got %d", len(testTasks), lines)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/tasks/export", nil)
+		w := httptest.NewRecorder()
+		newHandler(t, "").HandleExportTasks(w, req)
+
+		var tasks []model.Task
+		if err := json.NewDecoder(w.Body).Decode(&tasks); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(tasks) != len(testTasks) {
+			t.Errorf("Expected %d tasks, got %d", len(testTasks), len(tasks))
+		}
+	})
+
+	t.Run("public ids", func(t *testing.T) {
+		mockUsecase := &MockTaskUsecase{
+			exportFunc: func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+				parentId := 1
+				return fn(model.Task{Id: 2, PublicID: "b", ParentID: &parentId, PublicParentID: "a", Name: "Task", Status: model.Created, CreatedAt: now})
+			},
+		}
+		handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+		w := httptest.NewRecorder()
+		handler.HandleExportTasks(w, httptest.NewRequest("GET", "/tasks/export?format=csv", nil))
+		records, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil || len(records) != 2 || records[1][0] != "b" {
+			t.Errorf("Expected the public id in the id column, got %v, %v", records, err)
+		}
+
+		w = httptest.NewRecorder()
+		handler.HandleExportTasks(w, httptest.NewRequest("GET", "/tasks/export?format=ndjson", nil))
+		var task map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &task); err != nil || task["id"] != "b" || task["parent_id"] != "a" {
+			t.Errorf("Expected public ids, got %v, %v", task, err)
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/tasks/export?format=xml", nil)
+		w := httptest.NewRecorder()
+		newHandler(t, "").HandleExportTasks(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("csv rows are flushed while streaming", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mockUsecase := &MockTaskUsecase{
+			exportFunc: func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+				for i := range exportFlushEvery + 1 {
+					if i == exportFlushEvery {
+						// the header and a full batch of rows are sent before the next task is read
+						if lines := strings.Count(w.Body.String(), "\n"); lines != exportFlushEvery+1 {
+							t.Errorf("Expected %d flushed lines, got %d", exportFlushEvery+1, lines)
+						}
+					}
+					if err := fn(model.Task{Id: i, Name: "Task", Status: model.Created, CreatedAt: now}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+		handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+		req := httptest.NewRequest("GET", "/tasks/export?format=csv", nil)
+		handler.HandleExportTasks(w, req)
+
+		if !w.Flushed {
+			t.Error("Expected the response to be flushed")
+		}
+	})
+
+	t.Run("usecase error before first task", func(t *testing.T) {
+		mockUsecase := &MockTaskUsecase{
+			exportFunc: func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+				return errors.New("usecase error")
+			},
+		}
+		handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+		req := httptest.NewRequest("GET", "/tasks/export?format=csv", nil)
+		w := httptest.NewRecorder()
+		handler.HandleExportTasks(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+	})
+
+	t.Run("usecase error after first task", func(t *testing.T) {
+		mockUsecase := &MockTaskUsecase{
+			exportFunc: func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+				if err := fn(testTasks[0]); err != nil {
+					return err
+				}
+				return errors.New("usecase error")
+			},
+		}
+		handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+		w := httptest.NewRecorder()
+		handler.HandleExportTasks(w, httptest.NewRequest("GET", "/tasks/export?format=csv", nil))
+		reader := csv.NewReader(w.Body)
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if err != nil || len(records) != 3 || strings.Join(records[2], ",") != "error,failed to export tasks" {
+			t.Errorf("Expected the header, a row and an error row, got %v, %v", records, err)
+		}
+
+		w = httptest.NewRecorder()
+		handler.HandleExportTasks(w, httptest.NewRequest("GET", "/tasks/export?format=ndjson", nil))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != 2 || lines[1] != `{"error":"failed to export tasks"}` {
+			t.Errorf("Expected a task and an error line, got %q", lines)
+		}
+
+		w = httptest.NewRecorder()
+		handler.HandleExportTasks(w, httptest.NewRequest("GET", "/tasks/export", nil))
+		var elements []map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &elements); err != nil {
+			t.Fatalf("Expected a closed array, got %q: %v", w.Body.String(), err)
+		}
+		if len(elements) != 2 || elements[1]["error"] != "failed to export tasks" {
+			t.Errorf("Expected a task and an error object, got %v", elements)
+		}
+	})
+
+	t.Run("encoder error after first task", func(t *testing.T) {
+		handler := newHandler(t, "")
+		w := httptest.NewRecorder()
+		enc := &failingTaskEncoder{jsonTaskEncoder: newJSONTaskEncoder(w), failAt: 1}
+		handler.exportTasks(w, httptest.NewRequest("GET", "/tasks/export", nil), model.Filter{}, enc)
+
+		var elements []map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &elements); err != nil {
+			t.Fatalf("Expected a closed array, got %q: %v", w.Body.String(), err)
+		}
+		if len(elements) != 2 || elements[0]["name"] != testTasks[0].Name || elements[1]["error"] != "failed to export tasks" {
+			t.Errorf("Expected a task and an error object, got %v", elements)
+		}
+	})
+}
+
+func TestHandleImportTasks(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		contentType    string
+		body           string
+		wantDryRun     bool
+		wantRows       []dto.ImportTaskRow
+		wantRowErrors  []int
+		expectedStatus int
+	}{
+		{
+			name:        "csv with header mapping",
+			target:      "/tasks/import?map=title:name,state:status",
+			contentType: "text/csv",
+			body: "Title,State,description,ignored\n" +
+				"first,created,d1,x\n" +
+				"second,done,d2,y\n",
+			wantRows: []dto.ImportTaskRow{
+				{Row: 2, Request: dto.PostTaskRequest{Name: "first", Status: model.Created, Description: "d1"}},
+				{Row: 3, Request: dto.PostTaskRequest{Name: "second", Status: model.Done, Description: "d2"}},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "csv with broken row",
+			target:      "/tasks/import?format=csv",
+			contentType: "",
+			body: "name,status\n" +
+				"first,created\n" +
+				"only one field\n" +
+				"third,done\n",
+			wantRows: []dto.ImportTaskRow{
+				{Row: 2, Request: dto.PostTaskRequest{Name: "first", Status: model.Created}},
+				{Row: 4, Request: dto.PostTaskRequest{Name: "third", Status: model.Done}},
+			},
+			wantRowErrors:  []int{3},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "ndjson dry run",
+			target:      "/tasks/import?dry_run=true",
+			contentType: "application/x-ndjson",
+			body: `{"name": "first", "status": "created"}` + "\n" +
+				"\n" +
+				`{"name": broken}` + "\n" +
+				`{"name": "fourth", "status": "inProgress", "description": null}` + "\n",
+			wantDryRun: true,
+			wantRows: []dto.ImportTaskRow{
+				{Row: 1, Request: dto.PostTaskRequest{Name: "first", Status: model.Created}},
+				{Row: 4, Request: dto.PostTaskRequest{Name: "fourth", Status: model.InProgress}},
+			},
+			wantRowErrors:  []int{3},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "csv with priority and due date",
+			target:      "/tasks/import",
+			contentType: "text/csv",
+			body: "name,status,priority,due_at\n" +
+				"first,created,high,2025-06-01T12:00:00Z\n" +
+				"second,created,,not a date\n",
+			wantRows: []dto.ImportTaskRow{
+				{Row: 2, Request: dto.PostTaskRequest{
+					Name:     "first",
+					Status:   model.Created,
+					Priority: model.PriorityHigh,
+					DueAt:    time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+				}},
+			},
+			wantRowErrors:  []int{3},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown format",
+			target:         "/tasks/import",
+			contentType:    "application/xml",
+			body:           "<tasks/>",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid mapping",
+			target:         "/tasks/import?format=csv&map=title:unknown",
+			body:           "title\nfirst\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockTaskUsecase{
+				importFunc: func(ctx context.Context, rows []dto.ImportTaskRow, dryRun bool) (dto.ImportTasksResponse, error) {
+					if dryRun != tt.wantDryRun {
+						t.Errorf("Expected dryRun %v, got %v", tt.wantDryRun, dryRun)
+					}
+					if len(rows) != len(tt.wantRows) {
+						t.Fatalf("Expected %d rows, got %d: %v", len(tt.wantRows), len(rows), rows)
+					}
+					for i := range rows {
+						if rows[i] != tt.wantRows[i] {
+							t.Errorf("Row mismatch. Expected %+v, got %+v", tt.wantRows[i], rows[i])
+						}
+					}
+					return dto.ImportTasksResponse{
+						DryRun:   dryRun,
+						Total:    len(rows),
+						Imported: len(rows),
+						Ids:      []dto.TaskRef{},
+						Errors:   []dto.ImportRowError{},
+					}, nil
+				},
+			}
+			handler, _ := NewTaskHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("POST", tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			handler.HandleImportTasks(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response dto.ImportTasksResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Total != len(tt.wantRows)+len(tt.wantRowErrors) {
+				t.Errorf("Unexpected total %d", response.Total)
+			}
+			if response.Failed != len(tt.wantRowErrors) || len(response.Errors) != len(tt.wantRowErrors) {
+				t.Fatalf("Expected errors for rows %v, got %v", tt.wantRowErrors, response.Errors)
+			}
+			for i, row := range tt.wantRowErrors {
+				if response.Errors[i].Row != row {
+					t.Errorf("Expected error for row %d, got %d", row, response.Errors[i].Row)
+				}
+			}
+		})
+	}
+}
+
+// failingTaskEncoder fails to encode the task at failAt
+type failingTaskEncoder struct {
+	*jsonTaskEncoder
+	failAt  int
+	encoded int
+}
+
+func (e *failingTaskEncoder) Encode(task model.Task) error {
+	if e.encoded == e.failAt {
+		return errors.New("encoder error")
+	}
+	e.encoded++
+	return e.jsonTaskEncoder.Encode(task)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strconv"
+)
+
+const userHandlerName = "UserHandler"
+
+type UserUsecase interface {
+	Store(ctx context.Context, request dto.PostUserRequest) (int, error)
+	GetAll(ctx context.Context) (dto.GetAllUsersResponse, error)
+	GetByUserId(ctx context.Context, userId int) (dto.GetUserByIdResponse, error)
+	Update(ctx context.Context, userId int, request dto.PutUserRequest) (dto.GetUserByIdResponse, error)
+	Delete(ctx context.Context, userId int) error
+	GetTasks(ctx context.Context, userId int) (dto.GetAllTasksResponse, error)
+}
+
+type UserHandler struct {
+	userUsecase UserUsecase
+	logger      Logger
+}
+
+func NewUserHandler(logger Logger, userUsecase UserUsecase) (*UserHandler, error) {
+	if userUsecase == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", userHandlerName)
+	}
+
+	return &UserHandler{userUsecase, logger}, nil
+}
+
+func (uh *UserHandler) HandlePostUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var postReq dto.PostUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&postReq); err != nil {
+		respondWithError(uh.logger, w, http.StatusBadRequest, "invalid data in user")
+		return
+	}
+
+	if err := model.ValidateUser(model.User{Name: postReq.Name, Email: postReq.Email}); err != nil {
+		uh.logger.Log("error in %v: error while user validation: %v", userHandlerName, err)
+		respondWithError(uh.logger, w, http.StatusBadRequest, "invalid data in user")
+		return
+	}
+
+	id, err := uh.userUsecase.Store(ctx, postReq)
+	if err != nil {
+		respondWithUsecaseError(uh.logger, w, err, "user", "failed to store user")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, id)
+}
+
+func (uh *UserHandler) HandleGetAllUsers(w http.ResponseWriter, r *http.Request) {
+	response, err := uh.userUsecase.GetAll(r.Context())
+	if err != nil {
+		uh.logger.Log("error in %v: %v", userHandlerName, err)
+		respondWithUsecaseError(uh.logger, w, err, "user", "failed to retrieve users")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) HandleGetUserById(w http.ResponseWriter, r *http.Request) {
+	userId, ok := userIdFromPath(uh.logger, w, r)
+	if !ok {
+		return
+	}
+
+	response, err := uh.userUsecase.GetByUserId(r.Context(), userId)
+	if err != nil {
+		respondWithUsecaseError(uh.logger, w, err, "user", "failed to retrieve user")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) HandlePutUser(w http.ResponseWriter, r *http.Request) {
+	userId, ok := userIdFromPath(uh.logger, w, r)
+	if !ok {
+		return
+	}
+
+	var putReq dto.PutUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&putReq); err != nil {
+		respondWithError(uh.logger, w, http.StatusBadRequest, "invalid data in user")
+		return
+	}
+
+	if err := model.ValidateUser(model.User{Id: userId, Name: putReq.Name, Email: putReq.Email}); err != nil {
+		uh.logger.Log("error in %v: error while user validation: %v", userHandlerName, err)
+		respondWithError(uh.logger, w, http.StatusBadRequest, "invalid data in user")
+		return
+	}
+
+	response, err := uh.userUsecase.Update(r.Context(), userId, putReq)
+	if err != nil {
+		respondWithUsecaseError(uh.logger, w, err, "user", "failed to update user")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	userId, ok := userIdFromPath(uh.logger, w, r)
+	if !ok {
+		return
+	}
+
+	if err := uh.userUsecase.Delete(r.Context(), userId); err != nil {
+		respondWithUsecaseError(uh.logger, w, err, "user", "failed to delete user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (uh *UserHandler) HandleGetUserTasks(w http.ResponseWriter, r *http.Request) {
+	userId, ok := userIdFromPath(uh.logger, w, r)
+	if !ok {
+		return
+	}
+
+	response, err := uh.userUsecase.GetTasks(r.Context(), userId)
+	if err != nil {
+		uh.logger.Log("error in %v: %v", userHandlerName, err)
+		respondWithUsecaseError(uh.logger, w, err, "user", "failed to retrieve tasks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// userIdFromPath parses user_id path value and responds with an error if it's invalid
+func userIdFromPath(logger Logger, w http.ResponseWriter, r *http.Request) (int, bool) {
+	userIdParam := r.PathValue("user_id")
+	if userIdParam == "" {
+		respondWithError(logger, w, http.StatusBadRequest, "user_id wasn't provided")
+		return 0, false
+	}
+
+	userId, err := strconv.Atoi(userIdParam)
+	if err != nil {
+		respondWithError(logger, w, http.StatusBadRequest, "invalid user_id parameter")
+		return 0, false
+	}
+	return userId, true
+}
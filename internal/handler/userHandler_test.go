@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockUserUsecase struct {
+	storeFunc       func(ctx context.Context, request dto.PostUserRequest) (int, error)
+	getAllFunc      func(ctx context.Context) (dto.GetAllUsersResponse, error)
+	getByUserIdFunc func(ctx context.Context, userId int) (dto.GetUserByIdResponse, error)
+	updateFunc      func(ctx context.Context, userId int, request dto.PutUserRequest) (dto.GetUserByIdResponse, error)
+	deleteFunc      func(ctx context.Context, userId int) error
+	getTasksFunc    func(ctx context.Context, userId int) (dto.GetAllTasksResponse, error)
+}
+
+func (m *MockUserUsecase) Store(ctx context.Context, request dto.PostUserRequest) (int, error) {
+	return m.storeFunc(ctx, request)
+}
+
+func (m *MockUserUsecase) GetAll(ctx context.Context) (dto.GetAllUsersResponse, error) {
+	return m.getAllFunc(ctx)
+}
+
+func (m *MockUserUsecase) GetByUserId(ctx context.Context, userId int) (dto.GetUserByIdResponse, error) {
+	return m.getByUserIdFunc(ctx, userId)
+}
+
+func (m *MockUserUsecase) Update(ctx context.Context, userId int, request dto.PutUserRequest) (dto.GetUserByIdResponse, error) {
+	return m.updateFunc(ctx, userId, request)
+}
+
+func (m *MockUserUsecase) Delete(ctx context.Context, userId int) error {
+	return m.deleteFunc(ctx, userId)
+}
+
+func (m *MockUserUsecase) GetTasks(ctx context.Context, userId int) (dto.GetAllTasksResponse, error) {
+	return m.getTasksFunc(ctx, userId)
+}
+
+func TestHandlePostUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		usecaseError   error
+		expectedStatus int
+	}{
+		{
+			name:           "successful post",
+			body:           `{"name": "John", "email": "john@example.com"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid email",
+			body:           `{"name": "John", "email": "john"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed body",
+			body:           `{"name": `,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "duplicate email",
+			body:           `{"name": "John", "email": "john@example.com"}`,
+			usecaseError:   fmt.Errorf("storage: %w", model.ErrAlreadyExists),
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockUserUsecase{
+				storeFunc: func(ctx context.Context, request dto.PostUserRequest) (int, error) {
+					return 1, tt.usecaseError
+				},
+			}
+			handler, _ := NewUserHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("POST", "/users", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.HandlePostUser(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandleGetUserTasks(t *testing.T) {
+	tests := []struct {
+		name           string
+		userId         string
+		usecaseError   error
+		expectedStatus int
+	}{
+		{
+			name:           "successful get",
+			userId:         "1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid user id",
+			userId:         "abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "user not found",
+			userId:         "2",
+			usecaseError:   fmt.Errorf("storage: %w", model.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsecase := &MockUserUsecase{
+				getTasksFunc: func(ctx context.Context, userId int) (dto.GetAllTasksResponse, error) {
+					if tt.usecaseError != nil {
+						return dto.GetAllTasksResponse{}, tt.usecaseError
+					}
+					return dto.GetAllTasksResponse{Amount: 1, Tasks: []model.Task{{Id: 3, AssigneeID: userId}}}, nil
+				},
+			}
+			handler, _ := NewUserHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("GET", "/users/"+tt.userId+"/tasks", nil)
+			req.SetPathValue("user_id", tt.userId)
+			w := httptest.NewRecorder()
+			handler.HandleGetUserTasks(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				var response dto.GetAllTasksResponse
+				if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				if response.Amount != 1 || response.Tasks[0].AssigneeID != 1 {
+					t.Errorf("Unexpected response %v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleDeleteUser(t *testing.T) {
+	mockUsecase := &MockUserUsecase{
+		deleteFunc: func(ctx context.Context, userId int) error {
+			if userId != 1 {
+				return fmt.Errorf("storage: %w", model.ErrNotFound)
+			}
+			return nil
+		},
+	}
+	handler, _ := NewUserHandler(&MockLogger{}, mockUsecase)
+
+	for userId, expectedStatus := range map[string]int{"1": http.StatusNoContent, "2": http.StatusNotFound} {
+		req := httptest.NewRequest("DELETE", "/users/"+userId, nil)
+		req.SetPathValue("user_id", userId)
+		w := httptest.NewRecorder()
+		handler.HandleDeleteUser(w, req)
+
+		if w.Code != expectedStatus {
+			t.Errorf("Expected status %d for user %s, got %d", expectedStatus, userId, w.Code)
+		}
+	}
+}
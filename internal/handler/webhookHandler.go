@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+)
+
+const webhookHandlerName = "WebhookHandler"
+
+type WebhookUsecase interface {
+	Store(ctx context.Context, request dto.PostWebhookRequest) (dto.PostWebhookResponse, error)
+	GetAll(ctx context.Context) (dto.GetAllWebhooksResponse, error)
+	GetById(ctx context.Context, webhookId int) (dto.GetWebhookByIdResponse, error)
+	Update(ctx context.Context, webhookId int, request dto.PutWebhookRequest) (dto.GetWebhookByIdResponse, error)
+	Delete(ctx context.Context, webhookId int) error
+	GetDeliveries(ctx context.Context, webhookId int, page model.Page) (dto.GetWebhookDeliveriesResponse, error)
+	GetDeadLetters(ctx context.Context, webhookId int) (dto.GetDeadLettersResponse, error)
+	Redeliver(ctx context.Context, webhookId int, deliveryId string) error
+}
+
+type WebhookHandler struct {
+	webhookUsecase WebhookUsecase
+	logger         Logger
+}
+
+func NewWebhookHandler(logger Logger, webhookUsecase WebhookUsecase) (*WebhookHandler, error) {
+	if webhookUsecase == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", webhookHandlerName)
+	}
+
+	return &WebhookHandler{webhookUsecase, logger}, nil
+}
+
+// HandlePostWebhook creates the webhook and responds with its id and secret, the secret isn't shown again
+func (wh *WebhookHandler) HandlePostWebhook(w http.ResponseWriter, r *http.Request) {
+	var postReq dto.PostWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&postReq); err != nil {
+		respondWithError(wh.logger, w, http.StatusBadRequest, "invalid data in webhook")
+		return
+	}
+
+	response, err := wh.webhookUsecase.Store(r.Context(), postReq)
+	if err != nil {
+		wh.logger.Log("error in %v: %v", webhookHandlerName, err)
+		respondWithUsecaseError(wh.logger, w, err, "webhook", "failed to store webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (wh *WebhookHandler) HandleGetAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	response, err := wh.webhookUsecase.GetAll(r.Context())
+	if err != nil {
+		wh.logger.Log("error in %v: %v", webhookHandlerName, err)
+		respondWithUsecaseError(wh.logger, w, err, "webhook", "failed to retrieve webhooks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (wh *WebhookHandler) HandleGetWebhookById(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := idFromPath(wh.logger, w, r, "webhook_id")
+	if !ok {
+		return
+	}
+
+	response, err := wh.webhookUsecase.GetById(r.Context(), webhookId)
+	if err != nil {
+		wh.logger.Log("error in %v: %v", webhookHandlerName, err)
+		respondWithUsecaseError(wh.logger, w, err, "webhook", "failed to retrieve webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (wh *WebhookHandler) HandlePutWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := idFromPath(wh.logger, w, r, "webhook_id")
+	if !ok {
+		return
+	}
+
+	var putReq dto.PutWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&putReq); err != nil {
+		respondWithError(wh.logger, w, http.StatusBadRequest, "invalid data in webhook")
+		return
+	}
+
+	response, err := wh.webhookUsecase.Update(r.Context(), webhookId, putReq)
+	if err != nil {
+		wh.logger.Log("error in %v: %v", webhookHandlerName, err)
+		respondWithUsecaseError(wh.logger, w, err, "webhook", "failed to update webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (wh *WebhookHandler) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := idFromPath(wh.logger, w, r, "webhook_id")
+	if !ok {
+		return
+	}
+
+	if err := wh.webhookUsecase.Delete(r.Context(), webhookId); err != nil {
+		wh.logger.Log("error in %v: %v", webhookHandlerName, err)
+		respondWithUsecaseError(wh.logger, w, err, "webhook", "failed to delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetDeliveries responds with a page of the delivery log of the webhook, newest first.
+// The page is selected with limit and offset query parameters.
+func (wh *WebhookHandler) HandleGetDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := idFromPath(wh.logger, w, r, "webhook_id")
+	if !ok {
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		respondWithError(wh.logger, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := wh.webhookUsecase.GetDeliveries(r.Context(), webhookId, page)
+	if err != nil {
+		wh.logger.Log("error in %v: %v", webhookHandlerName, err)
+		respondWithUsecaseError(wh.logger, w, err, "webhook", "failed to retrieve deliveries")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (wh *WebhookHandler) HandleGetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := idFromPath(wh.logger, w, r, "webhook_id")
+	if !ok {
+		return
+	}
+
+	response, err := wh.webhookUsecase.GetDeadLetters(r.Context(), webhookId)
+	if err != nil {
+		wh.logger.Log("error in %v: %v", webhookHandlerName, err)
+		respondWithUsecaseError(wh.logger, w, err, "webhook", "failed to retrieve dead letters")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// HandleRedeliver queues the dead letter for delivery again, the outcome shows up in the delivery log
+func (wh *WebhookHandler) HandleRedeliver(w http.ResponseWriter, r *http.Request) {
+	webhookId, ok := idFromPath(wh.logger, w, r, "webhook_id")
+	if !ok {
+		return
+	}
+
+	if err := wh.webhookUsecase.Redeliver(r.Context(), webhookId, r.PathValue("delivery_id")); err != nil {
+		wh.logger.Log("error in %v: %v", webhookHandlerName, err)
+		respondWithUsecaseError(wh.logger, w, err, "webhook or dead letter", "failed to redeliver")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
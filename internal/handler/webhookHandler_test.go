@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockWebhookUsecase struct {
+	storeFunc     func(ctx context.Context, request dto.PostWebhookRequest) (dto.PostWebhookResponse, error)
+	redeliverFunc func(ctx context.Context, webhookId int, deliveryId string) error
+}
+
+func (m *MockWebhookUsecase) Store(ctx context.Context, request dto.PostWebhookRequest) (dto.PostWebhookResponse, error) {
+	return m.storeFunc(ctx, request)
+}
+
+func (m *MockWebhookUsecase) GetAll(ctx context.Context) (dto.GetAllWebhooksResponse, error) {
+	return dto.GetAllWebhooksResponse{}, nil
+}
+
+func (m *MockWebhookUsecase) GetById(ctx context.Context, webhookId int) (dto.GetWebhookByIdResponse, error) {
+	return dto.GetWebhookByIdResponse{}, model.ErrNotFound
+}
+
+func (m *MockWebhookUsecase) Update(ctx context.Context, webhookId int, request dto.PutWebhookRequest) (dto.GetWebhookByIdResponse, error) {
+	return dto.GetWebhookByIdResponse{}, nil
+}
+
+func (m *MockWebhookUsecase) Delete(ctx context.Context, webhookId int) error {
+	return nil
+}
+
+func (m *MockWebhookUsecase) GetDeliveries(ctx context.Context, webhookId int, page model.Page) (dto.GetWebhookDeliveriesResponse, error) {
+	return dto.GetWebhookDeliveriesResponse{}, nil
+}
+
+func (m *MockWebhookUsecase) GetDeadLetters(ctx context.Context, webhookId int) (dto.GetDeadLettersResponse, error) {
+	return dto.GetDeadLettersResponse{}, nil
+}
+
+func (m *MockWebhookUsecase) Redeliver(ctx context.Context, webhookId int, deliveryId string) error {
+	return m.redeliverFunc(ctx, webhookId, deliveryId)
+}
+
+func TestHandlePostWebhook(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		usecaseError   error
+		expectedStatus int
+	}{
+		{name: "created", body: `{"url":"https://ci.example.com/hook","events":["task.completed"]}`, expectedStatus: http.StatusOK},
+		{name: "malformed body", body: `{"url":`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid webhook", body: `{"url":"ci"}`, usecaseError: fmt.Errorf("usecase: %w", model.Invalid(fmt.Errorf("invalid url"))), expectedStatus: http.StatusBadRequest},
+		{name: "forbidden", body: `{"url":"https://ci.example.com/hook"}`, usecaseError: model.ErrForbidden, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored dto.PostWebhookRequest
+			mockUsecase := &MockWebhookUsecase{
+				storeFunc: func(ctx context.Context, request dto.PostWebhookRequest) (dto.PostWebhookResponse, error) {
+					stored = request
+					return dto.PostWebhookResponse{Id: 1, Secret: "0123456789abcdef"}, tt.usecaseError
+				},
+			}
+			handler, _ := NewWebhookHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("POST", "/webhooks", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.HandlePostWebhook(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				if len(stored.Events) != 1 || stored.Events[0] != model.WebhookTaskCompleted {
+					t.Errorf("Expected the events to be decoded, got %+v", stored)
+				}
+				if !strings.Contains(w.Body.String(), `"secret":"0123456789abcdef"`) {
+					t.Errorf("Expected the secret in the response, got %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestHandleRedeliver(t *testing.T) {
+	tests := []struct {
+		name           string
+		webhookId      string
+		usecaseError   error
+		expectedStatus int
+	}{
+		{name: "queued", webhookId: "1", expectedStatus: http.StatusAccepted},
+		{name: "invalid id", webhookId: "abc", expectedStatus: http.StatusBadRequest},
+		{name: "missing letter", webhookId: "1", usecaseError: fmt.Errorf("storage: %w", model.ErrNotFound), expectedStatus: http.StatusNotFound},
+		{name: "queue is full", webhookId: "1", usecaseError: fmt.Errorf("queue is full"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deliveryId string
+			mockUsecase := &MockWebhookUsecase{
+				redeliverFunc: func(ctx context.Context, webhookId int, id string) error {
+					deliveryId = id
+					return tt.usecaseError
+				},
+			}
+			handler, _ := NewWebhookHandler(&MockLogger{}, mockUsecase)
+
+			req := httptest.NewRequest("POST", "/webhooks/"+tt.webhookId+"/dead-letters/a1/redeliver", nil)
+			req.SetPathValue("webhook_id", tt.webhookId)
+			req.SetPathValue("delivery_id", "a1")
+			w := httptest.NewRecorder()
+			handler.HandleRedeliver(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == http.StatusAccepted && deliveryId != "a1" {
+				t.Errorf("Expected delivery a1, got %q", deliveryId)
+			}
+		})
+	}
+}
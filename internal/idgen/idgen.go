@@ -0,0 +1,176 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/clock"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Strategies of ids, see New
+const (
+	StrategyUUIDv7    = "uuidv7"
+	StrategySnowflake = "snowflake"
+)
+
+// Generator returns unique ids of new entities
+type Generator interface {
+	NextID() (string, error)
+}
+
+// New creates the generator of the strategy, node is used only by snowflake ids
+func New(strategy string, c clock.Clock, node int) (Generator, error) {
+	switch strategy {
+	case StrategyUUIDv7:
+		return NewUUIDv7(c, rand.Reader)
+	case StrategySnowflake:
+		return NewSnowflake(c, node)
+	}
+	return nil, fmt.Errorf("unknown id strategy %q", strategy)
+}
+
+// Sequential returns decimal numbers one after another, they are predictable and meant for tests,
+// so it isn't a strategy of New
+type Sequential struct {
+	next atomic.Int64
+}
+
+// NewSequential starts the sequence at start
+func NewSequential(start int64) *Sequential {
+	s := &Sequential{}
+	s.next.Store(start)
+	return s
+}
+
+func (s *Sequential) NextID() (string, error) {
+	return strconv.FormatInt(s.next.Add(1)-1, 10), nil
+}
+
+// UUIDv7 returns version 7 uuids of RFC 9562: 48 bits of unix milliseconds followed by random bits.
+//
+// Ids of one generator are ordered: within a millisecond the 12 bits after the version are a counter
+// starting at a random value, and when it runs out or the clock goes back the last millisecond is
+// reused or moved forward. The remaining 62 bits are random, so ids can't be guessed from each other.
+type UUIDv7 struct {
+	clock  clock.Clock
+	random io.Reader
+	lastMs int64
+	seq    uint16
+	m      sync.Mutex
+}
+
+// NewUUIDv7 creates the generator reading random bits from random, crypto/rand.Reader is the one to use
+func NewUUIDv7(c clock.Clock, random io.Reader) (*UUIDv7, error) {
+	if c == nil || random == nil {
+		return nil, errors.New("nil values in UUIDv7 constructor")
+	}
+	return &UUIDv7{clock: c, random: random}, nil
+}
+
+func (g *UUIDv7) NextID() (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(g.random, b[6:]); err != nil {
+		return "", fmt.Errorf("couldn't read random bits: %w", err)
+	}
+
+	g.m.Lock()
+	ms := g.clock.Now().UnixMilli()
+	if ms < 0 || ms >= 1<<48 {
+		g.m.Unlock()
+		return "", fmt.Errorf("time %v can't be put into a uuid", ms)
+	}
+	switch {
+	case ms > g.lastMs:
+		// the top bit is left clear, so the counter has room to grow within the millisecond
+		g.seq = (uint16(b[6])<<8 | uint16(b[7])) & 0x7ff
+	case g.seq < 0xfff:
+		ms = g.lastMs
+		g.seq++
+	default:
+		ms = g.lastMs + 1
+		g.seq = 0
+	}
+	g.lastMs = ms
+	seq := g.seq
+	g.m.Unlock()
+
+	b[0], b[1], b[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	b[3], b[4], b[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+	b[6], b[7] = 0x70|byte(seq>>8), byte(seq)
+	b[8] = 0x80 | b[8]&0x3f
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:]), nil
+}
+
+// SnowflakeEpoch is the moment snowflake timestamps count from
+var SnowflakeEpoch = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	// MaxSnowflakeNode is the largest node number of snowflake ids
+	MaxSnowflakeNode = 1<<snowflakeNodeBits - 1
+	maxSnowflakeSeq  = 1<<snowflakeSeqBits - 1
+	maxSnowflakeTime = 1<<41 - 1
+)
+
+// Snowflake returns decimal 63 bit ids made of 41 bits of milliseconds since SnowflakeEpoch,
+// 10 bits of the node and a 12 bit sequence within the millisecond.
+//
+// Ids of different nodes never collide, and ids of one node are ordered: when the sequence
+// runs out or the clock goes back the next millisecond is borrowed instead of waiting for it.
+type Snowflake struct {
+	clock  clock.Clock
+	node   int64
+	lastMs int64
+	seq    int64
+	m      sync.Mutex
+}
+
+func NewSnowflake(c clock.Clock, node int) (*Snowflake, error) {
+	if c == nil {
+		return nil, errors.New("nil values in Snowflake constructor")
+	}
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("snowflake node %v is out of range [0, %v]", node, MaxSnowflakeNode)
+	}
+	return &Snowflake{clock: c, node: int64(node), lastMs: -1}, nil
+}
+
+func (g *Snowflake) NextID() (string, error) {
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	now := g.clock.Now()
+	ms, seq := now.Sub(SnowflakeEpoch).Milliseconds(), int64(0)
+	switch {
+	case ms > g.lastMs:
+	case g.seq < maxSnowflakeSeq:
+		ms, seq = g.lastMs, g.seq+1
+	default:
+		ms = g.lastMs + 1
+	}
+	if ms < 0 || ms > maxSnowflakeTime {
+		return "", fmt.Errorf("time %v is out of range of snowflake ids", now)
+	}
+	g.lastMs, g.seq = ms, seq
+
+	id := ms<<(snowflakeNodeBits+snowflakeSeqBits) | g.node<<snowflakeSeqBits | seq
+	return strconv.FormatInt(id, 10), nil
+}
@@ -0,0 +1,188 @@
+package idgen
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var uuidV7Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNew(t *testing.T) {
+	c := clock.NewManual(time.Now())
+	for _, strategy := range []string{StrategyUUIDv7, StrategySnowflake} {
+		g, err := New(strategy, c, 1)
+		if err != nil {
+			t.Fatalf("%v: expected no error, got %v", strategy, err)
+		}
+		if id, err := g.NextID(); err != nil || id == "" {
+			t.Errorf("%v: unexpected id %q, error %v", strategy, id, err)
+		}
+	}
+	for _, strategy := range []string{"random", "sequential"} {
+		if _, err := New(strategy, c, 0); err == nil {
+			t.Errorf("Expected error for the strategy %q", strategy)
+		}
+	}
+	if _, err := New(StrategySnowflake, c, MaxSnowflakeNode+1); err == nil {
+		t.Error("Expected error for a node out of range")
+	}
+	if _, err := NewUUIDv7(nil, rand.Reader); err == nil {
+		t.Error("Expected error for a nil clock")
+	}
+}
+
+func TestSequential(t *testing.T) {
+	g := NewSequential(5)
+	for _, expected := range []string{"5", "6", "7"} {
+		if id, _ := g.NextID(); id != expected {
+			t.Errorf("Expected %v, got %v", expected, id)
+		}
+	}
+}
+
+func TestUUIDv7(t *testing.T) {
+	start := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	c := clock.NewManual(start)
+	g, _ := NewUUIDv7(c, rand.Reader)
+
+	t.Run("format and timestamp", func(t *testing.T) {
+		id, err := g.NextID()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !uuidV7Pattern.MatchString(id) {
+			t.Fatalf("%q isn't a version 7 uuid", id)
+		}
+		ms, _ := strconv.ParseInt(id[0:8]+id[9:13], 16, 64)
+		if ms != start.UnixMilli() {
+			t.Errorf("Expected timestamp %v, got %v", start.UnixMilli(), ms)
+		}
+	})
+
+	t.Run("ordered within a millisecond and when the clock goes back", func(t *testing.T) {
+		last, _ := g.NextID()
+		for i := 0; i < 5000; i++ {
+			if i == 2500 {
+				c.Set(start.Add(-time.Hour))
+			}
+			id, err := g.NextID()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if id <= last || !uuidV7Pattern.MatchString(id) {
+				t.Fatalf("Expected %q after %q", id, last)
+			}
+			last = id
+		}
+	})
+
+	t.Run("random bits", func(t *testing.T) {
+		g, _ := NewUUIDv7(clock.NewManual(start), bytes.NewReader(make([]byte, 10)))
+		id, _ := g.NextID()
+		ms := fmt.Sprintf("%012x", start.UnixMilli())
+		if expected := ms[:8] + "-" + ms[8:] + "-7000-8000-000000000000"; id != expected {
+			t.Errorf("Expected %v, got %v", expected, id)
+		}
+		if _, err := g.NextID(); err == nil {
+			t.Error("Expected error when random bits run out")
+		}
+	})
+}
+
+func TestSnowflake(t *testing.T) {
+	start := SnowflakeEpoch.Add(1500 * time.Millisecond)
+	c := clock.NewManual(start)
+	g, _ := NewSnowflake(c, 3)
+
+	parse := func(id string) (ms, node, seq int64) {
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			t.Fatalf("%q isn't a number: %v", id, err)
+		}
+		return n >> 22, n >> 12 & MaxSnowflakeNode, n & maxSnowflakeSeq
+	}
+
+	id, _ := g.NextID()
+	if ms, node, seq := parse(id); ms != 1500 || node != 3 || seq != 0 {
+		t.Errorf("Unexpected parts %v %v %v of %v", ms, node, seq, id)
+	}
+
+	t.Run("sequence runs out", func(t *testing.T) {
+		var last string
+		for i := 0; i < maxSnowflakeSeq; i++ {
+			last, _ = g.NextID()
+		}
+		if ms, _, seq := parse(last); ms != 1500 || seq != maxSnowflakeSeq {
+			t.Errorf("Unexpected parts %v %v of %v", ms, seq, last)
+		}
+		next, _ := g.NextID()
+		if ms, _, seq := parse(next); ms != 1501 || seq != 0 {
+			t.Errorf("Expected the next millisecond to be borrowed, got %v %v", ms, seq)
+		}
+	})
+
+	t.Run("clock goes back", func(t *testing.T) {
+		c.Set(start.Add(-time.Second))
+		id, _ := g.NextID()
+		if ms, _, seq := parse(id); ms != 1501 || seq != 1 {
+			t.Errorf("Unexpected parts %v %v", ms, seq)
+		}
+	})
+
+	t.Run("before the epoch", func(t *testing.T) {
+		g, _ := NewSnowflake(clock.NewManual(SnowflakeEpoch.Add(-time.Second)), 0)
+		if _, err := g.NextID(); err == nil {
+			t.Error("Expected error before the epoch")
+		}
+	})
+}
+
+func TestUnique(t *testing.T) {
+	c := clock.System{}
+	uuids, _ := NewUUIDv7(c, rand.Reader)
+	snowflakes, _ := NewSnowflake(c, 1)
+	for name, g := range map[string]Generator{"uuidv7": uuids, "snowflake": snowflakes, "sequential": NewSequential(0)} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				seen = make(map[string]struct{})
+				m    sync.Mutex
+				wg   sync.WaitGroup
+				errs = make(chan error, 8)
+			)
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 2000; i++ {
+						id, err := g.NextID()
+						if err != nil {
+							errs <- err
+							return
+						}
+						m.Lock()
+						_, dup := seen[id]
+						seen[id] = struct{}{}
+						m.Unlock()
+						if dup {
+							errs <- errors.New("duplicate id " + id)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"encoding/json"
+	"ivanjabrony/test_lo/internal/auth"
+	"net/http"
+)
+
+type AuthMiddleware struct {
+	authenticator *auth.Authenticator
+	logger        Logger
+	publicPaths   map[string]bool
+}
+
+// NewAuthMiddleware creates a middleware that lets through only authenticated requests,
+// except the requests to publicPaths
+func NewAuthMiddleware(logger Logger, authenticator *auth.Authenticator, publicPaths ...string) AuthMiddleware {
+	public := make(map[string]bool, len(publicPaths))
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+	return AuthMiddleware{authenticator, logger, public}
+}
+
+// Authenticate puts the authenticated auth.Principal into the request context
+func (am AuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if am.publicPaths[req.URL.Path] {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		principal, err := am.authenticator.Authenticate(req)
+		if err != nil {
+			am.logger.Log("authentication failed for %s %s: %v", req.Method, req.URL.Path, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tasks"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		next.ServeHTTP(w, req.WithContext(auth.WithPrincipal(req.Context(), principal)))
+	})
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockLogger struct {
+	logs []string
+}
+
+func (m *MockLogger) Log(format string, info ...any) {
+	m.logs = append(m.logs, fmt.Sprintf(format, info...))
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	keys, _ := auth.ParseAPIKeys("ci:5:admin:" + auth.HashAPIKey("secret"))
+	authenticator, _ := auth.NewAuthenticator(keys, nil)
+
+	var gotPrincipal auth.Principal
+	var gotOk bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPrincipal, gotOk = auth.FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	h := NewAuthMiddleware(&MockLogger{}, authenticator, "/health").Authenticate(next)
+
+	tests := []struct {
+		name           string
+		path           string
+		apiKey         string
+		expectedStatus int
+		wantPrincipal  bool
+	}{
+		{name: "public path", path: "/health", expectedStatus: http.StatusOK},
+		{name: "no credentials", path: "/tasks", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong key", path: "/tasks", apiKey: "wrong", expectedStatus: http.StatusUnauthorized},
+		{name: "valid key", path: "/tasks", apiKey: "secret", expectedStatus: http.StatusOK, wantPrincipal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOk = false
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set(auth.APIKeyHeader, tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected WWW-Authenticate header")
+			}
+			if gotOk != tt.wantPrincipal {
+				t.Fatalf("Expected principal in context %v, got %v", tt.wantPrincipal, gotOk)
+			}
+			if tt.wantPrincipal && (gotPrincipal.Subject != "ci" || gotPrincipal.UserID != 5) {
+				t.Errorf("Unexpected principal %+v", gotPrincipal)
+			}
+		})
+	}
+}
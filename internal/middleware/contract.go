@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/openapi"
+	"net"
+	"net/http"
+)
+
+// maxContractBodySize limits the bodies buffered for validation, longer bodies aren't checked
+const maxContractBodySize = 1 << 20
+
+// ContractMiddleware checks requests and responses against the OpenAPI document and reports every
+// mismatch, it never changes them. It's meant for tests, where drift between the handlers and the document fails the test.
+type ContractMiddleware struct {
+	doc    *openapi.Document
+	report func(r *http.Request, err error)
+}
+
+func NewContractMiddleware(doc *openapi.Document, report func(r *http.Request, err error)) ContractMiddleware {
+	return ContractMiddleware{doc: doc, report: report}
+}
+
+// Validate reports routes missing from the document, responses not matching the document and requests not matching it
+// that the handler accepted. Requests rejected with 4xx are expected to be invalid, so they aren't reported.
+func (cm ContractMiddleware) Validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		op, pathValues, ok := cm.doc.Find(req.Method, req.URL.Path)
+		if !ok {
+			cw := &contractWriter{ResponseWriter: w}
+			next.ServeHTTP(cw, req)
+			// the router rejects requests to unknown routes, anything else means a route is missing from the document
+			if cw.status != http.StatusNotFound && cw.status != http.StatusMethodNotAllowed {
+				cm.report(req, fmt.Errorf("%v %v isn't documented", req.Method, req.URL.Path))
+			}
+			return
+		}
+
+		var body []byte
+		if req.Body != nil {
+			var err error
+			if body, err = io.ReadAll(io.LimitReader(req.Body, maxContractBodySize+1)); err != nil {
+				cm.report(req, fmt.Errorf("reading request body: %w", err))
+			}
+			req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+		}
+
+		cw := &contractWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, req)
+		if cw.hijacked {
+			return
+		}
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+
+		var errs []error
+		if cw.status < 400 || cw.status >= 500 {
+			if len(body) <= maxContractBodySize {
+				if err := cm.doc.ValidateRequest(op, pathValues, req.URL.Query(), req.Header.Get("Content-Type"), body); err != nil {
+					errs = append(errs, fmt.Errorf("request accepted with status %d: %w", cw.status, err))
+				}
+			}
+		}
+		if !cw.truncated {
+			if err := cm.doc.ValidateResponse(op, cw.status, w.Header(), cw.body.Bytes()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			cm.report(req, fmt.Errorf("%v %v: %w", req.Method, req.URL.Path, err))
+		}
+	})
+}
+
+// contractWriter keeps a copy of the response for validation, streaming and hijacking go through untouched
+type contractWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+	hijacked  bool
+}
+
+func (cw *contractWriter) WriteHeader(code int) {
+	if cw.status == 0 {
+		cw.status = code
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *contractWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.truncated {
+		if cw.body.Len()+len(p) > maxContractBodySize {
+			cw.truncated = true
+			cw.body.Reset()
+		} else {
+			cw.body.Write(p)
+		}
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *contractWriter) Flush() {
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *contractWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.hijacked = true
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+func (cw *contractWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"ivanjabrony/test_lo/internal/openapi"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type contractItem struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type contractItemRequest struct {
+	Name string `json:"name"`
+}
+
+type contractError struct {
+	Error string `json:"error"`
+}
+
+func TestContractMiddleware(t *testing.T) {
+	doc, err := openapi.Generate(openapi.Info{Title: "test", Version: "1"}, []openapi.Endpoint{
+		{Method: "GET", Path: "/items/{item_id}", OperationID: "getItem", Response: contractItem{}},
+		{Method: "POST", Path: "/items", OperationID: "createItem", Request: contractItemRequest{}, Response: 0},
+	}, openapi.WithErrorResponse(contractError{}))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		status   int
+		response string
+		expected string
+	}{
+		{name: "valid", method: "GET", path: "/items/1", status: 200, response: `{"id": 1, "name": "a"}`},
+		{name: "drifted response", method: "GET", path: "/items/1", status: 200, response: `{"id": 1, "amount": "a"}`,
+			expected: "GET /items/1: response body of status 200: $: property name is required\n$: property amount isn't documented"},
+		{name: "documented error", method: "GET", path: "/items/1", status: 404, response: `{"error": "not found"}`},
+		{name: "accepted invalid request", method: "POST", path: "/items", body: `{"title": "a"}`, status: 200, response: `1`,
+			expected: "POST /items: request accepted with status 200: request body: $: property title isn't documented"},
+		{name: "rejected invalid request", method: "POST", path: "/items", body: `{"title": "a"}`, status: 400, response: `{"error": "invalid item"}`},
+		{name: "undocumented route", method: "GET", path: "/items", status: 200, response: `[]`, expected: "GET /items isn't documented"},
+		{name: "unknown route", method: "GET", path: "/unknown", status: 404, response: `{"error": "not found"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported []string
+			cm := NewContractMiddleware(doc, func(r *http.Request, err error) {
+				reported = append(reported, err.Error())
+			})
+			var received string
+			h := cm.Validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = string(body)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			if received != tt.body {
+				t.Errorf("Expected the handler to read %q, got %q", tt.body, received)
+			}
+			if rr.Code != tt.status || rr.Body.String() != tt.response {
+				t.Errorf("Expected the response to pass through, got %d %v", rr.Code, rr.Body.String())
+			}
+			if tt.expected == "" && len(reported) > 0 {
+				t.Errorf("Expected no violations, got %v", reported)
+			}
+			if tt.expected != "" && (len(reported) != 1 || reported[0] != tt.expected) {
+				encoded, _ := json.Marshal(reported)
+				t.Errorf("Expected violation %q, got %s", tt.expected, encoded)
+			}
+		})
+	}
+}
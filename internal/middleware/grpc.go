@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/requestid"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The interceptors below are the gRPC counterparts of the http middlewares, credentials and the request id
+// are taken from the metadata keys named like the http headers.
+
+// UnaryAuthenticate puts the authenticated auth.Principal into the context of unary calls
+func (am AuthMiddleware) UnaryAuthenticate() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := am.authenticateMetadata(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthenticate puts the authenticated auth.Principal into the context of streaming calls
+func (am AuthMiddleware) StreamAuthenticate() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := am.authenticateMetadata(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ss, ctx})
+	}
+}
+
+func (am AuthMiddleware) authenticateMetadata(ctx context.Context, method string) (context.Context, error) {
+	if am.publicPaths[method] {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := am.authenticator.AuthenticateCredentials(firstValue(md, auth.APIKeyHeader), firstValue(md, "Authorization"))
+	if err != nil {
+		am.logger.Log("authentication failed for %s: %v", method, err)
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// UnaryAssignID puts the request id into the context and the response header of unary calls
+func (rm RequestIDMiddleware) UnaryAssignID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(assignMetadataID(ctx), req)
+	}
+}
+
+// StreamAssignID puts the request id into the context and the response header of streaming calls
+func (rm RequestIDMiddleware) StreamAssignID() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ss, assignMetadataID(ss.Context())})
+	}
+}
+
+func assignMetadataID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := firstValue(md, requestid.Header)
+	if !requestid.IsValid(id) {
+		id = requestid.New()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestid.Header, id))
+	return requestid.WithID(ctx, id)
+}
+
+// UnaryLogging logs the method, the status code and the duration of unary calls
+func (lm LoggerMiddleware) UnaryLogging() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		lm.logger.Log("gRPC %s %s %s", info.FullMethod, status.Code(err), time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamLogging logs the method, the status code and the duration of streaming calls
+func (lm LoggerMiddleware) StreamLogging() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		lm.logger.Log("gRPC %s %s %s", info.FullMethod, status.Code(err), time.Since(start))
+		return err
+	}
+}
+
+// serverStream replaces the context of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// firstValue returns the first value of the metadata key, the key is case insensitive
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"ivanjabrony/test_lo/internal/requestid"
+	"net/http"
+)
+
+type RequestIDMiddleware struct{}
+
+func NewRequestIDMiddleware() RequestIDMiddleware {
+	return RequestIDMiddleware{}
+}
+
+// AssignID puts the request id into the context and the response headers,
+// a valid id sent by the client is kept, otherwise a new one is generated
+func (rm RequestIDMiddleware) AssignID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(requestid.Header)
+		if !requestid.IsValid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+
+		next.ServeHTTP(w, req.WithContext(requestid.WithID(req.Context(), id)))
+	})
+}
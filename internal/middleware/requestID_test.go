@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"ivanjabrony/test_lo/internal/requestid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var gotId string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotId = requestid.FromContext(r.Context())
+	})
+	h := NewRequestIDMiddleware().AssignID(next)
+
+	tests := []struct {
+		name     string
+		header   string
+		wantKept bool
+	}{
+		{name: "client id", header: "req-42", wantKept: true},
+		{name: "no id", header: ""},
+		{name: "too long", header: strings.Repeat("a", requestid.MaxLength+1)},
+		{name: "not printable", header: "a b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/tasks", nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if gotId == "" || w.Header().Get(requestid.Header) != gotId {
+				t.Fatalf("Expected the id in the context and the response, got %q and %q", gotId, w.Header().Get(requestid.Header))
+			}
+			if (gotId == tt.header) != tt.wantKept {
+				t.Errorf("Expected client id to be kept: %v, got %q", tt.wantKept, gotId)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// AuditAction is the kind of a task mutation recorded in the audit log
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	// AuditStatusChange is an update that changed the status, other fields may have changed with it
+	AuditStatusChange AuditAction = "status_change"
+	AuditDelete       AuditAction = "delete"
+)
+
+func (a AuditAction) IsValid() bool {
+	switch a {
+	case AuditCreate, AuditUpdate, AuditStatusChange, AuditDelete:
+		return true
+	}
+	return false
+}
+
+// FieldChange is a change of a single task field, values are json encoded, null means the field was empty
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// AuditEntry records a single task mutation. Entries form a hash chain:
+// Hash covers every other field including PrevHash, the hash of the previous entry,
+// so changing or removing any entry breaks every hash after it.
+type AuditEntry struct {
+	// Seq is the position of the entry in the chain, starting from 1
+	Seq       int `json:"seq"`
+	ProjectID int `json:"project_id"`
+	TaskID    int `json:"task_id"`
+	// TaskPublicID is the public id of the task when it has one, responses show it in place of TaskID
+	TaskPublicID string      `json:"task_public_id,omitempty"`
+	Action       AuditAction `json:"action"`
+	// Actor is the subject of the principal, empty when authentication is disabled
+	Actor     string        `json:"actor,omitempty"`
+	ActorID   int           `json:"actor_id,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	At        time.Time     `json:"at"`
+	Changes   []FieldChange `json:"changes"`
+	// PrevHash is empty for the first entry
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// ComputeHash returns the hex encoded sha256 of the entry without its Hash
+func (e AuditEntry) ComputeHash() string {
+	e.Hash = ""
+	payload, _ := json.Marshal(e)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditChain checks hashes and links of consecutive entries of a chain starting from its first entry,
+// it returns Seq of the first broken entry or 0 if the chain is intact
+func VerifyAuditChain(entries []AuditEntry) int {
+	prevHash := ""
+	for i, entry := range entries {
+		if entry.Seq != i+1 || entry.PrevHash != prevHash || entry.ComputeHash() != entry.Hash {
+			return i + 1
+		}
+		prevHash = entry.Hash
+	}
+	return 0
+}
+
+// AuditFilter selects audit entries, zero fields match everything
+type AuditFilter struct {
+	ProjectID int
+	// TaskID is a pointer because 0 is a valid task id
+	TaskID *int
+	// TaskPublicID selects entries of the task with the public id, or of the task without one whose id
+	// is the public id in decimal, see Task.PublicIds
+	TaskPublicID string
+	ActorID      int
+	Action       AuditAction
+	// From and To bound the time of the entries, both are inclusive
+	From time.Time
+	To   time.Time
+}
+
+func ValidateAuditFilter(filter AuditFilter) error {
+	if filter.ProjectID < 0 || filter.ActorID < 0 || filter.TaskID != nil && *filter.TaskID < 0 {
+		return errors.New("invalid audit filter: negative ids are forbidden")
+	}
+	if filter.Action != "" && !filter.Action.IsValid() {
+		return errors.New("invalid action in audit filter: unknown type")
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return errors.New("invalid time range in audit filter: from is after to")
+	}
+	return nil
+}
+
+// DiffTasks returns the changed fields of the task, a zero Task stands for a task that
+// doesn't exist yet or doesn't exist anymore
+func DiffTasks(before, after Task) []FieldChange {
+	fields := []struct {
+		name          string
+		before, after any
+	}{
+		{"status", before.Status, after.Status},
+		{"name", before.Name, after.Name},
+		{"description", before.Description, after.Description},
+		{"priority", before.Priority, after.Priority},
+		{"assignee_id", optionalUser(before.AssigneeID), optionalUser(after.AssigneeID)},
+		{"reporter_id", optionalUser(before.ReporterID), optionalUser(after.ReporterID)},
+		{"tag_ids", before.TagIDs, after.TagIDs},
+		{"parent_id", parentId(before), parentId(after)},
+		{"blocked_by", blockedBy(before), blockedBy(after)},
+		{"due_at", optionalTime(before.DueAt), optionalTime(after.DueAt)},
+		{"recurrence", before.Recurrence, after.Recurrence},
+		{"started_at", optionalTime(before.StartedAt), optionalTime(after.StartedAt)},
+		{"completed_at", optionalTime(before.CompletedAt), optionalTime(after.CompletedAt)},
+	}
+
+	changes := make([]FieldChange, 0)
+	for _, field := range fields {
+		b, a := encodeField(field.before), encodeField(field.after)
+		if !bytes.Equal(b, a) {
+			changes = append(changes, FieldChange{Field: field.name, Before: b, After: a})
+		}
+	}
+	return changes
+}
+
+// encodeField encodes empty values as null, so a created task shows only the fields that were set
+func encodeField(value any) json.RawMessage {
+	encoded, _ := json.Marshal(value)
+	switch string(encoded) {
+	case `""`, "[]":
+		return json.RawMessage("null")
+	}
+	return encoded
+}
+
+// ReferencesTask reports whether the entry is of the task the public id refers to, see AuditFilter.TaskPublicID
+func (e AuditEntry) ReferencesTask(publicId string) bool {
+	if e.TaskPublicID != "" {
+		return e.TaskPublicID == publicId
+	}
+	return strconv.Itoa(e.TaskID) == publicId
+}
+
+// parentId and blockedBy record related tasks as task responses show them, by public ids once they have them
+func parentId(task Task) any {
+	if ids, public := task.PublicIds(); public {
+		return ids.ParentID
+	}
+	return task.ParentID
+}
+
+func blockedBy(task Task) any {
+	if ids, public := task.PublicIds(); public {
+		return ids.BlockedBy
+	}
+	return task.BlockedBy
+}
+
+func optionalUser(userId int) *int {
+	if userId == NoUser {
+		return nil
+	}
+	return &userId
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
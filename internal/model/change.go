@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// TaskChangeType is the kind of a committed task change delivered to subscribers
+type TaskChangeType string
+
+const (
+	TaskChangeCreated TaskChangeType = "created"
+	TaskChangeUpdated TaskChangeType = "updated"
+	TaskChangeDeleted TaskChangeType = "deleted"
+)
+
+// TaskChange is a committed change of a task published to subscribers of task changes
+type TaskChange struct {
+	// ID is assigned by the broker, ids of published changes grow by one starting from 1
+	ID        int
+	Type      TaskChangeType
+	ProjectID int
+	At        time.Time
+	// Task is the state after the change, for deleted tasks the last state before it
+	Task Task
+	// Previous is the state before an update, so subscribers notice tasks leaving their filters
+	Previous *Task
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxCommentLength limits the body of a comment in runes
+const MaxCommentLength = 10000
+
+// Comment is a message in the discussion of a task, its body is Markdown
+type Comment struct {
+	Id        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	AuthorID  int       `json:"author_id,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	// EditedAt is set by the storage when the body changes
+	EditedAt time.Time `json:"edited_at,omitzero"`
+}
+
+// Page selects a part of a list, Limit is clamped to MaxPageLimit
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// DefaultPage is used when the client doesn't ask for a page
+var DefaultPage = Page{Limit: DefaultPageLimit, Offset: 0}
+
+func ValidatePage(page Page) error {
+	if page.Limit <= 0 || page.Limit > MaxPageLimit {
+		return errors.New("invalid limit in page: it must be between 1 and 100")
+	}
+	if page.Offset < 0 {
+		return errors.New("invalid offset in page: negative values are forbidden")
+	}
+	return nil
+}
+
+func ValidateComment(comment Comment) error {
+	if comment.Id < 0 || comment.TaskID < 0 {
+		return errors.New("invalid id in comment: negative values are forbidden")
+	}
+	if strings.TrimSpace(comment.Body) == "" {
+		return errors.New("invalid body in comment: empty body is forbidden")
+	}
+	if utf8.RuneCountInString(comment.Body) > MaxCommentLength {
+		return errors.New("invalid body in comment: body is too long")
+	}
+	return nil
+}
+
+var unsafeLink = regexp.MustCompile(`(?i)\]\(\s*(javascript|vbscript|data|file):[^)]*\)`)
+
+// SanitizeMarkdown makes a comment body safe to render: raw html is escaped so it's shown as text,
+// links with script or data schemes are replaced with "#" and control characters are dropped.
+// The rest of the Markdown is kept as is.
+func SanitizeMarkdown(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' && r != '\t' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, body)
+	body = strings.ReplaceAll(body, "&", "&amp;")
+	body = strings.ReplaceAll(body, "<", "&lt;")
+	body = unsafeLink.ReplaceAllString(body, "](#)")
+
+	return strings.TrimSpace(body)
+}
@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"encoding/json"
+	"ivanjabrony/test_lo/internal/model"
+)
+
+// GetAuditResponse is a page of audit entries, Total counts all the matching ones
+type GetAuditResponse struct {
+	Amount  int                `json:"amount"`
+	Total   int                `json:"total"`
+	Limit   int                `json:"limit"`
+	Offset  int                `json:"offset"`
+	Entries []model.AuditEntry `json:"entries"`
+}
+
+// MarshalJSON shows the entries as AuditEntryResponse does
+func (r GetAuditResponse) MarshalJSON() ([]byte, error) {
+	type response GetAuditResponse
+	var entries []AuditEntryResponse
+	if r.Entries != nil {
+		entries = make([]AuditEntryResponse, len(r.Entries))
+		for i, entry := range r.Entries {
+			entries[i] = AuditEntryResponse(entry)
+		}
+	}
+	return json.Marshal(struct {
+		response
+		Entries []AuditEntryResponse `json:"entries"`
+	}{response(r), entries})
+}
+
+// AuditEntryResponse is the entry as it's sent, task_id is the public id of the task when it has one.
+// Hashes cover the entry as it's stored, with both ids.
+type AuditEntryResponse model.AuditEntry
+
+func (e AuditEntryResponse) MarshalJSON() ([]byte, error) {
+	type entry model.AuditEntry
+	if e.TaskPublicID == "" {
+		return json.Marshal(entry(e))
+	}
+	return json.Marshal(struct {
+		entry
+		TaskID       string `json:"task_id"`
+		TaskPublicID string `json:"task_public_id,omitempty"`
+	}{entry(e), e.TaskPublicID, ""})
+}
+
+type VerifyAuditResponse struct {
+	Valid   bool `json:"valid"`
+	Entries int  `json:"entries"`
+	// BrokenAt is the seq of the first entry that doesn't match its hash or the previous entry
+	BrokenAt int `json:"broken_at,omitempty"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"time"
+)
+
+type PostCommentRequest struct {
+	Body string `json:"body"`
+}
+
+type PutCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// GetCommentsResponse is a page of the comments of a task, Total counts all of them
+type GetCommentsResponse struct {
+	Amount   int                      `json:"amount"`
+	Total    int                      `json:"total"`
+	Limit    int                      `json:"limit"`
+	Offset   int                      `json:"offset"`
+	Comments []GetCommentByIdResponse `json:"comments"`
+}
+
+type GetCommentByIdResponse struct {
+	Id        int       `json:"id"`
+	TaskID    TaskRef   `json:"task_id"`
+	AuthorID  int       `json:"author_id,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	EditedAt  time.Time `json:"edited_at,omitzero"`
+}
@@ -1,10 +1,32 @@
 package dto
 
 import (
+	"encoding/json"
 	"ivanjabrony/test_lo/internal/model"
 )
 
 type GetAllTasksResponse struct {
 	Amount int          `json:"amount"`
 	Tasks  []model.Task `json:"tasks"`
+	// Total, Limit and Offset are set when a page of the tasks was asked for, Total counts every matching task
+	Total  int `json:"total,omitempty"`
+	Limit  int `json:"limit,omitempty"`
+	Offset int `json:"offset,omitempty"`
+}
+
+// MarshalJSON shows the tasks as TaskResponse does
+func (r GetAllTasksResponse) MarshalJSON() ([]byte, error) {
+	type response GetAllTasksResponse
+	var tasks []TaskResponse
+	if r.Tasks != nil {
+		tasks = make([]TaskResponse, len(r.Tasks))
+		for i, task := range r.Tasks {
+			tasks[i] = TaskResponse(task)
+		}
+	}
+	return json.Marshal(struct {
+		Amount int            `json:"amount"`
+		Tasks  []TaskResponse `json:"tasks"`
+		response
+	}{r.Amount, tasks, response(r)})
 }
@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"ivanjabrony/test_lo/internal/model"
+	"time"
+)
+
+type GetAllProjectsResponse struct {
+	Amount   int             `json:"amount"`
+	Projects []model.Project `json:"projects"`
+}
+
+type GetProjectByIdResponse struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	TaskQuota int       `json:"task_quota"`
+	CreatedAt time.Time `json:"created_at"`
+}
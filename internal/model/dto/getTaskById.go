@@ -1,14 +1,59 @@
 package dto
 
 import (
+	"encoding/json"
 	"ivanjabrony/test_lo/internal/model"
 	"time"
 )
 
 type GetTaskByIdResponse struct {
-	Id          int              `json:"id"`
-	Status      model.TaskStatus `json:"status"`
-	Name        string           `json:"name"`
-	Description string           `json:"amount"`
-	CreatedAt   time.Time        `json:"created_at"`
+	Id int `json:"id"`
+	// PublicID is set when tasks are given public ids, then it's shown in place of Id, see MarshalJSON
+	PublicID    string             `json:"public_id,omitempty"`
+	Status      model.TaskStatus   `json:"status"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Priority    model.TaskPriority `json:"priority"`
+	AssigneeID  int                `json:"assignee_id,omitempty"`
+	ReporterID  int                `json:"reporter_id,omitempty"`
+	TagIDs      []int              `json:"tag_ids,omitempty"`
+	ParentID    *int               `json:"parent_id,omitempty"`
+	BlockedBy   []int              `json:"blocked_by,omitempty"`
+	// PublicParentID and PublicBlockedBy are shown in place of ParentID and BlockedBy, see model.Task
+	PublicParentID  string   `json:"-"`
+	PublicBlockedBy []string `json:"-"`
+	// CommentCount is filled by the single task endpoints, lists, trees and tag responses leave it zero
+	CommentCount int               `json:"comment_count"`
+	DueAt        time.Time         `json:"due_at,omitzero"`
+	Overdue      bool              `json:"overdue"`
+	Recurrence   *model.Recurrence `json:"recurrence,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	StartedAt    time.Time         `json:"started_at,omitzero"`
+	CompletedAt  time.Time         `json:"completed_at,omitzero"`
+}
+
+// MarshalJSON shows public ids in id, parent_id and blocked_by once the task or the tasks it refers to have them
+func (r GetTaskByIdResponse) MarshalJSON() ([]byte, error) {
+	type response GetTaskByIdResponse
+	ids, ok := r.publicIds()
+	if !ok {
+		return json.Marshal(response(r))
+	}
+	return json.Marshal(struct {
+		Id string `json:"id"`
+		response
+		ParentID  *string  `json:"parent_id,omitempty"`
+		BlockedBy []string `json:"blocked_by,omitempty"`
+	}{ids.Id, response(r), ids.ParentID, ids.BlockedBy})
+}
+
+// PublicIds are the ids shown in place of the ids of the task, its parent and blockers, see model.Task.PublicIds
+func (r GetTaskByIdResponse) PublicIds() model.TaskIds {
+	ids, _ := r.publicIds()
+	return ids
+}
+
+func (r GetTaskByIdResponse) publicIds() (model.TaskIds, bool) {
+	return model.Task{Id: r.Id, PublicID: r.PublicID, ParentID: r.ParentID, BlockedBy: r.BlockedBy,
+		PublicParentID: r.PublicParentID, PublicBlockedBy: r.PublicBlockedBy}.PublicIds()
 }
@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"ivanjabrony/test_lo/internal/model"
+	"time"
+)
+
+type GetAllUsersResponse struct {
+	Amount int          `json:"amount"`
+	Users  []model.User `json:"users"`
+}
+
+type GetUserByIdResponse struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
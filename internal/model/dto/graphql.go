@@ -0,0 +1,17 @@
+package dto
+
+import "ivanjabrony/test_lo/internal/graphql"
+
+// GraphQLRequest is a graphql query, variables are decoded with numbers as json.Number
+type GraphQLRequest struct {
+	Query string `json:"query"`
+	// OperationName picks the operation to run when the query has several of them
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// GraphQLResponse describes the encoding of graphql.Result, data is missing when the query wasn't executed
+type GraphQLResponse struct {
+	Data   any              `json:"data,omitempty"`
+	Errors []*graphql.Error `json:"errors,omitempty"`
+}
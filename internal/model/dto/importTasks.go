@@ -0,0 +1,20 @@
+package dto
+
+type ImportTaskRow struct {
+	Row     int
+	Request PostTaskRequest
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type ImportTasksResponse struct {
+	DryRun   bool             `json:"dry_run"`
+	Total    int              `json:"total"`
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Ids      []TaskRef        `json:"ids"`
+	Errors   []ImportRowError `json:"errors"`
+}
@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"ivanjabrony/test_lo/internal/model"
+	"time"
+)
+
+// PutNotificationPreferencesRequest replaces preferences of a user
+type PutNotificationPreferencesRequest struct {
+	// Channels reminders are sent through, a missing list means the default channels
+	// and an empty one turns reminders off
+	Channels   []model.NotificationChannel `json:"channels"`
+	Muted      []model.ReminderRule        `json:"muted,omitempty"`
+	WebhookURL string                      `json:"webhook_url,omitempty"`
+}
+
+type GetNotificationPreferencesResponse struct {
+	UserID int `json:"user_id"`
+	// Channels are the ones reminders are sent through, the default ones unless the user has chosen
+	Channels []model.NotificationChannel `json:"channels"`
+	// DefaultChannels is true when the user hasn't chosen channels
+	DefaultChannels bool                 `json:"default_channels"`
+	Muted           []model.ReminderRule `json:"muted"`
+	WebhookURL      string               `json:"webhook_url,omitempty"`
+}
+
+// ReminderPayload is the body of a reminder sent to the webhook of a user
+type ReminderPayload struct {
+	Rule      model.ReminderRule  `json:"rule"`
+	UserID    int                 `json:"user_id"`
+	ProjectID int                 `json:"project_id"`
+	Anchor    time.Time           `json:"anchor"`
+	At        time.Time           `json:"at"`
+	Task      GetTaskByIdResponse `json:"task"`
+}
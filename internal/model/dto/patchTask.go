@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"ivanjabrony/test_lo/internal/model"
+	"time"
+)
+
+// PatchTaskRequest holds fields to change, nil fields are left untouched
+type PatchTaskRequest struct {
+	Status      *model.TaskStatus   `json:"status,omitempty"`
+	Name        *string             `json:"name,omitempty"`
+	Description *string             `json:"description,omitempty"`
+	Priority    *model.TaskPriority `json:"priority,omitempty"`
+	AssigneeID  *int                `json:"assignee_id,omitempty"`
+	ParentID    *TaskRef            `json:"parent_id,omitempty"`
+	// ClearParent makes the task a top level one, it takes precedence over ParentID
+	ClearParent bool       `json:"clear_parent,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	// AllowPastDue permits moving the due date to the past
+	AllowPastDue bool `json:"allow_past_due,omitempty"`
+	// Recurrence replaces the schedule of the task
+	Recurrence *RecurrenceRequest `json:"recurrence,omitempty"`
+	// ClearRecurrence stops the series at this task, it takes precedence over Recurrence
+	ClearRecurrence bool `json:"clear_recurrence,omitempty"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+// PostProjectRequest creates a project, a missing task quota falls back to the configured default
+type PostProjectRequest struct {
+	Name      string `json:"name"`
+	TaskQuota *int   `json:"task_quota,omitempty"`
+}
+
+// PatchProjectRequest holds fields to change, nil fields are left untouched
+type PatchProjectRequest struct {
+	Name      *string `json:"name,omitempty"`
+	TaskQuota *int    `json:"task_quota,omitempty"`
+}
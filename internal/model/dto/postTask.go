@@ -6,9 +6,28 @@ import (
 )
 
 type PostTaskRequest struct {
-	Status      model.TaskStatus `json:"status"`
-	Name        string           `json:"name"`
-	Description string           `json:"description"`
+	Status      model.TaskStatus   `json:"status"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Priority    model.TaskPriority `json:"priority,omitempty"`
+	AssigneeID  int                `json:"assignee_id,omitempty"`
+	ReporterID  int                `json:"reporter_id,omitempty"`
+	ParentID    *TaskRef           `json:"parent_id,omitempty"`
+	DueAt       time.Time          `json:"due_at,omitzero"`
+	// AllowPastDue permits a due date in the past, e.g. for tasks entered after the fact
+	AllowPastDue bool `json:"allow_past_due,omitempty"`
+	// Recurrence makes the task recreate itself, without a due date the first occurrence is due at the next time of the rule
+	Recurrence *RecurrenceRequest `json:"recurrence,omitempty"`
+}
+
+// RecurrenceRequest sets the schedule of a recurring task
+type RecurrenceRequest struct {
+	// Rule is a cron expression like "0 9 * * MON" or an RRULE like "FREQ=WEEKLY;BYDAY=MO"
+	Rule string `json:"rule"`
+	// TimeZone is an IANA name like "Europe/Berlin", UTC when empty
+	TimeZone string `json:"time_zone,omitempty"`
+	// Start anchors intervals and counts of RRULEs, it's the due date of the task when omitted
+	Start time.Time `json:"start,omitzero"`
 }
 
 type PostTaskResponse struct {
@@ -0,0 +1,11 @@
+package dto
+
+type PostUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type PutUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
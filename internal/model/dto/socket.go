@@ -0,0 +1,58 @@
+package dto
+
+import "encoding/json"
+
+// SocketMessageType is the type of a message of the WebSocket API
+type SocketMessageType string
+
+// messages sent by clients
+const (
+	SocketSubscribe   SocketMessageType = "subscribe"
+	SocketUnsubscribe SocketMessageType = "unsubscribe"
+	SocketCreate      SocketMessageType = "create"
+	SocketUpdate      SocketMessageType = "update"
+)
+
+// messages sent by the server
+const (
+	SocketSubscribed   SocketMessageType = "subscribed"
+	SocketUnsubscribed SocketMessageType = "unsubscribed"
+	SocketChange       SocketMessageType = "change"
+	SocketReset        SocketMessageType = "reset"
+	SocketTask         SocketMessageType = "task"
+	SocketError        SocketMessageType = "error"
+)
+
+// SocketRequest is a message of a WebSocket client
+type SocketRequest struct {
+	// ID is chosen by the client, the reply to the request carries it
+	ID   string            `json:"id,omitempty"`
+	Type SocketMessageType `json:"type"`
+
+	// Filter holds query parameters of GET /tasks the subscription is limited to
+	Filter map[string]string `json:"filter,omitempty"`
+	// TaskIDs limit the subscription to the tasks, empty means every task matching the filter
+	TaskIDs []TaskRef `json:"task_ids,omitempty"`
+	// LastEventID resumes the subscription after the change with the id
+	LastEventID int `json:"last_event_id,omitempty"`
+	// Subscription is the id of the subscription to unsubscribe from
+	Subscription int `json:"subscription,omitempty"`
+
+	// TaskID is the task to update
+	TaskID *TaskRef `json:"task_id,omitempty"`
+	// Task is PostTaskRequest for create and PatchTaskRequest for update
+	Task json.RawMessage `json:"task,omitempty"`
+}
+
+// SocketResponse is a message of the server to a WebSocket client
+type SocketResponse struct {
+	// ID is the id of the request the message replies to
+	ID           string               `json:"id,omitempty"`
+	Type         SocketMessageType    `json:"type"`
+	Subscription int                  `json:"subscription,omitempty"`
+	Task         *GetTaskByIdResponse `json:"task,omitempty"`
+	Event        *TaskChangeEvent     `json:"event,omitempty"`
+	// Status is the http status matching the error
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
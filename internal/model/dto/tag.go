@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"ivanjabrony/test_lo/internal/model"
+	"time"
+)
+
+type PostTagRequest struct {
+	Name string `json:"name"`
+}
+
+type PutTagRequest struct {
+	Name string `json:"name"`
+}
+
+type GetAllTagsResponse struct {
+	Amount int         `json:"amount"`
+	Tags   []model.Tag `json:"tags"`
+}
+
+type GetTagByIdResponse struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	TaskCount int       `json:"task_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
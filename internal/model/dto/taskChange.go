@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"ivanjabrony/test_lo/internal/model"
+	"time"
+)
+
+// TaskChangeEvent is a committed change of a task sent to subscribers
+type TaskChangeEvent struct {
+	ID        int                  `json:"id"`
+	Type      model.TaskChangeType `json:"type"`
+	ProjectID int                  `json:"project_id"`
+	At        time.Time            `json:"at"`
+	// Task is the state after the change, for deleted tasks the last state before it
+	Task GetTaskByIdResponse `json:"task"`
+}
+
+// TaskChangeStream is a subscription to changes of tasks
+type TaskChangeStream struct {
+	// Replay holds the changes published after the last change the subscriber saw
+	Replay []TaskChangeEvent
+	// Complete is false when some of the missed changes can't be replayed anymore
+	// and the subscriber has to reload the tasks
+	Complete bool
+	// Events are closed when the subscriber falls too far behind or its context is done
+	Events <-chan TaskChangeEvent
+}
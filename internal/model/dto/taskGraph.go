@@ -0,0 +1,38 @@
+package dto
+
+import "encoding/json"
+
+// GetSubtasksResponse is a task with its subtasks down to the requested depth
+type GetSubtasksResponse struct {
+	GetTaskByIdResponse
+	Subtasks []GetSubtasksResponse `json:"subtasks"`
+}
+
+// MarshalJSON shows public ids like GetTaskByIdResponse.MarshalJSON, which would hide the subtasks if it was promoted
+func (r GetSubtasksResponse) MarshalJSON() ([]byte, error) {
+	type response GetTaskByIdResponse
+	type subtasks struct {
+		Subtasks []GetSubtasksResponse `json:"subtasks"`
+	}
+	ids, ok := r.publicIds()
+	if !ok {
+		return json.Marshal(struct {
+			response
+			subtasks
+		}{response(r.GetTaskByIdResponse), subtasks{r.Subtasks}})
+	}
+	return json.Marshal(struct {
+		Id string `json:"id"`
+		response
+		ParentID  *string  `json:"parent_id,omitempty"`
+		BlockedBy []string `json:"blocked_by,omitempty"`
+		subtasks
+	}{ids.Id, response(r.GetTaskByIdResponse), ids.ParentID, ids.BlockedBy, subtasks{r.Subtasks}})
+}
+
+// GetDependencyOrderResponse lists the blockers of a task in an order they can be done in,
+// the task itself is the last one
+type GetDependencyOrderResponse struct {
+	Amount int                   `json:"amount"`
+	Tasks  []GetTaskByIdResponse `json:"tasks"`
+}
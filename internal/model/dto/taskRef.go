@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+)
+
+// TaskRef refers to another task in requests and responses: by its id, a json number,
+// or by its public id, a json string, when tasks are given public ids. Internal callers set both
+// when they know both, the one the server uses is taken.
+type TaskRef struct {
+	Id       int
+	PublicID string
+}
+
+func (r TaskRef) MarshalJSON() ([]byte, error) {
+	if r.PublicID != "" {
+		return json.Marshal(r.PublicID)
+	}
+	return json.Marshal(r.Id)
+}
+
+func (r *TaskRef) UnmarshalJSON(data []byte) error {
+	*r = TaskRef{}
+	if len(data) == 0 || data[0] != '"' {
+		return json.Unmarshal(data, &r.Id)
+	}
+	if err := json.Unmarshal(data, &r.PublicID); err != nil {
+		return err
+	}
+	if r.PublicID == "" {
+		return errors.New("empty task id")
+	}
+	return nil
+}
+
+// TaskResponse is the task as it's sent in lists and exports, it shows public ids like GetTaskByIdResponse
+type TaskResponse model.Task
+
+func (t TaskResponse) MarshalJSON() ([]byte, error) {
+	type task model.Task
+	ids, ok := model.Task(t).PublicIds()
+	if !ok {
+		return json.Marshal(task(t))
+	}
+	return json.Marshal(struct {
+		Id string `json:"id"`
+		task
+		ParentID  *string  `json:"parent_id,omitempty"`
+		BlockedBy []string `json:"blocked_by,omitempty"`
+	}{ids.Id, task(t), ids.ParentID, ids.BlockedBy})
+}
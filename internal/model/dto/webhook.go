@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"ivanjabrony/test_lo/internal/model"
+	"time"
+)
+
+type PostWebhookRequest struct {
+	URL string `json:"url"`
+	// Secret signs deliveries, a random one is generated when it's empty
+	Secret string               `json:"secret,omitempty"`
+	Events []model.WebhookEvent `json:"events,omitempty"`
+	// Active defaults to true
+	Active *bool `json:"active,omitempty"`
+}
+
+// PostWebhookResponse is the only response showing the secret of the webhook
+type PostWebhookResponse struct {
+	Id     int    `json:"id"`
+	Secret string `json:"secret"`
+}
+
+type PutWebhookRequest struct {
+	URL string `json:"url"`
+	// Secret replaces the secret of the webhook, empty keeps it
+	Secret string               `json:"secret,omitempty"`
+	Events []model.WebhookEvent `json:"events,omitempty"`
+	Active bool                 `json:"active"`
+}
+
+type GetWebhookByIdResponse struct {
+	Id        int                  `json:"id"`
+	ProjectID int                  `json:"project_id"`
+	URL       string               `json:"url"`
+	Events    []model.WebhookEvent `json:"events"`
+	Active    bool                 `json:"active"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+type GetAllWebhooksResponse struct {
+	Amount   int                      `json:"amount"`
+	Webhooks []GetWebhookByIdResponse `json:"webhooks"`
+}
+
+// GetWebhookDeliveriesResponse is a page of the delivery log of a webhook, newest first
+type GetWebhookDeliveriesResponse struct {
+	Amount     int                     `json:"amount"`
+	Total      int                     `json:"total"`
+	Limit      int                     `json:"limit"`
+	Offset     int                     `json:"offset"`
+	Deliveries []model.WebhookDelivery `json:"deliveries"`
+}
+
+type GetDeadLettersResponse struct {
+	Amount      int                `json:"amount"`
+	DeadLetters []model.DeadLetter `json:"dead_letters"`
+}
+
+// WebhookPayload is the body of a delivery
+type WebhookPayload struct {
+	DeliveryID string              `json:"delivery_id"`
+	Event      model.WebhookEvent  `json:"event"`
+	ChangeID   int                 `json:"change_id"`
+	ProjectID  int                 `json:"project_id"`
+	At         time.Time           `json:"at"`
+	Task       GetTaskByIdResponse `json:"task"`
+}
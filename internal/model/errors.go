@@ -0,0 +1,45 @@
+package model
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when requested entity doesn't exist
+	ErrNotFound = errors.New("nonexistent id")
+	// ErrInvalid is returned when entity refers to data that can't be accepted
+	ErrInvalid = errors.New("invalid data")
+	// ErrAlreadyExists is returned when unique entity field is already taken
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrUnauthenticated is returned when an action requires a caller, but there is none
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned when the caller isn't allowed to perform an action
+	ErrForbidden = errors.New("forbidden")
+	// ErrQuotaExceeded is returned when a project can't hold more tasks
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrCycle is returned when a parent or a blocker would make a task depend on itself
+	ErrCycle = errors.New("dependency cycle")
+	// ErrIncomplete is returned when a task can't be done while it has open blockers or unfinished subtasks
+	ErrIncomplete = errors.New("open blockers or unfinished subtasks")
+	// ErrUnsupported is returned when the configured storage can't perform an operation
+	ErrUnsupported = errors.New("unsupported by storage")
+)
+
+// invalidError keeps the message of a validation error and makes it match ErrInvalid
+type invalidError struct {
+	err error
+}
+
+func (e invalidError) Error() string {
+	return e.err.Error()
+}
+
+func (e invalidError) Unwrap() []error {
+	return []error{ErrInvalid, e.err}
+}
+
+// Invalid marks a validation error, so it matches ErrInvalid with errors.Is
+func Invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	return invalidError{err}
+}
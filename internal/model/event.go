@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// TaskEventType is the kind of a domain event of the event sourced task storage
+type TaskEventType string
+
+const (
+	TaskCreated       TaskEventType = "task_created"
+	TaskRenamed       TaskEventType = "task_renamed"
+	TaskStatusChanged TaskEventType = "task_status_changed"
+	// TaskDetailsChanged covers every other field set by updates: description, priority,
+	// assignee, reporter, due date and parent
+	TaskDetailsChanged TaskEventType = "task_details_changed"
+	TaskDeleted        TaskEventType = "task_deleted"
+	BlockerAdded       TaskEventType = "blocker_added"
+	BlockerRemoved     TaskEventType = "blocker_removed"
+	TagCreated         TaskEventType = "tag_created"
+	TagRenamed         TaskEventType = "tag_renamed"
+	TagDeleted         TaskEventType = "tag_deleted"
+	TagAttached        TaskEventType = "tag_attached"
+	TagDetached        TaskEventType = "tag_detached"
+)
+
+// TaskEvent is a single change of tasks or tags of a project. Events are facts: they are
+// recorded only after the change was checked, and folding them in order of Seq gives
+// the state of the storage at the time of the last one.
+type TaskEvent struct {
+	// Seq is the position of the event in the log, starting from 1
+	Seq       int           `json:"seq"`
+	ProjectID int           `json:"project_id"`
+	Type      TaskEventType `json:"type"`
+	At        time.Time     `json:"at"`
+	TaskID    int           `json:"task_id"`
+	// Task is the created task for TaskCreated and the task with new details for TaskDetailsChanged
+	Task   *Task      `json:"task,omitempty"`
+	Name   string     `json:"name,omitempty"`
+	Status TaskStatus `json:"status,omitempty"`
+	// RelatedID is the blocker of blocker events and the tag of TagDeleted, TagAttached and TagDetached
+	RelatedID int `json:"related_id"`
+	// Tag is the created tag for TagCreated and the tag with its new name for TagRenamed
+	Tag *Tag `json:"tag,omitempty"`
+}
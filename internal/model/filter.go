@@ -1,11 +1,85 @@
 package model
 
+import "time"
+
 var EmptyFilter Filter = Filter{}
 
 const Created TaskStatus = "created"
 const InProgress TaskStatus = "inProgress"
 const Done TaskStatus = "done"
 
+// SortField is a task field GetAll can order by, tasks are ordered by id by default
+type SortField string
+
+const (
+	SortById          SortField = "id"
+	SortByDueAt       SortField = "due_at"
+	SortByPriority    SortField = "priority"
+	SortByCreatedAt   SortField = "created_at"
+	SortByStartedAt   SortField = "started_at"
+	SortByCompletedAt SortField = "completed_at"
+)
+
 type Filter struct {
-	Status TaskStatus
+	Status     TaskStatus
+	AssigneeID int
+	Priority   TaskPriority
+	// Overdue keeps only the tasks that aren't done and are past their due date
+	Overdue bool
+	// Recurring keeps only the latest occurrences of recurring tasks
+	Recurring bool
+	// DueBefore and DueAfter bound the due date, tasks without one never match them
+	DueBefore time.Time
+	DueAfter  time.Time
+	// CreatedBefore and CreatedAfter bound the creation time the same way
+	CreatedBefore time.Time
+	CreatedAfter  time.Time
+	// Tags keeps tasks tagged with the named tags, TagMatch decides whether any (default) or all of them are needed
+	Tags     []string
+	TagMatch TagMatch
+
+	SortBy SortField
+	// Descending reverses the order, tasks without the sorted timestamp stay at the end either way
+	Descending bool
+
+	// Page selects a part of the ordered matching tasks, every task is returned when Limit is 0
+	Page Page
+}
+
+// Paged reports whether the filter asks for a page of the tasks
+func (f Filter) Paged() bool {
+	return f.Page != Page{}
+}
+
+// Matches reports whether the task matches every field of the filter but Tags, tags are matched by storages
+// with their indexes since the task only knows ids of its tags
+func (f Filter) Matches(task Task, now time.Time) bool {
+	if f.Status != "" && task.Status != f.Status {
+		return false
+	}
+	if f.AssigneeID != NoUser && task.AssigneeID != f.AssigneeID {
+		return false
+	}
+	if f.Priority != "" && task.Priority != f.Priority {
+		return false
+	}
+	if f.Overdue && !task.IsOverdue(now) {
+		return false
+	}
+	if f.Recurring && task.Recurrence == nil {
+		return false
+	}
+	if !f.DueBefore.IsZero() && (task.DueAt.IsZero() || !task.DueAt.Before(f.DueBefore)) {
+		return false
+	}
+	if !f.DueAfter.IsZero() && (task.DueAt.IsZero() || task.DueAt.Before(f.DueAfter)) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !task.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && task.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	return true
 }
@@ -1,27 +1,66 @@
 package mapper
 
 import (
+	taskv1 "ivanjabrony/test_lo/api/task/v1"
 	"ivanjabrony/test_lo/internal/model"
 	"ivanjabrony/test_lo/internal/model/dto"
 	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func PostTaskRequestToTask(request dto.PostTaskRequest) model.Task {
+	priority := request.Priority
+	if priority == "" {
+		priority = model.DefaultPriority
+	}
 	return model.Task{
 		Id:          0,
 		Status:      request.Status,
 		Description: request.Description,
 		Name:        request.Name,
+		Priority:    priority,
+		AssigneeID:  request.AssigneeID,
+		ReporterID:  request.ReporterID,
+		ParentID:    taskRefId(request.ParentID),
+		DueAt:       request.DueAt,
+		Recurrence:  RecurrenceRequestToRecurrence(request.Recurrence),
 		CreatedAt:   time.Time{}}
 }
 
-func TaskToGetTaskByIdReponse(task model.Task) dto.GetTaskByIdResponse {
+// RecurrenceRequestToRecurrence maps the requested schedule, a zero start is set by the usecase
+func RecurrenceRequestToRecurrence(request *dto.RecurrenceRequest) *model.Recurrence {
+	if request == nil {
+		return nil
+	}
+	return &model.Recurrence{
+		Rule:     request.Rule,
+		TimeZone: request.TimeZone,
+		Start:    request.Start}
+}
+
+// TaskToGetTaskByIdReponse maps the task, it's overdue if it's due before now
+func TaskToGetTaskByIdReponse(task model.Task, now time.Time) dto.GetTaskByIdResponse {
 	return dto.GetTaskByIdResponse{
-		Id:          task.Id,
-		Status:      task.Status,
-		Description: task.Description,
-		Name:        task.Name,
-		CreatedAt:   task.CreatedAt}
+		Id:              task.Id,
+		PublicID:        task.PublicID,
+		Status:          task.Status,
+		Description:     task.Description,
+		Name:            task.Name,
+		Priority:        task.Priority,
+		AssigneeID:      task.AssigneeID,
+		ReporterID:      task.ReporterID,
+		TagIDs:          task.TagIDs,
+		ParentID:        task.ParentID,
+		BlockedBy:       task.BlockedBy,
+		PublicParentID:  task.PublicParentID,
+		PublicBlockedBy: task.PublicBlockedBy,
+		DueAt:           task.DueAt,
+		Overdue:         task.IsOverdue(now),
+		Recurrence:      task.Recurrence,
+		CreatedAt:       task.CreatedAt,
+		StartedAt:       task.StartedAt,
+		CompletedAt:     task.CompletedAt}
 }
 
 func TasksToGetAllTasksResponse(tasks []model.Task) dto.GetAllTasksResponse {
@@ -36,3 +75,485 @@ func TaskToPostTaskReponse(task model.Task) dto.PostTaskResponse {
 		Id:        task.Id,
 		CreatedAt: task.CreatedAt}
 }
+
+func PostUserRequestToUser(request dto.PostUserRequest) model.User {
+	return model.User{
+		Id:        0,
+		Name:      request.Name,
+		Email:     request.Email,
+		CreatedAt: time.Time{}}
+}
+
+func PutUserRequestToUser(userId int, request dto.PutUserRequest) model.User {
+	return model.User{
+		Id:    userId,
+		Name:  request.Name,
+		Email: request.Email}
+}
+
+func UserToGetUserByIdResponse(user model.User) dto.GetUserByIdResponse {
+	return dto.GetUserByIdResponse{
+		Id:        user.Id,
+		Name:      user.Name,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt}
+}
+
+func UsersToGetAllUsersResponse(users []model.User) dto.GetAllUsersResponse {
+	return dto.GetAllUsersResponse{
+		Amount: len(users),
+		Users:  users,
+	}
+}
+
+func ApplyPatchTaskRequest(task model.Task, request dto.PatchTaskRequest) model.Task {
+	if request.Status != nil {
+		task.Status = *request.Status
+	}
+	if request.Name != nil {
+		task.Name = *request.Name
+	}
+	if request.Description != nil {
+		task.Description = *request.Description
+	}
+	if request.Priority != nil {
+		task.Priority = *request.Priority
+	}
+	if request.AssigneeID != nil {
+		task.AssigneeID = *request.AssigneeID
+	}
+	if request.DueAt != nil {
+		task.DueAt = *request.DueAt
+	}
+	if request.ParentID != nil {
+		task.ParentID = &request.ParentID.Id
+	}
+	if request.ClearParent {
+		task.ParentID = nil
+	}
+	if request.Recurrence != nil {
+		task.Recurrence = RecurrenceRequestToRecurrence(request.Recurrence)
+	}
+	if request.ClearRecurrence {
+		task.Recurrence = nil
+	}
+	return task
+}
+
+func PostProjectRequestToProject(request dto.PostProjectRequest, defaultQuota int) model.Project {
+	project := model.Project{
+		Name:      request.Name,
+		TaskQuota: defaultQuota}
+	if request.TaskQuota != nil {
+		project.TaskQuota = *request.TaskQuota
+	}
+	return project
+}
+
+func ApplyPatchProjectRequest(project model.Project, request dto.PatchProjectRequest) model.Project {
+	if request.Name != nil {
+		project.Name = *request.Name
+	}
+	if request.TaskQuota != nil {
+		project.TaskQuota = *request.TaskQuota
+	}
+	return project
+}
+
+func ProjectToGetProjectByIdResponse(project model.Project) dto.GetProjectByIdResponse {
+	return dto.GetProjectByIdResponse{
+		Id:        project.Id,
+		Name:      project.Name,
+		TaskQuota: project.TaskQuota,
+		CreatedAt: project.CreatedAt}
+}
+
+func ProjectsToGetAllProjectsResponse(projects []model.Project) dto.GetAllProjectsResponse {
+	return dto.GetAllProjectsResponse{
+		Amount:   len(projects),
+		Projects: projects,
+	}
+}
+
+func PostTagRequestToTag(request dto.PostTagRequest) model.Tag {
+	return model.Tag{
+		Name: model.NormalizeTagName(request.Name)}
+}
+
+func PutTagRequestToTag(tagId int, request dto.PutTagRequest) model.Tag {
+	return model.Tag{
+		Id:   tagId,
+		Name: model.NormalizeTagName(request.Name)}
+}
+
+func TagsToGetAllTagsResponse(tags []model.Tag) dto.GetAllTagsResponse {
+	return dto.GetAllTagsResponse{
+		Amount: len(tags),
+		Tags:   tags,
+	}
+}
+
+func TagToGetTagByIdResponse(tag model.Tag) dto.GetTagByIdResponse {
+	return dto.GetTagByIdResponse{
+		Id:        tag.Id,
+		Name:      tag.Name,
+		TaskCount: tag.TaskCount,
+		CreatedAt: tag.CreatedAt}
+}
+
+func TaskNodeToGetSubtasksResponse(node model.TaskNode, now time.Time) dto.GetSubtasksResponse {
+	subtasks := make([]dto.GetSubtasksResponse, 0, len(node.Subtasks))
+	for _, subtask := range node.Subtasks {
+		subtasks = append(subtasks, TaskNodeToGetSubtasksResponse(subtask, now))
+	}
+	return dto.GetSubtasksResponse{
+		GetTaskByIdResponse: TaskToGetTaskByIdReponse(node.Task, now),
+		Subtasks:            subtasks,
+	}
+}
+
+func TasksToGetDependencyOrderResponse(tasks []model.Task, now time.Time) dto.GetDependencyOrderResponse {
+	ans := make([]dto.GetTaskByIdResponse, 0, len(tasks))
+	for _, task := range tasks {
+		ans = append(ans, TaskToGetTaskByIdReponse(task, now))
+	}
+	return dto.GetDependencyOrderResponse{
+		Amount: len(ans),
+		Tasks:  ans,
+	}
+}
+
+func PostCommentRequestToComment(taskId, authorId int, request dto.PostCommentRequest) model.Comment {
+	return model.Comment{
+		TaskID:   taskId,
+		AuthorID: authorId,
+		Body:     model.SanitizeMarkdown(request.Body)}
+}
+
+func PutCommentRequestToComment(taskId, commentId int, request dto.PutCommentRequest) model.Comment {
+	return model.Comment{
+		Id:     commentId,
+		TaskID: taskId,
+		Body:   model.SanitizeMarkdown(request.Body)}
+}
+
+// CommentsToGetCommentsResponse maps comments of the task, they refer to it by its public id once it has one
+func CommentsToGetCommentsResponse(task model.Task, comments []model.Comment, total int, page model.Page) dto.GetCommentsResponse {
+	ans := make([]dto.GetCommentByIdResponse, 0, len(comments))
+	for _, comment := range comments {
+		ans = append(ans, CommentToGetCommentByIdResponse(task, comment))
+	}
+	return dto.GetCommentsResponse{
+		Amount:   len(comments),
+		Total:    total,
+		Limit:    page.Limit,
+		Offset:   page.Offset,
+		Comments: ans,
+	}
+}
+
+func CommentToGetCommentByIdResponse(task model.Task, comment model.Comment) dto.GetCommentByIdResponse {
+	return dto.GetCommentByIdResponse{
+		Id:        comment.Id,
+		TaskID:    dto.TaskRef{Id: comment.TaskID, PublicID: task.PublicID},
+		AuthorID:  comment.AuthorID,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt,
+		EditedAt:  comment.EditedAt}
+}
+
+func AuditEntriesToGetAuditResponse(entries []model.AuditEntry, total int, page model.Page) dto.GetAuditResponse {
+	return dto.GetAuditResponse{
+		Amount:  len(entries),
+		Total:   total,
+		Limit:   page.Limit,
+		Offset:  page.Offset,
+		Entries: entries,
+	}
+}
+
+func TaskChangeToTaskChangeEvent(change model.TaskChange) dto.TaskChangeEvent {
+	return dto.TaskChangeEvent{
+		ID:        change.ID,
+		Type:      change.Type,
+		ProjectID: change.ProjectID,
+		At:        change.At,
+		Task:      TaskToGetTaskByIdReponse(change.Task, change.At),
+	}
+}
+
+func PostWebhookRequestToWebhook(request dto.PostWebhookRequest) model.Webhook {
+	active := true
+	if request.Active != nil {
+		active = *request.Active
+	}
+	return model.Webhook{
+		URL:    request.URL,
+		Secret: request.Secret,
+		Events: request.Events,
+		Active: active,
+	}
+}
+
+// PutWebhookRequestToWebhook replaces the webhook, the secret is kept unless a new one is sent
+func PutWebhookRequestToWebhook(webhook model.Webhook, request dto.PutWebhookRequest) model.Webhook {
+	webhook.URL = request.URL
+	webhook.Events = request.Events
+	webhook.Active = request.Active
+	if request.Secret != "" {
+		webhook.Secret = request.Secret
+	}
+	return webhook
+}
+
+func WebhookToGetWebhookByIdResponse(webhook model.Webhook) dto.GetWebhookByIdResponse {
+	events := webhook.Events
+	if events == nil {
+		events = make([]model.WebhookEvent, 0)
+	}
+	return dto.GetWebhookByIdResponse{
+		Id:        webhook.Id,
+		ProjectID: webhook.ProjectID,
+		URL:       webhook.URL,
+		Events:    events,
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt}
+}
+
+func WebhooksToGetAllWebhooksResponse(webhooks []model.Webhook) dto.GetAllWebhooksResponse {
+	ans := make([]dto.GetWebhookByIdResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		ans = append(ans, WebhookToGetWebhookByIdResponse(webhook))
+	}
+	return dto.GetAllWebhooksResponse{
+		Amount:   len(ans),
+		Webhooks: ans,
+	}
+}
+
+func WebhookDeliveriesToGetWebhookDeliveriesResponse(deliveries []model.WebhookDelivery, total int, page model.Page) dto.GetWebhookDeliveriesResponse {
+	return dto.GetWebhookDeliveriesResponse{
+		Amount:     len(deliveries),
+		Total:      total,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		Deliveries: deliveries,
+	}
+}
+
+func DeadLettersToGetDeadLettersResponse(letters []model.DeadLetter) dto.GetDeadLettersResponse {
+	return dto.GetDeadLettersResponse{
+		Amount:      len(letters),
+		DeadLetters: letters,
+	}
+}
+
+func TaskChangeToWebhookPayload(deliveryId string, event model.WebhookEvent, change model.TaskChange) dto.WebhookPayload {
+	return dto.WebhookPayload{
+		DeliveryID: deliveryId,
+		Event:      event,
+		ChangeID:   change.ID,
+		ProjectID:  change.ProjectID,
+		At:         change.At,
+		Task:       TaskToGetTaskByIdReponse(change.Task, change.At),
+	}
+}
+
+func CreateTaskRequestToPostTaskRequest(request *taskv1.CreateTaskRequest) dto.PostTaskRequest {
+	return dto.PostTaskRequest{
+		Status:       model.TaskStatus(request.GetStatus()),
+		Name:         request.GetName(),
+		Description:  request.GetDescription(),
+		Priority:     model.TaskPriority(request.GetPriority()),
+		AssigneeID:   int(request.GetAssigneeId()),
+		ReporterID:   int(request.GetReporterId()),
+		ParentID:     protoTaskRef(request.ParentId, request.ParentPublicId),
+		DueAt:        timestampToTime(request.GetDueAt()),
+		AllowPastDue: request.GetAllowPastDue(),
+	}
+}
+
+// UpdateTaskRequestToPatchTaskRequest keeps unset fields of the request nil, so they are left untouched
+func UpdateTaskRequestToPatchTaskRequest(request *taskv1.UpdateTaskRequest) dto.PatchTaskRequest {
+	patch := dto.PatchTaskRequest{
+		Name:         request.Name,
+		Description:  request.Description,
+		AssigneeID:   optionalInt(request.AssigneeId),
+		ParentID:     protoTaskRef(request.ParentId, request.ParentPublicId),
+		ClearParent:  request.GetClearParent(),
+		AllowPastDue: request.GetAllowPastDue(),
+	}
+	if request.Status != nil {
+		status := model.TaskStatus(*request.Status)
+		patch.Status = &status
+	}
+	if request.Priority != nil {
+		priority := model.TaskPriority(*request.Priority)
+		patch.Priority = &priority
+	}
+	if request.DueAt != nil {
+		dueAt := request.DueAt.AsTime()
+		patch.DueAt = &dueAt
+	}
+	return patch
+}
+
+// TaskFilterToFilter builds the filter the way GET /tasks parses its query params, it isn't validated
+func TaskFilterToFilter(filter *taskv1.TaskFilter) model.Filter {
+	ans := model.Filter{
+		Status:     model.TaskStatus(filter.GetStatus()),
+		AssigneeID: int(filter.GetAssigneeId()),
+		Priority:   model.TaskPriority(filter.GetPriority()),
+		Overdue:    filter.GetOverdue(),
+		DueBefore:  timestampToTime(filter.GetDueBefore()),
+		DueAfter:   timestampToTime(filter.GetDueAfter()),
+		TagMatch:   model.TagMatch(filter.GetTagMatch()),
+		SortBy:     model.SortField(filter.GetSortBy()),
+		Descending: filter.GetDescending(),
+	}
+	for _, name := range filter.GetTags() {
+		if name = model.NormalizeTagName(name); name != "" {
+			ans.Tags = append(ans.Tags, name)
+		}
+	}
+	return ans
+}
+
+func GetTaskByIdResponseToProtoTask(task dto.GetTaskByIdResponse) *taskv1.Task {
+	ans := &taskv1.Task{
+		Id:           int64(task.Id),
+		Status:       string(task.Status),
+		Name:         task.Name,
+		Description:  task.Description,
+		Priority:     string(task.Priority),
+		AssigneeId:   int64(task.AssigneeID),
+		ReporterId:   int64(task.ReporterID),
+		TagIds:       intsToInt64s(task.TagIDs),
+		BlockedBy:    intsToInt64s(task.BlockedBy),
+		CommentCount: int64(task.CommentCount),
+		DueAt:        timeToTimestamp(task.DueAt),
+		Overdue:      task.Overdue,
+		CreatedAt:    timeToTimestamp(task.CreatedAt),
+		StartedAt:    timeToTimestamp(task.StartedAt),
+		CompletedAt:  timeToTimestamp(task.CompletedAt),
+	}
+	if task.ParentID != nil {
+		parentId := int64(*task.ParentID)
+		ans.ParentId = &parentId
+	}
+	return ans
+}
+
+// GetTaskByIdResponseToPublicProtoTask maps the task for servers giving tasks public ids,
+// the public fields are set in place of the int64 ids, which are left zero
+func GetTaskByIdResponseToPublicProtoTask(task dto.GetTaskByIdResponse) *taskv1.Task {
+	ans := GetTaskByIdResponseToProtoTask(task)
+	ids := task.PublicIds()
+	ans.Id, ans.ParentId, ans.BlockedBy = 0, nil, nil
+	ans.PublicId, ans.PublicParentId, ans.PublicBlockedBy = ids.Id, ids.ParentID, ids.BlockedBy
+	return ans
+}
+
+func TaskChangeEventToProtoTaskEvent(event dto.TaskChangeEvent) *taskv1.TaskEvent {
+	return &taskv1.TaskEvent{
+		Id:        int64(event.ID),
+		Type:      string(event.Type),
+		ProjectId: int64(event.ProjectID),
+		At:        timeToTimestamp(event.At),
+		Task:      GetTaskByIdResponseToProtoTask(event.Task),
+	}
+}
+
+// timeToTimestamp maps the zero time, that means there is no timestamp, to nil
+func timeToTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timestampToTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// taskRefId is the id the ref points to, the usecase resolves public ids before the mapping
+func taskRefId(ref *dto.TaskRef) *int {
+	if ref == nil {
+		return nil
+	}
+	id := ref.Id
+	return &id
+}
+
+// protoTaskRef refers to the task by the public id if it's set, the usecase checks which one the server uses
+func protoTaskRef(id *int64, publicId *string) *dto.TaskRef {
+	switch {
+	case publicId != nil:
+		return &dto.TaskRef{PublicID: *publicId}
+	case id != nil:
+		return &dto.TaskRef{Id: int(*id)}
+	default:
+		return nil
+	}
+}
+
+func optionalInt(value *int64) *int {
+	if value == nil {
+		return nil
+	}
+	ans := int(*value)
+	return &ans
+}
+
+func intsToInt64s(values []int) []int64 {
+	if len(values) == 0 {
+		return nil
+	}
+	ans := make([]int64, 0, len(values))
+	for _, value := range values {
+		ans = append(ans, int64(value))
+	}
+	return ans
+}
+
+func PutNotificationPreferencesRequestToPreferences(userId int, request dto.PutNotificationPreferencesRequest) model.NotificationPreferences {
+	return model.NotificationPreferences{
+		UserID:     userId,
+		Channels:   request.Channels,
+		Muted:      request.Muted,
+		WebhookURL: request.WebhookURL,
+	}
+}
+
+// PreferencesToGetNotificationPreferencesResponse shows the default channels in place of unchosen ones
+func PreferencesToGetNotificationPreferencesResponse(preferences model.NotificationPreferences, defaults []model.NotificationChannel) dto.GetNotificationPreferencesResponse {
+	channels := preferences.Channels
+	if channels == nil {
+		channels = defaults
+	}
+	muted := preferences.Muted
+	if muted == nil {
+		muted = make([]model.ReminderRule, 0)
+	}
+	return dto.GetNotificationPreferencesResponse{
+		UserID:          preferences.UserID,
+		Channels:        append(make([]model.NotificationChannel, 0, len(channels)), channels...),
+		DefaultChannels: preferences.Channels == nil,
+		Muted:           muted,
+		WebhookURL:      preferences.WebhookURL,
+	}
+}
+
+func ReminderToReminderPayload(reminder model.Reminder) dto.ReminderPayload {
+	return dto.ReminderPayload{
+		Rule:      reminder.Rule,
+		UserID:    reminder.Recipient.Id,
+		ProjectID: reminder.Task.ProjectID,
+		Anchor:    reminder.Anchor,
+		At:        reminder.At,
+		Task:      TaskToGetTaskByIdReponse(reminder.Task, reminder.At),
+	}
+}
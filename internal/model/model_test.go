@@ -1,6 +1,7 @@
 package model
 
 import (
+	"slices"
 	"testing"
 	"time"
 )
@@ -55,6 +56,16 @@ func TestTaskValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name:    "valid urgent task",
+			task:    Task{Id: 1, Status: Created, Name: "task 1", Priority: PriorityUrgent},
+			wantErr: false,
+		},
+		{
+			name:    "unknown priority",
+			task:    Task{Id: 1, Status: Created, Name: "task 1", Priority: "critical"},
+			wantErr: true,
+		},
 		{
 			name: "negative id",
 			task: Task{
@@ -104,6 +115,56 @@ func TestFilterValidation(t *testing.T) {
 			filter:  Filter{Status: "invalid"},
 			wantErr: true,
 		},
+		{
+			name:    "valid assignee filter",
+			filter:  Filter{AssigneeID: 3},
+			wantErr: false,
+		},
+		{
+			name:    "negative assignee filter",
+			filter:  Filter{Status: Done, AssigneeID: -3},
+			wantErr: true,
+		},
+		{
+			name:    "valid priority and sorting",
+			filter:  Filter{Priority: PriorityHigh, Overdue: true, SortBy: SortByDueAt, Descending: true},
+			wantErr: false,
+		},
+		{
+			name:    "unknown priority filter",
+			filter:  Filter{Priority: "critical"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown sort field",
+			filter:  Filter{SortBy: "name"},
+			wantErr: true,
+		},
+		{
+			name:    "inverted due range",
+			filter:  Filter{DueAfter: time.Now(), DueBefore: time.Now().Add(-time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "inverted creation range",
+			filter:  Filter{CreatedAfter: time.Now(), CreatedBefore: time.Now().Add(-time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "open creation range",
+			filter:  Filter{CreatedAfter: time.Now()},
+			wantErr: false,
+		},
+		{
+			name:    "page",
+			filter:  Filter{Page: Page{Limit: 20, Offset: 40}},
+			wantErr: false,
+		},
+		{
+			name:    "page over the limit",
+			filter:  Filter{Page: Page{Limit: MaxPageLimit + 1}},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -115,3 +176,356 @@ func TestFilterValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestUserValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    User
+		wantErr bool
+	}{
+		{
+			name:    "valid user",
+			user:    User{Id: 1, Name: "John", Email: "john@example.com"},
+			wantErr: false,
+		},
+		{
+			name:    "empty name",
+			user:    User{Id: 1, Name: "  ", Email: "john@example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "malformed email",
+			user:    User{Id: 1, Name: "John", Email: "john.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "email with display name",
+			user:    User{Id: 1, Name: "John", Email: "John <john@example.com>"},
+			wantErr: true,
+		},
+		{
+			name:    "negative id",
+			user:    User{Id: -1, Name: "John", Email: "john@example.com"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUser(tt.user)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDueAt(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := ValidateDueAt(now.Add(time.Hour), now, false); err != nil {
+		t.Errorf("Expected future due date to be valid, got %v", err)
+	}
+	if err := ValidateDueAt(time.Time{}, now, false); err != nil {
+		t.Errorf("Expected missing due date to be valid, got %v", err)
+	}
+	if err := ValidateDueAt(now.Add(-time.Hour), now, false); err == nil {
+		t.Error("Expected past due date to be invalid")
+	}
+	if err := ValidateDueAt(now.Add(-time.Hour), now, true); err != nil {
+		t.Errorf("Expected explicitly allowed past due date to be valid, got %v", err)
+	}
+}
+
+func TestApplyStatusTransition(t *testing.T) {
+	started := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		task          Task
+		previous      TaskStatus
+		wantStarted   time.Time
+		wantCompleted time.Time
+	}{
+		{
+			name:        "start",
+			task:        Task{Status: InProgress},
+			previous:    Created,
+			wantStarted: now,
+		},
+		{
+			name:          "finish started task",
+			task:          Task{Status: Done, StartedAt: started},
+			previous:      InProgress,
+			wantStarted:   started,
+			wantCompleted: now,
+		},
+		{
+			name:          "finish without starting",
+			task:          Task{Status: Done},
+			previous:      Created,
+			wantStarted:   now,
+			wantCompleted: now,
+		},
+		{
+			name:        "back to work",
+			task:        Task{Status: InProgress, StartedAt: started, CompletedAt: started},
+			previous:    Done,
+			wantStarted: started,
+		},
+		{
+			name:     "reopen",
+			task:     Task{Status: Created, StartedAt: started, CompletedAt: started},
+			previous: Done,
+		},
+		{
+			name:          "unchanged status",
+			task:          Task{Status: Done, StartedAt: started, CompletedAt: started},
+			previous:      Done,
+			wantStarted:   started,
+			wantCompleted: started,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			ApplyStatusTransition(&task, tt.previous, now)
+			if !task.StartedAt.Equal(tt.wantStarted) || !task.CompletedAt.Equal(tt.wantCompleted) {
+				t.Errorf("Expected started %v and completed %v, got %v and %v",
+					tt.wantStarted, tt.wantCompleted, task.StartedAt, task.CompletedAt)
+			}
+		})
+	}
+}
+
+func TestIsOverdue(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	if !(Task{Status: InProgress, DueAt: now.Add(-time.Minute)}).IsOverdue(now) {
+		t.Error("Expected task past its due date to be overdue")
+	}
+	if (Task{Status: Done, DueAt: now.Add(-time.Minute)}).IsOverdue(now) {
+		t.Error("Expected done task not to be overdue")
+	}
+	if (Task{Status: Created}).IsOverdue(now) {
+		t.Error("Expected task without due date not to be overdue")
+	}
+}
+
+func TestSanitizeMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "plain markdown", body: "**done**, see [docs](https://example.com)\n> quote", want: "**done**, see [docs](https://example.com)\n> quote"},
+		{name: "raw html", body: "<script>alert(1)</script>", want: "&lt;script>alert(1)&lt;/script>"},
+		{name: "entities", body: "a &lt; b", want: "a &amp;lt; b"},
+		{name: "script link", body: "[x](JavaScript:alert(1)", want: "[x](#)"},
+		{name: "data link", body: "[x]( data:text/html;base64,PHN)", want: "[x](#)"},
+		{name: "control characters", body: "  a\r\nb\x00c\x1b  ", want: "a\nbc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeMarkdown(tt.body); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDiffTasks(t *testing.T) {
+	parent := 0
+	created := Task{Id: 3, Name: "task", Status: Created, Priority: PriorityMedium, ParentID: &parent}
+	updated := created
+	updated.Status = Done
+	updated.AssigneeID = 2
+	updated.CompletedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		before   Task
+		after    Task
+		expected map[string][2]string
+	}{
+		{
+			name:   "create",
+			before: Task{},
+			after:  created,
+			expected: map[string][2]string{
+				"status":    {"null", `"created"`},
+				"name":      {"null", `"task"`},
+				"priority":  {"null", `"medium"`},
+				"parent_id": {"null", "0"},
+			},
+		},
+		{
+			name:   "update",
+			before: created,
+			after:  updated,
+			expected: map[string][2]string{
+				"status":       {`"created"`, `"done"`},
+				"assignee_id":  {"null", "2"},
+				"completed_at": {"null", `"2024-01-02T03:04:05Z"`},
+			},
+		},
+		{name: "no changes", before: updated, after: updated, expected: map[string][2]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := DiffTasks(tt.before, tt.after)
+			if len(changes) != len(tt.expected) {
+				t.Fatalf("Expected %d changes, got %+v", len(tt.expected), changes)
+			}
+			for _, change := range changes {
+				want, ok := tt.expected[change.Field]
+				if !ok || string(change.Before) != want[0] || string(change.After) != want[1] {
+					t.Errorf("Unexpected change of %s: %s -> %s", change.Field, change.Before, change.After)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	entries := make([]AuditEntry, 0)
+	prevHash := ""
+	for i, action := range []AuditAction{AuditCreate, AuditStatusChange, AuditDelete} {
+		entry := AuditEntry{Seq: i + 1, TaskID: 1, Action: action, At: time.Unix(int64(i), 0).UTC(), PrevHash: prevHash}
+		entry.Hash = entry.ComputeHash()
+		prevHash = entry.Hash
+		entries = append(entries, entry)
+	}
+
+	tampered := func(change func([]AuditEntry) []AuditEntry) []AuditEntry {
+		return change(append([]AuditEntry(nil), entries...))
+	}
+	tests := []struct {
+		name     string
+		entries  []AuditEntry
+		brokenAt int
+	}{
+		{name: "intact", entries: entries, brokenAt: 0},
+		{name: "empty", entries: nil, brokenAt: 0},
+		{name: "changed field", entries: tampered(func(e []AuditEntry) []AuditEntry { e[1].ActorID = 5; return e }), brokenAt: 2},
+		{name: "removed entry", entries: tampered(func(e []AuditEntry) []AuditEntry { return append(e[:1], e[2:]...) }), brokenAt: 2},
+		{
+			name: "rehashed entry",
+			entries: tampered(func(e []AuditEntry) []AuditEntry {
+				e[0].Action = AuditUpdate
+				e[0].Hash = e[0].ComputeHash()
+				return e
+			}),
+			brokenAt: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyAuditChain(tt.entries); got != tt.brokenAt {
+				t.Errorf("Expected chain to break at %d, got %d", tt.brokenAt, got)
+			}
+		})
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	open, done := Task{Status: InProgress}, Task{Status: Done}
+	tests := []struct {
+		name   string
+		change TaskChange
+		want   []WebhookEvent
+	}{
+		{name: "created", change: TaskChange{Type: TaskChangeCreated, Task: open}, want: []WebhookEvent{WebhookTaskCreated}},
+		{name: "created as done", change: TaskChange{Type: TaskChangeCreated, Task: done}, want: []WebhookEvent{WebhookTaskCreated, WebhookTaskCompleted}},
+		{name: "completed", change: TaskChange{Type: TaskChangeUpdated, Task: done, Previous: &open}, want: []WebhookEvent{WebhookTaskUpdated, WebhookTaskCompleted}},
+		{name: "done task renamed", change: TaskChange{Type: TaskChangeUpdated, Task: done, Previous: &done}, want: []WebhookEvent{WebhookTaskUpdated}},
+		{name: "deleted", change: TaskChange{Type: TaskChangeDeleted, Task: done}, want: []WebhookEvent{WebhookTaskDeleted}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WebhookEvents(tt.change); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWebhookValidation(t *testing.T) {
+	valid := Webhook{URL: "https://ci.example.com/hooks/tasks", Secret: "0123456789abcdef", Events: []WebhookEvent{WebhookTaskCompleted}}
+	with := func(change func(*Webhook)) Webhook {
+		webhook := valid
+		change(&webhook)
+		return webhook
+	}
+
+	tests := []struct {
+		name    string
+		webhook Webhook
+		wantErr bool
+	}{
+		{name: "valid", webhook: valid, wantErr: false},
+		{name: "every event", webhook: with(func(w *Webhook) { w.Events = nil }), wantErr: false},
+		{name: "relative url", webhook: with(func(w *Webhook) { w.URL = "/hooks" }), wantErr: true},
+		{name: "ftp url", webhook: with(func(w *Webhook) { w.URL = "ftp://example.com" }), wantErr: true},
+		{name: "short secret", webhook: with(func(w *Webhook) { w.Secret = "secret" }), wantErr: true},
+		{name: "unknown event", webhook: with(func(w *Webhook) { w.Events = []WebhookEvent{"task.archived"} }), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateWebhook(tt.webhook); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	valid := NotificationPreferences{UserID: 1, Channels: []NotificationChannel{ChannelEmail, ChannelWebhook}, WebhookURL: "https://chat.example.com/hooks/1"}
+	with := func(change func(*NotificationPreferences)) NotificationPreferences {
+		preferences := valid
+		change(&preferences)
+		return preferences
+	}
+
+	tests := []struct {
+		name        string
+		preferences NotificationPreferences
+		wantErr     bool
+	}{
+		{name: "valid", preferences: valid, wantErr: false},
+		{name: "default channels", preferences: NotificationPreferences{UserID: 1}, wantErr: false},
+		{name: "unknown channel", preferences: with(func(p *NotificationPreferences) { p.Channels = []NotificationChannel{"sms"} }), wantErr: true},
+		{name: "unknown muted rule", preferences: with(func(p *NotificationPreferences) { p.Muted = []ReminderRule{"weekly"} }), wantErr: true},
+		{name: "webhook without url", preferences: with(func(p *NotificationPreferences) { p.WebhookURL = "" }), wantErr: true},
+		{name: "relative url", preferences: with(func(p *NotificationPreferences) { p.WebhookURL = "/hooks" }), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateNotificationPreferences(tt.preferences); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNotificationPreferences() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	defaults := []NotificationChannel{ChannelLog}
+	if !(NotificationPreferences{}).Wants(ReminderOverdue, ChannelLog, defaults) {
+		t.Error("Expected default channels without preferences")
+	}
+	if (NotificationPreferences{Channels: []NotificationChannel{}}).Wants(ReminderOverdue, ChannelLog, defaults) {
+		t.Error("Expected no reminders with an empty list of channels")
+	}
+	if (NotificationPreferences{Muted: []ReminderRule{ReminderStale}}).Wants(ReminderStale, ChannelLog, defaults) {
+		t.Error("Expected no reminders of a muted rule")
+	}
+	if !valid.Wants(ReminderDueSoon, ChannelWebhook, defaults) || valid.Wants(ReminderDueSoon, ChannelLog, defaults) {
+		t.Error("Expected only the chosen channels")
+	}
+}
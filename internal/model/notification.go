@@ -0,0 +1,118 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// ReminderRule is a kind of reminder about a task
+type ReminderRule string
+
+const (
+	// ReminderDueSoon is sent when the due date of an unfinished task is near
+	ReminderDueSoon ReminderRule = "due_soon"
+	// ReminderOverdue is sent when an unfinished task is past its due date
+	ReminderOverdue ReminderRule = "overdue"
+	// ReminderStale is sent when a task stays in progress for too long
+	ReminderStale ReminderRule = "stale"
+)
+
+func (r ReminderRule) IsValid() bool {
+	switch r {
+	case ReminderDueSoon, ReminderOverdue, ReminderStale:
+		return true
+	}
+	return false
+}
+
+// NotificationChannel is a way reminders reach users
+type NotificationChannel string
+
+const (
+	// ChannelLog writes reminders to the server log
+	ChannelLog NotificationChannel = "log"
+	// ChannelEmail sends reminders to the email of the user
+	ChannelEmail NotificationChannel = "email"
+	// ChannelWebhook POSTs reminders to the webhook url of the user
+	ChannelWebhook NotificationChannel = "webhook"
+)
+
+func (c NotificationChannel) IsValid() bool {
+	switch c {
+	case ChannelLog, ChannelEmail, ChannelWebhook:
+		return true
+	}
+	return false
+}
+
+// NotificationPreferences are the reminders a user wants and how they are sent
+type NotificationPreferences struct {
+	UserID int `json:"user_id"`
+	// Channels reminders are sent through, nil means the default channels of the server
+	// and an empty list turns reminders off
+	Channels []NotificationChannel `json:"channels"`
+	// Muted rules don't send reminders to the user
+	Muted []ReminderRule `json:"muted,omitempty"`
+	// WebhookURL receives reminders of the webhook channel
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// Wants reports whether the user wants reminders of the rule through the channel,
+// defaults are the channels used when the user hasn't chosen any
+func (p NotificationPreferences) Wants(rule ReminderRule, channel NotificationChannel, defaults []NotificationChannel) bool {
+	if slices.Contains(p.Muted, rule) {
+		return false
+	}
+	channels := p.Channels
+	if channels == nil {
+		channels = defaults
+	}
+	return slices.Contains(channels, channel)
+}
+
+func ValidateNotificationPreferences(preferences NotificationPreferences) error {
+	for _, channel := range preferences.Channels {
+		if !channel.IsValid() {
+			return fmt.Errorf("invalid channel in notification preferences: unknown channel %q", channel)
+		}
+	}
+	for _, rule := range preferences.Muted {
+		if !rule.IsValid() {
+			return fmt.Errorf("invalid muted rule in notification preferences: unknown rule %q", rule)
+		}
+	}
+	if preferences.WebhookURL != "" {
+		u, err := url.Parse(preferences.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("invalid webhook_url in notification preferences: absolute http or https url is expected")
+		}
+	}
+	if slices.Contains(preferences.Channels, ChannelWebhook) && preferences.WebhookURL == "" {
+		return errors.New("invalid webhook_url in notification preferences: the webhook channel needs an url")
+	}
+	return nil
+}
+
+// Reminder is a notification about a task sent to a user
+type Reminder struct {
+	Rule      ReminderRule
+	Task      Task
+	Recipient User
+	// Anchor is the moment the reminder is about, the due date or the start of the work
+	Anchor time.Time
+	At     time.Time
+}
+
+// ReminderKey identifies a sent reminder, the same reminder isn't sent through a channel twice
+type ReminderKey struct {
+	Rule      ReminderRule
+	ProjectID int
+	TaskID    int
+	// Anchor makes a reminder about a moved due date or a restarted work a new one
+	Anchor  time.Time
+	UserID  int
+	Channel NotificationChannel
+}
@@ -0,0 +1,32 @@
+package model
+
+type TaskPriority string
+
+const (
+	PriorityLow    TaskPriority = "low"
+	PriorityMedium TaskPriority = "medium"
+	PriorityHigh   TaskPriority = "high"
+	PriorityUrgent TaskPriority = "urgent"
+)
+
+// DefaultPriority is given to tasks created without a priority
+const DefaultPriority = PriorityMedium
+
+// Rank orders priorities from low to urgent, unknown priorities rank below low
+func (p TaskPriority) Rank() int {
+	switch p {
+	case PriorityLow:
+		return 1
+	case PriorityMedium:
+		return 2
+	case PriorityHigh:
+		return 3
+	case PriorityUrgent:
+		return 4
+	}
+	return 0
+}
+
+func (p TaskPriority) IsValid() bool {
+	return p.Rank() > 0
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// DefaultProjectId is the project used by the routes that aren't nested under /projects
+const DefaultProjectId = 1
+
+// NoQuota means that the amount of tasks in a project isn't limited
+const NoQuota = 0
+
+type Project struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	TaskQuota int       `json:"task_quota"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"fmt"
+	"ivanjabrony/test_lo/internal/recurrence"
+	"strings"
+	"time"
+)
+
+// Recurrence recreates a task on a schedule, only the latest occurrence of a series holds it
+type Recurrence struct {
+	// Rule is a cron expression or an RRULE, see package recurrence
+	Rule string `json:"rule"`
+	// TimeZone is the IANA name of the location the rule is evaluated in, UTC when empty
+	TimeZone string `json:"time_zone,omitempty"`
+	// Start is the due date of the first occurrence, it anchors intervals and counts of RRULEs
+	Start time.Time `json:"start"`
+}
+
+// Schedule parses the rule in its time zone
+func (r Recurrence) Schedule() (recurrence.Schedule, error) {
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil || strings.EqualFold(r.TimeZone, "local") {
+		return nil, fmt.Errorf("unknown time zone %q", r.TimeZone)
+	}
+	return recurrence.Parse(r.Rule, r.Start, loc)
+}
+
+// ValidateRecurrence rejects rules and time zones that can't be parsed, a nil recurrence is valid
+func ValidateRecurrence(r *Recurrence) error {
+	if r == nil {
+		return nil
+	}
+	if strings.TrimSpace(r.Rule) == "" {
+		return fmt.Errorf("invalid recurrence in task: empty rule is forbidden")
+	}
+	if _, err := r.Schedule(); err != nil {
+		return fmt.Errorf("invalid recurrence in task: %w", err)
+	}
+	return nil
+}
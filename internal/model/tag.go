@@ -0,0 +1,52 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxTagNameLength limits tag names, they are shown next to every tagged task
+const MaxTagNameLength = 64
+
+// Tag is a label of a project, tasks are attached to tags by id so renaming a tag
+// doesn't touch the tasks
+type Tag struct {
+	Id        int    `json:"id"`
+	ProjectID int    `json:"project_id"`
+	Name      string `json:"name"`
+	// TaskCount is an amount of tasks the tag is attached to, it's computed by the storage
+	TaskCount int       `json:"task_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TagMatch decides how a filter with several tags matches tasks
+type TagMatch string
+
+const (
+	// TagMatchAny keeps tasks having at least one of the tags
+	TagMatchAny TagMatch = "any"
+	// TagMatchAll keeps tasks having every tag
+	TagMatchAll TagMatch = "all"
+)
+
+// NormalizeTagName makes tag names case insensitive
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func ValidateTag(tag Tag) error {
+	if tag.Id < 0 {
+		return errors.New("invalid tagId in tag: negative values are forbidden")
+	}
+	if tag.Name == "" {
+		return errors.New("invalid name in tag: empty name is forbidden")
+	}
+	if len(tag.Name) > MaxTagNameLength {
+		return errors.New("invalid name in tag: name is too long")
+	}
+	if strings.ContainsAny(tag.Name, ", \t\n") {
+		return errors.New("invalid name in tag: commas and whitespace are forbidden")
+	}
+	return nil
+}
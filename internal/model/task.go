@@ -1,15 +1,115 @@
 package model
 
 import (
+	"strconv"
 	"time"
 )
 
 type TaskStatus string
 
 type Task struct {
-	Id          int        `json:"id"`
-	Status      TaskStatus `json:"status"`
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	CreatedAt   time.Time  `json:"created_at"`
+	Id int `json:"id"`
+	// PublicID is given to new tasks when public ids are generated, see storage.WithIDGenerator.
+	// Unlike Id it's unique across projects and can't be enumerated
+	PublicID    string       `json:"public_id,omitempty"`
+	ProjectID   int          `json:"project_id"`
+	Status      TaskStatus   `json:"status"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Priority    TaskPriority `json:"priority"`
+	AssigneeID  int          `json:"assignee_id,omitempty"`
+	ReporterID  int          `json:"reporter_id,omitempty"`
+	// TagIDs is changed only by attaching and detaching tags
+	TagIDs []int `json:"tag_ids,omitempty"`
+	// ParentID is the id of the task this one is a subtask of, nil for top level tasks
+	ParentID *int `json:"parent_id,omitempty"`
+	// BlockedBy holds ids of the tasks blocking this one, it's changed only by adding and removing blockers
+	BlockedBy []int `json:"blocked_by,omitempty"`
+	// PublicParentID and PublicBlockedBy are the public ids of the parent and the blockers, PublicBlockedBy
+	// in the order of BlockedBy. The storage keeps them, they are empty while the tasks have no public ids
+	PublicParentID  string    `json:"-"`
+	PublicBlockedBy []string  `json:"-"`
+	DueAt           time.Time `json:"due_at,omitzero"`
+	// Recurrence makes the scheduler create the next occurrence when this one is done or due
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	// StartedAt and CompletedAt are set by status transitions, see ApplyStatusTransition
+	StartedAt   time.Time `json:"started_at,omitzero"`
+	CompletedAt time.Time `json:"completed_at,omitzero"`
+}
+
+// IsOverdue reports whether the task has a due date before now and isn't done yet
+func (t Task) IsOverdue(now time.Time) bool {
+	return t.Status != Done && !t.DueAt.IsZero() && t.DueAt.Before(now)
+}
+
+// TaskIds are id, parent_id and blocked_by of a task as clients see them once tasks have public ids
+type TaskIds struct {
+	Id        string
+	ParentID  *string
+	BlockedBy []string
+}
+
+// PublicIds returns the public ids shown in place of the ids of the task, its parent and blockers,
+// it's false when none of them has a public id. Tasks created before public ids were given have none,
+// their ids are shown in decimal, so every id of the task has the same type.
+func (t Task) PublicIds() (TaskIds, bool) {
+	public := t.PublicID != "" || t.PublicParentID != "" || t.PublicBlockedBy != nil
+	ids := TaskIds{Id: publicId(t.Id, t.PublicID)}
+	if t.ParentID != nil {
+		parent := publicId(*t.ParentID, t.PublicParentID)
+		ids.ParentID = &parent
+	}
+	for i, blockerId := range t.BlockedBy {
+		var blocker string
+		if i < len(t.PublicBlockedBy) {
+			blocker = t.PublicBlockedBy[i]
+		}
+		ids.BlockedBy = append(ids.BlockedBy, publicId(blockerId, blocker))
+	}
+	return ids, public
+}
+
+func publicId(id int, publicId string) string {
+	if publicId == "" {
+		return strconv.Itoa(id)
+	}
+	return publicId
+}
+
+// ApplyStatusTransition updates StartedAt and CompletedAt of the task moved from the previous status.
+//
+// Starting the work sets StartedAt once, finishing sets CompletedAt (and StartedAt if the task
+// was never started), moving a done task back clears CompletedAt and reopening it as created
+// clears both.
+func ApplyStatusTransition(task *Task, previous TaskStatus, now time.Time) {
+	if task.Status == previous {
+		return
+	}
+	switch task.Status {
+	case Created:
+		task.StartedAt = time.Time{}
+		task.CompletedAt = time.Time{}
+	case InProgress:
+		if task.StartedAt.IsZero() {
+			task.StartedAt = now
+		}
+		task.CompletedAt = time.Time{}
+	case Done:
+		if task.StartedAt.IsZero() {
+			task.StartedAt = now
+		}
+		task.CompletedAt = now
+	}
+}
+
+// HasParent reports whether the task is a subtask
+func (t Task) HasParent() bool {
+	return t.ParentID != nil
+}
+
+// TaskNode is a task with its subtasks
+type TaskNode struct {
+	Task     Task
+	Subtasks []TaskNode
 }
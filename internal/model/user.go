@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// NoUser is a zero user id, it means that a task isn't assigned to anyone.
+// Real user ids start from 1.
+const NoUser = 0
+
+type User struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
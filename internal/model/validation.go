@@ -1,6 +1,11 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+)
 
 func ValidateTask(task Task) error {
 	if task.Id < 0 {
@@ -12,13 +17,86 @@ func ValidateTask(task Task) error {
 	if task.Status != Done && task.Status != InProgress && task.Status != Created {
 		return errors.New("invalid status in task: unknown type")
 	}
+	if task.AssigneeID < 0 {
+		return errors.New("invalid assignee in task: negative values are forbidden")
+	}
+	if task.ReporterID < 0 {
+		return errors.New("invalid reporter in task: negative values are forbidden")
+	}
+	if task.ParentID != nil && *task.ParentID < 0 {
+		return errors.New("invalid parent in task: negative values are forbidden")
+	}
+	if task.Priority != "" && !task.Priority.IsValid() {
+		return errors.New("invalid priority in task: unknown type")
+	}
+	if err := ValidateRecurrence(task.Recurrence); err != nil {
+		return err
+	}
+
+	return nil
+}
 
+// ValidateDueAt rejects due dates in the past unless allowPast is set, a zero due date means there is none
+func ValidateDueAt(dueAt, now time.Time, allowPast bool) error {
+	if !allowPast && !dueAt.IsZero() && dueAt.Before(now) {
+		return errors.New("invalid due date in task: due date in the past is forbidden")
+	}
 	return nil
 }
 
 func ValidateFilter(filter Filter) error {
-	if filter.Status != Done && filter.Status != InProgress && filter.Status != Created {
+	if filter.Status != "" && filter.Status != Done && filter.Status != InProgress && filter.Status != Created {
 		return errors.New("invalid status in filter: unknown type")
 	}
+	if filter.AssigneeID < 0 {
+		return errors.New("invalid assignee in filter: negative values are forbidden")
+	}
+	if filter.Priority != "" && !filter.Priority.IsValid() {
+		return errors.New("invalid priority in filter: unknown type")
+	}
+	if !filter.DueBefore.IsZero() && !filter.DueAfter.IsZero() && filter.DueBefore.Before(filter.DueAfter) {
+		return errors.New("invalid due date range in filter: due_before is earlier than due_after")
+	}
+	if !filter.CreatedBefore.IsZero() && !filter.CreatedAfter.IsZero() && filter.CreatedBefore.Before(filter.CreatedAfter) {
+		return errors.New("invalid creation time range in filter: created_before is earlier than created_after")
+	}
+	if filter.TagMatch != "" && filter.TagMatch != TagMatchAny && filter.TagMatch != TagMatchAll {
+		return errors.New("invalid tag match in filter: unknown type")
+	}
+	switch filter.SortBy {
+	case "", SortById, SortByDueAt, SortByPriority, SortByCreatedAt, SortByStartedAt, SortByCompletedAt:
+	default:
+		return errors.New("invalid sort field in filter: unknown field")
+	}
+	if filter.Paged() {
+		return ValidatePage(filter.Page)
+	}
+	return nil
+}
+
+func ValidateUser(user User) error {
+	if user.Id < 0 {
+		return errors.New("invalid userId in user: negative values are forbidden")
+	}
+	if strings.TrimSpace(user.Name) == "" {
+		return errors.New("invalid name in user: empty name is forbidden")
+	}
+	address, err := mail.ParseAddress(user.Email)
+	if err != nil || address.Address != user.Email {
+		return errors.New("invalid email in user: malformed address")
+	}
+	return nil
+}
+
+func ValidateProject(project Project) error {
+	if project.Id < 0 {
+		return errors.New("invalid projectId in project: negative values are forbidden")
+	}
+	if strings.TrimSpace(project.Name) == "" {
+		return errors.New("invalid name in project: empty name is forbidden")
+	}
+	if project.TaskQuota < 0 {
+		return errors.New("invalid task quota in project: negative values are forbidden")
+	}
 	return nil
 }
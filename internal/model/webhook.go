@@ -0,0 +1,132 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// MinWebhookSecretLength is the shortest secret deliveries may be signed with
+const MinWebhookSecretLength = 16
+
+// WebhookEvent is a kind of task event webhooks subscribe to
+type WebhookEvent string
+
+const (
+	WebhookTaskCreated WebhookEvent = "task.created"
+	WebhookTaskUpdated WebhookEvent = "task.updated"
+	// WebhookTaskCompleted is sent when a task is created as done or moves to done
+	WebhookTaskCompleted WebhookEvent = "task.completed"
+	WebhookTaskDeleted   WebhookEvent = "task.deleted"
+)
+
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookTaskCreated, WebhookTaskUpdated, WebhookTaskCompleted, WebhookTaskDeleted:
+		return true
+	}
+	return false
+}
+
+// WebhookEvents returns the events the change triggers, completion of a task is sent
+// besides its creation or update
+func WebhookEvents(change TaskChange) []WebhookEvent {
+	switch change.Type {
+	case TaskChangeCreated:
+		if change.Task.Status == Done {
+			return []WebhookEvent{WebhookTaskCreated, WebhookTaskCompleted}
+		}
+		return []WebhookEvent{WebhookTaskCreated}
+	case TaskChangeUpdated:
+		if change.Task.Status == Done && (change.Previous == nil || change.Previous.Status != Done) {
+			return []WebhookEvent{WebhookTaskUpdated, WebhookTaskCompleted}
+		}
+		return []WebhookEvent{WebhookTaskUpdated}
+	case TaskChangeDeleted:
+		return []WebhookEvent{WebhookTaskDeleted}
+	}
+	return nil
+}
+
+// Webhook is a subscription of an outside service to task events of a project
+type Webhook struct {
+	Id        int
+	ProjectID int
+	// URL receives deliveries as POST requests
+	URL string
+	// Secret signs deliveries, see webhook.Sign
+	Secret string
+	// Events the webhook is subscribed to, empty means every event
+	Events []WebhookEvent
+	Active bool
+	// CreatedAt is set by the storage
+	CreatedAt time.Time
+}
+
+// Subscribed reports whether the webhook wants the event
+func (w Webhook) Subscribed(event WebhookEvent) bool {
+	return w.Active && (len(w.Events) == 0 || slices.Contains(w.Events, event))
+}
+
+func ValidateWebhook(webhook Webhook) error {
+	if webhook.Id < 0 {
+		return errors.New("invalid id in webhook: negative values are forbidden")
+	}
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid url in webhook: absolute http or https url is expected")
+	}
+	if len(webhook.Secret) < MinWebhookSecretLength {
+		return fmt.Errorf("invalid secret in webhook: at least %v characters are expected", MinWebhookSecretLength)
+	}
+	for _, event := range webhook.Events {
+		if !event.IsValid() {
+			return fmt.Errorf("invalid event in webhook: unknown event %q", event)
+		}
+	}
+	return nil
+}
+
+// DeliveryStatus is the outcome of an attempt to deliver an event to a webhook
+type DeliveryStatus string
+
+const (
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryFailed attempts are retried
+	DeliveryFailed DeliveryStatus = "failed"
+	// DeliveryDead is the last failed attempt, the delivery goes to the dead letters
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is an attempt to deliver an event, the delivery log of a webhook keeps them
+type WebhookDelivery struct {
+	// Id is assigned by the storage
+	Id int `json:"id"`
+	// DeliveryID is the same for every attempt of a delivery, receivers use it to drop duplicates
+	DeliveryID   string         `json:"delivery_id"`
+	WebhookID    int            `json:"webhook_id"`
+	Event        WebhookEvent   `json:"event"`
+	ChangeID     int            `json:"change_id"`
+	Attempt      int            `json:"attempt"`
+	Status       DeliveryStatus `json:"status"`
+	ResponseCode int            `json:"response_code,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	At           time.Time      `json:"at"`
+	// NextAttemptAt is set on failed attempts
+	NextAttemptAt time.Time `json:"next_attempt_at,omitzero"`
+}
+
+// DeadLetter is a delivery that failed every attempt, it can be delivered again by hand
+type DeadLetter struct {
+	DeliveryID string          `json:"delivery_id"`
+	WebhookID  int             `json:"webhook_id"`
+	Event      WebhookEvent    `json:"event"`
+	ChangeID   int             `json:"change_id"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	LastError  string          `json:"last_error"`
+	At         time.Time       `json:"at"`
+}
@@ -0,0 +1,412 @@
+package openapi
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentJSON is the content type of json bodies
+const ContentJSON = "application/json"
+
+// Endpoint describes a route of the api
+type Endpoint struct {
+	Method string
+	// Path is the path of the net/http pattern of the route, its wildcards are path parameters
+	Path        string
+	OperationID string
+	Summary     string
+	Description string
+	Tag         string
+	// Params are the query and header parameters, and the path parameters that aren't integers
+	Params []Param
+	// Request is a value of the type of the json body, nil for routes without a body. A *Schema is used as is.
+	Request any
+	// RequestTypes are the content types of the body when it isn't json, the body is described as a string
+	RequestTypes []string
+	// Status is the status of a successful response, 200 when it's 0
+	Status int
+	// Response is a value of the type of the successful json response, nil for an empty body. A *Schema is used as is.
+	Response any
+	// ResponseTypes are the content types of the successful response besides json, the body is described as a string
+	ResponseTypes []string
+	// Public endpoints don't need authentication
+	Public bool
+}
+
+// Param is a parameter of an endpoint
+type Param struct {
+	Name string
+	// In is "query", "header" or "path"
+	In          string
+	Description string
+	Required    bool
+	Schema      *Schema
+}
+
+// Query describes an optional query parameter
+func Query(name string, schema *Schema, description string) Param {
+	return Param{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func String() *Schema   { return &Schema{Type: []string{"string"}} }
+func Integer() *Schema  { return &Schema{Type: []string{"integer"}} }
+func Boolean() *Schema  { return &Schema{Type: []string{"boolean"}} }
+func DateTime() *Schema { return &Schema{Type: []string{"string"}, Format: "date-time"} }
+
+// Enum is a string schema limited to the values
+func Enum[T ~string](values ...T) *Schema {
+	s := String()
+	for _, value := range values {
+		s.Enum = append(s.Enum, string(value))
+	}
+	return s
+}
+
+// Option configures Generate
+type Option func(*generator)
+
+// WithEnum limits the values of the named string type of the values to them
+func WithEnum[T ~string](values ...T) Option {
+	return WithSchema[T](Enum(values...))
+}
+
+// WithSchema describes values of the type with the schema instead of deriving it, for types encoding themselves
+func WithSchema[T any](schema *Schema) Option {
+	return func(g *generator) {
+		g.schemas[reflect.TypeFor[T]()] = schema
+	}
+}
+
+// WithProperty describes the json property of the struct type with the schema instead of deriving it from
+// its field, for properties the MarshalJSON of the type encodes differently
+func WithProperty[T any](name string, schema *Schema) Option {
+	return func(g *generator) {
+		g.properties[propertyKey{reflect.TypeFor[T](), name}] = schema
+	}
+}
+
+// WithPathParam describes the path parameter of every endpoint that doesn't describe it in Params, they are integers by default
+func WithPathParam(name string, schema *Schema) Option {
+	return func(g *generator) {
+		g.pathParams[name] = schema
+	}
+}
+
+// WithErrorResponse describes the body of every response besides the successful one
+func WithErrorResponse(value any) Option {
+	return func(g *generator) {
+		g.errorResponse = value
+	}
+}
+
+// WithSecurity adds a security scheme, endpoints that aren't public need one of the schemes
+func WithSecurity(name string, scheme *SecurityScheme) Option {
+	return func(g *generator) {
+		g.doc.Components.SecuritySchemes[name] = scheme
+		g.doc.Security = append(g.doc.Security, SecurityRequirement{name: {}})
+	}
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	wildcard       = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(\.\.\.)?\}`)
+)
+
+type generator struct {
+	doc           *Document
+	schemas       map[reflect.Type]*Schema
+	properties    map[propertyKey]*Schema
+	pathParams    map[string]*Schema
+	errorResponse any
+	// types are the types of the schemas of the components, names of the components must be unique
+	types map[string]reflect.Type
+	// request is set while request bodies are described, requests holds the types described then
+	request  bool
+	requests map[reflect.Type]bool
+	// fields map names of go fields to the json names and the type they were first seen in
+	fields map[string]jsonField
+	errors []error
+}
+
+type propertyKey struct {
+	owner reflect.Type
+	name  string
+}
+
+type jsonField struct {
+	name  string
+	owner reflect.Type
+}
+
+// Generate builds the document of the endpoints, schemas of request and response bodies are derived from
+// the json encoding of the types of the values given in Request and Response. Fields without omitempty or
+// omitzero are required in responses, in requests every field is optional, as encoding/json treats them.
+//
+// A go field has to have the same json name in every type, a field named differently in one of them
+// is most likely a mistake in its tag and makes Generate fail.
+func Generate(info Info, endpoints []Endpoint, opts ...Option) (*Document, error) {
+	g := &generator{
+		doc: &Document{
+			OpenAPI:    Version,
+			Info:       info,
+			Paths:      make(map[string]PathItem),
+			Components: Components{Schemas: make(map[string]*Schema), SecuritySchemes: make(map[string]*SecurityScheme)},
+		},
+		schemas:    make(map[reflect.Type]*Schema),
+		properties: make(map[propertyKey]*Schema),
+		pathParams: make(map[string]*Schema),
+		types:      make(map[string]reflect.Type),
+		requests:   make(map[reflect.Type]bool),
+		fields:     make(map[string]jsonField),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	operationIds := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		method := strings.ToLower(endpoint.Method)
+		if g.doc.Paths[endpoint.Path] == nil {
+			g.doc.Paths[endpoint.Path] = make(PathItem)
+		}
+		if g.doc.Paths[endpoint.Path][method] != nil {
+			g.errorf("%v %v is described twice", endpoint.Method, endpoint.Path)
+			continue
+		}
+		if endpoint.OperationID == "" || operationIds[endpoint.OperationID] {
+			g.errorf("%v %v needs a unique operation id, got %q", endpoint.Method, endpoint.Path, endpoint.OperationID)
+		}
+		operationIds[endpoint.OperationID] = true
+		g.doc.Paths[endpoint.Path][method] = g.operation(endpoint)
+	}
+
+	if err := errors.Join(g.errors...); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	return g.doc, nil
+}
+
+func (g *generator) errorf(format string, args ...any) {
+	g.errors = append(g.errors, fmt.Errorf(format, args...))
+}
+
+func (g *generator) operation(endpoint Endpoint) *Operation {
+	op := &Operation{
+		OperationID: endpoint.OperationID,
+		Summary:     endpoint.Summary,
+		Description: endpoint.Description,
+		Responses:   make(map[string]*Response),
+	}
+	if endpoint.Tag != "" {
+		op.Tags = []string{endpoint.Tag}
+	}
+	if endpoint.Public {
+		op.Security = &[]SecurityRequirement{}
+	}
+
+	for _, match := range wildcard.FindAllStringSubmatch(endpoint.Path, -1) {
+		param := &Parameter{Name: match[1], In: "path", Required: true, Schema: cmp.Or(g.pathParams[match[1]], Integer())}
+		for _, p := range endpoint.Params {
+			if p.In == "path" && p.Name == param.Name {
+				param.Description, param.Schema = p.Description, p.Schema
+			}
+		}
+		op.Parameters = append(op.Parameters, param)
+	}
+	for _, p := range endpoint.Params {
+		switch p.In {
+		case "query", "header":
+			op.Parameters = append(op.Parameters, &Parameter{Name: p.Name, In: p.In, Description: p.Description, Required: p.Required, Schema: p.Schema})
+		case "path":
+			if !slices.ContainsFunc(op.Parameters, func(param *Parameter) bool { return param.Name == p.Name }) {
+				g.errorf("%v %v has no path parameter %v", endpoint.Method, endpoint.Path, p.Name)
+			}
+		default:
+			g.errorf("%v %v: unknown location %q of parameter %v", endpoint.Method, endpoint.Path, p.In, p.Name)
+		}
+	}
+
+	if endpoint.Request != nil || len(endpoint.RequestTypes) > 0 {
+		g.request = true
+		op.RequestBody = &RequestBody{Required: true, Content: g.content(endpoint.Request, endpoint.RequestTypes)}
+		g.request = false
+	}
+
+	status := endpoint.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = &Response{
+		Description: http.StatusText(status),
+		Content:     g.content(endpoint.Response, endpoint.ResponseTypes),
+	}
+	if g.errorResponse != nil {
+		op.Responses["default"] = &Response{Description: "Error", Content: g.content(g.errorResponse, nil)}
+	}
+	return op
+}
+
+// content describes a json body of the type of value and bodies of other types as strings
+func (g *generator) content(value any, types []string) map[string]*MediaType {
+	if value == nil && len(types) == 0 {
+		return nil
+	}
+	content := make(map[string]*MediaType)
+	if value != nil {
+		content[ContentJSON] = &MediaType{Schema: g.schemaOfValue(value)}
+	}
+	for _, contentType := range types {
+		content[contentType] = &MediaType{Schema: String()}
+	}
+	return content
+}
+
+func (g *generator) schemaOfValue(value any) *Schema {
+	if s, ok := value.(*Schema); ok {
+		return s
+	}
+	return g.schemaOf(reflect.TypeOf(value))
+}
+
+// schemaOf describes the json encoding of values of the type, structs become schemas of the components
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	if s, ok := g.schemas[t]; ok {
+		return s
+	}
+	switch t {
+	case timeType:
+		return DateTime()
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schemaOf(t.Elem()))
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: []string{"number"}}
+	case reflect.String:
+		return String()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: []string{"string"}, Format: "byte"}
+		}
+		return &Schema{Type: []string{"array"}, Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: []string{"object"}, AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	case reflect.Interface:
+		return &Schema{}
+	default:
+		g.errorf("type %v can't be described", t)
+		return &Schema{}
+	}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	name := t.Name()
+	if name == "" {
+		g.errorf("anonymous struct %v can't be described", t)
+		return &Schema{}
+	}
+	if known, ok := g.types[name]; ok {
+		if known != t {
+			g.errorf("types %v and %v have the same name", known, t)
+		} else if g.requests[t] != g.request {
+			g.errorf("type %v is used in both requests and responses", t)
+		}
+		return refTo(name)
+	}
+	g.types[name] = t
+	g.requests[t] = g.request
+
+	s := &Schema{Type: []string{"object"}, Properties: make(map[string]*Schema), Closed: true}
+	// the schema is registered first, so types referring to themselves end up with a reference
+	g.doc.Components.Schemas[name] = s
+	g.addFields(s, t, t)
+	return refTo(name)
+}
+
+// addFields adds the fields of t to the schema of owner, fields of embedded structs are promoted like encoding/json does
+func (g *generator) addFields(s *Schema, t, owner reflect.Type) {
+	for _, field := range reflect.VisibleFields(t) {
+		if len(field.Index) > 1 || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.addFields(s, field.Type, owner)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		g.checkName(owner, field.Name, name)
+
+		omitted := slices.ContainsFunc(strings.Split(opts, ","), func(opt string) bool { return opt == "omitempty" || opt == "omitzero" })
+		property, ok := g.properties[propertyKey{t, name}]
+		if !ok {
+			property = g.schemaOf(field.Type)
+		}
+		// nil slices and maps are encoded as null unless they are omitted
+		if !omitted && (field.Type.Kind() == reflect.Slice || field.Type.Kind() == reflect.Map) && property.Format != "byte" {
+			property = nullable(property)
+		}
+		s.Properties[name] = property
+		// encoding/json leaves missing fields of requests empty, so only fields of responses are required
+		if !omitted && !g.request {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// checkName reports go fields named differently in json than in the types seen before
+func (g *generator) checkName(owner reflect.Type, field, name string) {
+	seen, ok := g.fields[field]
+	if !ok {
+		g.fields[field] = jsonField{name: name, owner: owner}
+		return
+	}
+	if seen.name != name {
+		g.errorf("field %v of %v is %q in json, but it's %q in %v", field, owner, name, seen.name, seen.owner)
+	}
+}
+
+// nullable allows null besides the values of the schema
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: []string{"null"}}}}
+	}
+	if len(s.Type) == 0 || slices.Contains(s.Type, "null") {
+		return s
+	}
+	ans := *s
+	ans.Type = append(slices.Clone(s.Type), "null")
+	if ans.Enum != nil {
+		ans.Enum = append(slices.Clone(ans.Enum), nil)
+	}
+	return &ans
+}
@@ -0,0 +1,192 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type color string
+
+type item struct {
+	Id    int       `json:"id"`
+	Name  string    `json:"name"`
+	Color color     `json:"color,omitempty"`
+	Tags  []string  `json:"tags"`
+	Due   time.Time `json:"due,omitzero"`
+	Owner *owner    `json:"owner,omitempty"`
+	// Children refer to the type itself
+	Children []item `json:"children,omitempty"`
+	internal int
+}
+
+type owner struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type itemRequest struct {
+	Name  string `json:"name"`
+	Color *color `json:"color,omitempty"`
+}
+
+type itemPage struct {
+	item
+	Total int `json:"total"`
+}
+
+// renamedItem names Name differently than item does
+type renamedItem struct {
+	Name string `json:"title"`
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func TestGenerate(t *testing.T) {
+	doc, err := Generate(Info{Title: "test", Version: "1"}, []Endpoint{
+		{Method: "GET", Path: "/items", OperationID: "listItems", Params: []Param{Query("color", Enum[color]("red", "blue"), "")}, Response: []item{}},
+		{Method: "POST", Path: "/items", OperationID: "createItem", Request: itemRequest{}, Response: 0},
+		{Method: "GET", Path: "/items/{item_id}", OperationID: "getItem", Response: itemPage{}},
+		{Method: "DELETE", Path: "/items/{item_id}", OperationID: "deleteItem", Status: 204},
+		{Method: "GET", Path: "/health", OperationID: "health", ResponseTypes: []string{"text/plain"}, Public: true},
+	},
+		WithEnum[color]("red", "blue"),
+		WithErrorResponse(errorBody{}),
+		WithSecurity("apiKey", &SecurityScheme{Type: "apiKey", Name: "X-API-Key", In: "header"}))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := []string{
+		`"openapi":"3.1.0"`,
+		`"item":{"properties":{"children":{"items":{"$ref":"#/components/schemas/item"},"type":"array"},"color":{"enum":["red","blue"],"type":"string"},"due":{"format":"date-time","type":"string"},"id":{"type":"integer"},"name":{"type":"string"},"owner":{"anyOf":[{"$ref":"#/components/schemas/owner"},{"type":"null"}]},"tags":{"items":{"type":"string"},"type":["array","null"]}},"required":["id","name","tags"],"type":"object","additionalProperties":false}`,
+		`"itemRequest":{"properties":{"color":{"enum":["red","blue",null],"type":["string","null"]},"name":{"type":"string"}},"type":"object","additionalProperties":false}`,
+		`"itemPage":{"properties":{"children"`,
+		`"required":["id","name","tags","total"]`,
+		`"parameters":[{"name":"item_id","in":"path","required":true,"schema":{"type":"integer"}}]`,
+		`"responses":{"204":{"description":"No Content"},"default":{"description":"Error","content":{"application/json":{"schema":{"$ref":"#/components/schemas/errorBody"}}}}}`,
+		`"content":{"text/plain":{"schema":{"type":"string"}}}`,
+		`"security":[]`,
+		`"security":[{"apiKey":[]}]`,
+	}
+	for _, part := range expected {
+		if !strings.Contains(string(data), part) {
+			t.Errorf("Expected the document to contain %s, got\n%s", part, data)
+		}
+	}
+}
+
+// itemRef is encoded as the id or the name of an item by its MarshalJSON
+type itemRef struct {
+	Id   int
+	Name string
+}
+
+type link struct {
+	From itemRef `json:"from"`
+	To   int     `json:"to"`
+}
+
+func TestGenerateOverrides(t *testing.T) {
+	idOrName := &Schema{Type: []string{"integer", "string"}}
+	doc, err := Generate(Info{Title: "test", Version: "1"}, []Endpoint{
+		{Method: "POST", Path: "/links", OperationID: "createLink", Request: link{}},
+		{Method: "GET", Path: "/items/{item_id}/owner", OperationID: "getOwner", Response: owner{}},
+	},
+		WithSchema[itemRef](idOrName),
+		WithProperty[owner]("id", idOrName),
+		WithPathParam("item_id", idOrName))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := []string{
+		`"link":{"properties":{"from":{"type":["integer","string"]},"to":{"type":"integer"}}`,
+		`"owner":{"properties":{"id":{"type":["integer","string"]},"name":{"type":"string"}}`,
+		`"parameters":[{"name":"item_id","in":"path","required":true,"schema":{"type":["integer","string"]}}]`,
+	}
+	for _, part := range expected {
+		if !strings.Contains(string(data), part) {
+			t.Errorf("Expected the document to contain %s, got\n%s", part, data)
+		}
+	}
+	if strings.Contains(string(data), `"itemRef"`) {
+		t.Errorf("Expected no schema of itemRef, got\n%s", data)
+	}
+
+	op, values, _ := doc.Find("GET", "/items/seven/owner")
+	if err := doc.ValidateRequest(op, values, nil, "", nil); err != nil {
+		t.Errorf("Expected a string path parameter to be valid, got %v", err)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []Endpoint
+		expected  string
+	}{
+		{
+			name: "json name drift",
+			endpoints: []Endpoint{
+				{Method: "GET", Path: "/items", OperationID: "listItems", Response: item{}},
+				{Method: "GET", Path: "/renamed", OperationID: "listRenamed", Response: renamedItem{}},
+			},
+			expected: `field Name of openapi.renamedItem is "title" in json, but it's "name" in openapi.item`,
+		},
+		{
+			name: "duplicate route",
+			endpoints: []Endpoint{
+				{Method: "GET", Path: "/items", OperationID: "a"},
+				{Method: "GET", Path: "/items", OperationID: "b"},
+			},
+			expected: "GET /items is described twice",
+		},
+		{
+			name: "duplicate operation id",
+			endpoints: []Endpoint{
+				{Method: "GET", Path: "/items", OperationID: "a"},
+				{Method: "POST", Path: "/items", OperationID: "a"},
+			},
+			expected: `POST /items needs a unique operation id, got "a"`,
+		},
+		{
+			name:      "unknown path parameter",
+			endpoints: []Endpoint{{Method: "GET", Path: "/items", OperationID: "a", Params: []Param{{Name: "item_id", In: "path"}}}},
+			expected:  "GET /items has no path parameter item_id",
+		},
+		{
+			name: "type in requests and responses",
+			endpoints: []Endpoint{
+				{Method: "POST", Path: "/items", OperationID: "a", Request: owner{}},
+				{Method: "GET", Path: "/items", OperationID: "b", Response: owner{}},
+			},
+			expected: "type openapi.owner is used in both requests and responses",
+		},
+		{
+			name:      "anonymous struct",
+			endpoints: []Endpoint{{Method: "GET", Path: "/items", OperationID: "a", Response: struct{ Id int }{}}},
+			expected:  "can't be described",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate(Info{Title: "test", Version: "1"}, tt.endpoints)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Find returns the operation serving the request path and the values of its path parameters. Like
+// net/http, a literal segment takes precedence over a wildcard, so /tasks/export isn't /tasks/{task_id}.
+func (d *Document) Find(method, path string) (*Operation, map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var best *Operation
+	var bestValues map[string]string
+	var bestWildcards []bool
+
+	for template, item := range d.Paths {
+		op := item[strings.ToLower(method)]
+		if op == nil {
+			continue
+		}
+		values, wildcards, ok := matchPath(template, segments)
+		if !ok {
+			continue
+		}
+		if best == nil || moreSpecific(wildcards, bestWildcards) {
+			best, bestValues, bestWildcards = op, values, wildcards
+		}
+	}
+	return best, bestValues, best != nil
+}
+
+func matchPath(template string, segments []string) (map[string]string, []bool, bool) {
+	parts := strings.Split(strings.Trim(template, "/"), "/")
+	values := make(map[string]string)
+	wildcards := make([]bool, len(parts))
+	for i, part := range parts {
+		if name, ok := strings.CutPrefix(part, "{"); ok {
+			name = strings.TrimSuffix(name, "}")
+			if rest, ok := strings.CutSuffix(name, "..."); ok {
+				if i >= len(segments) {
+					return nil, nil, false
+				}
+				values[rest] = strings.Join(segments[i:], "/")
+				wildcards[i] = true
+				return values, wildcards, true
+			}
+			if i >= len(segments) || segments[i] == "" {
+				return nil, nil, false
+			}
+			values[name] = segments[i]
+			wildcards[i] = true
+			continue
+		}
+		if i >= len(segments) || segments[i] != part {
+			return nil, nil, false
+		}
+	}
+	return values, wildcards, len(parts) == len(segments)
+}
+
+// moreSpecific reports whether the first differing segment is a literal in a and a wildcard in b
+func moreSpecific(a, b []bool) bool {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return !a[i]
+		}
+	}
+	return len(a) > len(b)
+}
+
+// ValidateRequest checks the parameters and the json body of a request to the operation
+func (d *Document) ValidateRequest(op *Operation, pathValues map[string]string, query url.Values, contentType string, body []byte) error {
+	var errs []error
+	for _, param := range op.Parameters {
+		var value string
+		var present bool
+		switch param.In {
+		case "path":
+			value, present = pathValues[param.Name]
+		case "query":
+			present = query.Has(param.Name)
+			value = query.Get(param.Name)
+		}
+		if !present {
+			if param.Required {
+				errs = append(errs, fmt.Errorf("%v parameter %v is required", param.In, param.Name))
+			}
+			continue
+		}
+		if err := d.validateParam(param.Schema, value); err != nil {
+			errs = append(errs, fmt.Errorf("%v parameter %v: %w", param.In, param.Name, err))
+		}
+	}
+
+	if op.RequestBody != nil {
+		if len(body) == 0 && op.RequestBody.Required {
+			errs = append(errs, errors.New("request body is required"))
+		} else if len(body) > 0 {
+			if err := d.validateBody(op.RequestBody.Content, contentType, body); err != nil {
+				errs = append(errs, fmt.Errorf("request body: %w", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateResponse checks the status and the json body of a response of the operation
+func (d *Document) ValidateResponse(op *Operation, status int, header http.Header, body []byte) error {
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if response, ok = op.Responses["default"]; !ok {
+			return fmt.Errorf("status %d isn't documented", status)
+		}
+	}
+	if len(response.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d is documented without a body", status)
+		}
+		return nil
+	}
+	if err := d.validateBody(response.Content, header.Get("Content-Type"), body); err != nil {
+		return fmt.Errorf("response body of status %d: %w", status, err)
+	}
+	return nil
+}
+
+// validateBody checks json bodies against the schema of their content type, bodies of other types are only
+// checked to be of a documented type
+func (d *Document) validateBody(content map[string]*MediaType, contentType string, body []byte) error {
+	mediaType := ContentJSON
+	if contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return fmt.Errorf("invalid content type %q", contentType)
+		}
+		mediaType = parsed
+	}
+	media, ok := content[mediaType]
+	if !ok {
+		return fmt.Errorf("content type %v isn't documented", mediaType)
+	}
+	if mediaType != ContentJSON {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	return d.Validate(media.Schema, value)
+}
+
+// validateParam checks a parameter, integers and booleans are parsed from the text of the parameter,
+// parameters that may be strings too are kept as strings when they aren't integers
+func (d *Document) validateParam(s *Schema, value string) error {
+	s = d.resolve(s)
+	if s == nil {
+		return nil
+	}
+	var parsed any = value
+	switch {
+	case slices.Contains(s.Type, "integer"):
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			parsed = json.Number(value)
+		} else if !slices.Contains(s.Type, "string") {
+			return fmt.Errorf("%q isn't an integer", value)
+		}
+	case slices.Contains(s.Type, "boolean"):
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't a boolean", value)
+		}
+		parsed = b
+	}
+	return d.Validate(s, parsed)
+}
+
+// Validate checks a value decoded from json with numbers as json.Number against the schema,
+// every mismatch is reported with its path in the value
+func (d *Document) Validate(s *Schema, value any) error {
+	var errs []error
+	d.validate(s, value, "$", &errs)
+	return errors.Join(errs...)
+}
+
+func (d *Document) validate(s *Schema, value any, path string, errs *[]error) {
+	if s != nil && s.Ref != "" {
+		resolved := d.resolve(s)
+		if resolved == nil {
+			*errs = append(*errs, fmt.Errorf("%v: unknown schema %v", path, s.Ref))
+			return
+		}
+		s = resolved
+	}
+	if s == nil {
+		return
+	}
+
+	if len(s.AnyOf) > 0 {
+		for _, option := range s.AnyOf {
+			var optionErrs []error
+			if d.validate(option, value, path, &optionErrs); len(optionErrs) == 0 {
+				return
+			}
+		}
+		*errs = append(*errs, fmt.Errorf("%v: %v matches none of the allowed schemas", path, describe(value)))
+		return
+	}
+
+	if len(s.Type) > 0 && !matchesType(s.Type, value) {
+		*errs = append(*errs, fmt.Errorf("%v: expected %v, got %v", path, strings.Join(s.Type, " or "), describe(value)))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed any) bool { return allowed == value }) {
+		*errs = append(*errs, fmt.Errorf("%v: %v isn't one of %v", path, describe(value), s.Enum))
+	}
+	if str, ok := value.(string); ok && s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+			*errs = append(*errs, fmt.Errorf("%v: %q isn't an RFC 3339 date-time", path, str))
+		}
+	}
+
+	switch value := value.(type) {
+	case []any:
+		for i, item := range value {
+			d.validate(s.Items, item, fmt.Sprintf("%v[%d]", path, i), errs)
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				*errs = append(*errs, fmt.Errorf("%v: property %v is required", path, name))
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			switch {
+			case ok:
+				d.validate(property, value[name], path+"."+name, errs)
+			case s.Closed:
+				*errs = append(*errs, fmt.Errorf("%v: property %v isn't documented", path, name))
+			case s.AdditionalProperties != nil:
+				d.validate(s.AdditionalProperties, value[name], path+"."+name, errs)
+			}
+		}
+	}
+}
+
+func matchesType(types []string, value any) bool {
+	for _, t := range types {
+		switch value := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if _, err := value.Int64(); err == nil && t == "integer" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func describe(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(value)
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,164 @@
+package openapi
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestDocument(t *testing.T) *Document {
+	t.Helper()
+	doc, err := Generate(Info{Title: "test", Version: "1"}, []Endpoint{
+		{Method: "GET", Path: "/items", OperationID: "listItems", Params: []Param{Query("limit", Integer(), "")}, Response: []item{}},
+		{Method: "POST", Path: "/items", OperationID: "createItem", Request: itemRequest{}, Response: 0},
+		{Method: "GET", Path: "/items/{item_id}", OperationID: "getItem", Response: item{}},
+		{Method: "GET", Path: "/items/export", OperationID: "exportItems", ResponseTypes: []string{"text/csv"}},
+		{Method: "DELETE", Path: "/items/{item_id}", OperationID: "deleteItem", Status: http.StatusNoContent},
+		{Method: "GET", Path: "/files/{path...}", OperationID: "getFile", Params: []Param{{Name: "path", In: "path", Schema: String()}}},
+	}, WithEnum[color]("red", "blue"), WithErrorResponse(errorBody{}))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	return doc
+}
+
+func TestFind(t *testing.T) {
+	doc := newTestDocument(t)
+	tests := []struct {
+		method      string
+		path        string
+		expectedOp  string
+		expectedVal map[string]string
+	}{
+		{method: "GET", path: "/items", expectedOp: "listItems"},
+		{method: "GET", path: "/items/7", expectedOp: "getItem", expectedVal: map[string]string{"item_id": "7"}},
+		{method: "GET", path: "/items/export", expectedOp: "exportItems"},
+		{method: "DELETE", path: "/items/7/", expectedOp: "deleteItem", expectedVal: map[string]string{"item_id": "7"}},
+		{method: "GET", path: "/files/a/b.txt", expectedOp: "getFile", expectedVal: map[string]string{"path": "a/b.txt"}},
+		{method: "PUT", path: "/items/7"},
+		{method: "GET", path: "/items/7/children"},
+		{method: "GET", path: "/unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			op, values, ok := doc.Find(tt.method, tt.path)
+			if ok != (tt.expectedOp != "") {
+				t.Fatalf("Expected found %v, got %v", tt.expectedOp != "", ok)
+			}
+			if !ok {
+				return
+			}
+			if op.OperationID != tt.expectedOp {
+				t.Errorf("Expected %v, got %v", tt.expectedOp, op.OperationID)
+			}
+			for name, value := range tt.expectedVal {
+				if values[name] != value {
+					t.Errorf("Expected %v to be %q, got %q", name, value, values[name])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	doc := newTestDocument(t)
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		query    string
+		body     string
+		expected string
+	}{
+		{name: "valid query", method: "GET", path: "/items", query: "limit=5"},
+		{name: "invalid query", method: "GET", path: "/items", query: "limit=five", expected: `query parameter limit: "five" isn't an integer`},
+		{name: "invalid path", method: "GET", path: "/items/seven", expected: `path parameter item_id: "seven" isn't an integer`},
+		{name: "valid body", method: "POST", path: "/items", body: `{"name": "a", "color": "red"}`},
+		{name: "fields of requests are optional", method: "POST", path: "/items", body: `{}`},
+		{name: "null pointer", method: "POST", path: "/items", body: `{"color": null}`},
+		{name: "unknown enum value", method: "POST", path: "/items", body: `{"color": "green"}`, expected: `$.color: "green" isn't one of [red blue <nil>]`},
+		{name: "unknown property", method: "POST", path: "/items", body: `{"name": "a", "amount": 1}`, expected: "$: property amount isn't documented"},
+		{name: "missing body", method: "POST", path: "/items", expected: "request body is required"},
+		{name: "malformed body", method: "POST", path: "/items", body: `{"name": `, expected: "invalid json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, values, ok := doc.Find(tt.method, tt.path)
+			if !ok {
+				t.Fatalf("%v %v isn't found", tt.method, tt.path)
+			}
+			query, _ := url.ParseQuery(tt.query)
+			err := doc.ValidateRequest(op, values, query, "application/json", []byte(tt.body))
+			if tt.expected == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := newTestDocument(t)
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		body        string
+		expected    string
+	}{
+		{name: "valid item", path: "/items/1", status: 200, body: `{"id": 1, "name": "a", "tags": null, "owner": {"id": 2, "name": "b"}}`},
+		{name: "renamed field", path: "/items/1", status: 200, body: `{"id": 1, "amount": "a", "tags": []}`,
+			expected: "$: property name is required\n$: property amount isn't documented"},
+		{name: "wrong type", path: "/items/1", status: 200, body: `{"id": 1.5, "name": "a", "tags": [1]}`,
+			expected: "$.id: expected integer, got 1.5\n$.tags[0]: expected string, got 1"},
+		{name: "invalid date-time", path: "/items/1", status: 200, body: `{"id": 1, "name": "a", "tags": [], "due": "tomorrow"}`,
+			expected: `$.due: "tomorrow" isn't an RFC 3339 date-time`},
+		{name: "wrong nested object", path: "/items/1", status: 200, body: `{"id": 1, "name": "a", "tags": [], "owner": 2}`,
+			expected: "$.owner: 2 matches none of the allowed schemas"},
+		{name: "list", path: "/items", status: 200, body: `[{"id": 1, "name": "a", "tags": [], "children": [{"id": 2}]}]`,
+			expected: "$[0].children[0]: property name is required"},
+		{name: "error", path: "/items/1", status: 404, body: `{"error": "not found"}`},
+		{name: "wrong error", path: "/items/1", status: 500, body: `{"message": "oops"}`, expected: "$: property error is required"},
+		{name: "non-json body", path: "/items/export", status: 200, contentType: "text/csv; charset=utf-8", body: "id,name"},
+		{name: "undocumented content type", path: "/items/export", status: 200, contentType: "text/html", body: "<p>",
+			expected: "content type text/html isn't documented"},
+		{name: "body of an empty response", path: "/files/a", status: 200, body: "{}", expected: "status 200 is documented without a body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, _, ok := doc.Find("GET", tt.path)
+			if !ok {
+				t.Fatalf("%v isn't found", tt.path)
+			}
+			header := http.Header{}
+			if tt.contentType != "" {
+				header.Set("Content-Type", tt.contentType)
+			}
+			err := doc.ValidateResponse(op, tt.status, header, []byte(tt.body))
+			if tt.expected == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateUndocumentedStatus(t *testing.T) {
+	doc, err := Generate(Info{Title: "test", Version: "1"}, []Endpoint{{Method: "GET", Path: "/items", OperationID: "listItems"}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	op, _, _ := doc.Find("GET", "/items")
+	if err := doc.ValidateResponse(op, http.StatusNotFound, http.Header{}, nil); err == nil || err.Error() != "status 404 isn't documented" {
+		t.Errorf("Expected undocumented status error, got %v", err)
+	}
+}
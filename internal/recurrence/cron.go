@@ -0,0 +1,196 @@
+package recurrence
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros are the shorthands of cron expressions
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames   = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	weekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+// cronField describes the values of a field of a cron expression
+type cronField struct {
+	name     string
+	min, max int
+	// names are accepted instead of numbers starting from min
+	names []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	// 7 is sunday as well as 0
+	{name: "day of week", min: 0, max: 7, names: weekdayNames},
+}
+
+// cron is a standard five field expression: minute, hour, day of month, month and day of week.
+// Fields are lists of values, ranges and steps, months and weekdays may be named. As in Vixie cron,
+// when both day fields are restricted a day matching either of them matches.
+type cron struct {
+	minutes, hours, days, months, weekdays uint64
+	// daysStar and weekdaysStar are set for fields starting with "*"
+	daysStar, weekdaysStar bool
+	loc                    *time.Location
+}
+
+func parseCron(expr string, loc *time.Location) (*cron, error) {
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%w: cron expression %q has %v fields, expected %v", ErrInvalidRule, expr, len(fields), len(cronFields))
+	}
+	sets := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	c := &cron{
+		minutes:      sets[0],
+		hours:        sets[1],
+		days:         sets[2],
+		months:       sets[3],
+		weekdays:     sets[4],
+		daysStar:     strings.HasPrefix(fields[2], "*"),
+		weekdaysStar: strings.HasPrefix(fields[4], "*"),
+		loc:          loc,
+	}
+	if c.weekdays&(1<<7) != 0 {
+		c.weekdays |= 1
+	}
+	return c, nil
+}
+
+// parse returns the set of values of the field as bits
+func (f cronField) parse(field string) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		valueRange, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("%w: invalid step %q of %v", ErrInvalidRule, stepText, f.name)
+			}
+		}
+
+		var low, high int
+		switch from, to, isRange := strings.Cut(valueRange, "-"); {
+		case valueRange == "*":
+			low, high = f.min, f.max
+		case isRange:
+			var err error
+			if low, err = f.value(from); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(to); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if low, err = f.value(valueRange); err != nil {
+				return 0, err
+			}
+			high = low
+			if hasStep {
+				// "5/15" runs from 5 to the end of the range
+				high = f.max
+			}
+		}
+		if low > high {
+			return 0, fmt.Errorf("%w: empty range %q of %v", ErrInvalidRule, valueRange, f.name)
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (f cronField) value(text string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(text, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%w: %v %q isn't in %v..%v", ErrInvalidRule, f.name, text, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (c *cron) matchesDay(d date) bool {
+	if c.months&(1<<int(d.Month())) == 0 {
+		return false
+	}
+	day := c.days&(1<<d.Day()) != 0
+	weekday := c.weekdays&(1<<int(d.Weekday())) != 0
+	switch {
+	case c.daysStar && c.weekdaysStar:
+		return true
+	case c.daysStar:
+		return weekday
+	case c.weekdaysStar:
+		return day
+	}
+	return day || weekday
+}
+
+// transitionHours bounds how far a transition moves a wall time, so the search within a day
+// starts that much before t and ends that much after the first found time
+const transitionHours = 3
+
+func (c *cron) Next(t time.Time) time.Time {
+	local := t.In(c.loc)
+	start := dateOf(local)
+	for i := range searchYears * 366 {
+		d := start.addDays(i)
+		if !c.matchesDay(d) {
+			continue
+		}
+		// times moved by a transition may be out of the order of wall times, so the earliest one is searched
+		var next time.Time
+		for hours := c.hours; hours != 0; hours &= hours - 1 {
+			hour := bits.TrailingZeros64(hours)
+			if i == 0 && hour < local.Hour()-transitionHours {
+				continue
+			}
+			if !next.IsZero() && hour > next.In(c.loc).Hour()+transitionHours {
+				break
+			}
+			for minutes := c.minutes; minutes != 0; minutes &= minutes - 1 {
+				occurrence := wallTime(d, hour, bits.TrailingZeros64(minutes), c.loc)
+				if occurrence.After(t) && (next.IsZero() || occurrence.Before(next)) {
+					next = occurrence
+				}
+			}
+		}
+		if !next.IsZero() {
+			return next
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,104 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("Unexpected error loading %v: %v", name, err)
+	}
+	return loc
+}
+
+// at parses a wall time of loc written as "2006-01-02 15:04"
+func at(t *testing.T, loc *time.Location, value string) time.Time {
+	t.Helper()
+	parsed, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing %v: %v", value, err)
+	}
+	return parsed
+}
+
+func TestCronNext(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	newYork := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name  string
+		expr  string
+		loc   *time.Location
+		after string
+		// want is a time in UTC, empty when there are no more occurrences
+		want string
+	}{
+		{name: "every quarter", expr: "*/15 * * * *", loc: time.UTC, after: "2026-10-16 10:07", want: "2026-10-16 10:15"},
+		{name: "occurrence is after the time", expr: "*/15 * * * *", loc: time.UTC, after: "2026-10-16 10:15", want: "2026-10-16 10:30"},
+		{name: "step from a value", expr: "5/20 * * * *", loc: time.UTC, after: "2026-10-16 10:06", want: "2026-10-16 10:25"},
+		{name: "working days", expr: "0 9 * * MON-FRI", loc: time.UTC, after: "2026-10-16 10:00", want: "2026-10-19 09:00"},
+		{name: "sunday as 7", expr: "0 12 * * 7", loc: time.UTC, after: "2026-10-16 10:00", want: "2026-10-18 12:00"},
+		{name: "named months", expr: "0 0 1 jan,jul *", loc: time.UTC, after: "2026-10-16 10:00", want: "2027-01-01 00:00"},
+		{name: "hourly macro", expr: "@hourly", loc: time.UTC, after: "2026-10-16 10:30", want: "2026-10-16 11:00"},
+		{name: "either day field", expr: "0 0 13 * FRI", loc: time.UTC, after: "2026-10-01 00:00", want: "2026-10-02 00:00"},
+		{name: "leap day", expr: "0 0 29 2 *", loc: time.UTC, after: "2026-01-01 00:00", want: "2028-02-29 00:00"},
+		{name: "day that never comes", expr: "0 0 31 2 *", loc: time.UTC, after: "2026-01-01 00:00", want: ""},
+		{name: "local time", expr: "0 9 * * *", loc: berlin, after: "2026-10-16 10:00", want: "2026-10-17 07:00"},
+		{name: "same wall time after spring forward", expr: "0 9 * * *", loc: newYork, after: "2026-03-07 15:00", want: "2026-03-08 13:00"},
+		{name: "skipped time moves past the gap", expr: "30 2 * * *", loc: berlin, after: "2026-03-28 12:00", want: "2026-03-29 01:30"},
+		{name: "skipped time moves past the gap west", expr: "30 2 * * *", loc: newYork, after: "2026-03-07 12:00", want: "2026-03-08 07:30"},
+		{name: "repeated time happens at its first instant", expr: "30 2 * * *", loc: berlin, after: "2026-10-24 12:00", want: "2026-10-25 00:30"},
+		{name: "repeated time happens once", expr: "30 2 * * *", loc: berlin, after: "2026-10-25 00:30", want: "2026-10-26 01:30"},
+		{name: "repeated time happens once west", expr: "30 1 * * *", loc: newYork, after: "2026-11-01 05:30", want: "2026-11-02 06:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expr, time.Time{}, tt.loc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := schedule.Next(at(t, time.UTC, tt.after))
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("Expected no occurrence, got %v", got)
+				}
+				return
+			}
+			if want := at(t, time.UTC, tt.want); !got.Equal(want) {
+				t.Errorf("Expected %v, got %v", want, got.UTC())
+			}
+			if got.Location() != tt.loc {
+				t.Errorf("Expected occurrence in %v, got %v", tt.loc, got.Location())
+			}
+		})
+	}
+}
+
+func TestParseInvalidCron(t *testing.T) {
+	tests := []string{
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"0 0 * * FOO",
+		"0 0 1,,2 * *",
+		"@every",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Parse(expr, time.Time{}, time.UTC)
+			if !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("Expected ErrInvalidRule, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package recurrence computes occurrences of cron expressions and of a subset of RFC 5545 recurrence rules.
+//
+// Rules are evaluated in the wall clock time of a location, so "every day at 9:00" stays at 9:00 local time
+// across DST transitions. A wall time skipped by a transition happens as much later as the clocks moved,
+// e.g. 2:30 becomes 3:30 when clocks jump from 2:00 to 3:00, and a wall time repeated by a transition
+// happens once, at its first instant.
+package recurrence
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed rule
+type Schedule interface {
+	// Next returns the first occurrence strictly after t, zero when there are no more
+	Next(t time.Time) time.Time
+}
+
+// ErrInvalidRule is wrapped by errors of malformed and unsupported rules
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// searchYears bounds the search for the next occurrence, rules matching nothing in it have no more occurrences.
+// It covers rules matching only leap days, which may be eight years apart.
+const searchYears = 9
+
+// Parse parses a cron expression or an RRULE, which is told apart by its FREQ part.
+// The rule is evaluated in loc, start anchors intervals and the count of an RRULE, it's ignored by cron expressions.
+func Parse(rule string, start time.Time, loc *time.Location) (Schedule, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	rule = strings.TrimSpace(rule)
+	if strings.Contains(strings.ToUpper(rule), "FREQ=") {
+		return parseRRule(rule, start, loc)
+	}
+	return parseCron(rule, loc)
+}
+
+// date is a calendar day, its arithmetic is done in UTC so it isn't affected by transitions
+type date struct {
+	time.Time
+}
+
+func dateOf(t time.Time) date {
+	y, m, d := t.Date()
+	return date{time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+}
+
+func (d date) addDays(days int) date {
+	return date{d.AddDate(0, 0, days)}
+}
+
+// daysIn returns an amount of days in the month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// wallTime returns the instant of the wall clock time of the day in loc, resolving times skipped
+// and repeated by transitions as the package describes
+func wallTime(d date, hour, minute int, loc *time.Location) time.Time {
+	naive := time.Date(d.Year(), d.Month(), d.Day(), hour, minute, 0, 0, time.UTC)
+	// no location changes its offset twice within a day
+	_, before := naive.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := naive.Add(24 * time.Hour).In(loc).Zone()
+
+	var found time.Time
+	for _, offset := range []int{before, after} {
+		t := naive.Add(-time.Duration(offset) * time.Second).In(loc)
+		if t.Hour() != hour || t.Minute() != minute || t.Day() != d.Day() {
+			continue
+		}
+		if found.IsZero() || t.Before(found) {
+			found = t
+		}
+	}
+	if found.IsZero() {
+		// skipped by the transition, the offset before it moves the time past the gap
+		found = naive.Add(-time.Duration(before) * time.Second).In(loc)
+	}
+	return found
+}
@@ -0,0 +1,363 @@
+package recurrence
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type frequency int
+
+const (
+	daily frequency = iota
+	weekly
+	monthly
+	yearly
+)
+
+var frequencies = map[string]frequency{"DAILY": daily, "WEEKLY": weekly, "MONTHLY": monthly, "YEARLY": yearly}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// byDay is a weekday of BYDAY, n is its position in the month counting from the end when negative,
+// zero matches every such weekday
+type byDay struct {
+	n       int
+	weekday time.Weekday
+}
+
+// rrule is the subset of RFC 5545 recurrence rules with FREQ of DAILY, WEEKLY, MONTHLY or YEARLY,
+// INTERVAL, COUNT, UNTIL, BYMONTH, BYMONTHDAY, BYDAY, BYHOUR and BYMINUTE. Weeks start on monday,
+// BYDAY positions like 1MO or -1FR are counted within a month. The start is the first occurrence when
+// it matches the rule, nothing happens before it, and it gives the parts the rule doesn't set.
+type rrule struct {
+	freq     frequency
+	interval int
+	// count limits occurrences counted from the start, zero doesn't
+	count int
+	// until is the last moment of occurrences, zero doesn't limit them
+	until      time.Time
+	months     []int
+	monthDays  []int
+	days       []byDay
+	hours      []int
+	minutes    []int
+	start      time.Time
+	startLocal time.Time
+	loc        *time.Location
+}
+
+func parseRRule(rule string, start time.Time, loc *time.Location) (*rrule, error) {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %v", ErrInvalidRule, fmt.Sprintf(format, args...))
+	}
+	if start.IsZero() {
+		return nil, invalid("RRULE needs a start")
+	}
+	r := &rrule{
+		interval:   1,
+		start:      start.Truncate(time.Minute),
+		startLocal: start.In(loc).Truncate(time.Minute),
+		loc:        loc,
+	}
+
+	rule = strings.TrimPrefix(strings.ToUpper(rule), "RRULE:")
+	seen := make(map[string]bool)
+	hasFreq := false
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, invalid("malformed part %q", part)
+		}
+		if seen[key] {
+			return nil, invalid("%v is repeated", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			freq, ok := frequencies[value]
+			if !ok {
+				return nil, invalid("unsupported FREQ %q, expected DAILY, WEEKLY, MONTHLY or YEARLY", value)
+			}
+			r.freq, hasFreq = freq, true
+		case "INTERVAL":
+			if r.interval, err = strconv.Atoi(value); err != nil || r.interval < 1 {
+				return nil, invalid("INTERVAL must be a positive number, got %q", value)
+			}
+		case "COUNT":
+			if r.count, err = strconv.Atoi(value); err != nil || r.count < 1 {
+				return nil, invalid("COUNT must be a positive number, got %q", value)
+			}
+		case "UNTIL":
+			if r.until, err = parseUntil(value, loc); err != nil {
+				return nil, invalid("UNTIL must be a date or a date-time, got %q", value)
+			}
+		case "BYMONTH":
+			r.months, err = parseNumbers(value, 1, 12, false)
+		case "BYMONTHDAY":
+			r.monthDays, err = parseNumbers(value, 1, 31, true)
+		case "BYHOUR":
+			r.hours, err = parseNumbers(value, 0, 23, false)
+		case "BYMINUTE":
+			r.minutes, err = parseNumbers(value, 0, 59, false)
+		case "BYDAY":
+			r.days, err = parseDays(value)
+		default:
+			return nil, invalid("unsupported part %v", key)
+		}
+		if err != nil {
+			return nil, invalid("%v: %v", key, err)
+		}
+	}
+
+	switch {
+	case !hasFreq:
+		return nil, invalid("FREQ is missing")
+	case r.count != 0 && !r.until.IsZero():
+		return nil, invalid("COUNT and UNTIL can't be used together")
+	case r.freq == weekly && len(r.monthDays) > 0:
+		return nil, invalid("BYMONTHDAY can't be used with WEEKLY")
+	case r.freq == yearly && len(r.days) > 0 && len(r.months) == 0:
+		return nil, invalid("BYDAY with YEARLY needs BYMONTH")
+	}
+	if r.freq == daily || r.freq == weekly {
+		for _, d := range r.days {
+			if d.n != 0 {
+				return nil, invalid("BYDAY positions can be used only with MONTHLY and YEARLY")
+			}
+		}
+	}
+	if len(r.hours) == 0 {
+		r.hours = []int{r.startLocal.Hour()}
+	}
+	if len(r.minutes) == 0 {
+		r.minutes = []int{r.startLocal.Minute()}
+	}
+	return r, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return t, nil
+	}
+	// a date includes the whole day
+	t, err := time.ParseInLocation("20060102", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// parseNumbers parses a comma separated list of numbers in min..max, or in -max..-min too if negative
+func parseNumbers(value string, min, max int, negative bool) ([]int, error) {
+	numbers := make([]int, 0)
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		abs := n
+		if negative && n < 0 {
+			abs = -n
+		}
+		if err != nil || abs < min || abs > max {
+			return nil, fmt.Errorf("%q isn't in %v..%v", item, min, max)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+func parseDays(value string) ([]byDay, error) {
+	days := make([]byDay, 0)
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("malformed day %q", item)
+		}
+		weekday, ok := rruleWeekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", item)
+		}
+		day := byDay{weekday: weekday}
+		if position := item[:len(item)-2]; position != "" {
+			n, err := strconv.Atoi(position)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("position of %q isn't in 1..5 or -5..-1", item)
+			}
+			day.n = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func (r *rrule) Next(t time.Time) time.Time {
+	first := 0
+	if r.count == 0 {
+		// periods before the one of t can't have later occurrences, so they are skipped unless occurrences are counted
+		first = max(r.periodsBetween(r.startLocal, t.In(r.loc))/r.interval-1, 0)
+	}
+	limit := t.In(r.loc).AddDate(searchYears, 0, 0)
+	counted := 0
+	for period := first; ; period++ {
+		days := r.periodDays(period)
+		if len(days) == 0 && r.periodStart(period).After(limit) {
+			return time.Time{}
+		}
+		for _, d := range days {
+			if d.After(limit) {
+				return time.Time{}
+			}
+			for _, occurrence := range r.occurrences(d) {
+				if occurrence.Before(r.start) {
+					continue
+				}
+				counted++
+				if r.count != 0 && counted > r.count {
+					return time.Time{}
+				}
+				if !r.until.IsZero() && occurrence.After(r.until) {
+					return time.Time{}
+				}
+				if occurrence.After(t) {
+					return occurrence
+				}
+			}
+		}
+	}
+}
+
+// occurrences returns the times of the day in order
+func (r *rrule) occurrences(d date) []time.Time {
+	times := make([]time.Time, 0, len(r.hours)*len(r.minutes))
+	for _, hour := range r.hours {
+		for _, minute := range r.minutes {
+			times = append(times, wallTime(d, hour, minute, r.loc))
+		}
+	}
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(times, func(a, b time.Time) bool { return a.Equal(b) })
+}
+
+// periodsBetween returns an amount of whole periods of the frequency from the one of the start to the one of t
+func (r *rrule) periodsBetween(start, t time.Time) int {
+	switch r.freq {
+	case daily:
+		return int(dateOf(t).Sub(dateOf(start).Time).Hours() / 24)
+	case weekly:
+		return int(weekStart(dateOf(t)).Sub(weekStart(dateOf(start)).Time).Hours() / (24 * 7))
+	case monthly:
+		return (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+	}
+	return t.Year() - start.Year()
+}
+
+// periodStart returns the first day of the period
+func (r *rrule) periodStart(period int) date {
+	start := dateOf(r.startLocal)
+	n := period * r.interval
+	switch r.freq {
+	case daily:
+		return start.addDays(n)
+	case weekly:
+		return weekStart(start).addDays(7 * n)
+	case monthly:
+		return date{time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)}
+	}
+	return date{time.Date(start.Year()+n, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// periodDays returns the days of the period matching the rule in order
+func (r *rrule) periodDays(period int) []date {
+	first := r.periodStart(period)
+	days := make([]date, 0)
+	switch r.freq {
+	case daily:
+		if r.matchesMonth(first) && r.matchesMonthDay(first) && r.matchesWeekday(first) {
+			days = append(days, first)
+		}
+	case weekly:
+		weekdays := r.days
+		if len(weekdays) == 0 {
+			weekdays = []byDay{{weekday: r.startLocal.Weekday()}}
+		}
+		for i := range 7 {
+			d := first.addDays(i)
+			if r.matchesMonth(d) && slices.ContainsFunc(weekdays, func(b byDay) bool { return b.weekday == d.Weekday() }) {
+				days = append(days, d)
+			}
+		}
+	case monthly:
+		if r.matchesMonth(first) {
+			days = r.monthDaysOf(first.Year(), first.Month())
+		}
+	case yearly:
+		months := slices.Sorted(slices.Values(r.months))
+		switch {
+		case len(months) > 0:
+		case len(r.monthDays) > 0:
+			// days of the month without months recur in every month
+			months = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+		default:
+			months = []int{int(r.startLocal.Month())}
+		}
+		for _, month := range months {
+			days = append(days, r.monthDaysOf(first.Year(), time.Month(month))...)
+		}
+	}
+	return days
+}
+
+// monthDaysOf returns the days of the month matching BYMONTHDAY and BYDAY, or the day of the start without them
+func (r *rrule) monthDaysOf(year int, month time.Month) []date {
+	days := make([]date, 0)
+	for day := 1; day <= daysIn(year, month); day++ {
+		d := date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+		matches := r.matchesMonthDay(d) && r.matchesWeekday(d)
+		if len(r.monthDays) == 0 && len(r.days) == 0 {
+			matches = day == r.startLocal.Day()
+		}
+		if matches {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func (r *rrule) matchesMonth(d date) bool {
+	return len(r.months) == 0 || slices.Contains(r.months, int(d.Month()))
+}
+
+func (r *rrule) matchesMonthDay(d date) bool {
+	if len(r.monthDays) == 0 {
+		return true
+	}
+	fromEnd := d.Day() - daysIn(d.Year(), d.Month()) - 1
+	return slices.Contains(r.monthDays, d.Day()) || slices.Contains(r.monthDays, fromEnd)
+}
+
+func (r *rrule) matchesWeekday(d date) bool {
+	if len(r.days) == 0 {
+		return true
+	}
+	position := (d.Day()-1)/7 + 1
+	positionFromEnd := -((daysIn(d.Year(), d.Month())-d.Day())/7 + 1)
+	for _, b := range r.days {
+		if b.weekday == d.Weekday() && (b.n == 0 || b.n == position || b.n == positionFromEnd) {
+			return true
+		}
+	}
+	return false
+}
+
+// weekStart returns the monday of the week of the day
+func weekStart(d date) date {
+	return d.addDays(-((int(d.Weekday()) + 6) % 7))
+}
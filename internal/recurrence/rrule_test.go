@@ -0,0 +1,95 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRRuleNext(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	tests := []struct {
+		name  string
+		rule  string
+		loc   *time.Location
+		start string
+		after string
+		// want is a time in UTC, empty when there are no more occurrences
+		want string
+	}{
+		{name: "start is the first occurrence", rule: "FREQ=DAILY", loc: time.UTC, start: "2026-10-10 09:00", after: "2026-10-01 00:00", want: "2026-10-10 09:00"},
+		{name: "prefix", rule: "RRULE:FREQ=DAILY", loc: time.UTC, start: "2026-10-10 09:00", after: "2026-10-10 09:00", want: "2026-10-11 09:00"},
+		{name: "interval", rule: "FREQ=DAILY;INTERVAL=2", loc: time.UTC, start: "2026-10-01 09:00", after: "2026-10-02 00:00", want: "2026-10-03 09:00"},
+		{name: "long after the start", rule: "FREQ=DAILY;INTERVAL=3", loc: time.UTC, start: "2020-01-01 09:00", after: "2026-10-18 10:00", want: "2026-10-20 09:00"},
+		{name: "weekday of the start", rule: "FREQ=WEEKLY", loc: time.UTC, start: "2026-10-01 09:00", after: "2026-10-01 09:00", want: "2026-10-08 09:00"},
+		{name: "weekdays and times", rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR;BYHOUR=8,17;BYMINUTE=0", loc: time.UTC, start: "2026-10-01 00:00", after: "2026-10-16 09:00", want: "2026-10-16 17:00"},
+		{name: "every other week", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", loc: time.UTC, start: "2026-10-06 10:00", after: "2026-10-06 10:00", want: "2026-10-20 10:00"},
+		{name: "last friday", rule: "FREQ=MONTHLY;BYDAY=-1FR", loc: time.UTC, start: "2026-01-01 17:00", after: "2026-10-01 00:00", want: "2026-10-30 17:00"},
+		{name: "last day of month", rule: "FREQ=MONTHLY;BYMONTHDAY=-1", loc: time.UTC, start: "2026-01-31 12:00", after: "2026-02-01 00:00", want: "2026-02-28 12:00"},
+		{name: "months without the day are skipped", rule: "FREQ=MONTHLY", loc: time.UTC, start: "2026-01-31 12:00", after: "2026-02-01 00:00", want: "2026-03-31 12:00"},
+		{name: "fourth thursday of november", rule: "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", loc: time.UTC, start: "2026-01-01 00:00", after: "2026-10-18 00:00", want: "2026-11-26 00:00"},
+		{name: "days of every month", rule: "FREQ=YEARLY;BYMONTHDAY=1,15", loc: time.UTC, start: "2026-01-01 08:00", after: "2026-10-18 00:00", want: "2026-11-01 08:00"},
+		{name: "count", rule: "FREQ=DAILY;COUNT=3", loc: time.UTC, start: "2026-10-01 09:00", after: "2026-10-02 09:00", want: "2026-10-03 09:00"},
+		{name: "count is reached", rule: "FREQ=DAILY;COUNT=3", loc: time.UTC, start: "2026-10-01 09:00", after: "2026-10-03 09:00", want: ""},
+		{name: "until a date", rule: "FREQ=DAILY;UNTIL=20261005", loc: time.UTC, start: "2026-10-01 09:00", after: "2026-10-04 10:00", want: "2026-10-05 09:00"},
+		{name: "until is reached", rule: "FREQ=DAILY;UNTIL=20261005T090000Z", loc: time.UTC, start: "2026-10-01 09:00", after: "2026-10-05 09:00", want: ""},
+		{name: "same wall time across DST", rule: "FREQ=DAILY;BYHOUR=9;BYMINUTE=0", loc: berlin, start: "2026-03-27 08:00", after: "2026-03-28 08:00", want: "2026-03-29 07:00"},
+		{name: "skipped time moves past the gap", rule: "FREQ=DAILY", loc: berlin, start: "2026-03-27 01:30", after: "2026-03-28 01:30", want: "2026-03-29 01:30"},
+		{name: "repeated time happens once", rule: "FREQ=DAILY", loc: berlin, start: "2026-10-24 00:30", after: "2026-10-25 00:30", want: "2026-10-26 01:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.rule, at(t, time.UTC, tt.start), tt.loc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := schedule.Next(at(t, time.UTC, tt.after))
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("Expected no occurrence, got %v", got)
+				}
+				return
+			}
+			if want := at(t, time.UTC, tt.want); !got.Equal(want) {
+				t.Errorf("Expected %v, got %v", want, got.UTC())
+			}
+		})
+	}
+}
+
+func TestParseInvalidRRule(t *testing.T) {
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+	}{
+		{name: "no start", rule: "FREQ=DAILY"},
+		{name: "unsupported frequency", rule: "FREQ=HOURLY", start: start},
+		{name: "no frequency", rule: "INTERVAL=2;BYDAY=MO", start: start},
+		{name: "unknown part", rule: "FREQ=DAILY;WKST=SU", start: start},
+		{name: "repeated part", rule: "FREQ=DAILY;FREQ=WEEKLY", start: start},
+		{name: "malformed part", rule: "FREQ=DAILY;COUNT", start: start},
+		{name: "zero interval", rule: "FREQ=DAILY;INTERVAL=0", start: start},
+		{name: "count and until", rule: "FREQ=DAILY;COUNT=2;UNTIL=20261010", start: start},
+		{name: "malformed until", rule: "FREQ=DAILY;UNTIL=2026-10-10", start: start},
+		{name: "hour out of range", rule: "FREQ=DAILY;BYHOUR=24", start: start},
+		{name: "month day out of range", rule: "FREQ=MONTHLY;BYMONTHDAY=-32", start: start},
+		{name: "unknown weekday", rule: "FREQ=WEEKLY;BYDAY=XX", start: start},
+		{name: "position out of range", rule: "FREQ=MONTHLY;BYDAY=6MO", start: start},
+		{name: "position with daily", rule: "FREQ=DAILY;BYDAY=1MO", start: start},
+		{name: "month day with weekly", rule: "FREQ=WEEKLY;BYMONTHDAY=1", start: start},
+		{name: "yearly weekday without month", rule: "FREQ=YEARLY;BYDAY=MO", start: start},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.rule, tt.start, time.UTC)
+			if !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("Expected ErrInvalidRule, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"slices"
+	"sync"
+	"time"
+)
+
+const engineName = "ReminderEngine"
+
+// DefaultInterval is the delay between checks of tasks, reminders are sent up to it late
+const DefaultInterval = time.Minute
+
+type Logger interface {
+	Log(format string, info ...any)
+}
+
+type ProjectStorage interface {
+	GetAll(ctx context.Context) ([]model.Project, error)
+}
+
+type TaskStorage interface {
+	GetAll(ctx context.Context, filter model.Filter) ([]model.Task, int, error)
+}
+
+type UserStorage interface {
+	GetByUserId(ctx context.Context, userId int) (*model.User, error)
+}
+
+// NotificationStorage keeps preferences of users and the reminders sent to them, see storage.NotificationStorage
+type NotificationStorage interface {
+	GetPreferences(ctx context.Context, userId int) (*model.NotificationPreferences, error)
+	WasSent(ctx context.Context, key model.ReminderKey) (bool, error)
+	MarkSent(ctx context.Context, key model.ReminderKey, at time.Time) error
+}
+
+// Engine sends reminders about tasks.
+//
+// Every interval the rules are evaluated against the tasks of every project, and a reminder of every
+// matching rule goes to the assignee of the task, or to its reporter when it's unassigned. Users get
+// reminders through the channels they have chosen, or the default ones, except for the rules they have muted.
+// A reminder is sent through a channel once per task, rule and moment it's about. A failed one isn't marked
+// as sent, so it's tried again by the next check.
+type Engine struct {
+	logger        Logger
+	projects      ProjectStorage
+	tasks         TaskStorage
+	users         UserStorage
+	notifications NotificationStorage
+	notifiers     []Notifier
+	rules         []Rule
+	defaults      []model.NotificationChannel
+	interval      time.Duration
+	now           func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	start  sync.Once
+}
+
+// EngineOption configures optional behaviour of Engine
+type EngineOption func(*Engine)
+
+// WithRules replaces the default rules
+func WithRules(rules ...Rule) EngineOption {
+	return func(e *Engine) {
+		e.rules = rules
+	}
+}
+
+// WithNotifiers sets the channels reminders are sent through, only the log by default
+func WithNotifiers(notifiers ...Notifier) EngineOption {
+	return func(e *Engine) {
+		e.notifiers = notifiers
+	}
+}
+
+// WithDefaultChannels sets the channels of users who haven't chosen any, the log by default
+func WithDefaultChannels(channels ...model.NotificationChannel) EngineOption {
+	return func(e *Engine) {
+		e.defaults = channels
+	}
+}
+
+// WithInterval sets the delay between checks of tasks
+func WithInterval(interval time.Duration) EngineOption {
+	return func(e *Engine) {
+		e.interval = interval
+	}
+}
+
+// WithClock replaces the clock rules are evaluated with, tests use it to move through time
+func WithClock(now func() time.Time) EngineOption {
+	return func(e *Engine) {
+		e.now = now
+	}
+}
+
+func NewEngine(logger Logger, projects ProjectStorage, tasks TaskStorage, users UserStorage, notifications NotificationStorage, opts ...EngineOption) (*Engine, error) {
+	if logger == nil || projects == nil || tasks == nil || users == nil || notifications == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", engineName)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Engine{
+		logger:        logger,
+		projects:      projects,
+		tasks:         tasks,
+		users:         users,
+		notifications: notifications,
+		notifiers:     []Notifier{&LogNotifier{logger}},
+		rules:         DefaultRules(),
+		defaults:      []model.NotificationChannel{model.ChannelLog},
+		interval:      DefaultInterval,
+		now:           time.Now,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.interval <= 0 || e.now == nil || slices.Contains(e.notifiers, nil) || slices.Contains(e.rules, nil) {
+		cancel()
+		return nil, fmt.Errorf("%v: invalid interval %v, rules or notifiers", engineName, e.interval)
+	}
+	for _, channel := range e.defaults {
+		if !slices.ContainsFunc(e.notifiers, func(n Notifier) bool { return n.Channel() == channel }) {
+			cancel()
+			return nil, fmt.Errorf("%v: default channel %q has no notifier", engineName, channel)
+		}
+	}
+
+	logger.Log("Created %s successfully", engineName)
+	return e, nil
+}
+
+// Start checks tasks right away and then every interval, starting again does nothing
+func (e *Engine) Start() {
+	e.start.Do(func() {
+		e.wg.Add(1)
+		go e.run()
+	})
+}
+
+// Stop stops the checks and waits for the running one until ctx is done
+func (e *Engine) Stop(ctx context.Context) error {
+	e.cancel()
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%v: stopping: %w", engineName, ctx.Err())
+	}
+}
+
+func (e *Engine) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.tick(e.ctx)
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recipient is a user with their preferences, looked up once per check
+type recipient struct {
+	user        model.User
+	preferences model.NotificationPreferences
+}
+
+// tick evaluates the rules against the tasks of every project
+func (e *Engine) tick(ctx context.Context) {
+	projects, err := e.projects.GetAll(ctx)
+	if err != nil {
+		e.logger.Log("error in %v: couldn't get projects: %v", engineName, err)
+		return
+	}
+	now := e.now()
+	recipients := make(map[int]*recipient)
+	for _, project := range projects {
+		tasks, _, err := e.tasks.GetAll(tenant.WithScope(ctx, tenant.ForProject(project)), model.Filter{})
+		if err != nil {
+			e.logger.Log("error in %v: couldn't get tasks of project(%v): %v", engineName, project.Id, err)
+			continue
+		}
+		for _, task := range tasks {
+			if ctx.Err() != nil {
+				return
+			}
+			e.remind(ctx, task, now, recipients)
+		}
+	}
+}
+
+// remind sends reminders of every rule matching the task that weren't sent yet
+func (e *Engine) remind(ctx context.Context, task model.Task, now time.Time, recipients map[int]*recipient) {
+	userId := task.AssigneeID
+	if userId == model.NoUser {
+		userId = task.ReporterID
+	}
+	if userId == model.NoUser {
+		return
+	}
+	for _, rule := range e.rules {
+		anchor, ok := rule.Match(task, now)
+		if !ok {
+			continue
+		}
+		to, err := e.recipient(ctx, userId, recipients)
+		if err != nil {
+			e.logger.Log("error in %v: task(%v): %v", engineName, task.Id, err)
+			return
+		}
+		if to == nil {
+			return
+		}
+		reminder := model.Reminder{Rule: rule.Kind(), Task: task, Recipient: to.user, Anchor: anchor, At: now}
+		for _, notifier := range e.notifiers {
+			if to.preferences.Wants(reminder.Rule, notifier.Channel(), e.defaults) {
+				e.send(ctx, notifier, reminder, to.preferences)
+			}
+		}
+	}
+}
+
+// send notifies through the channel unless the reminder was sent, it's marked as sent only when it succeeds
+func (e *Engine) send(ctx context.Context, notifier Notifier, reminder model.Reminder, preferences model.NotificationPreferences) {
+	key := model.ReminderKey{
+		Rule:      reminder.Rule,
+		ProjectID: reminder.Task.ProjectID,
+		TaskID:    reminder.Task.Id,
+		Anchor:    reminder.Anchor,
+		UserID:    reminder.Recipient.Id,
+		Channel:   notifier.Channel(),
+	}
+	sent, err := e.notifications.WasSent(ctx, key)
+	if err != nil {
+		e.logger.Log("error in %v: %v", engineName, err)
+		return
+	}
+	if sent {
+		return
+	}
+	if err := notifier.Notify(ctx, reminder, preferences); err != nil {
+		e.logger.Log("error in %v: %v reminder of task(%v) to user(%v) through %v: %v",
+			engineName, reminder.Rule, reminder.Task.Id, reminder.Recipient.Id, key.Channel, err)
+		return
+	}
+	if err := e.notifications.MarkSent(ctx, key, reminder.At); err != nil {
+		e.logger.Log("error in %v: %v", engineName, err)
+	}
+}
+
+// recipient returns the user with their preferences, nil when the user doesn't exist anymore
+func (e *Engine) recipient(ctx context.Context, userId int, recipients map[int]*recipient) (*recipient, error) {
+	if to, ok := recipients[userId]; ok {
+		return to, nil
+	}
+	user, err := e.users.GetByUserId(ctx, userId)
+	if errors.Is(err, model.ErrNotFound) {
+		recipients[userId] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get user(%v): %w", userId, err)
+	}
+	preferences, err := e.notifications.GetPreferences(ctx, userId)
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		preferences = &model.NotificationPreferences{UserID: userId}
+	case err != nil:
+		return nil, fmt.Errorf("couldn't get preferences of user(%v): %w", userId, err)
+	}
+	to := &recipient{user: *user, preferences: *preferences}
+	recipients[userId] = to
+	return to, nil
+}
@@ -0,0 +1,423 @@
+package reminder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/storage"
+	"ivanjabrony/test_lo/internal/tenant"
+	"ivanjabrony/test_lo/internal/webhook"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type MockLogger struct{}
+
+func (m *MockLogger) Log(format string, info ...any) {}
+
+// mail is a message accepted by the smtp stand-in
+type mail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is a local stand-in of a mail server speaking just enough smtp for net/smtp,
+// failing makes it reject messages
+type smtpServer struct {
+	listener net.Listener
+	mails    chan mail
+
+	m       sync.Mutex
+	failing bool
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &smtpServer{listener: listener, mails: make(chan mail, 16)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *smtpServer) setFailing(failing bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.failing = failing
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP stand-in")
+	var current mail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case verb == "EHLO" || verb == "HELO":
+			reply("250 localhost")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			current = mail{from: strings.Trim(line[len("MAIL FROM:"):], "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			current.to = append(current.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case verb == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			current.data = data.String()
+			s.m.Lock()
+			failing := s.failing
+			s.m.Unlock()
+			if failing {
+				reply("451 Try again later")
+				continue
+			}
+			s.mails <- current
+			reply("250 OK queued")
+		case verb == "RSET":
+			reply("250 OK")
+		case verb == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// received returns the mails accepted so far
+func (s *smtpServer) received() []mail {
+	mails := make([]mail, 0)
+	for {
+		select {
+		case m := <-s.mails:
+			mails = append(mails, m)
+		default:
+			return mails
+		}
+	}
+}
+
+// taskList is a task storage of the default project, tasks keep the timestamps they are stored with
+type taskList struct {
+	m     sync.Mutex
+	tasks []model.Task
+}
+
+func (l *taskList) GetAll(ctx context.Context, filter model.Filter) ([]model.Task, int, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if tenant.FromContext(ctx).ProjectID != model.DefaultProjectId {
+		return []model.Task{}, 0, nil
+	}
+	return slices.Clone(l.tasks), len(l.tasks), nil
+}
+
+// fixture is an engine over in-memory storages with a clock the test moves
+type fixture struct {
+	engine        *Engine
+	tasks         *taskList
+	users         *storage.UserStorage
+	notifications *storage.NotificationStorage
+	smtp          *smtpServer
+	webhookURL    string
+	hooks         chan hook
+
+	m   sync.Mutex
+	now time.Time
+}
+
+// hook is a reminder received by the webhook of a user
+type hook struct {
+	header http.Header
+	body   []byte
+}
+
+const testSecret = "0123456789abcdef"
+
+func newFixture(t *testing.T, now time.Time, opts ...EngineOption) *fixture {
+	t.Helper()
+	logger := &MockLogger{}
+	projects, _ := storage.NewProjectStorage(logger, model.NoQuota)
+	users, _ := storage.NewUserStorage(logger)
+	notifications, _ := storage.NewNotificationStorage(logger)
+
+	f := &fixture{
+		tasks:         &taskList{},
+		users:         users,
+		notifications: notifications,
+		smtp:          newSMTPServer(t),
+		hooks:         make(chan hook, 16),
+		now:           now,
+	}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.hooks <- hook{r.Header, body}
+	}))
+	t.Cleanup(receiver.Close)
+	f.webhookURL = receiver.URL
+
+	logNotifier, _ := NewLogNotifier(logger)
+	smtpNotifier, err := NewSMTPNotifier(f.smtp.listener.Addr().String(), "tasks@example.com", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	webhookNotifier, _ := NewWebhookNotifier(receiver.Client(), testSecret)
+
+	opts = append([]EngineOption{
+		WithClock(f.clock),
+		WithNotifiers(logNotifier, smtpNotifier, webhookNotifier),
+		WithDefaultChannels(model.ChannelEmail),
+	}, opts...)
+	engine, err := NewEngine(logger, projects, f.tasks, users, notifications, opts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.engine = engine
+	return f
+}
+
+func (f *fixture) clock() time.Time {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.now
+}
+
+// tickAt moves the clock and runs a check
+func (f *fixture) tickAt(now time.Time) {
+	f.m.Lock()
+	f.now = now
+	f.m.Unlock()
+	f.engine.tick(context.Background())
+}
+
+func (f *fixture) user(t *testing.T, name string) int {
+	t.Helper()
+	id, err := f.users.Store(context.Background(), model.User{Name: name, Email: strings.ToLower(name) + "@example.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return id
+}
+
+func (f *fixture) store(task model.Task) int {
+	f.tasks.m.Lock()
+	defer f.tasks.m.Unlock()
+	task.Id = len(f.tasks.tasks) + 1
+	task.ProjectID = model.DefaultProjectId
+	f.tasks.tasks = append(f.tasks.tasks, task)
+	return task.Id
+}
+
+func (f *fixture) update(id int, change func(*model.Task)) {
+	f.tasks.m.Lock()
+	defer f.tasks.m.Unlock()
+	change(&f.tasks.tasks[id-1])
+}
+
+// expectMails checks subjects of the mails received since the last check
+func (f *fixture) expectMails(t *testing.T, subjects ...string) []mail {
+	t.Helper()
+	mails := f.smtp.received()
+	if len(mails) != len(subjects) {
+		t.Fatalf("Expected %v mails, got %+v", len(subjects), mails)
+	}
+	for i, m := range mails {
+		if !strings.Contains(m.data, "Subject: "+subjects[i]+"\r\n") {
+			t.Errorf("Expected subject %q, got %q", subjects[i], m.data)
+		}
+	}
+	return mails
+}
+
+func utc(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestNewEngine(t *testing.T) {
+	logger := &MockLogger{}
+	projects, _ := storage.NewProjectStorage(logger, model.NoQuota)
+	tasks, _ := storage.NewTaskStorage(logger)
+	users, _ := storage.NewUserStorage(logger)
+	notifications, _ := storage.NewNotificationStorage(logger)
+
+	if _, err := NewEngine(logger, projects, nil, users, notifications); err == nil {
+		t.Errorf("Expected error for nil tasks")
+	}
+	if _, err := NewEngine(logger, projects, tasks, users, notifications, WithInterval(0)); err == nil {
+		t.Errorf("Expected error for zero interval")
+	}
+	if _, err := NewEngine(logger, projects, tasks, users, notifications, WithDefaultChannels(model.ChannelEmail)); err == nil {
+		t.Errorf("Expected error for a default channel without a notifier")
+	}
+	if _, err := NewEngine(logger, projects, tasks, users, notifications); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestRules(t *testing.T) {
+	now := utc(2026, 10, 19, 9, 0)
+	tests := []struct {
+		name   string
+		rule   Rule
+		task   model.Task
+		want   bool
+		anchor time.Time
+	}{
+		{name: "due within the window", rule: DueSoon(24 * time.Hour), task: model.Task{Status: model.Created, DueAt: now.Add(23 * time.Hour)}, want: true, anchor: now.Add(23 * time.Hour)},
+		{name: "due after the window", rule: DueSoon(24 * time.Hour), task: model.Task{Status: model.Created, DueAt: now.Add(25 * time.Hour)}, want: false},
+		{name: "due soon but done", rule: DueSoon(24 * time.Hour), task: model.Task{Status: model.Done, DueAt: now.Add(time.Hour)}, want: false},
+		{name: "already overdue isn't due soon", rule: DueSoon(24 * time.Hour), task: model.Task{Status: model.Created, DueAt: now.Add(-time.Hour)}, want: false},
+		{name: "overdue", rule: Overdue(), task: model.Task{Status: model.InProgress, DueAt: now.Add(-time.Minute)}, want: true, anchor: now.Add(-time.Minute)},
+		{name: "without due date", rule: Overdue(), task: model.Task{Status: model.Created}, want: false},
+		{name: "stale", rule: Stale(72 * time.Hour), task: model.Task{Status: model.InProgress, StartedAt: now.Add(-73 * time.Hour)}, want: true, anchor: now.Add(-73 * time.Hour)},
+		{name: "recently started", rule: Stale(72 * time.Hour), task: model.Task{Status: model.InProgress, StartedAt: now.Add(-71 * time.Hour)}, want: false},
+		{name: "stale but done", rule: Stale(72 * time.Hour), task: model.Task{Status: model.Done, StartedAt: now.Add(-100 * time.Hour)}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anchor, ok := tt.rule.Match(tt.task, now)
+			if ok != tt.want || (ok && !anchor.Equal(tt.anchor)) {
+				t.Errorf("Expected %v at %v, got %v at %v", tt.want, tt.anchor, ok, anchor)
+			}
+		})
+	}
+}
+
+func TestEngineSendsOnce(t *testing.T) {
+	f := newFixture(t, utc(2026, 10, 18, 9, 0))
+	john := f.user(t, "John")
+	due := utc(2026, 10, 19, 12, 0)
+	id := f.store(model.Task{Name: "Ship\r\nBcc: everyone@example.com", Status: model.Created, AssigneeID: john, DueAt: due})
+
+	f.tickAt(utc(2026, 10, 18, 9, 0))
+	f.expectMails(t)
+
+	f.tickAt(utc(2026, 10, 18, 13, 0))
+	mails := f.expectMails(t, `Task "Ship Bcc: everyone@example.com" is due in 23h0m0s`)
+	if m := mails[0]; m.from != "tasks@example.com" || len(m.to) != 1 || m.to[0] != "john@example.com" {
+		t.Errorf("Unexpected envelope %+v", m)
+	}
+
+	// the same reminder isn't sent again
+	f.tickAt(utc(2026, 10, 18, 14, 0))
+	f.tickAt(utc(2026, 10, 19, 11, 59))
+	f.expectMails(t)
+
+	f.tickAt(utc(2026, 10, 19, 12, 30))
+	f.expectMails(t, `Task "Ship Bcc: everyone@example.com" is overdue`)
+	f.tickAt(utc(2026, 10, 19, 13, 30))
+	f.expectMails(t)
+
+	// moving the due date makes it a new reminder
+	f.update(id, func(task *model.Task) { task.DueAt = utc(2026, 10, 20, 12, 0) })
+	f.tickAt(utc(2026, 10, 20, 12, 30))
+	f.expectMails(t, `Task "Ship Bcc: everyone@example.com" is overdue`)
+}
+
+func TestEngineStale(t *testing.T) {
+	f := newFixture(t, utc(2026, 10, 18, 9, 0), WithRules(Stale(72*time.Hour)))
+	jane := f.user(t, "Jane")
+	f.store(model.Task{Name: "Review", Status: model.InProgress, ReporterID: jane, StartedAt: utc(2026, 10, 14, 8, 0)})
+	f.store(model.Task{Name: "Unassigned", Status: model.InProgress, StartedAt: utc(2026, 10, 14, 8, 0)})
+
+	f.tickAt(utc(2026, 10, 18, 9, 0))
+	mails := f.expectMails(t, `Task "Review" is in progress for 97h0m0s`)
+	if m := mails[0]; len(m.to) != 1 || m.to[0] != "jane@example.com" {
+		t.Errorf("Expected the reporter of an unassigned task to be reminded, got %+v", m)
+	}
+}
+
+func TestEngineRetriesFailures(t *testing.T) {
+	f := newFixture(t, utc(2026, 10, 18, 9, 0))
+	john := f.user(t, "John")
+	f.store(model.Task{Name: "Report", Status: model.Created, AssigneeID: john, DueAt: utc(2026, 10, 17, 9, 0)})
+
+	f.smtp.setFailing(true)
+	f.tickAt(utc(2026, 10, 18, 9, 0))
+	f.expectMails(t)
+
+	f.smtp.setFailing(false)
+	f.tickAt(utc(2026, 10, 18, 9, 1))
+	f.expectMails(t, `Task "Report" is overdue`)
+}
+
+func TestEnginePreferences(t *testing.T) {
+	f := newFixture(t, utc(2026, 10, 18, 9, 0))
+	ctx := context.Background()
+	john, jane, jack := f.user(t, "John"), f.user(t, "Jane"), f.user(t, "Jack")
+	f.notifications.PutPreferences(ctx, model.NotificationPreferences{UserID: john, Muted: []model.ReminderRule{model.ReminderOverdue}})
+	f.notifications.PutPreferences(ctx, model.NotificationPreferences{UserID: jane, Channels: []model.NotificationChannel{model.ChannelWebhook}, WebhookURL: f.webhookURL})
+	f.notifications.PutPreferences(ctx, model.NotificationPreferences{UserID: jack, Channels: []model.NotificationChannel{}})
+
+	overdue := utc(2026, 10, 17, 9, 0)
+	f.store(model.Task{Name: "Muted", Status: model.Created, AssigneeID: john, DueAt: overdue})
+	janes := f.store(model.Task{Name: "Hooked", Status: model.Created, AssigneeID: jane, DueAt: overdue})
+	f.store(model.Task{Name: "Silent", Status: model.Created, AssigneeID: jack, DueAt: overdue})
+	f.store(model.Task{Name: "Nobody's", Status: model.Created, AssigneeID: 99, DueAt: overdue})
+
+	f.tickAt(utc(2026, 10, 18, 9, 0))
+	f.expectMails(t)
+
+	select {
+	case h := <-f.hooks:
+		var payload dto.ReminderPayload
+		if err := json.Unmarshal(h.body, &payload); err != nil {
+			t.Fatalf("Failed to decode the reminder: %v", err)
+		}
+		if payload.Rule != model.ReminderOverdue || payload.Task.Id != janes || payload.UserID != jane {
+			t.Errorf("Unexpected reminder %+v", payload)
+		}
+		timestamp, signature := h.header.Get(webhook.HeaderTimestamp), h.header.Get(webhook.HeaderSignature)
+		if h.header.Get(webhook.HeaderEvent) != "reminder.overdue" || !webhook.Verify(testSecret, timestamp, h.body, signature, 0, time.Time{}) {
+			t.Errorf("Expected a signed reminder, got headers %v", h.header)
+		}
+	default:
+		t.Fatalf("Expected a reminder through the webhook")
+	}
+	select {
+	case h := <-f.hooks:
+		t.Errorf("Expected a single webhook reminder, got %s", h.body)
+	default:
+	}
+}
@@ -0,0 +1,218 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"ivanjabrony/test_lo/internal/webhook"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout limits sending of a single reminder
+const DefaultTimeout = 10 * time.Second
+
+// maxResponseBody is read from responses of webhooks, so connections can be reused
+const maxResponseBody = 64 << 10
+
+// Notifier sends reminders through a channel
+type Notifier interface {
+	Channel() model.NotificationChannel
+	// Notify sends the reminder to its recipient, preferences hold the addresses the channel may need
+	Notify(ctx context.Context, reminder model.Reminder, preferences model.NotificationPreferences) error
+}
+
+// LogNotifier writes reminders to the log
+type LogNotifier struct {
+	logger Logger
+}
+
+func NewLogNotifier(logger Logger) (*LogNotifier, error) {
+	if logger == nil {
+		return nil, errors.New("nil values in LogNotifier constructor")
+	}
+	return &LogNotifier{logger}, nil
+}
+
+func (n *LogNotifier) Channel() model.NotificationChannel {
+	return model.ChannelLog
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, reminder model.Reminder, preferences model.NotificationPreferences) error {
+	n.logger.Log("Reminder for user(%v): %v", reminder.Recipient.Id, subject(reminder))
+	return nil
+}
+
+// SMTPNotifier emails reminders to users, STARTTLS is used when the server offers it
+type SMTPNotifier struct {
+	addr    string
+	from    string
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+// NewSMTPNotifier sends from the address through the mail server at addr, auth may be nil
+func NewSMTPNotifier(addr, from string, auth smtp.Auth) (*SMTPNotifier, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil || from == "" {
+		return nil, fmt.Errorf("SMTPNotifier: invalid address %q or sender %q", addr, from)
+	}
+	return &SMTPNotifier{addr: addr, from: from, auth: auth, timeout: DefaultTimeout}, nil
+}
+
+func (n *SMTPNotifier) Channel() model.NotificationChannel {
+	return model.ChannelEmail
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, reminder model.Reminder, preferences model.NotificationPreferences) error {
+	to := reminder.Recipient.Email
+	if to == "" {
+		return fmt.Errorf("user(%v) has no email", reminder.Recipient.Id)
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to the mail server: %w", err)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	host, _, _ := net.SplitHostPort(n.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("couldn't greet the mail server: %w", err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("couldn't start tls: %w", err)
+		}
+	}
+	if n.auth != nil {
+		if err := client.Auth(n.auth); err != nil {
+			return fmt.Errorf("couldn't authenticate: %w", err)
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.message(reminder, to)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds the email, the subject is encoded so task names can't break the headers
+func (n *SMTPNotifier) message(reminder model.Reminder, to string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject(reminder)))
+	fmt.Fprintf(&b, "Date: %s\r\n", reminder.At.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(body(reminder))
+	return b.Bytes()
+}
+
+// WebhookNotifier POSTs reminders as JSON to the webhook urls of users
+type WebhookNotifier struct {
+	client *http.Client
+	// secret signs reminders the way webhook deliveries are signed, empty leaves them unsigned
+	secret string
+}
+
+func NewWebhookNotifier(client *http.Client, secret string) (*WebhookNotifier, error) {
+	if client == nil {
+		return nil, errors.New("nil values in WebhookNotifier constructor")
+	}
+	return &WebhookNotifier{client: client, secret: secret}, nil
+}
+
+func (n *WebhookNotifier) Channel() model.NotificationChannel {
+	return model.ChannelWebhook
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, reminder model.Reminder, preferences model.NotificationPreferences) error {
+	if preferences.WebhookURL == "" {
+		return fmt.Errorf("user(%v) has no webhook url", reminder.Recipient.Id)
+	}
+	payload, err := json.Marshal(mapper.ReminderToReminderPayload(reminder))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, preferences.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(reminder.At.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "task-manager-reminders")
+	req.Header.Set(webhook.HeaderEvent, "reminder."+string(reminder.Rule))
+	req.Header.Set(webhook.HeaderTimestamp, timestamp)
+	if n.secret != "" {
+		req.Header.Set(webhook.HeaderSignature, webhook.Sign(n.secret, timestamp, payload))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// subject is a one line summary of the reminder
+func subject(reminder model.Reminder) string {
+	name := strings.Join(strings.Fields(reminder.Task.Name), " ")
+	switch reminder.Rule {
+	case model.ReminderDueSoon:
+		return fmt.Sprintf("Task %q is due in %v", name, reminder.Anchor.Sub(reminder.At).Round(time.Minute))
+	case model.ReminderOverdue:
+		return fmt.Sprintf("Task %q is overdue", name)
+	case model.ReminderStale:
+		return fmt.Sprintf("Task %q is in progress for %v", name, reminder.At.Sub(reminder.Anchor).Round(time.Hour))
+	}
+	return fmt.Sprintf("Reminder about task %q", name)
+}
+
+func body(reminder model.Reminder) string {
+	task := reminder.Task
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hello %s,\r\n\r\n%s.\r\n\r\n", reminder.Recipient.Name, subject(reminder))
+	fmt.Fprintf(&b, "Task: %v (project %v)\r\n", task.Id, task.ProjectID)
+	fmt.Fprintf(&b, "Status: %s\r\n", task.Status)
+	if !task.DueAt.IsZero() {
+		fmt.Fprintf(&b, "Due: %s\r\n", task.DueAt.UTC().Format(time.RFC3339))
+	}
+	if !task.StartedAt.IsZero() {
+		fmt.Fprintf(&b, "Started: %s\r\n", task.StartedAt.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package reminder
+
+import (
+	"ivanjabrony/test_lo/internal/model"
+	"time"
+)
+
+// Rule decides which tasks need a reminder
+type Rule interface {
+	Kind() model.ReminderRule
+	// Match reports whether the task needs a reminder at now, and returns the moment the reminder is about.
+	// A reminder is sent once per moment, so moving the due date or restarting the work sends it again.
+	Match(task model.Task, now time.Time) (time.Time, bool)
+}
+
+// DefaultRules remind of tasks due within a day, overdue tasks and tasks in progress for more than 3 days
+func DefaultRules() []Rule {
+	return []Rule{DueSoon(24 * time.Hour), Overdue(), Stale(72 * time.Hour)}
+}
+
+type dueSoon struct {
+	window time.Duration
+}
+
+// DueSoon reminds of unfinished tasks due within the window
+func DueSoon(window time.Duration) Rule {
+	return dueSoon{window}
+}
+
+func (r dueSoon) Kind() model.ReminderRule {
+	return model.ReminderDueSoon
+}
+
+func (r dueSoon) Match(task model.Task, now time.Time) (time.Time, bool) {
+	if task.Status == model.Done || task.DueAt.IsZero() {
+		return time.Time{}, false
+	}
+	return task.DueAt, !task.DueAt.Before(now) && !task.DueAt.After(now.Add(r.window))
+}
+
+type overdue struct{}
+
+// Overdue reminds of unfinished tasks past their due date
+func Overdue() Rule {
+	return overdue{}
+}
+
+func (r overdue) Kind() model.ReminderRule {
+	return model.ReminderOverdue
+}
+
+func (r overdue) Match(task model.Task, now time.Time) (time.Time, bool) {
+	return task.DueAt, task.IsOverdue(now)
+}
+
+type stale struct {
+	after time.Duration
+}
+
+// Stale reminds of tasks in progress for longer than after
+func Stale(after time.Duration) Rule {
+	return stale{after}
+}
+
+func (r stale) Kind() model.ReminderRule {
+	return model.ReminderStale
+}
+
+func (r stale) Match(task model.Task, now time.Time) (time.Time, bool) {
+	if task.Status != model.InProgress || task.StartedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return task.StartedAt, now.Sub(task.StartedAt) > r.after
+}
@@ -0,0 +1,260 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/recurrence"
+	"ivanjabrony/test_lo/internal/tenant"
+	"sync"
+	"time"
+)
+
+const schedulerName = "RecurrenceScheduler"
+
+// DefaultInterval is the delay between checks of recurring tasks, occurrences are created up to it late
+const DefaultInterval = 30 * time.Second
+
+// principal is the caller of the usecases, it acts in every project
+var principal = auth.Principal{Subject: "scheduler", Roles: []string{string(auth.RoleAdmin)}}
+
+type Logger interface {
+	Log(format string, info ...any)
+}
+
+type TaskUsecase interface {
+	Store(ctx context.Context, request dto.PostTaskRequest) (int, error)
+	GetAll(ctx context.Context, filter model.Filter) (dto.GetAllTasksResponse, error)
+	Update(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error)
+}
+
+type ProjectUsecase interface {
+	GetAll(ctx context.Context) (dto.GetAllProjectsResponse, error)
+}
+
+// Scheduler creates the occurrences of recurring tasks.
+//
+// A series of occurrences is led by its latest task, the only one holding the recurrence. The next
+// occurrence is created through TaskUsecase when the latest one is done or when the time of the next one
+// arrives, whichever is first, and the recurrence moves to it. Occurrences missed while the server was down
+// collapse into one, due at the latest missed time. A series ends when its rule has no more occurrences,
+// or when its latest task is deleted or loses the recurrence. Name, description, priority, assignee,
+// reporter and parent are carried over to the next occurrence, tags and blockers aren't.
+type Scheduler struct {
+	logger   Logger
+	tasks    TaskUsecase
+	projects ProjectUsecase
+	interval time.Duration
+	now      func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	start  sync.Once
+}
+
+// SchedulerOption configures optional behaviour of Scheduler
+type SchedulerOption func(*Scheduler)
+
+// WithInterval sets the delay between checks of recurring tasks
+func WithInterval(interval time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.interval = interval
+	}
+}
+
+// WithClock replaces the clock deciding which occurrences are due, tests use it to move through time
+func WithClock(now func() time.Time) SchedulerOption {
+	return func(s *Scheduler) {
+		s.now = now
+	}
+}
+
+func NewScheduler(logger Logger, tasks TaskUsecase, projects ProjectUsecase, opts ...SchedulerOption) (*Scheduler, error) {
+	if logger == nil || tasks == nil || projects == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", schedulerName)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		logger:   logger,
+		tasks:    tasks,
+		projects: projects,
+		interval: DefaultInterval,
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.interval <= 0 || s.now == nil {
+		cancel()
+		return nil, fmt.Errorf("%v: invalid interval %v", schedulerName, s.interval)
+	}
+
+	logger.Log("Created %s successfully", schedulerName)
+	return s, nil
+}
+
+// Start checks recurring tasks right away, catching up after downtime, and then every interval.
+// Starting again does nothing.
+func (s *Scheduler) Start() {
+	s.start.Do(func() {
+		s.wg.Add(1)
+		go s.run()
+	})
+}
+
+// Stop stops the checks and waits for the running one until ctx is done
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%v: stopping: %w", schedulerName, ctx.Err())
+	}
+}
+
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.tick(s.ctx)
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick advances every series of every project
+func (s *Scheduler) tick(ctx context.Context) {
+	ctx = auth.WithPrincipal(ctx, principal)
+	projects, err := s.projects.GetAll(ctx)
+	if err != nil {
+		s.logger.Log("error in %v: couldn't get projects: %v", schedulerName, err)
+		return
+	}
+	now := s.now()
+	for _, project := range projects.Projects {
+		if ctx.Err() != nil {
+			return
+		}
+		scoped := tenant.WithScope(ctx, tenant.ForProject(project))
+		heads, err := s.tasks.GetAll(scoped, model.Filter{Recurring: true})
+		if err != nil {
+			s.logger.Log("error in %v: couldn't get recurring tasks of project(%v): %v", schedulerName, project.Id, err)
+			continue
+		}
+		for _, head := range heads.Tasks {
+			if err := s.advance(scoped, head, heads.Tasks, now); err != nil {
+				s.logger.Log("error in %v: task(%v) of project(%v): %v", schedulerName, head.Id, project.Id, err)
+			}
+		}
+	}
+}
+
+// advance creates the next occurrence of the series led by head if it's time to
+func (s *Scheduler) advance(ctx context.Context, head model.Task, heads []model.Task, now time.Time) error {
+	schedule, err := head.Recurrence.Schedule()
+	if err != nil {
+		return fmt.Errorf("couldn't parse the recurrence: %w", err)
+	}
+	dueAt := head.DueAt
+	if dueAt.IsZero() {
+		// the due date was cleared, the series goes on from now
+		dueAt = now
+	}
+	next := schedule.Next(dueAt)
+	if !next.IsZero() && head.Status != model.Done && now.Before(next) {
+		return nil
+	}
+	if next.IsZero() {
+		s.logger.Log("%v: series of task(%v) has ended", schedulerName, head.Id)
+		return s.retire(ctx, head)
+	}
+
+	next, missed := latestDue(schedule, next, now)
+	if missed > 0 {
+		s.logger.Log("%v: %v missed occurrences of task(%v) are skipped", schedulerName, missed, head.Id)
+	}
+	if created, ok := findOccurrence(heads, head, next); ok {
+		// the occurrence was created before the recurrence could move to it
+		s.logger.Log("%v: occurrence of task(%v) due at %v already exists as task(%v)", schedulerName, head.Id, next, created.Id)
+		return s.retire(ctx, head)
+	}
+
+	id, err := s.tasks.Store(ctx, dto.PostTaskRequest{
+		Status:       model.Created,
+		Name:         head.Name,
+		Description:  head.Description,
+		Priority:     head.Priority,
+		AssigneeID:   head.AssigneeID,
+		ReporterID:   head.ReporterID,
+		ParentID:     parentRef(head),
+		DueAt:        next,
+		AllowPastDue: true,
+		Recurrence: &dto.RecurrenceRequest{
+			Rule:     head.Recurrence.Rule,
+			TimeZone: head.Recurrence.TimeZone,
+			Start:    head.Recurrence.Start,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create the next occurrence: %w", err)
+	}
+	s.logger.Log("%v: created task(%v) due at %v after task(%v)", schedulerName, id, next, head.Id)
+	return s.retire(ctx, head)
+}
+
+// retire clears the recurrence of the task, the series has moved on or ended
+func (s *Scheduler) retire(ctx context.Context, head model.Task) error {
+	if _, err := s.tasks.Update(ctx, head.Id, dto.PatchTaskRequest{ClearRecurrence: true}); err != nil {
+		return fmt.Errorf("couldn't clear the recurrence: %w", err)
+	}
+	return nil
+}
+
+// latestDue returns the latest occurrence from next up to now, or next if it's in the future,
+// and an amount of occurrences skipped on the way
+func latestDue(schedule recurrence.Schedule, next, now time.Time) (time.Time, int) {
+	missed := 0
+	for {
+		later := schedule.Next(next)
+		if later.IsZero() || later.After(now) {
+			return next, missed
+		}
+		next = later
+		missed++
+	}
+}
+
+// findOccurrence finds another latest occurrence of the same series due at the time
+func findOccurrence(heads []model.Task, head model.Task, dueAt time.Time) (model.Task, bool) {
+	for _, task := range heads {
+		if task.Id != head.Id && task.Name == head.Name && task.Recurrence.Rule == head.Recurrence.Rule &&
+			task.Recurrence.Start.Equal(head.Recurrence.Start) && task.DueAt.Equal(dueAt) {
+			return task, true
+		}
+	}
+	return model.Task{}, false
+}
+
+// parentRef refers to the parent of the task by both ids, so it's found with and without public ids
+func parentRef(task model.Task) *dto.TaskRef {
+	if task.ParentID == nil {
+		return nil
+	}
+	return &dto.TaskRef{Id: *task.ParentID, PublicID: task.PublicParentID}
+}
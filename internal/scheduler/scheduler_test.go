@@ -0,0 +1,244 @@
+package scheduler
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/storage"
+	"ivanjabrony/test_lo/internal/usecase"
+	"sync"
+	"testing"
+	"time"
+)
+
+type MockLogger struct{}
+
+func (m *MockLogger) Log(format string, info ...any) {}
+
+var admin = auth.Principal{Subject: "admin", Roles: []string{string(auth.RoleAdmin)}}
+
+// fixture is a scheduler over in-memory storages with a clock the test moves
+type fixture struct {
+	scheduler *Scheduler
+	tasks     *usecase.TaskUsecase
+	ctx       context.Context
+
+	m   sync.Mutex
+	now time.Time
+}
+
+func newFixture(t *testing.T, now time.Time) *fixture {
+	t.Helper()
+	logger := &MockLogger{}
+	taskStorage, _ := storage.NewTaskStorage(logger)
+	projectStorage, _ := storage.NewProjectStorage(logger, model.NoQuota)
+	policy := auth.NewPolicy(auth.DefaultRules, false)
+	tasks, _ := usecase.NewTaskUsecase(logger, taskStorage, usecase.WithPolicy(policy))
+	projects, _ := usecase.NewProjectUsecase(logger, projectStorage, usecase.WithProjectPolicy(policy))
+
+	f := &fixture{tasks: tasks, ctx: auth.WithPrincipal(context.Background(), admin), now: now}
+	scheduler, err := NewScheduler(logger, tasks, projects, WithClock(f.clock), WithInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.scheduler = scheduler
+	return f
+}
+
+func (f *fixture) clock() time.Time {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.now
+}
+
+// tickAt moves the clock and runs a check
+func (f *fixture) tickAt(now time.Time) {
+	f.m.Lock()
+	f.now = now
+	f.m.Unlock()
+	f.scheduler.tick(context.Background())
+}
+
+func (f *fixture) store(t *testing.T, dueAt time.Time, recurrence dto.RecurrenceRequest) int {
+	t.Helper()
+	id, err := f.tasks.Store(f.ctx, dto.PostTaskRequest{
+		Name:         "rotate on-call",
+		Status:       model.Created,
+		AssigneeID:   model.NoUser,
+		DueAt:        dueAt,
+		AllowPastDue: true,
+		Recurrence:   &recurrence,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return id
+}
+
+func (f *fixture) all(t *testing.T) []model.Task {
+	t.Helper()
+	response, err := f.tasks.GetAll(f.ctx, model.Filter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return response.Tasks
+}
+
+// expectSeries checks due dates of all the tasks in order and that only the last one recurs
+func (f *fixture) expectSeries(t *testing.T, dueDates ...time.Time) {
+	t.Helper()
+	tasks := f.all(t)
+	if len(tasks) != len(dueDates) {
+		t.Fatalf("Expected %v tasks, got %+v", len(dueDates), tasks)
+	}
+	for i, task := range tasks {
+		if !task.DueAt.Equal(dueDates[i]) {
+			t.Errorf("Expected task(%v) due at %v, got %v", task.Id, dueDates[i], task.DueAt.UTC())
+		}
+		if latest := i == len(tasks)-1; latest != (task.Recurrence != nil) {
+			t.Errorf("Expected recurrence of task(%v) only if it's the latest, got %+v", task.Id, task.Recurrence)
+		}
+	}
+}
+
+func utc(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestNewScheduler(t *testing.T) {
+	logger := &MockLogger{}
+	tasks, _ := usecase.NewTaskUsecase(logger, &storage.TaskStorage{})
+	projects, _ := usecase.NewProjectUsecase(logger, &storage.ProjectStorage{})
+
+	if _, err := NewScheduler(logger, nil, projects); err == nil {
+		t.Errorf("Expected error for nil tasks")
+	}
+	if _, err := NewScheduler(logger, tasks, projects, WithInterval(0)); err == nil {
+		t.Errorf("Expected error for zero interval")
+	}
+	if _, err := NewScheduler(logger, tasks, projects); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSchedulerTimeArrives(t *testing.T) {
+	// 9:00 in Berlin is 8:00 UTC in winter and 7:00 UTC after the clocks move on 2026-03-29
+	first := utc(2026, 3, 27, 8, 0)
+	f := newFixture(t, first)
+	f.store(t, first, dto.RecurrenceRequest{Rule: "0 9 * * *", TimeZone: "Europe/Berlin"})
+
+	f.tickAt(utc(2026, 3, 27, 10, 0))
+	f.expectSeries(t, first)
+
+	f.tickAt(utc(2026, 3, 28, 8, 0))
+	f.expectSeries(t, first, utc(2026, 3, 28, 8, 0))
+
+	f.tickAt(utc(2026, 3, 29, 7, 30))
+	f.expectSeries(t, first, utc(2026, 3, 28, 8, 0), utc(2026, 3, 29, 7, 0))
+
+	tasks := f.all(t)
+	if latest := tasks[2]; latest.Name != "rotate on-call" || latest.Status != model.Created || latest.Recurrence.TimeZone != "Europe/Berlin" {
+		t.Errorf("Unexpected occurrence %+v", latest)
+	}
+}
+
+func TestSchedulerCompletion(t *testing.T) {
+	first := utc(2026, 10, 19, 9, 0)
+	f := newFixture(t, utc(2026, 10, 16, 12, 0))
+	id := f.store(t, first, dto.RecurrenceRequest{Rule: "FREQ=WEEKLY;BYDAY=MO"})
+
+	f.tickAt(utc(2026, 10, 16, 12, 0))
+	f.expectSeries(t, first)
+
+	done := model.Done
+	if _, err := f.tasks.Update(f.ctx, id, dto.PatchTaskRequest{Status: &done}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.tickAt(utc(2026, 10, 16, 13, 0))
+	f.expectSeries(t, first, utc(2026, 10, 26, 9, 0))
+
+	// the new occurrence waits for its own time
+	f.tickAt(utc(2026, 10, 20, 13, 0))
+	f.expectSeries(t, first, utc(2026, 10, 26, 9, 0))
+}
+
+func TestSchedulerMissedRuns(t *testing.T) {
+	first := utc(2026, 10, 1, 9, 0)
+	f := newFixture(t, first)
+	f.store(t, first, dto.RecurrenceRequest{Rule: "0 9 * * *"})
+
+	// the server was down for more than a week
+	f.tickAt(utc(2026, 10, 10, 12, 0))
+	f.expectSeries(t, first, utc(2026, 10, 10, 9, 0))
+
+	f.tickAt(utc(2026, 10, 10, 12, 30))
+	f.expectSeries(t, first, utc(2026, 10, 10, 9, 0))
+
+	f.tickAt(utc(2026, 10, 11, 9, 0))
+	f.expectSeries(t, first, utc(2026, 10, 10, 9, 0), utc(2026, 10, 11, 9, 0))
+}
+
+func TestSchedulerSeriesEnds(t *testing.T) {
+	first := utc(2026, 10, 1, 9, 0)
+	f := newFixture(t, first)
+	f.store(t, first, dto.RecurrenceRequest{Rule: "FREQ=DAILY;COUNT=2"})
+
+	f.tickAt(utc(2026, 10, 2, 9, 0))
+	f.expectSeries(t, first, utc(2026, 10, 2, 9, 0))
+
+	f.tickAt(utc(2026, 10, 5, 9, 0))
+	tasks := f.all(t)
+	if len(tasks) != 2 || tasks[0].Recurrence != nil || tasks[1].Recurrence != nil {
+		t.Errorf("Expected the series to end after 2 tasks, got %+v", tasks)
+	}
+}
+
+func TestSchedulerExistingOccurrence(t *testing.T) {
+	first := utc(2026, 10, 1, 9, 0)
+	f := newFixture(t, first)
+	recurrence := dto.RecurrenceRequest{Rule: "0 9 * * *", Start: first}
+	f.store(t, first, recurrence)
+	// created by a check that stopped before the recurrence moved
+	f.store(t, utc(2026, 10, 2, 9, 0), recurrence)
+
+	f.tickAt(utc(2026, 10, 2, 10, 0))
+	f.expectSeries(t, first, utc(2026, 10, 2, 9, 0))
+}
+
+func TestSchedulerDeletedHead(t *testing.T) {
+	first := utc(2026, 10, 1, 9, 0)
+	f := newFixture(t, first)
+	id := f.store(t, first, dto.RecurrenceRequest{Rule: "0 9 * * *"})
+	if err := f.tasks.Delete(f.ctx, id); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	f.tickAt(utc(2026, 10, 2, 10, 0))
+	if tasks := f.all(t); len(tasks) != 0 {
+		t.Errorf("Expected no tasks, got %+v", tasks)
+	}
+}
+
+func TestSchedulerStartStop(t *testing.T) {
+	first := utc(2026, 10, 1, 9, 0)
+	f := newFixture(t, utc(2026, 10, 1, 12, 0))
+	f.store(t, first, dto.RecurrenceRequest{Rule: "@hourly"})
+
+	f.scheduler.Start()
+	f.scheduler.Start()
+	deadline := time.Now().Add(time.Second)
+	for len(f.all(t)) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the missed occurrence to be created on start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := f.scheduler.Stop(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.expectSeries(t, first, utc(2026, 10, 1, 12, 0))
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	taskv1 "ivanjabrony/test_lo/api/task/v1"
+	"ivanjabrony/test_lo/internal/config"
+	"ivanjabrony/test_lo/internal/handler"
+	"ivanjabrony/test_lo/internal/middleware"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Services are the services served by the gRPC server
+type Services struct {
+	Task *handler.TaskService
+}
+
+// GRPCServer is a gRPC server that ends streams on shutdown, so they don't hold it up
+type GRPCServer struct {
+	*grpc.Server
+	// streams is the context every stream is canceled with on shutdown
+	streams context.Context
+	cancel  context.CancelFunc
+}
+
+// NewGRPC creates the gRPC server, it authenticates calls like the http server does.
+// Reflection is enabled, so tools like grpcurl can list the services.
+func NewGRPC(cfg *config.Config, logger Logger, services Services) (*GRPCServer, error) {
+	streams, cancel := context.WithCancel(context.Background())
+	s := &GRPCServer{streams: streams, cancel: cancel}
+
+	lm := middleware.NewLoggerMiddleware(logger)
+	rm := middleware.NewRequestIDMiddleware()
+	unary := []grpc.UnaryServerInterceptor{lm.UnaryLogging(), rm.UnaryAssignID()}
+	stream := []grpc.StreamServerInterceptor{lm.StreamLogging(), rm.StreamAssignID(), s.endOnShutdown}
+
+	if cfg.AuthEnabled {
+		authenticator, err := NewAuthenticator(cfg)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("couldn't configure authentication: %w", err)
+		}
+		am := middleware.NewAuthMiddleware(logger, authenticator)
+		unary = append(unary, am.UnaryAuthenticate())
+		stream = append(stream, am.StreamAuthenticate())
+	} else {
+		logger.Log("Authentication is disabled, every gRPC method is public")
+	}
+
+	s.Server = grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	taskv1.RegisterTaskServiceServer(s.Server, services.Task)
+	reflection.Register(s.Server)
+	return s, nil
+}
+
+// Shutdown ends streams and waits for the other calls to finish, when ctx is done first they are canceled too
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	s.cancel()
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		return ctx.Err()
+	}
+}
+
+// endOnShutdown cancels the context of the stream on shutdown
+func (s *GRPCServer) endOnShutdown(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, cancel := context.WithCancel(ss.Context())
+	defer cancel()
+	stop := context.AfterFunc(s.streams, cancel)
+	defer stop()
+	return handler(srv, &serverStream{ss, ctx})
+}
+
+// serverStream replaces the context of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	taskv1 "ivanjabrony/test_lo/api/task/v1"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/config"
+	"ivanjabrony/test_lo/internal/requestid"
+	"ivanjabrony/test_lo/internal/storage"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestGRPC serves the gRPC api over an in memory listener and returns a client of it
+func newTestGRPC(t *testing.T, cfg *config.Config) (taskv1.TaskServiceClient, *GRPCServer) {
+	t.Helper()
+	taskStorage, _ := storage.NewTaskStorage(&MockLogger{}, storage.WithStrictCompletion(true))
+	_, services := newTestHandlers(t, taskStorage)
+	srv, err := NewGRPC(cfg, &MockLogger{}, services)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return taskv1.NewTaskServiceClient(conn), srv
+}
+
+func TestGRPCTaskService(t *testing.T) {
+	client, _ := newTestGRPC(t, &config.Config{AuthEnabled: false})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	watch, err := client.Watch(ctx, &taskv1.WatchTasksRequest{Filter: &taskv1.TaskFilter{Status: "done"}})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	var header metadata.MD
+	created, err := client.Create(ctx, &taskv1.CreateTaskRequest{Name: "first", Status: "created", Description: "over grpc"}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.Name != "first" || created.Description != "over grpc" || created.Priority == "" || created.CreatedAt == nil {
+		t.Errorf("Unexpected task %v", created)
+	}
+	if ids := header.Get(requestid.Header); len(ids) != 1 || !requestid.IsValid(ids[0]) {
+		t.Errorf("Expected a request id in the header, got %v", header)
+	}
+	for _, name := range []string{"second", "third"} {
+		client.Create(ctx, &taskv1.CreateTaskRequest{Name: name, Status: "created"})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		first, err := client.List(ctx, &taskv1.ListTasksRequest{PageSize: 2})
+		if err != nil || len(first.Tasks) != 2 || first.Total != 3 || first.NextPageToken == "" {
+			t.Fatalf("Unexpected first page %v, %v", first, err)
+		}
+		last, err := client.List(ctx, &taskv1.ListTasksRequest{PageSize: 2, PageToken: first.NextPageToken})
+		if err != nil || len(last.Tasks) != 1 || last.Tasks[0].Name != "third" || last.NextPageToken != "" {
+			t.Errorf("Unexpected last page %v, %v", last, err)
+		}
+	})
+
+	t.Run("update is streamed", func(t *testing.T) {
+		name := "renamed"
+		done := "done"
+		updated, err := client.Update(ctx, &taskv1.UpdateTaskRequest{Id: created.Id, Name: &name, Status: &done})
+		if err != nil || updated.Name != "renamed" || updated.Description != "over grpc" || updated.CompletedAt == nil {
+			t.Fatalf("Unexpected update %v, %v", updated, err)
+		}
+
+		event, err := watch.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if event.Type != "updated" || event.Id == 0 || event.Task.Name != "renamed" || event.Task.Status != "done" {
+			t.Errorf("Unexpected event %v", event)
+		}
+	})
+
+	t.Run("domain errors", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			call         func() error
+			expectedCode codes.Code
+		}{
+			{name: "invalid task", call: func() error {
+				_, err := client.Create(ctx, &taskv1.CreateTaskRequest{Name: "x", Status: "archived"})
+				return err
+			}, expectedCode: codes.InvalidArgument},
+			{name: "missing task", call: func() error {
+				_, err := client.Get(ctx, &taskv1.GetTaskRequest{Id: 42})
+				return err
+			}, expectedCode: codes.NotFound},
+			{name: "missing project", call: func() error {
+				_, err := client.List(ctx, &taskv1.ListTasksRequest{ProjectId: 9})
+				return err
+			}, expectedCode: codes.NotFound},
+			{name: "point in time reads need the events storage", call: func() error {
+				_, err := client.Get(ctx, &taskv1.GetTaskRequest{Id: created.Id, AsOf: created.CreatedAt})
+				return err
+			}, expectedCode: codes.Unimplemented},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if code := status.Code(tt.call()); code != tt.expectedCode {
+					t.Errorf("Expected code %v, got %v", tt.expectedCode, code)
+				}
+			})
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if _, err := client.Delete(ctx, &taskv1.DeleteTaskRequest{Id: created.Id}); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := client.Get(ctx, &taskv1.GetTaskRequest{Id: created.Id}); status.Code(err) != codes.NotFound {
+			t.Errorf("Expected NotFound after delete, got %v", err)
+		}
+	})
+}
+
+func TestGRPCAuthentication(t *testing.T) {
+	client, srv := newTestGRPC(t, &config.Config{AuthEnabled: true, APIKeys: "ci:5:admin:" + auth.HashAPIKey("secret")})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.List(ctx, &taskv1.ListTasksRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without credentials, got %v", err)
+	}
+	if _, err := client.List(metadata.AppendToOutgoingContext(ctx, "authorization", "ApiKey wrong"), &taskv1.ListTasksRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated with a wrong key, got %v", err)
+	}
+
+	authorized := metadata.AppendToOutgoingContext(ctx, "x-api-key", "secret")
+	if _, err := client.List(authorized, &taskv1.ListTasksRequest{}); err != nil {
+		t.Errorf("Expected the call to be authenticated, got %v", err)
+	}
+	watch, err := client.Watch(authorized, &taskv1.WatchTasksRequest{})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	// shutdown ends open streams instead of waiting for them
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer shutdownCancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		t.Errorf("Expected the server to stop gracefully, got %v", err)
+	}
+	if _, err := watch.Recv(); err == nil {
+		t.Errorf("Expected the stream to end, got %v", err)
+	}
+}
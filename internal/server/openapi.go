@@ -0,0 +1,334 @@
+package server
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/openapi"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// projectPrefix scopes routes to a project, routes under it are documented once without it
+const projectPrefix = "/projects/{project_id}"
+
+//go:embed docs.html
+var docsPage []byte
+
+// router registers routes on the mux and collects the documentation of each of them,
+// registering a route missing from endpointDocs is an error
+type router struct {
+	mux       *http.ServeMux
+	endpoints []openapi.Endpoint
+	errs      []error
+}
+
+func newRouter() *router {
+	return &router{mux: http.NewServeMux()}
+}
+
+func (rt *router) HandleFunc(pattern string, h http.HandlerFunc) {
+	rt.mux.HandleFunc(pattern, h)
+
+	method, path, _ := strings.Cut(pattern, " ")
+	endpoint, ok := endpointDocs[pattern]
+	if !ok {
+		unscoped, scoped := strings.CutPrefix(path, projectPrefix)
+		if endpoint, ok = endpointDocs[method+" "+unscoped]; !ok || !scoped {
+			rt.errs = append(rt.errs, fmt.Errorf("route %v isn't documented", pattern))
+			return
+		}
+		endpoint.OperationID += "InProject"
+		endpoint.Params = append(slices.Clip(endpoint.Params), openapi.Param{
+			Name: "project_id", In: "path", Description: "id of the project the route is limited to", Schema: openapi.Integer(),
+		})
+	}
+	endpoint.Method, endpoint.Path = method, path
+	rt.endpoints = append(rt.endpoints, endpoint)
+}
+
+// document generates the OpenAPI document of the registered routes
+func (rt *router) document() (*openapi.Document, error) {
+	if err := errors.Join(rt.errs...); err != nil {
+		return nil, err
+	}
+	return openapi.Generate(
+		openapi.Info{
+			Title:       "Task manager",
+			Version:     "1.0.0",
+			Description: "Routes under /projects/{project_id} are limited to the project, the other task routes use the default project.",
+		},
+		rt.endpoints,
+		openapi.WithEnum(model.Created, model.InProgress, model.Done),
+		openapi.WithEnum(model.PriorityLow, model.PriorityMedium, model.PriorityHigh, model.PriorityUrgent),
+		openapi.WithEnum(model.TaskChangeCreated, model.TaskChangeUpdated, model.TaskChangeDeleted),
+		openapi.WithEnum(model.AuditCreate, model.AuditUpdate, model.AuditStatusChange, model.AuditDelete),
+		openapi.WithEnum(model.WebhookTaskCreated, model.WebhookTaskUpdated, model.WebhookTaskCompleted, model.WebhookTaskDeleted),
+		openapi.WithEnum(model.DeliverySucceeded, model.DeliveryFailed, model.DeliveryDead),
+		openapi.WithSchema[dto.TaskRef](taskId),
+		openapi.WithPathParam("task_id", taskId),
+		openapi.WithPathParam("blocker_id", taskId),
+		openapi.WithProperty[model.Task]("id", taskId),
+		openapi.WithProperty[model.Task]("parent_id", nullableTaskId),
+		openapi.WithProperty[model.Task]("blocked_by", taskIds),
+		openapi.WithProperty[dto.GetTaskByIdResponse]("id", taskId),
+		openapi.WithProperty[dto.GetTaskByIdResponse]("parent_id", nullableTaskId),
+		openapi.WithProperty[dto.GetTaskByIdResponse]("blocked_by", taskIds),
+		openapi.WithProperty[model.AuditEntry]("task_id", taskId),
+		openapi.WithErrorResponse(dto.ErrorResponse{}),
+		openapi.WithSecurity("apiKey", &openapi.SecurityScheme{Type: "apiKey", Name: auth.APIKeyHeader, In: "header"}),
+		openapi.WithSecurity("bearer", &openapi.SecurityScheme{Type: "http", Scheme: "bearer", Description: "HS256 or RS256 signed jwt"}),
+	)
+}
+
+var (
+	// taskId describes ids of tasks, they are the public ids once tasks are given them, see model.Task.PublicIds
+	taskId         = &openapi.Schema{Type: []string{"integer", "string"}, Description: "id of the task, its public id when tasks have them"}
+	nullableTaskId = &openapi.Schema{Type: []string{"integer", "string", "null"}, Description: taskId.Description}
+	taskIds        = &openapi.Schema{Type: []string{"array"}, Items: taskId}
+
+	taskFilterParams = []openapi.Param{
+		openapi.Query("status", openapi.Enum(model.Created, model.InProgress, model.Done), ""),
+		openapi.Query("assignee", openapi.Integer(), "id of the assignee"),
+		openapi.Query("priority", openapi.Enum(model.PriorityLow, model.PriorityMedium, model.PriorityHigh, model.PriorityUrgent), ""),
+		openapi.Query("overdue", openapi.Boolean(), "only tasks past their due date that aren't done"),
+		openapi.Query("recurring", openapi.Boolean(), "only the latest occurrences of recurring tasks"),
+		openapi.Query("due_before", openapi.DateTime(), ""),
+		openapi.Query("due_after", openapi.DateTime(), ""),
+		openapi.Query("created_before", openapi.DateTime(), ""),
+		openapi.Query("created_after", openapi.DateTime(), ""),
+		openapi.Query("tags", openapi.String(), "comma separated tag names"),
+		openapi.Query("tag_match", openapi.Enum(model.TagMatchAny, model.TagMatchAll), "whether tasks need any or all of the tags, any by default"),
+		openapi.Query("sort", openapi.Enum(
+			"id", "-id", "due_at", "-due_at", "priority", "-priority",
+			"created_at", "-created_at", "started_at", "-started_at", "completed_at", "-completed_at",
+		), "field to order by, a leading - orders descending"),
+	}
+	pageParams = []openapi.Param{
+		openapi.Query("limit", openapi.Integer(), "page size"),
+		openapi.Query("offset", openapi.Integer(), "number of skipped items"),
+	}
+	auditParams = append([]openapi.Param{
+		openapi.Query("project_id", openapi.Integer(), ""),
+		openapi.Query("task_id", taskId, ""),
+		openapi.Query("actor_id", openapi.Integer(), ""),
+		openapi.Query("action", openapi.Enum(model.AuditCreate, model.AuditUpdate, model.AuditStatusChange, model.AuditDelete), ""),
+		openapi.Query("from", openapi.DateTime(), ""),
+		openapi.Query("to", openapi.DateTime(), ""),
+	}, pageParams...)
+)
+
+// endpointDocs documents every route of NewHTTP by its pattern, project scoped routes are found without projectPrefix
+var endpointDocs = map[string]openapi.Endpoint{
+	"GET /tasks": {
+		OperationID: "listTasks", Summary: "List tasks matching the filter", Tag: "tasks",
+		Description: "Every matching task is listed unless limit or offset ask for a page of them.",
+		Params:      slices.Concat(taskFilterParams, pageParams), Response: dto.GetAllTasksResponse{},
+	},
+	"GET /tasks/{task_id}": {
+		OperationID: "getTask", Summary: "Get a task", Tag: "tasks",
+		Params:   []openapi.Param{openapi.Query("as_of", openapi.DateTime(), "returns the task as it was at the time")},
+		Response: dto.GetTaskByIdResponse{},
+	},
+	"POST /tasks": {
+		OperationID: "createTask", Summary: "Create a task", Description: "Responds with the id of the task.", Tag: "tasks",
+		Request: dto.PostTaskRequest{}, Response: taskId,
+	},
+	"PATCH /tasks/{task_id}": {
+		OperationID: "updateTask", Summary: "Update fields of a task", Tag: "tasks",
+		Request: dto.PatchTaskRequest{}, Response: dto.GetTaskByIdResponse{},
+	},
+	"DELETE /tasks/{task_id}": {
+		OperationID: "deleteTask", Summary: "Delete a task", Tag: "tasks", Status: http.StatusNoContent,
+	},
+	"GET /tasks/export": {
+		OperationID: "exportTasks", Summary: "Export tasks matching the filter", Tag: "tasks",
+		Params: append([]openapi.Param{
+			openapi.Query("format", openapi.Enum("json", "csv", "ndjson"), "json by default"),
+		}, taskFilterParams...),
+		Response: []model.Task{}, ResponseTypes: []string{"text/csv", "application/x-ndjson"},
+	},
+	"GET /tasks/events": {
+		OperationID: "streamTaskEvents", Summary: "Stream changes of tasks matching the filter as server-sent events", Tag: "tasks",
+		Params: append([]openapi.Param{
+			{Name: "Last-Event-ID", In: "header", Description: "id of the last received change, the missed ones are sent first", Schema: openapi.Integer()},
+		}, taskFilterParams...),
+		ResponseTypes: []string{"text/event-stream"},
+	},
+	"POST /tasks/import": {
+		OperationID: "importTasks", Summary: "Import tasks from csv or ndjson", Tag: "tasks",
+		Params: []openapi.Param{
+			openapi.Query("format", openapi.Enum("csv", "ndjson"), "taken from Content-Type by default"),
+			openapi.Query("dry_run", openapi.Boolean(), "only validates the tasks"),
+			openapi.Query("map", openapi.String(), "source:target pairs renaming columns, can be repeated"),
+		},
+		RequestTypes: []string{"text/csv", "application/x-ndjson", "application/ndjson", "application/jsonl"}, Response: dto.ImportTasksResponse{},
+	},
+	"GET /tasks/{task_id}/subtasks": {
+		OperationID: "getSubtasks", Summary: "Get a task with its subtasks", Tag: "tasks",
+		Params:   []openapi.Param{openapi.Query("depth", openapi.Integer(), "levels of subtasks to include")},
+		Response: dto.GetSubtasksResponse{},
+	},
+	"GET /tasks/{task_id}/dependencies/order": {
+		OperationID: "getDependencyOrder", Summary: "List the blockers of a task in an order they can be done in", Tag: "tasks",
+		Response: dto.GetDependencyOrderResponse{},
+	},
+	"PUT /tasks/{task_id}/blockers/{blocker_id}": {
+		OperationID: "addBlocker", Summary: "Block a task by another one", Tag: "tasks", Response: dto.GetTaskByIdResponse{},
+	},
+	"DELETE /tasks/{task_id}/blockers/{blocker_id}": {
+		OperationID: "removeBlocker", Summary: "Unblock a task", Tag: "tasks", Response: dto.GetTaskByIdResponse{},
+	},
+	"GET /tasks/{task_id}/history": {
+		OperationID: "getTaskHistory", Summary: "List changes of a task, oldest first", Tag: "audit",
+		Params: pageParams, Response: dto.GetAuditResponse{},
+	},
+
+	"GET /tags": {
+		OperationID: "listTags", Summary: "List tags", Tag: "tags", Response: dto.GetAllTagsResponse{},
+	},
+	"GET /tags/{tag_id}": {
+		OperationID: "getTag", Summary: "Get a tag", Tag: "tags", Response: dto.GetTagByIdResponse{},
+	},
+	"POST /tags": {
+		OperationID: "createTag", Summary: "Create a tag", Description: "Responds with the id of the tag.", Tag: "tags",
+		Request: dto.PostTagRequest{}, Response: 0,
+	},
+	"PUT /tags/{tag_id}": {
+		OperationID: "renameTag", Summary: "Rename a tag", Tag: "tags", Request: dto.PutTagRequest{}, Response: dto.GetTagByIdResponse{},
+	},
+	"DELETE /tags/{tag_id}": {
+		OperationID: "deleteTag", Summary: "Delete a tag", Tag: "tags", Status: http.StatusNoContent,
+	},
+	"PUT /tasks/{task_id}/tags/{tag_id}": {
+		OperationID: "attachTag", Summary: "Attach a tag to a task", Tag: "tags", Response: dto.GetTaskByIdResponse{},
+	},
+	"DELETE /tasks/{task_id}/tags/{tag_id}": {
+		OperationID: "detachTag", Summary: "Detach a tag from a task", Tag: "tags", Response: dto.GetTaskByIdResponse{},
+	},
+
+	"GET /tasks/{task_id}/comments": {
+		OperationID: "listComments", Summary: "List comments of a task", Tag: "comments",
+		Params: pageParams, Response: dto.GetCommentsResponse{},
+	},
+	"POST /tasks/{task_id}/comments": {
+		OperationID: "createComment", Summary: "Comment a task", Description: "Responds with the id of the comment.", Tag: "comments",
+		Request: dto.PostCommentRequest{}, Response: 0,
+	},
+	"PUT /tasks/{task_id}/comments/{comment_id}": {
+		OperationID: "editComment", Summary: "Edit a comment", Tag: "comments",
+		Request: dto.PutCommentRequest{}, Response: dto.GetCommentByIdResponse{},
+	},
+	"DELETE /tasks/{task_id}/comments/{comment_id}": {
+		OperationID: "deleteComment", Summary: "Delete a comment", Tag: "comments", Status: http.StatusNoContent,
+	},
+
+	"GET /ws": {
+		OperationID: "openWebSocket", Summary: "Open a WebSocket for task subscriptions and commands", Tag: "realtime",
+		Status: http.StatusSwitchingProtocols,
+	},
+	"POST /graphql": {
+		OperationID: "queryGraphQL", Summary: "Run a graphql query", Tag: "graphql",
+		Description: "Errors of a valid query are reported in the errors of a 200 response.",
+		Request:     dto.GraphQLRequest{}, Response: dto.GraphQLResponse{},
+	},
+	"GET /graphql/schema": {
+		OperationID: "getGraphQLSchema", Summary: "Get the graphql schema in SDL", Tag: "graphql", ResponseTypes: []string{"text/plain"},
+	},
+
+	"GET /webhooks": {
+		OperationID: "listWebhooks", Summary: "List webhooks", Tag: "webhooks", Response: dto.GetAllWebhooksResponse{},
+	},
+	"GET /webhooks/{webhook_id}": {
+		OperationID: "getWebhook", Summary: "Get a webhook", Tag: "webhooks", Response: dto.GetWebhookByIdResponse{},
+	},
+	"POST /webhooks": {
+		OperationID: "createWebhook", Summary: "Create a webhook", Description: "A secret is generated when none is given.", Tag: "webhooks",
+		Request: dto.PostWebhookRequest{}, Response: dto.PostWebhookResponse{},
+	},
+	"PUT /webhooks/{webhook_id}": {
+		OperationID: "replaceWebhook", Summary: "Replace a webhook", Tag: "webhooks",
+		Request: dto.PutWebhookRequest{}, Response: dto.GetWebhookByIdResponse{},
+	},
+	"DELETE /webhooks/{webhook_id}": {
+		OperationID: "deleteWebhook", Summary: "Delete a webhook", Tag: "webhooks", Status: http.StatusNoContent,
+	},
+	"GET /webhooks/{webhook_id}/deliveries": {
+		OperationID: "listDeliveries", Summary: "List delivery attempts of a webhook", Tag: "webhooks",
+		Params: pageParams, Response: dto.GetWebhookDeliveriesResponse{},
+	},
+	"GET /webhooks/{webhook_id}/dead-letters": {
+		OperationID: "listDeadLetters", Summary: "List deliveries that failed every attempt", Tag: "webhooks",
+		Response: dto.GetDeadLettersResponse{},
+	},
+	"POST /webhooks/{webhook_id}/dead-letters/{delivery_id}/redeliver": {
+		OperationID: "redeliver", Summary: "Deliver a dead letter again", Tag: "webhooks", Status: http.StatusAccepted,
+		Params: []openapi.Param{{Name: "delivery_id", In: "path", Schema: openapi.String()}},
+	},
+
+	"GET /projects": {
+		OperationID: "listProjects", Summary: "List projects", Tag: "projects", Response: dto.GetAllProjectsResponse{},
+	},
+	"GET /projects/{project_id}": {
+		OperationID: "getProject", Summary: "Get a project", Tag: "projects", Response: dto.GetProjectByIdResponse{},
+	},
+	"POST /projects": {
+		OperationID: "createProject", Summary: "Create a project", Description: "Responds with the id of the project.", Tag: "projects",
+		Request: dto.PostProjectRequest{}, Response: 0,
+	},
+	"PATCH /projects/{project_id}": {
+		OperationID: "updateProject", Summary: "Update a project", Tag: "projects",
+		Request: dto.PatchProjectRequest{}, Response: dto.GetProjectByIdResponse{},
+	},
+
+	"GET /users": {
+		OperationID: "listUsers", Summary: "List users", Tag: "users", Response: dto.GetAllUsersResponse{},
+	},
+	"GET /users/{user_id}": {
+		OperationID: "getUser", Summary: "Get a user", Tag: "users", Response: dto.GetUserByIdResponse{},
+	},
+	"POST /users": {
+		OperationID: "createUser", Summary: "Create a user", Description: "Responds with the id of the user.", Tag: "users",
+		Request: dto.PostUserRequest{}, Response: 0,
+	},
+	"PUT /users/{user_id}": {
+		OperationID: "replaceUser", Summary: "Replace a user", Tag: "users", Request: dto.PutUserRequest{}, Response: dto.GetUserByIdResponse{},
+	},
+	"DELETE /users/{user_id}": {
+		OperationID: "deleteUser", Summary: "Delete a user", Tag: "users", Status: http.StatusNoContent,
+	},
+	"GET /users/{user_id}/tasks": {
+		OperationID: "listUserTasks", Summary: "List tasks of a user in the default project", Tag: "users", Response: dto.GetAllTasksResponse{},
+	},
+	"GET /users/{user_id}/notifications": {
+		OperationID: "getNotificationPreferences", Summary: "Get notification preferences of a user", Tag: "users",
+		Description: "Users read their own preferences, admins read everyone's.", Response: dto.GetNotificationPreferencesResponse{},
+	},
+	"PUT /users/{user_id}/notifications": {
+		OperationID: "replaceNotificationPreferences", Summary: "Replace notification preferences of a user", Tag: "users",
+		Description: "A missing channels list means the default channels of the server and an empty one turns reminders off.",
+		Request:     dto.PutNotificationPreferencesRequest{}, Response: dto.GetNotificationPreferencesResponse{},
+	},
+
+	"GET /audit": {
+		OperationID: "listAudit", Summary: "List the audit log of every project", Tag: "audit",
+		Params: auditParams, Response: dto.GetAuditResponse{},
+	},
+	"GET /audit/verify": {
+		OperationID: "verifyAudit", Summary: "Check the hash chain of the audit log", Tag: "audit", Response: dto.VerifyAuditResponse{},
+	},
+
+	"GET /health": {
+		OperationID: "health", Summary: "Check the server is up", Tag: "meta", ResponseTypes: []string{"text/plain"}, Public: true,
+	},
+	"GET /openapi.json": {
+		OperationID: "getOpenAPI", Summary: "Get this document", Tag: "meta", Response: &openapi.Schema{Type: []string{"object"}}, Public: true,
+	},
+	"GET /docs": {
+		OperationID: "getDocs", Summary: "Browse this document", Tag: "meta", ResponseTypes: []string{"text/html"}, Public: true,
+	},
+}
@@ -1,35 +1,228 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
 	"ivanjabrony/test_lo/internal/config"
 	"ivanjabrony/test_lo/internal/handler"
 	"ivanjabrony/test_lo/internal/middleware"
+	"net"
 	"net/http"
+	"os"
 )
 
 type Logger interface {
 	Log(format string, info ...any)
 }
 
-func NewHTTP(
-	cfg *config.Config,
-	logger Logger,
-	taskHandler *handler.TaskHandler) (*http.Server, error) {
+// publicPaths are reachable without authentication
+var publicPaths = []string{"/health", "/metrics", "/openapi.json", "/docs"}
 
-	r := http.NewServeMux()
+// Handlers are the handlers served by the http server
+type Handlers struct {
+	Task    *handler.TaskHandler
+	User    *handler.UserHandler
+	Project *handler.ProjectHandler
+	Tag     *handler.TagHandler
+	Comment *handler.CommentHandler
+	Audit   *handler.AuditHandler
+	Socket  *handler.SocketHandler
+	Webhook *handler.WebhookHandler
+	GraphQL *handler.GraphQLHandler
+	// Notification serves notification preferences of users
+	Notification *handler.NotificationHandler
+}
+
+// HTTPOption configures NewHTTP
+type HTTPOption func(*httpOptions)
+
+type httpOptions struct {
+	contractReport func(r *http.Request, err error)
+}
+
+// WithContractValidation checks every request and response against the OpenAPI document of the server
+// and reports the mismatches, it's meant for tests
+func WithContractValidation(report func(r *http.Request, err error)) HTTPOption {
+	return func(o *httpOptions) {
+		o.contractReport = report
+	}
+}
+
+func NewHTTP(cfg *config.Config, logger Logger, handlers Handlers, opts ...HTTPOption) (*http.Server, error) {
+	var options httpOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	userHandler, projectHandler := handlers.User, handlers.Project
+
+	r := newRouter()
+
+	registerProjectRoutes(r, "", handlers, projectHandler.DefaultScoped)
+	registerProjectRoutes(r, "/projects/{project_id}", handlers, projectHandler.Scoped)
+
+	r.HandleFunc("GET /projects", projectHandler.HandleGetAllProjects)
+	r.HandleFunc("GET /projects/{project_id}", projectHandler.HandleGetProjectById)
+	r.HandleFunc("POST /projects", projectHandler.HandlePostProject)
+	r.HandleFunc("PATCH /projects/{project_id}", projectHandler.HandlePatchProject)
+
+	r.HandleFunc("GET /users", userHandler.HandleGetAllUsers)
+	r.HandleFunc("GET /users/{user_id}", userHandler.HandleGetUserById)
+	r.HandleFunc("POST /users", userHandler.HandlePostUser)
+	r.HandleFunc("PUT /users/{user_id}", userHandler.HandlePutUser)
+	r.HandleFunc("DELETE /users/{user_id}", userHandler.HandleDeleteUser)
+	r.HandleFunc("GET /users/{user_id}/tasks", projectHandler.DefaultScoped(userHandler.HandleGetUserTasks))
+	r.HandleFunc("GET /users/{user_id}/notifications", handlers.Notification.HandleGetPreferences)
+	r.HandleFunc("PUT /users/{user_id}/notifications", handlers.Notification.HandlePutPreferences)
+
+	r.HandleFunc("GET /graphql/schema", handlers.GraphQL.HandleGetSchema)
+
+	r.HandleFunc("GET /audit", handlers.Audit.HandleGetAudit)
+	r.HandleFunc("GET /audit/verify", handlers.Audit.HandleVerifyAudit)
 
-	r.HandleFunc("GET /tasks", taskHandler.HandleGetAllTasks)
-	r.HandleFunc("GET /tasks/{task_id}", taskHandler.HandleGetTaskById)
-	r.HandleFunc("POST /tasks", taskHandler.HandlePostTask)
 	r.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
+	// the document describes every route registered above, so they can't drift apart
+	var spec []byte
+	r.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(spec)
+	})
+	r.HandleFunc("GET /docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(docsPage)
+	})
+	doc, err := r.document()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't describe the api: %w", err)
+	}
+	if spec, err = json.Marshal(doc); err != nil {
+		return nil, fmt.Errorf("couldn't encode the api description: %w", err)
+	}
+
+	var h http.Handler = r.mux
+	if options.contractReport != nil {
+		h = middleware.NewContractMiddleware(doc, options.contractReport).Validate(h)
+	}
+	if cfg.AuthEnabled {
+		authenticator, err := NewAuthenticator(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't configure authentication: %w", err)
+		}
+		h = middleware.NewAuthMiddleware(logger, authenticator, publicPaths...).Authenticate(h)
+	} else {
+		logger.Log("Authentication is disabled, every endpoint is public")
+	}
+
+	h = middleware.NewRequestIDMiddleware().AssignID(h)
 	mw := middleware.NewLoggerMiddleware(logger)
 
-	return &http.Server{
-		Addr:    ":" + cfg.HttpPort,
-		Handler: mw.Logging(r),
-	}, nil
+	// requests share a context canceled on shutdown, so event streams end instead of holding it up
+	baseCtx, cancel := context.WithCancel(context.Background())
+	srv := &http.Server{
+		Addr:        ":" + cfg.HttpPort,
+		Handler:     mw.Logging(h),
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	srv.RegisterOnShutdown(cancel)
+	return srv, nil
+}
+
+// registerProjectRoutes registers task, tag, comment, history, websocket, graphql and webhook routes under the prefix, every handler is limited
+// to the project chosen by scoped, so tasks of other projects can't be reached
+func registerProjectRoutes(
+	r *router,
+	prefix string,
+	handlers Handlers,
+	scoped func(http.HandlerFunc) http.HandlerFunc) {
+
+	taskHandler, tagHandler, commentHandler := handlers.Task, handlers.Tag, handlers.Comment
+	// public ids of tasks are looked up in the project, so they are resolved inside its scope
+	projectScoped := scoped
+	scoped = func(next http.HandlerFunc) http.HandlerFunc {
+		return projectScoped(taskHandler.ResolvePublicIds(next))
+	}
+
+	r.HandleFunc("GET "+prefix+"/tasks", scoped(taskHandler.HandleGetAllTasks))
+	r.HandleFunc("GET "+prefix+"/tasks/{task_id}", scoped(taskHandler.HandleGetTaskById))
+	r.HandleFunc("POST "+prefix+"/tasks", scoped(taskHandler.HandlePostTask))
+	r.HandleFunc("PATCH "+prefix+"/tasks/{task_id}", scoped(taskHandler.HandlePatchTask))
+	r.HandleFunc("DELETE "+prefix+"/tasks/{task_id}", scoped(taskHandler.HandleDeleteTask))
+	r.HandleFunc("GET "+prefix+"/tasks/export", scoped(taskHandler.HandleExportTasks))
+	r.HandleFunc("GET "+prefix+"/tasks/events", scoped(taskHandler.HandleTaskEvents))
+	r.HandleFunc("POST "+prefix+"/tasks/import", scoped(taskHandler.HandleImportTasks))
+	r.HandleFunc("GET "+prefix+"/tasks/{task_id}/subtasks", scoped(taskHandler.HandleGetSubtasks))
+	r.HandleFunc("GET "+prefix+"/tasks/{task_id}/dependencies/order", scoped(taskHandler.HandleGetDependencyOrder))
+	r.HandleFunc("PUT "+prefix+"/tasks/{task_id}/blockers/{blocker_id}", scoped(taskHandler.HandleAddBlocker))
+	r.HandleFunc("DELETE "+prefix+"/tasks/{task_id}/blockers/{blocker_id}", scoped(taskHandler.HandleRemoveBlocker))
+
+	r.HandleFunc("GET "+prefix+"/tags", scoped(tagHandler.HandleGetAllTags))
+	r.HandleFunc("GET "+prefix+"/tags/{tag_id}", scoped(tagHandler.HandleGetTagById))
+	r.HandleFunc("POST "+prefix+"/tags", scoped(tagHandler.HandlePostTag))
+	r.HandleFunc("PUT "+prefix+"/tags/{tag_id}", scoped(tagHandler.HandlePutTag))
+	r.HandleFunc("DELETE "+prefix+"/tags/{tag_id}", scoped(tagHandler.HandleDeleteTag))
+	r.HandleFunc("PUT "+prefix+"/tasks/{task_id}/tags/{tag_id}", scoped(tagHandler.HandleAttachTag))
+	r.HandleFunc("DELETE "+prefix+"/tasks/{task_id}/tags/{tag_id}", scoped(tagHandler.HandleDetachTag))
+
+	r.HandleFunc("GET "+prefix+"/tasks/{task_id}/comments", scoped(commentHandler.HandleGetComments))
+	r.HandleFunc("POST "+prefix+"/tasks/{task_id}/comments", scoped(commentHandler.HandlePostComment))
+	r.HandleFunc("PUT "+prefix+"/tasks/{task_id}/comments/{comment_id}", scoped(commentHandler.HandlePutComment))
+	r.HandleFunc("DELETE "+prefix+"/tasks/{task_id}/comments/{comment_id}", scoped(commentHandler.HandleDeleteComment))
+
+	r.HandleFunc("GET "+prefix+"/tasks/{task_id}/history", scoped(handlers.Audit.HandleGetTaskHistory))
+
+	r.HandleFunc("GET "+prefix+"/ws", scoped(handlers.Socket.HandleWebSocket))
+	r.HandleFunc("POST "+prefix+"/graphql", scoped(handlers.GraphQL.HandleGraphQL))
+
+	webhookHandler := handlers.Webhook
+	r.HandleFunc("GET "+prefix+"/webhooks", scoped(webhookHandler.HandleGetAllWebhooks))
+	r.HandleFunc("GET "+prefix+"/webhooks/{webhook_id}", scoped(webhookHandler.HandleGetWebhookById))
+	r.HandleFunc("POST "+prefix+"/webhooks", scoped(webhookHandler.HandlePostWebhook))
+	r.HandleFunc("PUT "+prefix+"/webhooks/{webhook_id}", scoped(webhookHandler.HandlePutWebhook))
+	r.HandleFunc("DELETE "+prefix+"/webhooks/{webhook_id}", scoped(webhookHandler.HandleDeleteWebhook))
+	r.HandleFunc("GET "+prefix+"/webhooks/{webhook_id}/deliveries", scoped(webhookHandler.HandleGetDeliveries))
+	r.HandleFunc("GET "+prefix+"/webhooks/{webhook_id}/dead-letters", scoped(webhookHandler.HandleGetDeadLetters))
+	r.HandleFunc("POST "+prefix+"/webhooks/{webhook_id}/dead-letters/{delivery_id}/redeliver", scoped(webhookHandler.HandleRedeliver))
+}
+
+// NewAuthenticator builds the authenticator of both apis from API keys and JWT keys of the config
+func NewAuthenticator(cfg *config.Config) (*auth.Authenticator, error) {
+	keys, err := auth.ParseAPIKeys(cfg.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	var verifier *auth.JWTVerifier
+	if cfg.JWTSecret != "" || cfg.JWTPublicKeyFile != "" {
+		jwtCfg := auth.JWTConfig{
+			HMACSecret: []byte(cfg.JWTSecret),
+			Issuer:     cfg.JWTIssuer,
+			Audience:   cfg.JWTAudience,
+		}
+		if cfg.JWTPublicKeyFile != "" {
+			data, err := os.ReadFile(cfg.JWTPublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if jwtCfg.PublicKey, err = auth.ParseRSAPublicKey(data); err != nil {
+				return nil, err
+			}
+		}
+		if verifier, err = auth.NewJWTVerifier(jwtCfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(keys) == 0 && verifier == nil {
+		return nil, errors.New("authentication is enabled but neither API_KEYS nor JWT keys are configured")
+	}
+	return auth.NewAuthenticator(keys, verifier)
 }
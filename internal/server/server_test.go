@@ -0,0 +1,684 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"ivanjabrony/test_lo/internal/broker"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/config"
+	"ivanjabrony/test_lo/internal/handler"
+	"ivanjabrony/test_lo/internal/idgen"
+	"ivanjabrony/test_lo/internal/storage"
+	"ivanjabrony/test_lo/internal/usecase"
+	"ivanjabrony/test_lo/internal/webhook"
+	"ivanjabrony/test_lo/internal/websocket"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockLogger struct{}
+
+func (m *MockLogger) Log(format string, info ...any) {}
+
+// testTaskStorage is provided by both task storages
+type testTaskStorage interface {
+	usecase.TaskStorage
+	usecase.TagStorage
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	taskStorage, _ := storage.NewTaskStorage(&MockLogger{}, storage.WithStrictCompletion(true))
+	return newTestServerWithStorage(t, taskStorage)
+}
+
+func newTestServerWithStorage(t *testing.T, taskStorage testTaskStorage) *httptest.Server {
+	t.Helper()
+	handlers, _ := newTestHandlers(t, taskStorage)
+	// every request of the tests is checked against the OpenAPI document, so handlers and DTOs can't drift from it
+	srv, err := NewHTTP(&config.Config{AuthEnabled: false}, &MockLogger{}, handlers, WithContractValidation(func(r *http.Request, err error) {
+		t.Errorf("Contract violation: %v", err)
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// newTestHandlers builds handlers of the http server and services of the gRPC server over the same usecases
+func newTestHandlers(t *testing.T, taskStorage testTaskStorage) (Handlers, Services) {
+	t.Helper()
+	logger := &MockLogger{}
+	userStorage, _ := storage.NewUserStorage(logger)
+	projectStorage, _ := storage.NewProjectStorage(logger, 0)
+	commentStorage, _ := storage.NewCommentStorage(logger)
+	auditStorage, _ := storage.NewAuditStorage(logger)
+	changeBroker, _ := broker.NewBroker(logger)
+	webhookStorage, _ := storage.NewWebhookStorage(logger)
+	notificationStorage, _ := storage.NewNotificationStorage(logger)
+	dispatcher, _ := webhook.NewDispatcher(logger, changeBroker, webhookStorage, webhook.WithBackoff(time.Millisecond, time.Millisecond))
+	dispatcher.Start()
+	t.Cleanup(func() { dispatcher.Stop(context.Background()) })
+
+	taskUsecase, _ := usecase.NewTaskUsecase(logger, taskStorage,
+		usecase.WithUserStorage(userStorage),
+		usecase.WithCommentStorage(commentStorage),
+		usecase.WithAuditStorage(auditStorage),
+		usecase.WithChangeBroker(changeBroker))
+	userUsecase, _ := usecase.NewUserUsecase(logger, userStorage, taskStorage)
+	projectUsecase, _ := usecase.NewProjectUsecase(logger, projectStorage)
+	tagUsecase, _ := usecase.NewTagUsecase(logger, taskStorage, taskStorage)
+	commentUsecase, _ := usecase.NewCommentUsecase(logger, commentStorage, taskStorage)
+	auditUsecase, _ := usecase.NewAuditUsecase(logger, auditStorage, taskStorage)
+	webhookUsecase, _ := usecase.NewWebhookUsecase(logger, webhookStorage, usecase.WithRedeliverer(dispatcher))
+	notificationUsecase, _ := usecase.NewNotificationUsecase(logger, notificationStorage, userStorage)
+
+	taskHandler, _ := handler.NewTaskHandler(logger, taskUsecase)
+	userHandler, _ := handler.NewUserHandler(logger, userUsecase)
+	projectHandler, _ := handler.NewProjectHandler(logger, projectUsecase)
+	tagHandler, _ := handler.NewTagHandler(logger, tagUsecase)
+	commentHandler, _ := handler.NewCommentHandler(logger, commentUsecase)
+	auditHandler, _ := handler.NewAuditHandler(logger, auditUsecase)
+	socketHandler, _ := handler.NewSocketHandler(logger, taskUsecase)
+	webhookHandler, _ := handler.NewWebhookHandler(logger, webhookUsecase)
+	notificationHandler, _ := handler.NewNotificationHandler(logger, notificationUsecase)
+	taskService, _ := handler.NewTaskService(logger, taskUsecase, projectUsecase)
+	graphqlHandler, _ := handler.NewGraphQLHandler(logger, taskUsecase)
+
+	return Handlers{
+		Task:         taskHandler,
+		User:         userHandler,
+		Project:      projectHandler,
+		Tag:          tagHandler,
+		Comment:      commentHandler,
+		Audit:        auditHandler,
+		Socket:       socketHandler,
+		Webhook:      webhookHandler,
+		GraphQL:      graphqlHandler,
+		Notification: notificationHandler,
+	}, Services{Task: taskService}
+}
+
+func doRequest(t *testing.T, method, url, body string) (int, map[string]any) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var payload map[string]any
+	json.NewDecoder(resp.Body).Decode(&payload)
+	return resp.StatusCode, payload
+}
+
+func TestProjectIsolation(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, name := range []string{"Team A", "Team B"} {
+		if code, _ := doRequest(t, "POST", ts.URL+"/projects", `{"name": "`+name+`", "task_quota": 1}`); code != http.StatusOK {
+			t.Fatalf("Failed to create project %q: %d", name, code)
+		}
+	}
+	if code, _ := doRequest(t, "POST", ts.URL+"/projects/2/tasks", `{"name": "secret", "status": "created"}`); code != http.StatusOK {
+		t.Fatalf("Failed to create task: %d", code)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "owner project reads the task", method: "GET", path: "/projects/2/tasks/0", expectedStatus: http.StatusOK},
+		{name: "other project can't read it", method: "GET", path: "/projects/3/tasks/0", expectedStatus: http.StatusNotFound},
+		{name: "legacy routes can't read it", method: "GET", path: "/tasks/0", expectedStatus: http.StatusNotFound},
+		{name: "other project can't update it", method: "PATCH", path: "/projects/3/tasks/0", body: `{"name": "x"}`, expectedStatus: http.StatusNotFound},
+		{name: "other project can't delete it", method: "DELETE", path: "/projects/3/tasks/0", expectedStatus: http.StatusNotFound},
+		{name: "nonexistent project", method: "GET", path: "/projects/9/tasks", expectedStatus: http.StatusNotFound},
+		{name: "quota is exceeded", method: "POST", path: "/projects/2/tasks", body: `{"name": "second", "status": "created"}`, expectedStatus: http.StatusConflict},
+		{name: "other project has its own quota", method: "POST", path: "/projects/3/tasks", body: `{"name": "first", "status": "created"}`, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := doRequest(t, tt.method, ts.URL+tt.path, tt.body)
+			if code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, code)
+			}
+		})
+	}
+
+	_, payload := doRequest(t, "GET", ts.URL+"/projects/3/tasks", "")
+	if payload["amount"] != float64(1) {
+		t.Errorf("Expected project B to list only its own task, got %v", payload)
+	}
+}
+
+func TestTagRoutes(t *testing.T) {
+	ts := newTestServer(t)
+
+	for range 3 {
+		doRequest(t, "POST", ts.URL+"/tasks", `{"name": "task", "status": "created"}`)
+	}
+	for _, name := range []string{"backend", "bug"} {
+		if code, _ := doRequest(t, "POST", ts.URL+"/tags", `{"name": "`+name+`"}`); code != http.StatusOK {
+			t.Fatalf("Failed to create tag %q: %d", name, code)
+		}
+	}
+	for _, path := range []string{"/tasks/0/tags/1", "/tasks/0/tags/2", "/tasks/1/tags/2"} {
+		if code, _ := doRequest(t, "PUT", ts.URL+path, ""); code != http.StatusOK {
+			t.Fatalf("Failed to attach %s: %d", path, code)
+		}
+	}
+
+	tests := []struct {
+		query    string
+		expected float64
+	}{
+		{query: "tags=backend,bug", expected: 2},
+		{query: "tags=backend,bug&tag_match=all", expected: 1},
+		{query: "tags=frontend", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, payload := doRequest(t, "GET", ts.URL+"/tasks?"+tt.query, "")
+			if payload["amount"] != tt.expected {
+				t.Errorf("Expected %v tasks, got %v", tt.expected, payload["amount"])
+			}
+		})
+	}
+
+	if code, _ := doRequest(t, "DELETE", ts.URL+"/tasks/1/tags/2", ""); code != http.StatusOK {
+		t.Errorf("Failed to detach tag: %d", code)
+	}
+	_, tag := doRequest(t, "GET", ts.URL+"/tags/2", "")
+	if tag["task_count"] != float64(1) {
+		t.Errorf("Expected bug to be used once, got %v", tag)
+	}
+
+	if code, _ := doRequest(t, "GET", ts.URL+"/projects/1/tags/2", ""); code != http.StatusOK {
+		t.Errorf("Expected legacy routes to share tags with the default project, got %d", code)
+	}
+}
+
+func TestDependencyRoutes(t *testing.T) {
+	ts := newTestServer(t)
+
+	doRequest(t, "POST", ts.URL+"/tasks", `{"name": "epic", "status": "created"}`)
+	doRequest(t, "POST", ts.URL+"/tasks", `{"name": "story", "status": "created", "parent_id": 0}`)
+	doRequest(t, "POST", ts.URL+"/tasks", `{"name": "release", "status": "created"}`)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "story blocks release", method: "PUT", path: "/tasks/2/blockers/1", expectedStatus: http.StatusOK},
+		{name: "release can't block story", method: "PUT", path: "/tasks/1/blockers/2", expectedStatus: http.StatusConflict},
+		{name: "epic can't be its own subtask", method: "PATCH", path: "/tasks/0", body: `{"parent_id": 1}`, expectedStatus: http.StatusConflict},
+		{name: "missing parent", method: "POST", path: "/tasks", body: `{"name": "x", "status": "created", "parent_id": 9}`, expectedStatus: http.StatusBadRequest},
+		{name: "release is blocked", method: "PATCH", path: "/tasks/2", body: `{"status": "done"}`, expectedStatus: http.StatusConflict},
+		{name: "epic has open subtasks", method: "PATCH", path: "/tasks/0", body: `{"status": "done"}`, expectedStatus: http.StatusConflict},
+		{name: "story is done", method: "PATCH", path: "/tasks/1", body: `{"status": "done"}`, expectedStatus: http.StatusOK},
+		{name: "release is done", method: "PATCH", path: "/tasks/2", body: `{"status": "done"}`, expectedStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := doRequest(t, tt.method, ts.URL+tt.path, tt.body)
+			if code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, code)
+			}
+		})
+	}
+
+	_, tree := doRequest(t, "GET", ts.URL+"/tasks/0/subtasks?depth=2", "")
+	if subtasks, _ := tree["subtasks"].([]any); len(subtasks) != 1 {
+		t.Errorf("Expected epic to have a single subtask, got %v", tree)
+	}
+	_, order := doRequest(t, "GET", ts.URL+"/tasks/2/dependencies/order", "")
+	if order["amount"] != float64(2) {
+		t.Errorf("Expected story and release in the order, got %v", order)
+	}
+}
+
+func TestCommentRoutes(t *testing.T) {
+	ts := newTestServer(t)
+
+	doRequest(t, "POST", ts.URL+"/tasks", `{"name": "task", "status": "created"}`)
+	for _, body := range []string{"first", "second", "<i>third</i>"} {
+		if code, _ := doRequest(t, "POST", ts.URL+"/tasks/0/comments", `{"body": "`+body+`"}`); code != http.StatusOK {
+			t.Fatalf("Failed to post comment %q: %d", body, code)
+		}
+	}
+
+	_, page := doRequest(t, "GET", ts.URL+"/tasks/0/comments?limit=1&offset=2", "")
+	comments, _ := page["comments"].([]any)
+	if page["total"] != float64(3) || len(comments) != 1 {
+		t.Fatalf("Unexpected page %v", page)
+	}
+	if body := comments[0].(map[string]any)["body"]; body != "&lt;i>third&lt;/i>" {
+		t.Errorf("Expected html to be escaped, got %v", body)
+	}
+
+	if code, _ := doRequest(t, "PUT", ts.URL+"/tasks/0/comments/1", `{"body": "edited"}`); code != http.StatusOK {
+		t.Errorf("Failed to edit comment: %d", code)
+	}
+	if code, _ := doRequest(t, "DELETE", ts.URL+"/tasks/0/comments/2", ""); code != http.StatusNoContent {
+		t.Errorf("Failed to delete comment: %d", code)
+	}
+	_, task := doRequest(t, "GET", ts.URL+"/tasks/0", "")
+	if task["comment_count"] != float64(2) {
+		t.Errorf("Expected 2 comments on the task, got %v", task["comment_count"])
+	}
+
+	if code, _ := doRequest(t, "GET", ts.URL+"/projects/1/tasks/0/comments", ""); code != http.StatusOK {
+		t.Errorf("Expected legacy routes to share comments with the default project, got %d", code)
+	}
+}
+
+func TestAuditRoutes(t *testing.T) {
+	ts := newTestServer(t)
+
+	doRequest(t, "POST", ts.URL+"/tasks", `{"name": "task", "status": "created"}`)
+	doRequest(t, "PATCH", ts.URL+"/tasks/0", `{"status": "done"}`)
+	doRequest(t, "POST", ts.URL+"/projects", `{"name": "Team A"}`)
+	doRequest(t, "POST", ts.URL+"/projects/2/tasks", `{"name": "other", "status": "created"}`)
+	doRequest(t, "DELETE", ts.URL+"/projects/2/tasks/0", "")
+
+	_, history := doRequest(t, "GET", ts.URL+"/tasks/0/history", "")
+	entries, _ := history["entries"].([]any)
+	if len(entries) != 2 || entries[1].(map[string]any)["action"] != "status_change" {
+		t.Fatalf("Unexpected history %v", history)
+	}
+	if entries[0].(map[string]any)["request_id"] == "" {
+		t.Errorf("Expected entries to carry request ids, got %v", entries[0])
+	}
+
+	_, history = doRequest(t, "GET", ts.URL+"/projects/2/tasks/0/history", "")
+	if history["total"] != float64(2) {
+		t.Errorf("Expected history of the deleted task, got %v", history)
+	}
+
+	_, audit := doRequest(t, "GET", ts.URL+"/audit?action=delete", "")
+	if audit["total"] != float64(1) {
+		t.Errorf("Expected a single delete in the log, got %v", audit)
+	}
+	_, verification := doRequest(t, "GET", ts.URL+"/audit/verify", "")
+	if verification["valid"] != true || verification["entries"] != float64(4) {
+		t.Errorf("Expected intact log of 4 entries, got %v", verification)
+	}
+}
+
+func TestPointInTimeRoute(t *testing.T) {
+	eventStorage, _ := storage.NewEventTaskStorage(&MockLogger{}, storage.WithEventStrictCompletion(true))
+	ts := newTestServerWithStorage(t, eventStorage)
+
+	if code, _ := doRequest(t, "POST", ts.URL+"/tasks", `{"name": "draft", "status": "created"}`); code != http.StatusOK {
+		t.Fatalf("Failed to create task: %v", code)
+	}
+	time.Sleep(time.Millisecond)
+	asOf := time.Now().UTC().Format(time.RFC3339Nano)
+	time.Sleep(time.Millisecond)
+	if code, _ := doRequest(t, "PATCH", ts.URL+"/tasks/0", `{"name": "final", "status": "inProgress"}`); code != http.StatusOK {
+		t.Fatalf("Failed to update task: %v", code)
+	}
+
+	code, current := doRequest(t, "GET", ts.URL+"/tasks/0", "")
+	if code != http.StatusOK || current["name"] != "final" {
+		t.Errorf("Expected the current task, got %v %v", code, current)
+	}
+	code, past := doRequest(t, "GET", ts.URL+"/tasks/0?as_of="+asOf, "")
+	if code != http.StatusOK || past["name"] != "draft" || past["status"] != "created" {
+		t.Errorf("Expected the past task, got %v %v", code, past)
+	}
+	if code, _ := doRequest(t, "GET", ts.URL+"/tasks/0?as_of=2000-01-01T00:00:00Z", ""); code != http.StatusNotFound {
+		t.Errorf("Expected 404 before creation, got %v", code)
+	}
+
+	memory := newTestServer(t)
+	doRequest(t, "POST", memory.URL+"/tasks", `{"name": "draft", "status": "created"}`)
+	if code, _ := doRequest(t, "GET", memory.URL+"/tasks/0?as_of="+asOf, ""); code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 without event storage, got %v", code)
+	}
+}
+
+func TestTaskEventsRoute(t *testing.T) {
+	ts := newTestServer(t)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/tasks/events?status=done", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %v %v", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	lines := bufio.NewScanner(resp.Body)
+	// the stream is subscribed once the comment opening it arrives
+	if !lines.Scan() || lines.Text() != ": connected" {
+		t.Fatalf("Expected the stream to open, got %q", lines.Text())
+	}
+
+	doRequest(t, "POST", ts.URL+"/tasks", `{"name": "open", "status": "created"}`)
+	doRequest(t, "POST", ts.URL+"/tasks", `{"name": "finished", "status": "done"}`)
+
+	expected := []string{"id: 2", "event: created"}
+	for _, want := range expected {
+		for lines.Scan() && lines.Text() == "" {
+		}
+		if lines.Text() != want {
+			t.Fatalf("Expected %q, got %q", want, lines.Text())
+		}
+	}
+	if !lines.Scan() || !strings.Contains(lines.Text(), `"name":"finished"`) {
+		t.Errorf("Expected the finished task, got %q", lines.Text())
+	}
+}
+
+func TestWebSocketRoute(t *testing.T) {
+	ts := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/projects/1/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close(websocket.CloseNormal, "")
+
+	read := func() map[string]any {
+		t.Helper()
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		var message map[string]any
+		json.Unmarshal(data, &message)
+		return message
+	}
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"id": "s", "type": "subscribe"}`))
+	if message := read(); message["type"] != "subscribed" {
+		t.Fatalf("Expected a subscription, got %v", message)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"id": "c", "type": "create", "task": {"name": "over socket", "status": "created"}}`))
+
+	// the change may come before or after the reply to the command
+	seen := map[string]bool{}
+	for range 2 {
+		message := read()
+		seen[message["type"].(string)] = true
+	}
+	if !seen["task"] || !seen["change"] {
+		t.Errorf("Expected the created task and its change, got %v", seen)
+	}
+
+	code, payload := doRequest(t, "GET", ts.URL+"/tasks", "")
+	if code != http.StatusOK || payload["amount"] != float64(1) {
+		t.Errorf("Expected the task created over the socket, got %v %v", code, payload)
+	}
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	ts := newTestServer(t)
+	events := make(chan string, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events <- r.Header.Get(webhook.HeaderEvent)
+	}))
+	defer receiver.Close()
+
+	doRequest(t, "POST", ts.URL+"/projects", `{"name": "Team A"}`)
+	code, created := doRequest(t, "POST", ts.URL+"/projects/2/webhooks", `{"url": "`+receiver.URL+`", "events": ["task.completed"]}`)
+	if code != http.StatusOK || created["secret"] == "" {
+		t.Fatalf("Failed to create webhook: %d %v", code, created)
+	}
+	if code, payload := doRequest(t, "GET", ts.URL+"/webhooks", ""); code != http.StatusOK || payload["amount"] != float64(0) {
+		t.Errorf("Expected the webhook to be hidden from other projects, got %d %v", code, payload)
+	}
+	if code, _ := doRequest(t, "POST", ts.URL+"/projects/2/webhooks", `{"url": "not a url"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid webhook, got %d", http.StatusBadRequest, code)
+	}
+
+	doRequest(t, "POST", ts.URL+"/tasks", `{"name": "elsewhere", "status": "done"}`)
+	doRequest(t, "POST", ts.URL+"/projects/2/tasks", `{"name": "open", "status": "created"}`)
+	doRequest(t, "POST", ts.URL+"/projects/2/tasks", `{"name": "finished", "status": "done"}`)
+	select {
+	case event := <-events:
+		if event != "task.completed" {
+			t.Errorf("Expected task.completed, got %q", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected a delivery")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Expected a single delivery, got %q", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	id := strconv.Itoa(int(created["id"].(float64)))
+	var payload map[string]any
+	deadline := time.Now().Add(time.Second)
+	for payload["amount"] != float64(1) && time.Now().Before(deadline) {
+		_, payload = doRequest(t, "GET", ts.URL+"/projects/2/webhooks/"+id+"/deliveries", "")
+	}
+	if payload["amount"] != float64(1) {
+		t.Errorf("Expected the delivery to be logged, got %v", payload)
+	}
+
+	if code, _ := doRequest(t, "POST", ts.URL+"/projects/2/webhooks/"+id+"/dead-letters/none/redeliver", ""); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing dead letter, got %d", http.StatusNotFound, code)
+	}
+	if code, _ := doRequest(t, "DELETE", ts.URL+"/projects/2/webhooks/"+id, ""); code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, code)
+	}
+}
+
+func TestGraphQLRoutes(t *testing.T) {
+	ts := newTestServer(t)
+	doRequest(t, "POST", ts.URL+"/projects", `{"name": "Team A"}`)
+
+	mutation := `{"query": "mutation { parent: createTask(input: {name: \"parent\", status: \"created\"}) { id } child: createTask(input: {name: \"child\", status: \"created\", parentId: 0}) { id parent { name } } }"}`
+	code, payload := doRequest(t, "POST", ts.URL+"/projects/2/graphql", mutation)
+	if code != http.StatusOK || payload["errors"] != nil {
+		t.Fatalf("Failed to create tasks: %d %v", code, payload)
+	}
+	if child := payload["data"].(map[string]any)["child"].(map[string]any); child["parent"].(map[string]any)["name"] != "parent" {
+		t.Errorf("Expected the child of the parent, got %v", child)
+	}
+
+	query := `{"query": "{ tasks { total tasks { name subtasks { name } } } }"}`
+	_, payload = doRequest(t, "POST", ts.URL+"/projects/2/graphql", query)
+	if tasks := payload["data"].(map[string]any)["tasks"].(map[string]any); tasks["total"] != float64(2) {
+		t.Errorf("Expected the tasks of the project, got %v", payload)
+	}
+	_, payload = doRequest(t, "POST", ts.URL+"/graphql", query)
+	if tasks := payload["data"].(map[string]any)["tasks"].(map[string]any); tasks["total"] != float64(0) {
+		t.Errorf("Expected the tasks to be hidden from other projects, got %v", payload)
+	}
+	if code, _ := doRequest(t, "POST", ts.URL+"/projects/9/graphql", query); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a nonexistent project, got %d", http.StatusNotFound, code)
+	}
+
+	resp, err := http.Get(ts.URL + "/graphql/schema")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status %d for the schema, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+// TestOpenAPIDocument compares the served document with api/openapi.json, run with -update to rewrite it
+func TestOpenAPIDocument(t *testing.T) {
+	ts := newTestServer(t)
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var doc any
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("Invalid document: %v", err)
+	}
+	actual, _ := json.MarshalIndent(doc, "", "  ")
+	actual = append(actual, '\n')
+
+	goldenPath := filepath.Join("..", "..", "api", "openapi.json")
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, actual, 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("The document differs from %v, run the test with -update if the change is intended", goldenPath)
+	}
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	taskStorage, _ := storage.NewTaskStorage(&MockLogger{})
+	handlers, _ := newTestHandlers(t, taskStorage)
+	cfg := &config.Config{AuthEnabled: true, JWTSecret: strings.Repeat("s", 32)}
+	srv, err := NewHTTP(cfg, &MockLogger{}, handlers)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler)
+	defer ts.Close()
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{path: "/openapi.json", status: http.StatusOK, contentType: "application/json"},
+		{path: "/docs", status: http.StatusOK, contentType: "text/html; charset=utf-8"},
+		{path: "/tasks", status: http.StatusUnauthorized, contentType: "application/json"},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(ts.URL + tt.path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || resp.Header.Get("Content-Type") != tt.contentType {
+			t.Errorf("Expected %d %v for %v, got %d %v", tt.status, tt.contentType, tt.path, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+	}
+}
+
+func TestUndocumentedRoute(t *testing.T) {
+	rt := newRouter()
+	rt.HandleFunc("GET /undocumented", func(w http.ResponseWriter, r *http.Request) {})
+	rt.HandleFunc("GET "+projectPrefix+"/undocumented", func(w http.ResponseWriter, r *http.Request) {})
+	if _, err := rt.document(); err == nil || !strings.Contains(err.Error(), "route GET /undocumented isn't documented") ||
+		!strings.Contains(err.Error(), "route GET /projects/{project_id}/undocumented isn't documented") {
+		t.Errorf("Expected undocumented routes to be reported, got %v", err)
+	}
+}
+
+func TestNotificationRoutes(t *testing.T) {
+	ts := newTestServer(t)
+	doRequest(t, "POST", ts.URL+"/users", `{"name": "John", "email": "john@example.com"}`)
+
+	code, preferences := doRequest(t, "GET", ts.URL+"/users/1/notifications", "")
+	if code != http.StatusOK || preferences["default_channels"] != true {
+		t.Fatalf("Expected the default channels, got %d %v", code, preferences)
+	}
+	code, preferences = doRequest(t, "PUT", ts.URL+"/users/1/notifications", `{"channels": [], "muted": ["stale"]}`)
+	if code != http.StatusOK || preferences["default_channels"] != false || len(preferences["channels"].([]any)) != 0 {
+		t.Errorf("Expected reminders to be turned off, got %d %v", code, preferences)
+	}
+	if code, _ := doRequest(t, "PUT", ts.URL+"/users/1/notifications", `{"channels": ["email"]}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unavailable channel, got %d", http.StatusBadRequest, code)
+	}
+	if code, _ := doRequest(t, "GET", ts.URL+"/users/99/notifications", ""); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a nonexistent user, got %d", http.StatusNotFound, code)
+	}
+}
+
+func TestPublicIdRoutes(t *testing.T) {
+	logger := &MockLogger{}
+	uuids, _ := idgen.NewUUIDv7(clock.System{}, rand.Reader)
+	taskStorage, _ := storage.NewTaskStorage(logger, storage.WithIDGenerator(uuids))
+	handlers, _ := newTestHandlers(t, taskStorage)
+	taskUsecase, _ := usecase.NewTaskUsecase(logger, taskStorage)
+	handlers.Task, _ = handler.NewTaskHandler(logger, taskUsecase, handler.WithPublicIds(taskUsecase))
+	srv, err := NewHTTP(&config.Config{}, logger, handlers, WithContractValidation(func(r *http.Request, err error) {
+		t.Errorf("Contract violation: %v", err)
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler)
+	t.Cleanup(ts.Close)
+
+	publicIds := make([]string, 0)
+	for _, name := range []string{"story", "release"} {
+		resp, err := http.Post(ts.URL+"/tasks", "application/json", strings.NewReader(`{"name": "`+name+`", "status": "created"}`))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var publicId string
+		json.NewDecoder(resp.Body).Decode(&publicId)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || publicId == "" {
+			t.Fatalf("Expected a public id, got %d %q", resp.StatusCode, publicId)
+		}
+		publicIds = append(publicIds, publicId)
+	}
+	story, release := publicIds[0], publicIds[1]
+	doRequest(t, "POST", ts.URL+"/projects", `{"name": "Team B"}`)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "read by public id", method: "GET", path: "/tasks/" + story, expectedStatus: http.StatusOK},
+		{name: "ids aren't accepted", method: "GET", path: "/tasks/0", expectedStatus: http.StatusNotFound},
+		{name: "blocker by public id", method: "PUT", path: "/tasks/" + release + "/blockers/" + story, expectedStatus: http.StatusOK},
+		{name: "comment by public id", method: "POST", path: "/tasks/" + story + "/comments", body: `{"body": "ready"}`, expectedStatus: http.StatusOK},
+		{name: "other project can't read it", method: "GET", path: "/projects/2/tasks/" + story, expectedStatus: http.StatusNotFound},
+		{name: "update by public id", method: "PATCH", path: "/tasks/" + story, body: `{"status": "done"}`, expectedStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := doRequest(t, tt.method, ts.URL+tt.path, tt.body)
+			if code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, code)
+			}
+		})
+	}
+
+	_, task := doRequest(t, "GET", ts.URL+"/tasks/"+release, "")
+	if task["public_id"] != release || task["blocked_by"] == nil {
+		t.Errorf("Expected the blocked release, got %v", task)
+	}
+}
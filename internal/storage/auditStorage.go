@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"slices"
+	"sync"
+)
+
+const auditStorageName = "AuditStorage"
+
+// AuditStorage is an append-only log of task mutations of every project.
+//
+// It's kept apart from TaskStorage on purpose: entries outlive the tasks they describe,
+// and nothing but Append can change the log. Entries are chained by hashes, see model.AuditEntry.
+type AuditStorage struct {
+	entries []model.AuditEntry
+	logger  Logger
+	clock   Clock
+	m       sync.RWMutex
+}
+
+// AuditStorageOption configures optional behaviour of AuditStorage
+type AuditStorageOption func(*AuditStorage)
+
+// WithAuditClock replaces the system clock entries are timestamped with
+func WithAuditClock(clock Clock) AuditStorageOption {
+	return func(as *AuditStorage) {
+		as.clock = clock
+	}
+}
+
+func NewAuditStorage(logger Logger, opts ...AuditStorageOption) (*AuditStorage, error) {
+	as := &AuditStorage{
+		entries: make([]model.AuditEntry, 0),
+		logger:  logger,
+		clock:   clock.System{},
+	}
+	for _, opt := range opts {
+		opt(as)
+	}
+	logger.Log("Created %s successfully", auditStorageName)
+
+	return as, nil
+}
+
+// Append links the entry to the end of the chain and returns it with Seq, At and hashes set
+func (as *AuditStorage) Append(ctx context.Context, entry model.AuditEntry) (*model.AuditEntry, error) {
+	as.m.Lock()
+	defer as.m.Unlock()
+	entry.Seq = len(as.entries) + 1
+	entry.At = as.clock.Now().UTC()
+	entry.Changes = slices.Clone(entry.Changes)
+	entry.PrevHash = ""
+	if len(as.entries) > 0 {
+		entry.PrevHash = as.entries[len(as.entries)-1].Hash
+	}
+	entry.Hash = entry.ComputeHash()
+	as.entries = append(as.entries, entry)
+
+	ans := cloneEntry(entry)
+	return &ans, nil
+}
+
+// GetAll returns a page of the entries matching the filter in the order they were appended,
+// and the total amount of matching entries
+func (as *AuditStorage) GetAll(ctx context.Context, filter model.AuditFilter, page model.Page) ([]model.AuditEntry, int, error) {
+	as.m.RLock()
+	defer as.m.RUnlock()
+	ans := make([]model.AuditEntry, 0, min(page.Limit, len(as.entries)))
+	total := 0
+	for _, entry := range as.entries {
+		if !matchesAuditFilter(entry, filter) {
+			continue
+		}
+		if total >= page.Offset && len(ans) < page.Limit {
+			ans = append(ans, cloneEntry(entry))
+		}
+		total++
+	}
+
+	return ans, total, nil
+}
+
+// Verify recomputes the whole chain, it returns the amount of entries
+// and Seq of the first broken entry or 0 if the log is intact
+func (as *AuditStorage) Verify(ctx context.Context) (int, int, error) {
+	as.m.RLock()
+	defer as.m.RUnlock()
+
+	return len(as.entries), model.VerifyAuditChain(as.entries), nil
+}
+
+func matchesAuditFilter(entry model.AuditEntry, filter model.AuditFilter) bool {
+	if filter.ProjectID != 0 && entry.ProjectID != filter.ProjectID {
+		return false
+	}
+	if filter.TaskID != nil && entry.TaskID != *filter.TaskID {
+		return false
+	}
+	if filter.TaskPublicID != "" && !entry.ReferencesTask(filter.TaskPublicID) {
+		return false
+	}
+	if filter.ActorID != model.NoUser && entry.ActorID != filter.ActorID {
+		return false
+	}
+	if filter.Action != "" && entry.Action != filter.Action {
+		return false
+	}
+	if !filter.From.IsZero() && entry.At.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && entry.At.After(filter.To) {
+		return false
+	}
+	return true
+}
+
+// cloneEntry copies the changes, so the stored entries can't be modified through the returned ones
+func cloneEntry(entry model.AuditEntry) model.AuditEntry {
+	changes := make([]model.FieldChange, 0, len(entry.Changes))
+	for _, change := range entry.Changes {
+		changes = append(changes, model.FieldChange{
+			Field:  change.Field,
+			Before: slices.Clone(change.Before),
+			After:  slices.Clone(change.After),
+		})
+	}
+	entry.Changes = changes
+	return entry
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"testing"
+	"time"
+)
+
+func TestAuditStorage(t *testing.T) {
+	mockLogger := &MockLogger{}
+	start := time.Date(2030, time.June, 1, 12, 0, 0, 0, time.UTC)
+	storage, _ := NewAuditStorage(mockLogger, WithAuditClock(clock.NewManual(start)))
+	ctx := context.Background()
+	taskOf := func(id int) *int { return &id }
+
+	for _, entry := range []model.AuditEntry{
+		{ProjectID: 1, TaskID: 0, Action: model.AuditCreate, ActorID: 1},
+		{ProjectID: 1, TaskID: 0, Action: model.AuditStatusChange, ActorID: 2},
+		{ProjectID: 2, TaskID: 0, Action: model.AuditCreate, ActorID: 1},
+		{ProjectID: 1, TaskID: 1, Action: model.AuditCreate, ActorID: 1},
+	} {
+		if _, err := storage.Append(ctx, entry); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	t.Run("filter", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			filter   model.AuditFilter
+			page     model.Page
+			expected []int
+			total    int
+		}{
+			{name: "everything", filter: model.AuditFilter{}, page: model.DefaultPage, expected: []int{1, 2, 3, 4}, total: 4},
+			{name: "task of a project", filter: model.AuditFilter{ProjectID: 1, TaskID: taskOf(0)}, page: model.DefaultPage, expected: []int{1, 2}, total: 2},
+			{name: "actor", filter: model.AuditFilter{ActorID: 1}, page: model.Page{Limit: 1, Offset: 1}, expected: []int{3}, total: 3},
+			{name: "action", filter: model.AuditFilter{Action: model.AuditStatusChange}, page: model.DefaultPage, expected: []int{2}, total: 1},
+			{name: "time range", filter: model.AuditFilter{From: start, To: start}, page: model.DefaultPage, expected: []int{1, 2, 3, 4}, total: 4},
+			{name: "future", filter: model.AuditFilter{From: start.Add(time.Hour)}, page: model.DefaultPage, expected: []int{}, total: 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				entries, total, _ := storage.GetAll(ctx, tt.filter, tt.page)
+				if total != tt.total || len(entries) != len(tt.expected) {
+					t.Fatalf("Expected %v of %d entries, got %v of %d", tt.expected, tt.total, entries, total)
+				}
+				for i, entry := range entries {
+					if entry.Seq != tt.expected[i] {
+						t.Errorf("Expected entry %d at %d, got %d", tt.expected[i], i, entry.Seq)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		entries, _, _ := storage.GetAll(ctx, model.AuditFilter{}, model.DefaultPage)
+		if entries[0].PrevHash != "" || entries[1].PrevHash != entries[0].Hash {
+			t.Errorf("Expected entries to be chained, got %+v", entries[:2])
+		}
+		if count, brokenAt, _ := storage.Verify(ctx); count != 4 || brokenAt != 0 {
+			t.Errorf("Expected intact log of 4 entries, got %d broken at %d", count, brokenAt)
+		}
+
+		storage.entries[1].ActorID = 3
+		if _, brokenAt, _ := storage.Verify(ctx); brokenAt != 2 {
+			t.Errorf("Expected tampered entry to be detected, got %d", brokenAt)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"slices"
+	"sync"
+	"time"
+)
+
+const commentStorageName = "CommentStorage"
+
+// threadKey identifies comments of a task, task ids are only unique inside a project
+type threadKey struct {
+	projectId int
+	taskId    int
+}
+
+// CommentStorage keeps comments of tasks of every project, ids are assigned starting from 1
+// and are unique across projects, but a comment is reachable only through the thread it belongs to
+type CommentStorage struct {
+	comments map[int]model.Comment
+	// threads maps tasks to ids of their comments in the order they were posted
+	threads   map[threadKey][]int
+	idCounter int
+	logger    Logger
+	clock     Clock
+	m         sync.RWMutex
+}
+
+// CommentStorageOption configures optional behaviour of CommentStorage
+type CommentStorageOption func(*CommentStorage)
+
+// WithCommentClock replaces the system clock comments are timestamped with
+func WithCommentClock(clock Clock) CommentStorageOption {
+	return func(cs *CommentStorage) {
+		cs.clock = clock
+	}
+}
+
+func NewCommentStorage(logger Logger, opts ...CommentStorageOption) (*CommentStorage, error) {
+	cs := &CommentStorage{
+		comments: make(map[int]model.Comment),
+		threads:  make(map[threadKey][]int),
+		logger:   logger,
+		clock:    clock.System{},
+	}
+	for _, opt := range opts {
+		opt(cs)
+	}
+	logger.Log("Created %s successfully", commentStorageName)
+
+	return cs, nil
+}
+
+func (cs *CommentStorage) Store(ctx context.Context, comment model.Comment) (int, error) {
+	key := thread(ctx, comment.TaskID)
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	cs.idCounter++
+	comment.Id = cs.idCounter
+	comment.CreatedAt = cs.clock.Now()
+	comment.EditedAt = time.Time{}
+	cs.comments[comment.Id] = comment
+	cs.threads[key] = append(cs.threads[key], comment.Id)
+
+	return comment.Id, nil
+}
+
+// GetByTaskId returns a page of comments of the task, oldest first, and the total amount of them
+func (cs *CommentStorage) GetByTaskId(ctx context.Context, taskId int, page model.Page) ([]model.Comment, int, error) {
+	key := thread(ctx, taskId)
+	cs.m.RLock()
+	defer cs.m.RUnlock()
+	ids := cs.threads[key]
+	total := len(ids)
+
+	start := min(page.Offset, total)
+	end := min(start+page.Limit, total)
+	ans := make([]model.Comment, 0, end-start)
+	for _, id := range ids[start:end] {
+		ans = append(ans, cs.comments[id])
+	}
+
+	return ans, total, nil
+}
+
+func (cs *CommentStorage) GetByCommentId(ctx context.Context, taskId, commentId int) (*model.Comment, error) {
+	key := thread(ctx, taskId)
+	cs.m.RLock()
+	defer cs.m.RUnlock()
+	if !cs.inThread(key, commentId) {
+		return nil, fmt.Errorf("%v: error while retrieving comment by id(%v): %w", commentStorageName, commentId, model.ErrNotFound)
+	}
+	comment := cs.comments[commentId]
+
+	return &comment, nil
+}
+
+// Update replaces the body of an existing comment and marks it as edited
+func (cs *CommentStorage) Update(ctx context.Context, comment model.Comment) (*model.Comment, error) {
+	key := thread(ctx, comment.TaskID)
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	if !cs.inThread(key, comment.Id) {
+		return nil, fmt.Errorf("%v: error while updating comment by id(%v): %w", commentStorageName, comment.Id, model.ErrNotFound)
+	}
+	stored := cs.comments[comment.Id]
+	stored.Body = comment.Body
+	stored.EditedAt = cs.clock.Now()
+	cs.comments[comment.Id] = stored
+
+	return &stored, nil
+}
+
+func (cs *CommentStorage) Delete(ctx context.Context, taskId, commentId int) error {
+	key := thread(ctx, taskId)
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	if !cs.inThread(key, commentId) {
+		return fmt.Errorf("%v: error while deleting comment by id(%v): %w", commentStorageName, commentId, model.ErrNotFound)
+	}
+	delete(cs.comments, commentId)
+	cs.threads[key] = slices.DeleteFunc(cs.threads[key], func(id int) bool { return id == commentId })
+	if len(cs.threads[key]) == 0 {
+		delete(cs.threads, key)
+	}
+
+	return nil
+}
+
+// DeleteByTaskId removes the whole thread of the task and returns the amount of removed comments
+func (cs *CommentStorage) DeleteByTaskId(ctx context.Context, taskId int) (int, error) {
+	key := thread(ctx, taskId)
+	cs.m.Lock()
+	defer cs.m.Unlock()
+	ids := cs.threads[key]
+	for _, id := range ids {
+		delete(cs.comments, id)
+	}
+	delete(cs.threads, key)
+
+	return len(ids), nil
+}
+
+// Count returns the amount of comments of the task
+func (cs *CommentStorage) Count(ctx context.Context, taskId int) (int, error) {
+	key := thread(ctx, taskId)
+	cs.m.RLock()
+	defer cs.m.RUnlock()
+
+	return len(cs.threads[key]), nil
+}
+
+// inThread reports whether the comment belongs to the thread, must be called under lock
+func (cs *CommentStorage) inThread(key threadKey, commentId int) bool {
+	comment, ok := cs.comments[commentId]
+	return ok && comment.TaskID == key.taskId && slices.Contains(cs.threads[key], commentId)
+}
+
+func thread(ctx context.Context, taskId int) threadKey {
+	return threadKey{projectId: tenant.FromContext(ctx).ProjectID, taskId: taskId}
+}
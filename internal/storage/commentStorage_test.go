@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"testing"
+	"time"
+)
+
+func TestCommentStorage(t *testing.T) {
+	mockLogger := &MockLogger{}
+	c := clock.NewManual(time.Date(2030, time.June, 1, 12, 0, 0, 0, time.UTC))
+	storage, _ := NewCommentStorage(mockLogger, WithCommentClock(c))
+	ctx := context.Background()
+	otherProject := tenant.WithScope(ctx, tenant.Scope{ProjectID: 2})
+
+	for _, body := range []string{"first", "second", "third"} {
+		storage.Store(ctx, model.Comment{TaskID: 0, AuthorID: 1, Body: body})
+	}
+	storage.Store(ctx, model.Comment{TaskID: 1, Body: "other task"})
+	foreign, _ := storage.Store(otherProject, model.Comment{TaskID: 0, Body: "other project"})
+
+	t.Run("pages", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			page     model.Page
+			expected []string
+		}{
+			{name: "whole thread", page: model.DefaultPage, expected: []string{"first", "second", "third"}},
+			{name: "limit", page: model.Page{Limit: 2}, expected: []string{"first", "second"}},
+			{name: "offset", page: model.Page{Limit: 2, Offset: 2}, expected: []string{"third"}},
+			{name: "past the end", page: model.Page{Limit: 2, Offset: 5}, expected: []string{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				comments, total, err := storage.GetByTaskId(ctx, 0, tt.page)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if total != 3 || len(comments) != len(tt.expected) {
+					t.Fatalf("Expected %v of 3 comments, got %v of %d", tt.expected, comments, total)
+				}
+				for i, comment := range comments {
+					if comment.Body != tt.expected[i] {
+						t.Errorf("Expected %q at %d, got %q", tt.expected[i], i, comment.Body)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("threads are isolated", func(t *testing.T) {
+		if _, err := storage.GetByCommentId(ctx, 0, foreign); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a comment of another project, got %v", err)
+		}
+		if _, err := storage.GetByCommentId(ctx, 1, 1); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a comment of another task, got %v", err)
+		}
+		if err := storage.Delete(ctx, 0, foreign); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		edited := c.Advance(time.Minute)
+		updated, err := storage.Update(ctx, model.Comment{Id: 1, TaskID: 0, Body: "edited"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if updated.Body != "edited" || updated.AuthorID != 1 || !updated.EditedAt.Equal(edited) ||
+			!updated.CreatedAt.Equal(edited.Add(-time.Minute)) {
+			t.Errorf("Unexpected comment %+v", updated)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		storage.Delete(ctx, 0, 2)
+		if count, _ := storage.Count(ctx, 0); count != 2 {
+			t.Errorf("Expected 2 comments left, got %d", count)
+		}
+
+		deleted, _ := storage.DeleteByTaskId(ctx, 0)
+		if deleted != 2 {
+			t.Errorf("Expected 2 comments to be deleted, got %d", deleted)
+		}
+		if count, _ := storage.Count(otherProject, 0); count != 1 {
+			t.Errorf("Expected comments of the other project to stay, got %d", count)
+		}
+	})
+}
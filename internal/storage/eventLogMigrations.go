@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EventLogVersion is the format version of the event log files this build reads and writes
+const EventLogVersion = 1
+
+// versionSuffix is appended to the path of the event log to get the file keeping its format version,
+// a log without it was written before versions were recorded
+const versionSuffix = ".version"
+
+// errEventLogVersion is returned when the event log has to be migrated before it's opened
+var errEventLogVersion = errors.New("unsupported event log version")
+
+// EventLogMigration changes the files of an event log from the format Version-1 to Version and back
+type EventLogMigration struct {
+	Version     int
+	Description string
+	up          func(path string, logger Logger) error
+	down        func(path string, logger Logger) error
+}
+
+// eventLogMigrations are ordered by Version starting from 1, the last one is EventLogVersion
+var eventLogMigrations = []EventLogMigration{
+	{
+		Version:     1,
+		Description: "check that every event of the log folds and record the format version",
+		up:          checkEventLog,
+		down:        func(string, Logger) error { return nil },
+	},
+}
+
+// EventLogMigrations returns every known migration ordered by version
+func EventLogMigrations() []EventLogMigration {
+	return append([]EventLogMigration(nil), eventLogMigrations...)
+}
+
+// ReadEventLogVersion returns the format version recorded for the event log at path, 0 if there is none
+func ReadEventLogVersion(path string) (int, error) {
+	data, err := os.ReadFile(path + versionSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("malformed version file %v: %q", path+versionSuffix, data)
+	}
+	return version, nil
+}
+
+func writeEventLogVersion(path string, version int) error {
+	if version == 0 {
+		if err := os.Remove(path + versionSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	tmp := path + versionSuffix + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(version)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path+versionSuffix)
+}
+
+// CheckEventLogVersion fails unless the log at path can be opened by this build without migrations
+func CheckEventLogVersion(path string) error {
+	version, err := ReadEventLogVersion(path)
+	if err != nil {
+		return err
+	}
+	return checkReadableVersion(path, version)
+}
+
+// checkReadableVersion fails unless the log at path is in the format of this build
+func checkReadableVersion(path string, version int) error {
+	if version == 0 {
+		// logs written before versions were recorded are in the format of version 1
+		version = 1
+	}
+	if version > EventLogVersion {
+		return fmt.Errorf("%v is of version %v, newer than %v: %w", path, version, EventLogVersion, errEventLogVersion)
+	}
+	if version < EventLogVersion {
+		return fmt.Errorf("%v is of version %v, run the migrations up to %v: %w", path, version, EventLogVersion, errEventLogVersion)
+	}
+	return nil
+}
+
+// MigrateEventLog applies migrations up or down until the event log at path is of the target version
+// and returns the applied ones in the order they ran. The version is recorded after every migration,
+// so a failed run resumes from the last successful one. The log mustn't be open while it's migrated.
+func MigrateEventLog(logger Logger, path string, target int) ([]EventLogMigration, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("nil values in MigrateEventLog")
+	}
+	if target < 0 || target > EventLogVersion {
+		return nil, fmt.Errorf("%v: target version %v is out of range 0..%v", eventStorageName, target, EventLogVersion)
+	}
+	current, err := ReadEventLogVersion(path)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", eventStorageName, err)
+	}
+	if current > EventLogVersion {
+		return nil, fmt.Errorf("%v: %v is of version %v, newer than %v: %w", eventStorageName, path, current, EventLogVersion, errEventLogVersion)
+	}
+
+	applied := make([]EventLogMigration, 0)
+	for ; current < target; current++ {
+		m := eventLogMigrations[current]
+		if err := m.up(path, logger); err != nil {
+			return applied, fmt.Errorf("%v: migration %v up: %w", eventStorageName, m.Version, err)
+		}
+		if err := writeEventLogVersion(path, m.Version); err != nil {
+			return applied, fmt.Errorf("%v: couldn't record version %v: %w", eventStorageName, m.Version, err)
+		}
+		applied = append(applied, m)
+		logger.Log("Migrated event log %v up to version %v", path, m.Version)
+	}
+	for ; current > target; current-- {
+		m := eventLogMigrations[current-1]
+		if err := m.down(path, logger); err != nil {
+			return applied, fmt.Errorf("%v: migration %v down: %w", eventStorageName, m.Version, err)
+		}
+		if err := writeEventLogVersion(path, m.Version-1); err != nil {
+			return applied, fmt.Errorf("%v: couldn't record version %v: %w", eventStorageName, m.Version-1, err)
+		}
+		applied = append(applied, m)
+		logger.Log("Migrated event log %v down to version %v", path, m.Version-1)
+	}
+	return applied, nil
+}
+
+// checkEventLog folds the whole log ignoring the snapshot, so a log that can't be recovered isn't given a version
+func checkEventLog(path string, logger Logger) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	events, err := readEvents(file)
+	if err != nil {
+		return err
+	}
+	es := &EventTaskStorage{logger: logger, clock: clock.System{}}
+	_, err = es.fold(nil, events)
+	return err
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEventLogMigrationsOrder(t *testing.T) {
+	for i, m := range EventLogMigrations() {
+		if m.Version != i+1 || m.up == nil || m.down == nil || m.Description == "" {
+			t.Errorf("Malformed migration at %v: %+v", i, m)
+		}
+	}
+	if got := len(EventLogMigrations()); got != EventLogVersion {
+		t.Errorf("Expected %v migrations, got %v", EventLogVersion, got)
+	}
+}
+
+func TestMigrateEventLog(t *testing.T) {
+	ctx := context.Background()
+
+	// legacyLog writes a log with a few events and no version, like the ones written before versions were recorded
+	legacyLog := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		es, err := NewEventTaskStorage(&MockLogger{}, WithEventLog(path))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		es.Store(ctx, model.Task{Name: "first", Status: model.Created})
+		es.Store(ctx, model.Task{Name: "second", Status: model.Done})
+		es.Close()
+		os.Remove(path + versionSuffix)
+		return path
+	}
+	versionOf := func(t *testing.T, path string) int {
+		t.Helper()
+		version, err := ReadEventLogVersion(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return version
+	}
+
+	t.Run("new log is given the current version", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		es, err := NewEventTaskStorage(&MockLogger{}, WithEventLog(path))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		es.Close()
+		if got := versionOf(t, path); got != EventLogVersion {
+			t.Errorf("Expected version %v, got %v", EventLogVersion, got)
+		}
+	})
+
+	t.Run("legacy log is opened and migrated", func(t *testing.T) {
+		path := legacyLog(t)
+		if got := versionOf(t, path); got != 0 {
+			t.Fatalf("Expected version 0, got %v", got)
+		}
+		es, err := NewEventTaskStorage(&MockLogger{}, WithEventLog(path))
+		if err != nil {
+			t.Fatalf("Expected the legacy log to be readable, got %v", err)
+		}
+		es.Close()
+		if got := versionOf(t, path); got != 0 {
+			t.Errorf("Expected opening not to record a version, got %v", got)
+		}
+
+		applied, err := MigrateEventLog(&MockLogger{}, path, EventLogVersion)
+		if err != nil || len(applied) != EventLogVersion || applied[0].Version != 1 {
+			t.Fatalf("Expected every migration applied, got %+v, %v", applied, err)
+		}
+		if got := versionOf(t, path); got != EventLogVersion {
+			t.Errorf("Expected version %v, got %v", EventLogVersion, got)
+		}
+		if applied, err := MigrateEventLog(&MockLogger{}, path, EventLogVersion); err != nil || len(applied) != 0 {
+			t.Errorf("Expected nothing to apply, got %+v, %v", applied, err)
+		}
+
+		applied, err = MigrateEventLog(&MockLogger{}, path, 0)
+		if err != nil || len(applied) != EventLogVersion || applied[len(applied)-1].Version != 1 {
+			t.Fatalf("Expected every migration reverted, got %+v, %v", applied, err)
+		}
+		if _, err := os.Stat(path + versionSuffix); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected the version file to be removed, got %v", err)
+		}
+	})
+
+	t.Run("broken log isn't given a version", func(t *testing.T) {
+		path := legacyLog(t)
+		data, _ := os.ReadFile(path)
+		os.WriteFile(path, append(data, []byte("{\"seq\":3,\"type\":\"task_deleted\",\"task_id\":42}\n")...), 0o644)
+		if _, err := MigrateEventLog(&MockLogger{}, path, EventLogVersion); !errors.Is(err, errInconsistentEvent) {
+			t.Errorf("Expected errInconsistentEvent, got %v", err)
+		}
+		if got := versionOf(t, path); got != 0 {
+			t.Errorf("Expected version 0, got %v", got)
+		}
+	})
+
+	t.Run("unsupported versions", func(t *testing.T) {
+		path := legacyLog(t)
+		if _, err := MigrateEventLog(&MockLogger{}, path, EventLogVersion+1); err == nil {
+			t.Errorf("Expected an error for a target out of range")
+		}
+		os.WriteFile(path+versionSuffix, []byte("99\n"), 0o644)
+		if _, err := MigrateEventLog(&MockLogger{}, path, 0); !errors.Is(err, errEventLogVersion) {
+			t.Errorf("Expected errEventLogVersion from migration, got %v", err)
+		}
+		if _, err := NewEventTaskStorage(&MockLogger{}, WithEventLog(path)); !errors.Is(err, errEventLogVersion) {
+			t.Errorf("Expected errEventLogVersion from the storage, got %v", err)
+		}
+		os.WriteFile(path+versionSuffix, []byte("one"), 0o644)
+		if _, err := ReadEventLogVersion(path); err == nil {
+			t.Errorf("Expected an error for a malformed version")
+		}
+	})
+}
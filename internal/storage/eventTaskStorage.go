@@ -0,0 +1,634 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const eventStorageName = "EventTaskStorage"
+
+const (
+	// DefaultSnapshotInterval is an amount of events between two snapshots of the projection
+	DefaultSnapshotInterval = 1000
+	// maxSnapshots limits snapshots kept in memory for point-in-time reads,
+	// when there are more every second one is dropped
+	maxSnapshots = 64
+	// snapshotSuffix is appended to the path of the event log to get the path of the last snapshot
+	snapshotSuffix = ".snapshot"
+	// maxEventSize limits a single line of the event log
+	maxEventSize = 16 << 20
+)
+
+// EventTaskStorage is a task storage keeping the log of domain events as the source of truth.
+//
+// Every mutation is checked against the current state, recorded as one or more model.TaskEvent
+// and only then folded into the projection, a TaskStorage nothing else writes to. Reads are
+// served by the projection, so they behave exactly as with TaskStorage.
+//
+// The projection can be rebuilt from the log at any time, and past states of a project are
+// folded on demand for point-in-time reads. Snapshots of the projection are taken every
+// snapshotInterval events: they speed up point-in-time reads and, when the log is kept in a file,
+// recovery on start, which folds only the events recorded after the last snapshot.
+type EventTaskStorage struct {
+	projection atomic.Pointer[TaskStorage]
+	events     []model.TaskEvent
+	// snapshots are ordered by Seq, the first one may be loaded from the snapshot file
+	snapshots        []projectionSnapshot
+	snapshotInterval int
+	strictCompletion bool
+	// path is the file of the event log, empty keeps events only in memory
+	path   string
+	file   *os.File
+	logger Logger
+	// clock tells the time of recorded events
+	clock Clock
+	// ids is nil when tasks have no public ids
+	ids IDGenerator
+	// m serializes mutations and guards events and snapshots,
+	// it's always taken before locks of the projection
+	m sync.RWMutex
+}
+
+// EventTaskStorageOption configures optional behaviour of EventTaskStorage
+type EventTaskStorageOption func(*EventTaskStorage)
+
+// WithEventLog keeps events in the file at path, appending a line of json per event.
+// The last snapshot is kept next to it, in path with ".snapshot" suffix, and the format version
+// in path with ".version" suffix, see MigrateEventLog.
+func WithEventLog(path string) EventTaskStorageOption {
+	return func(es *EventTaskStorage) {
+		es.path = path
+	}
+}
+
+// WithSnapshotInterval sets an amount of events between snapshots, 0 disables snapshots
+func WithSnapshotInterval(interval int) EventTaskStorageOption {
+	return func(es *EventTaskStorage) {
+		es.snapshotInterval = interval
+	}
+}
+
+// WithEventClock is WithClock of the event sourced storage, the time of events comes from the clock
+func WithEventClock(clock Clock) EventTaskStorageOption {
+	return func(es *EventTaskStorage) {
+		es.clock = clock
+	}
+}
+
+// WithEventIDGenerator is WithIDGenerator of the event sourced storage, public ids are recorded
+// in the events, so they survive replays
+func WithEventIDGenerator(ids IDGenerator) EventTaskStorageOption {
+	return func(es *EventTaskStorage) {
+		es.ids = ids
+	}
+}
+
+// WithEventStrictCompletion is WithStrictCompletion of the event sourced storage
+func WithEventStrictCompletion(strict bool) EventTaskStorageOption {
+	return func(es *EventTaskStorage) {
+		es.strictCompletion = strict
+	}
+}
+
+// NewEventTaskStorage creates the storage, recovering it from the event log and the snapshot if there is a file
+func NewEventTaskStorage(logger Logger, opts ...EventTaskStorageOption) (*EventTaskStorage, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", eventStorageName)
+	}
+	es := &EventTaskStorage{
+		events:           make([]model.TaskEvent, 0),
+		snapshotInterval: DefaultSnapshotInterval,
+		logger:           logger,
+		clock:            clock.System{},
+	}
+	for _, opt := range opts {
+		opt(es)
+	}
+	if es.clock == nil {
+		return nil, fmt.Errorf("%v: nil clock", eventStorageName)
+	}
+	if es.snapshotInterval < 0 {
+		return nil, fmt.Errorf("%v: negative snapshot interval %v", eventStorageName, es.snapshotInterval)
+	}
+
+	from := 0
+	if es.path != "" {
+		if err := es.load(); err != nil {
+			return nil, fmt.Errorf("%v: error while loading event log %v: %w", eventStorageName, es.path, err)
+		}
+	}
+	var last *projectionSnapshot
+	if len(es.snapshots) > 0 {
+		last = &es.snapshots[0]
+		from = last.Seq
+	}
+	projection, err := es.fold(last, es.events[from:])
+	if err != nil {
+		es.Close()
+		return nil, fmt.Errorf("%v: error while folding event log: %w", eventStorageName, err)
+	}
+	es.projection.Store(projection)
+
+	logger.Log("Created %s successfully, recovered %v events, %v of them from the snapshot", eventStorageName, len(es.events), from)
+	return es, nil
+}
+
+// load reads the snapshot and the events from files and opens the log for appending,
+// a new log is given the current format version
+func (es *EventTaskStorage) load() error {
+	version, err := ReadEventLogVersion(es.path)
+	if err != nil {
+		return err
+	}
+	if err := checkReadableVersion(es.path, version); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(es.path + snapshotSuffix)
+	switch {
+	case err == nil:
+		var snapshot projectionSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+		es.snapshots = append(es.snapshots, snapshot)
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	file, err := os.OpenFile(es.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if es.events, err = readEvents(file); err != nil {
+		file.Close()
+		return err
+	}
+	if len(es.snapshots) > 0 && es.snapshots[0].Seq > len(es.events) {
+		file.Close()
+		return fmt.Errorf("snapshot of event(%v) is ahead of the log: %w", es.snapshots[0].Seq, errInconsistentEvent)
+	}
+	if version == 0 && len(es.events) == 0 {
+		if err := writeEventLogVersion(es.path, EventLogVersion); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	es.file = file
+	return nil
+}
+
+// readEvents reads the lines of the event log checking that events follow each other
+func readEvents(r io.Reader) ([]model.TaskEvent, error) {
+	events := make([]model.TaskEvent, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxEventSize)
+	for scanner.Scan() {
+		var e model.TaskEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("event(%v): %w", len(events)+1, err)
+		}
+		if e.Seq != len(events)+1 {
+			return nil, fmt.Errorf("event(%v) has seq %v: %w", len(events)+1, e.Seq, errInconsistentEvent)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Close closes the file of the event log, the storage can't be changed after it
+func (es *EventTaskStorage) Close() error {
+	es.m.Lock()
+	defer es.m.Unlock()
+	if es.file == nil {
+		return nil
+	}
+	err := es.file.Close()
+	es.file = nil
+	return err
+}
+
+// fold builds a projection from the snapshot, nil for an empty state, and the events following it
+func (es *EventTaskStorage) fold(snapshot *projectionSnapshot, events []model.TaskEvent) (*TaskStorage, error) {
+	st := &TaskStorage{
+		partitions:       make(map[int]*taskPartition),
+		logger:           es.logger,
+		clock:            es.clock,
+		strictCompletion: es.strictCompletion,
+	}
+	if snapshot != nil {
+		for _, state := range snapshot.Partitions {
+			st.partitions[state.ProjectID] = restorePartition(state)
+		}
+	}
+	for _, e := range events {
+		if err := st.partitionOf(e.ProjectID).applyEvent(e); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// Rebuild folds the whole event log into a new projection and replaces the current one with it
+func (es *EventTaskStorage) Rebuild(ctx context.Context) error {
+	es.m.Lock()
+	defer es.m.Unlock()
+	projection, err := es.fold(nil, es.events)
+	if err != nil {
+		return fmt.Errorf("%v: error while rebuilding projection: %w", eventStorageName, err)
+	}
+	es.projection.Store(projection)
+
+	es.logger.Log("Rebuilt projection of %s from %v events", eventStorageName, len(es.events))
+	return nil
+}
+
+// GetByTaskIdAsOf returns the task as it was at the moment, model.ErrNotFound if it didn't exist then
+func (es *EventTaskStorage) GetByTaskIdAsOf(ctx context.Context, taskId int, asOf time.Time) (*model.Task, error) {
+	projectId := tenant.FromContext(ctx).ProjectID
+
+	es.m.RLock()
+	p, err := es.partitionAsOf(projectId, asOf)
+	es.m.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("%v: error while folding project(%v) as of %v: %w", eventStorageName, projectId, asOf, err)
+	}
+	if !p.exists(taskId) {
+		return nil, fmt.Errorf("%v: error while retrieving task by id(%v) as of %v: %w", eventStorageName, taskId, asOf, model.ErrNotFound)
+	}
+
+	ans := p.tasks[taskId]
+	return &ans, nil
+}
+
+// partitionAsOf folds events of the project recorded until the moment, starting from the latest
+// snapshot taken before it, must be called under lock
+func (es *EventTaskStorage) partitionAsOf(projectId int, asOf time.Time) (*taskPartition, error) {
+	p := newTaskPartition(projectId)
+	from := 0
+	if i := sort.Search(len(es.snapshots), func(i int) bool { return es.snapshots[i].At.After(asOf) }) - 1; i >= 0 {
+		if state, ok := es.snapshots[i].partition(projectId); ok {
+			p = restorePartition(state)
+		}
+		from = es.snapshots[i].Seq
+	}
+	for _, e := range es.events[from:] {
+		if e.At.After(asOf) {
+			break
+		}
+		if e.ProjectID != projectId {
+			continue
+		}
+		if err := p.applyEvent(e); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// mutate runs decide under the lock of the partition of the project from the context scope.
+// decide checks the change and records its events with record, then a snapshot is taken if it's due.
+func (es *EventTaskStorage) mutate(ctx context.Context, decide func(p *taskPartition, scope tenant.Scope) error) error {
+	es.m.Lock()
+	defer es.m.Unlock()
+	projection := es.projection.Load()
+	p, scope := projection.partitionForWrite(ctx)
+
+	p.m.Lock()
+	err := decide(p, scope)
+	p.m.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if es.snapshotInterval > 0 && len(es.events)-es.lastSnapshotSeq() >= es.snapshotInterval {
+		if err := es.snapshot(projection); err != nil {
+			// the events are already recorded, only recovery gets slower
+			es.logger.Log("Failed to take snapshot of %s at event %v: %v", eventStorageName, len(es.events), err)
+		}
+	}
+	return nil
+}
+
+// record appends the events to the log and folds them into the partition, must be called
+// under both locks. Events are written to the file before they are folded.
+func (es *EventTaskStorage) record(p *taskPartition, events ...model.TaskEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if es.path != "" && es.file == nil {
+		return fmt.Errorf("event log is closed")
+	}
+
+	now := es.clock.Now().UTC()
+	if len(es.events) > 0 && now.Before(es.events[len(es.events)-1].At) {
+		// point-in-time reads rely on events being ordered by time
+		now = es.events[len(es.events)-1].At
+	}
+	var buf bytes.Buffer
+	for i := range events {
+		events[i].Seq = len(es.events) + i + 1
+		events[i].ProjectID = p.projectId
+		events[i].At = now
+		if es.file != nil {
+			line, err := json.Marshal(events[i])
+			if err != nil {
+				return err
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+	if es.file != nil {
+		if _, err := es.file.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("error while writing events: %w", err)
+		}
+	}
+
+	for _, e := range events {
+		es.events = append(es.events, e)
+		if err := p.applyEvent(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (es *EventTaskStorage) lastSnapshotSeq() int {
+	if len(es.snapshots) == 0 {
+		return 0
+	}
+	return es.snapshots[len(es.snapshots)-1].Seq
+}
+
+// snapshot copies every partition of the projection and writes the copy next to the event log,
+// must be called under lock
+func (es *EventTaskStorage) snapshot(projection *TaskStorage) error {
+	snapshot := projectionSnapshot{
+		Seq: len(es.events),
+		At:  es.events[len(es.events)-1].At,
+	}
+	projection.m.RLock()
+	for _, p := range projection.partitions {
+		p.m.RLock()
+		snapshot.Partitions = append(snapshot.Partitions, p.state())
+		p.m.RUnlock()
+	}
+	projection.m.RUnlock()
+	slices.SortFunc(snapshot.Partitions, func(a, b partitionState) int { return a.ProjectID - b.ProjectID })
+
+	es.snapshots = append(es.snapshots, snapshot)
+	if len(es.snapshots) > maxSnapshots {
+		kept := es.snapshots[:0]
+		for i, s := range es.snapshots {
+			if i%2 == 1 || i == len(es.snapshots)-1 {
+				kept = append(kept, s)
+			}
+		}
+		es.snapshots = kept
+	}
+
+	if es.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	tmp := es.path + snapshotSuffix + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, es.path+snapshotSuffix)
+}
+
+func (es *EventTaskStorage) Store(ctx context.Context, task model.Task) (int, error) {
+	err := es.mutate(ctx, func(p *taskPartition, scope tenant.Scope) error {
+		if err := p.checkStore(task, scope); err != nil {
+			return err
+		}
+		publicId, err := nextPublicId(es.ids)
+		if err != nil {
+			return err
+		}
+		task.Id, task.PublicID = p.idCounter, publicId
+		return es.record(p, model.TaskEvent{Type: model.TaskCreated, TaskID: task.Id, Task: &task})
+	})
+	if err != nil {
+		return -1, fmt.Errorf("%v: error while storing task: %w", eventStorageName, err)
+	}
+
+	es.logger.Log("Stored task: %v sucsessfully", task.Id)
+
+	return task.Id, nil
+}
+
+func (es *EventTaskStorage) Update(ctx context.Context, task model.Task) (*model.Task, error) {
+	var updated model.Task
+	err := es.mutate(ctx, func(p *taskPartition, _ tenant.Scope) error {
+		if err := p.checkUpdate(task, es.strictCompletion); err != nil {
+			return err
+		}
+		if err := es.record(p, updateEvents(p.tasks[task.Id], task)...); err != nil {
+			return err
+		}
+		updated = p.tasks[task.Id]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v: error while updating task by id(%v): %w", eventStorageName, task.Id, err)
+	}
+
+	es.logger.Log("Updated task: %v sucsessfully", updated)
+
+	return &updated, nil
+}
+
+func (es *EventTaskStorage) Delete(ctx context.Context, taskId int) error {
+	err := es.mutate(ctx, func(p *taskPartition, _ tenant.Scope) error {
+		if !p.exists(taskId) {
+			return model.ErrNotFound
+		}
+		return es.record(p, model.TaskEvent{Type: model.TaskDeleted, TaskID: taskId})
+	})
+	if err != nil {
+		return fmt.Errorf("%v: error while deleting task by id(%v): %w", eventStorageName, taskId, err)
+	}
+
+	es.logger.Log("Deleted task: %v sucsessfully", taskId)
+
+	return nil
+}
+
+func (es *EventTaskStorage) AddBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error) {
+	var ans model.Task
+	err := es.mutate(ctx, func(p *taskPartition, _ tenant.Scope) error {
+		if err := checkBlocker(p, taskId, blockerId); err != nil {
+			return err
+		}
+		if !slices.Contains(p.tasks[taskId].BlockedBy, blockerId) {
+			if err := es.record(p, model.TaskEvent{Type: model.BlockerAdded, TaskID: taskId, RelatedID: blockerId}); err != nil {
+				return err
+			}
+		}
+		ans = p.tasks[taskId]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v: error while adding blocker(%v) to task(%v): %w", eventStorageName, blockerId, taskId, err)
+	}
+	return &ans, nil
+}
+
+func (es *EventTaskStorage) RemoveBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error) {
+	var ans model.Task
+	err := es.mutate(ctx, func(p *taskPartition, _ tenant.Scope) error {
+		if !p.exists(taskId) || !p.exists(blockerId) {
+			return model.ErrNotFound
+		}
+		if slices.Contains(p.tasks[taskId].BlockedBy, blockerId) {
+			if err := es.record(p, model.TaskEvent{Type: model.BlockerRemoved, TaskID: taskId, RelatedID: blockerId}); err != nil {
+				return err
+			}
+		}
+		ans = p.tasks[taskId]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v: error while removing blocker(%v) of task(%v): %w", eventStorageName, blockerId, taskId, err)
+	}
+	return &ans, nil
+}
+
+func (es *EventTaskStorage) StoreTag(ctx context.Context, tag model.Tag) (int, error) {
+	err := es.mutate(ctx, func(p *taskPartition, _ tenant.Scope) error {
+		if err := p.checkTagName(tag); err != nil {
+			return err
+		}
+		tag.Id = p.tagCounter + 1
+		return es.record(p, model.TaskEvent{Type: model.TagCreated, Tag: &tag})
+	})
+	if err != nil {
+		return -1, fmt.Errorf("%v: error while storing tag %v: %w", eventStorageName, tag.Name, err)
+	}
+
+	es.logger.Log("Stored tag: %v sucsessfully", tag.Id)
+
+	return tag.Id, nil
+}
+
+func (es *EventTaskStorage) UpdateTag(ctx context.Context, tag model.Tag) (*model.Tag, error) {
+	var updated model.Tag
+	err := es.mutate(ctx, func(p *taskPartition, _ tenant.Scope) error {
+		stored, ok := p.tags[tag.Id]
+		if !ok {
+			return model.ErrNotFound
+		}
+		if err := p.checkTagName(tag); err != nil {
+			return err
+		}
+		if stored.Name != tag.Name {
+			renamed := model.Tag{Id: tag.Id, Name: tag.Name}
+			if err := es.record(p, model.TaskEvent{Type: model.TagRenamed, Tag: &renamed}); err != nil {
+				return err
+			}
+		}
+		updated = p.tagWithCount(tag.Id)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v: error while updating tag by id(%v): %w", eventStorageName, tag.Id, err)
+	}
+	return &updated, nil
+}
+
+func (es *EventTaskStorage) DeleteTag(ctx context.Context, tagId int) error {
+	err := es.mutate(ctx, func(p *taskPartition, _ tenant.Scope) error {
+		if _, ok := p.tags[tagId]; !ok {
+			return model.ErrNotFound
+		}
+		return es.record(p, model.TaskEvent{Type: model.TagDeleted, RelatedID: tagId})
+	})
+	if err != nil {
+		return fmt.Errorf("%v: error while deleting tag by id(%v): %w", eventStorageName, tagId, err)
+	}
+	return nil
+}
+
+func (es *EventTaskStorage) AttachTag(ctx context.Context, taskId, tagId int) (*model.Task, error) {
+	return es.tagging(ctx, model.TagAttached, taskId, tagId)
+}
+
+func (es *EventTaskStorage) DetachTag(ctx context.Context, taskId, tagId int) (*model.Task, error) {
+	return es.tagging(ctx, model.TagDetached, taskId, tagId)
+}
+
+// tagging records attaching or detaching the tag if it changes the task
+func (es *EventTaskStorage) tagging(ctx context.Context, eventType model.TaskEventType, taskId, tagId int) (*model.Task, error) {
+	var ans model.Task
+	err := es.mutate(ctx, func(p *taskPartition, _ tenant.Scope) error {
+		if err := p.checkTagging(taskId, tagId); err != nil {
+			return err
+		}
+		if slices.Contains(p.tasks[taskId].TagIDs, tagId) != (eventType == model.TagAttached) {
+			if err := es.record(p, model.TaskEvent{Type: eventType, TaskID: taskId, RelatedID: tagId}); err != nil {
+				return err
+			}
+		}
+		ans = p.tasks[taskId]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%v: error while changing tag(%v) of task(%v): %w", eventStorageName, tagId, taskId, err)
+	}
+	return &ans, nil
+}
+
+func (es *EventTaskStorage) GetAll(ctx context.Context, filter model.Filter) ([]model.Task, int, error) {
+	return es.projection.Load().GetAll(ctx, filter)
+}
+
+func (es *EventTaskStorage) ForEach(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+	return es.projection.Load().ForEach(ctx, filter, fn)
+}
+
+func (es *EventTaskStorage) GetByTaskId(ctx context.Context, taskId int) (*model.Task, error) {
+	return es.projection.Load().GetByTaskId(ctx, taskId)
+}
+
+func (es *EventTaskStorage) GetByPublicId(ctx context.Context, publicId string) (*model.Task, error) {
+	return es.projection.Load().GetByPublicId(ctx, publicId)
+}
+
+func (es *EventTaskStorage) GetSubtasks(ctx context.Context, taskId, depth int) (*model.TaskNode, error) {
+	return es.projection.Load().GetSubtasks(ctx, taskId, depth)
+}
+
+func (es *EventTaskStorage) DependencyOrder(ctx context.Context, taskId int) ([]model.Task, error) {
+	return es.projection.Load().DependencyOrder(ctx, taskId)
+}
+
+func (es *EventTaskStorage) GetAllTags(ctx context.Context) ([]model.Tag, error) {
+	return es.projection.Load().GetAllTags(ctx)
+}
+
+func (es *EventTaskStorage) GetTagById(ctx context.Context, tagId int) (*model.Tag, error) {
+	return es.projection.Load().GetTagById(ctx, tagId)
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/idgen"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stepClock returns times a minute apart
+type stepClock struct {
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	c.now = c.now.Add(time.Minute)
+	return c.now
+}
+
+// fakeClock returns times a minute apart starting from start
+func fakeClock(start time.Time) *stepClock {
+	return &stepClock{now: start}
+}
+
+// fillEventStorage runs every kind of mutation in two projects
+func fillEventStorage(t *testing.T, es *EventTaskStorage) {
+	t.Helper()
+	ctx := context.Background()
+	other := tenant.WithScope(ctx, tenant.Scope{ProjectID: 2})
+	parentOf := func(id int) *int { return &id }
+
+	root, _ := es.Store(ctx, model.Task{Name: "root", Status: model.Created})
+	child, _ := es.Store(ctx, model.Task{Name: "child", Status: model.Created, ParentID: parentOf(root)})
+	blocker, _ := es.Store(ctx, model.Task{Name: "blocker", Status: model.InProgress, Priority: model.PriorityHigh,
+		Recurrence: &model.Recurrence{Rule: "0 9 * * MON", TimeZone: "Europe/Berlin", Start: time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)}})
+	deleted, _ := es.Store(ctx, model.Task{Name: "deleted", Status: model.Created})
+	es.Store(other, model.Task{Name: "other project", Status: model.Created})
+
+	if _, err := es.AddBlocker(ctx, root, blocker); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tagId, _ := es.StoreTag(ctx, model.Tag{Name: "backend"})
+	removedTag, _ := es.StoreTag(ctx, model.Tag{Name: "removed"})
+	es.AttachTag(ctx, child, tagId)
+	es.AttachTag(ctx, blocker, removedTag)
+	es.UpdateTag(ctx, model.Tag{Id: tagId, Name: "api"})
+	es.DeleteTag(ctx, removedTag)
+
+	task, _ := es.GetByTaskId(ctx, child)
+	task.Name, task.Status, task.Description = "renamed child", model.Done, "details"
+	task.Recurrence = &model.Recurrence{Rule: "FREQ=DAILY", Start: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)}
+	if _, err := es.Update(ctx, *task); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := es.Delete(ctx, deleted); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// projectState returns everything reads of the project can see
+func projectState(t *testing.T, ctx context.Context, es *EventTaskStorage) ([]model.Task, []model.Tag) {
+	t.Helper()
+	tasks, _, err := es.GetAll(ctx, model.Filter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tags, err := es.GetAllTags(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return tasks, tags
+}
+
+func TestEventTaskStorage(t *testing.T) {
+	ctx := context.Background()
+	es, _ := NewEventTaskStorage(&MockLogger{}, WithEventStrictCompletion(true), WithSnapshotInterval(4))
+	es.clock = fakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	fillEventStorage(t, es)
+
+	t.Run("reads of the projection", func(t *testing.T) {
+		tasks, tags := projectState(t, ctx, es)
+		if len(tasks) != 3 || tasks[1].Name != "renamed child" || tasks[1].Status != model.Done || tasks[1].CompletedAt.IsZero() {
+			t.Errorf("Unexpected tasks %+v", tasks)
+		}
+		if tasks[1].Recurrence == nil || tasks[1].Recurrence.Rule != "FREQ=DAILY" || tasks[2].Recurrence == nil || tasks[2].Recurrence.TimeZone != "Europe/Berlin" {
+			t.Errorf("Unexpected recurrences %+v", tasks)
+		}
+		if !reflect.DeepEqual(tasks[1].TagIDs, []int{1}) || !reflect.DeepEqual(tasks[0].BlockedBy, []int{2}) || tasks[2].TagIDs != nil {
+			t.Errorf("Unexpected tags or blockers %+v", tasks)
+		}
+		if len(tags) != 1 || tags[0].Name != "api" || tags[0].TaskCount != 1 {
+			t.Errorf("Unexpected tags %+v", tags)
+		}
+		node, _ := es.GetSubtasks(ctx, 0, 1)
+		if len(node.Subtasks) != 1 || node.Subtasks[0].Task.Id != 1 {
+			t.Errorf("Unexpected subtasks %+v", node)
+		}
+	})
+
+	t.Run("rejected changes aren't recorded", func(t *testing.T) {
+		recorded := len(es.events)
+		if _, err := es.AddBlocker(ctx, 2, 0); !errors.Is(err, model.ErrCycle) {
+			t.Errorf("Expected ErrCycle, got %v", err)
+		}
+		root, _ := es.GetByTaskId(ctx, 0)
+		root.Status = model.Done
+		if _, err := es.Update(ctx, *root); !errors.Is(err, model.ErrIncomplete) {
+			t.Errorf("Expected ErrIncomplete, got %v", err)
+		}
+		if err := es.Delete(ctx, 3); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if _, err := es.StoreTag(ctx, model.Tag{Name: "api"}); !errors.Is(err, model.ErrAlreadyExists) {
+			t.Errorf("Expected ErrAlreadyExists, got %v", err)
+		}
+		if _, err := es.AttachTag(ctx, 1, 1); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if len(es.events) != recorded {
+			t.Errorf("Expected %v events, got %v", recorded, len(es.events))
+		}
+	})
+
+	t.Run("update is split into events", func(t *testing.T) {
+		types := make([]model.TaskEventType, 0)
+		for _, e := range es.events {
+			if e.TaskID == 1 && (e.Type == model.TaskRenamed || e.Type == model.TaskStatusChanged || e.Type == model.TaskDetailsChanged) {
+				types = append(types, e.Type)
+			}
+		}
+		want := []model.TaskEventType{model.TaskDetailsChanged, model.TaskRenamed, model.TaskStatusChanged}
+		if !reflect.DeepEqual(types, want) {
+			t.Errorf("Expected events %v, got %v", want, types)
+		}
+	})
+
+	t.Run("rebuild", func(t *testing.T) {
+		other := tenant.WithScope(ctx, tenant.Scope{ProjectID: 2})
+		tasks, tags := projectState(t, ctx, es)
+		otherTasks, _ := projectState(t, other, es)
+		if err := es.Rebuild(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		rebuiltTasks, rebuiltTags := projectState(t, ctx, es)
+		rebuiltOther, _ := projectState(t, other, es)
+		if !reflect.DeepEqual(tasks, rebuiltTasks) || !reflect.DeepEqual(tags, rebuiltTags) || !reflect.DeepEqual(otherTasks, rebuiltOther) {
+			t.Errorf("Rebuilt projection differs:\n%+v\n%+v", tasks, rebuiltTasks)
+		}
+		if _, err := es.Store(ctx, model.Task{Name: "after rebuild", Status: model.Created}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestEventTaskStorageAsOf(t *testing.T) {
+	ctx := context.Background()
+	for _, interval := range []int{0, 1, 3} {
+		es, _ := NewEventTaskStorage(&MockLogger{}, WithSnapshotInterval(interval))
+		start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		es.clock = fakeClock(start)
+		fillEventStorage(t, es)
+		minute := func(n int) time.Time { return start.Add(time.Duration(n) * time.Minute) }
+
+		tests := []struct {
+			name    string
+			taskId  int
+			asOf    time.Time
+			want    string
+			wantErr error
+		}{
+			{name: "before creation", taskId: 1, asOf: minute(1), wantErr: model.ErrNotFound},
+			{name: "created", taskId: 1, asOf: minute(2), want: "child"},
+			{name: "between events", taskId: 1, asOf: minute(13).Add(-time.Second), want: "child"},
+			{name: "renamed", taskId: 1, asOf: minute(15), want: "renamed child"},
+			{name: "before deletion", taskId: 3, asOf: minute(13), want: "deleted"},
+			{name: "deleted", taskId: 3, asOf: minute(14), wantErr: model.ErrNotFound},
+			{name: "far future", taskId: 1, asOf: minute(1000), want: "renamed child"},
+		}
+		for _, tt := range tests {
+			task, err := es.GetByTaskIdAsOf(ctx, tt.taskId, tt.asOf)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("%v with interval %v: expected error %v, got %v", tt.name, interval, tt.wantErr, err)
+				continue
+			}
+			if err == nil && task.Name != tt.want {
+				t.Errorf("%v with interval %v: expected %q, got %q", tt.name, interval, tt.want, task.Name)
+			}
+		}
+
+		past, _ := es.GetByTaskIdAsOf(ctx, 1, minute(10))
+		if len(past.TagIDs) != 1 || past.Status != model.Created || !past.CompletedAt.IsZero() {
+			t.Errorf("Unexpected past state with interval %v: %+v", interval, past)
+		}
+		other := tenant.WithScope(ctx, tenant.Scope{ProjectID: 2})
+		if task, err := es.GetByTaskIdAsOf(other, 0, minute(1000)); err != nil || task.Name != "other project" {
+			t.Errorf("Unexpected task of the other project %+v, %v", task, err)
+		}
+	}
+}
+
+func TestEventTaskStorageRecovery(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	es, err := NewEventTaskStorage(&MockLogger{}, WithEventLog(path), WithSnapshotInterval(5), WithEventIDGenerator(idgen.NewSequential(1)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fillEventStorage(t, es)
+	tasks, tags := projectState(t, ctx, es)
+	recorded := len(es.events)
+	es.Close()
+	if _, err := es.Store(ctx, model.Task{Name: "closed", Status: model.Created}); err == nil {
+		t.Errorf("Expected an error from the closed storage")
+	}
+	if _, err := os.Stat(path + snapshotSuffix); err != nil {
+		t.Fatalf("Expected a snapshot file: %v", err)
+	}
+
+	t.Run("from snapshot and log", func(t *testing.T) {
+		recovered, err := NewEventTaskStorage(&MockLogger{}, WithEventLog(path), WithSnapshotInterval(5))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer recovered.Close()
+		if len(recovered.events) != recorded || recovered.lastSnapshotSeq() != recorded/5*5 {
+			t.Errorf("Expected %v events and snapshot of %v, got %v and %v", recorded, recorded/5*5, len(recovered.events), recovered.lastSnapshotSeq())
+		}
+		gotTasks, gotTags := projectState(t, ctx, recovered)
+		if !reflect.DeepEqual(tasks, gotTasks) || !reflect.DeepEqual(tags, gotTags) {
+			t.Errorf("Recovered state differs:\n%+v\n%+v", tasks, gotTasks)
+		}
+		if task, err := recovered.GetByPublicId(ctx, tasks[0].PublicID); err != nil || tasks[0].PublicID == "" || task.Id != tasks[0].Id {
+			t.Errorf("Expected task %v by public id %q, got %+v, %v", tasks[0].Id, tasks[0].PublicID, task, err)
+		}
+		if id, err := recovered.Store(ctx, model.Task{Name: "after recovery", Status: model.Created}); err != nil || id != 4 {
+			t.Errorf("Expected id 4, got %v, %v", id, err)
+		}
+	})
+
+	t.Run("broken log", func(t *testing.T) {
+		data, _ := os.ReadFile(path)
+		broken := filepath.Join(t.TempDir(), "broken.jsonl")
+		os.WriteFile(broken, append(data, []byte("{\"seq\":100}\n")...), 0o644)
+		if _, err := NewEventTaskStorage(&MockLogger{}, WithEventLog(broken)); !errors.Is(err, errInconsistentEvent) {
+			t.Errorf("Expected errInconsistentEvent, got %v", err)
+		}
+	})
+}
+
+func TestRestoreLegacySnapshot(t *testing.T) {
+	parent := 0
+	// snapshots before NextID kept deleted tasks as placeholders in their positions
+	legacy := partitionState{
+		ProjectID: 1,
+		Tasks: []model.Task{
+			{Id: 0, ProjectID: 1, Name: "parent", Status: model.Created},
+			{Id: 1, ProjectID: 1},
+			{Id: 2, ProjectID: 1, Name: "child", Status: model.Created, ParentID: &parent},
+		},
+		Deleted: []int{1},
+	}
+
+	p := restorePartition(legacy)
+	if p.exists(1) || !p.exists(0) || !p.exists(2) || p.count() != 2 || p.idCounter != 3 {
+		t.Fatalf("Expected tasks 0 and 2 and the next id 3, got %v tasks and the next id %v", p.count(), p.idCounter)
+	}
+	if _, ok := p.subtaskIds(0)[2]; !ok {
+		t.Errorf("Expected task 2 to be a subtask of task 0")
+	}
+
+	state := p.state()
+	if state.NextID != 3 || len(state.Tasks) != 2 || len(state.Deleted) != 0 || state.Tasks[1].Id != 2 {
+		t.Errorf("Expected live tasks in order of ids and the next id 3, got %+v", state)
+	}
+	if restored := restorePartition(state); restored.count() != 2 || restored.idCounter != 3 {
+		t.Errorf("Expected the state to restore the partition, got %v tasks and the next id %v", restored.count(), restored.idCounter)
+	}
+}
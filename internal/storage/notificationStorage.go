@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"slices"
+	"sync"
+	"time"
+)
+
+const notificationStorageName = "NotificationStorage"
+
+// NotificationStorage keeps notification preferences of users and the reminders sent to them.
+// Users and their preferences aren't scoped to projects. Sent reminders are kept for the lifetime
+// of the storage, so a reminder isn't sent twice while its anchor stays the same.
+type NotificationStorage struct {
+	preferences map[int]model.NotificationPreferences
+	sent        map[model.ReminderKey]time.Time
+	logger      Logger
+	m           sync.RWMutex
+}
+
+func NewNotificationStorage(logger Logger) (*NotificationStorage, error) {
+	logger.Log("Created %s successfully", notificationStorageName)
+
+	return &NotificationStorage{
+		preferences: make(map[int]model.NotificationPreferences),
+		sent:        make(map[model.ReminderKey]time.Time),
+		logger:      logger,
+	}, nil
+}
+
+// GetPreferences returns preferences of the user, ErrNotFound means the user hasn't set any
+func (ns *NotificationStorage) GetPreferences(ctx context.Context, userId int) (*model.NotificationPreferences, error) {
+	ns.m.RLock()
+	defer ns.m.RUnlock()
+	preferences, ok := ns.preferences[userId]
+	if !ok {
+		return nil, fmt.Errorf("%v: error while retrieving preferences of user(%v): %w", notificationStorageName, userId, model.ErrNotFound)
+	}
+	preferences = clonePreferences(preferences)
+
+	return &preferences, nil
+}
+
+// PutPreferences replaces preferences of the user
+func (ns *NotificationStorage) PutPreferences(ctx context.Context, preferences model.NotificationPreferences) error {
+	ns.m.Lock()
+	defer ns.m.Unlock()
+	ns.preferences[preferences.UserID] = clonePreferences(preferences)
+
+	return nil
+}
+
+// WasSent reports whether the reminder was sent
+func (ns *NotificationStorage) WasSent(ctx context.Context, key model.ReminderKey) (bool, error) {
+	ns.m.RLock()
+	defer ns.m.RUnlock()
+	_, ok := ns.sent[normalizeKey(key)]
+
+	return ok, nil
+}
+
+// MarkSent remembers the reminder sent at the time
+func (ns *NotificationStorage) MarkSent(ctx context.Context, key model.ReminderKey, at time.Time) error {
+	ns.m.Lock()
+	defer ns.m.Unlock()
+	ns.sent[normalizeKey(key)] = at
+
+	return nil
+}
+
+// normalizeKey drops the location and the monotonic reading of the anchor, so equal moments make equal keys
+func normalizeKey(key model.ReminderKey) model.ReminderKey {
+	key.Anchor = key.Anchor.UTC().Round(0)
+	return key
+}
+
+func clonePreferences(preferences model.NotificationPreferences) model.NotificationPreferences {
+	preferences.Channels = slices.Clone(preferences.Channels)
+	preferences.Muted = slices.Clone(preferences.Muted)
+	return preferences
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"testing"
+	"time"
+)
+
+func TestNotificationStorage(t *testing.T) {
+	storage, err := NewNotificationStorage(&MockLogger{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("preferences", func(t *testing.T) {
+		if _, err := storage.GetPreferences(ctx, 1); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		channels := []model.NotificationChannel{model.ChannelEmail}
+		if err := storage.PutPreferences(ctx, model.NotificationPreferences{UserID: 1, Channels: channels}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		channels[0] = model.ChannelWebhook
+
+		preferences, err := storage.GetPreferences(ctx, 1)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(preferences.Channels) != 1 || preferences.Channels[0] != model.ChannelEmail {
+			t.Errorf("Expected stored preferences not to change with the request, got %+v", preferences)
+		}
+	})
+
+	t.Run("sent reminders", func(t *testing.T) {
+		anchor := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+		key := model.ReminderKey{Rule: model.ReminderOverdue, ProjectID: 1, TaskID: 1, Anchor: anchor, UserID: 1, Channel: model.ChannelLog}
+
+		if sent, _ := storage.WasSent(ctx, key); sent {
+			t.Errorf("Expected the reminder not to be sent yet")
+		}
+		if err := storage.MarkSent(ctx, key, anchor); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		berlin := key
+		berlin.Anchor = anchor.In(time.FixedZone("CEST", 2*60*60))
+		if sent, _ := storage.WasSent(ctx, berlin); !sent {
+			t.Errorf("Expected the same moment in another zone to be sent")
+		}
+		other := key
+		other.Channel = model.ChannelEmail
+		if sent, _ := storage.WasSent(ctx, other); sent {
+			t.Errorf("Expected another channel not to be sent")
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"sort"
+	"sync"
+)
+
+const projectStorageName = "ProjectStorage"
+
+type ProjectStorage struct {
+	projects  map[int]model.Project
+	idCounter int
+	logger    Logger
+	clock     Clock
+	m         sync.RWMutex
+}
+
+// ProjectStorageOption configures optional behaviour of ProjectStorage
+type ProjectStorageOption func(*ProjectStorage)
+
+// WithProjectClock replaces the system clock projects are timestamped with
+func WithProjectClock(clock Clock) ProjectStorageOption {
+	return func(ps *ProjectStorage) {
+		ps.clock = clock
+	}
+}
+
+// NewProjectStorage creates a storage with the default project, which holds tasks of the legacy /tasks routes
+func NewProjectStorage(logger Logger, defaultQuota int, opts ...ProjectStorageOption) (*ProjectStorage, error) {
+	if defaultQuota < model.NoQuota {
+		return nil, fmt.Errorf("%v: negative default task quota(%v)", projectStorageName, defaultQuota)
+	}
+	ps := &ProjectStorage{
+		projects:  make(map[int]model.Project),
+		idCounter: model.DefaultProjectId,
+		logger:    logger,
+		clock:     clock.System{},
+	}
+	for _, opt := range opts {
+		opt(ps)
+	}
+	ps.projects[model.DefaultProjectId] = model.Project{
+		Id:        model.DefaultProjectId,
+		Name:      "default",
+		TaskQuota: defaultQuota,
+		CreatedAt: ps.clock.Now(),
+	}
+	logger.Log("Created %s successfully", projectStorageName)
+
+	return ps, nil
+}
+
+// Store saves a new project, ids continue after the default project
+func (ps *ProjectStorage) Store(ctx context.Context, project model.Project) (int, error) {
+	ps.m.Lock()
+	defer ps.m.Unlock()
+	ps.idCounter++
+	project.Id = ps.idCounter
+	project.CreatedAt = ps.clock.Now()
+	ps.projects[project.Id] = project
+
+	ps.logger.Log("Stored project: %v sucsessfully", project)
+
+	return project.Id, nil
+}
+
+func (ps *ProjectStorage) GetAll(ctx context.Context) ([]model.Project, error) {
+	ps.m.RLock()
+	defer ps.m.RUnlock()
+	ans := make([]model.Project, 0, len(ps.projects))
+	for _, project := range ps.projects {
+		ans = append(ans, project)
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Id < ans[j].Id })
+
+	return ans, nil
+}
+
+func (ps *ProjectStorage) GetByProjectId(ctx context.Context, projectId int) (*model.Project, error) {
+	ps.m.RLock()
+	defer ps.m.RUnlock()
+	project, ok := ps.projects[projectId]
+	if !ok {
+		return nil, fmt.Errorf("%v: error while retrieving project by id(%v): %w", projectStorageName, projectId, model.ErrNotFound)
+	}
+
+	return &project, nil
+}
+
+// Update replaces name and task quota of an existing project
+func (ps *ProjectStorage) Update(ctx context.Context, project model.Project) (*model.Project, error) {
+	ps.m.Lock()
+	defer ps.m.Unlock()
+	stored, ok := ps.projects[project.Id]
+	if !ok {
+		return nil, fmt.Errorf("%v: error while updating project by id(%v): %w", projectStorageName, project.Id, model.ErrNotFound)
+	}
+	stored.Name = project.Name
+	stored.TaskQuota = project.TaskQuota
+	ps.projects[project.Id] = stored
+
+	return &stored, nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"testing"
+)
+
+func TestProjectStorage(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, err := NewProjectStorage(mockLogger, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("default project exists", func(t *testing.T) {
+		project, err := storage.GetByProjectId(ctx, model.DefaultProjectId)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if project.TaskQuota != 100 {
+			t.Errorf("Expected default quota 100, got %d", project.TaskQuota)
+		}
+	})
+
+	id, err := storage.Store(ctx, model.Project{Name: "Team A", TaskQuota: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if id != model.DefaultProjectId+1 {
+		t.Errorf("Expected id right after the default project, got %d", id)
+	}
+
+	t.Run("get all", func(t *testing.T) {
+		projects, _ := storage.GetAll(ctx)
+		if len(projects) != 2 || projects[0].Id != model.DefaultProjectId || projects[1].Id != id {
+			t.Errorf("Unexpected projects %v", projects)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		updated, err := storage.Update(ctx, model.Project{Id: id, Name: "Team B", TaskQuota: 20})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if updated.Name != "Team B" || updated.TaskQuota != 20 || updated.CreatedAt.IsZero() {
+			t.Errorf("Unexpected project %v", updated)
+		}
+
+		if _, err := storage.Update(ctx, model.Project{Id: 99, Name: "x"}); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	if _, err := NewProjectStorage(mockLogger, -1); err == nil {
+		t.Error("Expected error for negative default quota")
+	}
+}
@@ -0,0 +1,706 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"math/bits"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const shardedStorageName = "ShardedTaskStorage"
+
+// DefaultShards is an amount of shards of every project of ShardedTaskStorage
+const DefaultShards = 32
+
+// errRestructure is returned by the fast path of Update when the update reads other tasks
+// and has to be retried under the graph lock
+var errRestructure = errors.New("update changes the graph")
+
+// ShardedTaskStorage keeps tasks of every project like TaskStorage does, but splits them into shards
+// by id, so writers of different tasks don't wait for each other.
+//
+// Every shard has its own lock and an index of its tasks by status. Ids are allocated and the quota
+// is reserved with atomic counters, so storing a task without a parent takes a single shard lock.
+// Changes linking tasks together (parents, blockers, tags and deletes) hold the graph lock
+// of the project for writing, reads of the graphs and updates hold it for reading.
+//
+// The graph lock is always taken before shard locks, and a shard lock is never held while
+// another one is taken.
+type ShardedTaskStorage struct {
+	// projects maps project ids to *shardedProject
+	projects sync.Map
+	shards   int
+	logger   Logger
+	clock    Clock
+	// ids is nil when tasks have no public ids
+	ids IDGenerator
+	// strictCompletion forbids finishing tasks with open blockers or unfinished subtasks
+	strictCompletion bool
+}
+
+// ShardedTaskStorageOption configures optional behaviour of ShardedTaskStorage
+type ShardedTaskStorageOption func(*ShardedTaskStorage)
+
+// WithShards sets an amount of shards of every project, it's rounded up to a power of two
+func WithShards(shards int) ShardedTaskStorageOption {
+	return func(st *ShardedTaskStorage) {
+		st.shards = shards
+	}
+}
+
+// WithShardedStrictCompletion is WithStrictCompletion of ShardedTaskStorage
+func WithShardedStrictCompletion(strict bool) ShardedTaskStorageOption {
+	return func(st *ShardedTaskStorage) {
+		st.strictCompletion = strict
+	}
+}
+
+// WithShardedClock replaces the system clock, tests use it to control timestamps
+func WithShardedClock(clock Clock) ShardedTaskStorageOption {
+	return func(st *ShardedTaskStorage) {
+		st.clock = clock
+	}
+}
+
+// WithShardedIDGenerator gives every new task a public id from the generator
+func WithShardedIDGenerator(ids IDGenerator) ShardedTaskStorageOption {
+	return func(st *ShardedTaskStorage) {
+		st.ids = ids
+	}
+}
+
+type shardedProject struct {
+	projectId int
+	shards    []taskShard
+	mask      int
+	// nextId is the id of the next task, ids of deleted tasks aren't reused
+	nextId atomic.Int64
+	// count is an amount of tasks that aren't deleted, Store reserves a place before the task appears
+	count atomic.Int64
+	// publicIds maps public ids of tasks that aren't deleted to their ids
+	publicIds sync.Map
+
+	// graph guards the tags and both graphs below and ParentID, BlockedBy and TagIDs of tasks,
+	// while it's held for writing they may change in several shards
+	graph sync.RWMutex
+	projectTags
+	// children maps task ids to ids of their subtasks
+	children map[int]map[int]struct{}
+	// blocks maps task ids to ids of the tasks they block, it's the reverse of Task.BlockedBy
+	blocks map[int]map[int]struct{}
+}
+
+type taskShard struct {
+	tasks map[int]model.Task
+	// byStatus maps statuses to ids of the tasks of the shard in them
+	byStatus map[model.TaskStatus]map[int]struct{}
+	m        sync.RWMutex
+	// pad keeps locks of neighbouring shards in different cache lines
+	_ [64]byte
+}
+
+func newShardedProject(projectId, shards int) *shardedProject {
+	p := &shardedProject{
+		projectId:   projectId,
+		shards:      make([]taskShard, shards),
+		mask:        shards - 1,
+		projectTags: newProjectTags(),
+		children:    make(map[int]map[int]struct{}),
+		blocks:      make(map[int]map[int]struct{}),
+	}
+	for i := range p.shards {
+		p.shards[i].tasks = make(map[int]model.Task)
+		p.shards[i].byStatus = make(map[model.TaskStatus]map[int]struct{})
+	}
+	return p
+}
+
+func NewShardedTaskStorage(logger Logger, opts ...ShardedTaskStorageOption) (*ShardedTaskStorage, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", shardedStorageName)
+	}
+	st := &ShardedTaskStorage{
+		shards: DefaultShards,
+		logger: logger,
+		clock:  clock.System{},
+	}
+	for _, opt := range opts {
+		opt(st)
+	}
+	if st.clock == nil {
+		return nil, fmt.Errorf("%v: nil clock", shardedStorageName)
+	}
+	if st.shards <= 0 {
+		return nil, fmt.Errorf("%v: amount of shards must be positive, got %v", shardedStorageName, st.shards)
+	}
+	st.shards = 1 << bits.Len(uint(st.shards-1))
+
+	logger.Log("Created %s with %v shards successfully", shardedStorageName, st.shards)
+	return st, nil
+}
+
+// emptyShardedProject is returned for reads from projects without tasks, it's never modified
+var emptyShardedProject = newShardedProject(0, 1)
+
+// project returns the project from the context scope
+func (st *ShardedTaskStorage) project(ctx context.Context) *shardedProject {
+	if p, ok := st.projects.Load(tenant.FromContext(ctx).ProjectID); ok {
+		return p.(*shardedProject)
+	}
+	return emptyShardedProject
+}
+
+// projectForWrite returns the project from the context scope, creating it if needed
+func (st *ShardedTaskStorage) projectForWrite(ctx context.Context) (*shardedProject, tenant.Scope) {
+	scope := tenant.FromContext(ctx)
+	if p, ok := st.projects.Load(scope.ProjectID); ok {
+		return p.(*shardedProject), scope
+	}
+	p, _ := st.projects.LoadOrStore(scope.ProjectID, newShardedProject(scope.ProjectID, st.shards))
+	return p.(*shardedProject), scope
+}
+
+func (st *ShardedTaskStorage) Store(ctx context.Context, task model.Task) (int, error) {
+	p, scope := st.projectForWrite(ctx)
+	if err := p.reserve(scope.TaskQuota); err != nil {
+		return -1, fmt.Errorf("%v: error while storing task in project(%v): %w", shardedStorageName, p.projectId, err)
+	}
+	var parent model.Task
+	if task.ParentID != nil {
+		p.graph.Lock()
+		defer p.graph.Unlock()
+		var ok bool
+		if parent, ok = p.task(*task.ParentID); !ok {
+			p.count.Add(-1)
+			err := model.Invalid(fmt.Errorf("parent task(%v) doesn't exist", *task.ParentID))
+			return -1, fmt.Errorf("%v: error while storing task in project(%v): %w", shardedStorageName, p.projectId, err)
+		}
+	}
+	publicId, err := nextPublicId(st.ids)
+	if err != nil {
+		p.count.Add(-1)
+		return -1, fmt.Errorf("%v: error while storing task in project(%v): %w", shardedStorageName, p.projectId, err)
+	}
+	task.PublicID = publicId
+	task = newTask(task, p.projectId, st.clock.Now())
+	task.Id = int(p.nextId.Add(1) - 1)
+	task.PublicParentID = parent.PublicID
+
+	// the public id is indexed first, so a delete racing with the store always finds it
+	if task.PublicID != "" {
+		p.publicIds.Store(task.PublicID, task.Id)
+	}
+	p.put(task)
+	link(p.children, task.ParentID, task.Id)
+
+	st.logger.Log("Stored task: %v sucsessfully", task)
+
+	return task.Id, nil
+}
+
+// GetAll returns the page of the tasks matching the filter ordered by filter.SortBy, by id if it isn't set,
+// and the amount of every matching task. Tasks of a status are found with the status indexes of the shards.
+func (st *ShardedTaskStorage) GetAll(ctx context.Context, filter model.Filter) ([]model.Task, int, error) {
+	p := st.project(ctx)
+	now := st.clock.Now()
+	tasks := make([]model.Task, 0)
+
+	p.graph.RLock()
+	if ids, indexed := p.candidates(filter); indexed {
+		for _, id := range ids {
+			if task, ok := p.task(id); ok && filter.Matches(task, now) {
+				tasks = append(tasks, task)
+			}
+		}
+	} else {
+		for i := range p.shards {
+			tasks = p.shards[i].collect(tasks, filter, now)
+		}
+		slices.SortFunc(tasks, func(a, b model.Task) int { return cmp.Compare(a.Id, b.Id) })
+	}
+	p.graph.RUnlock()
+
+	// matching tasks are copied from the shards under their locks, the pager keeps only the page of them
+	pager := newTaskPager(filter, true)
+	for i := range tasks {
+		pos := i
+		if pager.ordered && filter.Descending {
+			pos = len(tasks) - 1 - i
+		}
+		pager.add(tasks[pos])
+	}
+	page, total := pager.page()
+	return page, total, nil
+}
+
+// ForEach calls fn for every task matching the filter in order of their ids like TaskStorage.ForEach does,
+// tasks of a batch are copied under the locks of their shards one by one
+func (st *ShardedTaskStorage) ForEach(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+	p := st.project(ctx)
+	now := st.clock.Now()
+	// tasks tagged after the iteration started aren't visited
+	p.graph.RLock()
+	ids, indexed := p.candidates(filter)
+	p.graph.RUnlock()
+
+	batch := make([]model.Task, 0, forEachBatchSize)
+	for pos := 0; ; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch = batch[:0]
+		limit := int(p.nextId.Load())
+		if indexed {
+			limit = len(ids)
+		}
+		for ; pos < limit && len(batch) < forEachBatchSize; pos++ {
+			id := pos
+			if indexed {
+				id = ids[pos]
+			}
+			if task, ok := p.task(id); ok && filter.Matches(task, now) {
+				batch = append(batch, task)
+			}
+		}
+		done := pos >= limit
+
+		for _, task := range batch {
+			if err := fn(task); err != nil {
+				return err
+			}
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+func (st *ShardedTaskStorage) GetByTaskId(ctx context.Context, taskId int) (*model.Task, error) {
+	task, ok := st.project(ctx).task(taskId)
+	if !ok {
+		return nil, fmt.Errorf("%v: error while retrieving task by id(%v): %w", shardedStorageName, taskId, model.ErrNotFound)
+	}
+	return &task, nil
+}
+
+// GetByPublicId returns the task of the project from the context scope with the public id
+func (st *ShardedTaskStorage) GetByPublicId(ctx context.Context, publicId string) (*model.Task, error) {
+	p := st.project(ctx)
+	if id, ok := p.publicIds.Load(publicId); ok && publicId != "" {
+		// the task may be deleted after its id was found
+		if task, ok := p.task(id.(int)); ok && task.PublicID == publicId {
+			return &task, nil
+		}
+	}
+	return nil, fmt.Errorf("%v: error while retrieving task by public id(%q): %w", shardedStorageName, publicId, model.ErrNotFound)
+}
+
+// Update replaces mutable fields of an existing task like TaskStorage.Update does.
+//
+// Most updates change only the task itself and hold the graph lock for reading, so they run
+// in parallel. Changes of the parent and strict completions check other tasks and are retried
+// with the graph lock held for writing.
+func (st *ShardedTaskStorage) Update(ctx context.Context, task model.Task) (*model.Task, error) {
+	p := st.project(ctx)
+	now := st.clock.Now()
+
+	p.graph.RLock()
+	updated, err := p.update(task, now, st.strictCompletion, false)
+	p.graph.RUnlock()
+	if errors.Is(err, errRestructure) {
+		p.graph.Lock()
+		updated, err = p.update(task, now, st.strictCompletion, true)
+		p.graph.Unlock()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: error while updating task by id(%v): %w", shardedStorageName, task.Id, err)
+	}
+
+	st.logger.Log("Updated task: %v sucsessfully", updated)
+
+	return &updated, nil
+}
+
+func (st *ShardedTaskStorage) Delete(ctx context.Context, taskId int) error {
+	p := st.project(ctx)
+	p.graph.Lock()
+	defer p.graph.Unlock()
+	task, ok := p.task(taskId)
+	if !ok {
+		return fmt.Errorf("%v: error while deleting task by id(%v): %w", shardedStorageName, taskId, model.ErrNotFound)
+	}
+
+	for _, tagId := range task.TagIDs {
+		delete(p.tagIndex[tagId], taskId)
+	}
+	// subtasks become top level tasks and the tasks it blocked are unblocked
+	unlink(p.children, task.ParentID, taskId)
+	for childId := range p.children[taskId] {
+		p.modify(childId, func(child *model.Task) { child.ParentID, child.PublicParentID = nil, "" })
+	}
+	delete(p.children, taskId)
+	for _, blockerId := range task.BlockedBy {
+		unlink(p.blocks, &blockerId, taskId)
+	}
+	for blockedId := range p.blocks[taskId] {
+		p.setBlockers(blockedId, func(ids []int) []int { return without(ids, taskId) })
+	}
+	delete(p.blocks, taskId)
+
+	p.remove(taskId)
+	if task.PublicID != "" {
+		p.publicIds.Delete(task.PublicID)
+	}
+	p.count.Add(-1)
+
+	st.logger.Log("Deleted task: %v of project %v sucsessfully", taskId, p.projectId)
+
+	return nil
+}
+
+// AddBlocker makes blockerId block taskId like TaskStorage.AddBlocker does
+func (st *ShardedTaskStorage) AddBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error) {
+	p := st.project(ctx)
+	p.graph.Lock()
+	defer p.graph.Unlock()
+	if err := checkBlocker(p, taskId, blockerId); err != nil {
+		return nil, fmt.Errorf("%v: error while adding blocker(%v) to task(%v): %w", shardedStorageName, blockerId, taskId, err)
+	}
+	ans, _ := p.setBlockers(taskId, func(ids []int) []int { return with(ids, blockerId) })
+	link(p.blocks, &blockerId, taskId)
+	return &ans, nil
+}
+
+// RemoveBlocker removes the blocker of the task, removing a task that isn't a blocker changes nothing
+func (st *ShardedTaskStorage) RemoveBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error) {
+	p := st.project(ctx)
+	p.graph.Lock()
+	defer p.graph.Unlock()
+	if _, ok := p.task(blockerId); !ok {
+		return nil, fmt.Errorf("%v: error while removing blocker(%v) of task(%v): %w", shardedStorageName, blockerId, taskId, model.ErrNotFound)
+	}
+	ans, ok := p.setBlockers(taskId, func(ids []int) []int { return without(ids, blockerId) })
+	if !ok {
+		return nil, fmt.Errorf("%v: error while removing blocker(%v) of task(%v): %w", shardedStorageName, blockerId, taskId, model.ErrNotFound)
+	}
+	unlink(p.blocks, &blockerId, taskId)
+	return &ans, nil
+}
+
+// GetSubtasks returns the task with its subtasks down to depth levels, subtasks are ordered by id
+func (st *ShardedTaskStorage) GetSubtasks(ctx context.Context, taskId, depth int) (*model.TaskNode, error) {
+	p := st.project(ctx)
+	p.graph.RLock()
+	defer p.graph.RUnlock()
+	if _, ok := p.task(taskId); !ok {
+		return nil, fmt.Errorf("%v: error while retrieving subtasks of task(%v): %w", shardedStorageName, taskId, model.ErrNotFound)
+	}
+	node := subtree(p, taskId, depth)
+
+	return &node, nil
+}
+
+// DependencyOrder returns the task after every task blocking it like TaskStorage.DependencyOrder does
+func (st *ShardedTaskStorage) DependencyOrder(ctx context.Context, taskId int) ([]model.Task, error) {
+	p := st.project(ctx)
+	p.graph.RLock()
+	defer p.graph.RUnlock()
+	if _, ok := p.task(taskId); !ok {
+		return nil, fmt.Errorf("%v: error while ordering dependencies of task(%v): %w", shardedStorageName, taskId, model.ErrNotFound)
+	}
+	ans, ok := dependencyOrder(p, taskId)
+	if !ok {
+		// unreachable while edges are checked on insert
+		return nil, fmt.Errorf("%v: error while ordering dependencies of task(%v): %w", shardedStorageName, taskId, model.ErrCycle)
+	}
+
+	return ans, nil
+}
+
+// StoreTag saves a new tag of the project, tag ids are counted per project starting from 1
+func (st *ShardedTaskStorage) StoreTag(ctx context.Context, tag model.Tag) (int, error) {
+	p, _ := st.projectForWrite(ctx)
+	p.graph.Lock()
+	defer p.graph.Unlock()
+	if err := p.checkTagName(tag); err != nil {
+		return -1, fmt.Errorf("%v: error while storing tag %v: %w", shardedStorageName, tag.Name, err)
+	}
+	tag = p.storeTag(tag, p.projectId, st.clock.Now())
+
+	st.logger.Log("Stored tag: %v sucsessfully", tag)
+
+	return tag.Id, nil
+}
+
+// GetAllTags returns tags of the project with their usage counts ordered by id
+func (st *ShardedTaskStorage) GetAllTags(ctx context.Context) ([]model.Tag, error) {
+	p := st.project(ctx)
+	p.graph.RLock()
+	defer p.graph.RUnlock()
+
+	return p.allTags(), nil
+}
+
+func (st *ShardedTaskStorage) GetTagById(ctx context.Context, tagId int) (*model.Tag, error) {
+	p := st.project(ctx)
+	p.graph.RLock()
+	defer p.graph.RUnlock()
+	if _, ok := p.tags[tagId]; !ok {
+		return nil, fmt.Errorf("%v: error while retrieving tag by id(%v): %w", shardedStorageName, tagId, model.ErrNotFound)
+	}
+	tag := p.tagWithCount(tagId)
+
+	return &tag, nil
+}
+
+// UpdateTag renames an existing tag, tasks keep it attached
+func (st *ShardedTaskStorage) UpdateTag(ctx context.Context, tag model.Tag) (*model.Tag, error) {
+	p := st.project(ctx)
+	p.graph.Lock()
+	defer p.graph.Unlock()
+	if _, ok := p.tags[tag.Id]; !ok {
+		return nil, fmt.Errorf("%v: error while updating tag by id(%v): %w", shardedStorageName, tag.Id, model.ErrNotFound)
+	}
+	if err := p.checkTagName(tag); err != nil {
+		return nil, fmt.Errorf("%v: error while updating tag %v: %w", shardedStorageName, tag.Name, err)
+	}
+	p.renameTag(tag)
+
+	updated := p.tagWithCount(tag.Id)
+	return &updated, nil
+}
+
+// DeleteTag removes the tag and detaches it from every task
+func (st *ShardedTaskStorage) DeleteTag(ctx context.Context, tagId int) error {
+	p := st.project(ctx)
+	p.graph.Lock()
+	defer p.graph.Unlock()
+	if _, ok := p.tags[tagId]; !ok {
+		return fmt.Errorf("%v: error while deleting tag by id(%v): %w", shardedStorageName, tagId, model.ErrNotFound)
+	}
+	for taskId := range p.tagIndex[tagId] {
+		p.modify(taskId, func(task *model.Task) { task.TagIDs = without(task.TagIDs, tagId) })
+	}
+	p.deleteTag(tagId)
+
+	st.logger.Log("Deleted tag: %v of project %v sucsessfully", tagId, p.projectId)
+
+	return nil
+}
+
+// AttachTag attaches the tag to the task, attaching it again changes nothing
+func (st *ShardedTaskStorage) AttachTag(ctx context.Context, taskId, tagId int) (*model.Task, error) {
+	p := st.project(ctx)
+	p.graph.Lock()
+	defer p.graph.Unlock()
+	if err := p.checkTagging(taskId, tagId); err != nil {
+		return nil, fmt.Errorf("%v: error while attaching tag(%v) to task(%v): %w", shardedStorageName, tagId, taskId, err)
+	}
+	ans, _ := p.modify(taskId, func(task *model.Task) { task.TagIDs = with(task.TagIDs, tagId) })
+	p.tagIndex[tagId][taskId] = struct{}{}
+	return &ans, nil
+}
+
+// DetachTag detaches the tag from the task, detaching a tag that isn't attached changes nothing
+func (st *ShardedTaskStorage) DetachTag(ctx context.Context, taskId, tagId int) (*model.Task, error) {
+	p := st.project(ctx)
+	p.graph.Lock()
+	defer p.graph.Unlock()
+	if err := p.checkTagging(taskId, tagId); err != nil {
+		return nil, fmt.Errorf("%v: error while detaching tag(%v) from task(%v): %w", shardedStorageName, tagId, taskId, err)
+	}
+	ans, _ := p.modify(taskId, func(task *model.Task) { task.TagIDs = without(task.TagIDs, tagId) })
+	delete(p.tagIndex[tagId], taskId)
+	return &ans, nil
+}
+
+// reserve takes a place for a new task unless the quota is reached
+func (p *shardedProject) reserve(quota int) error {
+	for {
+		count := p.count.Load()
+		if quota != model.NoQuota && count >= int64(quota) {
+			return model.ErrQuotaExceeded
+		}
+		if p.count.CompareAndSwap(count, count+1) {
+			return nil
+		}
+	}
+}
+
+// update replaces mutable fields of the task under the lock of its shard. Without restructure
+// changes of the parent and strict completions are left with errRestructure, with it the graph lock
+// has to be held for writing, so the checks of other tasks stay true until the task is written.
+func (p *shardedProject) update(task model.Task, now time.Time, strict, restructure bool) (model.Task, error) {
+	// the parent is looked up before the shard is locked, it may be in the same shard
+	var publicParentId string
+	if restructure {
+		stored, ok := p.task(task.Id)
+		if !ok {
+			return model.Task{}, model.ErrNotFound
+		}
+		if !sameId(stored.ParentID, task.ParentID) {
+			if err := checkParent(p, task.Id, task.ParentID); err != nil {
+				return model.Task{}, err
+			}
+			publicParentId = publicIdOf(p, task.ParentID)
+		}
+		if strict && task.Status == model.Done && stored.Status != model.Done && incomplete(p, task.Id) {
+			return model.Task{}, model.ErrIncomplete
+		}
+	}
+
+	sh := p.shard(task.Id)
+	sh.m.Lock()
+	defer sh.m.Unlock()
+	stored, ok := sh.tasks[task.Id]
+	if !ok {
+		return model.Task{}, model.ErrNotFound
+	}
+	reparented := !sameId(stored.ParentID, task.ParentID)
+	completed := strict && task.Status == model.Done && stored.Status != model.Done
+	if !restructure && (reparented || completed) {
+		return model.Task{}, errRestructure
+	}
+	task = updatedTask(stored, task, now)
+	if reparented {
+		task.PublicParentID = publicParentId
+		unlink(p.children, stored.ParentID, task.Id)
+		link(p.children, task.ParentID, task.Id)
+	}
+	sh.put(task, stored.Status)
+	return task, nil
+}
+
+// checkTagging checks that both the task and the tag exist, must be called under the graph lock
+func (p *shardedProject) checkTagging(taskId, tagId int) error {
+	if _, ok := p.task(taskId); !ok {
+		return fmt.Errorf("task: %w", model.ErrNotFound)
+	}
+	if _, ok := p.tags[tagId]; !ok {
+		return fmt.Errorf("tag: %w", model.ErrNotFound)
+	}
+	return nil
+}
+
+func (p *shardedProject) shard(taskId int) *taskShard {
+	return &p.shards[taskId&p.mask]
+}
+
+// task returns the task if it exists, the shard is locked inside
+func (p *shardedProject) task(taskId int) (model.Task, bool) {
+	sh := p.shard(taskId)
+	sh.m.RLock()
+	defer sh.m.RUnlock()
+	task, ok := sh.tasks[taskId]
+	return task, ok
+}
+
+// subtaskIds returns ids of direct subtasks of the task, must be called under the graph lock
+func (p *shardedProject) subtaskIds(taskId int) map[int]struct{} {
+	return p.children[taskId]
+}
+
+// blockedIds returns ids of the tasks the task blocks, must be called under the graph lock
+func (p *shardedProject) blockedIds(taskId int) map[int]struct{} {
+	return p.blocks[taskId]
+}
+
+// put adds a new task to its shard
+func (p *shardedProject) put(task model.Task) {
+	sh := p.shard(task.Id)
+	sh.m.Lock()
+	defer sh.m.Unlock()
+	sh.put(task, "")
+}
+
+// modify changes the task with fn under the lock of its shard and returns the changed task,
+// it's false if the task doesn't exist
+func (p *shardedProject) modify(taskId int, fn func(*model.Task)) (model.Task, bool) {
+	sh := p.shard(taskId)
+	sh.m.Lock()
+	defer sh.m.Unlock()
+	task, ok := sh.tasks[taskId]
+	if !ok {
+		return model.Task{}, false
+	}
+	status := task.Status
+	fn(&task)
+	sh.put(task, status)
+	return task, true
+}
+
+// setBlockers replaces the blockers of the task with the result of fn together with their public ids,
+// it's false if the task doesn't exist. It must be called under the graph lock held for writing,
+// the blockers are looked up before the shard of the task is locked.
+func (p *shardedProject) setBlockers(taskId int, fn func([]int) []int) (model.Task, bool) {
+	task, ok := p.task(taskId)
+	if !ok {
+		return model.Task{}, false
+	}
+	blockedBy := fn(task.BlockedBy)
+	publicBlockedBy := publicIdsOf(p, blockedBy)
+	return p.modify(taskId, func(task *model.Task) { task.BlockedBy, task.PublicBlockedBy = blockedBy, publicBlockedBy })
+}
+
+// remove deletes the task from its shard
+func (p *shardedProject) remove(taskId int) {
+	sh := p.shard(taskId)
+	sh.m.Lock()
+	defer sh.m.Unlock()
+	if task, ok := sh.tasks[taskId]; ok {
+		sh.unindex(taskId, task.Status)
+		delete(sh.tasks, taskId)
+	}
+}
+
+// put writes the task and moves it from the index of its previous status, empty for new tasks,
+// must be called under the shard lock
+func (sh *taskShard) put(task model.Task, previous model.TaskStatus) {
+	if previous != task.Status {
+		if previous != "" {
+			sh.unindex(task.Id, previous)
+		}
+		if sh.byStatus[task.Status] == nil {
+			sh.byStatus[task.Status] = make(map[int]struct{})
+		}
+		sh.byStatus[task.Status][task.Id] = struct{}{}
+	}
+	sh.tasks[task.Id] = task
+}
+
+func (sh *taskShard) unindex(taskId int, status model.TaskStatus) {
+	delete(sh.byStatus[status], taskId)
+	if len(sh.byStatus[status]) == 0 {
+		delete(sh.byStatus, status)
+	}
+}
+
+// collect appends the tasks of the shard matching the filter, only the tasks of the status
+// are checked when the filter has one
+func (sh *taskShard) collect(ans []model.Task, filter model.Filter, now time.Time) []model.Task {
+	sh.m.RLock()
+	defer sh.m.RUnlock()
+	if filter.Status != "" {
+		for id := range sh.byStatus[filter.Status] {
+			if task := sh.tasks[id]; filter.Matches(task, now) {
+				ans = append(ans, task)
+			}
+		}
+		return ans
+	}
+	for _, task := range sh.tasks {
+		if filter.Matches(task, now) {
+			ans = append(ans, task)
+		}
+	}
+	return ans
+}
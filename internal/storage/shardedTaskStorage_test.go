@@ -0,0 +1,415 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/idgen"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// silentLogger drops messages, unlike MockLogger it's safe for concurrent use
+type silentLogger struct{}
+
+func (silentLogger) Log(string, ...any) {}
+
+// shardedCompared is the part of both task storages the tests and benchmarks below call
+type shardedCompared interface {
+	Store(ctx context.Context, task model.Task) (int, error)
+	GetAll(ctx context.Context, filter model.Filter) ([]model.Task, int, error)
+	GetByTaskId(ctx context.Context, taskId int) (*model.Task, error)
+	Update(ctx context.Context, task model.Task) (*model.Task, error)
+	Delete(ctx context.Context, taskId int) error
+}
+
+func TestNewShardedTaskStorage(t *testing.T) {
+	if _, err := NewShardedTaskStorage(nil); err == nil {
+		t.Error("Expected error for a nil logger")
+	}
+	if _, err := NewShardedTaskStorage(silentLogger{}, WithShards(0)); err == nil {
+		t.Error("Expected error for zero shards")
+	}
+	if _, err := NewShardedTaskStorage(silentLogger{}, WithShardedClock(nil)); err == nil {
+		t.Error("Expected error for a nil clock")
+	}
+	for shards, expected := range map[int]int{1: 1, 5: 8, 32: 32, 33: 64} {
+		st, err := NewShardedTaskStorage(silentLogger{}, WithShards(shards))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if st.shards != expected {
+			t.Errorf("Expected %v shards to be rounded to %v, got %v", shards, expected, st.shards)
+		}
+	}
+}
+
+// TestShardedMatchesTaskStorage runs the same random commands against both storages
+// and expects the same answers from them
+func TestShardedMatchesTaskStorage(t *testing.T) {
+	c := clock.NewManual(time.Date(2030, time.May, 1, 9, 0, 0, 0, time.UTC))
+	plain, _ := NewTaskStorage(silentLogger{}, WithStrictCompletion(true), WithClock(c), WithIDGenerator(idgen.NewSequential(1)))
+	sharded, _ := NewShardedTaskStorage(silentLogger{}, WithShards(4),
+		WithShardedStrictCompletion(true), WithShardedClock(c), WithShardedIDGenerator(idgen.NewSequential(1)))
+
+	rnd := rand.New(rand.NewPCG(49, 1))
+	projects := []context.Context{
+		tenant.WithScope(context.Background(), tenant.Scope{ProjectID: 1, TaskQuota: 40}),
+		tenant.WithScope(context.Background(), tenant.Scope{ProjectID: 2}),
+	}
+	statuses := []model.TaskStatus{model.Created, model.InProgress, model.Done}
+	priorities := []model.TaskPriority{"", model.PriorityLow, model.PriorityHigh}
+	tagNames := []string{"backend", "frontend", "urgent", "later"}
+	sentinels := []error{model.ErrNotFound, model.ErrCycle, model.ErrIncomplete, model.ErrQuotaExceeded, model.ErrAlreadyExists}
+
+	same := func(step int, op string, a, b any, errA, errB error) {
+		t.Helper()
+		if (errA == nil) != (errB == nil) {
+			t.Fatalf("step %v %v: errors differ: %v and %v", step, op, errA, errB)
+		}
+		for _, sentinel := range sentinels {
+			if errors.Is(errA, sentinel) != errors.Is(errB, sentinel) {
+				t.Fatalf("step %v %v: errors differ: %v and %v", step, op, errA, errB)
+			}
+		}
+		if errA == nil && !reflect.DeepEqual(a, b) {
+			t.Fatalf("step %v %v: answers differ:\n%+v\n%+v", step, op, a, b)
+		}
+	}
+	randomId := func() int { return rnd.IntN(60) - 2 }
+	randomParent := func() *int {
+		if rnd.IntN(3) > 0 {
+			return nil
+		}
+		id := randomId()
+		return &id
+	}
+	randomTask := func() model.Task {
+		return model.Task{
+			Name:     fmt.Sprintf("task %v", rnd.IntN(1000)),
+			Status:   statuses[rnd.IntN(len(statuses))],
+			Priority: priorities[rnd.IntN(len(priorities))],
+			DueAt:    c.Now().Add(time.Duration(rnd.IntN(48)-24) * time.Hour),
+			ParentID: randomParent(),
+		}
+	}
+	randomFilter := func() model.Filter {
+		f := model.Filter{SortBy: []model.SortField{"", model.SortByPriority, model.SortByDueAt, model.SortByCompletedAt}[rnd.IntN(4)]}
+		f.Descending = rnd.IntN(2) == 0
+		if rnd.IntN(2) == 0 {
+			f.Status = statuses[rnd.IntN(len(statuses))]
+		}
+		if rnd.IntN(3) == 0 {
+			f.Tags = []string{tagNames[rnd.IntN(len(tagNames))], tagNames[rnd.IntN(len(tagNames))]}
+			f.TagMatch = []model.TagMatch{model.TagMatchAny, model.TagMatchAll}[rnd.IntN(2)]
+		}
+		f.Overdue = rnd.IntN(5) == 0
+		if rnd.IntN(3) == 0 {
+			f.Page = model.Page{Limit: 1 + rnd.IntN(10), Offset: rnd.IntN(20)}
+		}
+		return f
+	}
+
+	for step := 0; step < 3000; step++ {
+		ctx := projects[rnd.IntN(len(projects))]
+		c.Advance(time.Minute)
+		switch op := rnd.IntN(12); op {
+		case 0, 1, 2:
+			task := randomTask()
+			a, errA := plain.Store(ctx, task)
+			b, errB := sharded.Store(ctx, task)
+			same(step, "store", a, b, errA, errB)
+		case 3, 4:
+			task := randomTask()
+			task.Id = randomId()
+			if stored, err := plain.GetByTaskId(ctx, task.Id); err == nil && rnd.IntN(2) == 0 {
+				task.ParentID = stored.ParentID
+			}
+			a, errA := plain.Update(ctx, task)
+			b, errB := sharded.Update(ctx, task)
+			same(step, "update", a, b, errA, errB)
+		case 5:
+			id := randomId()
+			same(step, "delete", nil, nil, plain.Delete(ctx, id), sharded.Delete(ctx, id))
+		case 6:
+			taskId, blockerId := randomId(), randomId()
+			a, errA := plain.AddBlocker(ctx, taskId, blockerId)
+			b, errB := sharded.AddBlocker(ctx, taskId, blockerId)
+			same(step, "add blocker", a, b, errA, errB)
+		case 7:
+			taskId, blockerId := randomId(), randomId()
+			a, errA := plain.RemoveBlocker(ctx, taskId, blockerId)
+			b, errB := sharded.RemoveBlocker(ctx, taskId, blockerId)
+			same(step, "remove blocker", a, b, errA, errB)
+		case 8:
+			tag := model.Tag{Name: tagNames[rnd.IntN(len(tagNames))]}
+			a, errA := plain.StoreTag(ctx, tag)
+			b, errB := sharded.StoreTag(ctx, tag)
+			same(step, "store tag", a, b, errA, errB)
+		case 9:
+			taskId, tagId := randomId(), rnd.IntN(6)
+			if rnd.IntN(3) > 0 {
+				a, errA := plain.AttachTag(ctx, taskId, tagId)
+				b, errB := sharded.AttachTag(ctx, taskId, tagId)
+				same(step, "attach tag", a, b, errA, errB)
+			} else {
+				a, errA := plain.DetachTag(ctx, taskId, tagId)
+				b, errB := sharded.DetachTag(ctx, taskId, tagId)
+				same(step, "detach tag", a, b, errA, errB)
+			}
+		case 10:
+			tagId := rnd.IntN(6)
+			if rnd.IntN(2) == 0 {
+				same(step, "delete tag", nil, nil, plain.DeleteTag(ctx, tagId), sharded.DeleteTag(ctx, tagId))
+			} else {
+				tag := model.Tag{Id: tagId, Name: tagNames[rnd.IntN(len(tagNames))]}
+				a, errA := plain.UpdateTag(ctx, tag)
+				b, errB := sharded.UpdateTag(ctx, tag)
+				same(step, "rename tag", a, b, errA, errB)
+			}
+		case 11:
+			id := randomId()
+			a, errA := plain.GetSubtasks(ctx, id, 3)
+			b, errB := sharded.GetSubtasks(ctx, id, 3)
+			same(step, "subtasks", a, b, errA, errB)
+			order, errA := plain.DependencyOrder(ctx, id)
+			shardedOrder, errB := sharded.DependencyOrder(ctx, id)
+			same(step, "dependency order", order, shardedOrder, errA, errB)
+		}
+
+		filter := randomFilter()
+		a, totalA, errA := plain.GetAll(ctx, filter)
+		b, totalB, errB := sharded.GetAll(ctx, filter)
+		same(step, fmt.Sprintf("get all %+v", filter), a, b, errA, errB)
+		same(step, fmt.Sprintf("total of %+v", filter), totalA, totalB, nil, nil)
+	}
+
+	for _, ctx := range projects {
+		var a, b []model.Task
+		plain.ForEach(ctx, model.EmptyFilter, func(task model.Task) error { a = append(a, task); return nil })
+		sharded.ForEach(ctx, model.EmptyFilter, func(task model.Task) error { b = append(b, task); return nil })
+		same(-1, "for each", a, b, nil, nil)
+
+		tagsA, _ := plain.GetAllTags(ctx)
+		tagsB, _ := sharded.GetAllTags(ctx)
+		same(-1, "tags", tagsA, tagsB, nil, nil)
+
+		publicIds := make(map[int]string)
+		for _, task := range a {
+			publicIds[task.Id] = task.PublicID
+		}
+		for _, task := range a {
+			// both storages keep the public ids of the parents and blockers that are left
+			var publicBlockedBy []string
+			for _, blockerId := range task.BlockedBy {
+				publicBlockedBy = append(publicBlockedBy, publicIds[blockerId])
+			}
+			if task.ParentID != nil && task.PublicParentID != publicIds[*task.ParentID] ||
+				task.ParentID == nil && task.PublicParentID != "" || !reflect.DeepEqual(task.PublicBlockedBy, publicBlockedBy) {
+				t.Fatalf("Task %+v doesn't refer to the public ids of its parent and blockers", task)
+			}
+			if task.PublicID == "" {
+				continue
+			}
+			found, err := sharded.GetByPublicId(ctx, task.PublicID)
+			same(-1, "public id", &task, found, nil, err)
+		}
+	}
+}
+
+func TestShardedConcurrency(t *testing.T) {
+	st, _ := NewShardedTaskStorage(silentLogger{}, WithShards(4), WithShardedIDGenerator(idgen.NewSequential(0)))
+	ctx := tenant.WithScope(context.Background(), tenant.Scope{ProjectID: 7})
+	statuses := []model.TaskStatus{model.Created, model.InProgress, model.Done}
+
+	var (
+		wg      sync.WaitGroup
+		stored  atomic.Int64
+		deleted atomic.Int64
+	)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 300; i++ {
+				switch rand.IntN(6) {
+				case 0, 1:
+					var parent *int
+					if rand.IntN(4) == 0 {
+						id := rand.IntN(int(stored.Load()) + 1)
+						parent = &id
+					}
+					if _, err := st.Store(ctx, model.Task{Name: "task", Status: model.Created, ParentID: parent}); err == nil {
+						stored.Add(1)
+					}
+				case 2:
+					st.Update(ctx, model.Task{Id: rand.IntN(int(stored.Load()) + 1), Name: "updated", Status: statuses[rand.IntN(3)]})
+				case 3:
+					st.AddBlocker(ctx, rand.IntN(int(stored.Load())+1), rand.IntN(int(stored.Load())+1))
+				case 4:
+					if st.Delete(ctx, rand.IntN(int(stored.Load())+1)) == nil {
+						deleted.Add(1)
+					}
+				case 5:
+					st.GetAll(ctx, model.Filter{Status: statuses[rand.IntN(3)]})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	all, _, _ := st.GetAll(ctx, model.EmptyFilter)
+	if int64(len(all)) != stored.Load()-deleted.Load() {
+		t.Fatalf("Expected %v tasks, got %v", stored.Load()-deleted.Load(), len(all))
+	}
+	p, indexed := st.project(ctx), 0
+	for i := range p.shards {
+		for status, ids := range p.shards[i].byStatus {
+			for id := range ids {
+				if task := p.shards[i].tasks[id]; task.Status != status {
+					t.Errorf("Task %v of status %v is indexed as %v", id, task.Status, status)
+				}
+			}
+			indexed += len(ids)
+		}
+	}
+	if indexed != len(all) {
+		t.Errorf("Expected status indexes to hold %v tasks, got %v", len(all), indexed)
+	}
+	for _, task := range all {
+		for _, blockerId := range task.BlockedBy {
+			if _, err := st.GetByTaskId(ctx, blockerId); err != nil {
+				t.Errorf("Task %v is blocked by deleted task %v", task.Id, blockerId)
+			}
+		}
+		if task.ParentID != nil {
+			if _, err := st.GetByTaskId(ctx, *task.ParentID); err != nil {
+				t.Errorf("Task %v is a subtask of deleted task %v", task.Id, *task.ParentID)
+			}
+		}
+		if found, err := st.GetByPublicId(ctx, task.PublicID); err != nil || found.Id != task.Id {
+			t.Errorf("Task %v isn't found by its public id %q: %v", task.Id, task.PublicID, err)
+		}
+	}
+}
+
+func TestShardedTaskQuota(t *testing.T) {
+	st, _ := NewShardedTaskStorage(silentLogger{})
+	ctx := tenant.WithScope(context.Background(), tenant.Scope{ProjectID: 2, TaskQuota: 100})
+
+	var (
+		wg       sync.WaitGroup
+		stored   atomic.Int64
+		rejected atomic.Int64
+	)
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				_, err := st.Store(ctx, model.Task{Name: "task", Status: model.Created})
+				switch {
+				case err == nil:
+					stored.Add(1)
+				case errors.Is(err, model.ErrQuotaExceeded):
+					rejected.Add(1)
+				default:
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if stored.Load() != 100 || rejected.Load() != 220 {
+		t.Fatalf("Expected 100 stored and 220 rejected tasks, got %v and %v", stored.Load(), rejected.Load())
+	}
+	if err := st.Delete(ctx, 42); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if id, err := st.Store(ctx, model.Task{Name: "task", Status: model.Created}); err != nil || id != 100 {
+		t.Errorf("Expected deleted task to free the quota and ids not to be reused, got %v %v", id, err)
+	}
+}
+
+// Benchmarks compare both storages under parallel load, run them with
+//
+//	go test ./internal/storage -run '^$' -bench . -cpu 1,4,16
+
+func benchmarkStorages(b *testing.B) map[string]shardedCompared {
+	plain, _ := NewTaskStorage(silentLogger{})
+	sharded, _ := NewShardedTaskStorage(silentLogger{})
+	return map[string]shardedCompared{"TaskStorage": plain, "ShardedTaskStorage": sharded}
+}
+
+// fillBenchmark stores n tasks, every hundredth of them is done
+func fillBenchmark(b *testing.B, st shardedCompared, ctx context.Context, n int) {
+	for i := 0; i < n; i++ {
+		status := model.Created
+		if i%100 == 0 {
+			status = model.Done
+		}
+		if _, err := st.Store(ctx, model.Task{Name: "task", Status: status}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStore(b *testing.B) {
+	ctx := context.Background()
+	for name, st := range benchmarkStorages(b) {
+		b.Run(name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					st.Store(ctx, model.Task{Name: "task", Status: model.Created})
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkMixed reads tasks by id and updates every tenth of them
+func BenchmarkMixed(b *testing.B) {
+	const tasks = 10000
+	ctx := context.Background()
+	for name, st := range benchmarkStorages(b) {
+		fillBenchmark(b, st, ctx, tasks)
+		b.Run(name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					id := rand.IntN(tasks)
+					if rand.IntN(10) == 0 {
+						st.Update(ctx, model.Task{Id: id, Name: "updated", Status: model.InProgress})
+					} else {
+						st.GetByTaskId(ctx, id)
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkGetAllByStatus lists done tasks while every tenth call stores a new one
+func BenchmarkGetAllByStatus(b *testing.B) {
+	ctx := context.Background()
+	for name, st := range benchmarkStorages(b) {
+		fillBenchmark(b, st, ctx, 10000)
+		b.Run(name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if rand.IntN(10) == 0 {
+						st.Store(ctx, model.Task{Name: "task", Status: model.Created})
+					} else {
+						st.GetAll(ctx, model.Filter{Status: model.Done})
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Tags live in the partitions of TaskStorage next to the tasks they are attached to,
+// so the tag index always changes together with the tasks under the same lock.
+//
+// TagIDs of a stored task are never modified in place, attaching and detaching replace
+// the slice, so copies of tasks returned earlier stay valid.
+
+// StoreTag saves a new tag of the project, tag ids are counted per project starting from 1
+func (st *TaskStorage) StoreTag(ctx context.Context, tag model.Tag) (int, error) {
+	p, _ := st.partitionForWrite(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if err := p.checkTagName(tag); err != nil {
+		return -1, fmt.Errorf("%v: error while storing tag %v: %w", storageName, tag.Name, err)
+	}
+	tag = p.applyStoreTag(tag, st.clock.Now())
+
+	st.logger.Log("Stored tag: %v sucsessfully", tag)
+
+	return tag.Id, nil
+}
+
+// GetAllTags returns tags of the project with their usage counts ordered by id
+func (st *TaskStorage) GetAllTags(ctx context.Context) ([]model.Tag, error) {
+	p := st.partition(ctx)
+	p.m.RLock()
+	defer p.m.RUnlock()
+
+	return p.allTags(), nil
+}
+
+func (st *TaskStorage) GetTagById(ctx context.Context, tagId int) (*model.Tag, error) {
+	p := st.partition(ctx)
+	p.m.RLock()
+	defer p.m.RUnlock()
+	if _, ok := p.tags[tagId]; !ok {
+		return nil, fmt.Errorf("%v: error while retrieving tag by id(%v): %w", storageName, tagId, model.ErrNotFound)
+	}
+	tag := p.tagWithCount(tagId)
+
+	return &tag, nil
+}
+
+// UpdateTag renames an existing tag, tasks keep it attached
+func (st *TaskStorage) UpdateTag(ctx context.Context, tag model.Tag) (*model.Tag, error) {
+	p := st.partition(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if _, ok := p.tags[tag.Id]; !ok {
+		return nil, fmt.Errorf("%v: error while updating tag by id(%v): %w", storageName, tag.Id, model.ErrNotFound)
+	}
+	if err := p.checkTagName(tag); err != nil {
+		return nil, fmt.Errorf("%v: error while updating tag %v: %w", storageName, tag.Name, err)
+	}
+	p.applyRenameTag(tag)
+
+	updated := p.tagWithCount(tag.Id)
+	return &updated, nil
+}
+
+// DeleteTag removes the tag and detaches it from every task
+func (st *TaskStorage) DeleteTag(ctx context.Context, tagId int) error {
+	p := st.partition(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if _, ok := p.tags[tagId]; !ok {
+		return fmt.Errorf("%v: error while deleting tag by id(%v): %w", storageName, tagId, model.ErrNotFound)
+	}
+	p.applyDeleteTag(tagId)
+
+	st.logger.Log("Deleted tag: %v of project %v sucsessfully", tagId, p.projectId)
+
+	return nil
+}
+
+// AttachTag attaches the tag to the task, attaching it again changes nothing
+func (st *TaskStorage) AttachTag(ctx context.Context, taskId, tagId int) (*model.Task, error) {
+	p := st.partition(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if err := p.checkTagging(taskId, tagId); err != nil {
+		return nil, fmt.Errorf("%v: error while attaching tag(%v) to task(%v): %w", storageName, tagId, taskId, err)
+	}
+	ans := p.applyAttachTag(taskId, tagId)
+	return &ans, nil
+}
+
+// DetachTag detaches the tag from the task, detaching a tag that isn't attached changes nothing
+func (st *TaskStorage) DetachTag(ctx context.Context, taskId, tagId int) (*model.Task, error) {
+	p := st.partition(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if err := p.checkTagging(taskId, tagId); err != nil {
+		return nil, fmt.Errorf("%v: error while detaching tag(%v) from task(%v): %w", storageName, tagId, taskId, err)
+	}
+	ans := p.applyDetachTag(taskId, tagId)
+	return &ans, nil
+}
+
+// projectTags are the tags of a project with the index of the tasks they are attached to
+type projectTags struct {
+	tags       map[int]model.Tag
+	tagCounter int
+	// tagsByName maps normalized tag names to ids
+	tagsByName map[string]int
+	// tagIndex maps tag ids to ids of the tasks they are attached to
+	tagIndex map[int]map[int]struct{}
+}
+
+func newProjectTags() projectTags {
+	return projectTags{
+		tags:       make(map[int]model.Tag),
+		tagsByName: make(map[string]int),
+		tagIndex:   make(map[int]map[int]struct{}),
+	}
+}
+
+// checkTagName checks that no other tag of the project has the name, must be called under lock
+func (t *projectTags) checkTagName(tag model.Tag) error {
+	if id, ok := t.tagsByName[tag.Name]; ok && id != tag.Id {
+		return model.ErrAlreadyExists
+	}
+	return nil
+}
+
+// storeTag adds the tag with the next id, must be called under lock
+func (t *projectTags) storeTag(tag model.Tag, projectId int, now time.Time) model.Tag {
+	t.tagCounter++
+	tag.Id = t.tagCounter
+	tag.ProjectID = projectId
+	tag.TaskCount = 0
+	tag.CreatedAt = now
+	t.tags[tag.Id] = tag
+	t.tagsByName[tag.Name] = tag.Id
+	t.tagIndex[tag.Id] = make(map[int]struct{})
+
+	return tag
+}
+
+func (t *projectTags) renameTag(tag model.Tag) {
+	stored := t.tags[tag.Id]
+	delete(t.tagsByName, stored.Name)
+	stored.Name = tag.Name
+	t.tags[tag.Id] = stored
+	t.tagsByName[stored.Name] = stored.Id
+}
+
+// deleteTag removes the tag and its index, tasks have to be detached from it before, must be called under lock
+func (t *projectTags) deleteTag(tagId int) {
+	delete(t.tagIndex, tagId)
+	delete(t.tagsByName, t.tags[tagId].Name)
+	delete(t.tags, tagId)
+}
+
+// tagWithCount returns the tag with its usage count, must be called under lock
+func (t *projectTags) tagWithCount(tagId int) model.Tag {
+	tag := t.tags[tagId]
+	tag.TaskCount = len(t.tagIndex[tagId])
+	return tag
+}
+
+// allTags returns the tags with their usage counts ordered by id, must be called under lock
+func (t *projectTags) allTags() []model.Tag {
+	ans := make([]model.Tag, 0, len(t.tags))
+	for id := range t.tags {
+		ans = append(ans, t.tagWithCount(id))
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Id < ans[j].Id })
+	return ans
+}
+
+// applyStoreTag adds the tag with the next id, must be called under lock
+func (p *taskPartition) applyStoreTag(tag model.Tag, now time.Time) model.Tag {
+	return p.storeTag(tag, p.projectId, now)
+}
+
+func (p *taskPartition) applyRenameTag(tag model.Tag) {
+	p.renameTag(tag)
+}
+
+// applyDeleteTag removes the tag and detaches it from every task, must be called under lock
+func (p *taskPartition) applyDeleteTag(tagId int) {
+	for taskId := range p.tagIndex[tagId] {
+		task := p.tasks[taskId]
+		task.TagIDs = without(task.TagIDs, tagId)
+		p.tasks[taskId] = task
+	}
+	p.deleteTag(tagId)
+}
+
+func (p *taskPartition) applyAttachTag(taskId, tagId int) model.Task {
+	task := p.tasks[taskId]
+	task.TagIDs = with(task.TagIDs, tagId)
+	p.tasks[taskId] = task
+	p.tagIndex[tagId][taskId] = struct{}{}
+	return task
+}
+
+func (p *taskPartition) applyDetachTag(taskId, tagId int) model.Task {
+	task := p.tasks[taskId]
+	task.TagIDs = without(task.TagIDs, tagId)
+	p.tasks[taskId] = task
+	delete(p.tagIndex[tagId], taskId)
+	return task
+}
+
+// checkTagging checks that both the task and the tag exist, must be called under lock
+func (p *taskPartition) checkTagging(taskId, tagId int) error {
+	if !p.exists(taskId) {
+		return fmt.Errorf("task: %w", model.ErrNotFound)
+	}
+	if _, ok := p.tags[tagId]; !ok {
+		return fmt.Errorf("tag: %w", model.ErrNotFound)
+	}
+	return nil
+}
+
+// candidates returns sorted ids of the tasks matching tags of the filter using the tag index.
+// indexed is false when the filter has no tags and every task has to be checked, must be called under lock
+func (t *projectTags) candidates(filter model.Filter) (ids []int, indexed bool) {
+	if len(filter.Tags) == 0 {
+		return nil, false
+	}
+
+	sets := make([]map[int]struct{}, 0, len(filter.Tags))
+	for _, name := range filter.Tags {
+		tagId, ok := t.tagsByName[model.NormalizeTagName(name)]
+		if !ok {
+			if filter.TagMatch == model.TagMatchAll {
+				return []int{}, true
+			}
+			continue
+		}
+		sets = append(sets, t.tagIndex[tagId])
+	}
+	if len(sets) == 0 {
+		return []int{}, true
+	}
+
+	ids = make([]int, 0)
+	if filter.TagMatch == model.TagMatchAll {
+		// intersect starting from the smallest set
+		slices.SortFunc(sets, func(a, b map[int]struct{}) int { return len(a) - len(b) })
+	next:
+		for id := range sets[0] {
+			for _, set := range sets[1:] {
+				if _, ok := set[id]; !ok {
+					continue next
+				}
+			}
+			ids = append(ids, id)
+		}
+	} else {
+		seen := make(map[int]struct{})
+		for _, set := range sets {
+			for id := range set {
+				if _, ok := seen[id]; !ok {
+					seen[id] = struct{}{}
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	slices.Sort(ids)
+
+	return ids, true
+}
+
+// with returns a sorted copy of ids with the id, the original slice isn't modified
+func with(ids []int, id int) []int {
+	if slices.Contains(ids, id) {
+		return ids
+	}
+	ans := append(slices.Clone(ids), id)
+	slices.Sort(ans)
+	return ans
+}
+
+// without returns a copy of ids without the id, the original slice isn't modified
+func without(ids []int, id int) []int {
+	if !slices.Contains(ids, id) {
+		return ids
+	}
+	ans := make([]int, 0, len(ids)-1)
+	for _, v := range ids {
+		if v != id {
+			ans = append(ans, v)
+		}
+	}
+	if len(ans) == 0 {
+		return nil
+	}
+	return ans
+}
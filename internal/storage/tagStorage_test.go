@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"slices"
+	"testing"
+)
+
+func TestTagStorage(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, _ := NewTaskStorage(mockLogger)
+	ctx := context.Background()
+
+	backend, err := storage.StoreTag(ctx, model.Tag{Name: "backend"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bug, _ := storage.StoreTag(ctx, model.Tag{Name: "bug"})
+	if backend != 1 || bug != 2 {
+		t.Fatalf("Expected tag ids 1 and 2, got %d and %d", backend, bug)
+	}
+	if _, err := storage.StoreTag(ctx, model.Tag{Name: "bug"}); !errors.Is(err, model.ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists, got %v", err)
+	}
+
+	for range 3 {
+		storage.Store(ctx, model.Task{Name: "Task", Status: model.Created})
+	}
+	storage.AttachTag(ctx, 0, backend)
+	storage.AttachTag(ctx, 0, bug)
+	storage.AttachTag(ctx, 1, bug)
+	storage.AttachTag(ctx, 1, bug)
+
+	t.Run("attach", func(t *testing.T) {
+		task, _ := storage.GetByTaskId(ctx, 0)
+		if !slices.Equal(task.TagIDs, []int{backend, bug}) {
+			t.Errorf("Expected both tags on the task, got %v", task.TagIDs)
+		}
+		if _, err := storage.AttachTag(ctx, 99, bug); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for missing task, got %v", err)
+		}
+		if _, err := storage.AttachTag(ctx, 0, 99); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for missing tag, got %v", err)
+		}
+	})
+
+	t.Run("usage counts", func(t *testing.T) {
+		tags, _ := storage.GetAllTags(ctx)
+		if len(tags) != 2 || tags[0].TaskCount != 1 || tags[1].TaskCount != 2 {
+			t.Errorf("Unexpected tags %v", tags)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			filter   model.Filter
+			expected []int
+		}{
+			{name: "any", filter: model.Filter{Tags: []string{"backend", "bug"}}, expected: []int{0, 1}},
+			{name: "all", filter: model.Filter{Tags: []string{"backend", "BUG"}, TagMatch: model.TagMatchAll}, expected: []int{0}},
+			{name: "unknown tag with any", filter: model.Filter{Tags: []string{"bug", "frontend"}}, expected: []int{0, 1}},
+			{name: "unknown tag with all", filter: model.Filter{Tags: []string{"bug", "frontend"}, TagMatch: model.TagMatchAll}, expected: []int{}},
+			{name: "combined with status", filter: model.Filter{Tags: []string{"bug"}, Status: model.Done}, expected: []int{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tasks, _, _ := storage.GetAll(ctx, tt.filter)
+				ids := make([]int, 0)
+				for _, task := range tasks {
+					ids = append(ids, task.Id)
+				}
+				if !slices.Equal(ids, tt.expected) {
+					t.Errorf("Expected %v, got %v", tt.expected, ids)
+				}
+
+				iterated := make([]int, 0)
+				storage.ForEach(ctx, tt.filter, func(task model.Task) error {
+					iterated = append(iterated, task.Id)
+					return nil
+				})
+				if !slices.Equal(iterated, tt.expected) {
+					t.Errorf("Expected ForEach to visit %v, got %v", tt.expected, iterated)
+				}
+			})
+		}
+	})
+
+	t.Run("update keeps tags", func(t *testing.T) {
+		task, _ := storage.GetByTaskId(ctx, 1)
+		updated, _ := storage.Update(ctx, model.Task{Id: 1, Name: "Renamed", Status: model.Done})
+		if !slices.Equal(updated.TagIDs, task.TagIDs) {
+			t.Errorf("Expected tags %v to be kept, got %v", task.TagIDs, updated.TagIDs)
+		}
+	})
+
+	t.Run("detach", func(t *testing.T) {
+		before, _ := storage.GetByTaskId(ctx, 0)
+		task, err := storage.DetachTag(ctx, 0, backend)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !slices.Equal(task.TagIDs, []int{bug}) {
+			t.Errorf("Expected only bug tag, got %v", task.TagIDs)
+		}
+		if !slices.Equal(before.TagIDs, []int{backend, bug}) {
+			t.Errorf("Previously returned copy was modified: %v", before.TagIDs)
+		}
+		tag, _ := storage.GetTagById(ctx, backend)
+		if tag.TaskCount != 0 {
+			t.Errorf("Expected backend to be unused, got %d", tag.TaskCount)
+		}
+	})
+
+	t.Run("deleting a task drops it from the index", func(t *testing.T) {
+		storage.Delete(ctx, 1)
+		tag, _ := storage.GetTagById(ctx, bug)
+		if tag.TaskCount != 1 {
+			t.Errorf("Expected bug to be used once, got %d", tag.TaskCount)
+		}
+	})
+
+	t.Run("rename and delete a tag", func(t *testing.T) {
+		if _, err := storage.UpdateTag(ctx, model.Tag{Id: backend, Name: "bug"}); !errors.Is(err, model.ErrAlreadyExists) {
+			t.Errorf("Expected ErrAlreadyExists, got %v", err)
+		}
+		if _, err := storage.UpdateTag(ctx, model.Tag{Id: bug, Name: "defect"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		tasks, _, _ := storage.GetAll(ctx, model.Filter{Tags: []string{"defect"}})
+		if len(tasks) != 1 {
+			t.Errorf("Expected renamed tag to keep its tasks, got %v", tasks)
+		}
+
+		if err := storage.DeleteTag(ctx, bug); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		task, _ := storage.GetByTaskId(ctx, 0)
+		if len(task.TagIDs) != 0 {
+			t.Errorf("Expected deleted tag to be detached, got %v", task.TagIDs)
+		}
+	})
+
+	t.Run("tags are scoped to the project", func(t *testing.T) {
+		other := tenant.WithScope(ctx, tenant.Scope{ProjectID: 2})
+		tags, _ := storage.GetAllTags(other)
+		if len(tags) != 0 {
+			t.Errorf("Expected no tags in another project, got %v", tags)
+		}
+		if _, err := storage.AttachTag(other, 0, backend); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"slices"
+	"time"
+)
+
+// errInconsistentEvent is returned when an event of the log doesn't fit the state it's folded into
+var errInconsistentEvent = errors.New("inconsistent event")
+
+// applyEvent folds the event into the partition with the applies TaskStorage uses, must be called under lock.
+// Events were checked before they were recorded, so only references that would break
+// the partition are checked again.
+func (p *taskPartition) applyEvent(e model.TaskEvent) error {
+	inconsistent := fmt.Errorf("event(%v) %v: %w", e.Seq, e.Type, errInconsistentEvent)
+	switch e.Type {
+	case model.TaskCreated:
+		if e.Task == nil || e.Task.Id != p.idCounter {
+			return inconsistent
+		}
+		p.applyStore(*e.Task, e.At)
+	case model.TaskRenamed, model.TaskStatusChanged, model.TaskDetailsChanged:
+		if !p.exists(e.TaskID) {
+			return inconsistent
+		}
+		task := p.tasks[e.TaskID]
+		switch e.Type {
+		case model.TaskRenamed:
+			task.Name = e.Name
+		case model.TaskStatusChanged:
+			task.Status = e.Status
+		case model.TaskDetailsChanged:
+			if e.Task == nil {
+				return inconsistent
+			}
+			task.Description = e.Task.Description
+			task.Priority = e.Task.Priority
+			task.AssigneeID = e.Task.AssigneeID
+			task.ReporterID = e.Task.ReporterID
+			task.DueAt = e.Task.DueAt
+			task.ParentID = e.Task.ParentID
+			task.Recurrence = e.Task.Recurrence
+		}
+		p.applyUpdate(task, e.At)
+	case model.TaskDeleted:
+		if !p.exists(e.TaskID) {
+			return inconsistent
+		}
+		p.applyDelete(e.TaskID)
+	case model.BlockerAdded, model.BlockerRemoved:
+		if !p.exists(e.TaskID) || !p.exists(e.RelatedID) {
+			return inconsistent
+		}
+		if e.Type == model.BlockerAdded {
+			p.applyAddBlocker(e.TaskID, e.RelatedID)
+		} else {
+			p.applyRemoveBlocker(e.TaskID, e.RelatedID)
+		}
+	case model.TagCreated:
+		if e.Tag == nil || e.Tag.Id != p.tagCounter+1 {
+			return inconsistent
+		}
+		p.applyStoreTag(*e.Tag, e.At)
+	case model.TagRenamed:
+		if e.Tag == nil {
+			return inconsistent
+		}
+		if _, ok := p.tags[e.Tag.Id]; !ok {
+			return inconsistent
+		}
+		p.applyRenameTag(*e.Tag)
+	case model.TagDeleted:
+		if _, ok := p.tags[e.RelatedID]; !ok {
+			return inconsistent
+		}
+		p.applyDeleteTag(e.RelatedID)
+	case model.TagAttached, model.TagDetached:
+		if p.checkTagging(e.TaskID, e.RelatedID) != nil {
+			return inconsistent
+		}
+		if e.Type == model.TagAttached {
+			p.applyAttachTag(e.TaskID, e.RelatedID)
+		} else {
+			p.applyDetachTag(e.TaskID, e.RelatedID)
+		}
+	default:
+		return inconsistent
+	}
+	return nil
+}
+
+// updateEvents returns the events changing the stored task into the updated one,
+// there are none if nothing changed
+func updateEvents(stored, task model.Task) []model.TaskEvent {
+	events := make([]model.TaskEvent, 0, 3)
+	if task.Description != stored.Description || task.Priority != stored.Priority ||
+		task.AssigneeID != stored.AssigneeID || task.ReporterID != stored.ReporterID ||
+		!task.DueAt.Equal(stored.DueAt) || !sameId(task.ParentID, stored.ParentID) ||
+		!sameRecurrence(task.Recurrence, stored.Recurrence) {
+		details := model.Task{
+			Description: task.Description,
+			Priority:    task.Priority,
+			AssigneeID:  task.AssigneeID,
+			ReporterID:  task.ReporterID,
+			DueAt:       task.DueAt,
+			ParentID:    cloneId(task.ParentID),
+			Recurrence:  cloneRecurrence(task.Recurrence),
+		}
+		events = append(events, model.TaskEvent{Type: model.TaskDetailsChanged, TaskID: task.Id, Task: &details})
+	}
+	if task.Name != stored.Name {
+		events = append(events, model.TaskEvent{Type: model.TaskRenamed, TaskID: task.Id, Name: task.Name})
+	}
+	if task.Status != stored.Status {
+		events = append(events, model.TaskEvent{Type: model.TaskStatusChanged, TaskID: task.Id, Status: task.Status})
+	}
+	return events
+}
+
+// partitionState is everything a partition can't derive: indexes and graphs are rebuilt from tasks
+type partitionState struct {
+	ProjectID int `json:"project_id"`
+	// Tasks are the tasks that aren't deleted in order of ids
+	Tasks []model.Task `json:"tasks"`
+	// NextID is the id of the next task, snapshots without it hold placeholders of deleted tasks
+	// listed in Deleted, so position of a task is its id
+	NextID     int         `json:"next_id,omitempty"`
+	Deleted    []int       `json:"deleted,omitempty"`
+	Tags       []model.Tag `json:"tags"`
+	TagCounter int         `json:"tag_counter"`
+}
+
+// projectionSnapshot is the state of every partition after the event with Seq was folded
+type projectionSnapshot struct {
+	Seq        int              `json:"seq"`
+	At         time.Time        `json:"at"`
+	Partitions []partitionState `json:"partitions"`
+}
+
+func (s projectionSnapshot) partition(projectId int) (partitionState, bool) {
+	for _, state := range s.Partitions {
+		if state.ProjectID == projectId {
+			return state, true
+		}
+	}
+	return partitionState{}, false
+}
+
+// state copies the partition, must be called under lock
+func (p *taskPartition) state() partitionState {
+	tasks := make([]model.Task, 0, len(p.tasks))
+	for _, task := range p.tasks {
+		tasks = append(tasks, task)
+	}
+	slices.SortFunc(tasks, func(a, b model.Task) int { return cmp.Compare(a.Id, b.Id) })
+	tags := make([]model.Tag, 0, len(p.tags))
+	for _, tag := range p.tags {
+		tags = append(tags, tag)
+	}
+	slices.SortFunc(tags, func(a, b model.Tag) int { return cmp.Compare(a.Id, b.Id) })
+
+	return partitionState{
+		ProjectID:  p.projectId,
+		Tasks:      tasks,
+		NextID:     p.idCounter,
+		Tags:       tags,
+		TagCounter: p.tagCounter,
+	}
+}
+
+// restorePartition builds a partition from the state, the state isn't shared with it
+func restorePartition(state partitionState) *taskPartition {
+	p := newTaskPartition(state.ProjectID)
+	p.idCounter = cmp.Or(state.NextID, len(state.Tasks))
+	for _, task := range state.Tasks {
+		if !slices.Contains(state.Deleted, task.Id) {
+			p.tasks[task.Id] = task
+		}
+	}
+	for _, tag := range state.Tags {
+		p.tags[tag.Id] = tag
+		p.tagsByName[tag.Name] = tag.Id
+		p.tagIndex[tag.Id] = make(map[int]struct{})
+	}
+	p.tagCounter = state.TagCounter
+
+	for _, task := range p.tasks {
+		if task.PublicID != "" {
+			p.publicIds[task.PublicID] = task.Id
+		}
+		p.index(task)
+		for _, tagId := range task.TagIDs {
+			if index, ok := p.tagIndex[tagId]; ok {
+				index[task.Id] = struct{}{}
+			}
+		}
+		link(p.children, task.ParentID, task.Id)
+		for _, blockerId := range task.BlockedBy {
+			link(p.blocks, &blockerId, task.Id)
+		}
+	}
+	// public ids of the parent and the blockers aren't kept in snapshots
+	for id, task := range p.tasks {
+		task.PublicParentID = publicIdOf(p, task.ParentID)
+		task.PublicBlockedBy = publicIdsOf(p, task.BlockedBy)
+		p.tasks[id] = task
+	}
+	return p
+}
@@ -0,0 +1,329 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"slices"
+)
+
+// Subtasks and blockers form two separate graphs inside a partition. Both are kept acyclic:
+// every new parent and every new blocker is checked before the edge is inserted.
+//
+// BlockedBy of a stored task is never modified in place, it's replaced like TagIDs.
+
+// AddBlocker makes blockerId block taskId, adding an existing blocker changes nothing.
+// It's rejected with model.ErrCycle if taskId already blocks blockerId, directly or not.
+func (st *TaskStorage) AddBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error) {
+	p := st.partition(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if err := checkBlocker(p, taskId, blockerId); err != nil {
+		return nil, fmt.Errorf("%v: error while adding blocker(%v) to task(%v): %w", storageName, blockerId, taskId, err)
+	}
+	ans := p.applyAddBlocker(taskId, blockerId)
+	return &ans, nil
+}
+
+// RemoveBlocker removes the blocker of the task, removing a task that isn't a blocker changes nothing
+func (st *TaskStorage) RemoveBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error) {
+	p := st.partition(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if !p.exists(taskId) || !p.exists(blockerId) {
+		return nil, fmt.Errorf("%v: error while removing blocker(%v) of task(%v): %w", storageName, blockerId, taskId, model.ErrNotFound)
+	}
+	ans := p.applyRemoveBlocker(taskId, blockerId)
+	return &ans, nil
+}
+
+// GetSubtasks returns the task with its subtasks down to depth levels, subtasks are ordered by id
+func (st *TaskStorage) GetSubtasks(ctx context.Context, taskId, depth int) (*model.TaskNode, error) {
+	p := st.partition(ctx)
+	p.m.RLock()
+	defer p.m.RUnlock()
+	if !p.exists(taskId) {
+		return nil, fmt.Errorf("%v: error while retrieving subtasks of task(%v): %w", storageName, taskId, model.ErrNotFound)
+	}
+	node := subtree(p, taskId, depth)
+
+	return &node, nil
+}
+
+// DependencyOrder returns the task and every task blocking it directly or not in an order
+// they can be done in: each task comes after all of its blockers, the task itself is the last one.
+// Tasks that can be done at the same point are ordered by id.
+func (st *TaskStorage) DependencyOrder(ctx context.Context, taskId int) ([]model.Task, error) {
+	p := st.partition(ctx)
+	p.m.RLock()
+	defer p.m.RUnlock()
+	if !p.exists(taskId) {
+		return nil, fmt.Errorf("%v: error while ordering dependencies of task(%v): %w", storageName, taskId, model.ErrNotFound)
+	}
+
+	ans, ok := dependencyOrder(p, taskId)
+	if !ok {
+		// unreachable while edges are checked on insert
+		return nil, fmt.Errorf("%v: error while ordering dependencies of task(%v): %w", storageName, taskId, model.ErrCycle)
+	}
+
+	return ans, nil
+}
+
+// applyAddBlocker adds the edge checked by checkBlocker, must be called under lock
+func (p *taskPartition) applyAddBlocker(taskId, blockerId int) model.Task {
+	task := p.tasks[taskId]
+	task.BlockedBy = with(task.BlockedBy, blockerId)
+	task.PublicBlockedBy = publicIdsOf(p, task.BlockedBy)
+	p.tasks[taskId] = task
+	link(p.blocks, &blockerId, taskId)
+	return task
+}
+
+func (p *taskPartition) applyRemoveBlocker(taskId, blockerId int) model.Task {
+	task := p.tasks[taskId]
+	task.BlockedBy = without(task.BlockedBy, blockerId)
+	task.PublicBlockedBy = publicIdsOf(p, task.BlockedBy)
+	p.tasks[taskId] = task
+	unlink(p.blocks, &blockerId, taskId)
+	return task
+}
+
+// taskGraph gives the checks and walks over subtasks and blockers access to the tasks of a project,
+// it's implemented by partitions of TaskStorage and projects of ShardedTaskStorage
+type taskGraph interface {
+	// task returns the task if it exists
+	task(taskId int) (model.Task, bool)
+	// subtaskIds returns ids of direct subtasks of the task
+	subtaskIds(taskId int) map[int]struct{}
+	// blockedIds returns ids of the tasks the task blocks
+	blockedIds(taskId int) map[int]struct{}
+}
+
+func (p *taskPartition) task(taskId int) (model.Task, bool) {
+	if !p.exists(taskId) {
+		return model.Task{}, false
+	}
+	return p.tasks[taskId], true
+}
+
+func (p *taskPartition) subtaskIds(taskId int) map[int]struct{} {
+	return p.children[taskId]
+}
+
+func (p *taskPartition) blockedIds(taskId int) map[int]struct{} {
+	return p.blocks[taskId]
+}
+
+// checkParent checks that the parent exists and isn't the task or one of its subtasks, must be called under lock
+func checkParent(g taskGraph, taskId int, parentId *int) error {
+	if parentId == nil {
+		return nil
+	}
+	for ancestor := parentId; ancestor != nil; {
+		if *ancestor == taskId {
+			return fmt.Errorf("task(%v) can't be a subtask of its own subtask(%v): %w", taskId, *parentId, model.ErrCycle)
+		}
+		task, ok := g.task(*ancestor)
+		if !ok {
+			return model.Invalid(fmt.Errorf("parent task(%v) doesn't exist", *parentId))
+		}
+		ancestor = task.ParentID
+	}
+	return nil
+}
+
+// checkBlocker checks that both tasks exist and the task doesn't block the blocker, must be called under lock
+func checkBlocker(g taskGraph, taskId, blockerId int) error {
+	_, taskOk := g.task(taskId)
+	_, blockerOk := g.task(blockerId)
+	if !taskOk || !blockerOk {
+		return model.ErrNotFound
+	}
+	if dependsOn(g, blockerId, taskId) {
+		return model.ErrCycle
+	}
+	return nil
+}
+
+// dependsOn reports whether target blocks the task directly or not, the task depends on itself,
+// must be called under lock
+func dependsOn(g taskGraph, taskId, target int) bool {
+	visited := make(map[int]struct{})
+	stack := []int{taskId}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == target {
+			return true
+		}
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+		task, _ := g.task(id)
+		stack = append(stack, task.BlockedBy...)
+	}
+	return false
+}
+
+// incomplete reports whether the task has blockers or direct subtasks that aren't done, must be called under lock
+func incomplete(g taskGraph, taskId int) bool {
+	task, _ := g.task(taskId)
+	for _, blockerId := range task.BlockedBy {
+		if blocker, _ := g.task(blockerId); blocker.Status != model.Done {
+			return true
+		}
+	}
+	for childId := range g.subtaskIds(taskId) {
+		if child, _ := g.task(childId); child.Status != model.Done {
+			return true
+		}
+	}
+	return false
+}
+
+// subtree builds the node of the task with depth levels of subtasks, must be called under lock
+func subtree(g taskGraph, taskId, depth int) model.TaskNode {
+	task, _ := g.task(taskId)
+	node := model.TaskNode{Task: task}
+	children := g.subtaskIds(taskId)
+	if depth <= 0 || len(children) == 0 {
+		return node
+	}
+	childIds := make([]int, 0, len(children))
+	for id := range children {
+		childIds = append(childIds, id)
+	}
+	slices.Sort(childIds)
+
+	node.Subtasks = make([]model.TaskNode, 0, len(childIds))
+	for _, id := range childIds {
+		node.Subtasks = append(node.Subtasks, subtree(g, id, depth-1))
+	}
+	return node
+}
+
+// dependencyOrder returns the task after every task blocking it directly or not, see DependencyOrder.
+// It's false if blockers form a cycle, must be called under lock
+func dependencyOrder(g taskGraph, taskId int) ([]model.Task, bool) {
+	// collect the closure and count blockers of every task inside it
+	tasks := make(map[int]model.Task)
+	pending := make(map[int]int)
+	stack := []int{taskId}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := pending[id]; ok {
+			continue
+		}
+		tasks[id], _ = g.task(id)
+		pending[id] = len(tasks[id].BlockedBy)
+		stack = append(stack, tasks[id].BlockedBy...)
+	}
+
+	ready := make([]int, 0)
+	for id, blockers := range pending {
+		if blockers == 0 {
+			ready = append(ready, id)
+		}
+	}
+	ans := make([]model.Task, 0, len(pending))
+	for len(ready) > 0 {
+		slices.Sort(ready)
+		id := ready[0]
+		ready = ready[1:]
+		ans = append(ans, tasks[id])
+		for blocked := range g.blockedIds(id) {
+			if _, ok := pending[blocked]; !ok {
+				continue
+			}
+			pending[blocked]--
+			if pending[blocked] == 0 {
+				ready = append(ready, blocked)
+			}
+		}
+	}
+	return ans, len(ans) == len(pending)
+}
+
+// detachFromGraph removes the task being deleted from both graphs, its subtasks become top level
+// tasks and the tasks it blocked are unblocked, must be called under lock
+func (p *taskPartition) detachFromGraph(taskId int) {
+	task := p.tasks[taskId]
+	unlink(p.children, task.ParentID, taskId)
+	for childId := range p.children[taskId] {
+		child := p.tasks[childId]
+		child.ParentID, child.PublicParentID = nil, ""
+		p.tasks[childId] = child
+	}
+	delete(p.children, taskId)
+
+	for _, blockerId := range task.BlockedBy {
+		unlink(p.blocks, &blockerId, taskId)
+	}
+	for blockedId := range p.blocks[taskId] {
+		blocked := p.tasks[blockedId]
+		blocked.BlockedBy = without(blocked.BlockedBy, taskId)
+		blocked.PublicBlockedBy = publicIdsOf(p, blocked.BlockedBy)
+		p.tasks[blockedId] = blocked
+	}
+	delete(p.blocks, taskId)
+}
+
+// link adds the edge from -> to into the adjacency map, nil from means there is no edge
+func link(edges map[int]map[int]struct{}, from *int, to int) {
+	if from == nil {
+		return
+	}
+	if edges[*from] == nil {
+		edges[*from] = make(map[int]struct{})
+	}
+	edges[*from][to] = struct{}{}
+}
+
+func unlink(edges map[int]map[int]struct{}, from *int, to int) {
+	if from == nil {
+		return
+	}
+	delete(edges[*from], to)
+	if len(edges[*from]) == 0 {
+		delete(edges, *from)
+	}
+}
+
+// publicIdOf returns the public id of the task, empty when there is no task or it has no public id
+func publicIdOf(g taskGraph, taskId *int) string {
+	if taskId == nil {
+		return ""
+	}
+	task, _ := g.task(*taskId)
+	return task.PublicID
+}
+
+// publicIdsOf returns the public ids of the tasks in their order, nil when none of them has a public id
+func publicIdsOf(g taskGraph, taskIds []int) []string {
+	ans := make([]string, len(taskIds))
+	for i, taskId := range taskIds {
+		ans[i] = publicIdOf(g, &taskId)
+	}
+	if !slices.ContainsFunc(ans, func(publicId string) bool { return publicId != "" }) {
+		return nil
+	}
+	return ans
+}
+
+func cloneId(id *int) *int {
+	if id == nil {
+		return nil
+	}
+	v := *id
+	return &v
+}
+
+func sameId(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
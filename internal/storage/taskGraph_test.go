@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"slices"
+	"testing"
+)
+
+func taskIds(tasks []model.Task) []int {
+	ids := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.Id)
+	}
+	return ids
+}
+
+func TestSubtasks(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, _ := NewTaskStorage(mockLogger, WithStrictCompletion(true))
+	ctx := context.Background()
+	parentOf := func(id int) *int { return &id }
+
+	root, _ := storage.Store(ctx, model.Task{Name: "root", Status: model.Created})
+	child, _ := storage.Store(ctx, model.Task{Name: "child", Status: model.Created, ParentID: parentOf(root)})
+	grandchild, _ := storage.Store(ctx, model.Task{Name: "grandchild", Status: model.Created, ParentID: parentOf(child)})
+	storage.Store(ctx, model.Task{Name: "second child", Status: model.Done, ParentID: parentOf(root)})
+
+	t.Run("missing parent", func(t *testing.T) {
+		_, err := storage.Store(ctx, model.Task{Name: "orphan", Status: model.Created, ParentID: parentOf(99)})
+		if !errors.Is(err, model.ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("tree with depth", func(t *testing.T) {
+		node, err := storage.GetSubtasks(ctx, root, 1)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(node.Subtasks) != 2 || node.Subtasks[0].Task.Id != child || len(node.Subtasks[0].Subtasks) != 0 {
+			t.Errorf("Unexpected tree of depth 1 %+v", node)
+		}
+
+		node, _ = storage.GetSubtasks(ctx, root, 5)
+		if len(node.Subtasks[0].Subtasks) != 1 || node.Subtasks[0].Subtasks[0].Task.Id != grandchild {
+			t.Errorf("Unexpected full tree %+v", node)
+		}
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		rootTask, _ := storage.GetByTaskId(ctx, root)
+		rootTask.ParentID = parentOf(grandchild)
+		if _, err := storage.Update(ctx, *rootTask); !errors.Is(err, model.ErrCycle) {
+			t.Errorf("Expected ErrCycle for a subtask as parent, got %v", err)
+		}
+		rootTask.ParentID = parentOf(root)
+		if _, err := storage.Update(ctx, *rootTask); !errors.Is(err, model.ErrCycle) {
+			t.Errorf("Expected ErrCycle for the task itself, got %v", err)
+		}
+	})
+
+	t.Run("parent can't be done before subtasks", func(t *testing.T) {
+		childTask, _ := storage.GetByTaskId(ctx, child)
+		childTask.Status = model.Done
+		if _, err := storage.Update(ctx, *childTask); !errors.Is(err, model.ErrIncomplete) {
+			t.Fatalf("Expected ErrIncomplete, got %v", err)
+		}
+
+		grandchildTask, _ := storage.GetByTaskId(ctx, grandchild)
+		grandchildTask.Status = model.Done
+		storage.Update(ctx, *grandchildTask)
+		if _, err := storage.Update(ctx, *childTask); err != nil {
+			t.Errorf("Expected child to be done after its subtask, got %v", err)
+		}
+	})
+
+	t.Run("moving a subtask", func(t *testing.T) {
+		grandchildTask, _ := storage.GetByTaskId(ctx, grandchild)
+		grandchildTask.ParentID = parentOf(root)
+		if _, err := storage.Update(ctx, *grandchildTask); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		node, _ := storage.GetSubtasks(ctx, root, 1)
+		if len(node.Subtasks) != 3 {
+			t.Errorf("Expected 3 subtasks of root, got %+v", node.Subtasks)
+		}
+	})
+
+	t.Run("deleting a parent promotes subtasks", func(t *testing.T) {
+		storage.Delete(ctx, root)
+		childTask, _ := storage.GetByTaskId(ctx, child)
+		if childTask.HasParent() {
+			t.Errorf("Expected child to become a top level task, got parent %v", *childTask.ParentID)
+		}
+	})
+}
+
+func TestBlockers(t *testing.T) {
+	mockLogger := &MockLogger{}
+	ctx := context.Background()
+
+	newStorage := func(strict bool) *TaskStorage {
+		storage, _ := NewTaskStorage(mockLogger, WithStrictCompletion(strict))
+		for _, name := range []string{"design", "backend", "frontend", "release", "unrelated"} {
+			storage.Store(ctx, model.Task{Name: name, Status: model.Created})
+		}
+		// design -> backend -> release, design -> frontend -> release
+		storage.AddBlocker(ctx, 1, 0)
+		storage.AddBlocker(ctx, 2, 0)
+		storage.AddBlocker(ctx, 3, 2)
+		storage.AddBlocker(ctx, 3, 1)
+		return storage
+	}
+
+	t.Run("cycle detection", func(t *testing.T) {
+		storage := newStorage(true)
+		tests := []struct {
+			name      string
+			taskId    int
+			blockerId int
+			wantErr   error
+		}{
+			{name: "direct cycle", taskId: 0, blockerId: 1, wantErr: model.ErrCycle},
+			{name: "transitive cycle", taskId: 0, blockerId: 3, wantErr: model.ErrCycle},
+			{name: "self", taskId: 4, blockerId: 4, wantErr: model.ErrCycle},
+			{name: "missing blocker", taskId: 4, blockerId: 99, wantErr: model.ErrNotFound},
+			{name: "diamond edge", taskId: 3, blockerId: 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := storage.AddBlocker(ctx, tt.taskId, tt.blockerId); !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
+
+	t.Run("topological order", func(t *testing.T) {
+		storage := newStorage(true)
+		tasks, err := storage.DependencyOrder(ctx, 3)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !slices.Equal(taskIds(tasks), []int{0, 1, 2, 3}) {
+			t.Errorf("Unexpected order %v", taskIds(tasks))
+		}
+
+		tasks, _ = storage.DependencyOrder(ctx, 4)
+		if !slices.Equal(taskIds(tasks), []int{4}) {
+			t.Errorf("Expected only the task itself, got %v", taskIds(tasks))
+		}
+	})
+
+	t.Run("open blockers", func(t *testing.T) {
+		storage := newStorage(true)
+		if _, err := storage.Update(ctx, model.Task{Id: 1, Name: "backend", Status: model.Done}); !errors.Is(err, model.ErrIncomplete) {
+			t.Errorf("Expected ErrIncomplete, got %v", err)
+		}
+		storage.Update(ctx, model.Task{Id: 0, Name: "design", Status: model.Done})
+		if _, err := storage.Update(ctx, model.Task{Id: 1, Name: "backend", Status: model.Done}); err != nil {
+			t.Errorf("Expected backend to be done after design, got %v", err)
+		}
+
+		relaxed := newStorage(false)
+		if _, err := relaxed.Update(ctx, model.Task{Id: 3, Name: "release", Status: model.Done}); err != nil {
+			t.Errorf("Expected non strict storage to allow it, got %v", err)
+		}
+	})
+
+	t.Run("remove and delete", func(t *testing.T) {
+		storage := newStorage(true)
+		task, _ := storage.RemoveBlocker(ctx, 3, 2)
+		if !slices.Equal(task.BlockedBy, []int{1}) {
+			t.Errorf("Expected only backend to block release, got %v", task.BlockedBy)
+		}
+
+		storage.Delete(ctx, 0)
+		backend, _ := storage.GetByTaskId(ctx, 1)
+		if len(backend.BlockedBy) != 0 {
+			t.Errorf("Expected deleted blocker to be removed, got %v", backend.BlockedBy)
+		}
+		tasks, _ := storage.DependencyOrder(ctx, 3)
+		if !slices.Equal(taskIds(tasks), []int{1, 3}) {
+			t.Errorf("Unexpected order %v", taskIds(tasks))
+		}
+	})
+}
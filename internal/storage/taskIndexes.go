@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"ivanjabrony/test_lo/internal/btree"
+	"ivanjabrony/test_lo/internal/model"
+	"math"
+	"slices"
+	"time"
+)
+
+// Secondary indexes of a partition let GetAll and ForEach check only the tasks that may match
+// the filter instead of every task of the project. They are changed by the applies together with
+// the tasks, so they are folded from events and rebuilt from snapshots like the rest of the partition.
+
+// createdKey orders tasks by creation time, tasks created at the same moment are ordered by id
+type createdKey struct {
+	at time.Time
+	id int
+}
+
+func createdLess(a, b createdKey) bool {
+	if c := a.at.Compare(b.at); c != 0 {
+		return c < 0
+	}
+	return a.id < b.id
+}
+
+func idLess(a, b int) bool {
+	return a < b
+}
+
+type taskIndexes struct {
+	// byStatus maps statuses to ordered ids of the tasks in them
+	byStatus map[model.TaskStatus]*btree.BTree[int]
+	// byCreated orders the tasks by creation time, it never changes after a task is stored
+	byCreated *btree.BTree[createdKey]
+}
+
+func newTaskIndexes() taskIndexes {
+	return taskIndexes{
+		byStatus:  make(map[model.TaskStatus]*btree.BTree[int]),
+		byCreated: btree.New(createdLess),
+	}
+}
+
+// index adds a stored task to the indexes, must be called under lock
+func (ix *taskIndexes) index(task model.Task) {
+	ix.indexStatus(task.Id, task.Status)
+	ix.byCreated.Insert(createdKey{task.CreatedAt, task.Id})
+}
+
+// reindex moves the updated task between the status indexes, must be called under lock
+func (ix *taskIndexes) reindex(stored, task model.Task) {
+	if stored.Status != task.Status {
+		ix.unindexStatus(task.Id, stored.Status)
+		ix.indexStatus(task.Id, task.Status)
+	}
+}
+
+// unindex removes a task being deleted from the indexes, must be called under lock
+func (ix *taskIndexes) unindex(task model.Task) {
+	ix.unindexStatus(task.Id, task.Status)
+	ix.byCreated.Delete(createdKey{task.CreatedAt, task.Id})
+}
+
+func (ix *taskIndexes) indexStatus(taskId int, status model.TaskStatus) {
+	ids, ok := ix.byStatus[status]
+	if !ok {
+		ids = btree.New(idLess)
+		ix.byStatus[status] = ids
+	}
+	ids.Insert(taskId)
+}
+
+func (ix *taskIndexes) unindexStatus(taskId int, status model.TaskStatus) {
+	if ids, ok := ix.byStatus[status]; ok {
+		ids.Delete(taskId)
+		if ids.Len() == 0 {
+			delete(ix.byStatus, status)
+		}
+	}
+}
+
+// createdBetween calls fn with ids of the tasks created in [after, before) ordered by creation time
+// until it returns false, zero bounds are open
+func (ix *taskIndexes) createdBetween(after, before time.Time, fn func(int) bool) {
+	ix.byCreated.AscendFrom(createdKey{after, math.MinInt}, func(key createdKey) bool {
+		if !before.IsZero() && !key.at.Before(before) {
+			return false
+		}
+		return fn(key.id)
+	})
+}
+
+// Sources of the ids plan returns
+const (
+	planScan = iota
+	planTags
+	planStatus
+	planCreated
+)
+
+// plan returns sorted ids of the tasks that may match the filter taken from the smallest of the tag,
+// status and creation time indexes the filter can use. indexed is false when no index is smaller
+// than the partition and every task has to be checked, must be called under lock
+func (p *taskPartition) plan(filter model.Filter) (ids []int, indexed bool) {
+	best, size := planScan, p.count()
+
+	tagIds, tagged := p.candidates(filter)
+	if tagged && len(tagIds) < size {
+		best, size = planTags, len(tagIds)
+	}
+
+	var statusIds *btree.BTree[int]
+	if filter.Status != "" {
+		// there is no index of a status no task has
+		statusIds = p.byStatus[filter.Status]
+		n := 0
+		if statusIds != nil {
+			n = statusIds.Len()
+		}
+		if n < size {
+			best, size = planStatus, n
+		}
+	}
+
+	var createdIds []int
+	if !filter.CreatedAfter.IsZero() || !filter.CreatedBefore.IsZero() {
+		createdIds = make([]int, 0)
+		p.createdBetween(filter.CreatedAfter, filter.CreatedBefore, func(id int) bool {
+			// the range isn't smaller than the best index, there is no need to count further
+			if len(createdIds) >= size {
+				return false
+			}
+			createdIds = append(createdIds, id)
+			return true
+		})
+		if len(createdIds) < size {
+			best = planCreated
+		}
+	}
+
+	switch best {
+	case planTags:
+		return tagIds, true
+	case planStatus:
+		ids = make([]int, 0, size)
+		if statusIds != nil {
+			statusIds.Ascend(func(id int) bool {
+				ids = append(ids, id)
+				return true
+			})
+		}
+		return ids, true
+	case planCreated:
+		slices.Sort(createdIds)
+		return createdIds, true
+	}
+	return nil, false
+}
+
+// matcher returns the check of every field of the filter including tags, must be called under lock
+func (p *taskPartition) matcher(filter model.Filter, now time.Time) func(model.Task) bool {
+	if len(filter.Tags) == 0 {
+		return func(task model.Task) bool { return filter.Matches(task, now) }
+	}
+	tagIds := make([]int, 0, len(filter.Tags))
+	for _, name := range filter.Tags {
+		tagId, ok := p.tagsByName[model.NormalizeTagName(name)]
+		if !ok {
+			if filter.TagMatch == model.TagMatchAll {
+				return func(model.Task) bool { return false }
+			}
+			continue
+		}
+		tagIds = append(tagIds, tagId)
+	}
+	return func(task model.Task) bool {
+		if !filter.Matches(task, now) {
+			return false
+		}
+		for _, tagId := range tagIds {
+			attached := slices.Contains(task.TagIDs, tagId)
+			if attached && filter.TagMatch != model.TagMatchAll {
+				return true
+			}
+			if !attached && filter.TagMatch == model.TagMatchAll {
+				return false
+			}
+		}
+		return filter.TagMatch == model.TagMatchAll && len(tagIds) > 0
+	}
+}
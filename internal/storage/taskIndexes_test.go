@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+var (
+	indexEpoch    = time.Date(2030, time.June, 1, 0, 0, 0, 0, time.UTC)
+	indexStatuses = []model.TaskStatus{model.Created, model.InProgress, model.Done}
+	indexTags     = []string{"api", "db", "ui"}
+)
+
+const (
+	opStore = iota
+	opUpdate
+	opDelete
+	opAttach
+	opDetach
+	opDeleteTag
+	opCount
+)
+
+type indexOp struct {
+	kind   int
+	id     int
+	status model.TaskStatus
+	tag    string
+	// shift moves the clock before the operation, it may go back
+	shift time.Duration
+}
+
+// indexScenario is a random history of a project and filters to ask it, it's generated by testing/quick
+type indexScenario struct {
+	ops     []indexOp
+	filters []model.Filter
+}
+
+func (indexScenario) Generate(rnd *rand.Rand, size int) reflect.Value {
+	randomTime := func() time.Time {
+		return indexEpoch.Add(time.Duration(rnd.Intn(4*size+1)) * time.Minute)
+	}
+	s := indexScenario{}
+	for range rnd.Intn(4*size + 1) {
+		op := indexOp{
+			kind:   rnd.Intn(opCount),
+			id:     rnd.Intn(size + 1),
+			status: indexStatuses[rnd.Intn(len(indexStatuses))],
+			tag:    indexTags[rnd.Intn(len(indexTags))],
+			shift:  time.Duration(rnd.Intn(7)-2) * time.Minute,
+		}
+		if op.kind == opStore || rnd.Intn(3) == 0 {
+			op.kind = opStore
+		}
+		s.ops = append(s.ops, op)
+	}
+	for range 10 {
+		f := model.Filter{}
+		if rnd.Intn(2) == 0 {
+			f.Status = indexStatuses[rnd.Intn(len(indexStatuses))]
+		}
+		if rnd.Intn(2) == 0 {
+			f.CreatedAfter = randomTime()
+		}
+		if rnd.Intn(2) == 0 {
+			f.CreatedBefore = randomTime()
+		}
+		if rnd.Intn(3) == 0 {
+			f.Tags = []string{indexTags[rnd.Intn(len(indexTags))], "missing"}[:1+rnd.Intn(2)]
+			f.TagMatch = []model.TagMatch{"", model.TagMatchAny, model.TagMatchAll}[rnd.Intn(3)]
+		}
+		if rnd.Intn(4) == 0 {
+			f.SortBy = model.SortByCreatedAt
+		}
+		f.Descending = rnd.Intn(4) == 0
+		if rnd.Intn(3) == 0 {
+			f.Page = model.Page{Limit: 1 + rnd.Intn(size+1), Offset: rnd.Intn(size + 1)}
+		}
+		s.filters = append(s.filters, f)
+	}
+	return reflect.ValueOf(s)
+}
+
+// run replays the history against a new storage
+func (s indexScenario) run() (*TaskStorage, context.Context) {
+	c := clock.NewManual(indexEpoch)
+	st, _ := NewTaskStorage(silentLogger{}, WithClock(c))
+	ctx := context.Background()
+	for _, name := range indexTags {
+		st.StoreTag(ctx, model.Tag{Name: name})
+	}
+	for _, op := range s.ops {
+		c.Advance(op.shift)
+		switch op.kind {
+		case opStore:
+			st.Store(ctx, model.Task{Name: "task", Status: op.status})
+		case opUpdate:
+			st.Update(ctx, model.Task{Id: op.id, Name: "updated", Status: op.status})
+		case opDelete:
+			st.Delete(ctx, op.id)
+		case opAttach, opDetach:
+			tagId := slices.Index(indexTags, op.tag) + 1
+			if op.kind == opAttach {
+				st.AttachTag(ctx, op.id, tagId)
+			} else {
+				st.DetachTag(ctx, op.id, tagId)
+			}
+		case opDeleteTag:
+			st.DeleteTag(ctx, slices.Index(indexTags, op.tag)+1)
+		}
+	}
+	return st, ctx
+}
+
+// scan is GetAll without indexes and pages: every task of the partition is checked
+func scan(p *taskPartition, filter model.Filter, now time.Time) []model.Task {
+	ans := make([]model.Task, 0)
+	for id := range p.idCounter {
+		task, ok := p.tasks[id]
+		if !ok || !filter.Matches(task, now) {
+			continue
+		}
+		if len(filter.Tags) > 0 {
+			attached := 0
+			for _, name := range filter.Tags {
+				if tagId, ok := p.tagsByName[name]; ok && slices.Contains(task.TagIDs, tagId) {
+					attached++
+				}
+			}
+			if attached == 0 || filter.TagMatch == model.TagMatchAll && attached < len(filter.Tags) {
+				continue
+			}
+		}
+		ans = append(ans, task)
+	}
+	sortTasks(ans, filter.SortBy, filter.Descending)
+	return ans
+}
+
+func TestIndexesMatchScan(t *testing.T) {
+	property := func(s indexScenario) bool {
+		st, ctx := s.run()
+		p := st.partition(ctx)
+
+		// the indexes hold every task that isn't deleted and nothing else
+		statuses, created := 0, 0
+		for status, ids := range p.byStatus {
+			ids.Ascend(func(id int) bool {
+				if !p.exists(id) || p.tasks[id].Status != status {
+					t.Errorf("task(%v) is indexed with status %v", id, status)
+				}
+				statuses++
+				return true
+			})
+		}
+		p.byCreated.Ascend(func(key createdKey) bool {
+			if !p.exists(key.id) || !p.tasks[key.id].CreatedAt.Equal(key.at) {
+				t.Errorf("task(%v) is indexed as created at %v", key.id, key.at)
+			}
+			created++
+			return true
+		})
+		if statuses != p.count() || created != p.count() {
+			t.Errorf("expected %v indexed tasks, got %v by status and %v by creation time", p.count(), statuses, created)
+		}
+
+		for _, filter := range s.filters {
+			expected := scan(p, filter, indexEpoch)
+			page := expected
+			if filter.Paged() {
+				start := min(filter.Page.Offset, len(expected))
+				page = expected[start:min(start+filter.Page.Limit, len(expected))]
+			}
+			got, total, _ := st.GetAll(ctx, filter)
+			if !reflect.DeepEqual(got, page) || total != len(expected) {
+				t.Errorf("GetAll(%+v) returned %v of %v tasks, a scan finds %v of %v", filter, len(got), total, len(page), len(expected))
+				return false
+			}
+
+			slices.SortFunc(expected, func(a, b model.Task) int { return a.Id - b.Id })
+			iterated := make([]model.Task, 0)
+			st.ForEach(ctx, filter, func(task model.Task) error {
+				iterated = append(iterated, task)
+				return nil
+			})
+			if !reflect.DeepEqual(iterated, expected) {
+				t.Errorf("ForEach(%+v) visited %v tasks, a scan finds %v", filter, len(iterated), len(expected))
+				return false
+			}
+		}
+		return !t.Failed()
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 300}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPlanPicksSmallestIndex(t *testing.T) {
+	c := clock.NewManual(indexEpoch)
+	st, _ := NewTaskStorage(silentLogger{}, WithClock(c))
+	ctx := context.Background()
+	tagId, _ := st.StoreTag(ctx, model.Tag{Name: "rare"})
+	for i := range 100 {
+		c.Advance(time.Minute)
+		status := model.Created
+		if i%20 == 0 {
+			status = model.Done
+		}
+		st.Store(ctx, model.Task{Name: "task", Status: status})
+	}
+	for _, id := range []int{1, 2, 3} {
+		st.AttachTag(ctx, id, tagId)
+	}
+	p := st.partition(ctx)
+
+	minute := func(n int) time.Time { return indexEpoch.Add(time.Duration(n) * time.Minute) }
+	tests := []struct {
+		name     string
+		filter   model.Filter
+		expected []int
+		indexed  bool
+	}{
+		{name: "no index", filter: model.Filter{Priority: model.PriorityHigh}},
+		{name: "rare status", filter: model.Filter{Status: model.Done}, expected: []int{0, 20, 40, 60, 80}, indexed: true},
+		{name: "status no task has", filter: model.Filter{Status: model.InProgress}, expected: []int{}, indexed: true},
+		{name: "tags", filter: model.Filter{Tags: []string{"rare"}}, expected: []int{1, 2, 3}, indexed: true},
+		{
+			name:     "creation range is smaller than the status",
+			filter:   model.Filter{Status: model.Done, CreatedAfter: minute(50), CreatedBefore: minute(53)},
+			expected: []int{49, 50, 51},
+			indexed:  true,
+		},
+		{
+			name:     "status is smaller than the creation range",
+			filter:   model.Filter{Status: model.Done, CreatedAfter: minute(10)},
+			expected: []int{0, 20, 40, 60, 80},
+			indexed:  true,
+		},
+		{
+			name:     "tags are smaller than everything",
+			filter:   model.Filter{Status: model.Done, CreatedBefore: minute(30), Tags: []string{"rare"}},
+			expected: []int{1, 2, 3},
+			indexed:  true,
+		},
+		{name: "creation range covering every task", filter: model.Filter{CreatedAfter: minute(0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, indexed := p.plan(tt.filter)
+			if indexed != tt.indexed || !slices.Equal(ids, tt.expected) {
+				t.Errorf("Expected %v %v, got %v %v", tt.expected, tt.indexed, ids, indexed)
+			}
+		})
+	}
+
+	t.Run("indexes follow updates and deletes", func(t *testing.T) {
+		st.Update(ctx, model.Task{Id: 20, Name: "task", Status: model.InProgress})
+		st.Delete(ctx, 40)
+		if ids, _ := p.plan(model.Filter{Status: model.Done}); !slices.Equal(ids, []int{0, 60, 80}) {
+			t.Errorf("Unexpected done tasks %v", ids)
+		}
+		if ids, _ := p.plan(model.Filter{Status: model.InProgress}); !slices.Equal(ids, []int{20}) {
+			t.Errorf("Unexpected tasks in progress %v", ids)
+		}
+		if ids, _ := p.plan(model.Filter{CreatedAfter: minute(40), CreatedBefore: minute(43)}); !slices.Equal(ids, []int{39, 41}) {
+			t.Errorf("Expected deleted task to leave the creation index, got %v", ids)
+		}
+	})
+}
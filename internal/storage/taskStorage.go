@@ -1,71 +1,556 @@
 package storage
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
 	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"slices"
 	"sync"
 	"time"
 )
 
 const storageName = "TaskStorage"
 
+// forEachBatchSize is an amount of tasks copied under a single read lock in ForEach
+const forEachBatchSize = 256
+
 type Logger interface {
 	Log(format string, info ...any)
 }
 
+// Clock tells the time of changes and of reads like overdue filters, see clock.System
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator returns public ids of new tasks, see idgen
+type IDGenerator interface {
+	NextID() (string, error)
+}
+
+// TaskStorage keeps tasks of every project in a separate partition.
+//
+// A partition is picked by the tenant scope of the context before anything else happens,
+// and every partition has its own tasks, id sequence and lock. There is no way to reach
+// a task of another project: ids of different projects overlap and are resolved only
+// inside the selected partition.
+//
+// Ids are numbered from 0 in every partition and aren't reused. Tasks may also get public ids from an IDGenerator,
+// they are unique across projects and are the ones to show when ids shouldn't be enumerable.
 type TaskStorage struct {
-	tasks     []model.Task
+	partitions map[int]*taskPartition
+	logger     Logger
+	clock      Clock
+	// ids is nil when tasks have no public ids
+	ids IDGenerator
+	// strictCompletion forbids finishing tasks with open blockers or unfinished subtasks
+	strictCompletion bool
+	// m guards partitions map, tasks are guarded by locks of the partitions
+	m sync.RWMutex
+}
+
+// TaskStorageOption configures optional behaviour of TaskStorage
+type TaskStorageOption func(*TaskStorage)
+
+// WithStrictCompletion makes Update reject moving a task to done while it has
+// open blockers or subtasks that aren't done
+func WithStrictCompletion(strict bool) TaskStorageOption {
+	return func(st *TaskStorage) {
+		st.strictCompletion = strict
+	}
+}
+
+// WithClock replaces the system clock, tests use it to control timestamps
+func WithClock(clock Clock) TaskStorageOption {
+	return func(st *TaskStorage) {
+		st.clock = clock
+	}
+}
+
+// WithIDGenerator gives every new task a public id from the generator
+func WithIDGenerator(ids IDGenerator) TaskStorageOption {
+	return func(st *TaskStorage) {
+		st.ids = ids
+	}
+}
+
+type taskPartition struct {
+	projectId int
+	// tasks maps ids to the tasks that aren't deleted
+	tasks map[int]model.Task
+	// idCounter is the id of the next task, ids of deleted tasks aren't reused
 	idCounter int
-	logger    Logger
-	m         sync.RWMutex
+	// publicIds maps public ids of tasks that aren't deleted to their ids
+	publicIds map[string]int
+
+	projectTags
+	taskIndexes
+
+	// children maps task ids to ids of their subtasks
+	children map[int]map[int]struct{}
+	// blocks maps task ids to ids of the tasks they block, it's the reverse of Task.BlockedBy
+	blocks map[int]map[int]struct{}
+
+	m sync.RWMutex
+}
+
+func newTaskPartition(projectId int) *taskPartition {
+	return &taskPartition{
+		projectId:   projectId,
+		tasks:       make(map[int]model.Task),
+		publicIds:   make(map[string]int),
+		projectTags: newProjectTags(),
+		taskIndexes: newTaskIndexes(),
+		children:    make(map[int]map[int]struct{}),
+		blocks:      make(map[int]map[int]struct{}),
+	}
 }
 
-func NewTaskStorage(logger Logger) (*TaskStorage, error) {
+func NewTaskStorage(logger Logger, opts ...TaskStorageOption) (*TaskStorage, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", storageName)
+	}
+	st := &TaskStorage{
+		partitions: make(map[int]*taskPartition),
+		logger:     logger,
+		clock:      clock.System{},
+	}
+	for _, opt := range opts {
+		opt(st)
+	}
+	if st.clock == nil {
+		return nil, fmt.Errorf("%v: nil clock", storageName)
+	}
+
 	logger.Log("Created %s successfully", storageName)
+	return st, nil
+}
+
+// emptyPartition is returned for reads from projects without tasks, it's never modified
+var emptyPartition = newTaskPartition(0)
 
-	return &TaskStorage{
-		make([]model.Task, 0),
-		0,
-		logger,
-		sync.RWMutex{},
-	}, nil
+// partition returns the partition of the project from the context scope
+func (st *TaskStorage) partition(ctx context.Context) *taskPartition {
+	scope := tenant.FromContext(ctx)
+
+	st.m.RLock()
+	defer st.m.RUnlock()
+	if p, ok := st.partitions[scope.ProjectID]; ok {
+		return p
+	}
+	return emptyPartition
 }
 
-func (st *TaskStorage) Store(ctx context.Context, task model.Task) (int, error) {
+// partitionForWrite returns the partition of the project from the context scope, creating it if needed
+func (st *TaskStorage) partitionForWrite(ctx context.Context) (*taskPartition, tenant.Scope) {
+	scope := tenant.FromContext(ctx)
+	return st.partitionOf(scope.ProjectID), scope
+}
+
+// partitionOf returns the partition of the project, creating it if needed
+func (st *TaskStorage) partitionOf(projectId int) *taskPartition {
+	st.m.RLock()
+	p, ok := st.partitions[projectId]
+	st.m.RUnlock()
+	if ok {
+		return p
+	}
+
 	st.m.Lock()
 	defer st.m.Unlock()
-	task.Id = len(st.tasks)
-	task.CreatedAt = time.Now()
-	st.tasks = append(st.tasks, task)
-	st.idCounter++
+	if p, ok = st.partitions[projectId]; !ok {
+		p = newTaskPartition(projectId)
+		st.partitions[projectId] = p
+	}
+	return p
+}
+
+func (st *TaskStorage) Store(ctx context.Context, task model.Task) (int, error) {
+	p, scope := st.partitionForWrite(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if err := p.checkStore(task, scope); err != nil {
+		return -1, fmt.Errorf("%v: error while storing task in project(%v): %w", storageName, p.projectId, err)
+	}
+	publicId, err := nextPublicId(st.ids)
+	if err != nil {
+		return -1, fmt.Errorf("%v: error while storing task in project(%v): %w", storageName, p.projectId, err)
+	}
+	task.PublicID = publicId
+	task = p.applyStore(task, st.clock.Now())
 
 	st.logger.Log("Stored task: %v sucsessfully", task)
 
 	return task.Id, nil
 }
 
-func (st *TaskStorage) GetAll(ctx context.Context, filter model.Filter) ([]model.Task, error) {
-	st.m.RLock()
-	defer st.m.RUnlock()
-	ans := make([]model.Task, 0)
+// GetAll returns the page of the tasks matching the filter ordered by filter.SortBy, by id if it isn't set,
+// and the amount of every matching task. Only the tasks of the most selective index the filter can use
+// are checked, see plan, and in order of ids only the tasks of the page are copied.
+func (st *TaskStorage) GetAll(ctx context.Context, filter model.Filter) ([]model.Task, int, error) {
+	p := st.partition(ctx)
+	now := st.clock.Now()
+	p.m.RLock()
+	defer p.m.RUnlock()
 
-	for _, task := range st.tasks {
-		if filter.Status == "" || task.Status == filter.Status {
-			ans = append(ans, task)
+	matches := p.matcher(filter, now)
+	ids, indexed := p.plan(filter)
+	n := p.idCounter
+	if indexed {
+		n = len(ids)
+	}
+	pager := newTaskPager(filter, true)
+	for i := range n {
+		pos := i
+		if pager.ordered && filter.Descending {
+			pos = n - 1 - i
+		}
+		id := pos
+		if indexed {
+			id = ids[pos]
+		}
+		if p.exists(id) && matches(p.tasks[id]) {
+			pager.add(p.tasks[id])
 		}
 	}
 
-	return ans, nil
+	tasks, total := pager.page()
+	return tasks, total, nil
+}
+
+// ForEach calls fn for every task matching the filter in order of their ids.
+//
+// Tasks are copied in small batches, so the lock isn't held while fn is running
+// and the whole storage is never copied at once. Sorting of the filter is ignored.
+func (st *TaskStorage) ForEach(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+	p := st.partition(ctx)
+	now := st.clock.Now()
+	// tasks entering the chosen index after the iteration started aren't visited
+	p.m.RLock()
+	ids, indexed := p.plan(filter)
+	matches := p.matcher(filter, now)
+	p.m.RUnlock()
+
+	batch := make([]model.Task, 0, forEachBatchSize)
+	for pos := 0; ; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch = batch[:0]
+		p.m.RLock()
+		limit := p.idCounter
+		if indexed {
+			limit = len(ids)
+		}
+		for ; pos < limit && len(batch) < forEachBatchSize; pos++ {
+			id := pos
+			if indexed {
+				id = ids[pos]
+			}
+			if p.exists(id) && matches(p.tasks[id]) {
+				batch = append(batch, p.tasks[id])
+			}
+		}
+		done := pos >= limit
+		p.m.RUnlock()
+
+		for _, task := range batch {
+			if err := fn(task); err != nil {
+				return err
+			}
+		}
+		if done {
+			return nil
+		}
+	}
 }
 
 func (st *TaskStorage) GetByTaskId(ctx context.Context, TaskId int) (*model.Task, error) {
-	st.m.RLock()
-	defer st.m.RUnlock()
-	if TaskId > st.idCounter || TaskId < 0 {
-		return nil, fmt.Errorf("%v: error while retrieving task by id(%v): nonexistent id", storageName, TaskId)
+	p := st.partition(ctx)
+	p.m.RLock()
+	defer p.m.RUnlock()
+	if !p.exists(TaskId) {
+		return nil, fmt.Errorf("%v: error while retrieving task by id(%v): %w", storageName, TaskId, model.ErrNotFound)
+	}
+	ans := p.tasks[TaskId]
+
+	return &ans, nil
+}
+
+// GetByPublicId returns the task of the project from the context scope with the public id
+func (st *TaskStorage) GetByPublicId(ctx context.Context, publicId string) (*model.Task, error) {
+	p := st.partition(ctx)
+	p.m.RLock()
+	defer p.m.RUnlock()
+	id, ok := p.publicIds[publicId]
+	if !ok || publicId == "" {
+		return nil, fmt.Errorf("%v: error while retrieving task by public id(%q): %w", storageName, publicId, model.ErrNotFound)
 	}
-	ans := st.tasks[TaskId]
+	ans := p.tasks[id]
 
 	return &ans, nil
 }
+
+// Update replaces mutable fields of an existing task, id, project and creation time are kept,
+// start and completion times follow the status transition.
+//
+// Changing the parent is rejected with model.ErrCycle if the task would become its own ancestor.
+func (st *TaskStorage) Update(ctx context.Context, task model.Task) (*model.Task, error) {
+	p := st.partition(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if err := p.checkUpdate(task, st.strictCompletion); err != nil {
+		return nil, fmt.Errorf("%v: error while updating task by id(%v): %w", storageName, task.Id, err)
+	}
+	task = p.applyUpdate(task, st.clock.Now())
+
+	st.logger.Log("Updated task: %v sucsessfully", task)
+
+	return &task, nil
+}
+
+func (st *TaskStorage) Delete(ctx context.Context, taskId int) error {
+	p := st.partition(ctx)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if !p.exists(taskId) {
+		return fmt.Errorf("%v: error while deleting task by id(%v): %w", storageName, taskId, model.ErrNotFound)
+	}
+	p.applyDelete(taskId)
+
+	st.logger.Log("Deleted task: %v of project %v sucsessfully", taskId, p.projectId)
+
+	return nil
+}
+
+// Mutations of a partition are split into checks and applies, so EventTaskStorage can check
+// a command, record it as an event and fold the event with the same code TaskStorage uses.
+// Applies never fail and take the time of the change instead of reading the clock.
+
+// checkStore checks the quota of the project and the parent of a new task, must be called under lock
+func (p *taskPartition) checkStore(task model.Task, scope tenant.Scope) error {
+	if scope.TaskQuota != model.NoQuota && p.count() >= scope.TaskQuota {
+		return model.ErrQuotaExceeded
+	}
+	if task.ParentID != nil && !p.exists(*task.ParentID) {
+		return model.Invalid(fmt.Errorf("parent task(%v) doesn't exist", *task.ParentID))
+	}
+	return nil
+}
+
+// applyStore stores the task with the next id, the public id is kept, must be called under lock
+func (p *taskPartition) applyStore(task model.Task, now time.Time) model.Task {
+	task.Id = p.idCounter
+	task = newTask(task, p.projectId, now)
+	task.PublicParentID = publicIdOf(p, task.ParentID)
+	p.tasks[task.Id] = task
+	p.idCounter++
+	p.index(task)
+	if task.PublicID != "" {
+		p.publicIds[task.PublicID] = task.Id
+	}
+	link(p.children, task.ParentID, task.Id)
+
+	return task
+}
+
+// checkUpdate checks that the task exists, its new parent doesn't form a cycle
+// and, if strict, that it isn't finished too early, must be called under lock
+func (p *taskPartition) checkUpdate(task model.Task, strict bool) error {
+	if !p.exists(task.Id) {
+		return model.ErrNotFound
+	}
+	stored := p.tasks[task.Id]
+	if !sameId(stored.ParentID, task.ParentID) {
+		if err := checkParent(p, task.Id, task.ParentID); err != nil {
+			return err
+		}
+	}
+	if strict && task.Status == model.Done && stored.Status != model.Done && incomplete(p, task.Id) {
+		return model.ErrIncomplete
+	}
+	return nil
+}
+
+// applyUpdate replaces mutable fields of the task, must be called under lock
+func (p *taskPartition) applyUpdate(task model.Task, now time.Time) model.Task {
+	stored := p.tasks[task.Id]
+	task = updatedTask(stored, task, now)
+	if !sameId(stored.ParentID, task.ParentID) {
+		task.PublicParentID = publicIdOf(p, task.ParentID)
+	}
+	p.tasks[task.Id] = task
+	p.reindex(stored, task)
+	unlink(p.children, stored.ParentID, task.Id)
+	link(p.children, task.ParentID, task.Id)
+
+	return task
+}
+
+// applyDelete removes the task with its place in the indexes and graphs, must be called under lock
+func (p *taskPartition) applyDelete(taskId int) {
+	for _, tagId := range p.tasks[taskId].TagIDs {
+		delete(p.tagIndex[tagId], taskId)
+	}
+	p.detachFromGraph(taskId)
+	p.unindex(p.tasks[taskId])
+	delete(p.publicIds, p.tasks[taskId].PublicID)
+	delete(p.tasks, taskId)
+}
+
+// newTask is the task as it's stored in the project, fields managed by the storage are reset
+func newTask(task model.Task, projectId int, now time.Time) model.Task {
+	task.ProjectID = projectId
+	task.CreatedAt = now
+	task.StartedAt, task.CompletedAt = time.Time{}, time.Time{}
+	task.TagIDs, task.BlockedBy, task.PublicBlockedBy = nil, nil, nil
+	task.PublicParentID = ""
+	task.ParentID = cloneId(task.ParentID)
+	task.Recurrence = cloneRecurrence(task.Recurrence)
+	model.ApplyStatusTransition(&task, "", now)
+	return task
+}
+
+// updatedTask is the stored task with mutable fields of the task, fields managed by the storage are kept.
+// The public id of the parent is kept too, callers changing the parent look the new one up
+func updatedTask(stored, task model.Task, now time.Time) model.Task {
+	task.PublicID, task.PublicParentID = stored.PublicID, stored.PublicParentID
+	task.ParentID = cloneId(task.ParentID)
+	task.Recurrence = cloneRecurrence(task.Recurrence)
+	task.ProjectID = stored.ProjectID
+	task.CreatedAt = stored.CreatedAt
+	task.StartedAt, task.CompletedAt = stored.StartedAt, stored.CompletedAt
+	task.TagIDs, task.BlockedBy, task.PublicBlockedBy = stored.TagIDs, stored.BlockedBy, stored.PublicBlockedBy
+	model.ApplyStatusTransition(&task, stored.Status, now)
+	return task
+}
+
+// nextPublicId returns the next id of the generator, an empty one without it
+func nextPublicId(ids IDGenerator) (string, error) {
+	if ids == nil {
+		return "", nil
+	}
+	publicId, err := ids.NextID()
+	if err != nil {
+		return "", fmt.Errorf("couldn't generate public id: %w", err)
+	}
+	return publicId, nil
+}
+
+// exists reports whether the task was stored and not deleted, must be called under lock
+func (p *taskPartition) exists(taskId int) bool {
+	_, ok := p.tasks[taskId]
+	return ok
+}
+
+// count returns an amount of tasks that aren't deleted, must be called under lock
+func (p *taskPartition) count() int {
+	return len(p.tasks)
+}
+
+// taskPager collects the page of the filter from the matching tasks and counts them
+type taskPager struct {
+	filter model.Filter
+	// ordered is true when tasks are added in the order of the filter, then only the tasks of the page are kept,
+	// otherwise every task is kept to be sorted
+	ordered bool
+	tasks   []model.Task
+	total   int
+}
+
+// newTaskPager creates a pager of the filter, byId tells whether tasks are added in order of their ids,
+// backwards when the filter is descending
+func newTaskPager(filter model.Filter, byId bool) *taskPager {
+	ordered := byId && (filter.SortBy == "" || filter.SortBy == model.SortById)
+	return &taskPager{filter: filter, ordered: ordered, tasks: make([]model.Task, 0)}
+}
+
+func (pg *taskPager) add(task model.Task) {
+	page := pg.filter.Page
+	if !pg.ordered || !pg.filter.Paged() || pg.total >= page.Offset && len(pg.tasks) < page.Limit {
+		pg.tasks = append(pg.tasks, task)
+	}
+	pg.total++
+}
+
+// page returns the tasks of the page and the amount of added tasks
+func (pg *taskPager) page() ([]model.Task, int) {
+	if pg.ordered {
+		return pg.tasks, pg.total
+	}
+	sortTasks(pg.tasks, pg.filter.SortBy, pg.filter.Descending)
+	if !pg.filter.Paged() {
+		return pg.tasks, pg.total
+	}
+	start := min(pg.filter.Page.Offset, len(pg.tasks))
+	end := min(start+pg.filter.Page.Limit, len(pg.tasks))
+	// the page is copied, so the rest of the tasks isn't kept in memory with it
+	return slices.Clone(pg.tasks[start:end]), pg.total
+}
+
+// sortTasks orders tasks by the field, ties and tasks without the field are ordered by id,
+// tasks missing the sorted timestamp are put at the end in both directions
+func sortTasks(tasks []model.Task, field model.SortField, descending bool) {
+	if field == "" || field == model.SortById {
+		if descending {
+			slices.Reverse(tasks)
+		}
+		return
+	}
+
+	slices.SortStableFunc(tasks, func(a, b model.Task) int {
+		var c int
+		if field == model.SortByPriority {
+			c = cmp.Compare(a.Priority.Rank(), b.Priority.Rank())
+		} else {
+			ta, tb := sortTime(a, field), sortTime(b, field)
+			switch {
+			case ta.IsZero() && tb.IsZero():
+				return cmp.Compare(a.Id, b.Id)
+			case ta.IsZero():
+				return 1
+			case tb.IsZero():
+				return -1
+			}
+			c = ta.Compare(tb)
+		}
+		if descending {
+			c = -c
+		}
+		if c == 0 {
+			return cmp.Compare(a.Id, b.Id)
+		}
+		return c
+	})
+}
+
+func sortTime(task model.Task, field model.SortField) time.Time {
+	switch field {
+	case model.SortByDueAt:
+		return task.DueAt
+	case model.SortByStartedAt:
+		return task.StartedAt
+	case model.SortByCompletedAt:
+		return task.CompletedAt
+	}
+	return task.CreatedAt
+}
+
+func cloneRecurrence(r *model.Recurrence) *model.Recurrence {
+	if r == nil {
+		return nil
+	}
+	v := *r
+	return &v
+}
+
+func sameRecurrence(a, b *model.Recurrence) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Rule == b.Rule && a.TimeZone == b.TimeZone && a.Start.Equal(b.Start)
+}
@@ -2,9 +2,16 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/idgen"
 	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // MockLogger is a mock implementation of the Logger interface for testing
@@ -59,11 +66,11 @@ func TestStore(t *testing.T) {
 			t.Errorf("Expected ID 0 for first task, got %d", id)
 		}
 
-		if len(storage.tasks) != 1 {
-			t.Fatalf("Expected 1 task in storage, got %d", len(storage.tasks))
+		if len(storage.partition(ctx).tasks) != 1 {
+			t.Fatalf("Expected 1 task in storage, got %d", len(storage.partition(ctx).tasks))
 		}
 
-		storedTask := storage.tasks[0]
+		storedTask := storage.partition(ctx).tasks[0]
 		if storedTask.Name != task.Name || storedTask.Description != task.Description || storedTask.Status != task.Status {
 			t.Error("Stored task doesn't match input task")
 		}
@@ -107,8 +114,8 @@ func TestStore(t *testing.T) {
 			uniqueIDs[id] = true
 		}
 
-		if len(storage.tasks) != tasksToAdd {
-			t.Errorf("Expected %d tasks in storage, got %d", tasksToAdd, len(storage.tasks))
+		if len(storage.partition(ctx).tasks) != tasksToAdd {
+			t.Errorf("Expected %d tasks in storage, got %d", tasksToAdd, len(storage.partition(ctx).tasks))
 		}
 	})
 }
@@ -149,6 +156,12 @@ func TestGetByTaskId(t *testing.T) {
 			wantTask: nil,
 			wantErr:  fmt.Errorf("%v: error while retrieving task by id(99): nonexistent id", storageName),
 		},
+		{
+			name:     "id right after the last one",
+			taskId:   3,
+			wantTask: nil,
+			wantErr:  fmt.Errorf("%v: error while retrieving task by id(3): nonexistent id", storageName),
+		},
 		{
 			name:     "negative id",
 			taskId:   -1,
@@ -186,10 +199,10 @@ func TestGetAll(t *testing.T) {
 	ctx := context.Background()
 
 	tasks := []model.Task{
-		{Name: "Task 1", Description: "Desc 1", Status: model.Created},
+		{Name: "Task 1", Description: "Desc 1", Status: model.Created, AssigneeID: 1},
 		{Name: "Task 2", Description: "Desc 2", Status: model.InProgress},
-		{Name: "Task 3", Description: "Desc 3", Status: model.Done},
-		{Name: "Task 4", Description: "Desc 4", Status: model.Created},
+		{Name: "Task 3", Description: "Desc 3", Status: model.Done, AssigneeID: 1},
+		{Name: "Task 4", Description: "Desc 4", Status: model.Created, AssigneeID: 2},
 	}
 
 	for i := range tasks {
@@ -229,11 +242,21 @@ func TestGetAll(t *testing.T) {
 			filter:  model.Filter{Status: "non-existent"},
 			wantLen: 0,
 		},
+		{
+			name:    "filter by assignee",
+			filter:  model.Filter{AssigneeID: 1},
+			wantLen: 2,
+		},
+		{
+			name:    "filter by status and assignee",
+			filter:  model.Filter{Status: model.Created, AssigneeID: 1},
+			wantLen: 1,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotTasks, err := storage.GetAll(ctx, tt.filter)
+			gotTasks, _, err := storage.GetAll(ctx, tt.filter)
 			if err != nil {
 				t.Fatalf("Unexpected error: %v", err)
 			}
@@ -250,3 +273,438 @@ func TestGetAll(t *testing.T) {
 		})
 	}
 }
+
+func TestForEach(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, _ := NewTaskStorage(mockLogger)
+	ctx := context.Background()
+
+	total := forEachBatchSize*2 + 10
+	for i := range total {
+		status := model.Created
+		if i%2 == 0 {
+			status = model.Done
+		}
+		if _, err := storage.Store(ctx, model.Task{Name: fmt.Sprintf("Task %d", i), Status: status}); err != nil {
+			t.Fatalf("Failed to setup test: %v", err)
+		}
+	}
+
+	t.Run("all tasks in order", func(t *testing.T) {
+		next := 0
+		err := storage.ForEach(ctx, model.EmptyFilter, func(task model.Task) error {
+			if task.Id != next {
+				t.Fatalf("Expected task %d, got %d", next, task.Id)
+			}
+			next++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if next != total {
+			t.Errorf("Expected %d tasks, got %d", total, next)
+		}
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		count := 0
+		err := storage.ForEach(ctx, model.Filter{Status: model.Done}, func(task model.Task) error {
+			if task.Status != model.Done {
+				t.Errorf("Expected status %q, got %q", model.Done, task.Status)
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if count != total/2 {
+			t.Errorf("Expected %d tasks, got %d", total/2, count)
+		}
+	})
+
+	t.Run("stops on callback error", func(t *testing.T) {
+		stop := fmt.Errorf("stop")
+		count := 0
+		err := storage.ForEach(ctx, model.EmptyFilter, func(task model.Task) error {
+			count++
+			if count == 3 {
+				return stop
+			}
+			return nil
+		})
+		if err != stop {
+			t.Errorf("Expected stop error, got %v", err)
+		}
+		if count != 3 {
+			t.Errorf("Expected 3 calls, got %d", count)
+		}
+	})
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, _ := NewTaskStorage(mockLogger)
+	ctx := context.Background()
+
+	for i := range 3 {
+		storage.Store(ctx, model.Task{Name: fmt.Sprintf("Task %d", i), Status: model.Created})
+	}
+
+	t.Run("update", func(t *testing.T) {
+		original, _ := storage.GetByTaskId(ctx, 1)
+		updated, err := storage.Update(ctx, model.Task{Id: 1, Name: "Renamed", Status: model.Done})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if updated.Name != "Renamed" || updated.Status != model.Done || !updated.CreatedAt.Equal(original.CreatedAt) {
+			t.Errorf("Unexpected updated task %v", updated)
+		}
+
+		if _, err := storage.Update(ctx, model.Task{Id: 5}); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := storage.Delete(ctx, 0); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := storage.Delete(ctx, 0); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound on second delete, got %v", err)
+		}
+		if _, err := storage.GetByTaskId(ctx, 0); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if _, err := storage.Update(ctx, model.Task{Id: 0, Status: model.Done}); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound on update of deleted task, got %v", err)
+		}
+
+		tasks, _, _ := storage.GetAll(ctx, model.EmptyFilter)
+		if len(tasks) != 2 {
+			t.Errorf("Expected 2 tasks left, got %d", len(tasks))
+		}
+
+		id, _ := storage.Store(ctx, model.Task{Name: "New", Status: model.Created})
+		if id != 3 {
+			t.Errorf("Expected ids not to be reused, got %d", id)
+		}
+	})
+}
+
+func TestTenantIsolation(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, _ := NewTaskStorage(mockLogger)
+	projectA := tenant.WithScope(context.Background(), tenant.Scope{ProjectID: 2})
+	projectB := tenant.WithScope(context.Background(), tenant.Scope{ProjectID: 3})
+
+	idA, _ := storage.Store(projectA, model.Task{Name: "secret of A", Status: model.Created})
+	storage.Store(projectA, model.Task{Name: "second of A", Status: model.Done})
+	idB, _ := storage.Store(projectB, model.Task{Name: "task of B", Status: model.Created})
+
+	if idA != 0 || idB != 0 {
+		t.Fatalf("Expected per project id sequences to start from 0, got %d and %d", idA, idB)
+	}
+
+	t.Run("get by id resolves inside the project", func(t *testing.T) {
+		task, err := storage.GetByTaskId(projectB, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if task.Name != "task of B" || task.ProjectID != 3 {
+			t.Errorf("Expected task of project B, got %v", task)
+		}
+
+		if _, err := storage.GetByTaskId(projectB, 1); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected second task of A to be invisible from B, got %v", err)
+		}
+	})
+
+	t.Run("listing and iteration see only the project", func(t *testing.T) {
+		tasks, _, _ := storage.GetAll(projectB, model.EmptyFilter)
+		if len(tasks) != 1 || tasks[0].ProjectID != 3 {
+			t.Errorf("Expected only the task of project B, got %v", tasks)
+		}
+
+		storage.ForEach(projectB, model.EmptyFilter, func(task model.Task) error {
+			if task.ProjectID != 3 {
+				t.Errorf("Iterated over task of project %d", task.ProjectID)
+			}
+			return nil
+		})
+
+		tasks, _, _ = storage.GetAll(context.Background(), model.EmptyFilter)
+		if len(tasks) != 0 {
+			t.Errorf("Expected default project to be empty, got %v", tasks)
+		}
+	})
+
+	t.Run("writes can't reach another project", func(t *testing.T) {
+		if _, err := storage.Update(projectB, model.Task{Id: 1, Name: "hijacked", Status: model.Done}); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		updated, err := storage.Update(projectB, model.Task{Id: 0, Name: "moved?", Status: model.Done, ProjectID: 2})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if updated.ProjectID != 3 {
+			t.Errorf("Expected task to stay in project B, got project %d", updated.ProjectID)
+		}
+		if err := storage.Delete(projectB, 1); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		task, _ := storage.GetByTaskId(projectA, 0)
+		if task.Name != "secret of A" {
+			t.Errorf("Task of project A was modified: %v", task)
+		}
+	})
+}
+
+func TestTaskQuota(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, _ := NewTaskStorage(mockLogger)
+	ctx := tenant.WithScope(context.Background(), tenant.Scope{ProjectID: 2, TaskQuota: 2})
+
+	for i := range 2 {
+		if _, err := storage.Store(ctx, model.Task{Name: fmt.Sprintf("Task %d", i), Status: model.Created}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := storage.Store(ctx, model.Task{Name: "Task 3", Status: model.Created}); !errors.Is(err, model.ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	other := tenant.WithScope(context.Background(), tenant.Scope{ProjectID: 3, TaskQuota: 2})
+	if _, err := storage.Store(other, model.Task{Name: "Task", Status: model.Created}); err != nil {
+		t.Errorf("Expected quota to be counted per project, got %v", err)
+	}
+
+	storage.Delete(ctx, 0)
+	if _, err := storage.Store(ctx, model.Task{Name: "Task 3", Status: model.Created}); err != nil {
+		t.Errorf("Expected deleted task to free the quota, got %v", err)
+	}
+}
+
+func TestStatusTimestamps(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, _ := NewTaskStorage(mockLogger)
+	ctx := context.Background()
+
+	id, _ := storage.Store(ctx, model.Task{Name: "Task", Status: model.Created, StartedAt: time.Now()})
+	task, _ := storage.GetByTaskId(ctx, id)
+	if !task.StartedAt.IsZero() || !task.CompletedAt.IsZero() {
+		t.Fatalf("Expected client timestamps to be ignored, got %v", task)
+	}
+
+	task.Status = model.InProgress
+	started, _ := storage.Update(ctx, *task)
+	if started.StartedAt.IsZero() || !started.CompletedAt.IsZero() {
+		t.Fatalf("Expected StartedAt to be set, got %v", started)
+	}
+
+	startedAt := started.StartedAt
+	started.Status = model.Done
+	started.StartedAt = time.Time{}
+	done, _ := storage.Update(ctx, *started)
+	if !done.StartedAt.Equal(startedAt) {
+		t.Errorf("Expected StartedAt to be kept, got %v", done)
+	}
+	if done.CompletedAt.IsZero() {
+		t.Errorf("Expected CompletedAt to be set, got %v", done)
+	}
+
+	doneId, _ := storage.Store(ctx, model.Task{Name: "Done at once", Status: model.Done})
+	doneAtOnce, _ := storage.GetByTaskId(ctx, doneId)
+	if doneAtOnce.StartedAt.IsZero() || doneAtOnce.CompletedAt.IsZero() {
+		t.Errorf("Expected both timestamps for task created as done, got %v", doneAtOnce)
+	}
+}
+
+// failingIds is an IDGenerator that always fails
+type failingIds struct{}
+
+func (failingIds) NextID() (string, error) {
+	return "", errors.New("no entropy")
+}
+
+func TestClockAndPublicIds(t *testing.T) {
+	start := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC)
+	c := clock.NewManual(start)
+	storage, _ := NewTaskStorage(&MockLogger{}, WithClock(c), WithIDGenerator(idgen.NewSequential(100)))
+	ctx := context.Background()
+	other := tenant.WithScope(ctx, tenant.Scope{ProjectID: 2})
+
+	id, _ := storage.Store(ctx, model.Task{Name: "first", Status: model.Created, PublicID: "chosen by client"})
+	otherId, _ := storage.Store(other, model.Task{Name: "other", Status: model.Created})
+	task, _ := storage.GetByTaskId(ctx, id)
+	if task.PublicID != "100" || !task.CreatedAt.Equal(start) {
+		t.Fatalf("Expected public id 100 created at %v, got %+v", start, task)
+	}
+	if otherTask, _ := storage.GetByTaskId(other, otherId); otherId != id || otherTask.PublicID != "101" {
+		t.Errorf("Expected overlapping ids with unique public ids, got %+v", otherTask)
+	}
+
+	t.Run("by public id", func(t *testing.T) {
+		got, err := storage.GetByPublicId(ctx, "100")
+		if err != nil || got.Id != id {
+			t.Errorf("Expected task %v, got %+v, %v", id, got, err)
+		}
+		if _, err := storage.GetByPublicId(ctx, "101"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a task of another project, got %v", err)
+		}
+		if _, err := storage.GetByPublicId(ctx, ""); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for an empty public id, got %v", err)
+		}
+	})
+
+	t.Run("update keeps public id and uses the clock", func(t *testing.T) {
+		startedAt := c.Advance(time.Hour)
+		task.Status, task.PublicID = model.InProgress, "changed"
+		updated, _ := storage.Update(ctx, *task)
+		if updated.PublicID != "100" || !updated.StartedAt.Equal(startedAt) || !updated.CreatedAt.Equal(start) {
+			t.Errorf("Unexpected task %+v", updated)
+		}
+	})
+
+	t.Run("overdue is checked with the clock", func(t *testing.T) {
+		storage.Update(ctx, model.Task{Id: id, Name: "first", Status: model.InProgress, DueAt: start.Add(2 * time.Hour)})
+		if tasks, _, _ := storage.GetAll(ctx, model.Filter{Overdue: true}); len(tasks) != 0 {
+			t.Errorf("Expected no overdue tasks, got %v", tasks)
+		}
+		c.Advance(2 * time.Hour)
+		if tasks, _, _ := storage.GetAll(ctx, model.Filter{Overdue: true}); len(tasks) != 1 {
+			t.Errorf("Expected an overdue task, got %v", tasks)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		storage.Delete(ctx, id)
+		if _, err := storage.GetByPublicId(ctx, "100"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("public ids of parents and blockers", func(t *testing.T) {
+		storage, _ := NewTaskStorage(&MockLogger{}, WithIDGenerator(idgen.NewSequential(1)))
+		parentId, _ := storage.Store(ctx, model.Task{Name: "parent", Status: model.Created})
+		blockerId, _ := storage.Store(ctx, model.Task{Name: "blocker", Status: model.Created})
+		childId, _ := storage.Store(ctx, model.Task{Name: "child", Status: model.Created, ParentID: &parentId})
+		storage.AddBlocker(ctx, childId, blockerId)
+		child, _ := storage.GetByTaskId(ctx, childId)
+		if child.PublicParentID != "1" || !reflect.DeepEqual(child.PublicBlockedBy, []string{"2"}) {
+			t.Fatalf("Expected parent 1 and blocker 2, got %q and %v", child.PublicParentID, child.PublicBlockedBy)
+		}
+
+		child.ParentID = &blockerId
+		if updated, _ := storage.Update(ctx, *child); updated.PublicParentID != "2" {
+			t.Errorf("Expected new parent 2, got %q", updated.PublicParentID)
+		}
+		storage.Delete(ctx, blockerId)
+		if child, _ := storage.GetByTaskId(ctx, childId); child.PublicParentID != "" || child.PublicBlockedBy != nil {
+			t.Errorf("Expected no public ids of the deleted task, got %q and %v", child.PublicParentID, child.PublicBlockedBy)
+		}
+	})
+
+	t.Run("without generator", func(t *testing.T) {
+		storage, _ := NewTaskStorage(&MockLogger{})
+		id, _ := storage.Store(ctx, model.Task{Name: "plain", Status: model.Created, PublicID: "chosen by client"})
+		if task, _ := storage.GetByTaskId(ctx, id); task.PublicID != "" {
+			t.Errorf("Expected no public id, got %q", task.PublicID)
+		}
+	})
+
+	t.Run("generator fails", func(t *testing.T) {
+		storage, _ := NewTaskStorage(&MockLogger{}, WithIDGenerator(failingIds{}))
+		if _, err := storage.Store(ctx, model.Task{Name: "task", Status: model.Created}); err == nil {
+			t.Error("Expected error from the generator")
+		}
+		if tasks, _, _ := storage.GetAll(ctx, model.Filter{}); len(tasks) != 0 {
+			t.Errorf("Expected nothing stored, got %v", tasks)
+		}
+	})
+
+	if _, err := NewTaskStorage(&MockLogger{}, WithClock(nil)); err == nil {
+		t.Error("Expected error for a nil clock")
+	}
+}
+
+func TestFilterAndSort(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, _ := NewTaskStorage(mockLogger)
+	ctx := context.Background()
+	now := time.Now()
+
+	tasks := []model.Task{
+		{Name: "overdue", Status: model.InProgress, Priority: model.PriorityHigh, DueAt: now.Add(-time.Hour)},
+		{Name: "late but done", Status: model.Done, Priority: model.PriorityLow, DueAt: now.Add(-2 * time.Hour)},
+		{Name: "tomorrow", Status: model.Created, Priority: model.PriorityUrgent, DueAt: now.Add(24 * time.Hour)},
+		{Name: "no due date", Status: model.Created, Priority: model.PriorityMedium},
+	}
+	for _, task := range tasks {
+		storage.Store(ctx, task)
+	}
+
+	names := func(tasks []model.Task) []string {
+		ans := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			ans = append(ans, task.Name)
+		}
+		return ans
+	}
+
+	tests := []struct {
+		name     string
+		filter   model.Filter
+		expected []string
+	}{
+		{
+			name:     "overdue",
+			filter:   model.Filter{Overdue: true},
+			expected: []string{"overdue"},
+		},
+		{
+			name:     "priority",
+			filter:   model.Filter{Priority: model.PriorityUrgent},
+			expected: []string{"tomorrow"},
+		},
+		{
+			name:     "due range",
+			filter:   model.Filter{DueAfter: now.Add(-90 * time.Minute), DueBefore: now.Add(48 * time.Hour)},
+			expected: []string{"overdue", "tomorrow"},
+		},
+		{
+			name:     "sort by due date keeps tasks without it last",
+			filter:   model.Filter{SortBy: model.SortByDueAt},
+			expected: []string{"late but done", "overdue", "tomorrow", "no due date"},
+		},
+		{
+			name:     "sort by due date descending",
+			filter:   model.Filter{SortBy: model.SortByDueAt, Descending: true},
+			expected: []string{"tomorrow", "overdue", "late but done", "no due date"},
+		},
+		{
+			name:     "sort by priority descending",
+			filter:   model.Filter{SortBy: model.SortByPriority, Descending: true},
+			expected: []string{"tomorrow", "overdue", "no due date", "late but done"},
+		},
+		{
+			name:     "sort by id descending",
+			filter:   model.Filter{Descending: true},
+			expected: []string{"no due date", "tomorrow", "late but done", "overdue"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := storage.GetAll(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(names(got), ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, names(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const userStorageName = "UserStorage"
+
+type UserStorage struct {
+	users     map[int]model.User
+	idCounter int
+	logger    Logger
+	clock     Clock
+	m         sync.RWMutex
+}
+
+// UserStorageOption configures optional behaviour of UserStorage
+type UserStorageOption func(*UserStorage)
+
+// WithUserClock replaces the system clock users are timestamped with
+func WithUserClock(clock Clock) UserStorageOption {
+	return func(us *UserStorage) {
+		us.clock = clock
+	}
+}
+
+func NewUserStorage(logger Logger, opts ...UserStorageOption) (*UserStorage, error) {
+	us := &UserStorage{
+		users:  make(map[int]model.User),
+		logger: logger,
+		clock:  clock.System{},
+	}
+	for _, opt := range opts {
+		opt(us)
+	}
+	logger.Log("Created %s successfully", userStorageName)
+
+	return us, nil
+}
+
+// Store saves a new user, ids are assigned starting from 1
+func (us *UserStorage) Store(ctx context.Context, user model.User) (int, error) {
+	us.m.Lock()
+	defer us.m.Unlock()
+	if us.emailTaken(user.Email, model.NoUser) {
+		return -1, fmt.Errorf("%v: error while storing user: email %v: %w", userStorageName, user.Email, model.ErrAlreadyExists)
+	}
+	us.idCounter++
+	user.Id = us.idCounter
+	user.CreatedAt = us.clock.Now()
+	us.users[user.Id] = user
+
+	us.logger.Log("Stored user: %v sucsessfully", user)
+
+	return user.Id, nil
+}
+
+func (us *UserStorage) GetAll(ctx context.Context) ([]model.User, error) {
+	us.m.RLock()
+	defer us.m.RUnlock()
+	ans := make([]model.User, 0, len(us.users))
+	for _, user := range us.users {
+		ans = append(ans, user)
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Id < ans[j].Id })
+
+	return ans, nil
+}
+
+func (us *UserStorage) GetByUserId(ctx context.Context, userId int) (*model.User, error) {
+	us.m.RLock()
+	defer us.m.RUnlock()
+	user, ok := us.users[userId]
+	if !ok {
+		return nil, fmt.Errorf("%v: error while retrieving user by id(%v): %w", userStorageName, userId, model.ErrNotFound)
+	}
+
+	return &user, nil
+}
+
+// Update replaces name and email of an existing user
+func (us *UserStorage) Update(ctx context.Context, user model.User) (*model.User, error) {
+	us.m.Lock()
+	defer us.m.Unlock()
+	stored, ok := us.users[user.Id]
+	if !ok {
+		return nil, fmt.Errorf("%v: error while updating user by id(%v): %w", userStorageName, user.Id, model.ErrNotFound)
+	}
+	if us.emailTaken(user.Email, user.Id) {
+		return nil, fmt.Errorf("%v: error while updating user: email %v: %w", userStorageName, user.Email, model.ErrAlreadyExists)
+	}
+	stored.Name = user.Name
+	stored.Email = user.Email
+	us.users[user.Id] = stored
+
+	return &stored, nil
+}
+
+func (us *UserStorage) Delete(ctx context.Context, userId int) error {
+	us.m.Lock()
+	defer us.m.Unlock()
+	if _, ok := us.users[userId]; !ok {
+		return fmt.Errorf("%v: error while deleting user by id(%v): %w", userStorageName, userId, model.ErrNotFound)
+	}
+	delete(us.users, userId)
+
+	return nil
+}
+
+// emailTaken reports whether any user except the given one has the email, must be called under lock
+func (us *UserStorage) emailTaken(email string, exceptId int) bool {
+	for _, user := range us.users {
+		if user.Id != exceptId && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"testing"
+)
+
+func TestUserStorage(t *testing.T) {
+	mockLogger := &MockLogger{}
+	storage, err := NewUserStorage(mockLogger)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	ctx := context.Background()
+
+	firstId, err := storage.Store(ctx, model.User{Name: "John", Email: "john@example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if firstId != 1 {
+		t.Errorf("Expected first user id to be 1, got %d", firstId)
+	}
+	secondId, _ := storage.Store(ctx, model.User{Name: "Jane", Email: "jane@example.com"})
+
+	t.Run("duplicate email", func(t *testing.T) {
+		_, err := storage.Store(ctx, model.User{Name: "Johnny", Email: "JOHN@example.com"})
+		if !errors.Is(err, model.ErrAlreadyExists) {
+			t.Errorf("Expected ErrAlreadyExists, got %v", err)
+		}
+	})
+
+	t.Run("get by id", func(t *testing.T) {
+		user, err := storage.GetByUserId(ctx, firstId)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if user.Name != "John" || user.CreatedAt.IsZero() {
+			t.Errorf("Unexpected user %v", user)
+		}
+
+		if _, err := storage.GetByUserId(ctx, 99); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		updated, err := storage.Update(ctx, model.User{Id: firstId, Name: "John Doe", Email: "john@example.com"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if updated.Name != "John Doe" || updated.CreatedAt.IsZero() {
+			t.Errorf("Unexpected user %v", updated)
+		}
+
+		_, err = storage.Update(ctx, model.User{Id: firstId, Name: "John", Email: "jane@example.com"})
+		if !errors.Is(err, model.ErrAlreadyExists) {
+			t.Errorf("Expected ErrAlreadyExists, got %v", err)
+		}
+
+		_, err = storage.Update(ctx, model.User{Id: 99, Name: "Nobody", Email: "nobody@example.com"})
+		if !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := storage.Delete(ctx, secondId); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := storage.Delete(ctx, secondId); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		users, _ := storage.GetAll(ctx)
+		if len(users) != 1 || users[0].Id != firstId {
+			t.Errorf("Expected only user %d to be left, got %v", firstId, users)
+		}
+	})
+}
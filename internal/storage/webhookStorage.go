@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"slices"
+	"sync"
+)
+
+const webhookStorageName = "WebhookStorage"
+
+// maxDeliveryLog is an amount of the latest delivery attempts kept per webhook
+const maxDeliveryLog = 200
+
+// WebhookStorage keeps webhooks of every project with their delivery logs and dead letters.
+// Webhook ids are unique across projects, but a webhook is reachable only from its project.
+// Deliveries and dead letters are written by the delivery workers that act outside of requests,
+// so they are addressed by the webhook id alone.
+type WebhookStorage struct {
+	webhooks   map[int]model.Webhook
+	idCounter  int
+	deliveries map[int][]model.WebhookDelivery
+	// deliveryCounter numbers attempts of every webhook
+	deliveryCounter int
+	deadLetters     map[int][]model.DeadLetter
+	logger          Logger
+	clock           Clock
+	m               sync.RWMutex
+}
+
+// WebhookStorageOption configures optional behaviour of WebhookStorage
+type WebhookStorageOption func(*WebhookStorage)
+
+// WithWebhookClock replaces the system clock webhooks are timestamped with
+func WithWebhookClock(clock Clock) WebhookStorageOption {
+	return func(ws *WebhookStorage) {
+		ws.clock = clock
+	}
+}
+
+func NewWebhookStorage(logger Logger, opts ...WebhookStorageOption) (*WebhookStorage, error) {
+	ws := &WebhookStorage{
+		webhooks:    make(map[int]model.Webhook),
+		deliveries:  make(map[int][]model.WebhookDelivery),
+		deadLetters: make(map[int][]model.DeadLetter),
+		logger:      logger,
+		clock:       clock.System{},
+	}
+	for _, opt := range opts {
+		opt(ws)
+	}
+	logger.Log("Created %s successfully", webhookStorageName)
+
+	return ws, nil
+}
+
+func (ws *WebhookStorage) Store(ctx context.Context, webhook model.Webhook) (int, error) {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+	ws.idCounter++
+	webhook.Id = ws.idCounter
+	webhook.ProjectID = tenant.FromContext(ctx).ProjectID
+	webhook.CreatedAt = ws.clock.Now()
+	webhook.Events = slices.Clone(webhook.Events)
+	ws.webhooks[webhook.Id] = webhook
+
+	return webhook.Id, nil
+}
+
+// GetAll returns webhooks of the project ordered by id
+func (ws *WebhookStorage) GetAll(ctx context.Context) ([]model.Webhook, error) {
+	projectId := tenant.FromContext(ctx).ProjectID
+	ws.m.RLock()
+	defer ws.m.RUnlock()
+	ans := make([]model.Webhook, 0)
+	for _, webhook := range ws.webhooks {
+		if webhook.ProjectID == projectId {
+			ans = append(ans, cloneWebhook(webhook))
+		}
+	}
+	slices.SortFunc(ans, func(a, b model.Webhook) int { return a.Id - b.Id })
+
+	return ans, nil
+}
+
+func (ws *WebhookStorage) GetById(ctx context.Context, webhookId int) (*model.Webhook, error) {
+	ws.m.RLock()
+	defer ws.m.RUnlock()
+	webhook, ok := ws.inProject(ctx, webhookId)
+	if !ok {
+		return nil, fmt.Errorf("%v: error while retrieving webhook by id(%v): %w", webhookStorageName, webhookId, model.ErrNotFound)
+	}
+	webhook = cloneWebhook(webhook)
+
+	return &webhook, nil
+}
+
+// Update replaces the webhook, its project and creation time are kept
+func (ws *WebhookStorage) Update(ctx context.Context, webhook model.Webhook) (*model.Webhook, error) {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+	stored, ok := ws.inProject(ctx, webhook.Id)
+	if !ok {
+		return nil, fmt.Errorf("%v: error while updating webhook by id(%v): %w", webhookStorageName, webhook.Id, model.ErrNotFound)
+	}
+	webhook.ProjectID = stored.ProjectID
+	webhook.CreatedAt = stored.CreatedAt
+	webhook.Events = slices.Clone(webhook.Events)
+	ws.webhooks[webhook.Id] = webhook
+
+	ans := cloneWebhook(webhook)
+	return &ans, nil
+}
+
+// Delete removes the webhook together with its delivery log and dead letters
+func (ws *WebhookStorage) Delete(ctx context.Context, webhookId int) error {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+	if _, ok := ws.inProject(ctx, webhookId); !ok {
+		return fmt.Errorf("%v: error while deleting webhook by id(%v): %w", webhookStorageName, webhookId, model.ErrNotFound)
+	}
+	delete(ws.webhooks, webhookId)
+	delete(ws.deliveries, webhookId)
+	delete(ws.deadLetters, webhookId)
+
+	return nil
+}
+
+// AppendDelivery adds the attempt to the delivery log of the webhook, only the latest attempts are kept.
+// Attempts of deleted webhooks are dropped.
+func (ws *WebhookStorage) AppendDelivery(ctx context.Context, delivery model.WebhookDelivery) error {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+	if _, ok := ws.webhooks[delivery.WebhookID]; !ok {
+		return fmt.Errorf("%v: error while logging delivery of webhook(%v): %w", webhookStorageName, delivery.WebhookID, model.ErrNotFound)
+	}
+	ws.deliveryCounter++
+	delivery.Id = ws.deliveryCounter
+	log := append(ws.deliveries[delivery.WebhookID], delivery)
+	if len(log) > maxDeliveryLog {
+		log = slices.Delete(log, 0, len(log)-maxDeliveryLog)
+	}
+	ws.deliveries[delivery.WebhookID] = log
+
+	return nil
+}
+
+// GetDeliveries returns a page of the delivery log of the webhook, newest first, and the amount of kept attempts
+func (ws *WebhookStorage) GetDeliveries(ctx context.Context, webhookId int, page model.Page) ([]model.WebhookDelivery, int, error) {
+	ws.m.RLock()
+	defer ws.m.RUnlock()
+	if _, ok := ws.inProject(ctx, webhookId); !ok {
+		return nil, 0, fmt.Errorf("%v: error while retrieving deliveries of webhook(%v): %w", webhookStorageName, webhookId, model.ErrNotFound)
+	}
+	log := ws.deliveries[webhookId]
+	total := len(log)
+
+	start := min(page.Offset, total)
+	end := min(start+page.Limit, total)
+	ans := make([]model.WebhookDelivery, 0, end-start)
+	for i := start; i < end; i++ {
+		ans = append(ans, log[total-1-i])
+	}
+
+	return ans, total, nil
+}
+
+// StoreDeadLetter keeps the delivery that failed every attempt, letters of deleted webhooks are dropped
+func (ws *WebhookStorage) StoreDeadLetter(ctx context.Context, letter model.DeadLetter) error {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+	if _, ok := ws.webhooks[letter.WebhookID]; !ok {
+		return fmt.Errorf("%v: error while storing dead letter of webhook(%v): %w", webhookStorageName, letter.WebhookID, model.ErrNotFound)
+	}
+	letter.Payload = slices.Clone(letter.Payload)
+	ws.deadLetters[letter.WebhookID] = append(ws.deadLetters[letter.WebhookID], letter)
+
+	return nil
+}
+
+// GetDeadLetters returns dead letters of the webhook, oldest first
+func (ws *WebhookStorage) GetDeadLetters(ctx context.Context, webhookId int) ([]model.DeadLetter, error) {
+	ws.m.RLock()
+	defer ws.m.RUnlock()
+	if _, ok := ws.inProject(ctx, webhookId); !ok {
+		return nil, fmt.Errorf("%v: error while retrieving dead letters of webhook(%v): %w", webhookStorageName, webhookId, model.ErrNotFound)
+	}
+
+	return slices.Clone(ws.deadLetters[webhookId]), nil
+}
+
+// TakeDeadLetter removes the dead letter of the webhook and returns it
+func (ws *WebhookStorage) TakeDeadLetter(ctx context.Context, webhookId int, deliveryId string) (*model.DeadLetter, error) {
+	ws.m.Lock()
+	defer ws.m.Unlock()
+	if _, ok := ws.inProject(ctx, webhookId); !ok {
+		return nil, fmt.Errorf("%v: error while taking dead letter of webhook(%v): %w", webhookStorageName, webhookId, model.ErrNotFound)
+	}
+	letters := ws.deadLetters[webhookId]
+	i := slices.IndexFunc(letters, func(letter model.DeadLetter) bool { return letter.DeliveryID == deliveryId })
+	if i < 0 {
+		return nil, fmt.Errorf("%v: error while taking dead letter(%v): %w", webhookStorageName, deliveryId, model.ErrNotFound)
+	}
+	letter := letters[i]
+	ws.deadLetters[webhookId] = slices.Delete(letters, i, i+1)
+
+	return &letter, nil
+}
+
+// inProject returns the webhook if it belongs to the project of the request, must be called under lock
+func (ws *WebhookStorage) inProject(ctx context.Context, webhookId int) (model.Webhook, bool) {
+	webhook, ok := ws.webhooks[webhookId]
+	if !ok || webhook.ProjectID != tenant.FromContext(ctx).ProjectID {
+		return model.Webhook{}, false
+	}
+	return webhook, true
+}
+
+func cloneWebhook(webhook model.Webhook) model.Webhook {
+	webhook.Events = slices.Clone(webhook.Events)
+	return webhook
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/tenant"
+	"testing"
+)
+
+func TestWebhookStorage(t *testing.T) {
+	storage, _ := NewWebhookStorage(&MockLogger{})
+	ctx := context.Background()
+	otherProject := tenant.WithScope(ctx, tenant.Scope{ProjectID: 2})
+
+	first, _ := storage.Store(ctx, model.Webhook{URL: "http://bot/first", Active: true})
+	second, _ := storage.Store(ctx, model.Webhook{URL: "http://bot/second", Events: []model.WebhookEvent{model.WebhookTaskDeleted}})
+	foreign, _ := storage.Store(otherProject, model.Webhook{URL: "http://ci/hook"})
+
+	t.Run("projects are isolated", func(t *testing.T) {
+		webhooks, _ := storage.GetAll(ctx)
+		if len(webhooks) != 2 || webhooks[0].Id != first || webhooks[1].Id != second {
+			t.Errorf("Expected webhooks %v and %v, got %+v", first, second, webhooks)
+		}
+		if _, err := storage.GetById(ctx, foreign); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a webhook of another project, got %v", err)
+		}
+		if _, err := storage.Update(ctx, model.Webhook{Id: foreign}); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if err := storage.Delete(ctx, foreign); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if _, _, err := storage.GetDeliveries(ctx, foreign, model.DefaultPage); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("update keeps the project", func(t *testing.T) {
+		updated, err := storage.Update(ctx, model.Webhook{Id: second, URL: "http://bot/renamed", ProjectID: 2})
+		if err != nil || updated.ProjectID != model.DefaultProjectId || updated.URL != "http://bot/renamed" || updated.CreatedAt.IsZero() {
+			t.Errorf("Unexpected update %+v, %v", updated, err)
+		}
+	})
+
+	t.Run("delivery log", func(t *testing.T) {
+		for attempt := 1; attempt <= maxDeliveryLog+5; attempt++ {
+			storage.AppendDelivery(ctx, model.WebhookDelivery{WebhookID: first, Attempt: attempt})
+		}
+		deliveries, total, _ := storage.GetDeliveries(ctx, first, model.Page{Limit: 2, Offset: 1})
+		if total != maxDeliveryLog || len(deliveries) != 2 {
+			t.Fatalf("Expected 2 of %v attempts, got %d of %d", maxDeliveryLog, len(deliveries), total)
+		}
+		if deliveries[0].Attempt != maxDeliveryLog+4 || deliveries[1].Attempt != maxDeliveryLog+3 {
+			t.Errorf("Expected the latest attempts newest first, got %+v", deliveries)
+		}
+		if err := storage.AppendDelivery(ctx, model.WebhookDelivery{WebhookID: 99}); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for an unknown webhook, got %v", err)
+		}
+	})
+
+	t.Run("dead letters", func(t *testing.T) {
+		for _, id := range []string{"a", "b"} {
+			storage.StoreDeadLetter(ctx, model.DeadLetter{DeliveryID: id, WebhookID: first})
+		}
+		letter, err := storage.TakeDeadLetter(ctx, first, "a")
+		if err != nil || letter.DeliveryID != "a" {
+			t.Fatalf("Expected dead letter a, got %+v, %v", letter, err)
+		}
+		if _, err := storage.TakeDeadLetter(ctx, first, "a"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected the letter to be taken, got %v", err)
+		}
+		if letters, _ := storage.GetDeadLetters(ctx, first); len(letters) != 1 || letters[0].DeliveryID != "b" {
+			t.Errorf("Expected dead letter b to be left, got %+v", letters)
+		}
+
+		storage.Delete(ctx, first)
+		if err := storage.StoreDeadLetter(ctx, model.DeadLetter{WebhookID: first}); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected letters of deleted webhooks to be dropped, got %v", err)
+		}
+	})
+}
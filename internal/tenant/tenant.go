@@ -0,0 +1,35 @@
+package tenant
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model"
+)
+
+// Scope selects the project every storage query of a request is limited to
+type Scope struct {
+	ProjectID int
+	// TaskQuota is the maximum amount of tasks in the project, model.NoQuota disables the limit
+	TaskQuota int
+}
+
+// Default is used when no scope is set, it's the scope of the legacy /tasks routes
+var Default = Scope{ProjectID: model.DefaultProjectId, TaskQuota: model.NoQuota}
+
+type scopeKey struct{}
+
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// FromContext returns the scope of the request or Default
+func FromContext(ctx context.Context) Scope {
+	if scope, ok := ctx.Value(scopeKey{}).(Scope); ok {
+		return scope
+	}
+	return Default
+}
+
+// ForProject builds the scope of a project
+func ForProject(project model.Project) Scope {
+	return Scope{ProjectID: project.Id, TaskQuota: project.TaskQuota}
+}
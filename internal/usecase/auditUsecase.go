@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"ivanjabrony/test_lo/internal/requestid"
+	"ivanjabrony/test_lo/internal/tenant"
+)
+
+const auditUsecaseName = "AuditUsecase"
+
+type AuditStorage interface {
+	Append(ctx context.Context, entry model.AuditEntry) (*model.AuditEntry, error)
+	GetAll(ctx context.Context, filter model.AuditFilter, page model.Page) ([]model.AuditEntry, int, error)
+	Verify(ctx context.Context) (int, int, error)
+}
+
+// AuditUsecase reads the audit log written by TaskUsecase
+type AuditUsecase struct {
+	logger       Logger
+	auditStorage AuditStorage
+	taskStorage  TaskStorage
+	policy       *auth.Policy
+}
+
+// AuditUsecaseOption configures optional dependencies of AuditUsecase
+type AuditUsecaseOption func(*AuditUsecase)
+
+// WithAuditPolicy enables authorization of every action against the principal from the context
+func WithAuditPolicy(policy *auth.Policy) AuditUsecaseOption {
+	return func(au *AuditUsecase) {
+		au.policy = policy
+	}
+}
+
+func NewAuditUsecase(logger Logger, auditStorage AuditStorage, taskStorage TaskStorage, opts ...AuditUsecaseOption) (*AuditUsecase, error) {
+	if auditStorage == nil || taskStorage == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", auditUsecaseName)
+	}
+
+	au := &AuditUsecase{logger: logger, auditStorage: auditStorage, taskStorage: taskStorage}
+	for _, opt := range opts {
+		opt(au)
+	}
+
+	logger.Log("Created %s successfully", auditUsecaseName)
+	return au, nil
+}
+
+// History returns the changes of a task of the project from the context, oldest first.
+// History of an existing task needs read access to it, history of a deleted task needs auth.AuditRead.
+func (au *AuditUsecase) History(ctx context.Context, taskId int, page model.Page) (dto.GetAuditResponse, error) {
+	if err := model.ValidatePage(page); err != nil {
+		return dto.GetAuditResponse{}, fmt.Errorf("%v: couldn't get history: %w", auditUsecaseName, model.Invalid(err))
+	}
+	task, err := au.taskStorage.GetByTaskId(ctx, taskId)
+	switch {
+	case err == nil:
+		err = au.authorize(ctx, auth.TaskRead, task)
+	case errors.Is(err, model.ErrNotFound):
+		err = au.authorize(ctx, auth.AuditRead, nil)
+	}
+	if err != nil {
+		return dto.GetAuditResponse{}, fmt.Errorf("%v: couldn't get history of the task(%v): %w", auditUsecaseName, taskId, err)
+	}
+
+	filter := model.AuditFilter{ProjectID: tenant.FromContext(ctx).ProjectID, TaskID: &taskId}
+	entries, total, err := au.auditStorage.GetAll(ctx, filter, page)
+	if err != nil {
+		return dto.GetAuditResponse{}, fmt.Errorf("%v: couldn't get history of the task(%v): %w", auditUsecaseName, taskId, err)
+	}
+	if task == nil && total == 0 {
+		return dto.GetAuditResponse{}, fmt.Errorf("%v: history of the task(%v): %w", auditUsecaseName, taskId, model.ErrNotFound)
+	}
+
+	return mapper.AuditEntriesToGetAuditResponse(entries, total, page), nil
+}
+
+// GetAll returns entries of every project matching the filter, oldest first
+func (au *AuditUsecase) GetAll(ctx context.Context, filter model.AuditFilter, page model.Page) (dto.GetAuditResponse, error) {
+	if err := au.authorize(ctx, auth.AuditRead, nil); err != nil {
+		return dto.GetAuditResponse{}, fmt.Errorf("%v: couldn't get the audit log: %w", auditUsecaseName, err)
+	}
+	if err := model.ValidatePage(page); err != nil {
+		return dto.GetAuditResponse{}, fmt.Errorf("%v: couldn't get the audit log: %w", auditUsecaseName, model.Invalid(err))
+	}
+	if err := model.ValidateAuditFilter(filter); err != nil {
+		return dto.GetAuditResponse{}, fmt.Errorf("%v: couldn't get the audit log: %w", auditUsecaseName, model.Invalid(err))
+	}
+	entries, total, err := au.auditStorage.GetAll(ctx, filter, page)
+	if err != nil {
+		return dto.GetAuditResponse{}, fmt.Errorf("%v: couldn't get the audit log: %w", auditUsecaseName, err)
+	}
+
+	return mapper.AuditEntriesToGetAuditResponse(entries, total, page), nil
+}
+
+// Verify checks the hash chain of the whole log
+func (au *AuditUsecase) Verify(ctx context.Context) (dto.VerifyAuditResponse, error) {
+	if err := au.authorize(ctx, auth.AuditRead, nil); err != nil {
+		return dto.VerifyAuditResponse{}, fmt.Errorf("%v: couldn't verify the audit log: %w", auditUsecaseName, err)
+	}
+	count, brokenAt, err := au.auditStorage.Verify(ctx)
+	if err != nil {
+		return dto.VerifyAuditResponse{}, fmt.Errorf("%v: couldn't verify the audit log: %w", auditUsecaseName, err)
+	}
+	if brokenAt != 0 {
+		au.logger.Log("error in %v: audit log is broken at entry %v", auditUsecaseName, brokenAt)
+	}
+
+	return dto.VerifyAuditResponse{Valid: brokenAt == 0, Entries: count, BrokenAt: brokenAt}, nil
+}
+
+func (au *AuditUsecase) authorize(ctx context.Context, permission auth.Permission, task *model.Task) error {
+	if au.policy == nil {
+		return nil
+	}
+	return au.policy.Authorize(ctx, permission, task)
+}
+
+// newAuditEntry describes the mutation of a task, a zero status before or after it
+// means that the task was created or deleted
+func newAuditEntry(ctx context.Context, before, after model.Task, changes []model.FieldChange) model.AuditEntry {
+	action := model.AuditUpdate
+	switch {
+	case before.Status == "":
+		action = model.AuditCreate
+	case after.Status == "":
+		action = model.AuditDelete
+	case before.Status != after.Status:
+		action = model.AuditStatusChange
+	}
+
+	entry := model.AuditEntry{
+		ProjectID:    tenant.FromContext(ctx).ProjectID,
+		TaskID:       after.Id,
+		TaskPublicID: cmp.Or(after.PublicID, before.PublicID),
+		Action:       action,
+		RequestID:    requestid.FromContext(ctx),
+		Changes:      changes,
+	}
+	if principal, ok := auth.FromContext(ctx); ok {
+		entry.Actor = principal.Subject
+		entry.ActorID = principal.UserID
+	}
+	return entry
+}
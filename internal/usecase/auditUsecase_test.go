@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/requestid"
+	"testing"
+)
+
+// MockAuditStorage keeps appended entries without chaining them
+type MockAuditStorage struct {
+	entries []model.AuditEntry
+}
+
+func (m *MockAuditStorage) Append(ctx context.Context, entry model.AuditEntry) (*model.AuditEntry, error) {
+	entry.Seq = len(m.entries) + 1
+	m.entries = append(m.entries, entry)
+	return &entry, nil
+}
+
+func (m *MockAuditStorage) GetAll(ctx context.Context, filter model.AuditFilter, page model.Page) ([]model.AuditEntry, int, error) {
+	ans := make([]model.AuditEntry, 0)
+	for _, entry := range m.entries {
+		if filter.TaskID == nil || entry.TaskID == *filter.TaskID {
+			ans = append(ans, entry)
+		}
+	}
+	return ans, len(ans), nil
+}
+
+func (m *MockAuditStorage) Verify(ctx context.Context) (int, int, error) {
+	return len(m.entries), 0, nil
+}
+
+func TestTaskAudit(t *testing.T) {
+	tasks := make(map[int]model.Task)
+	taskStorage := &MockTaskStorage{
+		storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+			task.Id = len(tasks)
+			tasks[task.Id] = task
+			return task.Id, nil
+		},
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (*model.Task, error) {
+			task, ok := tasks[taskId]
+			if !ok {
+				return nil, model.ErrNotFound
+			}
+			return &task, nil
+		},
+		updateFunc: func(ctx context.Context, task model.Task) (*model.Task, error) {
+			tasks[task.Id] = task
+			return &task, nil
+		},
+		deleteFunc: func(ctx context.Context, taskId int) error {
+			delete(tasks, taskId)
+			return nil
+		},
+	}
+	audit := &MockAuditStorage{}
+	policy := auth.NewPolicy(auth.DefaultRules, false)
+	taskUsecase, _ := NewTaskUsecase(&MockLogger{}, taskStorage, WithAuditStorage(audit), WithPolicy(policy))
+	auditUsecase, _ := NewAuditUsecase(&MockLogger{}, audit, taskStorage, WithAuditPolicy(policy))
+
+	member := requestid.WithID(auth.WithPrincipal(context.Background(), auth.Principal{Subject: "ann", UserID: 7, Roles: []string{"member"}}), "req-1")
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "root", UserID: 1, Roles: []string{"admin"}})
+	name, done := "renamed", model.Done
+
+	first, _ := taskUsecase.Store(member, dto.PostTaskRequest{Name: "first", Status: model.Created})
+	taskUsecase.Update(member, first, dto.PatchTaskRequest{Name: &name})
+	taskUsecase.Update(member, first, dto.PatchTaskRequest{Status: &done})
+	taskUsecase.Update(member, first, dto.PatchTaskRequest{Status: &done})
+	second, _ := taskUsecase.Store(member, dto.PostTaskRequest{Name: "second", Status: model.Created})
+	taskUsecase.Delete(member, second)
+
+	t.Run("recorded entries", func(t *testing.T) {
+		expected := []model.AuditAction{model.AuditCreate, model.AuditUpdate, model.AuditStatusChange, model.AuditCreate, model.AuditDelete}
+		if len(audit.entries) != len(expected) {
+			t.Fatalf("Expected %d entries, got %+v", len(expected), audit.entries)
+		}
+		for i, entry := range audit.entries {
+			if entry.Action != expected[i] {
+				t.Errorf("Expected %v at %d, got %v", expected[i], i, entry.Action)
+			}
+			if entry.Actor != "ann" || entry.ActorID != 7 || entry.RequestID != "req-1" || entry.ProjectID != model.DefaultProjectId {
+				t.Errorf("Unexpected entry metadata %+v", entry)
+			}
+		}
+		rename := audit.entries[1].Changes
+		if len(rename) != 1 || rename[0].Field != "name" || string(rename[0].Before) != `"first"` || string(rename[0].After) != `"renamed"` {
+			t.Errorf("Unexpected diff of the rename %+v", rename)
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		response, err := auditUsecase.History(member, first, model.DefaultPage)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Total != 3 {
+			t.Errorf("Expected 3 entries of the first task, got %+v", response)
+		}
+
+		if _, err := auditUsecase.History(member, second, model.DefaultPage); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected history of a deleted task to be forbidden to members, got %v", err)
+		}
+		if response, err := auditUsecase.History(admin, second, model.DefaultPage); err != nil || response.Total != 2 {
+			t.Errorf("Expected admin to read history of a deleted task, got %+v, %v", response, err)
+		}
+		if _, err := auditUsecase.History(admin, 9, model.DefaultPage); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a task that never existed, got %v", err)
+		}
+	})
+
+	t.Run("global log", func(t *testing.T) {
+		if _, err := auditUsecase.GetAll(member, model.AuditFilter{}, model.DefaultPage); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if _, err := auditUsecase.GetAll(admin, model.AuditFilter{Action: "rename"}, model.DefaultPage); !errors.Is(err, model.ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
+		}
+		if response, err := auditUsecase.Verify(admin); err != nil || !response.Valid || response.Entries != 5 {
+			t.Errorf("Expected a valid log of 5 entries, got %+v, %v", response, err)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+)
+
+const commentUsecaseName = "CommentUsecase"
+
+type CommentStorage interface {
+	Store(ctx context.Context, comment model.Comment) (int, error)
+	GetByTaskId(ctx context.Context, taskId int, page model.Page) ([]model.Comment, int, error)
+	GetByCommentId(ctx context.Context, taskId, commentId int) (*model.Comment, error)
+	Update(ctx context.Context, comment model.Comment) (*model.Comment, error)
+	Delete(ctx context.Context, taskId, commentId int) error
+	DeleteByTaskId(ctx context.Context, taskId int) (int, error)
+	Count(ctx context.Context, taskId int) (int, error)
+}
+
+// CommentUsecase manages discussions of tasks. Comments are visible to everyone who can read the task,
+// only their authors can edit them, and authors or moderators can delete them.
+type CommentUsecase struct {
+	logger         Logger
+	commentStorage CommentStorage
+	taskStorage    TaskStorage
+	policy         *auth.Policy
+}
+
+// CommentUsecaseOption configures optional dependencies of CommentUsecase
+type CommentUsecaseOption func(*CommentUsecase)
+
+// WithCommentPolicy enables authorization of every action against the principal from the context
+func WithCommentPolicy(policy *auth.Policy) CommentUsecaseOption {
+	return func(cu *CommentUsecase) {
+		cu.policy = policy
+	}
+}
+
+func NewCommentUsecase(logger Logger, commentStorage CommentStorage, taskStorage TaskStorage, opts ...CommentUsecaseOption) (*CommentUsecase, error) {
+	if commentStorage == nil || taskStorage == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", commentUsecaseName)
+	}
+
+	cu := &CommentUsecase{logger: logger, commentStorage: commentStorage, taskStorage: taskStorage}
+	for _, opt := range opts {
+		opt(cu)
+	}
+
+	logger.Log("Created %s successfully", commentUsecaseName)
+	return cu, nil
+}
+
+// Store posts a comment on the task, the authenticated user becomes its author
+func (cu *CommentUsecase) Store(ctx context.Context, taskId int, request dto.PostCommentRequest) (int, error) {
+	task, err := cu.readableTask(ctx, taskId)
+	if err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the comment: %w", commentUsecaseName, err)
+	}
+	if err := cu.authorize(ctx, auth.CommentWrite, task); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the comment: %w", commentUsecaseName, err)
+	}
+
+	authorId := model.NoUser
+	if principal, ok := auth.FromContext(ctx); ok {
+		authorId = principal.UserID
+	}
+	comment := mapper.PostCommentRequestToComment(taskId, authorId, request)
+	if err := model.ValidateComment(comment); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the comment: %w", commentUsecaseName, model.Invalid(err))
+	}
+	id, err := cu.commentStorage.Store(ctx, comment)
+	if err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the comment: %w", commentUsecaseName, err)
+	}
+	return id, nil
+}
+
+// GetByTaskId returns a page of the comments of the task, oldest first
+func (cu *CommentUsecase) GetByTaskId(ctx context.Context, taskId int, page model.Page) (dto.GetCommentsResponse, error) {
+	if err := model.ValidatePage(page); err != nil {
+		return dto.GetCommentsResponse{}, fmt.Errorf("%v: couldn't get comments: %w", commentUsecaseName, model.Invalid(err))
+	}
+	task, err := cu.readableTask(ctx, taskId)
+	if err != nil {
+		return dto.GetCommentsResponse{}, fmt.Errorf("%v: couldn't get comments: %w", commentUsecaseName, err)
+	}
+	comments, total, err := cu.commentStorage.GetByTaskId(ctx, taskId, page)
+	if err != nil {
+		return dto.GetCommentsResponse{}, fmt.Errorf("%v: couldn't get comments: %w", commentUsecaseName, err)
+	}
+
+	return mapper.CommentsToGetCommentsResponse(*task, comments, total, page), nil
+}
+
+// Update replaces the body of the comment, only its author can edit it
+func (cu *CommentUsecase) Update(ctx context.Context, taskId, commentId int, request dto.PutCommentRequest) (dto.GetCommentByIdResponse, error) {
+	task, err := cu.authorizeAuthor(ctx, taskId, commentId, false)
+	if err != nil {
+		return dto.GetCommentByIdResponse{}, fmt.Errorf("%v: couldn't update the comment(%v): %w", commentUsecaseName, commentId, err)
+	}
+	comment := mapper.PutCommentRequestToComment(taskId, commentId, request)
+	if err := model.ValidateComment(comment); err != nil {
+		return dto.GetCommentByIdResponse{}, fmt.Errorf("%v: couldn't update the comment: %w", commentUsecaseName, model.Invalid(err))
+	}
+	updated, err := cu.commentStorage.Update(ctx, comment)
+	if err != nil {
+		return dto.GetCommentByIdResponse{}, fmt.Errorf("%v: couldn't update the comment: %w", commentUsecaseName, err)
+	}
+
+	return mapper.CommentToGetCommentByIdResponse(*task, *updated), nil
+}
+
+// Delete removes the comment, it's allowed to its author and to moderators
+func (cu *CommentUsecase) Delete(ctx context.Context, taskId, commentId int) error {
+	if _, err := cu.authorizeAuthor(ctx, taskId, commentId, true); err != nil {
+		return fmt.Errorf("%v: couldn't delete the comment(%v): %w", commentUsecaseName, commentId, err)
+	}
+	if err := cu.commentStorage.Delete(ctx, taskId, commentId); err != nil {
+		return fmt.Errorf("%v: couldn't delete the comment: %w", commentUsecaseName, err)
+	}
+	return nil
+}
+
+// readableTask checks that the task exists and the caller can read it
+func (cu *CommentUsecase) readableTask(ctx context.Context, taskId int) (*model.Task, error) {
+	task, err := cu.taskStorage.GetByTaskId(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	if err := cu.authorize(ctx, auth.TaskRead, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// authorizeAuthor checks that the comment exists and belongs to the caller and returns the task of the comment,
+// moderated actions are also allowed with auth.CommentModerate
+func (cu *CommentUsecase) authorizeAuthor(ctx context.Context, taskId, commentId int, moderated bool) (*model.Task, error) {
+	task, err := cu.readableTask(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := cu.commentStorage.GetByCommentId(ctx, taskId, commentId)
+	if err != nil {
+		return nil, err
+	}
+	if cu.policy == nil {
+		return task, nil
+	}
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, model.ErrUnauthenticated
+	}
+	switch {
+	case principal.UserID != model.NoUser && principal.UserID == comment.AuthorID:
+		err = cu.authorize(ctx, auth.CommentWrite, task)
+	case moderated:
+		err = cu.authorize(ctx, auth.CommentModerate, task)
+	default:
+		err = model.ErrForbidden
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (cu *CommentUsecase) authorize(ctx context.Context, permission auth.Permission, task *model.Task) error {
+	if cu.policy == nil {
+		return nil
+	}
+	return cu.policy.Authorize(ctx, permission, task)
+}
@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"testing"
+)
+
+// MockCommentStorage is an in memory implementation of CommentStorage for testing, it ignores the project scope
+type MockCommentStorage struct {
+	comments  map[int]model.Comment
+	idCounter int
+}
+
+func newMockCommentStorage() *MockCommentStorage {
+	return &MockCommentStorage{comments: make(map[int]model.Comment)}
+}
+
+func (m *MockCommentStorage) Store(ctx context.Context, comment model.Comment) (int, error) {
+	m.idCounter++
+	comment.Id = m.idCounter
+	m.comments[comment.Id] = comment
+	return comment.Id, nil
+}
+
+func (m *MockCommentStorage) GetByTaskId(ctx context.Context, taskId int, page model.Page) ([]model.Comment, int, error) {
+	ans := make([]model.Comment, 0)
+	for id := 1; id <= m.idCounter; id++ {
+		if comment, ok := m.comments[id]; ok && comment.TaskID == taskId {
+			ans = append(ans, comment)
+		}
+	}
+	total := len(ans)
+	start := min(page.Offset, total)
+	return ans[start:min(start+page.Limit, total)], total, nil
+}
+
+func (m *MockCommentStorage) GetByCommentId(ctx context.Context, taskId, commentId int) (*model.Comment, error) {
+	comment, ok := m.comments[commentId]
+	if !ok || comment.TaskID != taskId {
+		return nil, model.ErrNotFound
+	}
+	return &comment, nil
+}
+
+func (m *MockCommentStorage) Update(ctx context.Context, comment model.Comment) (*model.Comment, error) {
+	stored := m.comments[comment.Id]
+	stored.Body = comment.Body
+	m.comments[comment.Id] = stored
+	return &stored, nil
+}
+
+func (m *MockCommentStorage) Delete(ctx context.Context, taskId, commentId int) error {
+	delete(m.comments, commentId)
+	return nil
+}
+
+func (m *MockCommentStorage) DeleteByTaskId(ctx context.Context, taskId int) (int, error) {
+	deleted := 0
+	for id, comment := range m.comments {
+		if comment.TaskID == taskId {
+			delete(m.comments, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MockCommentStorage) Count(ctx context.Context, taskId int) (int, error) {
+	_, total, err := m.GetByTaskId(ctx, taskId, model.Page{Limit: model.MaxPageLimit})
+	return total, err
+}
+
+func TestCommentUsecase(t *testing.T) {
+	tasks := map[int]model.Task{
+		0: {Id: 0, Name: "shared", Status: model.Created, ReporterID: 1},
+	}
+	taskStorage := &MockTaskStorage{
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (*model.Task, error) {
+			task, ok := tasks[taskId]
+			if !ok {
+				return nil, model.ErrNotFound
+			}
+			return &task, nil
+		},
+		deleteFunc: func(ctx context.Context, taskId int) error {
+			return nil
+		},
+	}
+	policy := auth.NewPolicy(auth.DefaultRules, false)
+
+	as := func(userId int, role auth.Role) context.Context {
+		return auth.WithPrincipal(context.Background(), auth.Principal{UserID: userId, Roles: []string{string(role)}})
+	}
+	author, other, admin, viewer := as(7, auth.RoleMember), as(8, auth.RoleMember), as(1, auth.RoleAdmin), as(9, auth.RoleViewer)
+
+	newUsecase := func() (*CommentUsecase, *MockCommentStorage) {
+		comments := newMockCommentStorage()
+		usecase, _ := NewCommentUsecase(&MockLogger{}, comments, taskStorage, WithCommentPolicy(policy))
+		return usecase, comments
+	}
+
+	t.Run("post", func(t *testing.T) {
+		usecase, comments := newUsecase()
+		id, err := usecase.Store(author, 0, dto.PostCommentRequest{Body: "looks <b>good</b>"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if stored := comments.comments[id]; stored.AuthorID != 7 || stored.Body != "looks &lt;b>good&lt;/b>" {
+			t.Errorf("Unexpected stored comment %+v", stored)
+		}
+
+		tests := []struct {
+			name    string
+			ctx     context.Context
+			taskId  int
+			body    string
+			wantErr error
+		}{
+			{name: "viewer can't comment", ctx: viewer, taskId: 0, body: "x", wantErr: model.ErrForbidden},
+			{name: "missing task", ctx: author, taskId: 5, body: "x", wantErr: model.ErrNotFound},
+			{name: "empty body", ctx: author, taskId: 0, body: " \n ", wantErr: model.ErrInvalid},
+			{name: "no principal", ctx: context.Background(), taskId: 0, body: "x", wantErr: model.ErrUnauthenticated},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := usecase.Store(tt.ctx, tt.taskId, dto.PostCommentRequest{Body: tt.body}); !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
+
+	t.Run("edit and delete", func(t *testing.T) {
+		usecase, comments := newUsecase()
+		id, _ := usecase.Store(author, 0, dto.PostCommentRequest{Body: "first"})
+
+		if _, err := usecase.Update(other, 0, id, dto.PutCommentRequest{Body: "hijacked"}); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden for another member, got %v", err)
+		}
+		if _, err := usecase.Update(admin, 0, id, dto.PutCommentRequest{Body: "moderated"}); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected admins not to edit comments of others, got %v", err)
+		}
+		response, err := usecase.Update(author, 0, id, dto.PutCommentRequest{Body: "edited"})
+		if err != nil || response.Body != "edited" {
+			t.Errorf("Expected author to edit the comment, got %v, %v", response, err)
+		}
+
+		if err := usecase.Delete(other, 0, id); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden for another member, got %v", err)
+		}
+		if err := usecase.Delete(admin, 0, id); err != nil {
+			t.Errorf("Expected admin to delete the comment, got %v", err)
+		}
+		if len(comments.comments) != 0 {
+			t.Errorf("Expected comment to be deleted, got %v", comments.comments)
+		}
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		usecase, _ := newUsecase()
+		for _, body := range []string{"a", "b", "c"} {
+			usecase.Store(author, 0, dto.PostCommentRequest{Body: body})
+		}
+
+		response, err := usecase.GetByTaskId(viewer, 0, model.Page{Limit: 2, Offset: 1})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Total != 3 || response.Amount != 2 || response.Comments[0].Body != "b" {
+			t.Errorf("Unexpected page %+v", response)
+		}
+		if _, err := usecase.GetByTaskId(viewer, 0, model.Page{Limit: model.MaxPageLimit + 1}); !errors.Is(err, model.ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for a too large page, got %v", err)
+		}
+	})
+
+	t.Run("task counts and cascades", func(t *testing.T) {
+		usecase, comments := newUsecase()
+		usecase.Store(author, 0, dto.PostCommentRequest{Body: "a"})
+		usecase.Store(author, 0, dto.PostCommentRequest{Body: "b"})
+		taskUsecase, _ := NewTaskUsecase(&MockLogger{}, taskStorage, WithCommentStorage(comments))
+
+		response, _ := taskUsecase.GetByTaskId(context.Background(), 0)
+		if response.CommentCount != 2 {
+			t.Errorf("Expected 2 comments, got %d", response.CommentCount)
+		}
+		if err := taskUsecase.Delete(context.Background(), 0); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(comments.comments) != 0 {
+			t.Errorf("Expected comments to be deleted with the task, got %v", comments.comments)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"slices"
+)
+
+const notificationUsecaseName = "NotificationUsecase"
+
+type NotificationStorage interface {
+	GetPreferences(ctx context.Context, userId int) (*model.NotificationPreferences, error)
+	PutPreferences(ctx context.Context, preferences model.NotificationPreferences) error
+}
+
+// NotificationUsecase manages notification preferences of users, reminders are sent by reminder.Engine.
+// Users manage their own preferences, and callers allowed to change users manage everyone's.
+type NotificationUsecase struct {
+	logger              Logger
+	notificationStorage NotificationStorage
+	userStorage         UserStorage
+	policy              *auth.Policy
+	// defaults are used for users who haven't chosen channels
+	defaults []model.NotificationChannel
+	// available are the channels the server can send through
+	available []model.NotificationChannel
+}
+
+// NotificationUsecaseOption configures optional dependencies of NotificationUsecase
+type NotificationUsecaseOption func(*NotificationUsecase)
+
+// WithNotificationPolicy enables authorization of every action against the principal from the context
+func WithNotificationPolicy(policy *auth.Policy) NotificationUsecaseOption {
+	return func(nu *NotificationUsecase) {
+		nu.policy = policy
+	}
+}
+
+// WithNotificationChannels sets the default channels and the channels users may choose, only the log by default
+func WithNotificationChannels(defaults, available []model.NotificationChannel) NotificationUsecaseOption {
+	return func(nu *NotificationUsecase) {
+		nu.defaults = slices.Clone(defaults)
+		nu.available = slices.Clone(available)
+	}
+}
+
+func NewNotificationUsecase(logger Logger, notificationStorage NotificationStorage, userStorage UserStorage, opts ...NotificationUsecaseOption) (*NotificationUsecase, error) {
+	if notificationStorage == nil || userStorage == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", notificationUsecaseName)
+	}
+
+	nu := &NotificationUsecase{
+		logger:              logger,
+		notificationStorage: notificationStorage,
+		userStorage:         userStorage,
+		defaults:            []model.NotificationChannel{model.ChannelLog},
+		available:           []model.NotificationChannel{model.ChannelLog},
+	}
+	for _, opt := range opts {
+		opt(nu)
+	}
+
+	logger.Log("Created %s successfully", notificationUsecaseName)
+	return nu, nil
+}
+
+// GetPreferences returns preferences of the user, users who haven't set any get the defaults
+func (nu *NotificationUsecase) GetPreferences(ctx context.Context, userId int) (dto.GetNotificationPreferencesResponse, error) {
+	if err := nu.authorize(ctx, userId); err != nil {
+		return dto.GetNotificationPreferencesResponse{}, fmt.Errorf("%v: %w", notificationUsecaseName, err)
+	}
+	if _, err := nu.userStorage.GetByUserId(ctx, userId); err != nil {
+		return dto.GetNotificationPreferencesResponse{}, fmt.Errorf("%v: %w", notificationUsecaseName, err)
+	}
+	preferences, err := nu.notificationStorage.GetPreferences(ctx, userId)
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		preferences = &model.NotificationPreferences{UserID: userId}
+	case err != nil:
+		return dto.GetNotificationPreferencesResponse{}, fmt.Errorf("%v: couldn't get preferences of the user(%v): %w", notificationUsecaseName, userId, err)
+	}
+
+	return mapper.PreferencesToGetNotificationPreferencesResponse(*preferences, nu.defaults), nil
+}
+
+// PutPreferences replaces preferences of the user, only the channels the server can send through may be chosen
+func (nu *NotificationUsecase) PutPreferences(ctx context.Context, userId int, request dto.PutNotificationPreferencesRequest) (dto.GetNotificationPreferencesResponse, error) {
+	if err := nu.authorize(ctx, userId); err != nil {
+		return dto.GetNotificationPreferencesResponse{}, fmt.Errorf("%v: couldn't update preferences: %w", notificationUsecaseName, err)
+	}
+	preferences := mapper.PutNotificationPreferencesRequestToPreferences(userId, request)
+	if err := model.ValidateNotificationPreferences(preferences); err != nil {
+		return dto.GetNotificationPreferencesResponse{}, fmt.Errorf("%v: couldn't update preferences: %w", notificationUsecaseName, model.Invalid(err))
+	}
+	for _, channel := range preferences.Channels {
+		if !slices.Contains(nu.available, channel) {
+			err := fmt.Errorf("invalid channel in notification preferences: %q isn't available, expected one of %v", channel, nu.available)
+			return dto.GetNotificationPreferencesResponse{}, fmt.Errorf("%v: couldn't update preferences: %w", notificationUsecaseName, model.Invalid(err))
+		}
+	}
+	if _, err := nu.userStorage.GetByUserId(ctx, userId); err != nil {
+		return dto.GetNotificationPreferencesResponse{}, fmt.Errorf("%v: couldn't update preferences: %w", notificationUsecaseName, err)
+	}
+	if err := nu.notificationStorage.PutPreferences(ctx, preferences); err != nil {
+		return dto.GetNotificationPreferencesResponse{}, fmt.Errorf("%v: couldn't update preferences of the user(%v): %w", notificationUsecaseName, userId, err)
+	}
+
+	return mapper.PreferencesToGetNotificationPreferencesResponse(preferences, nu.defaults), nil
+}
+
+// authorize lets users manage their own preferences, and callers allowed to change users everyone's
+func (nu *NotificationUsecase) authorize(ctx context.Context, userId int) error {
+	if nu.policy == nil {
+		return nil
+	}
+	if principal, ok := auth.FromContext(ctx); ok && principal.UserID != model.NoUser && principal.UserID == userId {
+		return nil
+	}
+	return nu.policy.Authorize(ctx, auth.UserWrite, nil)
+}
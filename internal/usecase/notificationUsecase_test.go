@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"slices"
+	"testing"
+)
+
+// MockNotificationStorage is an in memory implementation of NotificationStorage for testing
+type MockNotificationStorage struct {
+	preferences map[int]model.NotificationPreferences
+}
+
+func (m *MockNotificationStorage) GetPreferences(ctx context.Context, userId int) (*model.NotificationPreferences, error) {
+	preferences, ok := m.preferences[userId]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &preferences, nil
+}
+
+func (m *MockNotificationStorage) PutPreferences(ctx context.Context, preferences model.NotificationPreferences) error {
+	m.preferences[preferences.UserID] = preferences
+	return nil
+}
+
+func TestNotificationUsecase(t *testing.T) {
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1, Roles: []string{"admin"}})
+	john := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 2, Roles: []string{"member"}})
+	users := &MockUserStorage{users: map[int]model.User{1: {Id: 1, Name: "Admin"}, 2: {Id: 2, Name: "John"}, 3: {Id: 3, Name: "Jane"}}}
+	newUsecase := func() (*NotificationUsecase, *MockNotificationStorage) {
+		storage := &MockNotificationStorage{preferences: make(map[int]model.NotificationPreferences)}
+		usecase, _ := NewNotificationUsecase(&MockLogger{}, storage, users,
+			WithNotificationPolicy(auth.NewPolicy(auth.DefaultRules, false)),
+			WithNotificationChannels(
+				[]model.NotificationChannel{model.ChannelLog},
+				[]model.NotificationChannel{model.ChannelLog, model.ChannelEmail}))
+		return usecase, storage
+	}
+
+	t.Run("nil storage", func(t *testing.T) {
+		if _, err := NewNotificationUsecase(&MockLogger{}, nil, users); err == nil {
+			t.Error("Expected error for nil storage")
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		usecase, _ := newUsecase()
+		preferences, err := usecase.GetPreferences(john, 2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !preferences.DefaultChannels || !slices.Equal(preferences.Channels, []model.NotificationChannel{model.ChannelLog}) {
+			t.Errorf("Expected the default channels, got %+v", preferences)
+		}
+	})
+
+	t.Run("put", func(t *testing.T) {
+		usecase, storage := newUsecase()
+		request := dto.PutNotificationPreferencesRequest{
+			Channels: []model.NotificationChannel{model.ChannelEmail},
+			Muted:    []model.ReminderRule{model.ReminderStale},
+		}
+		preferences, err := usecase.PutPreferences(john, 2, request)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if preferences.DefaultChannels || !slices.Equal(storage.preferences[2].Channels, request.Channels) {
+			t.Errorf("Expected the chosen channels to be stored, got %+v", preferences)
+		}
+
+		off, err := usecase.PutPreferences(john, 2, dto.PutNotificationPreferencesRequest{Channels: []model.NotificationChannel{}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if off.DefaultChannels || len(off.Channels) != 0 {
+			t.Errorf("Expected reminders to be turned off, got %+v", off)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		usecase, _ := newUsecase()
+		requests := []dto.PutNotificationPreferencesRequest{
+			{Channels: []model.NotificationChannel{"sms"}},
+			{Channels: []model.NotificationChannel{model.ChannelWebhook}, WebhookURL: "https://chat.example.com/hook"},
+			{Muted: []model.ReminderRule{"hourly"}},
+		}
+		for _, request := range requests {
+			if _, err := usecase.PutPreferences(john, 2, request); !errors.Is(err, model.ErrInvalid) {
+				t.Errorf("Expected ErrInvalid for %+v, got %v", request, err)
+			}
+		}
+		if _, err := usecase.PutPreferences(admin, 99, dto.PutNotificationPreferencesRequest{}); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a nonexistent user, got %v", err)
+		}
+	})
+
+	t.Run("authorization", func(t *testing.T) {
+		usecase, _ := newUsecase()
+		if _, err := usecase.GetPreferences(john, 3); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden for preferences of another user, got %v", err)
+		}
+		if _, err := usecase.PutPreferences(john, 3, dto.PutNotificationPreferencesRequest{}); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden for preferences of another user, got %v", err)
+		}
+		if _, err := usecase.GetPreferences(context.Background(), 2); !errors.Is(err, model.ErrUnauthenticated) {
+			t.Errorf("Expected ErrUnauthenticated, got %v", err)
+		}
+		if _, err := usecase.PutPreferences(admin, 3, dto.PutNotificationPreferencesRequest{}); err != nil {
+			t.Errorf("Expected admins to manage preferences of everyone, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"ivanjabrony/test_lo/internal/tenant"
+)
+
+const projectUsecaseName = "ProjectUsecase"
+
+type ProjectStorage interface {
+	Store(ctx context.Context, project model.Project) (int, error)
+	GetAll(ctx context.Context) ([]model.Project, error)
+	GetByProjectId(ctx context.Context, projectId int) (*model.Project, error)
+	Update(ctx context.Context, project model.Project) (*model.Project, error)
+}
+
+type ProjectUsecase struct {
+	logger         Logger
+	projectStorage ProjectStorage
+	policy         *auth.Policy
+	defaultQuota   int
+}
+
+// ProjectUsecaseOption configures optional dependencies of ProjectUsecase
+type ProjectUsecaseOption func(*ProjectUsecase)
+
+// WithProjectPolicy enables authorization of every action against the principal from the context
+func WithProjectPolicy(policy *auth.Policy) ProjectUsecaseOption {
+	return func(pu *ProjectUsecase) {
+		pu.policy = policy
+	}
+}
+
+// WithDefaultTaskQuota sets the quota of projects created without an explicit one
+func WithDefaultTaskQuota(quota int) ProjectUsecaseOption {
+	return func(pu *ProjectUsecase) {
+		pu.defaultQuota = quota
+	}
+}
+
+func NewProjectUsecase(logger Logger, projectStorage ProjectStorage, opts ...ProjectUsecaseOption) (*ProjectUsecase, error) {
+	if projectStorage == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", projectUsecaseName)
+	}
+
+	pu := &ProjectUsecase{logger: logger, projectStorage: projectStorage, defaultQuota: model.NoQuota}
+	for _, opt := range opts {
+		opt(pu)
+	}
+
+	logger.Log("Created %s successfully", projectUsecaseName)
+	return pu, nil
+}
+
+func (pu *ProjectUsecase) Store(ctx context.Context, request dto.PostProjectRequest) (int, error) {
+	if err := pu.authorize(ctx, auth.ProjectWrite); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the project: %w", projectUsecaseName, err)
+	}
+	project := mapper.PostProjectRequestToProject(request, pu.defaultQuota)
+	if err := model.ValidateProject(project); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the project: %w", projectUsecaseName, model.Invalid(err))
+	}
+	id, err := pu.projectStorage.Store(ctx, project)
+	if err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the project: %w", projectUsecaseName, err)
+	}
+	return id, nil
+}
+
+func (pu *ProjectUsecase) GetAll(ctx context.Context) (dto.GetAllProjectsResponse, error) {
+	if err := pu.authorize(ctx, auth.ProjectRead); err != nil {
+		return dto.GetAllProjectsResponse{}, fmt.Errorf("%v: couldn't get all the projects: %w", projectUsecaseName, err)
+	}
+	projects, err := pu.projectStorage.GetAll(ctx)
+	if err != nil {
+		return dto.GetAllProjectsResponse{}, fmt.Errorf("%v: couldn't get all the projects: %w", projectUsecaseName, err)
+	}
+
+	return mapper.ProjectsToGetAllProjectsResponse(projects), nil
+}
+
+func (pu *ProjectUsecase) GetByProjectId(ctx context.Context, projectId int) (dto.GetProjectByIdResponse, error) {
+	if err := pu.authorize(ctx, auth.ProjectRead); err != nil {
+		return dto.GetProjectByIdResponse{}, fmt.Errorf("%v: %w", projectUsecaseName, err)
+	}
+	project, err := pu.projectStorage.GetByProjectId(ctx, projectId)
+	if err != nil {
+		return dto.GetProjectByIdResponse{}, fmt.Errorf("%v: %w", projectUsecaseName, err)
+	}
+
+	return mapper.ProjectToGetProjectByIdResponse(*project), nil
+}
+
+func (pu *ProjectUsecase) Update(ctx context.Context, projectId int, request dto.PatchProjectRequest) (dto.GetProjectByIdResponse, error) {
+	if err := pu.authorize(ctx, auth.ProjectWrite); err != nil {
+		return dto.GetProjectByIdResponse{}, fmt.Errorf("%v: couldn't update the project: %w", projectUsecaseName, err)
+	}
+	current, err := pu.projectStorage.GetByProjectId(ctx, projectId)
+	if err != nil {
+		return dto.GetProjectByIdResponse{}, fmt.Errorf("%v: couldn't update the project: %w", projectUsecaseName, err)
+	}
+	project := mapper.ApplyPatchProjectRequest(*current, request)
+	if err := model.ValidateProject(project); err != nil {
+		return dto.GetProjectByIdResponse{}, fmt.Errorf("%v: couldn't update the project: %w", projectUsecaseName, model.Invalid(err))
+	}
+	updated, err := pu.projectStorage.Update(ctx, project)
+	if err != nil {
+		return dto.GetProjectByIdResponse{}, fmt.Errorf("%v: couldn't update the project: %w", projectUsecaseName, err)
+	}
+
+	return mapper.ProjectToGetProjectByIdResponse(*updated), nil
+}
+
+// Scope resolves the tenant scope of the project, it isn't authorized because it doesn't
+// expose the project, access to tasks inside it is checked by TaskUsecase
+func (pu *ProjectUsecase) Scope(ctx context.Context, projectId int) (tenant.Scope, error) {
+	project, err := pu.projectStorage.GetByProjectId(ctx, projectId)
+	if err != nil {
+		return tenant.Scope{}, fmt.Errorf("%v: %w", projectUsecaseName, err)
+	}
+	return tenant.ForProject(*project), nil
+}
+
+func (pu *ProjectUsecase) authorize(ctx context.Context, permission auth.Permission) error {
+	if pu.policy == nil {
+		return nil
+	}
+	return pu.policy.Authorize(ctx, permission, nil)
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"testing"
+)
+
+// MockProjectStorage is a mock implementation of ProjectStorage for testing
+type MockProjectStorage struct {
+	projects map[int]model.Project
+}
+
+func (m *MockProjectStorage) Store(ctx context.Context, project model.Project) (int, error) {
+	project.Id = len(m.projects) + 1
+	m.projects[project.Id] = project
+	return project.Id, nil
+}
+
+func (m *MockProjectStorage) GetAll(ctx context.Context) ([]model.Project, error) {
+	ans := make([]model.Project, 0, len(m.projects))
+	for _, project := range m.projects {
+		ans = append(ans, project)
+	}
+	return ans, nil
+}
+
+func (m *MockProjectStorage) GetByProjectId(ctx context.Context, projectId int) (*model.Project, error) {
+	project, ok := m.projects[projectId]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &project, nil
+}
+
+func (m *MockProjectStorage) Update(ctx context.Context, project model.Project) (*model.Project, error) {
+	if _, ok := m.projects[project.Id]; !ok {
+		return nil, model.ErrNotFound
+	}
+	m.projects[project.Id] = project
+	return &project, nil
+}
+
+func TestProjectUsecase(t *testing.T) {
+	newUsecase := func() (*ProjectUsecase, *MockProjectStorage) {
+		projects := &MockProjectStorage{projects: map[int]model.Project{
+			model.DefaultProjectId: {Id: model.DefaultProjectId, Name: "default"},
+		}}
+		usecase, _ := NewProjectUsecase(&MockLogger{}, projects,
+			WithDefaultTaskQuota(50), WithProjectPolicy(auth.NewPolicy(auth.DefaultRules, false)))
+		return usecase, projects
+	}
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{Roles: []string{"admin"}})
+	member := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Roles: []string{"member"}})
+
+	t.Run("nil storage", func(t *testing.T) {
+		if _, err := NewProjectUsecase(&MockLogger{}, nil); err == nil {
+			t.Error("Expected error for nil storage")
+		}
+	})
+
+	t.Run("store uses default quota", func(t *testing.T) {
+		usecase, projects := newUsecase()
+		id, err := usecase.Store(admin, dto.PostProjectRequest{Name: "Team A"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if projects.projects[id].TaskQuota != 50 {
+			t.Errorf("Expected default quota 50, got %d", projects.projects[id].TaskQuota)
+		}
+
+		zero := 0
+		id, _ = usecase.Store(admin, dto.PostProjectRequest{Name: "Team B", TaskQuota: &zero})
+		if projects.projects[id].TaskQuota != model.NoQuota {
+			t.Errorf("Expected explicit unlimited quota, got %d", projects.projects[id].TaskQuota)
+		}
+	})
+
+	t.Run("invalid project", func(t *testing.T) {
+		usecase, _ := newUsecase()
+		negative := -1
+		if _, err := usecase.Store(admin, dto.PostProjectRequest{Name: " "}); !errors.Is(err, model.ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
+		}
+		_, err := usecase.Update(admin, model.DefaultProjectId, dto.PatchProjectRequest{TaskQuota: &negative})
+		if !errors.Is(err, model.ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("only admins write projects", func(t *testing.T) {
+		usecase, _ := newUsecase()
+		if _, err := usecase.Store(member, dto.PostProjectRequest{Name: "Team A"}); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if _, err := usecase.GetAll(member); err != nil {
+			t.Errorf("Expected member to read projects, got %v", err)
+		}
+	})
+
+	t.Run("scope of a project", func(t *testing.T) {
+		usecase, _ := newUsecase()
+		quota := 3
+		id, _ := usecase.Store(admin, dto.PostProjectRequest{Name: "Team A", TaskQuota: &quota})
+
+		scope, err := usecase.Scope(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if scope.ProjectID != id || scope.TaskQuota != 3 {
+			t.Errorf("Unexpected scope %+v", scope)
+		}
+		if _, err := usecase.Scope(context.Background(), 99); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+)
+
+const tagUsecaseName = "TagUsecase"
+
+type TagStorage interface {
+	StoreTag(ctx context.Context, tag model.Tag) (int, error)
+	GetAllTags(ctx context.Context) ([]model.Tag, error)
+	GetTagById(ctx context.Context, tagId int) (*model.Tag, error)
+	UpdateTag(ctx context.Context, tag model.Tag) (*model.Tag, error)
+	DeleteTag(ctx context.Context, tagId int) error
+	AttachTag(ctx context.Context, taskId, tagId int) (*model.Task, error)
+	DetachTag(ctx context.Context, taskId, tagId int) (*model.Task, error)
+}
+
+type TagUsecase struct {
+	logger      Logger
+	tagStorage  TagStorage
+	taskStorage TaskStorage
+	policy      *auth.Policy
+	clock       Clock
+}
+
+// TagUsecaseOption configures optional dependencies of TagUsecase
+type TagUsecaseOption func(*TagUsecase)
+
+// WithTagPolicy enables authorization of every action against the principal from the context
+func WithTagPolicy(policy *auth.Policy) TagUsecaseOption {
+	return func(tu *TagUsecase) {
+		tu.policy = policy
+	}
+}
+
+// WithTagClock replaces the system clock due dates of tagged tasks are checked against
+func WithTagClock(clock Clock) TagUsecaseOption {
+	return func(tu *TagUsecase) {
+		tu.clock = clock
+	}
+}
+
+func NewTagUsecase(logger Logger, tagStorage TagStorage, taskStorage TaskStorage, opts ...TagUsecaseOption) (*TagUsecase, error) {
+	if tagStorage == nil || taskStorage == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", tagUsecaseName)
+	}
+
+	tu := &TagUsecase{logger: logger, tagStorage: tagStorage, taskStorage: taskStorage, clock: clock.System{}}
+	for _, opt := range opts {
+		opt(tu)
+	}
+
+	logger.Log("Created %s successfully", tagUsecaseName)
+	return tu, nil
+}
+
+func (tu *TagUsecase) Store(ctx context.Context, request dto.PostTagRequest) (int, error) {
+	if err := tu.authorize(ctx, auth.TagWrite, nil); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the tag: %w", tagUsecaseName, err)
+	}
+	tag := mapper.PostTagRequestToTag(request)
+	if err := model.ValidateTag(tag); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the tag: %w", tagUsecaseName, model.Invalid(err))
+	}
+	id, err := tu.tagStorage.StoreTag(ctx, tag)
+	if err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the tag: %w", tagUsecaseName, err)
+	}
+	return id, nil
+}
+
+func (tu *TagUsecase) GetAll(ctx context.Context) (dto.GetAllTagsResponse, error) {
+	if err := tu.authorize(ctx, auth.TagRead, nil); err != nil {
+		return dto.GetAllTagsResponse{}, fmt.Errorf("%v: couldn't get all the tags: %w", tagUsecaseName, err)
+	}
+	tags, err := tu.tagStorage.GetAllTags(ctx)
+	if err != nil {
+		return dto.GetAllTagsResponse{}, fmt.Errorf("%v: couldn't get all the tags: %w", tagUsecaseName, err)
+	}
+
+	return mapper.TagsToGetAllTagsResponse(tags), nil
+}
+
+func (tu *TagUsecase) GetByTagId(ctx context.Context, tagId int) (dto.GetTagByIdResponse, error) {
+	if err := tu.authorize(ctx, auth.TagRead, nil); err != nil {
+		return dto.GetTagByIdResponse{}, fmt.Errorf("%v: %w", tagUsecaseName, err)
+	}
+	tag, err := tu.tagStorage.GetTagById(ctx, tagId)
+	if err != nil {
+		return dto.GetTagByIdResponse{}, fmt.Errorf("%v: %w", tagUsecaseName, err)
+	}
+
+	return mapper.TagToGetTagByIdResponse(*tag), nil
+}
+
+// Update renames the tag, tasks keep it attached
+func (tu *TagUsecase) Update(ctx context.Context, tagId int, request dto.PutTagRequest) (dto.GetTagByIdResponse, error) {
+	if err := tu.authorize(ctx, auth.TagWrite, nil); err != nil {
+		return dto.GetTagByIdResponse{}, fmt.Errorf("%v: couldn't update the tag: %w", tagUsecaseName, err)
+	}
+	tag := mapper.PutTagRequestToTag(tagId, request)
+	if err := model.ValidateTag(tag); err != nil {
+		return dto.GetTagByIdResponse{}, fmt.Errorf("%v: couldn't update the tag: %w", tagUsecaseName, model.Invalid(err))
+	}
+	updated, err := tu.tagStorage.UpdateTag(ctx, tag)
+	if err != nil {
+		return dto.GetTagByIdResponse{}, fmt.Errorf("%v: couldn't update the tag: %w", tagUsecaseName, err)
+	}
+
+	return mapper.TagToGetTagByIdResponse(*updated), nil
+}
+
+// Delete removes the tag and detaches it from every task
+func (tu *TagUsecase) Delete(ctx context.Context, tagId int) error {
+	if err := tu.authorize(ctx, auth.TagWrite, nil); err != nil {
+		return fmt.Errorf("%v: couldn't delete the tag: %w", tagUsecaseName, err)
+	}
+	if err := tu.tagStorage.DeleteTag(ctx, tagId); err != nil {
+		return fmt.Errorf("%v: couldn't delete the tag: %w", tagUsecaseName, err)
+	}
+	return nil
+}
+
+// Attach attaches the tag to the task, the caller needs write access to the task
+func (tu *TagUsecase) Attach(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error) {
+	if err := tu.authorizeTask(ctx, taskId); err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't attach the tag: %w", tagUsecaseName, err)
+	}
+	task, err := tu.tagStorage.AttachTag(ctx, taskId, tagId)
+	if err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't attach the tag: %w", tagUsecaseName, err)
+	}
+	return mapper.TaskToGetTaskByIdReponse(*task, tu.clock.Now()), nil
+}
+
+// Detach detaches the tag from the task, the caller needs write access to the task
+func (tu *TagUsecase) Detach(ctx context.Context, taskId, tagId int) (dto.GetTaskByIdResponse, error) {
+	if err := tu.authorizeTask(ctx, taskId); err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't detach the tag: %w", tagUsecaseName, err)
+	}
+	task, err := tu.tagStorage.DetachTag(ctx, taskId, tagId)
+	if err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't detach the tag: %w", tagUsecaseName, err)
+	}
+	return mapper.TaskToGetTaskByIdReponse(*task, tu.clock.Now()), nil
+}
+
+// authorizeTask checks write access to the task, it isn't looked up without a policy
+func (tu *TagUsecase) authorizeTask(ctx context.Context, taskId int) error {
+	if tu.policy == nil {
+		return nil
+	}
+	task, err := tu.taskStorage.GetByTaskId(ctx, taskId)
+	if err != nil {
+		return err
+	}
+	return tu.authorize(ctx, auth.TaskWrite, task)
+}
+
+func (tu *TagUsecase) authorize(ctx context.Context, permission auth.Permission, task *model.Task) error {
+	if tu.policy == nil {
+		return nil
+	}
+	return tu.policy.Authorize(ctx, permission, task)
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"testing"
+)
+
+// MockTagStorage is a mock implementation of TagStorage for testing
+type MockTagStorage struct {
+	storeTagFunc  func(ctx context.Context, tag model.Tag) (int, error)
+	attachTagFunc func(ctx context.Context, taskId, tagId int) (*model.Task, error)
+}
+
+func (m *MockTagStorage) StoreTag(ctx context.Context, tag model.Tag) (int, error) {
+	return m.storeTagFunc(ctx, tag)
+}
+
+func (m *MockTagStorage) GetAllTags(ctx context.Context) ([]model.Tag, error) {
+	return []model.Tag{}, nil
+}
+
+func (m *MockTagStorage) GetTagById(ctx context.Context, tagId int) (*model.Tag, error) {
+	return nil, model.ErrNotFound
+}
+
+func (m *MockTagStorage) UpdateTag(ctx context.Context, tag model.Tag) (*model.Tag, error) {
+	return &tag, nil
+}
+
+func (m *MockTagStorage) DeleteTag(ctx context.Context, tagId int) error {
+	return nil
+}
+
+func (m *MockTagStorage) AttachTag(ctx context.Context, taskId, tagId int) (*model.Task, error) {
+	return m.attachTagFunc(ctx, taskId, tagId)
+}
+
+func (m *MockTagStorage) DetachTag(ctx context.Context, taskId, tagId int) (*model.Task, error) {
+	return m.attachTagFunc(ctx, taskId, tagId)
+}
+
+func TestTagUsecase(t *testing.T) {
+	tasks := map[int]model.Task{
+		0: {Id: 0, Name: "own", Status: model.Created, ReporterID: 7},
+		1: {Id: 1, Name: "foreign", Status: model.Created, ReporterID: 1},
+	}
+	taskStorage := &MockTaskStorage{
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (*model.Task, error) {
+			task, ok := tasks[taskId]
+			if !ok {
+				return nil, model.ErrNotFound
+			}
+			return &task, nil
+		},
+	}
+	var storedName string
+	tagStorage := &MockTagStorage{
+		storeTagFunc: func(ctx context.Context, tag model.Tag) (int, error) {
+			storedName = tag.Name
+			return 1, nil
+		},
+		attachTagFunc: func(ctx context.Context, taskId, tagId int) (*model.Task, error) {
+			task := tasks[taskId]
+			task.TagIDs = []int{tagId}
+			return &task, nil
+		},
+	}
+	usecase, _ := NewTagUsecase(&MockLogger{}, tagStorage, taskStorage, WithTagPolicy(auth.NewPolicy(auth.DefaultRules, false)))
+
+	member := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Roles: []string{"member"}})
+	viewer := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Roles: []string{"viewer"}})
+
+	t.Run("nil storage", func(t *testing.T) {
+		if _, err := NewTagUsecase(&MockLogger{}, nil, taskStorage); err == nil {
+			t.Error("Expected error for nil storage")
+		}
+	})
+
+	t.Run("names are normalized", func(t *testing.T) {
+		if _, err := usecase.Store(member, dto.PostTagRequest{Name: "  Backend "}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if storedName != "backend" {
+			t.Errorf("Expected normalized name, got %q", storedName)
+		}
+	})
+
+	t.Run("invalid names", func(t *testing.T) {
+		for _, name := range []string{"", "a,b", "two words"} {
+			if _, err := usecase.Store(member, dto.PostTagRequest{Name: name}); !errors.Is(err, model.ErrInvalid) {
+				t.Errorf("Expected ErrInvalid for %q, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("viewer can't create tags", func(t *testing.T) {
+		if _, err := usecase.Store(viewer, dto.PostTagRequest{Name: "bug"}); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+	})
+
+	t.Run("attach needs write access to the task", func(t *testing.T) {
+		response, err := usecase.Attach(member, 0, 1)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(response.TagIDs) != 1 {
+			t.Errorf("Expected tag in response, got %v", response.TagIDs)
+		}
+		if _, err := usecase.Attach(member, 1, 1); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if _, err := usecase.Detach(member, 9, 1); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
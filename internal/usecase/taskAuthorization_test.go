@@ -0,0 +1,247 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"slices"
+	"testing"
+)
+
+func TestTaskUsecaseAuthorization(t *testing.T) {
+	tasks := map[int]model.Task{
+		0: {Id: 0, Name: "own", Status: model.Created, ReporterID: 7},
+		1: {Id: 1, Name: "foreign", Status: model.Created, ReporterID: 1, AssigneeID: 2},
+	}
+	newUsecase := func(hide bool, rules []auth.Rule) (*TaskUsecase, *[]string) {
+		calls := make([]string, 0)
+		mockStorage := &MockTaskStorage{
+			getByTaskIdFunc: func(ctx context.Context, taskId int) (*model.Task, error) {
+				task, ok := tasks[taskId]
+				if !ok {
+					return nil, model.ErrNotFound
+				}
+				return &task, nil
+			},
+			getAllFunc: func(ctx context.Context, filter model.Filter) ([]model.Task, int, error) {
+				if filter.Paged() {
+					calls = append(calls, "get page")
+					return []model.Task{tasks[filter.Page.Offset]}, 2, nil
+				}
+				return []model.Task{tasks[0], tasks[1]}, 2, nil
+			},
+			storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+				calls = append(calls, "store")
+				return 2, nil
+			},
+			updateFunc: func(ctx context.Context, task model.Task) (*model.Task, error) {
+				calls = append(calls, "update")
+				return &task, nil
+			},
+			deleteFunc: func(ctx context.Context, taskId int) error {
+				calls = append(calls, "delete")
+				return nil
+			},
+			addBlockerFunc: func(ctx context.Context, taskId, blockerId int) (*model.Task, error) {
+				calls = append(calls, "add blocker")
+				task := tasks[taskId]
+				task.BlockedBy = []int{blockerId}
+				return &task, nil
+			},
+			getSubtasksFunc: func(ctx context.Context, taskId, depth int) (*model.TaskNode, error) {
+				return &model.TaskNode{Task: tasks[taskId], Subtasks: []model.TaskNode{{Task: tasks[1]}}}, nil
+			},
+			dependencyFunc: func(ctx context.Context, taskId int) ([]model.Task, error) {
+				return []model.Task{tasks[1], tasks[taskId]}, nil
+			},
+		}
+		usecase, _ := NewTaskUsecase(&MockLogger{}, mockStorage, WithPolicy(auth.NewPolicy(rules, hide)))
+		return usecase, &calls
+	}
+
+	member := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Roles: []string{"member"}})
+	viewer := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Roles: []string{"viewer"}})
+	done := model.Done
+	foreignAssignee := 2
+
+	t.Run("member updates own task", func(t *testing.T) {
+		usecase, calls := newUsecase(false, auth.DefaultRules)
+		response, err := usecase.Update(member, 0, dto.PatchTaskRequest{Status: &done})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Status != model.Done || len(*calls) != 1 {
+			t.Errorf("Expected task to be updated, got %v with calls %v", response, *calls)
+		}
+	})
+
+	t.Run("member can't update foreign task", func(t *testing.T) {
+		usecase, calls := newUsecase(false, auth.DefaultRules)
+		_, err := usecase.Update(member, 1, dto.PatchTaskRequest{Status: &done})
+		if !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if len(*calls) != 0 {
+			t.Errorf("Expected storage not to be called, got %v", *calls)
+		}
+	})
+
+	t.Run("member can't give away a task and keep it", func(t *testing.T) {
+		usecase, _ := newUsecase(false, []auth.Rule{{Role: auth.RoleMember, Permission: auth.TaskWrite, Scope: auth.ScopeOwn}})
+		ctx := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 2, Roles: []string{"member"}})
+		other := 3
+		_, err := usecase.Update(ctx, 1, dto.PatchTaskRequest{AssigneeID: &other})
+		if !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+	})
+
+	t.Run("member can't create tasks for others", func(t *testing.T) {
+		usecase, calls := newUsecase(false, auth.DefaultRules)
+		_, err := usecase.Store(member, dto.PostTaskRequest{Name: "x", Status: model.Created, ReporterID: 1, AssigneeID: foreignAssignee})
+		if !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+
+		if _, err := usecase.Store(member, dto.PostTaskRequest{Name: "x", Status: model.Created}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if len(*calls) != 1 {
+			t.Errorf("Expected a single store call, got %v", *calls)
+		}
+	})
+
+	t.Run("member reports tasks only as themselves", func(t *testing.T) {
+		reporters := make([]int, 0)
+		mockStorage := &MockTaskStorage{
+			storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+				reporters = append(reporters, task.ReporterID)
+				return 0, nil
+			},
+		}
+		usecase, _ := NewTaskUsecase(&MockLogger{}, mockStorage, WithPolicy(auth.NewPolicy(auth.DefaultRules, false)))
+
+		if _, err := usecase.Store(member, dto.PostTaskRequest{Name: "x", Status: model.Created, ReporterID: 1}); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		response, err := usecase.Import(member, []dto.ImportTaskRow{
+			{Row: 1, Request: dto.PostTaskRequest{Name: "x", Status: model.Created, ReporterID: 1}},
+			{Row: 2, Request: dto.PostTaskRequest{Name: "x", Status: model.Created, ReporterID: 7}},
+		}, false)
+		if err != nil || response.Imported != 1 || len(response.Errors) != 1 || response.Errors[0].Row != 1 {
+			t.Errorf("Expected the row of another reporter to fail, got %+v, %v", response, err)
+		}
+		if _, err := usecase.Store(member, dto.PostTaskRequest{Name: "x", Status: model.Created}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		admin := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 9, Roles: []string{"admin"}})
+		if _, err := usecase.Store(admin, dto.PostTaskRequest{Name: "x", Status: model.Created, ReporterID: 1}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !slices.Equal(reporters, []int{7, 7, 1}) {
+			t.Errorf("Expected reporters [7 7 1], got %v", reporters)
+		}
+	})
+
+	t.Run("viewer can't delete", func(t *testing.T) {
+		usecase, calls := newUsecase(false, auth.DefaultRules)
+		if err := usecase.Delete(viewer, 0); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if len(*calls) != 0 {
+			t.Errorf("Expected storage not to be called, got %v", *calls)
+		}
+	})
+
+	t.Run("hidden task looks nonexistent", func(t *testing.T) {
+		rules := []auth.Rule{{Role: auth.RoleMember, Permission: auth.TaskRead, Scope: auth.ScopeOwn}}
+		usecase, _ := newUsecase(true, rules)
+
+		if _, err := usecase.GetByTaskId(member, 1); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+		if err := usecase.Delete(member, 1); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		response, err := usecase.GetAll(member, model.EmptyFilter)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Amount != 1 || response.Tasks[0].Id != 0 {
+			t.Errorf("Expected only own task to be listed, got %v", response.Tasks)
+		}
+	})
+
+	t.Run("page is taken by the storage when every task is readable", func(t *testing.T) {
+		usecase, calls := newUsecase(false, auth.DefaultRules)
+		response, err := usecase.GetAll(member, model.Filter{Page: model.Page{Limit: 1, Offset: 1}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(*calls) != 1 || response.Amount != 1 || response.Tasks[0].Id != 1 || response.Total != 2 {
+			t.Errorf("Expected the second of 2 tasks from the storage, got %+v with calls %v", response, *calls)
+		}
+	})
+
+	t.Run("page of readable tasks is taken after the rest are dropped", func(t *testing.T) {
+		rules := []auth.Rule{{Role: auth.RoleMember, Permission: auth.TaskRead, Scope: auth.ScopeOwn}}
+		usecase, calls := newUsecase(false, rules)
+		response, err := usecase.GetAll(member, model.Filter{Page: model.Page{Limit: 1}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(*calls) != 0 || response.Amount != 1 || response.Tasks[0].Id != 0 || response.Total != 1 {
+			t.Errorf("Expected only own task of 1, got %+v with calls %v", response, *calls)
+		}
+	})
+
+	t.Run("member blocks only own tasks", func(t *testing.T) {
+		usecase, calls := newUsecase(false, auth.DefaultRules)
+		if _, err := usecase.AddBlocker(member, 1, 0); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		response, err := usecase.AddBlocker(member, 0, 1)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(response.BlockedBy) != 1 || len(*calls) != 1 {
+			t.Errorf("Expected blocker to be added once, got %v with calls %v", response, *calls)
+		}
+	})
+
+	t.Run("hidden tasks are left out of graphs", func(t *testing.T) {
+		rules := []auth.Rule{{Role: auth.RoleMember, Permission: auth.TaskRead, Scope: auth.ScopeOwn}}
+		usecase, _ := newUsecase(true, rules)
+
+		tree, err := usecase.GetSubtasks(member, 0, 1)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(tree.Subtasks) != 0 {
+			t.Errorf("Expected foreign subtask to be hidden, got %v", tree.Subtasks)
+		}
+
+		order, err := usecase.DependencyOrder(member, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if order.Amount != 1 || order.Tasks[0].Id != 0 {
+			t.Errorf("Expected only own task in the order, got %v", order.Tasks)
+		}
+
+		if _, err := usecase.GetSubtasks(member, 1, 1); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("no principal", func(t *testing.T) {
+		usecase, _ := newUsecase(false, auth.DefaultRules)
+		if _, err := usecase.GetAll(context.Background(), model.EmptyFilter); !errors.Is(err, model.ErrUnauthenticated) {
+			t.Errorf("Expected ErrUnauthenticated, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/broker"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"ivanjabrony/test_lo/internal/tenant"
+	"slices"
+	"time"
+)
+
+// ChangeBroker delivers committed task changes to subscribers, see broker.Broker
+type ChangeBroker interface {
+	Publish(change model.TaskChange)
+	Subscribe(lastID int) (*broker.Subscription, []model.TaskChange, bool)
+}
+
+// tagLister is implemented by task storages keeping tags, it resolves tag filters of streams
+type tagLister interface {
+	GetAllTags(ctx context.Context) ([]model.Tag, error)
+}
+
+// newTaskChange describes the mutation made at the moment for subscribers, empty status before means the task
+// was created, empty status after means it was deleted
+func newTaskChange(ctx context.Context, before, after model.Task, at time.Time) model.TaskChange {
+	change := model.TaskChange{
+		Type:      model.TaskChangeUpdated,
+		ProjectID: tenant.FromContext(ctx).ProjectID,
+		At:        at.UTC(),
+		Task:      after,
+	}
+	switch {
+	case before.Status == "":
+		change.Type = model.TaskChangeCreated
+	case after.Status == "":
+		change.Type = model.TaskChangeDeleted
+		change.Task = before
+	default:
+		change.Previous = &before
+	}
+	return change
+}
+
+// Watch subscribes to changes of the tasks of the project matching the filter, sorting of the filter is ignored.
+// Changes published after lastEventID are replayed first, 0 means the caller saw nothing yet.
+//
+// An update is sent when the task matched the filter before or after it, so subscribers notice tasks leaving
+// the filter, and only when the caller can read the task after it. The stream ends when ctx is done
+// or the subscriber falls too far behind.
+func (tu *TaskUsecase) Watch(ctx context.Context, filter model.Filter, lastEventID int) (dto.TaskChangeStream, error) {
+	if tu.changeBroker == nil {
+		return dto.TaskChangeStream{}, fmt.Errorf("%v: streaming of task changes: %w", usecaseName, model.ErrUnsupported)
+	}
+	if err := tu.authorize(ctx, auth.TaskRead, nil); err != nil {
+		return dto.TaskChangeStream{}, fmt.Errorf("%v: couldn't watch the tasks: %w", usecaseName, err)
+	}
+	tagIds, err := tu.resolveTags(ctx, filter.Tags)
+	if err != nil {
+		return dto.TaskChangeStream{}, fmt.Errorf("%v: couldn't watch the tasks: %w", usecaseName, err)
+	}
+
+	projectId := tenant.FromContext(ctx).ProjectID
+	matches := func(task model.Task) bool {
+		return filter.Matches(task, tu.clock.Now()) && matchesTags(task.TagIDs, tagIds, filter.TagMatch)
+	}
+	visible := func(change model.TaskChange) bool {
+		if change.ProjectID != projectId || !tu.canRead(ctx, change.Task) {
+			return false
+		}
+		return matches(change.Task) || (change.Previous != nil && matches(*change.Previous))
+	}
+
+	sub, replay, complete := tu.changeBroker.Subscribe(lastEventID)
+	stream := dto.TaskChangeStream{Replay: make([]dto.TaskChangeEvent, 0), Complete: complete}
+	for _, change := range replay {
+		if visible(change) {
+			stream.Replay = append(stream.Replay, mapper.TaskChangeToTaskChangeEvent(change))
+		}
+	}
+
+	events := make(chan dto.TaskChangeEvent)
+	go func() {
+		defer close(events)
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case change, ok := <-sub.Changes():
+				if !ok {
+					return
+				}
+				if !visible(change) {
+					continue
+				}
+				select {
+				case events <- mapper.TaskChangeToTaskChangeEvent(change):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	stream.Events = events
+
+	return stream, nil
+}
+
+// resolveTags returns ids of the named tags of the project, unknown names get id -1 that no task has
+func (tu *TaskUsecase) resolveTags(ctx context.Context, names []string) ([]int, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	lister, ok := tu.taskStorage.(tagLister)
+	if !ok {
+		return nil, fmt.Errorf("tag filters: %w", model.ErrUnsupported)
+	}
+	tags, err := lister.GetAllTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		byName[tag.Name] = tag.Id
+	}
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		id, ok := byName[name]
+		if !ok {
+			id = -1
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// matchesTags reports whether the task tagged with taskTags has any or, with model.TagMatchAll,
+// all of the wanted tags, no wanted tags match every task
+func matchesTags(taskTags, wanted []int, match model.TagMatch) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, id := range wanted {
+		found := slices.Contains(taskTags, id)
+		if found && match != model.TagMatchAll {
+			return true
+		}
+		if !found && match == model.TagMatchAll {
+			return false
+		}
+	}
+	return match == model.TagMatchAll
+}
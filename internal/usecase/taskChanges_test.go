@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/broker"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/tenant"
+	"testing"
+	"time"
+)
+
+// MockTaggedTaskStorage is MockTaskStorage keeping tags
+type MockTaggedTaskStorage struct {
+	*MockTaskStorage
+	tags []model.Tag
+}
+
+func (m *MockTaggedTaskStorage) GetAllTags(ctx context.Context) ([]model.Tag, error) {
+	return m.tags, nil
+}
+
+func newMapTaskStorage() *MockTaskStorage {
+	tasks := make(map[int]model.Task)
+	return &MockTaskStorage{
+		storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+			task.Id = len(tasks)
+			tasks[task.Id] = task
+			return task.Id, nil
+		},
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (*model.Task, error) {
+			task, ok := tasks[taskId]
+			if !ok {
+				return nil, model.ErrNotFound
+			}
+			return &task, nil
+		},
+		updateFunc: func(ctx context.Context, task model.Task) (*model.Task, error) {
+			tasks[task.Id] = task
+			return &task, nil
+		},
+		deleteFunc: func(ctx context.Context, taskId int) error {
+			delete(tasks, taskId)
+			return nil
+		},
+	}
+}
+
+// receive returns the next event of the stream or fails after a second
+func receive(t *testing.T, stream dto.TaskChangeStream) (dto.TaskChangeEvent, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-stream.Events:
+		return event, ok
+	case <-time.After(time.Second):
+		t.Fatalf("No event was received")
+		return dto.TaskChangeEvent{}, false
+	}
+}
+
+func TestTaskChanges(t *testing.T) {
+	changeBroker, _ := broker.NewBroker(&MockLogger{})
+	taskUsecase, _ := NewTaskUsecase(&MockLogger{}, newMapTaskStorage(), WithChangeBroker(changeBroker))
+	ctx := context.Background()
+	done, name := model.Done, "renamed"
+
+	first, _ := taskUsecase.Store(ctx, dto.PostTaskRequest{Name: "first", Status: model.Created})
+	taskUsecase.Update(ctx, first, dto.PatchTaskRequest{Status: &done})
+	taskUsecase.Update(ctx, first, dto.PatchTaskRequest{Status: &done})
+	taskUsecase.Delete(ctx, first)
+
+	t.Run("published changes", func(t *testing.T) {
+		stream, err := taskUsecase.Watch(ctx, model.EmptyFilter, -1)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if stream.Complete {
+			t.Errorf("Expected an incomplete replay for an unknown id")
+		}
+		stream, _ = taskUsecase.Watch(ctx, model.EmptyFilter, 0)
+		if len(stream.Replay) != 0 {
+			t.Errorf("Expected no replay for a new subscriber, got %+v", stream.Replay)
+		}
+		stream, _ = taskUsecase.Watch(ctx, model.EmptyFilter, 1)
+		if !stream.Complete || len(stream.Replay) != 2 {
+			t.Fatalf("Expected 2 replayed changes, got %+v", stream.Replay)
+		}
+		updated, deleted := stream.Replay[0], stream.Replay[1]
+		if updated.ID != 2 || updated.Type != model.TaskChangeUpdated || updated.Task.Status != model.Done || updated.ProjectID != model.DefaultProjectId {
+			t.Errorf("Unexpected update %+v", updated)
+		}
+		if deleted.Type != model.TaskChangeDeleted || deleted.Task.Name != "first" {
+			t.Errorf("Unexpected deletion %+v", deleted)
+		}
+	})
+
+	t.Run("filtered stream", func(t *testing.T) {
+		watchCtx, cancel := context.WithCancel(ctx)
+		stream, _ := taskUsecase.Watch(watchCtx, model.Filter{Status: model.Done}, 0)
+
+		other := tenant.WithScope(ctx, tenant.Scope{ProjectID: 5})
+		taskUsecase.Store(other, dto.PostTaskRequest{Name: "other project", Status: model.Done})
+		taskUsecase.Store(ctx, dto.PostTaskRequest{Name: "open", Status: model.Created})
+		second, _ := taskUsecase.Store(ctx, dto.PostTaskRequest{Name: "second", Status: model.Done})
+		taskUsecase.Update(ctx, second, dto.PatchTaskRequest{Name: &name})
+		reopened := model.InProgress
+		taskUsecase.Update(ctx, second, dto.PatchTaskRequest{Status: &reopened})
+
+		expected := []struct {
+			changeType model.TaskChangeType
+			status     model.TaskStatus
+		}{
+			{model.TaskChangeCreated, model.Done},
+			{model.TaskChangeUpdated, model.Done},
+			{model.TaskChangeUpdated, model.InProgress},
+		}
+		for _, want := range expected {
+			event, _ := receive(t, stream)
+			if event.Type != want.changeType || event.Task.Status != want.status || event.Task.Id != second {
+				t.Errorf("Expected %v of task %v with status %v, got %+v", want.changeType, second, want.status, event)
+			}
+		}
+
+		cancel()
+		if _, ok := receive(t, stream); ok {
+			t.Errorf("Expected the stream to end with the context")
+		}
+	})
+
+	t.Run("unreadable tasks", func(t *testing.T) {
+		ownRead := auth.NewPolicy([]auth.Rule{
+			{Role: auth.RoleMember, Permission: auth.TaskRead, Scope: auth.ScopeOwn},
+			{Role: auth.RoleMember, Permission: auth.TaskWrite, Scope: auth.ScopeAny},
+		}, false)
+		restricted, _ := NewTaskUsecase(&MockLogger{}, newMapTaskStorage(), WithChangeBroker(changeBroker), WithPolicy(ownRead))
+		member := func(userId int) context.Context {
+			return auth.WithPrincipal(ctx, auth.Principal{UserID: userId, Roles: []string{"member"}})
+		}
+		watchCtx, cancel := context.WithCancel(member(7))
+		defer cancel()
+		stream, _ := restricted.Watch(watchCtx, model.EmptyFilter, 0)
+
+		restricted.Store(member(8), dto.PostTaskRequest{Name: "foreign", Status: model.Created})
+		restricted.Store(member(8), dto.PostTaskRequest{Name: "assigned", Status: model.Created, AssigneeID: 7})
+		if event, _ := receive(t, stream); event.Task.Name != "assigned" {
+			t.Errorf("Expected only the assigned task, got %+v", event)
+		}
+	})
+
+	t.Run("tag filters", func(t *testing.T) {
+		if _, err := taskUsecase.Watch(ctx, model.Filter{Tags: []string{"api"}}, 0); !errors.Is(err, model.ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported without tags in storage, got %v", err)
+		}
+		tagged := &MockTaggedTaskStorage{MockTaskStorage: newMapTaskStorage(), tags: []model.Tag{{Id: 1, Name: "api"}, {Id: 2, Name: "ui"}}}
+		tagUsecase, _ := NewTaskUsecase(&MockLogger{}, tagged, WithChangeBroker(changeBroker))
+		tagIds := func(names ...string) []int {
+			ids, _ := tagUsecase.resolveTags(ctx, names)
+			return ids
+		}
+
+		tests := []struct {
+			name     string
+			taskTags []int
+			wanted   []int
+			match    model.TagMatch
+			want     bool
+		}{
+			{name: "no filter", taskTags: nil, wanted: tagIds(), want: true},
+			{name: "any of", taskTags: []int{2}, wanted: tagIds("api", "ui"), want: true},
+			{name: "none of", taskTags: []int{3}, wanted: tagIds("api", "ui"), want: false},
+			{name: "all of", taskTags: []int{1, 2}, wanted: tagIds("api", "ui"), match: model.TagMatchAll, want: true},
+			{name: "not all of", taskTags: []int{1}, wanted: tagIds("api", "ui"), match: model.TagMatchAll, want: false},
+			{name: "unknown tag", taskTags: []int{1}, wanted: tagIds("api", "missing"), match: model.TagMatchAll, want: false},
+		}
+		for _, tt := range tests {
+			if got := matchesTags(tt.taskTags, tt.wanted, tt.match); got != tt.want {
+				t.Errorf("%v: expected %v, got %v", tt.name, tt.want, got)
+			}
+		}
+	})
+
+	t.Run("without broker", func(t *testing.T) {
+		plain, _ := NewTaskUsecase(&MockLogger{}, newMapTaskStorage())
+		if _, err := plain.Watch(ctx, model.EmptyFilter, 0); !errors.Is(err, model.ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", err)
+		}
+	})
+}
@@ -2,57 +2,191 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/clock"
 	"ivanjabrony/test_lo/internal/model"
 	"ivanjabrony/test_lo/internal/model/dto"
 	"ivanjabrony/test_lo/internal/model/mapper"
+	"strconv"
+	"time"
 )
 
 const usecaseName = "TaskUsecase"
 
 type TaskStorage interface {
 	Store(ctx context.Context, task model.Task) (int, error)
-	GetAll(ctx context.Context, filter model.Filter) ([]model.Task, error)
+	GetAll(ctx context.Context, filter model.Filter) ([]model.Task, int, error)
 	GetByTaskId(ctx context.Context, taskId int) (*model.Task, error)
+	ForEach(ctx context.Context, filter model.Filter, fn func(model.Task) error) error
+	Update(ctx context.Context, task model.Task) (*model.Task, error)
+	Delete(ctx context.Context, taskId int) error
+	AddBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error)
+	RemoveBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error)
+	GetSubtasks(ctx context.Context, taskId, depth int) (*model.TaskNode, error)
+	DependencyOrder(ctx context.Context, taskId int) ([]model.Task, error)
+}
+
+// PointInTimeTaskStorage is implemented by task storages that keep past states of tasks
+type PointInTimeTaskStorage interface {
+	GetByTaskIdAsOf(ctx context.Context, taskId int, asOf time.Time) (*model.Task, error)
+}
+
+// PublicIdTaskStorage is implemented by task storages that give tasks public ids
+type PublicIdTaskStorage interface {
+	GetByPublicId(ctx context.Context, publicId string) (*model.Task, error)
 }
 
 type Logger interface {
 	Log(format string, info ...any)
 }
 
+// Clock tells the time due dates are checked against and recurrences start at, see clock.System
+type Clock interface {
+	Now() time.Time
+}
+
 type TaskUsecase struct {
 	logger      Logger
 	taskStorage TaskStorage
+	userStorage UserStorage
+	// commentStorage is optional, with it responses include comment counts and deleted tasks lose their comments
+	commentStorage CommentStorage
+	// auditStorage is optional, with it every mutation is recorded in the audit log
+	auditStorage AuditStorage
+	// changeBroker is optional, with it every mutation is published to subscribers of task changes
+	changeBroker ChangeBroker
+	policy       *auth.Policy
+	clock        Clock
+	// publicIds makes requests refer to other tasks by their public ids
+	publicIds bool
+}
+
+// TaskUsecaseOption configures optional dependencies of TaskUsecase
+type TaskUsecaseOption func(*TaskUsecase)
+
+// WithUserStorage enables checking that assignees and reporters of stored tasks exist
+func WithUserStorage(users UserStorage) TaskUsecaseOption {
+	return func(tu *TaskUsecase) {
+		tu.userStorage = users
+	}
+}
+
+// WithCommentStorage enables comment counts in task responses and deletion of comments together with tasks
+func WithCommentStorage(comments CommentStorage) TaskUsecaseOption {
+	return func(tu *TaskUsecase) {
+		tu.commentStorage = comments
+	}
 }
 
-func NewTaskUsecase(logger Logger, storage TaskStorage) (*TaskUsecase, error) {
+// WithAuditStorage enables recording of every task mutation in the audit log
+func WithAuditStorage(audit AuditStorage) TaskUsecaseOption {
+	return func(tu *TaskUsecase) {
+		tu.auditStorage = audit
+	}
+}
+
+// WithChangeBroker enables publishing of every task mutation and streaming of task changes
+func WithChangeBroker(changeBroker ChangeBroker) TaskUsecaseOption {
+	return func(tu *TaskUsecase) {
+		tu.changeBroker = changeBroker
+	}
+}
+
+// WithClock replaces the system clock, tests use it to control timestamps
+func WithClock(clock Clock) TaskUsecaseOption {
+	return func(tu *TaskUsecase) {
+		tu.clock = clock
+	}
+}
+
+// WithPolicy enables authorization of every action against the principal from the context
+func WithPolicy(policy *auth.Policy) TaskUsecaseOption {
+	return func(tu *TaskUsecase) {
+		tu.policy = policy
+	}
+}
+
+// WithPublicIds makes requests refer to parents by public ids, the storage has to implement PublicIdTaskStorage
+func WithPublicIds() TaskUsecaseOption {
+	return func(tu *TaskUsecase) {
+		tu.publicIds = true
+	}
+}
+
+func NewTaskUsecase(logger Logger, storage TaskStorage, opts ...TaskUsecaseOption) (*TaskUsecase, error) {
 	if storage == nil {
 		return nil, fmt.Errorf("nil values in %v constructor", usecaseName)
 	}
 
+	tu := &TaskUsecase{logger: logger, taskStorage: storage, clock: clock.System{}}
+	for _, opt := range opts {
+		opt(tu)
+	}
+
 	logger.Log("Created %s successfully", usecaseName)
-	return &TaskUsecase{taskStorage: storage}, nil
+	return tu, nil
 }
 
 func (tu *TaskUsecase) Store(ctx context.Context, request dto.PostTaskRequest) (int, error) {
-	task := mapper.PostTaskRequestToTask(request)
-	if err := model.ValidateTask(task); err != nil {
+	var err error
+	if request.ParentID, err = tu.resolveRef(ctx, request.ParentID); err != nil {
 		return -1, fmt.Errorf("%v: couldn't store the task: %w", usecaseName, err)
 	}
+	task, err := tu.withReporter(ctx, mapper.PostTaskRequestToTask(request))
+	if err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the task: %w", usecaseName, err)
+	}
+	if err := tu.authorize(ctx, auth.TaskWrite, &task); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the task: %w", usecaseName, err)
+	}
+	now := tu.clock.Now()
+	if task, err = withRecurrence(task, nil, now); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the task: %w", usecaseName, err)
+	}
+	if err := tu.validateTask(ctx, task); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the task: %w", usecaseName, err)
+	}
+	if err := model.ValidateDueAt(task.DueAt, now, request.AllowPastDue); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the task: %w", usecaseName, model.Invalid(err))
+	}
 	id, err := tu.taskStorage.Store(ctx, task)
 	if err != nil {
 		return -1, fmt.Errorf("%v: couldn't store the task: %w", usecaseName, err)
 	}
+	tu.recordCreate(ctx, id, task)
 	return id, nil
 }
 
 func (tu *TaskUsecase) GetAll(ctx context.Context, filter model.Filter) (dto.GetAllTasksResponse, error) {
-	tasks, err := tu.taskStorage.GetAll(ctx, filter)
+	if err := tu.authorize(ctx, auth.TaskRead, nil); err != nil {
+		return dto.GetAllTasksResponse{}, fmt.Errorf("%v: couldn't get all the tasks: %w", usecaseName, err)
+	}
+	page, paged := filter.Page, filter.Paged()
+	readsEvery := tu.readsEveryTask(ctx)
+	if !readsEvery {
+		// tasks the caller can't read are dropped before paging, so the storage can't page them
+		filter.Page = model.Page{}
+	}
+	tasks, total, err := tu.taskStorage.GetAll(ctx, filter)
 	if err != nil {
 		return dto.GetAllTasksResponse{}, fmt.Errorf("%v: couldn't get all the tasks: %w", usecaseName, err)
 	}
+	if !readsEvery {
+		tasks = tu.readable(ctx, tasks)
+		total = len(tasks)
+		if paged {
+			start := min(page.Offset, total)
+			tasks = tasks[start:min(start+page.Limit, total)]
+		}
+	}
 
-	return mapper.TasksToGetAllTasksResponse(tasks), nil
+	response := mapper.TasksToGetAllTasksResponse(tasks)
+	if paged {
+		response.Total, response.Limit, response.Offset = total, page.Limit, page.Offset
+	}
+	return response, nil
 }
 
 func (tu *TaskUsecase) GetByTaskId(ctx context.Context, taskId int) (dto.GetTaskByIdResponse, error) {
@@ -60,7 +194,498 @@ func (tu *TaskUsecase) GetByTaskId(ctx context.Context, taskId int) (dto.GetTask
 	if err != nil {
 		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: %w", usecaseName, err)
 	}
-	response := mapper.TaskToGetTaskByIdReponse(*task)
+	if err := tu.authorize(ctx, auth.TaskRead, task); err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: task(%v): %w", usecaseName, taskId, err)
+	}
+	response := tu.taskResponse(ctx, *task)
 
 	return response, err
 }
+
+// ResolvePublicId returns the id of the task with the public id, the storage has to implement
+// PublicIdTaskStorage and the caller needs read access to the task. Tasks stored before public ids
+// were given have none, they are found by their id written in decimal.
+func (tu *TaskUsecase) ResolvePublicId(ctx context.Context, publicId string) (int, error) {
+	task, err := tu.publicTask(ctx, publicId)
+	if err != nil {
+		return -1, fmt.Errorf("%v: %w", usecaseName, err)
+	}
+	if err := tu.authorize(ctx, auth.TaskRead, task); err != nil {
+		return -1, fmt.Errorf("%v: task(%q): %w", usecaseName, publicId, err)
+	}
+
+	return task.Id, nil
+}
+
+// GetByTaskIdAsOf returns the task as it was at the moment, the storage has to implement
+// PointInTimeTaskStorage. Access is checked against the past state, and the response has no comment
+// count since comments aren't kept in the past.
+func (tu *TaskUsecase) GetByTaskIdAsOf(ctx context.Context, taskId int, asOf time.Time) (dto.GetTaskByIdResponse, error) {
+	storage, ok := tu.taskStorage.(PointInTimeTaskStorage)
+	if !ok {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: point-in-time reads: %w", usecaseName, model.ErrUnsupported)
+	}
+	task, err := storage.GetByTaskIdAsOf(ctx, taskId, asOf)
+	if err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: %w", usecaseName, err)
+	}
+	if err := tu.authorize(ctx, auth.TaskRead, task); err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: task(%v): %w", usecaseName, taskId, err)
+	}
+
+	return mapper.TaskToGetTaskByIdReponse(*task, tu.clock.Now()), nil
+}
+
+// Update applies the patch to the task, the caller needs write access to both the current
+// and the resulting task, so a member can't hand over a task and keep editing it
+func (tu *TaskUsecase) Update(ctx context.Context, taskId int, request dto.PatchTaskRequest) (dto.GetTaskByIdResponse, error) {
+	current, err := tu.taskStorage.GetByTaskId(ctx, taskId)
+	if err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't update the task: %w", usecaseName, err)
+	}
+	if request.ParentID, err = tu.resolveRef(ctx, request.ParentID); err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't update the task(%v): %w", usecaseName, taskId, err)
+	}
+	if err := tu.authorize(ctx, auth.TaskWrite, current); err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't update the task(%v): %w", usecaseName, taskId, err)
+	}
+
+	task := mapper.ApplyPatchTaskRequest(*current, request)
+	if err := tu.authorize(ctx, auth.TaskWrite, &task); err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't update the task(%v): %w", usecaseName, taskId, err)
+	}
+	now := tu.clock.Now()
+	if task, err = withRecurrence(task, current.Recurrence, now); err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't update the task: %w", usecaseName, err)
+	}
+	if err := tu.validateTask(ctx, task); err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't update the task: %w", usecaseName, err)
+	}
+	if request.DueAt != nil {
+		if err := model.ValidateDueAt(task.DueAt, now, request.AllowPastDue); err != nil {
+			return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't update the task: %w", usecaseName, model.Invalid(err))
+		}
+	}
+
+	updated, err := tu.taskStorage.Update(ctx, task)
+	if err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't update the task: %w", usecaseName, err)
+	}
+	tu.record(ctx, *current, *updated)
+	return tu.taskResponse(ctx, *updated), nil
+}
+
+func (tu *TaskUsecase) Delete(ctx context.Context, taskId int) error {
+	task, err := tu.taskStorage.GetByTaskId(ctx, taskId)
+	if err != nil {
+		return fmt.Errorf("%v: couldn't delete the task: %w", usecaseName, err)
+	}
+	if err := tu.authorize(ctx, auth.TaskDelete, task); err != nil {
+		return fmt.Errorf("%v: couldn't delete the task(%v): %w", usecaseName, taskId, err)
+	}
+
+	if err := tu.taskStorage.Delete(ctx, taskId); err != nil {
+		return fmt.Errorf("%v: couldn't delete the task: %w", usecaseName, err)
+	}
+	tu.record(ctx, *task, model.Task{Id: taskId})
+	if tu.commentStorage != nil {
+		if _, err := tu.commentStorage.DeleteByTaskId(ctx, taskId); err != nil {
+			return fmt.Errorf("%v: couldn't delete comments of the task(%v): %w", usecaseName, taskId, err)
+		}
+	}
+	return nil
+}
+
+// AddBlocker makes one task block another, the caller needs write access to the blocked task
+func (tu *TaskUsecase) AddBlocker(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error) {
+	current, err := tu.writableTask(ctx, taskId)
+	if err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't add blocker(%v) to the task(%v): %w", usecaseName, blockerId, taskId, err)
+	}
+	task, err := tu.taskStorage.AddBlocker(ctx, taskId, blockerId)
+	if err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't add blocker(%v) to the task(%v): %w", usecaseName, blockerId, taskId, err)
+	}
+	tu.record(ctx, *current, *task)
+	return tu.taskResponse(ctx, *task), nil
+}
+
+func (tu *TaskUsecase) RemoveBlocker(ctx context.Context, taskId, blockerId int) (dto.GetTaskByIdResponse, error) {
+	current, err := tu.writableTask(ctx, taskId)
+	if err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't remove blocker(%v) of the task(%v): %w", usecaseName, blockerId, taskId, err)
+	}
+	task, err := tu.taskStorage.RemoveBlocker(ctx, taskId, blockerId)
+	if err != nil {
+		return dto.GetTaskByIdResponse{}, fmt.Errorf("%v: couldn't remove blocker(%v) of the task(%v): %w", usecaseName, blockerId, taskId, err)
+	}
+	tu.record(ctx, *current, *task)
+	return tu.taskResponse(ctx, *task), nil
+}
+
+// GetSubtasks returns the task tree down to depth levels, subtrees the caller can't read are left out
+func (tu *TaskUsecase) GetSubtasks(ctx context.Context, taskId, depth int) (dto.GetSubtasksResponse, error) {
+	node, err := tu.taskStorage.GetSubtasks(ctx, taskId, depth)
+	if err != nil {
+		return dto.GetSubtasksResponse{}, fmt.Errorf("%v: couldn't get subtasks of the task(%v): %w", usecaseName, taskId, err)
+	}
+	if err := tu.authorize(ctx, auth.TaskRead, &node.Task); err != nil {
+		return dto.GetSubtasksResponse{}, fmt.Errorf("%v: task(%v): %w", usecaseName, taskId, err)
+	}
+
+	return mapper.TaskNodeToGetSubtasksResponse(tu.readableTree(ctx, *node), tu.clock.Now()), nil
+}
+
+// DependencyOrder returns the task and all of its blockers in an order they can be done in
+func (tu *TaskUsecase) DependencyOrder(ctx context.Context, taskId int) (dto.GetDependencyOrderResponse, error) {
+	tasks, err := tu.taskStorage.DependencyOrder(ctx, taskId)
+	if err != nil {
+		return dto.GetDependencyOrderResponse{}, fmt.Errorf("%v: couldn't order dependencies of the task(%v): %w", usecaseName, taskId, err)
+	}
+	if err := tu.authorize(ctx, auth.TaskRead, &tasks[len(tasks)-1]); err != nil {
+		return dto.GetDependencyOrderResponse{}, fmt.Errorf("%v: task(%v): %w", usecaseName, taskId, err)
+	}
+
+	return mapper.TasksToGetDependencyOrderResponse(tu.readable(ctx, tasks), tu.clock.Now()), nil
+}
+
+func (tu *TaskUsecase) Export(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+	if err := tu.authorize(ctx, auth.TaskRead, nil); err != nil {
+		return fmt.Errorf("%v: couldn't export tasks: %w", usecaseName, err)
+	}
+	err := tu.taskStorage.ForEach(ctx, filter, func(task model.Task) error {
+		if !tu.canRead(ctx, task) {
+			return nil
+		}
+		return fn(task)
+	})
+	if err != nil {
+		return fmt.Errorf("%v: couldn't export tasks: %w", usecaseName, err)
+	}
+	return nil
+}
+
+// Import validates every row and stores the valid ones unless dryRun is set.
+//
+// Invalid rows don't abort the import, they are reported in the response instead.
+// Due dates in the past are accepted, imported tasks are usually history.
+func (tu *TaskUsecase) Import(ctx context.Context, rows []dto.ImportTaskRow, dryRun bool) (dto.ImportTasksResponse, error) {
+	response := dto.ImportTasksResponse{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Ids:    make([]dto.TaskRef, 0, len(rows)),
+		Errors: make([]dto.ImportRowError, 0),
+	}
+
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return response, fmt.Errorf("%v: couldn't import tasks: %w", usecaseName, err)
+		}
+
+		parent, err := tu.resolveRef(ctx, row.Request.ParentID)
+		if err != nil {
+			response.Errors = append(response.Errors, dto.ImportRowError{Row: row.Row, Error: err.Error()})
+			continue
+		}
+		row.Request.ParentID = parent
+		task, err := tu.withReporter(ctx, mapper.PostTaskRequestToTask(row.Request))
+		if err != nil {
+			response.Errors = append(response.Errors, dto.ImportRowError{Row: row.Row, Error: err.Error()})
+			continue
+		}
+		if err := tu.authorize(ctx, auth.TaskWrite, &task); err != nil {
+			response.Errors = append(response.Errors, dto.ImportRowError{Row: row.Row, Error: err.Error()})
+			continue
+		}
+		if task, err = withRecurrence(task, nil, tu.clock.Now()); err != nil {
+			response.Errors = append(response.Errors, dto.ImportRowError{Row: row.Row, Error: err.Error()})
+			continue
+		}
+		if err := tu.validateTask(ctx, task); err != nil {
+			response.Errors = append(response.Errors, dto.ImportRowError{Row: row.Row, Error: err.Error()})
+			continue
+		}
+		if dryRun {
+			response.Imported++
+			continue
+		}
+
+		id, err := tu.taskStorage.Store(ctx, task)
+		if err != nil {
+			response.Errors = append(response.Errors, dto.ImportRowError{Row: row.Row, Error: err.Error()})
+			continue
+		}
+		tu.recordCreate(ctx, id, task)
+		response.Ids = append(response.Ids, tu.taskRef(ctx, id))
+		response.Imported++
+	}
+	response.Failed = len(response.Errors)
+
+	return response, nil
+}
+
+// authorize checks the permission if the usecase has a policy, task may be nil
+func (tu *TaskUsecase) authorize(ctx context.Context, permission auth.Permission, task *model.Task) error {
+	if tu.policy == nil {
+		return nil
+	}
+	return tu.policy.Authorize(ctx, permission, task)
+}
+
+// publicTask returns the task with the public id, or the task without one whose id is the public id in decimal
+func (tu *TaskUsecase) publicTask(ctx context.Context, publicId string) (*model.Task, error) {
+	storage, ok := tu.taskStorage.(PublicIdTaskStorage)
+	if !ok {
+		return nil, fmt.Errorf("public ids: %w", model.ErrUnsupported)
+	}
+	task, err := storage.GetByPublicId(ctx, publicId)
+	if !errors.Is(err, model.ErrNotFound) {
+		return task, err
+	}
+	taskId, convErr := strconv.Atoi(publicId)
+	if convErr != nil || strconv.Itoa(taskId) != publicId {
+		return nil, err
+	}
+	if stored, getErr := tu.taskStorage.GetByTaskId(ctx, taskId); getErr == nil && stored.PublicID == "" {
+		return stored, nil
+	}
+	return nil, err
+}
+
+// resolveRef sets the id of the parent the ref points to. With public ids a task is referred to by its public id,
+// or by its id while it has none. Without them it's referred to by its id, a string holds the id in decimal.
+// Refs to missing tasks are invalid.
+func (tu *TaskUsecase) resolveRef(ctx context.Context, ref *dto.TaskRef) (*dto.TaskRef, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	if !tu.publicIds {
+		if ref.PublicID == "" || ref.Id != 0 {
+			return ref, nil
+		}
+		taskId, err := strconv.Atoi(ref.PublicID)
+		if err != nil {
+			return nil, model.Invalid(fmt.Errorf("invalid parent_id: task ids are numbers, got %q", ref.PublicID))
+		}
+		return &dto.TaskRef{Id: taskId}, nil
+	}
+
+	var task *model.Task
+	var err error
+	if ref.PublicID != "" {
+		task, err = tu.publicTask(ctx, ref.PublicID)
+	} else if task, err = tu.taskStorage.GetByTaskId(ctx, ref.Id); err == nil && task.PublicID != "" {
+		task, err = nil, model.ErrNotFound
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, model.Invalid(fmt.Errorf("parent task(%v) doesn't exist", publicRef(*ref)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &dto.TaskRef{Id: task.Id, PublicID: task.PublicID}, nil
+}
+
+// taskRef refers to the stored task the way clients see it
+func (tu *TaskUsecase) taskRef(ctx context.Context, taskId int) dto.TaskRef {
+	ref := dto.TaskRef{Id: taskId}
+	if !tu.publicIds {
+		return ref
+	}
+	if task, err := tu.taskStorage.GetByTaskId(ctx, taskId); err == nil {
+		ref.PublicID = task.PublicID
+	}
+	return ref
+}
+
+// publicRef is the ref as it's written in requests
+func publicRef(ref dto.TaskRef) string {
+	if ref.PublicID != "" {
+		return strconv.Quote(ref.PublicID)
+	}
+	return strconv.Itoa(ref.Id)
+}
+
+// writableTask returns the task if it exists and the caller may change it
+func (tu *TaskUsecase) writableTask(ctx context.Context, taskId int) (*model.Task, error) {
+	task, err := tu.taskStorage.GetByTaskId(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	if err := tu.authorize(ctx, auth.TaskWrite, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (tu *TaskUsecase) canRead(ctx context.Context, task model.Task) bool {
+	return tu.authorize(ctx, auth.TaskRead, &task) == nil
+}
+
+// readsEveryTask reports whether the caller may read every task, so lists don't need to be checked task by task
+func (tu *TaskUsecase) readsEveryTask(ctx context.Context) bool {
+	if tu.policy == nil {
+		return true
+	}
+	principal, ok := auth.FromContext(ctx)
+	return ok && tu.policy.AllowedOnEveryTask(principal, auth.TaskRead)
+}
+
+// readable drops the tasks the caller isn't allowed to read
+func (tu *TaskUsecase) readable(ctx context.Context, tasks []model.Task) []model.Task {
+	if tu.policy == nil {
+		return tasks
+	}
+	ans := make([]model.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if tu.canRead(ctx, task) {
+			ans = append(ans, task)
+		}
+	}
+	return ans
+}
+
+// recordCreate records a stored task, the task is read back to include the fields set by the storage
+func (tu *TaskUsecase) recordCreate(ctx context.Context, id int, task model.Task) {
+	if tu.auditStorage == nil && tu.changeBroker == nil {
+		return
+	}
+	task.Id = id
+	if stored, err := tu.taskStorage.GetByTaskId(ctx, id); err == nil {
+		task = *stored
+	}
+	tu.record(ctx, model.Task{Id: id}, task)
+}
+
+// record appends the mutation of the task to the audit log and publishes it to subscribers,
+// updates that change nothing aren't recorded. The mutation has already happened, so a failure is only logged.
+func (tu *TaskUsecase) record(ctx context.Context, before, after model.Task) {
+	if tu.auditStorage == nil && tu.changeBroker == nil {
+		return
+	}
+	changes := model.DiffTasks(before, after)
+	if len(changes) == 0 {
+		return
+	}
+	if tu.changeBroker != nil {
+		tu.changeBroker.Publish(newTaskChange(ctx, before, after, tu.clock.Now()))
+	}
+	if tu.auditStorage == nil {
+		return
+	}
+
+	if _, err := tu.auditStorage.Append(ctx, newAuditEntry(ctx, before, after, changes)); err != nil {
+		tu.logger.Log("error in %v: couldn't record the change of the task(%v): %v", usecaseName, after.Id, err)
+	}
+}
+
+// taskResponse maps the task as of the clock and adds its comment count, a failed count is logged and left zero
+func (tu *TaskUsecase) taskResponse(ctx context.Context, task model.Task) dto.GetTaskByIdResponse {
+	response := mapper.TaskToGetTaskByIdReponse(task, tu.clock.Now())
+	if tu.commentStorage == nil {
+		return response
+	}
+	count, err := tu.commentStorage.Count(ctx, task.Id)
+	if err != nil {
+		tu.logger.Log("error in %v: couldn't count comments of the task(%v): %v", usecaseName, task.Id, err)
+		return response
+	}
+	response.CommentCount = count
+	return response
+}
+
+// readableTree drops the subtrees of the node the caller isn't allowed to read
+func (tu *TaskUsecase) readableTree(ctx context.Context, node model.TaskNode) model.TaskNode {
+	if tu.policy == nil {
+		return node
+	}
+	subtasks := make([]model.TaskNode, 0, len(node.Subtasks))
+	for _, subtask := range node.Subtasks {
+		if tu.canRead(ctx, subtask.Task) {
+			subtasks = append(subtasks, tu.readableTree(ctx, subtask))
+		}
+	}
+	node.Subtasks = subtasks
+	return node
+}
+
+// withReporter makes the authenticated user a reporter of the task. Only principals who may write every task
+// may report it on behalf of another user, others get model.ErrForbidden for a reporter other than themselves.
+func (tu *TaskUsecase) withReporter(ctx context.Context, task model.Task) (model.Task, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return task, nil
+	}
+	if tu.policy == nil || tu.policy.AllowedOnEveryTask(principal, auth.TaskWrite) {
+		if task.ReporterID == model.NoUser {
+			task.ReporterID = principal.UserID
+		}
+		return task, nil
+	}
+	if task.ReporterID != model.NoUser && task.ReporterID != principal.UserID {
+		return task, fmt.Errorf("reporting a task on behalf of user %d: %w", task.ReporterID, model.ErrForbidden)
+	}
+	task.ReporterID = principal.UserID
+	return task, nil
+}
+
+// withRecurrence completes the schedule of a recurring task. A new schedule starts at the due date,
+// or now if there is none, a changed one keeps the previous start unless it sets its own, and a task
+// without a due date is due at the next occurrence.
+func withRecurrence(task model.Task, previous *model.Recurrence, now time.Time) (model.Task, error) {
+	if task.Recurrence == nil {
+		return task, nil
+	}
+	recurrence := *task.Recurrence
+	if recurrence.Start.IsZero() {
+		switch {
+		case previous != nil:
+			recurrence.Start = previous.Start
+		case !task.DueAt.IsZero():
+			recurrence.Start = task.DueAt
+		default:
+			recurrence.Start = now.Truncate(time.Minute)
+		}
+	}
+	task.Recurrence = &recurrence
+	if err := model.ValidateRecurrence(task.Recurrence); err != nil {
+		return task, model.Invalid(err)
+	}
+	if task.DueAt.IsZero() {
+		schedule, _ := recurrence.Schedule()
+		if task.DueAt = schedule.Next(now); task.DueAt.IsZero() {
+			return task, model.Invalid(errors.New("invalid recurrence in task: the rule has no occurrences after now"))
+		}
+	}
+	return task, nil
+}
+
+// validateTask validates task fields and checks that the referenced users exist
+func (tu *TaskUsecase) validateTask(ctx context.Context, task model.Task) error {
+	if err := model.ValidateTask(task); err != nil {
+		return model.Invalid(err)
+	}
+	if tu.userStorage == nil {
+		return nil
+	}
+
+	references := []struct {
+		role   string
+		userId int
+	}{
+		{"assignee", task.AssigneeID},
+		{"reporter", task.ReporterID},
+	}
+	for _, ref := range references {
+		if ref.userId == model.NoUser {
+			continue
+		}
+		if _, err := tu.userStorage.GetByUserId(ctx, ref.userId); err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return fmt.Errorf("%w: %v with id(%v) doesn't exist", model.ErrInvalid, ref.role, ref.userId)
+			}
+			return err
+		}
+	}
+	return nil
+}
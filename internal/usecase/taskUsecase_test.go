@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/clock"
 	"ivanjabrony/test_lo/internal/model"
 	"ivanjabrony/test_lo/internal/model/dto"
 	"testing"
@@ -12,16 +14,23 @@ import (
 
 // MockTaskStorage is a mock implementation of TaskStorage for testing
 type MockTaskStorage struct {
-	storeFunc       func(ctx context.Context, task model.Task) (int, error)
-	getAllFunc      func(ctx context.Context, filter model.Filter) ([]model.Task, error)
-	getByTaskIdFunc func(ctx context.Context, taskId int) (*model.Task, error)
+	storeFunc         func(ctx context.Context, task model.Task) (int, error)
+	getAllFunc        func(ctx context.Context, filter model.Filter) ([]model.Task, int, error)
+	getByTaskIdFunc   func(ctx context.Context, taskId int) (*model.Task, error)
+	forEachFunc       func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error
+	updateFunc        func(ctx context.Context, task model.Task) (*model.Task, error)
+	deleteFunc        func(ctx context.Context, taskId int) error
+	addBlockerFunc    func(ctx context.Context, taskId, blockerId int) (*model.Task, error)
+	removeBlockerFunc func(ctx context.Context, taskId, blockerId int) (*model.Task, error)
+	getSubtasksFunc   func(ctx context.Context, taskId, depth int) (*model.TaskNode, error)
+	dependencyFunc    func(ctx context.Context, taskId int) ([]model.Task, error)
 }
 
 func (m *MockTaskStorage) Store(ctx context.Context, task model.Task) (int, error) {
 	return m.storeFunc(ctx, task)
 }
 
-func (m *MockTaskStorage) GetAll(ctx context.Context, filter model.Filter) ([]model.Task, error) {
+func (m *MockTaskStorage) GetAll(ctx context.Context, filter model.Filter) ([]model.Task, int, error) {
 	return m.getAllFunc(ctx, filter)
 }
 
@@ -29,6 +38,34 @@ func (m *MockTaskStorage) GetByTaskId(ctx context.Context, taskId int) (*model.T
 	return m.getByTaskIdFunc(ctx, taskId)
 }
 
+func (m *MockTaskStorage) ForEach(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+	return m.forEachFunc(ctx, filter, fn)
+}
+
+func (m *MockTaskStorage) Update(ctx context.Context, task model.Task) (*model.Task, error) {
+	return m.updateFunc(ctx, task)
+}
+
+func (m *MockTaskStorage) Delete(ctx context.Context, taskId int) error {
+	return m.deleteFunc(ctx, taskId)
+}
+
+func (m *MockTaskStorage) AddBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error) {
+	return m.addBlockerFunc(ctx, taskId, blockerId)
+}
+
+func (m *MockTaskStorage) RemoveBlocker(ctx context.Context, taskId, blockerId int) (*model.Task, error) {
+	return m.removeBlockerFunc(ctx, taskId, blockerId)
+}
+
+func (m *MockTaskStorage) GetSubtasks(ctx context.Context, taskId, depth int) (*model.TaskNode, error) {
+	return m.getSubtasksFunc(ctx, taskId, depth)
+}
+
+func (m *MockTaskStorage) DependencyOrder(ctx context.Context, taskId int) ([]model.Task, error) {
+	return m.dependencyFunc(ctx, taskId)
+}
+
 type MockLogger struct {
 	logs []string
 }
@@ -210,11 +247,11 @@ func TestGetAll(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockLogger := &MockLogger{}
 			mockStorage := &MockTaskStorage{
-				getAllFunc: func(ctx context.Context, filter model.Filter) ([]model.Task, error) {
+				getAllFunc: func(ctx context.Context, filter model.Filter) ([]model.Task, int, error) {
 					if filter.Status != tt.filter.Status {
 						t.Errorf("Filter mismatch. Expected %q, got %q", tt.filter.Status, filter.Status)
 					}
-					return tt.storageTasks, tt.storageError
+					return tt.storageTasks, len(tt.storageTasks), tt.storageError
 				},
 			}
 
@@ -323,3 +360,626 @@ func TestGetByTaskId(t *testing.T) {
 		})
 	}
 }
+
+// MockPointInTimeStorage is MockTaskStorage able to read past states of tasks
+type MockPointInTimeStorage struct {
+	MockTaskStorage
+	asOfFunc func(ctx context.Context, taskId int, asOf time.Time) (*model.Task, error)
+}
+
+func (m *MockPointInTimeStorage) GetByTaskIdAsOf(ctx context.Context, taskId int, asOf time.Time) (*model.Task, error) {
+	return m.asOfFunc(ctx, taskId, asOf)
+}
+
+func TestGetByTaskIdAsOf(t *testing.T) {
+	asOf := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := model.Task{Id: 3, Name: "old name", Status: model.Created, ReporterID: 7}
+	history := &MockPointInTimeStorage{
+		asOfFunc: func(ctx context.Context, taskId int, got time.Time) (*model.Task, error) {
+			if taskId != past.Id || !got.Equal(asOf) {
+				return nil, model.ErrNotFound
+			}
+			task := past
+			return &task, nil
+		},
+	}
+	ownReadRules := []auth.Rule{{Role: auth.RoleMember, Permission: auth.TaskRead, Scope: auth.ScopeOwn}}
+	member := func(userId int) context.Context {
+		return auth.WithPrincipal(context.Background(), auth.Principal{UserID: userId, Roles: []string{"member"}})
+	}
+
+	tests := []struct {
+		name     string
+		storage  TaskStorage
+		opts     []TaskUsecaseOption
+		ctx      context.Context
+		taskId   int
+		wantName string
+		wantErr  error
+	}{
+		{
+			name:     "past state",
+			storage:  history,
+			ctx:      context.Background(),
+			taskId:   3,
+			wantName: "old name",
+		},
+		{
+			name:    "task didn't exist",
+			storage: history,
+			ctx:     context.Background(),
+			taskId:  4,
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name:    "storage without history",
+			storage: &MockTaskStorage{},
+			ctx:     context.Background(),
+			taskId:  3,
+			wantErr: model.ErrUnsupported,
+		},
+		{
+			name:     "reporter of the past state",
+			storage:  history,
+			opts:     []TaskUsecaseOption{WithPolicy(auth.NewPolicy(ownReadRules, false))},
+			ctx:      member(7),
+			taskId:   3,
+			wantName: "old name",
+		},
+		{
+			name:    "stranger",
+			storage: history,
+			opts:    []TaskUsecaseOption{WithPolicy(auth.NewPolicy(ownReadRules, false))},
+			ctx:     member(8),
+			taskId:  3,
+			wantErr: model.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase, _ := NewTaskUsecase(&MockLogger{}, tt.storage, tt.opts...)
+			got, err := usecase.GetByTaskIdAsOf(tt.ctx, tt.taskId, asOf)
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got.Name != tt.wantName {
+				t.Errorf("Expected name %q, got %q", tt.wantName, got.Name)
+			}
+		})
+	}
+}
+
+// MockPublicIdStorage is MockTaskStorage giving tasks public ids
+type MockPublicIdStorage struct {
+	MockTaskStorage
+	getByPublicIdFunc func(ctx context.Context, publicId string) (*model.Task, error)
+}
+
+func (m *MockPublicIdStorage) GetByPublicId(ctx context.Context, publicId string) (*model.Task, error) {
+	return m.getByPublicIdFunc(ctx, publicId)
+}
+
+func TestResolvePublicId(t *testing.T) {
+	stored := model.Task{Id: 3, PublicID: "0190a4b2-7c1e-7000-8000-5f2a9c3d4e6b", Status: model.Created, ReporterID: 7}
+	// legacy is stored before public ids were enabled
+	legacy := model.Task{Id: 4, Status: model.Created}
+	storage := &MockPublicIdStorage{
+		MockTaskStorage: MockTaskStorage{
+			getByTaskIdFunc: func(ctx context.Context, taskId int) (*model.Task, error) {
+				for _, task := range []model.Task{stored, legacy} {
+					if task.Id == taskId {
+						return &task, nil
+					}
+				}
+				return nil, model.ErrNotFound
+			},
+		},
+		getByPublicIdFunc: func(ctx context.Context, publicId string) (*model.Task, error) {
+			if publicId != stored.PublicID {
+				return nil, model.ErrNotFound
+			}
+			task := stored
+			return &task, nil
+		},
+	}
+	ownReadRules := []auth.Rule{{Role: auth.RoleMember, Permission: auth.TaskRead, Scope: auth.ScopeOwn}}
+	member := func(userId int) context.Context {
+		return auth.WithPrincipal(context.Background(), auth.Principal{UserID: userId, Roles: []string{"member"}})
+	}
+
+	tests := []struct {
+		name     string
+		storage  TaskStorage
+		opts     []TaskUsecaseOption
+		ctx      context.Context
+		publicId string
+		wantId   int
+		wantErr  error
+	}{
+		{
+			name:     "known public id",
+			storage:  storage,
+			ctx:      context.Background(),
+			publicId: stored.PublicID,
+			wantId:   3,
+		},
+		{
+			name:     "unknown public id",
+			storage:  storage,
+			ctx:      context.Background(),
+			publicId: "missing",
+			wantId:   -1,
+			wantErr:  model.ErrNotFound,
+		},
+		{
+			name:     "id of a task without a public id",
+			storage:  storage,
+			ctx:      context.Background(),
+			publicId: "4",
+			wantId:   4,
+		},
+		{
+			name:     "id of a task with a public id",
+			storage:  storage,
+			ctx:      context.Background(),
+			publicId: "3",
+			wantId:   -1,
+			wantErr:  model.ErrNotFound,
+		},
+		{
+			name:     "id not in decimal",
+			storage:  storage,
+			ctx:      context.Background(),
+			publicId: "04",
+			wantId:   -1,
+			wantErr:  model.ErrNotFound,
+		},
+		{
+			name:     "storage without public ids",
+			storage:  &MockTaskStorage{},
+			ctx:      context.Background(),
+			publicId: stored.PublicID,
+			wantId:   -1,
+			wantErr:  model.ErrUnsupported,
+		},
+		{
+			name:     "reporter",
+			storage:  storage,
+			opts:     []TaskUsecaseOption{WithPolicy(auth.NewPolicy(ownReadRules, false))},
+			ctx:      member(7),
+			publicId: stored.PublicID,
+			wantId:   3,
+		},
+		{
+			name:     "stranger",
+			storage:  storage,
+			opts:     []TaskUsecaseOption{WithPolicy(auth.NewPolicy(ownReadRules, false))},
+			ctx:      member(8),
+			publicId: stored.PublicID,
+			wantId:   -1,
+			wantErr:  model.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase, _ := NewTaskUsecase(&MockLogger{}, tt.storage, tt.opts...)
+			got, err := usecase.ResolvePublicId(tt.ctx, tt.publicId)
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.wantId {
+				t.Errorf("Expected id %v, got %v", tt.wantId, got)
+			}
+		})
+	}
+}
+
+func TestStoreParentRef(t *testing.T) {
+	// 3 has a public id, 4 is stored before public ids were enabled
+	tasks := []model.Task{{Id: 3, PublicID: "p3", Status: model.Created}, {Id: 4, Status: model.Created}}
+	newStorage := func(parentId **int) *MockPublicIdStorage {
+		return &MockPublicIdStorage{
+			MockTaskStorage: MockTaskStorage{
+				storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+					*parentId = task.ParentID
+					return 5, nil
+				},
+				getByTaskIdFunc: func(ctx context.Context, taskId int) (*model.Task, error) {
+					for _, task := range tasks {
+						if task.Id == taskId {
+							return &task, nil
+						}
+					}
+					return nil, model.ErrNotFound
+				},
+			},
+			getByPublicIdFunc: func(ctx context.Context, publicId string) (*model.Task, error) {
+				for _, task := range tasks {
+					if task.PublicID != "" && task.PublicID == publicId {
+						return &task, nil
+					}
+				}
+				return nil, model.ErrNotFound
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		publicIds  bool
+		ref        dto.TaskRef
+		wantParent int
+		wantErr    error
+	}{
+		{name: "id", ref: dto.TaskRef{Id: 3}, wantParent: 3},
+		{name: "id in decimal", ref: dto.TaskRef{PublicID: "4"}, wantParent: 4},
+		{name: "id not a number", ref: dto.TaskRef{PublicID: "p3"}, wantErr: model.ErrInvalid},
+		{name: "public id", publicIds: true, ref: dto.TaskRef{PublicID: "p3"}, wantParent: 3},
+		{name: "id of a task without a public id", publicIds: true, ref: dto.TaskRef{PublicID: "4"}, wantParent: 4},
+		{name: "number of a task without a public id", publicIds: true, ref: dto.TaskRef{Id: 4}, wantParent: 4},
+		{name: "id of a task with a public id", publicIds: true, ref: dto.TaskRef{Id: 3}, wantErr: model.ErrInvalid},
+		{name: "unknown public id", publicIds: true, ref: dto.TaskRef{PublicID: "missing"}, wantErr: model.ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parentId *int
+			opts := []TaskUsecaseOption{}
+			if tt.publicIds {
+				opts = append(opts, WithPublicIds())
+			}
+			usecase, _ := NewTaskUsecase(&MockLogger{}, newStorage(&parentId), opts...)
+			ref := tt.ref
+			_, err := usecase.Store(context.Background(), dto.PostTaskRequest{Name: "child", Status: model.Created, ParentID: &ref})
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && (parentId == nil || *parentId != tt.wantParent) {
+				t.Errorf("Expected parent %d, got %v", tt.wantParent, parentId)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+
+	rows := []dto.ImportTaskRow{
+		{Row: 2, Request: dto.PostTaskRequest{Name: "Task 1", Status: model.Created}},
+		{Row: 3, Request: dto.PostTaskRequest{Name: "Task 2", Status: "invalid"}},
+		{Row: 4, Request: dto.PostTaskRequest{Name: "Task 3", Status: model.Done}},
+	}
+
+	tests := []struct {
+		name         string
+		dryRun       bool
+		wantStored   int
+		wantImported int
+		wantIds      []int
+	}{
+		{
+			name:         "import",
+			dryRun:       false,
+			wantStored:   2,
+			wantImported: 2,
+			wantIds:      []int{0, 1},
+		},
+		{
+			name:         "dry run",
+			dryRun:       true,
+			wantStored:   0,
+			wantImported: 2,
+			wantIds:      []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := 0
+			mockStorage := &MockTaskStorage{
+				storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+					stored++
+					return stored - 1, nil
+				},
+			}
+
+			usecase, _ := NewTaskUsecase(&MockLogger{}, mockStorage)
+			response, err := usecase.Import(ctx, rows, tt.dryRun)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if stored != tt.wantStored {
+				t.Errorf("Expected %d stored tasks, got %d", tt.wantStored, stored)
+			}
+			if response.Total != 3 || response.Imported != tt.wantImported || response.Failed != 1 {
+				t.Errorf("Unexpected counters: %+v", response)
+			}
+			if len(response.Ids) != len(tt.wantIds) {
+				t.Fatalf("Expected ids %v, got %v", tt.wantIds, response.Ids)
+			}
+			if len(response.Errors) != 1 || response.Errors[0].Row != 3 {
+				t.Errorf("Expected a single error for row 3, got %v", response.Errors)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	storageErr := errors.New("storage error")
+
+	mockStorage := &MockTaskStorage{
+		forEachFunc: func(ctx context.Context, filter model.Filter, fn func(model.Task) error) error {
+			if filter.Status != model.Done {
+				t.Errorf("Filter mismatch. Expected %q, got %q", model.Done, filter.Status)
+			}
+			if err := fn(model.Task{Id: 1, Status: model.Done}); err != nil {
+				return err
+			}
+			return storageErr
+		},
+	}
+
+	usecase, _ := NewTaskUsecase(&MockLogger{}, mockStorage)
+
+	exported := 0
+	err := usecase.Export(ctx, model.Filter{Status: model.Done}, func(task model.Task) error {
+		exported++
+		return nil
+	})
+
+	if exported != 1 {
+		t.Errorf("Expected 1 exported task, got %d", exported)
+	}
+	if !errors.Is(err, storageErr) {
+		t.Errorf("Expected wrapped storage error, got %v", err)
+	}
+}
+
+func TestStoreReporterFromPrincipal(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "john", UserID: 3})
+
+	tests := []struct {
+		name         string
+		request      dto.PostTaskRequest
+		wantReporter int
+	}{
+		{
+			name:         "reporter taken from principal",
+			request:      dto.PostTaskRequest{Name: "Task", Status: model.Created},
+			wantReporter: 3,
+		},
+		{
+			name:         "explicit reporter is kept without a policy",
+			request:      dto.PostTaskRequest{Name: "Task", Status: model.Created, ReporterID: 5},
+			wantReporter: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockTaskStorage{
+				storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+					if task.ReporterID != tt.wantReporter {
+						t.Errorf("Expected reporter %d, got %d", tt.wantReporter, task.ReporterID)
+					}
+					return 0, nil
+				},
+			}
+
+			usecase, _ := NewTaskUsecase(&MockLogger{}, mockStorage)
+			if _, err := usecase.Store(ctx, tt.request); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDueDateValidation(t *testing.T) {
+	// the clock is far ahead of the machine, so due dates are checked against it
+	now := time.Date(2040, time.March, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	stored := model.Task{Id: 0, Name: "Task", Status: model.Created, Priority: model.PriorityLow}
+
+	mockStorage := &MockTaskStorage{
+		storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+			return 0, nil
+		},
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (*model.Task, error) {
+			task := stored
+			return &task, nil
+		},
+		updateFunc: func(ctx context.Context, task model.Task) (*model.Task, error) {
+			return &task, nil
+		},
+	}
+	usecase, _ := NewTaskUsecase(&MockLogger{}, mockStorage, WithClock(clock.NewManual(now)))
+	ctx := context.Background()
+
+	storeTests := []struct {
+		name    string
+		request dto.PostTaskRequest
+		wantErr bool
+	}{
+		{name: "future due date", request: dto.PostTaskRequest{Name: "Task", Status: model.Created, DueAt: future}},
+		{name: "past due date", request: dto.PostTaskRequest{Name: "Task", Status: model.Created, DueAt: past}, wantErr: true},
+		{
+			name:    "allowed past due date",
+			request: dto.PostTaskRequest{Name: "Task", Status: model.Created, DueAt: past, AllowPastDue: true},
+		},
+		{name: "unknown priority", request: dto.PostTaskRequest{Name: "Task", Status: model.Created, Priority: "critical"}, wantErr: true},
+	}
+	for _, tt := range storeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := usecase.Store(ctx, tt.request)
+			if tt.wantErr != errors.Is(err, model.ErrInvalid) {
+				t.Errorf("Expected ErrInvalid %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("patch to past due date", func(t *testing.T) {
+		if _, err := usecase.Update(ctx, 0, dto.PatchTaskRequest{DueAt: &past}); !errors.Is(err, model.ErrInvalid) {
+			t.Errorf("Expected ErrInvalid, got %v", err)
+		}
+		if _, err := usecase.Update(ctx, 0, dto.PatchTaskRequest{DueAt: &past, AllowPastDue: true}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("untouched past due date doesn't block a patch", func(t *testing.T) {
+		stored.DueAt = past
+		name := "Renamed"
+		if _, err := usecase.Update(ctx, 0, dto.PatchTaskRequest{Name: &name}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("overdue as of the clock", func(t *testing.T) {
+		// the due date is still ahead of the machine
+		stored.DueAt = past
+		response, err := usecase.GetByTaskId(ctx, 0)
+		if err != nil || !response.Overdue {
+			t.Errorf("Expected the task to be overdue, got %+v, %v", response, err)
+		}
+	})
+
+	t.Run("import accepts past due dates", func(t *testing.T) {
+		rows := []dto.ImportTaskRow{{Row: 1, Request: dto.PostTaskRequest{Name: "Old", Status: model.Done, DueAt: past}}}
+		response, err := usecase.Import(ctx, rows, false)
+		if err != nil || response.Imported != 1 {
+			t.Errorf("Expected row to be imported, got %+v, %v", response, err)
+		}
+	})
+
+	t.Run("default priority", func(t *testing.T) {
+		mockStorage.storeFunc = func(ctx context.Context, task model.Task) (int, error) {
+			if task.Priority != model.DefaultPriority {
+				t.Errorf("Expected default priority, got %q", task.Priority)
+			}
+			return 0, nil
+		}
+		usecase.Store(ctx, dto.PostTaskRequest{Name: "Task", Status: model.Created})
+	})
+}
+
+func TestRecurrence(t *testing.T) {
+	now := time.Date(2040, time.March, 1, 12, 30, 0, 0, time.UTC)
+	due := now.Add(24 * time.Hour)
+	start := due.Add(-7 * 24 * time.Hour)
+	var stored model.Task
+	mockStorage := &MockTaskStorage{
+		storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+			stored = task
+			return 0, nil
+		},
+		getByTaskIdFunc: func(ctx context.Context, taskId int) (*model.Task, error) {
+			task := stored
+			return &task, nil
+		},
+		updateFunc: func(ctx context.Context, task model.Task) (*model.Task, error) {
+			stored = task
+			return &task, nil
+		},
+	}
+	usecase, _ := NewTaskUsecase(&MockLogger{}, mockStorage, WithClock(clock.NewManual(now)))
+	ctx := context.Background()
+
+	storeTests := []struct {
+		name       string
+		recurrence dto.RecurrenceRequest
+		dueAt      time.Time
+		wantErr    bool
+		check      func(t *testing.T, task model.Task)
+	}{
+		{
+			name:       "due at the next occurrence",
+			recurrence: dto.RecurrenceRequest{Rule: "0 9 * * *", TimeZone: "Europe/Berlin"},
+			check: func(t *testing.T, task model.Task) {
+				berlin := mustLoadLocation(t, "Europe/Berlin")
+				if expected := time.Date(2040, time.March, 2, 9, 0, 0, 0, berlin); !task.DueAt.Equal(expected) {
+					t.Errorf("Expected the next 9:00 in Berlin %v, got %v", expected, task.DueAt.In(berlin))
+				}
+			},
+		},
+		{
+			name:       "starts at the due date",
+			recurrence: dto.RecurrenceRequest{Rule: "FREQ=WEEKLY"},
+			dueAt:      due,
+			check: func(t *testing.T, task model.Task) {
+				if !task.DueAt.Equal(due) || !task.Recurrence.Start.Equal(due) {
+					t.Errorf("Expected due date and start %v, got %v and %v", due, task.DueAt, task.Recurrence.Start)
+				}
+			},
+		},
+		{
+			name:       "due at the given start",
+			recurrence: dto.RecurrenceRequest{Rule: "FREQ=DAILY;COUNT=3", Start: due},
+			check: func(t *testing.T, task model.Task) {
+				if !task.DueAt.Equal(due) {
+					t.Errorf("Expected due date %v, got %v", due, task.DueAt)
+				}
+			},
+		},
+		{name: "malformed rule", recurrence: dto.RecurrenceRequest{Rule: "every day"}, wantErr: true},
+		{name: "empty rule", recurrence: dto.RecurrenceRequest{Rule: " "}, wantErr: true},
+		{name: "unknown time zone", recurrence: dto.RecurrenceRequest{Rule: "@daily", TimeZone: "Mars/Olympus"}, wantErr: true},
+		{name: "no more occurrences", recurrence: dto.RecurrenceRequest{Rule: "FREQ=DAILY;UNTIL=20200101", Start: start.AddDate(-10, 0, 0)}, wantErr: true},
+	}
+	for _, tt := range storeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored = model.Task{}
+			recurrence := tt.recurrence
+			request := dto.PostTaskRequest{Name: "Task", Status: model.Created, DueAt: tt.dueAt, Recurrence: &recurrence}
+			_, err := usecase.Store(ctx, request)
+			if tt.wantErr {
+				if !errors.Is(err, model.ErrInvalid) {
+					t.Errorf("Expected ErrInvalid, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if stored.Recurrence == nil || stored.Recurrence.Rule != tt.recurrence.Rule {
+				t.Fatalf("Expected recurrence %+v to be stored, got %+v", tt.recurrence, stored.Recurrence)
+			}
+			tt.check(t, stored)
+		})
+	}
+
+	t.Run("changed rule keeps the start", func(t *testing.T) {
+		stored = model.Task{Name: "Task", Status: model.Created, DueAt: due, Recurrence: &model.Recurrence{Rule: "FREQ=DAILY", Start: start}}
+		response, err := usecase.Update(ctx, 0, dto.PatchTaskRequest{Recurrence: &dto.RecurrenceRequest{Rule: "FREQ=WEEKLY"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Recurrence == nil || response.Recurrence.Rule != "FREQ=WEEKLY" || !response.Recurrence.Start.Equal(start) {
+			t.Errorf("Expected weekly rule from %v, got %+v", start, response.Recurrence)
+		}
+	})
+
+	t.Run("cleared recurrence", func(t *testing.T) {
+		stored = model.Task{Name: "Task", Status: model.Created, DueAt: due, Recurrence: &model.Recurrence{Rule: "FREQ=DAILY", Start: start}}
+		response, err := usecase.Update(ctx, 0, dto.PatchTaskRequest{ClearRecurrence: true, Recurrence: &dto.RecurrenceRequest{Rule: "@daily"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Recurrence != nil || stored.Recurrence != nil {
+			t.Errorf("Expected no recurrence, got %+v", response.Recurrence)
+		}
+	})
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("Unexpected error loading %v: %v", name, err)
+	}
+	return loc
+}
@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+)
+
+const userUsecaseName = "UserUsecase"
+
+type UserStorage interface {
+	Store(ctx context.Context, user model.User) (int, error)
+	GetAll(ctx context.Context) ([]model.User, error)
+	GetByUserId(ctx context.Context, userId int) (*model.User, error)
+	Update(ctx context.Context, user model.User) (*model.User, error)
+	Delete(ctx context.Context, userId int) error
+}
+
+type UserUsecase struct {
+	logger      Logger
+	userStorage UserStorage
+	taskStorage TaskStorage
+	policy      *auth.Policy
+}
+
+// UserUsecaseOption configures optional dependencies of UserUsecase
+type UserUsecaseOption func(*UserUsecase)
+
+// WithUserPolicy enables authorization of every action against the principal from the context
+func WithUserPolicy(policy *auth.Policy) UserUsecaseOption {
+	return func(uu *UserUsecase) {
+		uu.policy = policy
+	}
+}
+
+func NewUserUsecase(logger Logger, userStorage UserStorage, taskStorage TaskStorage, opts ...UserUsecaseOption) (*UserUsecase, error) {
+	if userStorage == nil || taskStorage == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", userUsecaseName)
+	}
+
+	uu := &UserUsecase{logger: logger, userStorage: userStorage, taskStorage: taskStorage}
+	for _, opt := range opts {
+		opt(uu)
+	}
+
+	logger.Log("Created %s successfully", userUsecaseName)
+	return uu, nil
+}
+
+func (uu *UserUsecase) Store(ctx context.Context, request dto.PostUserRequest) (int, error) {
+	if err := uu.authorize(ctx, auth.UserWrite); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the user: %w", userUsecaseName, err)
+	}
+	user := mapper.PostUserRequestToUser(request)
+	if err := model.ValidateUser(user); err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the user: %w", userUsecaseName, err)
+	}
+	id, err := uu.userStorage.Store(ctx, user)
+	if err != nil {
+		return -1, fmt.Errorf("%v: couldn't store the user: %w", userUsecaseName, err)
+	}
+	return id, nil
+}
+
+func (uu *UserUsecase) GetAll(ctx context.Context) (dto.GetAllUsersResponse, error) {
+	if err := uu.authorize(ctx, auth.UserRead); err != nil {
+		return dto.GetAllUsersResponse{}, fmt.Errorf("%v: couldn't get all the users: %w", userUsecaseName, err)
+	}
+	users, err := uu.userStorage.GetAll(ctx)
+	if err != nil {
+		return dto.GetAllUsersResponse{}, fmt.Errorf("%v: couldn't get all the users: %w", userUsecaseName, err)
+	}
+
+	return mapper.UsersToGetAllUsersResponse(users), nil
+}
+
+func (uu *UserUsecase) GetByUserId(ctx context.Context, userId int) (dto.GetUserByIdResponse, error) {
+	if err := uu.authorize(ctx, auth.UserRead); err != nil {
+		return dto.GetUserByIdResponse{}, fmt.Errorf("%v: %w", userUsecaseName, err)
+	}
+	user, err := uu.userStorage.GetByUserId(ctx, userId)
+	if err != nil {
+		return dto.GetUserByIdResponse{}, fmt.Errorf("%v: %w", userUsecaseName, err)
+	}
+
+	return mapper.UserToGetUserByIdResponse(*user), nil
+}
+
+func (uu *UserUsecase) Update(ctx context.Context, userId int, request dto.PutUserRequest) (dto.GetUserByIdResponse, error) {
+	if err := uu.authorize(ctx, auth.UserWrite); err != nil {
+		return dto.GetUserByIdResponse{}, fmt.Errorf("%v: couldn't update the user: %w", userUsecaseName, err)
+	}
+	user := mapper.PutUserRequestToUser(userId, request)
+	if err := model.ValidateUser(user); err != nil {
+		return dto.GetUserByIdResponse{}, fmt.Errorf("%v: couldn't update the user: %w", userUsecaseName, err)
+	}
+	updated, err := uu.userStorage.Update(ctx, user)
+	if err != nil {
+		return dto.GetUserByIdResponse{}, fmt.Errorf("%v: couldn't update the user: %w", userUsecaseName, err)
+	}
+
+	return mapper.UserToGetUserByIdResponse(*updated), nil
+}
+
+func (uu *UserUsecase) Delete(ctx context.Context, userId int) error {
+	if err := uu.authorize(ctx, auth.UserWrite); err != nil {
+		return fmt.Errorf("%v: couldn't delete the user: %w", userUsecaseName, err)
+	}
+	if err := uu.userStorage.Delete(ctx, userId); err != nil {
+		return fmt.Errorf("%v: couldn't delete the user: %w", userUsecaseName, err)
+	}
+	return nil
+}
+
+// GetTasks returns all the tasks assigned to the user
+func (uu *UserUsecase) GetTasks(ctx context.Context, userId int) (dto.GetAllTasksResponse, error) {
+	if err := uu.authorize(ctx, auth.TaskRead); err != nil {
+		return dto.GetAllTasksResponse{}, fmt.Errorf("%v: %w", userUsecaseName, err)
+	}
+	if _, err := uu.userStorage.GetByUserId(ctx, userId); err != nil {
+		return dto.GetAllTasksResponse{}, fmt.Errorf("%v: %w", userUsecaseName, err)
+	}
+
+	tasks, _, err := uu.taskStorage.GetAll(ctx, model.Filter{AssigneeID: userId})
+	if err != nil {
+		return dto.GetAllTasksResponse{}, fmt.Errorf("%v: couldn't get tasks of the user: %w", userUsecaseName, err)
+	}
+
+	if uu.policy != nil {
+		readable := make([]model.Task, 0, len(tasks))
+		for _, task := range tasks {
+			if uu.policy.Authorize(ctx, auth.TaskRead, &task) == nil {
+				readable = append(readable, task)
+			}
+		}
+		tasks = readable
+	}
+
+	return mapper.TasksToGetAllTasksResponse(tasks), nil
+}
+
+func (uu *UserUsecase) authorize(ctx context.Context, permission auth.Permission) error {
+	if uu.policy == nil {
+		return nil
+	}
+	return uu.policy.Authorize(ctx, permission, nil)
+}
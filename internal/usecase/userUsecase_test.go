@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"testing"
+)
+
+// MockUserStorage is a mock implementation of UserStorage for testing
+type MockUserStorage struct {
+	users map[int]model.User
+}
+
+func (m *MockUserStorage) Store(ctx context.Context, user model.User) (int, error) {
+	user.Id = len(m.users) + 1
+	m.users[user.Id] = user
+	return user.Id, nil
+}
+
+func (m *MockUserStorage) GetAll(ctx context.Context) ([]model.User, error) {
+	users := make([]model.User, 0, len(m.users))
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (m *MockUserStorage) GetByUserId(ctx context.Context, userId int) (*model.User, error) {
+	user, ok := m.users[userId]
+	if !ok {
+		return nil, fmt.Errorf("mock: %w", model.ErrNotFound)
+	}
+	return &user, nil
+}
+
+func (m *MockUserStorage) Update(ctx context.Context, user model.User) (*model.User, error) {
+	if _, ok := m.users[user.Id]; !ok {
+		return nil, fmt.Errorf("mock: %w", model.ErrNotFound)
+	}
+	m.users[user.Id] = user
+	return &user, nil
+}
+
+func (m *MockUserStorage) Delete(ctx context.Context, userId int) error {
+	delete(m.users, userId)
+	return nil
+}
+
+func TestStoreValidatesUsers(t *testing.T) {
+	ctx := context.Background()
+	users := &MockUserStorage{users: map[int]model.User{1: {Id: 1, Name: "John"}}}
+
+	tests := []struct {
+		name       string
+		request    dto.PostTaskRequest
+		wantStored bool
+	}{
+		{
+			name:       "existing assignee and reporter",
+			request:    dto.PostTaskRequest{Name: "Task", Status: model.Created, AssigneeID: 1, ReporterID: 1},
+			wantStored: true,
+		},
+		{
+			name:       "unassigned task",
+			request:    dto.PostTaskRequest{Name: "Task", Status: model.Created},
+			wantStored: true,
+		},
+		{
+			name:       "nonexistent assignee",
+			request:    dto.PostTaskRequest{Name: "Task", Status: model.Created, AssigneeID: 2},
+			wantStored: false,
+		},
+		{
+			name:       "nonexistent reporter",
+			request:    dto.PostTaskRequest{Name: "Task", Status: model.Created, ReporterID: 2},
+			wantStored: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := false
+			mockStorage := &MockTaskStorage{
+				storeFunc: func(ctx context.Context, task model.Task) (int, error) {
+					stored = true
+					return 0, nil
+				},
+			}
+
+			usecase, _ := NewTaskUsecase(&MockLogger{}, mockStorage, WithUserStorage(users))
+			_, err := usecase.Store(ctx, tt.request)
+
+			if stored != tt.wantStored {
+				t.Errorf("Expected stored %v, got %v", tt.wantStored, stored)
+			}
+			if !tt.wantStored && !errors.Is(err, model.ErrInvalid) {
+				t.Errorf("Expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUserUsecase(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nil storage", func(t *testing.T) {
+		_, err := NewUserUsecase(&MockLogger{}, nil, &MockTaskStorage{})
+		if err == nil || err.Error() != "nil values in UserUsecase constructor" {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("store invalid user", func(t *testing.T) {
+		users := &MockUserStorage{users: map[int]model.User{}}
+		usecase, _ := NewUserUsecase(&MockLogger{}, users, &MockTaskStorage{})
+
+		if _, err := usecase.Store(ctx, dto.PostUserRequest{Name: "John", Email: "invalid"}); err == nil {
+			t.Error("Expected validation error, got nil")
+		}
+		if len(users.users) != 0 {
+			t.Errorf("Expected no users to be stored, got %d", len(users.users))
+		}
+	})
+
+	t.Run("get tasks", func(t *testing.T) {
+		users := &MockUserStorage{users: map[int]model.User{1: {Id: 1, Name: "John"}}}
+		mockStorage := &MockTaskStorage{
+			getAllFunc: func(ctx context.Context, filter model.Filter) ([]model.Task, int, error) {
+				if filter.AssigneeID != 1 {
+					t.Errorf("Expected assignee filter 1, got %d", filter.AssigneeID)
+				}
+				return []model.Task{{Id: 0, AssigneeID: 1}}, 1, nil
+			},
+		}
+		usecase, _ := NewUserUsecase(&MockLogger{}, users, mockStorage)
+
+		response, err := usecase.GetTasks(ctx, 1)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if response.Amount != 1 {
+			t.Errorf("Expected 1 task, got %d", response.Amount)
+		}
+
+		if _, err := usecase.GetTasks(ctx, 2); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/model/mapper"
+)
+
+const webhookUsecaseName = "WebhookUsecase"
+
+type WebhookStorage interface {
+	Store(ctx context.Context, webhook model.Webhook) (int, error)
+	GetAll(ctx context.Context) ([]model.Webhook, error)
+	GetById(ctx context.Context, webhookId int) (*model.Webhook, error)
+	Update(ctx context.Context, webhook model.Webhook) (*model.Webhook, error)
+	Delete(ctx context.Context, webhookId int) error
+	GetDeliveries(ctx context.Context, webhookId int, page model.Page) ([]model.WebhookDelivery, int, error)
+	StoreDeadLetter(ctx context.Context, letter model.DeadLetter) error
+	GetDeadLetters(ctx context.Context, webhookId int) ([]model.DeadLetter, error)
+	TakeDeadLetter(ctx context.Context, webhookId int, deliveryId string) (*model.DeadLetter, error)
+}
+
+// WebhookRedeliverer sends a dead letter to its webhook again, see webhook.Dispatcher
+type WebhookRedeliverer interface {
+	Redeliver(ctx context.Context, webhook model.Webhook, letter model.DeadLetter) error
+}
+
+// WebhookUsecase manages webhooks of the project from the context, deliveries are sent by webhook.Dispatcher
+type WebhookUsecase struct {
+	logger         Logger
+	webhookStorage WebhookStorage
+	redeliverer    WebhookRedeliverer
+	policy         *auth.Policy
+}
+
+// WebhookUsecaseOption configures optional dependencies of WebhookUsecase
+type WebhookUsecaseOption func(*WebhookUsecase)
+
+// WithWebhookPolicy enables authorization of every action against the principal from the context
+func WithWebhookPolicy(policy *auth.Policy) WebhookUsecaseOption {
+	return func(wu *WebhookUsecase) {
+		wu.policy = policy
+	}
+}
+
+// WithRedeliverer enables redelivery of dead letters
+func WithRedeliverer(redeliverer WebhookRedeliverer) WebhookUsecaseOption {
+	return func(wu *WebhookUsecase) {
+		wu.redeliverer = redeliverer
+	}
+}
+
+func NewWebhookUsecase(logger Logger, webhookStorage WebhookStorage, opts ...WebhookUsecaseOption) (*WebhookUsecase, error) {
+	if webhookStorage == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", webhookUsecaseName)
+	}
+
+	wu := &WebhookUsecase{logger: logger, webhookStorage: webhookStorage}
+	for _, opt := range opts {
+		opt(wu)
+	}
+
+	logger.Log("Created %s successfully", webhookUsecaseName)
+	return wu, nil
+}
+
+// Store creates the webhook, a random secret is generated when none is sent.
+// The secret is returned only here.
+func (wu *WebhookUsecase) Store(ctx context.Context, request dto.PostWebhookRequest) (dto.PostWebhookResponse, error) {
+	if err := wu.authorize(ctx); err != nil {
+		return dto.PostWebhookResponse{}, fmt.Errorf("%v: couldn't store the webhook: %w", webhookUsecaseName, err)
+	}
+	webhook := mapper.PostWebhookRequestToWebhook(request)
+	if webhook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return dto.PostWebhookResponse{}, fmt.Errorf("%v: couldn't generate the secret: %w", webhookUsecaseName, err)
+		}
+		webhook.Secret = secret
+	}
+	if err := model.ValidateWebhook(webhook); err != nil {
+		return dto.PostWebhookResponse{}, fmt.Errorf("%v: couldn't store the webhook: %w", webhookUsecaseName, model.Invalid(err))
+	}
+	id, err := wu.webhookStorage.Store(ctx, webhook)
+	if err != nil {
+		return dto.PostWebhookResponse{}, fmt.Errorf("%v: couldn't store the webhook: %w", webhookUsecaseName, err)
+	}
+
+	return dto.PostWebhookResponse{Id: id, Secret: webhook.Secret}, nil
+}
+
+func (wu *WebhookUsecase) GetAll(ctx context.Context) (dto.GetAllWebhooksResponse, error) {
+	if err := wu.authorize(ctx); err != nil {
+		return dto.GetAllWebhooksResponse{}, fmt.Errorf("%v: couldn't get all the webhooks: %w", webhookUsecaseName, err)
+	}
+	webhooks, err := wu.webhookStorage.GetAll(ctx)
+	if err != nil {
+		return dto.GetAllWebhooksResponse{}, fmt.Errorf("%v: couldn't get all the webhooks: %w", webhookUsecaseName, err)
+	}
+
+	return mapper.WebhooksToGetAllWebhooksResponse(webhooks), nil
+}
+
+func (wu *WebhookUsecase) GetById(ctx context.Context, webhookId int) (dto.GetWebhookByIdResponse, error) {
+	if err := wu.authorize(ctx); err != nil {
+		return dto.GetWebhookByIdResponse{}, fmt.Errorf("%v: %w", webhookUsecaseName, err)
+	}
+	webhook, err := wu.webhookStorage.GetById(ctx, webhookId)
+	if err != nil {
+		return dto.GetWebhookByIdResponse{}, fmt.Errorf("%v: %w", webhookUsecaseName, err)
+	}
+
+	return mapper.WebhookToGetWebhookByIdResponse(*webhook), nil
+}
+
+// Update replaces the webhook, its secret is kept unless a new one is sent
+func (wu *WebhookUsecase) Update(ctx context.Context, webhookId int, request dto.PutWebhookRequest) (dto.GetWebhookByIdResponse, error) {
+	if err := wu.authorize(ctx); err != nil {
+		return dto.GetWebhookByIdResponse{}, fmt.Errorf("%v: couldn't update the webhook: %w", webhookUsecaseName, err)
+	}
+	stored, err := wu.webhookStorage.GetById(ctx, webhookId)
+	if err != nil {
+		return dto.GetWebhookByIdResponse{}, fmt.Errorf("%v: couldn't update the webhook: %w", webhookUsecaseName, err)
+	}
+	webhook := mapper.PutWebhookRequestToWebhook(*stored, request)
+	if err := model.ValidateWebhook(webhook); err != nil {
+		return dto.GetWebhookByIdResponse{}, fmt.Errorf("%v: couldn't update the webhook: %w", webhookUsecaseName, model.Invalid(err))
+	}
+	updated, err := wu.webhookStorage.Update(ctx, webhook)
+	if err != nil {
+		return dto.GetWebhookByIdResponse{}, fmt.Errorf("%v: couldn't update the webhook: %w", webhookUsecaseName, err)
+	}
+
+	return mapper.WebhookToGetWebhookByIdResponse(*updated), nil
+}
+
+// Delete removes the webhook with its delivery log and dead letters, pending retries are dropped
+func (wu *WebhookUsecase) Delete(ctx context.Context, webhookId int) error {
+	if err := wu.authorize(ctx); err != nil {
+		return fmt.Errorf("%v: couldn't delete the webhook: %w", webhookUsecaseName, err)
+	}
+	if err := wu.webhookStorage.Delete(ctx, webhookId); err != nil {
+		return fmt.Errorf("%v: couldn't delete the webhook: %w", webhookUsecaseName, err)
+	}
+	return nil
+}
+
+// GetDeliveries returns a page of the delivery log of the webhook, newest first
+func (wu *WebhookUsecase) GetDeliveries(ctx context.Context, webhookId int, page model.Page) (dto.GetWebhookDeliveriesResponse, error) {
+	if err := model.ValidatePage(page); err != nil {
+		return dto.GetWebhookDeliveriesResponse{}, fmt.Errorf("%v: couldn't get deliveries: %w", webhookUsecaseName, model.Invalid(err))
+	}
+	if err := wu.authorize(ctx); err != nil {
+		return dto.GetWebhookDeliveriesResponse{}, fmt.Errorf("%v: couldn't get deliveries: %w", webhookUsecaseName, err)
+	}
+	deliveries, total, err := wu.webhookStorage.GetDeliveries(ctx, webhookId, page)
+	if err != nil {
+		return dto.GetWebhookDeliveriesResponse{}, fmt.Errorf("%v: couldn't get deliveries of the webhook(%v): %w", webhookUsecaseName, webhookId, err)
+	}
+
+	return mapper.WebhookDeliveriesToGetWebhookDeliveriesResponse(deliveries, total, page), nil
+}
+
+func (wu *WebhookUsecase) GetDeadLetters(ctx context.Context, webhookId int) (dto.GetDeadLettersResponse, error) {
+	if err := wu.authorize(ctx); err != nil {
+		return dto.GetDeadLettersResponse{}, fmt.Errorf("%v: couldn't get dead letters: %w", webhookUsecaseName, err)
+	}
+	letters, err := wu.webhookStorage.GetDeadLetters(ctx, webhookId)
+	if err != nil {
+		return dto.GetDeadLettersResponse{}, fmt.Errorf("%v: couldn't get dead letters of the webhook(%v): %w", webhookUsecaseName, webhookId, err)
+	}
+
+	return mapper.DeadLettersToGetDeadLettersResponse(letters), nil
+}
+
+// Redeliver takes the dead letter and delivers it again with the same delivery id,
+// the letter is kept when the delivery can't be queued
+func (wu *WebhookUsecase) Redeliver(ctx context.Context, webhookId int, deliveryId string) error {
+	if wu.redeliverer == nil {
+		return fmt.Errorf("%v: couldn't redeliver: %w", webhookUsecaseName, model.ErrUnsupported)
+	}
+	if err := wu.authorize(ctx); err != nil {
+		return fmt.Errorf("%v: couldn't redeliver: %w", webhookUsecaseName, err)
+	}
+	webhook, err := wu.webhookStorage.GetById(ctx, webhookId)
+	if err != nil {
+		return fmt.Errorf("%v: couldn't redeliver: %w", webhookUsecaseName, err)
+	}
+	letter, err := wu.webhookStorage.TakeDeadLetter(ctx, webhookId, deliveryId)
+	if err != nil {
+		return fmt.Errorf("%v: couldn't redeliver: %w", webhookUsecaseName, err)
+	}
+	if err := wu.redeliverer.Redeliver(ctx, *webhook, *letter); err != nil {
+		if restoreErr := wu.webhookStorage.StoreDeadLetter(ctx, *letter); restoreErr != nil {
+			wu.logger.Log("%v: lost dead letter(%v): %v", webhookUsecaseName, deliveryId, restoreErr)
+		}
+		return fmt.Errorf("%v: couldn't redeliver: %w", webhookUsecaseName, err)
+	}
+	return nil
+}
+
+func (wu *WebhookUsecase) authorize(ctx context.Context) error {
+	if wu.policy == nil {
+		return nil
+	}
+	return wu.policy.Authorize(ctx, auth.WebhookManage, nil)
+}
+
+// newWebhookSecret returns 32 random hex characters
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
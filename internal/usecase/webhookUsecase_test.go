@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"slices"
+	"testing"
+)
+
+// MockWebhookStorage is an in memory implementation of WebhookStorage for testing, it ignores the project scope
+type MockWebhookStorage struct {
+	webhooks    map[int]model.Webhook
+	deadLetters []model.DeadLetter
+	idCounter   int
+}
+
+func newMockWebhookStorage() *MockWebhookStorage {
+	return &MockWebhookStorage{webhooks: make(map[int]model.Webhook)}
+}
+
+func (m *MockWebhookStorage) Store(ctx context.Context, webhook model.Webhook) (int, error) {
+	m.idCounter++
+	webhook.Id = m.idCounter
+	m.webhooks[webhook.Id] = webhook
+	return webhook.Id, nil
+}
+
+func (m *MockWebhookStorage) GetAll(ctx context.Context) ([]model.Webhook, error) {
+	ans := make([]model.Webhook, 0)
+	for id := 1; id <= m.idCounter; id++ {
+		if webhook, ok := m.webhooks[id]; ok {
+			ans = append(ans, webhook)
+		}
+	}
+	return ans, nil
+}
+
+func (m *MockWebhookStorage) GetById(ctx context.Context, webhookId int) (*model.Webhook, error) {
+	webhook, ok := m.webhooks[webhookId]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &webhook, nil
+}
+
+func (m *MockWebhookStorage) Update(ctx context.Context, webhook model.Webhook) (*model.Webhook, error) {
+	m.webhooks[webhook.Id] = webhook
+	return &webhook, nil
+}
+
+func (m *MockWebhookStorage) Delete(ctx context.Context, webhookId int) error {
+	delete(m.webhooks, webhookId)
+	return nil
+}
+
+func (m *MockWebhookStorage) GetDeliveries(ctx context.Context, webhookId int, page model.Page) ([]model.WebhookDelivery, int, error) {
+	return []model.WebhookDelivery{}, 0, nil
+}
+
+func (m *MockWebhookStorage) StoreDeadLetter(ctx context.Context, letter model.DeadLetter) error {
+	m.deadLetters = append(m.deadLetters, letter)
+	return nil
+}
+
+func (m *MockWebhookStorage) GetDeadLetters(ctx context.Context, webhookId int) ([]model.DeadLetter, error) {
+	return slices.Clone(m.deadLetters), nil
+}
+
+func (m *MockWebhookStorage) TakeDeadLetter(ctx context.Context, webhookId int, deliveryId string) (*model.DeadLetter, error) {
+	i := slices.IndexFunc(m.deadLetters, func(letter model.DeadLetter) bool { return letter.DeliveryID == deliveryId })
+	if i < 0 {
+		return nil, model.ErrNotFound
+	}
+	letter := m.deadLetters[i]
+	m.deadLetters = slices.Delete(m.deadLetters, i, i+1)
+	return &letter, nil
+}
+
+// MockRedeliverer is a mock implementation of WebhookRedeliverer for testing
+type MockRedeliverer struct {
+	redeliverFunc func(ctx context.Context, webhook model.Webhook, letter model.DeadLetter) error
+}
+
+func (m *MockRedeliverer) Redeliver(ctx context.Context, webhook model.Webhook, letter model.DeadLetter) error {
+	return m.redeliverFunc(ctx, webhook, letter)
+}
+
+func TestWebhookUsecase(t *testing.T) {
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 1, Roles: []string{"admin"}})
+	member := auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Roles: []string{"member"}})
+	policy := WithWebhookPolicy(auth.NewPolicy(auth.DefaultRules, false))
+
+	t.Run("nil storage", func(t *testing.T) {
+		if _, err := NewWebhookUsecase(&MockLogger{}, nil); err == nil {
+			t.Error("Expected error for nil storage")
+		}
+	})
+
+	t.Run("secrets", func(t *testing.T) {
+		storage := newMockWebhookStorage()
+		usecase, _ := NewWebhookUsecase(&MockLogger{}, storage, policy)
+
+		generated, err := usecase.Store(admin, dto.PostWebhookRequest{URL: "https://ci.example.com/hook"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(generated.Secret) != 32 || storage.webhooks[generated.Id].Secret != generated.Secret || !storage.webhooks[generated.Id].Active {
+			t.Errorf("Expected a generated secret of an active webhook, got %+v", generated)
+		}
+
+		if _, err := usecase.Update(admin, generated.Id, dto.PutWebhookRequest{URL: "https://ci.example.com/v2"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if stored := storage.webhooks[generated.Id]; stored.Secret != generated.Secret || stored.Active || stored.URL != "https://ci.example.com/v2" {
+			t.Errorf("Expected the secret to be kept, got %+v", stored)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		usecase, _ := NewWebhookUsecase(&MockLogger{}, newMockWebhookStorage(), policy)
+		requests := []dto.PostWebhookRequest{
+			{URL: "ci.example.com/hook"},
+			{URL: "ftp://ci.example.com/hook"},
+			{URL: "https://ci.example.com/hook", Secret: "short"},
+			{URL: "https://ci.example.com/hook", Events: []model.WebhookEvent{"task.moved"}},
+		}
+		for _, request := range requests {
+			if _, err := usecase.Store(admin, request); !errors.Is(err, model.ErrInvalid) {
+				t.Errorf("Expected ErrInvalid for %+v, got %v", request, err)
+			}
+		}
+		if _, err := usecase.GetDeliveries(admin, 1, model.Page{Limit: 0}); !errors.Is(err, model.ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for an empty page, got %v", err)
+		}
+	})
+
+	t.Run("only admins manage webhooks", func(t *testing.T) {
+		usecase, _ := NewWebhookUsecase(&MockLogger{}, newMockWebhookStorage(), policy)
+		if _, err := usecase.GetAll(member); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+		if _, err := usecase.Store(member, dto.PostWebhookRequest{URL: "https://ci.example.com/hook"}); !errors.Is(err, model.ErrForbidden) {
+			t.Errorf("Expected ErrForbidden, got %v", err)
+		}
+	})
+
+	t.Run("redelivery", func(t *testing.T) {
+		storage := newMockWebhookStorage()
+		id, _ := storage.Store(admin, model.Webhook{URL: "https://ci.example.com/hook"})
+		storage.StoreDeadLetter(admin, model.DeadLetter{DeliveryID: "a", WebhookID: id})
+
+		unsupported, _ := NewWebhookUsecase(&MockLogger{}, storage, policy)
+		if err := unsupported.Redeliver(admin, id, "a"); !errors.Is(err, model.ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported without a redeliverer, got %v", err)
+		}
+
+		queueFull := errors.New("queue is full")
+		var redelivered []string
+		redeliverer := &MockRedeliverer{
+			redeliverFunc: func(ctx context.Context, webhook model.Webhook, letter model.DeadLetter) error {
+				if len(redelivered) == 0 {
+					redelivered = append(redelivered, "")
+					return queueFull
+				}
+				redelivered = append(redelivered, letter.DeliveryID)
+				return nil
+			},
+		}
+		usecase, _ := NewWebhookUsecase(&MockLogger{}, storage, policy, WithRedeliverer(redeliverer))
+		if err := usecase.Redeliver(admin, id, "a"); !errors.Is(err, queueFull) {
+			t.Fatalf("Expected the queue error, got %v", err)
+		}
+		if len(storage.deadLetters) != 1 {
+			t.Fatalf("Expected the letter to be kept after a failed redelivery")
+		}
+		if err := usecase.Redeliver(admin, id, "a"); err != nil || redelivered[1] != "a" || len(storage.deadLetters) != 0 {
+			t.Errorf("Expected the letter to be redelivered, got %v, %v", redelivered, err)
+		}
+		if err := usecase.Redeliver(admin, id, "a"); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a taken letter, got %v", err)
+		}
+	})
+}
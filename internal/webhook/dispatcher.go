@@ -0,0 +1,409 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/broker"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/mapper"
+	"ivanjabrony/test_lo/internal/tenant"
+	mathrand "math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const dispatcherName = "WebhookDispatcher"
+
+const (
+	DefaultWorkers     = 4
+	DefaultMaxAttempts = 5
+	// DefaultBackoff is the delay before the first retry, every next one waits twice as long
+	DefaultBackoff    = time.Second
+	DefaultMaxBackoff = 5 * time.Minute
+	DefaultTimeout    = 10 * time.Second
+	// DefaultQueueSize is an amount of deliveries waiting for a free worker
+	DefaultQueueSize = 256
+
+	// maxResponseBody is read from responses of receivers, so connections can be reused
+	maxResponseBody = 64 << 10
+)
+
+type Logger interface {
+	Log(format string, info ...any)
+}
+
+// ChangeSource publishes committed task changes, see broker.Broker
+type ChangeSource interface {
+	Subscribe(lastID int) (*broker.Subscription, []model.TaskChange, bool)
+}
+
+// Clock tells the time attempts are logged and signed with, see clock.System
+type Clock interface {
+	Now() time.Time
+}
+
+// Storage keeps webhooks, their delivery logs and dead letters, see storage.WebhookStorage
+type Storage interface {
+	GetAll(ctx context.Context) ([]model.Webhook, error)
+	AppendDelivery(ctx context.Context, delivery model.WebhookDelivery) error
+	StoreDeadLetter(ctx context.Context, letter model.DeadLetter) error
+}
+
+// Dispatcher delivers task changes to the webhooks of their projects.
+//
+// Changes are taken from the broker and turned into deliveries, one per webhook and event, that a pool
+// of workers POSTs to the webhooks. Every attempt is logged. A failed attempt is retried after an exponential
+// backoff with jitter, and after the last attempt the delivery goes to the dead letters of the webhook.
+// Deliveries waiting for a worker or a retry are kept in memory and are lost on shutdown.
+type Dispatcher struct {
+	source      ChangeSource
+	storage     Storage
+	client      *http.Client
+	logger      Logger
+	workers     int
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	queue       chan delivery
+	clock       Clock
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	start  sync.Once
+}
+
+// delivery is an event on its way to a webhook
+type delivery struct {
+	id       string
+	webhook  model.Webhook
+	event    model.WebhookEvent
+	changeID int
+	payload  []byte
+	attempt  int
+}
+
+// DispatcherOption configures optional behaviour of Dispatcher
+type DispatcherOption func(*Dispatcher)
+
+// WithWorkers sets an amount of deliveries sent at once
+func WithWorkers(workers int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.workers = workers
+	}
+}
+
+// WithMaxAttempts sets an amount of attempts before a delivery goes to the dead letters
+func WithMaxAttempts(attempts int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.maxAttempts = attempts
+	}
+}
+
+// WithBackoff sets the delay before the first retry and the longest delay between retries
+func WithBackoff(backoff, maxBackoff time.Duration) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.backoff = backoff
+		d.maxBackoff = maxBackoff
+	}
+}
+
+// WithHTTPClient replaces the client deliveries are sent with, it should have a timeout
+func WithHTTPClient(client *http.Client) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithQueueSize sets an amount of deliveries waiting for a free worker
+func WithQueueSize(size int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.queue = make(chan delivery, size)
+	}
+}
+
+// WithClock replaces the system clock, tests use it to control timestamps
+func WithClock(clock Clock) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.clock = clock
+	}
+}
+
+func NewDispatcher(logger Logger, source ChangeSource, storage Storage, opts ...DispatcherOption) (*Dispatcher, error) {
+	if logger == nil || source == nil || storage == nil {
+		return nil, fmt.Errorf("nil values in %v constructor", dispatcherName)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		source:      source,
+		storage:     storage,
+		client:      &http.Client{Timeout: DefaultTimeout},
+		logger:      logger,
+		workers:     DefaultWorkers,
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+		queue:       make(chan delivery, DefaultQueueSize),
+		clock:       clock.System{},
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.workers < 1 || d.maxAttempts < 1 || d.backoff <= 0 || d.maxBackoff < d.backoff || d.client == nil {
+		cancel()
+		return nil, fmt.Errorf("%v: invalid workers %v, attempts %v or backoff %v..%v", dispatcherName, d.workers, d.maxAttempts, d.backoff, d.maxBackoff)
+	}
+
+	logger.Log("Created %s successfully", dispatcherName)
+	return d, nil
+}
+
+// Start subscribes to task changes and starts the workers, starting again does nothing
+func (d *Dispatcher) Start() {
+	d.start.Do(func() {
+		sub, _, _ := d.source.Subscribe(0)
+		d.wg.Add(d.workers + 1)
+		go d.consume(sub)
+		for range d.workers {
+			go d.work()
+		}
+	})
+}
+
+// Stop stops taking changes and waits for the deliveries being sent until ctx is done,
+// waiting deliveries and retries are dropped
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.cancel()
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%v: stopping: %w", dispatcherName, ctx.Err())
+	}
+}
+
+// Redeliver sends the dead letter to the webhook again, starting with the first attempt
+func (d *Dispatcher) Redeliver(ctx context.Context, webhook model.Webhook, letter model.DeadLetter) error {
+	return d.enqueue(ctx, delivery{
+		id:       letter.DeliveryID,
+		webhook:  webhook,
+		event:    letter.Event,
+		changeID: letter.ChangeID,
+		payload:  letter.Payload,
+		attempt:  1,
+	})
+}
+
+// consume turns changes into deliveries. A subscription dropped for falling behind is renewed
+// from the last change seen, changes that can't be replayed anymore are lost.
+func (d *Dispatcher) consume(sub *broker.Subscription) {
+	defer d.wg.Done()
+	lastID := 0
+	for {
+		select {
+		case <-d.ctx.Done():
+			sub.Close()
+			return
+		case change, ok := <-sub.Changes():
+			if ok {
+				d.dispatch(change)
+				lastID = change.ID
+				continue
+			}
+		}
+
+		var replay []model.TaskChange
+		var complete bool
+		sub, replay, complete = d.source.Subscribe(lastID)
+		if !complete {
+			d.logger.Log("error in %v: changes after %v were lost while falling behind", dispatcherName, lastID)
+		}
+		for _, change := range replay {
+			d.dispatch(change)
+			lastID = change.ID
+		}
+	}
+}
+
+// dispatch queues deliveries of the change to the webhooks of its project
+func (d *Dispatcher) dispatch(change model.TaskChange) {
+	webhooks, err := d.storage.GetAll(tenant.WithScope(d.ctx, tenant.Scope{ProjectID: change.ProjectID}))
+	if err != nil {
+		d.logger.Log("error in %v: change(%v) isn't delivered: %v", dispatcherName, change.ID, err)
+		return
+	}
+	for _, event := range model.WebhookEvents(change) {
+		for _, webhook := range webhooks {
+			if !webhook.Subscribed(event) {
+				continue
+			}
+			id := newDeliveryID()
+			payload, err := json.Marshal(mapper.TaskChangeToWebhookPayload(id, event, change))
+			if err != nil {
+				d.logger.Log("error in %v: change(%v) isn't delivered: %v", dispatcherName, change.ID, err)
+				return
+			}
+			next := delivery{id: id, webhook: webhook, event: event, changeID: change.ID, payload: payload, attempt: 1}
+			if err := d.enqueue(d.ctx, next); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// enqueue waits for room in the queue until ctx is done or the dispatcher stops
+func (d *Dispatcher) enqueue(ctx context.Context, next delivery) error {
+	select {
+	case d.queue <- next:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.ctx.Done():
+		return fmt.Errorf("%v: %w", dispatcherName, d.ctx.Err())
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case next := <-d.queue:
+			d.deliver(next)
+		}
+	}
+}
+
+// deliver makes an attempt, logs it, and schedules a retry or stores a dead letter when it fails
+func (d *Dispatcher) deliver(next delivery) {
+	entry := model.WebhookDelivery{
+		DeliveryID: next.id,
+		WebhookID:  next.webhook.Id,
+		Event:      next.event,
+		ChangeID:   next.changeID,
+		Attempt:    next.attempt,
+		Status:     model.DeliverySucceeded,
+		At:         d.clock.Now().UTC(),
+	}
+	code, err := d.post(next)
+	entry.ResponseCode = code
+
+	var delay time.Duration
+	switch {
+	case err == nil:
+	case next.attempt >= d.maxAttempts:
+		entry.Status, entry.Error = model.DeliveryDead, err.Error()
+	default:
+		delay = d.retryDelay(next.attempt)
+		entry.Status, entry.Error = model.DeliveryFailed, err.Error()
+		entry.NextAttemptAt = entry.At.Add(delay)
+	}
+
+	// the context has no scope, logs and letters are addressed by the webhook id
+	if err := d.storage.AppendDelivery(context.Background(), entry); err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			// the webhook was deleted meanwhile
+			return
+		}
+		d.logger.Log("error in %v: %v", dispatcherName, err)
+	}
+
+	switch entry.Status {
+	case model.DeliveryDead:
+		letter := model.DeadLetter{
+			DeliveryID: next.id,
+			WebhookID:  next.webhook.Id,
+			Event:      next.event,
+			ChangeID:   next.changeID,
+			Payload:    next.payload,
+			Attempts:   next.attempt,
+			LastError:  entry.Error,
+			At:         entry.At,
+		}
+		if err := d.storage.StoreDeadLetter(context.Background(), letter); err != nil && !errors.Is(err, model.ErrNotFound) {
+			d.logger.Log("error in %v: %v", dispatcherName, err)
+		}
+	case model.DeliveryFailed:
+		next.attempt++
+		d.retry(next, delay)
+	}
+}
+
+// retry queues the delivery again after the delay, unless the dispatcher stops first
+func (d *Dispatcher) retry(next delivery, delay time.Duration) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-timer.C:
+			d.enqueue(d.ctx, next)
+		}
+	}()
+}
+
+// retryDelay doubles the backoff with every failed attempt up to the maximum, and picks a random delay
+// from its upper half, so receivers coming back up aren't hit by every retry at once
+func (d *Dispatcher) retryDelay(attempt int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempt && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, d.maxBackoff)
+	half := delay / 2
+	return half + mathrand.N(delay-half+1)
+}
+
+// post sends the delivery, responses other than 2xx are errors
+func (d *Dispatcher) post(next delivery) (int, error) {
+	// deliveries being sent are finished on stop, the client timeout limits them
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, next.webhook.URL, bytes.NewReader(next.payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(d.clock.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "task-manager-webhooks")
+	req.Header.Set(HeaderEvent, string(next.event))
+	req.Header.Set(HeaderDelivery, next.id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(next.webhook.Secret, timestamp, next.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %v", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func newDeliveryID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
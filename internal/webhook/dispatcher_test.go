@@ -0,0 +1,270 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"ivanjabrony/test_lo/internal/broker"
+	"ivanjabrony/test_lo/internal/clock"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/storage"
+	"ivanjabrony/test_lo/internal/tenant"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type MockLogger struct{}
+
+func (m *MockLogger) Log(format string, info ...any) {}
+
+const testSecret = "0123456789abcdef"
+
+// receiver is a webhook endpoint answering with the statuses in turn, the last one repeats
+type receiver struct {
+	statuses  []int
+	requests  []*http.Request
+	payloads  []dto.WebhookPayload
+	signed    []bool
+	m         sync.Mutex
+	server    *httptest.Server
+	delivered chan struct{}
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rc := &receiver{statuses: statuses, delivered: make(chan struct{}, 100)}
+	rc.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload dto.WebhookPayload
+		json.Unmarshal(body, &payload)
+		signed := Verify(testSecret, r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature), time.Minute, time.Now())
+
+		rc.m.Lock()
+		status := rc.statuses[min(len(rc.requests), len(rc.statuses)-1)]
+		rc.requests = append(rc.requests, r)
+		rc.payloads = append(rc.payloads, payload)
+		rc.signed = append(rc.signed, signed)
+		rc.m.Unlock()
+
+		w.WriteHeader(status)
+		rc.delivered <- struct{}{}
+	}))
+	t.Cleanup(rc.server.Close)
+	return rc
+}
+
+// wait waits for n requests to the receiver
+func (rc *receiver) wait(t *testing.T, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case <-rc.delivered:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected %d deliveries", n)
+		}
+	}
+}
+
+// eventually waits for the condition for a second
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Condition wasn't met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newTestDispatcher(t *testing.T, opts ...DispatcherOption) (*Dispatcher, *broker.Broker, *storage.WebhookStorage) {
+	t.Helper()
+	changes, _ := broker.NewBroker(&MockLogger{})
+	webhooks, _ := storage.NewWebhookStorage(&MockLogger{})
+	opts = append([]DispatcherOption{WithBackoff(time.Millisecond, 4*time.Millisecond), WithMaxAttempts(3)}, opts...)
+	dispatcher, err := NewDispatcher(&MockLogger{}, changes, webhooks, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+	dispatcher.Start()
+	t.Cleanup(func() { dispatcher.Stop(context.Background()) })
+	return dispatcher, changes, webhooks
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	open, done := model.Task{Id: 3, Name: "release", Status: model.InProgress}, model.Task{Id: 3, Name: "release", Status: model.Done}
+	completion := model.TaskChange{Type: model.TaskChangeUpdated, ProjectID: model.DefaultProjectId, Task: done, Previous: &open}
+
+	t.Run("signed deliveries of subscribed events", func(t *testing.T) {
+		_, changes, webhooks := newTestDispatcher(t)
+		ci := newReceiver(t, http.StatusOK)
+		other := newReceiver(t, http.StatusOK)
+		hook, _ := webhooks.Store(ctx, model.Webhook{URL: ci.server.URL, Secret: testSecret, Active: true, Events: []model.WebhookEvent{model.WebhookTaskCompleted}})
+		webhooks.Store(tenant.WithScope(ctx, tenant.Scope{ProjectID: 2}), model.Webhook{URL: other.server.URL, Secret: testSecret, Active: true})
+		webhooks.Store(ctx, model.Webhook{URL: other.server.URL, Secret: testSecret, Active: false})
+
+		changes.Publish(model.TaskChange{Type: model.TaskChangeCreated, ProjectID: model.DefaultProjectId, Task: open})
+		changes.Publish(completion)
+		ci.wait(t, 1)
+
+		ci.m.Lock()
+		request, payload, signed := ci.requests[0], ci.payloads[0], ci.signed[0]
+		ci.m.Unlock()
+		if !signed {
+			t.Errorf("Expected a valid signature")
+		}
+		if request.Header.Get(HeaderEvent) != string(model.WebhookTaskCompleted) || request.Header.Get(HeaderDelivery) != payload.DeliveryID {
+			t.Errorf("Unexpected headers %v", request.Header)
+		}
+		if payload.Event != model.WebhookTaskCompleted || payload.ChangeID != 2 || payload.Task.Status != model.Done || payload.Task.Name != "release" {
+			t.Errorf("Unexpected payload %+v", payload)
+		}
+		eventually(t, func() bool {
+			deliveries, _, _ := webhooks.GetDeliveries(ctx, hook, model.DefaultPage)
+			return len(deliveries) == 1 && deliveries[0].Status == model.DeliverySucceeded && deliveries[0].ResponseCode == http.StatusOK
+		})
+
+		select {
+		case <-other.delivered:
+			t.Errorf("Expected no deliveries to inactive webhooks and webhooks of other projects")
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+
+	t.Run("timestamps of the clock", func(t *testing.T) {
+		now := time.Date(2030, time.June, 1, 12, 0, 0, 0, time.UTC)
+		_, changes, webhooks := newTestDispatcher(t, WithClock(clock.NewManual(now)))
+		ci := newReceiver(t, http.StatusOK)
+		hook, _ := webhooks.Store(ctx, model.Webhook{URL: ci.server.URL, Secret: testSecret, Active: true, Events: []model.WebhookEvent{model.WebhookTaskCompleted}})
+
+		changes.Publish(completion)
+		ci.wait(t, 1)
+
+		ci.m.Lock()
+		timestamp := ci.requests[0].Header.Get(HeaderTimestamp)
+		ci.m.Unlock()
+		if timestamp != strconv.FormatInt(now.Unix(), 10) {
+			t.Errorf("Expected the timestamp of the clock, got %v", timestamp)
+		}
+		eventually(t, func() bool {
+			deliveries, _, _ := webhooks.GetDeliveries(ctx, hook, model.DefaultPage)
+			return len(deliveries) == 1 && deliveries[0].At.Equal(now)
+		})
+	})
+
+	t.Run("retries until success", func(t *testing.T) {
+		_, changes, webhooks := newTestDispatcher(t)
+		flaky := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent)
+		hook, _ := webhooks.Store(ctx, model.Webhook{URL: flaky.server.URL, Secret: testSecret, Active: true, Events: []model.WebhookEvent{model.WebhookTaskCompleted}})
+
+		changes.Publish(completion)
+		flaky.wait(t, 3)
+
+		var deliveries []model.WebhookDelivery
+		eventually(t, func() bool {
+			deliveries, _, _ = webhooks.GetDeliveries(ctx, hook, model.DefaultPage)
+			return len(deliveries) == 3
+		})
+		// the log is newest first
+		expected := []model.DeliveryStatus{model.DeliverySucceeded, model.DeliveryFailed, model.DeliveryFailed}
+		for i, delivery := range deliveries {
+			if delivery.Status != expected[i] || delivery.Attempt != 3-i || delivery.DeliveryID != deliveries[0].DeliveryID {
+				t.Errorf("Unexpected attempt %+v", delivery)
+			}
+		}
+		if deliveries[1].NextAttemptAt.Before(deliveries[1].At) || deliveries[1].ResponseCode != http.StatusBadGateway {
+			t.Errorf("Expected the failed attempt to be logged with its retry, got %+v", deliveries[1])
+		}
+	})
+
+	t.Run("dead letters", func(t *testing.T) {
+		dispatcher, changes, webhooks := newTestDispatcher(t)
+		down := newReceiver(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+		hook, _ := webhooks.Store(ctx, model.Webhook{URL: down.server.URL, Secret: testSecret, Active: true, Events: []model.WebhookEvent{model.WebhookTaskDeleted}})
+
+		changes.Publish(model.TaskChange{Type: model.TaskChangeDeleted, ProjectID: model.DefaultProjectId, Task: done})
+		down.wait(t, 3)
+
+		var letters []model.DeadLetter
+		eventually(t, func() bool {
+			letters, _ = webhooks.GetDeadLetters(ctx, hook)
+			return len(letters) == 1
+		})
+		if letters[0].Attempts != 3 || letters[0].Event != model.WebhookTaskDeleted || letters[0].LastError == "" {
+			t.Errorf("Unexpected dead letter %+v", letters[0])
+		}
+		deliveries, _, _ := webhooks.GetDeliveries(ctx, hook, model.DefaultPage)
+		if deliveries[0].Status != model.DeliveryDead {
+			t.Errorf("Expected the last attempt to be dead, got %+v", deliveries[0])
+		}
+
+		webhook, _ := webhooks.GetById(ctx, hook)
+		letter, _ := webhooks.TakeDeadLetter(ctx, hook, letters[0].DeliveryID)
+		if err := dispatcher.Redeliver(ctx, *webhook, *letter); err != nil {
+			t.Fatalf("Redelivery failed: %v", err)
+		}
+		down.wait(t, 1)
+		down.m.Lock()
+		redelivered := down.payloads[3]
+		down.m.Unlock()
+		if redelivered.DeliveryID != letter.DeliveryID {
+			t.Errorf("Expected the same delivery id, got %+v", redelivered)
+		}
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	dispatcher, _ := NewDispatcher(&MockLogger{}, &broker.Broker{}, &storage.WebhookStorage{}, WithBackoff(time.Second, 10*time.Second))
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: 500 * time.Millisecond, max: time.Second},
+		{attempt: 3, min: 2 * time.Second, max: 4 * time.Second},
+		{attempt: 5, min: 5 * time.Second, max: 10 * time.Second},
+		{attempt: 100, min: 5 * time.Second, max: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if delay := dispatcher.retryDelay(tt.attempt); delay < tt.min || delay > tt.max {
+				t.Errorf("Attempt %d: expected delay in %v..%v, got %v", tt.attempt, tt.min, tt.max, delay)
+			}
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	timestamp := "1700000000"
+	body := []byte(`{"event":"task.completed"}`)
+	signature := Sign(testSecret, timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		signature string
+		now       time.Time
+		want      bool
+	}{
+		{name: "valid", secret: testSecret, timestamp: timestamp, body: body, signature: signature, now: now, want: true},
+		{name: "other secret", secret: "fedcba9876543210", timestamp: timestamp, body: body, signature: signature, now: now, want: false},
+		{name: "changed body", secret: testSecret, timestamp: timestamp, body: []byte(`{}`), signature: signature, now: now, want: false},
+		{name: "changed timestamp", secret: testSecret, timestamp: "1700000001", body: body, signature: signature, now: now, want: false},
+		{name: "replayed later", secret: testSecret, timestamp: timestamp, body: body, signature: signature, now: now.Add(time.Hour), want: false},
+		{name: "no prefix", secret: testSecret, timestamp: timestamp, body: body, signature: signature[len("sha256="):], now: now, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, tt.body, tt.signature, 5*time.Minute, tt.now); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the type of a data message
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// opcodes of frames, RFC 6455 section 5.2
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// status codes of close frames, RFC 6455 section 7.4.1
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+)
+
+// maxControlPayload is the largest payload of a control frame
+const maxControlPayload = 125
+
+// ErrCloseSent is returned by writes after the close frame was sent
+var ErrCloseSent = errors.New("websocket: close frame already sent")
+
+// CloseError is returned by ReadMessage when the connection was closed with a close frame,
+// sent by the peer or by the connection itself after the peer broke the protocol
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %v", e.Code, e.Reason)
+}
+
+// Conn is an upgraded websocket connection. One goroutine may read at a time,
+// writes may come from any goroutine.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// isClient conns mask the frames they send and expect unmasked frames
+	isClient bool
+
+	readLimit   int64
+	pongHandler func()
+
+	writeTimeout time.Duration
+	wm           sync.Mutex
+	closeSent    bool
+}
+
+func newConn(conn net.Conn, reader *bufio.Reader, isClient bool) *Conn {
+	if reader == nil {
+		reader = bufio.NewReader(conn)
+	}
+	return &Conn{conn: conn, reader: reader, isClient: isClient}
+}
+
+// SetReadLimit limits the size of a message, bigger messages close the connection
+// with CloseMessageTooBig, 0 means no limit
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetReadDeadline sets the deadline of reading the next frame, the connection is broken after it passes
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetPongHandler sets a function called by ReadMessage on every pong frame
+func (c *Conn) SetPongHandler(handler func()) {
+	c.pongHandler = handler
+}
+
+// SetWriteTimeout limits writing of a single frame, 0 means no limit
+func (c *Conn) SetWriteTimeout(timeout time.Duration) {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	c.writeTimeout = timeout
+}
+
+// ReadMessage returns the next data message, joining its fragments. Pings are answered
+// and pongs are passed to the pong handler on the way.
+//
+// A close frame of the peer is answered and returned as *CloseError, so is a violation of the protocol
+// by the peer after the connection sent a close frame with the matching status.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var messageType MessageType
+	var message []byte
+	for {
+		opcode, fin, payload, err := c.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil && !errors.Is(err, ErrCloseSent) {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			if c.pongHandler != nil {
+				c.pongHandler()
+			}
+			continue
+		case opClose:
+			return 0, nil, c.handleClose(payload)
+		case opContinuation:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType = MessageType(opcode)
+		}
+
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, c.fail(CloseInvalidPayload, "invalid utf-8 in text message")
+		}
+		return messageType, message, nil
+	}
+}
+
+// readFrame reads the next frame, buffered is the size of the fragments of the message read before it
+func (c *Conn) readFrame(buffered int64) (opcode byte, fin bool, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return 0, false, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return 0, false, nil, c.fail(CloseProtocolError, "reserved bits are set")
+	}
+	if masked == c.isClient {
+		return 0, false, nil, c.fail(CloseProtocolError, "invalid masking of the frame")
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return 0, false, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return 0, false, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+		if length>>63 != 0 {
+			return 0, false, nil, c.fail(CloseProtocolError, "invalid frame length")
+		}
+	}
+
+	switch opcode {
+	case opClose, opPing, opPong:
+		if !fin || length > maxControlPayload {
+			return 0, false, nil, c.fail(CloseProtocolError, "invalid control frame")
+		}
+	case opContinuation, opText, opBinary:
+		if c.readLimit > 0 && buffered+int64(length) > c.readLimit {
+			return 0, false, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+	default:
+		return 0, false, nil, c.fail(CloseProtocolError, "unknown opcode")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return 0, false, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, false, nil, err
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return opcode, fin, payload, nil
+}
+
+// handleClose answers the close frame of the peer with its status
+func (c *Conn) handleClose(payload []byte) error {
+	code, reason := CloseNoStatus, ""
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		reason = string(payload[2:])
+		if !validCloseCode(code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(reason) {
+			return c.fail(CloseInvalidPayload, "invalid utf-8 in close reason")
+		}
+	}
+
+	reply := code
+	if code == CloseNoStatus {
+		reply = CloseNormal
+	}
+	c.writeClose(reply, "")
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// validCloseCode reports whether the code may be sent in a close frame
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// fail closes the connection with the status after the peer broke the protocol
+func (c *Conn) fail(code int, reason string) error {
+	c.writeClose(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// WriteMessage sends the data as a single frame
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return c.writeFrame(byte(messageType), data)
+}
+
+// Ping sends a ping frame, the peer answers it with a pong
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: ping payload too big")
+	}
+	return c.writeFrame(opPing, data)
+}
+
+// Close sends a close frame with the status, unless one was sent already, and closes the connection
+func (c *Conn) Close(code int, reason string) error {
+	c.writeClose(code, reason)
+	return c.conn.Close()
+}
+
+// writeClose sends a close frame once, nothing can be written after it
+func (c *Conn) writeClose(code int, reason string) error {
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	c.closeSent = true
+	return c.writeFrameLocked(opClose, payload)
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+// writeFrameLocked writes a final frame, must be called under lock
+func (c *Conn) writeFrameLocked(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+
+	var maskBit byte
+	if c.isClient {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	if c.isClient {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	if c.writeTimeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+			return err
+		}
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// maskBytes masks or unmasks the payload in place, RFC 6455 section 5.3
+func maskBytes(mask [4]byte, payload []byte) {
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+}
@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Dial opens a client connection to a ws:// or wss:// url, header is sent with the handshake.
+// The response of the server is returned when the handshake fails, its body is closed.
+func Dial(ctx context.Context, rawURL string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("websocket dial: %w", err)
+	}
+	var dialer interface {
+		DialContext(ctx context.Context, network, address string) (net.Conn, error)
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme, dialer = "http", &net.Dialer{}
+	case "wss":
+		u.Scheme, dialer = "https", &tls.Dialer{}
+	default:
+		return nil, nil, fmt.Errorf("websocket dial: unsupported scheme %q", u.Scheme)
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), map[string]string{"http": "80", "https": "443"}[u.Scheme])
+	}
+
+	netConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("websocket dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: header.Clone()}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, fmt.Errorf("websocket dial: %w", err)
+	}
+	reader := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, fmt.Errorf("websocket dial: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		resp.Body.Close()
+		netConn.Close()
+		return nil, resp, fmt.Errorf("websocket dial: handshake failed with %v", resp.Status)
+	}
+
+	netConn.SetDeadline(time.Time{})
+	return newConn(netConn, reader, true), resp, nil
+}
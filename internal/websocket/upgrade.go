@@ -0,0 +1,100 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// acceptGUID is appended to the key of the client to prove the server speaks websocket, RFC 6455 section 1.3
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError is a rejected upgrade request, nothing is written to the client yet,
+// so the caller responds with Status
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("websocket handshake: %v", e.Message)
+}
+
+// Upgrade validates the opening handshake, takes over the connection of the request and responds
+// with 101 Switching Protocols. Browsers are only let in from the origin of the request host or
+// one of allowedOrigins, clients sending no Origin aren't browsers and are always let in.
+//
+// After a successful upgrade w must not be used anymore.
+func Upgrade(w http.ResponseWriter, r *http.Request, allowedOrigins ...string) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, &HandshakeError{http.StatusMethodNotAllowed, "upgrade requires GET"}
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, &HandshakeError{http.StatusBadRequest, "not a websocket upgrade request"}
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, &HandshakeError{http.StatusUpgradeRequired, "unsupported websocket version"}
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, &HandshakeError{http.StatusBadRequest, "invalid Sec-WebSocket-Key"}
+	}
+	if !originAllowed(r, allowedOrigins) {
+		return nil, &HandshakeError{http.StatusForbidden, "origin not allowed"}
+	}
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, &HandshakeError{http.StatusInternalServerError, "connection can't be upgraded"}
+	}
+	// deadlines of the http server don't apply to the upgraded connection
+	netConn.SetDeadline(time.Time{})
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n\r\n"
+	if _, err := netConn.Write([]byte(response)); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("websocket handshake: %w", err)
+	}
+
+	// the client may send frames right after the handshake, they can be buffered already
+	return newConn(netConn, rw.Reader, false), nil
+}
+
+// AcceptKey returns Sec-WebSocket-Accept for the Sec-WebSocket-Key of the client
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContainsToken reports whether the comma separated header has the token, case insensitively
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func originAllowed(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if slices.Contains(allowedOrigins, origin) {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
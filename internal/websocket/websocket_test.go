@@ -0,0 +1,219 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// the example of RFC 6455 section 1.3
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept key %q", got)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		header         map[string]string
+		allowedOrigins []string
+		expectedStatus int
+	}{
+		{name: "not get", method: "POST", expectedStatus: http.StatusMethodNotAllowed},
+		{name: "not an upgrade", header: map[string]string{"Connection": "keep-alive"}, expectedStatus: http.StatusBadRequest},
+		{name: "old version", header: map[string]string{"Sec-WebSocket-Version": "8"}, expectedStatus: http.StatusUpgradeRequired},
+		{name: "invalid key", header: map[string]string{"Sec-WebSocket-Key": "short"}, expectedStatus: http.StatusBadRequest},
+		{name: "foreign origin", header: map[string]string{"Origin": "https://evil.example"}, expectedStatus: http.StatusForbidden},
+		// the recorder can't be hijacked, so handshakes passing validation fail there
+		{name: "same origin", header: map[string]string{"Origin": "http://example.com"}, expectedStatus: http.StatusInternalServerError},
+		{name: "allowed origin", header: map[string]string{"Origin": "https://app.example"}, allowedOrigins: []string{"https://app.example"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req := httptest.NewRequest(method, "http://example.com/ws", nil)
+			req.Header.Set("Connection", "keep-alive, Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+
+			_, err := Upgrade(httptest.NewRecorder(), req, tt.allowedOrigins...)
+			var handshakeErr *HandshakeError
+			if !errors.As(err, &handshakeErr) || handshakeErr.Status != tt.expectedStatus {
+				t.Errorf("Expected handshake error with status %d, got %v", tt.expectedStatus, err)
+			}
+		})
+	}
+}
+
+func TestConn(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		conn.SetReadLimit(1 << 20)
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				conn.Close(CloseNormal, "")
+				return
+			}
+			conn.WriteMessage(messageType, message)
+		}
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := Dial(ctx, "ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	pongs := 0
+	conn.SetPongHandler(func() { pongs++ })
+	if err := conn.Ping([]byte("ping")); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	messages := []struct {
+		messageType MessageType
+		data        []byte
+	}{
+		{TextMessage, []byte("hello")},
+		{BinaryMessage, bytes.Repeat([]byte{7}, 300)},
+		{TextMessage, bytes.Repeat([]byte("a"), 70000)},
+	}
+	for _, message := range messages {
+		if err := conn.WriteMessage(message.messageType, message.data); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		messageType, data, err := conn.ReadMessage()
+		if err != nil || messageType != message.messageType || !bytes.Equal(data, message.data) {
+			t.Errorf("Expected the echo of %d bytes, got %d bytes, %v", len(message.data), len(data), err)
+		}
+	}
+	if pongs != 1 {
+		t.Errorf("Expected a pong, got %d", pongs)
+	}
+
+	conn.WriteMessage(BinaryMessage, make([]byte, 1<<20+1))
+	var closeErr *CloseError
+	if _, _, err := conn.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != CloseMessageTooBig {
+		t.Errorf("Expected the server to close with %d, got %v", CloseMessageTooBig, err)
+	}
+	conn.Close(CloseNormal, "")
+}
+
+// clientFrame encodes a masked frame the way a client sends it
+func clientFrame(opcode byte, fin bool, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	mask := [4]byte{1, 2, 3, 4}
+	frame = append(frame, mask[:]...)
+	masked := append([]byte(nil), payload...)
+	maskBytes(mask, masked)
+	return append(frame, masked...)
+}
+
+func TestReadMessage(t *testing.T) {
+	closePayload := binary.BigEndian.AppendUint16(nil, 4000)
+
+	tests := []struct {
+		name            string
+		frames          [][]byte
+		expectedMessage string
+		expectedClose   int
+	}{
+		{name: "single frame", frames: [][]byte{clientFrame(opText, true, []byte("task"))}, expectedMessage: "task"},
+		{
+			name: "fragments with a ping between them",
+			frames: [][]byte{
+				clientFrame(opText, false, []byte("ta")),
+				clientFrame(opPing, true, nil),
+				clientFrame(opContinuation, true, []byte("sk")),
+			},
+			expectedMessage: "task",
+		},
+		{name: "unmasked frame", frames: [][]byte{{0x81, 0x01, 'a'}}, expectedClose: CloseProtocolError},
+		{name: "reserved bits", frames: [][]byte{clientFrame(0x40|opText, true, []byte("a"))}, expectedClose: CloseProtocolError},
+		{name: "unknown opcode", frames: [][]byte{clientFrame(0x3, true, nil)}, expectedClose: CloseProtocolError},
+		{name: "fragmented ping", frames: [][]byte{clientFrame(opPing, false, nil)}, expectedClose: CloseProtocolError},
+		{name: "lone continuation", frames: [][]byte{clientFrame(opContinuation, true, []byte("a"))}, expectedClose: CloseProtocolError},
+		{
+			name:          "interleaved messages",
+			frames:        [][]byte{clientFrame(opText, false, []byte("a")), clientFrame(opText, true, []byte("b"))},
+			expectedClose: CloseProtocolError,
+		},
+		{name: "invalid utf-8", frames: [][]byte{clientFrame(opText, true, []byte{0xff, 0xfe})}, expectedClose: CloseInvalidPayload},
+		{name: "too big", frames: [][]byte{clientFrame(opBinary, true, make([]byte, 20))}, expectedClose: CloseMessageTooBig},
+		{name: "close by peer", frames: [][]byte{clientFrame(opClose, true, closePayload)}, expectedClose: 4000},
+		{name: "invalid close code", frames: [][]byte{clientFrame(opClose, true, binary.BigEndian.AppendUint16(nil, 1005))}, expectedClose: CloseProtocolError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			conn := newConn(server, nil, false)
+			conn.SetReadLimit(16)
+			go func() {
+				for _, frame := range tt.frames {
+					if _, err := client.Write(frame); err != nil {
+						return
+					}
+				}
+			}()
+			// the pipe blocks writes of the server until they are read
+			replies := make(chan []byte)
+			go func() {
+				data, _ := io.ReadAll(client)
+				replies <- data
+			}()
+
+			_, message, err := conn.ReadMessage()
+			conn.Close(CloseNormal, "")
+			reply := <-replies
+
+			if tt.expectedClose == 0 {
+				if err != nil || string(message) != tt.expectedMessage {
+					t.Errorf("Expected %q, got %q, %v", tt.expectedMessage, message, err)
+				}
+				return
+			}
+			var closeErr *CloseError
+			if !errors.As(err, &closeErr) || closeErr.Code != tt.expectedClose {
+				t.Fatalf("Expected close with %d, got %v", tt.expectedClose, err)
+			}
+			// the close frame sent back carries the status of the peer or of the violation
+			closeFrame := bytes.Index(reply, []byte{0x80 | opClose})
+			if closeFrame < 0 || len(reply) < closeFrame+4 {
+				t.Fatalf("Expected a close frame in %v", reply)
+			}
+			if code := int(binary.BigEndian.Uint16(reply[closeFrame+2:])); code != tt.expectedClose {
+				t.Errorf("Expected close frame with %d, got %d", tt.expectedClose, code)
+			}
+		})
+	}
+}
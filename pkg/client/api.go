@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/graphql"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/websocket"
+	"net/http"
+)
+
+// GraphQL runs the query and decodes its data into data, which may be nil. Errors of the executed query
+// are returned as *GraphQLError after the data of the fields that didn't fail is decoded.
+func (c *Client) GraphQL(ctx context.Context, query dto.GraphQLRequest, data any) error {
+	var response struct {
+		Data   json.RawMessage  `json:"data"`
+		Errors []*graphql.Error `json:"errors"`
+	}
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/graphql", scoped: true, body: query}, &response); err != nil {
+		return err
+	}
+	if data != nil && len(response.Data) > 0 && string(response.Data) != "null" {
+		if err := json.Unmarshal(response.Data, data); err != nil {
+			return fmt.Errorf("%v: decoding graphql data: %w", clientName, err)
+		}
+	}
+	if len(response.Errors) > 0 {
+		return &GraphQLError{Errors: response.Errors}
+	}
+	return nil
+}
+
+// GraphQLSchema returns the graphql schema in the schema definition language
+func (c *Client) GraphQLSchema(ctx context.Context) (string, error) {
+	return c.getText(ctx, "/graphql/schema")
+}
+
+// Health returns nil when the server is up
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.getText(ctx, "/health")
+	return err
+}
+
+// OpenAPI returns the OpenAPI document of the server
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var document json.RawMessage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/openapi.json"}, &document)
+	return document, err
+}
+
+// DialWebSocket opens a connection to the WebSocket API, messages are dto.SocketRequest and dto.SocketResponse.
+// Dial errors aren't retried.
+func (c *Client) DialWebSocket(ctx context.Context) (*websocket.Conn, error) {
+	u := *c.baseURL
+	u.Path += c.projectPrefix + "/ws"
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+
+	header := http.Header{"User-Agent": {c.userAgent}}
+	if c.auth != nil {
+		// the header of a stand-in request carries the credentials, Dial sends them with the handshake
+		authReq := &http.Request{Method: http.MethodGet, URL: &u, Header: header}
+		authReq = authReq.WithContext(ctx)
+		if err := c.auth.Apply(authReq); err != nil {
+			return nil, fmt.Errorf("%v: GET /ws: authenticating: %w", clientName, err)
+		}
+	}
+
+	conn, resp, err := websocket.Dial(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil && resp.StatusCode >= 400 {
+			return nil, newAPIError(resp)
+		}
+		return nil, fmt.Errorf("%v: GET /ws: %w", clientName, err)
+	}
+	return conn, nil
+}
+
+func (c *Client) getText(ctx context.Context, path string) (string, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: path})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("%v: GET %v: reading response: %w", clientName, path, err)
+	}
+	return string(body), nil
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ListAudit returns a page of the audit entries matching the filter, oldest first
+func (c *Client) ListAudit(ctx context.Context, filter AuditFilter, page model.Page) (AuditList, error) {
+	query := url.Values{}
+	if filter.ProjectID != 0 {
+		query.Set("project_id", strconv.Itoa(filter.ProjectID))
+	}
+	if filter.TaskID != "" {
+		query.Set("task_id", string(filter.TaskID))
+	}
+	if filter.ActorID != 0 {
+		query.Set("actor_id", strconv.Itoa(filter.ActorID))
+	}
+	if filter.Action != "" {
+		query.Set("action", string(filter.Action))
+	}
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.Format(time.RFC3339))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.Format(time.RFC3339))
+	}
+	addPage(query, page)
+
+	var response AuditList
+	err := c.do(ctx, request{method: http.MethodGet, path: "/audit", query: query}, &response)
+	return response, err
+}
+
+// VerifyAudit checks the hash chain of the audit log
+func (c *Client) VerifyAudit(ctx context.Context) (dto.VerifyAuditResponse, error) {
+	var response dto.VerifyAuditResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/audit/verify"}, &response)
+	return response, err
+}
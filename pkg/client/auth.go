@@ -0,0 +1,47 @@
+package client
+
+import (
+	"errors"
+	"ivanjabrony/test_lo/internal/auth"
+	"net/http"
+)
+
+// Auth adds credentials to a request, it's applied to every attempt of a call
+type Auth interface {
+	Apply(r *http.Request) error
+}
+
+// AuthFunc adapts a function to Auth
+type AuthFunc func(r *http.Request) error
+
+func (f AuthFunc) Apply(r *http.Request) error {
+	return f(r)
+}
+
+// APIKey authenticates with a static api key
+func APIKey(key string) Auth {
+	return AuthFunc(func(r *http.Request) error {
+		r.Header.Set(auth.APIKeyHeader, key)
+		return nil
+	})
+}
+
+// BearerToken authenticates with a jwt
+func BearerToken(token string) Auth {
+	return TokenSource(func(r *http.Request) (string, error) { return token, nil })
+}
+
+// TokenSource authenticates with a jwt returned by source, so tokens can be refreshed between calls
+func TokenSource(source func(r *http.Request) (string, error)) Auth {
+	return AuthFunc(func(r *http.Request) error {
+		token, err := source(r)
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			return errors.New("empty bearer token")
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
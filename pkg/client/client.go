@@ -0,0 +1,271 @@
+// Package client is a typed client of the task API.
+//
+// Requests and responses are the dto and model types the server uses, so the client can't drift from it.
+// Idempotent calls are retried with exponential backoff, errors of the API are returned as *APIError
+// matching the errors of the model package with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/requestid"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const clientName = "task client"
+
+// RetryPolicy decides how idempotent calls are retried after network errors and 429, 502, 503 and 504 responses.
+// A delay doubles after every attempt up to MaxDelay, Retry-After of the response is used when it's given.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt too, 1 disables retries
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// Client calls the task API, it's safe for concurrent use
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	auth       Auth
+	retry      RetryPolicy
+	userAgent  string
+	// projectPrefix scopes task, tag, comment, webhook and graphql calls to a project, empty uses the default one
+	projectPrefix string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends requests with the client instead of http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAuth authenticates every request
+func WithAuth(auth Auth) Option {
+	return func(c *Client) {
+		c.auth = auth
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithProject scopes the calls of project routes to the project
+func WithProject(projectId int) Option {
+	return func(c *Client) {
+		c.projectPrefix = "/projects/" + strconv.Itoa(projectId)
+	}
+}
+
+// New creates a client of the API served at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%v: invalid base url %q", clientName, baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{baseURL: u, httpClient: http.DefaultClient, retry: DefaultRetryPolicy, userAgent: "taskclient"}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil || c.retry.MaxAttempts < 1 || c.retry.BaseDelay < 0 || c.retry.MaxDelay < c.retry.BaseDelay {
+		return nil, fmt.Errorf("nil values in %v constructor", clientName)
+	}
+	return c, nil
+}
+
+// InProject returns a copy of the client with project routes scoped to the project
+func (c *Client) InProject(projectId int) *Client {
+	scoped := *c
+	WithProject(projectId)(&scoped)
+	return &scoped
+}
+
+// request describes a call, body is encoded as json unless rawBody is set
+type request struct {
+	method string
+	path   string
+	// scoped paths are prefixed with the project of the client
+	scoped      bool
+	query       url.Values
+	header      http.Header
+	body        any
+	rawBody     io.Reader
+	contentType string
+}
+
+// do sends the request and decodes the json response into out, out may be nil for responses without a body
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%v: %v %v: decoding response: %w", clientName, req.method, req.path, err)
+	}
+	return nil
+}
+
+// send sends the request, retrying idempotent ones, the body of a successful response is left to the caller.
+// Responses with error statuses are returned as *APIError.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+	if req.rawBody == nil && req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, fmt.Errorf("%v: %v %v: encoding request: %w", clientName, req.method, req.path, err)
+		}
+		req.contentType = "application/json"
+	}
+
+	attempts := 1
+	if isIdempotent(req.method) && req.rawBody == nil {
+		attempts = c.retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		httpReq, err := c.newHTTPRequest(ctx, req, body)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.httpClient.Do(httpReq)
+		if err == nil && resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		var retryAfter time.Duration
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%v: %v %v: %w", clientName, req.method, req.path, ctx.Err())
+			}
+			err = fmt.Errorf("%v: %v %v: %w", clientName, req.method, req.path, err)
+		} else {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			err = newAPIError(resp)
+			if !isRetryableStatus(resp.StatusCode) {
+				return nil, err
+			}
+		}
+		if attempt >= attempts {
+			return nil, err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%v: %v %v: %w", clientName, req.method, req.path, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) newHTTPRequest(ctx context.Context, req request, body []byte) (*http.Request, error) {
+	u := *c.baseURL
+	if req.scoped {
+		u.Path += c.projectPrefix
+	}
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	var reader io.Reader = req.rawBody
+	if reader == nil && body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v %v: %w", clientName, req.method, req.path, err)
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if c.auth != nil {
+		if err := c.auth.Apply(httpReq); err != nil {
+			return nil, fmt.Errorf("%v: %v %v: authenticating: %w", clientName, req.method, req.path, err)
+		}
+	}
+	return httpReq, nil
+}
+
+// backoff returns the delay before the next attempt, a random half of it spreads retries of many clients
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, c.retry.MaxDelay)
+	}
+	delay := c.retry.BaseDelay << min(attempt-1, 30)
+	if delay <= 0 || delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int64N(half+1))
+	}
+	return delay
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses delay-seconds and http-date values, invalid values are ignored
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// requestIDOf returns the id the server gave to the request of the response
+func requestIDOf(resp *http.Response) string {
+	return resp.Header.Get(requestid.Header)
+}
@@ -0,0 +1,681 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"ivanjabrony/test_lo/cmd/app"
+	"ivanjabrony/test_lo/internal/auth"
+	"ivanjabrony/test_lo/internal/config"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"ivanjabrony/test_lo/internal/server"
+	"ivanjabrony/test_lo/internal/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type MockLogger struct{}
+
+func (m *MockLogger) Log(format string, info ...any) {}
+
+const testAPIKey = "secret"
+
+// newTestAPI serves the application built from the config the way the binary does, every call of the client
+// is checked against the OpenAPI document of the server. configure changes the config of the tests.
+func newTestAPI(t *testing.T, configure ...func(*config.Config)) *httptest.Server {
+	t.Helper()
+	cfg := &config.Config{
+		AuthEnabled:               true,
+		APIKeys:                   "ci:0:admin:" + auth.HashAPIKey(testAPIKey),
+		StrictTaskCompletion:      true,
+		TaskStorage:               "memory",
+		StreamReplayBuffer:        16,
+		StreamClientBuffer:        16,
+		StreamHeartbeatSeconds:    1,
+		WSMaxMessageSize:          65536,
+		WSPingIntervalSeconds:     30,
+		WSRateLimit:               20,
+		WSRateBurst:               40,
+		WebhookWorkers:            1,
+		WebhookMaxAttempts:        1,
+		WebhookBackoffSeconds:     1,
+		WebhookTimeoutSeconds:     1,
+		RecurrenceIntervalSeconds: 30,
+		ReminderIntervalSeconds:   60,
+		ReminderDueSoonHours:      24,
+		ReminderStaleHours:        72,
+		NotifyDefaultChannels:     "log",
+		GraphQLMaxDepth:           10,
+		GraphQLMaxComplexity:      1000,
+	}
+	cfg.WebhookMaxBackoffSeconds = cfg.WebhookBackoffSeconds
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	handlers, workers, _, err := app.InitializeAdapters(cfg, &MockLogger{})
+	if err != nil {
+		t.Fatalf("Failed to initialize adapters: %v", err)
+	}
+	workers.Webhooks.Start()
+	t.Cleanup(func() { workers.Webhooks.Stop(context.Background()) })
+
+	srv, err := server.NewHTTP(cfg, &MockLogger{}, server.Handlers{
+		Task:         handlers.Task,
+		User:         handlers.User,
+		Project:      handlers.Project,
+		Tag:          handlers.Tag,
+		Comment:      handlers.Comment,
+		Audit:        handlers.Audit,
+		Socket:       handlers.Socket,
+		Webhook:      handlers.Webhook,
+		GraphQL:      handlers.GraphQL,
+		Notification: handlers.Notification,
+	}, server.WithContractValidation(func(r *http.Request, err error) {
+		t.Errorf("Contract violation: %v", err)
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestClient(t *testing.T, baseURL string, opts ...Option) *Client {
+	t.Helper()
+	opts = append([]Option{
+		WithAuth(APIKey(testAPIKey)),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}),
+	}, opts...)
+	c, err := New(baseURL, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return c
+}
+
+func TestClientTasks(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newTestAPI(t).URL)
+
+	ids := make([]TaskID, 0)
+	for _, name := range []string{"first", "second", "third", "fourth", "fifth"} {
+		id, err := c.CreateTask(ctx, PostTaskRequest{Name: name, Description: "task", Status: StatusCreated, Priority: PriorityHigh})
+		if err != nil {
+			t.Fatalf("CreateTask failed: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	status := StatusInProgress
+	updated, err := c.UpdateTask(ctx, ids[0], PatchTaskRequest{Status: &status})
+	if err != nil || updated.Status != StatusInProgress {
+		t.Fatalf("UpdateTask returned %+v, %v", updated, err)
+	}
+	task, err := c.GetTask(ctx, ids[0])
+	if err != nil || task.Name != "first" || task.Status != StatusInProgress {
+		t.Fatalf("GetTask returned %+v, %v", task, err)
+	}
+
+	filtered, err := c.ListTasks(ctx, Filter{Status: StatusCreated, SortBy: model.SortById, Descending: true})
+	if err != nil || filtered.Amount != 4 || filtered.Tasks[0].ID != ids[4] {
+		t.Fatalf("ListTasks returned %+v, %v", filtered, err)
+	}
+
+	page, err := c.ListTasksPage(ctx, Filter{}, Page{Limit: 2, Offset: 4})
+	if err != nil || page.Amount != 1 || page.Total != 5 || page.Limit != 2 || page.Offset != 4 {
+		t.Fatalf("ListTasksPage returned %+v, %v", page, err)
+	}
+
+	var iterated []TaskID
+	for task, err := range c.Tasks(ctx, Filter{}, 2) {
+		if err != nil {
+			t.Fatalf("Tasks failed: %v", err)
+		}
+		iterated = append(iterated, task.ID)
+	}
+	if len(iterated) != len(ids) {
+		t.Errorf("Expected the iterator to yield %v, got %v", ids, iterated)
+	}
+	for task := range c.Tasks(ctx, Filter{}, 2) {
+		if task.ID != ids[0] {
+			t.Errorf("Expected the iterator to start with %v, got %v", ids[0], task.ID)
+		}
+		break
+	}
+
+	tagId, err := c.CreateTag(ctx, PostTagRequest{Name: "backend"})
+	if err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+	if _, err := c.AttachTag(ctx, ids[1], tagId); err != nil {
+		t.Fatalf("AttachTag failed: %v", err)
+	}
+	tagged, err := c.ListTasks(ctx, Filter{Tags: []string{"backend"}, TagMatch: TagMatchAll})
+	if err != nil || tagged.Amount != 1 || tagged.Tasks[0].ID != ids[1] {
+		t.Fatalf("ListTasks by tag returned %+v, %v", tagged, err)
+	}
+
+	if _, err := c.AddBlocker(ctx, ids[2], ids[3]); err != nil {
+		t.Fatalf("AddBlocker failed: %v", err)
+	}
+	if _, err := c.AddBlocker(ctx, ids[3], ids[2]); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected ErrCycle, got %v", err)
+	}
+	done := StatusDone
+	if _, err := c.UpdateTask(ctx, ids[2], PatchTaskRequest{Status: &done}); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("Expected ErrIncomplete, got %v", err)
+	}
+
+	commentId, err := c.CreateComment(ctx, ids[0], PostCommentRequest{Body: "looks good"})
+	if err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	comments, err := c.ListComments(ctx, ids[0], Page{})
+	if err != nil || comments.Total != 1 || comments.Comments[0].Id != commentId || comments.Comments[0].TaskID != ids[0] {
+		t.Fatalf("ListComments returned %+v, %v", comments, err)
+	}
+
+	history, err := c.GetTaskHistory(ctx, ids[0], Page{Limit: 10})
+	if err != nil || history.Total != 2 {
+		t.Fatalf("GetTaskHistory returned %+v, %v", history, err)
+	}
+	audit, err := c.ListAudit(ctx, AuditFilter{TaskID: ids[0], Action: model.AuditStatusChange}, Page{})
+	if err != nil || audit.Total != 1 || audit.Entries[0].TaskID != ids[0] {
+		t.Fatalf("ListAudit returned %+v, %v", audit, err)
+	}
+	if verified, err := c.VerifyAudit(ctx); err != nil || !verified.Valid {
+		t.Fatalf("VerifyAudit returned %+v, %v", verified, err)
+	}
+
+	if err := c.DeleteTask(ctx, ids[4]); err != nil {
+		t.Fatalf("DeleteTask failed: %v", err)
+	}
+	_, err = c.GetTask(ctx, ids[4])
+	var apiErr *APIError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.RequestID == "" {
+		t.Errorf("Expected a not found APIError with a request id, got %#v", err)
+	}
+}
+
+func TestClientPublicIds(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newTestAPI(t, func(cfg *config.Config) { cfg.IDStrategy = "uuidv7" }).URL)
+
+	parentId, err := c.CreateTask(ctx, PostTaskRequest{Name: "parent", Status: StatusCreated})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	if _, err := strconv.Atoi(string(parentId)); err == nil {
+		t.Fatalf("Expected a public id, got %q", parentId)
+	}
+	childId, err := c.CreateTask(ctx, PostTaskRequest{Name: "child", Status: StatusCreated, ParentID: parentId.Ref()})
+	if err != nil {
+		t.Fatalf("CreateTask with a parent failed: %v", err)
+	}
+	if _, err := c.CreateTask(ctx, PostTaskRequest{Name: "orphan", Status: StatusCreated, ParentID: TaskID("missing").Ref()}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for a missing parent, got %v", err)
+	}
+
+	child, err := c.GetTask(ctx, childId)
+	if err != nil || child.ID != childId || child.ParentID == nil || *child.ParentID != parentId {
+		t.Fatalf("GetTask returned %+v, %v", child, err)
+	}
+	blocked, err := c.AddBlocker(ctx, parentId, childId)
+	if err != nil || len(blocked.BlockedBy) != 1 || blocked.BlockedBy[0] != childId {
+		t.Fatalf("AddBlocker returned %+v, %v", blocked, err)
+	}
+	tree, err := c.GetSubtasks(ctx, parentId, 1)
+	if err != nil || len(tree.Subtasks) != 1 || tree.Subtasks[0].ID != childId {
+		t.Fatalf("GetSubtasks returned %+v, %v", tree, err)
+	}
+	list, err := c.ListTasks(ctx, Filter{})
+	if err != nil || list.Amount != 2 || list.Tasks[0].ID != parentId {
+		t.Fatalf("ListTasks returned %+v, %v", list, err)
+	}
+
+	if _, err := c.CreateComment(ctx, childId, PostCommentRequest{Body: "soon"}); err != nil {
+		t.Fatalf("CreateComment failed: %v", err)
+	}
+	comments, err := c.ListComments(ctx, childId, Page{})
+	if err != nil || comments.Total != 1 || comments.Comments[0].TaskID != childId {
+		t.Fatalf("ListComments returned %+v, %v", comments, err)
+	}
+	audit, err := c.ListAudit(ctx, AuditFilter{TaskID: childId}, Page{})
+	if err != nil || audit.Total != 1 || audit.Entries[0].TaskID != childId {
+		t.Fatalf("ListAudit returned %+v, %v", audit, err)
+	}
+
+	imported, err := c.ImportTasks(ctx, FormatNDJSON, strings.NewReader(`{"name": "imported", "status": "created", "parent_id": "`+string(parentId)+`"}`+"\n"), ImportOptions{})
+	if err != nil || imported.Imported != 1 || len(imported.Ids) != 1 || imported.Ids[0] == "" {
+		t.Fatalf("ImportTasks returned %+v, %v", imported, err)
+	}
+	if _, err := c.GetTask(ctx, "0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ids not to address tasks with public ids, got %v", err)
+	}
+}
+
+func TestClientTransfer(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newTestAPI(t).URL)
+
+	body := `{"name": "imported", "description": "a", "status": "created"}` + "\n" + `{"name": "invalid", "status": "unknown"}` + "\n"
+	imported, err := c.ImportTasks(ctx, FormatNDJSON, strings.NewReader(body), ImportOptions{})
+	if err != nil || imported.Imported != 1 || imported.Failed != 1 {
+		t.Fatalf("ImportTasks returned %+v, %v", imported, err)
+	}
+
+	export, err := c.ExportTasks(ctx, FormatCSV, Filter{Status: StatusCreated})
+	if err != nil {
+		t.Fatalf("ExportTasks failed: %v", err)
+	}
+	defer export.Close()
+	data, _ := io.ReadAll(export)
+	if !strings.Contains(string(data), "imported") {
+		t.Errorf("Expected the export to contain the imported task, got %q", data)
+	}
+}
+
+func TestClientProjects(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newTestAPI(t).URL)
+
+	quota := 1
+	projectId, err := c.CreateProject(ctx, PostProjectRequest{Name: "limited", TaskQuota: &quota})
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	scoped := c.InProject(projectId)
+	if _, err := scoped.CreateTask(ctx, PostTaskRequest{Name: "task", Status: StatusCreated}); err != nil {
+		t.Fatalf("CreateTask in the project failed: %v", err)
+	}
+	if _, err := scoped.CreateTask(ctx, PostTaskRequest{Name: "task", Status: StatusCreated}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	inProject, err := scoped.ListTasks(ctx, Filter{})
+	if err != nil || inProject.Amount != 1 {
+		t.Fatalf("ListTasks in the project returned %+v, %v", inProject, err)
+	}
+	inDefault, err := c.ListTasks(ctx, Filter{})
+	if err != nil || inDefault.Amount != 0 {
+		t.Fatalf("ListTasks in the default project returned %+v, %v", inDefault, err)
+	}
+
+	userId, err := c.CreateUser(ctx, PostUserRequest{Name: "john", Email: "john@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := c.CreateUser(ctx, PostUserRequest{Name: "john", Email: "john@example.com"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists, got %v", err)
+	}
+	if err := c.DeleteUser(ctx, userId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	webhook, err := c.CreateWebhook(ctx, PostWebhookRequest{URL: "http://127.0.0.1:1/hook"})
+	if err != nil || webhook.Secret == "" {
+		t.Fatalf("CreateWebhook returned %+v, %v", webhook, err)
+	}
+	webhooks, err := c.ListWebhooks(ctx)
+	if err != nil || webhooks.Amount != 1 {
+		t.Fatalf("ListWebhooks returned %+v, %v", webhooks, err)
+	}
+	if err := c.Redeliver(ctx, webhook.Id, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClientAPI(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestAPI(t)
+	c := newTestClient(t, ts.URL)
+
+	if err := c.Health(ctx); err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	document, err := c.OpenAPI(ctx)
+	if err != nil || !strings.Contains(string(document), `"openapi"`) {
+		t.Fatalf("OpenAPI returned %.40s, %v", document, err)
+	}
+	schema, err := c.GraphQLSchema(ctx)
+	if err != nil || !strings.Contains(schema, "type Query") {
+		t.Fatalf("GraphQLSchema returned %.40s, %v", schema, err)
+	}
+
+	if _, err := c.CreateTask(ctx, PostTaskRequest{Name: "task", Status: StatusCreated}); err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	var data struct {
+		Tasks struct {
+			Total int `json:"total"`
+		} `json:"tasks"`
+	}
+	if err := c.GraphQL(ctx, GraphQLRequest{Query: "{ tasks { total } }"}, &data); err != nil || data.Tasks.Total != 1 {
+		t.Fatalf("GraphQL returned %+v, %v", data, err)
+	}
+	var gqlErr *GraphQLError
+	if err := c.GraphQL(ctx, GraphQLRequest{Query: "{ task(id: 404) { name } }"}, nil); !errors.As(err, &gqlErr) || len(gqlErr.Errors) != 1 {
+		t.Errorf("Expected a GraphQLError, got %v", err)
+	}
+
+	conn, err := c.DialWebSocket(ctx)
+	if err != nil {
+		t.Fatalf("DialWebSocket failed: %v", err)
+	}
+	defer conn.Close(1000, "")
+	subscribe, _ := json.Marshal(SocketRequest{ID: "1", Type: dto.SocketSubscribe})
+	if err := conn.WriteMessage(websocket.TextMessage, subscribe); err != nil {
+		t.Fatalf("Writing to the websocket failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, message, err := conn.ReadMessage()
+	var reply dto.SocketResponse
+	if err != nil || json.Unmarshal(message, &reply) != nil || reply.Type != dto.SocketSubscribed || reply.ID != "1" {
+		t.Errorf("Expected a subscribed reply, got %s, %v", message, err)
+	}
+}
+
+func TestClientWatchTasks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := newTestClient(t, newTestAPI(t).URL)
+
+	stream, err := c.WatchTasks(ctx, Filter{Status: StatusCreated}, 0)
+	if err != nil {
+		t.Fatalf("WatchTasks failed: %v", err)
+	}
+	defer stream.Close()
+
+	id, err := c.CreateTask(ctx, PostTaskRequest{Name: "watched", Status: StatusCreated})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	event, err := stream.Next()
+	if err != nil || event.Type != model.TaskChangeCreated || event.Task.ID != id {
+		t.Fatalf("Next returned %+v, %v", event, err)
+	}
+	if stream.LastEventID() != event.ID {
+		t.Errorf("Expected the last event id %d, got %d", event.ID, stream.LastEventID())
+	}
+
+	// resuming after the first change replays the next one
+	if _, err := c.CreateTask(ctx, PostTaskRequest{Name: "missed", Status: StatusCreated}); err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+	resumed, err := c.WatchTasks(ctx, Filter{}, event.ID)
+	if err != nil {
+		t.Fatalf("WatchTasks failed: %v", err)
+	}
+	defer resumed.Close()
+	if replayed, err := resumed.Next(); err != nil || replayed.Task.Name != "missed" {
+		t.Errorf("Expected the missed change, got %+v, %v", replayed, err)
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestAPI(t)
+
+	tests := []struct {
+		name     string
+		auth     Auth
+		expected error
+	}{
+		{name: "api key", auth: APIKey(testAPIKey)},
+		{name: "no credentials", expected: ErrUnauthenticated},
+		{name: "wrong api key", auth: APIKey("wrong"), expected: ErrUnauthenticated},
+		{name: "invalid bearer token", auth: BearerToken("not.a.jwt"), expected: ErrUnauthenticated},
+		{name: "failing token source", auth: TokenSource(func(r *http.Request) (string, error) {
+			return "", errors.New("token expired")
+		}), expected: errors.New("token expired")},
+	}
+
+	// public routes need no credentials
+	if c, _ := New(ts.URL); c.Health(ctx) != nil {
+		t.Errorf("Expected health to be public")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{}
+			if tt.auth != nil {
+				opts = append(opts, WithAuth(tt.auth))
+			}
+			c, err := New(ts.URL, opts...)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			_, err = c.ListTasks(ctx, Filter{})
+			switch {
+			case tt.expected == nil && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tt.expected == nil:
+			case errors.Is(tt.expected, model.ErrUnauthenticated) && !errors.Is(err, tt.expected):
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			case !errors.Is(tt.expected, model.ErrUnauthenticated) && (err == nil || !strings.Contains(err.Error(), tt.expected.Error())):
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		call             func(ctx context.Context, c *Client) error
+		statuses         []int
+		retryAfter       string
+		timeout          time.Duration
+		expectedAttempts int32
+		expectedError    error
+		expectedStatus   int
+	}{
+		{
+			name:             "retried until success",
+			call:             func(ctx context.Context, c *Client) error { _, err := c.GetTask(ctx, "1"); return err },
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			expectedAttempts: 3,
+		},
+		{
+			name:             "attempts exhausted",
+			call:             func(ctx context.Context, c *Client) error { _, err := c.GetTask(ctx, "1"); return err },
+			statuses:         []int{http.StatusServiceUnavailable},
+			expectedAttempts: 3,
+			expectedStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:             "retry after",
+			call:             func(ctx context.Context, c *Client) error { return c.DeleteTask(ctx, "1") },
+			statuses:         []int{http.StatusTooManyRequests, http.StatusNoContent},
+			retryAfter:       "0",
+			expectedAttempts: 2,
+		},
+		{
+			name:             "client errors aren't retried",
+			call:             func(ctx context.Context, c *Client) error { _, err := c.GetTask(ctx, "1"); return err },
+			statuses:         []int{http.StatusNotFound},
+			expectedAttempts: 1,
+			expectedError:    ErrNotFound,
+		},
+		{
+			name: "posts aren't retried",
+			call: func(ctx context.Context, c *Client) error {
+				_, err := c.CreateTask(ctx, PostTaskRequest{Name: "task", Status: StatusCreated})
+				return err
+			},
+			statuses:         []int{http.StatusServiceUnavailable},
+			expectedAttempts: 1,
+			expectedStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:             "context ends during backoff",
+			call:             func(ctx context.Context, c *Client) error { _, err := c.GetTask(ctx, "1"); return err },
+			statuses:         []int{http.StatusServiceUnavailable},
+			retryAfter:       "60",
+			timeout:          50 * time.Millisecond,
+			expectedAttempts: 1,
+			expectedError:    context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := int(attempts.Add(1))
+				status := tt.statuses[min(attempt, len(tt.statuses))-1]
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				switch {
+				case status >= 400:
+					json.NewEncoder(w).Encode(dto.ErrorResponse{Error: "failed"})
+				case status != http.StatusNoContent:
+					json.NewEncoder(w).Encode(dto.GetTaskByIdResponse{Id: 1})
+				}
+			}))
+			defer ts.Close()
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			// a retry after delay longer than MaxDelay is cut to it
+			c := newTestClient(t, ts.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Minute}))
+			err := tt.call(ctx, c)
+
+			if got := attempts.Load(); got != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, got)
+			}
+			var apiErr *APIError
+			switch {
+			case tt.expectedError != nil && !errors.Is(err, tt.expectedError):
+				t.Errorf("Expected %v, got %v", tt.expectedError, err)
+			case tt.expectedStatus != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.expectedStatus || !apiErr.Temporary()):
+				t.Errorf("Expected a temporary APIError with status %d, got %v", tt.expectedStatus, err)
+			case tt.expectedError == nil && tt.expectedStatus == 0 && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "valid", baseURL: "http://localhost:8080/"},
+		{name: "with path", baseURL: "https://example.com/api"},
+		{name: "no scheme", baseURL: "localhost:8080", wantErr: true},
+		{name: "unsupported scheme", baseURL: "ftp://example.com", wantErr: true},
+		{name: "nil http client", baseURL: "http://localhost", opts: []Option{WithHTTPClient(nil)}, wantErr: true},
+		{name: "no attempts", baseURL: "http://localhost", opts: []Option{WithRetryPolicy(RetryPolicy{})}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.baseURL, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	due := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		filter   model.Filter
+		expected string
+	}{
+		{name: "empty", filter: model.Filter{}, expected: ""},
+		{
+			name:     "every field",
+			filter:   model.Filter{Status: model.Done, AssigneeID: 3, Priority: model.PriorityHigh, Overdue: true, DueBefore: due, DueAfter: due},
+			expected: "assignee=3&due_after=2025-01-02T03%3A04%3A05Z&due_before=2025-01-02T03%3A04%3A05Z&overdue=true&priority=high&status=done",
+		},
+		{name: "tags", filter: model.Filter{Tags: []string{"a", "b"}, TagMatch: model.TagMatchAll}, expected: "tag_match=all&tags=a%2Cb"},
+		{name: "sort", filter: model.Filter{SortBy: model.SortByDueAt}, expected: "sort=due_at"},
+		{name: "descending", filter: model.Filter{Descending: true}, expected: "sort=-id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterQuery(tt.filter).Encode(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{
+			name:     "events",
+			body:     ": connected\n\nid: 1\nevent: created\ndata: {\"id\":1,\"type\":\"created\"}\n\n: heartbeat\n\nid: 2\nevent: deleted\ndata: {\"id\":2,\"type\":\"deleted\"}\n\n",
+			expected: []string{"1 created", "2 deleted", "EOF"},
+		},
+		{
+			name:     "reset",
+			body:     "event: reset\ndata: {}\n\nid: 7\r\nevent: updated\r\ndata: {\"id\":7,\"type\":\"updated\"}\r\n\r\n",
+			expected: []string{"reset", "7 updated", "EOF"},
+		},
+		{
+			name:     "cut off event",
+			body:     "id: 1\nevent: created\ndata: {\"id\":1,",
+			expected: []string{"EOF"},
+		},
+		{
+			name:     "invalid data",
+			body:     "id: 1\nevent: created\ndata: {\"id\":\n\n",
+			expected: []string{"decoding event 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := io.NopCloser(strings.NewReader(tt.body))
+			stream := &EventStream{body: body, reader: bufio.NewReader(body)}
+			for _, expected := range tt.expected {
+				event, err := stream.Next()
+				var got string
+				switch {
+				case errors.Is(err, ErrStreamReset):
+					got = "reset"
+				case errors.Is(err, io.EOF):
+					got = "EOF"
+				case err != nil:
+					got = err.Error()
+				default:
+					got = strconv.Itoa(event.ID) + " " + string(event.Type)
+				}
+				if !strings.Contains(got, expected) {
+					t.Errorf("Expected %q, got %q", expected, got)
+				}
+			}
+		})
+	}
+}
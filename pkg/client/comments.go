@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListComments returns a page of the comments of the task, oldest first
+func (c *Client) ListComments(ctx context.Context, taskId TaskID, page model.Page) (CommentList, error) {
+	query := url.Values{}
+	addPage(query, page)
+	var response CommentList
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(taskId) + "/comments", scoped: true, query: query}, &response)
+	return response, err
+}
+
+// CreateComment returns the id of the created comment, the caller is its author
+func (c *Client) CreateComment(ctx context.Context, taskId TaskID, comment dto.PostCommentRequest) (int, error) {
+	var id int
+	err := c.do(ctx, request{method: http.MethodPost, path: taskPath(taskId) + "/comments", scoped: true, body: comment}, &id)
+	return id, err
+}
+
+func (c *Client) EditComment(ctx context.Context, taskId TaskID, commentId int, comment dto.PutCommentRequest) (Comment, error) {
+	var response Comment
+	err := c.do(ctx, request{method: http.MethodPut, path: commentPath(taskId, commentId), scoped: true, body: comment}, &response)
+	return response, err
+}
+
+func (c *Client) DeleteComment(ctx context.Context, taskId TaskID, commentId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: commentPath(taskId, commentId), scoped: true}, nil)
+}
+
+func commentPath(taskId TaskID, commentId int) string {
+	return taskPath(taskId) + "/comments/" + strconv.Itoa(commentId)
+}
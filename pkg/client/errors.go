@@ -0,0 +1,101 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/graphql"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strings"
+)
+
+// Errors of the model package the errors of the API match, they are repeated here for callers outside of the module
+var (
+	ErrNotFound        = model.ErrNotFound
+	ErrInvalid         = model.ErrInvalid
+	ErrAlreadyExists   = model.ErrAlreadyExists
+	ErrUnauthenticated = model.ErrUnauthenticated
+	ErrForbidden       = model.ErrForbidden
+	ErrQuotaExceeded   = model.ErrQuotaExceeded
+	ErrCycle           = model.ErrCycle
+	ErrIncomplete      = model.ErrIncomplete
+	ErrUnsupported     = model.ErrUnsupported
+)
+
+// maxErrorBodySize limits the part of an error response read for its message
+const maxErrorBodySize = 64 << 10
+
+// APIError is a response with an error status
+type APIError struct {
+	StatusCode int
+	// Message is the error of the response body, or the status text when the body has none
+	Message string
+	// RequestID identifies the request in the logs of the server
+	RequestID string
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	apiErr := &APIError{StatusCode: resp.StatusCode, RequestID: requestIDOf(resp)}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	io.Copy(io.Discard, resp.Body)
+	var body dto.ErrorResponse
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		apiErr.Message = body.Error
+	} else {
+		apiErr.Message = strings.ToLower(http.StatusText(resp.StatusCode))
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v: %d %v", clientName, e.StatusCode, e.Message)
+}
+
+// Unwrap returns the error of the model package the status stands for, conflicts are told apart by the message
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return model.ErrInvalid
+	case http.StatusUnauthorized:
+		return model.ErrUnauthenticated
+	case http.StatusForbidden:
+		return model.ErrForbidden
+	case http.StatusNotFound:
+		return model.ErrNotFound
+	case http.StatusConflict:
+		switch {
+		case strings.Contains(e.Message, "quota"):
+			return model.ErrQuotaExceeded
+		case strings.Contains(e.Message, "cycle"):
+			return model.ErrCycle
+		case strings.Contains(e.Message, "open blockers"):
+			return model.ErrIncomplete
+		}
+		return model.ErrAlreadyExists
+	case http.StatusNotImplemented:
+		return model.ErrUnsupported
+	}
+	return nil
+}
+
+// Temporary reports whether the call may succeed when it's made again later
+func (e *APIError) Temporary() bool {
+	return e.StatusCode >= 500 || isRetryableStatus(e.StatusCode)
+}
+
+// GraphQLError holds the errors of an executed graphql query, data of the fields that didn't fail is still decoded
+type GraphQLError struct {
+	Errors []*graphql.Error
+}
+
+func (e *GraphQLError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Message)
+	}
+	return fmt.Sprintf("%v: graphql: %v", clientName, strings.Join(messages, "; "))
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ivanjabrony/test_lo/internal/model"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrStreamReset is returned by EventStream.Next when some of the changes after the last event id can't be
+// replayed anymore. The stream goes on with the next changes, the caller reloads the tasks it keeps.
+var ErrStreamReset = errors.New("task client: event stream reset, reload the tasks")
+
+// EventStream reads changes of tasks sent by GET /tasks/events, it isn't safe for concurrent use
+type EventStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	// lastEventID is the id of the last read change, it resumes the stream after reconnecting
+	lastEventID int
+}
+
+// WatchTasks streams changes of the tasks matching the filter, a positive lastEventID sends the changes
+// after it first. The stream ends with io.EOF when the server closes it, e.g. because the caller fell behind,
+// it's resumed by calling WatchTasks again with LastEventID of the stream.
+func (c *Client) WatchTasks(ctx context.Context, filter model.Filter, lastEventID int) (*EventStream, error) {
+	header := http.Header{"Accept": {"text/event-stream"}}
+	if lastEventID > 0 {
+		header.Set("Last-Event-ID", strconv.Itoa(lastEventID))
+	}
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/tasks/events", scoped: true, query: filterQuery(filter), header: header})
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{body: resp.Body, reader: bufio.NewReader(resp.Body), lastEventID: lastEventID}, nil
+}
+
+// Next blocks until the next change, heartbeats and comments are skipped
+func (s *EventStream) Next() (TaskChangeEvent, error) {
+	for {
+		name, id, data, err := s.readEvent()
+		if err != nil {
+			return TaskChangeEvent{}, err
+		}
+		if name == "reset" {
+			return TaskChangeEvent{}, ErrStreamReset
+		}
+		if data == "" {
+			continue
+		}
+
+		var event TaskChangeEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return TaskChangeEvent{}, fmt.Errorf("%v: decoding event %v: %w", clientName, id, err)
+		}
+		if parsed, err := strconv.Atoi(id); err == nil {
+			s.lastEventID = parsed
+		}
+		return event, nil
+	}
+}
+
+// LastEventID returns the id of the last change read from the stream
+func (s *EventStream) LastEventID() int {
+	return s.lastEventID
+}
+
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
+
+// readEvent reads the fields of the next event up to the blank line ending it
+func (s *EventStream) readEvent() (name, id, data string, err error) {
+	hasFields := false
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && line == "" {
+				return "", "", "", io.EOF
+			}
+			if !errors.Is(err, io.EOF) {
+				return "", "", "", fmt.Errorf("%v: reading event stream: %w", clientName, err)
+			}
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if hasFields {
+				return name, id, data, nil
+			}
+			if err != nil {
+				return "", "", "", io.EOF
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		hasFields = true
+		switch field {
+		case "event":
+			name = value
+		case "id":
+			id = value
+		case "data":
+			if data != "" {
+				data += "\n"
+			}
+			data += value
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strconv"
+)
+
+func (c *Client) ListProjects(ctx context.Context) (dto.GetAllProjectsResponse, error) {
+	var response dto.GetAllProjectsResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/projects"}, &response)
+	return response, err
+}
+
+func (c *Client) GetProject(ctx context.Context, projectId int) (dto.GetProjectByIdResponse, error) {
+	var response dto.GetProjectByIdResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: projectPath(projectId)}, &response)
+	return response, err
+}
+
+// CreateProject returns the id of the created project
+func (c *Client) CreateProject(ctx context.Context, project dto.PostProjectRequest) (int, error) {
+	var id int
+	err := c.do(ctx, request{method: http.MethodPost, path: "/projects", body: project}, &id)
+	return id, err
+}
+
+func (c *Client) UpdateProject(ctx context.Context, projectId int, patch dto.PatchProjectRequest) (dto.GetProjectByIdResponse, error) {
+	var response dto.GetProjectByIdResponse
+	err := c.do(ctx, request{method: http.MethodPatch, path: projectPath(projectId), body: patch}, &response)
+	return response, err
+}
+
+func projectPath(projectId int) string {
+	return "/projects/" + strconv.Itoa(projectId)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strconv"
+)
+
+func (c *Client) ListTags(ctx context.Context) (dto.GetAllTagsResponse, error) {
+	var response dto.GetAllTagsResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/tags", scoped: true}, &response)
+	return response, err
+}
+
+func (c *Client) GetTag(ctx context.Context, tagId int) (dto.GetTagByIdResponse, error) {
+	var response dto.GetTagByIdResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: tagPath(tagId), scoped: true}, &response)
+	return response, err
+}
+
+// CreateTag returns the id of the created tag
+func (c *Client) CreateTag(ctx context.Context, tag dto.PostTagRequest) (int, error) {
+	var id int
+	err := c.do(ctx, request{method: http.MethodPost, path: "/tags", scoped: true, body: tag}, &id)
+	return id, err
+}
+
+func (c *Client) RenameTag(ctx context.Context, tagId int, tag dto.PutTagRequest) (dto.GetTagByIdResponse, error) {
+	var response dto.GetTagByIdResponse
+	err := c.do(ctx, request{method: http.MethodPut, path: tagPath(tagId), scoped: true, body: tag}, &response)
+	return response, err
+}
+
+func (c *Client) DeleteTag(ctx context.Context, tagId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: tagPath(tagId), scoped: true}, nil)
+}
+
+func (c *Client) AttachTag(ctx context.Context, taskId TaskID, tagId int) (Task, error) {
+	var response Task
+	err := c.do(ctx, request{method: http.MethodPut, path: taskPath(taskId) + tagPath(tagId), scoped: true}, &response)
+	return response, err
+}
+
+func (c *Client) DetachTag(ctx context.Context, taskId TaskID, tagId int) (Task, error) {
+	var response Task
+	err := c.do(ctx, request{method: http.MethodDelete, path: taskPath(taskId) + tagPath(tagId), scoped: true}, &response)
+	return response, err
+}
+
+func tagPath(tagId int) string {
+	return "/tags/" + strconv.Itoa(tagId)
+}
@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export and import formats
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// ListTasks returns every task matching the filter
+func (c *Client) ListTasks(ctx context.Context, filter model.Filter) (TaskList, error) {
+	var response TaskList
+	err := c.do(ctx, request{method: http.MethodGet, path: "/tasks", scoped: true, query: filterQuery(filter)}, &response)
+	return response, err
+}
+
+// ListTasksPage returns a page of the tasks matching the filter, Total of the response counts all of them
+func (c *Client) ListTasksPage(ctx context.Context, filter model.Filter, page model.Page) (TaskList, error) {
+	query := filterQuery(filter)
+	addPage(query, page)
+	var response TaskList
+	err := c.do(ctx, request{method: http.MethodGet, path: "/tasks", scoped: true, query: query}, &response)
+	return response, err
+}
+
+// Tasks iterates over the tasks matching the filter, loading pageSize of them at once. Iteration stops
+// after the first error. Tasks created or deleted while iterating may be skipped or seen twice.
+func (c *Client) Tasks(ctx context.Context, filter model.Filter, pageSize int) iter.Seq2[Task, error] {
+	return func(yield func(Task, error) bool) {
+		page := model.Page{Limit: pageSize}
+		for {
+			response, err := c.ListTasksPage(ctx, filter, page)
+			if err != nil {
+				yield(Task{}, err)
+				return
+			}
+			for _, task := range response.Tasks {
+				if !yield(task, nil) {
+					return
+				}
+			}
+			page.Offset += len(response.Tasks)
+			if len(response.Tasks) == 0 || page.Offset >= response.Total {
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) GetTask(ctx context.Context, taskId TaskID) (Task, error) {
+	var response Task
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(taskId), scoped: true}, &response)
+	return response, err
+}
+
+// GetTaskAsOf returns the task as it was at the time, the task storage of the server has to keep events
+func (c *Client) GetTaskAsOf(ctx context.Context, taskId TaskID, asOf time.Time) (Task, error) {
+	query := url.Values{"as_of": {asOf.Format(time.RFC3339)}}
+	var response Task
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(taskId), scoped: true, query: query}, &response)
+	return response, err
+}
+
+// CreateTask returns the id of the created task
+func (c *Client) CreateTask(ctx context.Context, task dto.PostTaskRequest) (TaskID, error) {
+	var id TaskID
+	err := c.do(ctx, request{method: http.MethodPost, path: "/tasks", scoped: true, body: task}, &id)
+	return id, err
+}
+
+func (c *Client) UpdateTask(ctx context.Context, taskId TaskID, patch dto.PatchTaskRequest) (Task, error) {
+	var response Task
+	err := c.do(ctx, request{method: http.MethodPatch, path: taskPath(taskId), scoped: true, body: patch}, &response)
+	return response, err
+}
+
+func (c *Client) DeleteTask(ctx context.Context, taskId TaskID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: taskPath(taskId), scoped: true}, nil)
+}
+
+// ExportTasks streams the tasks matching the filter in the format, the caller closes the returned body
+func (c *Client) ExportTasks(ctx context.Context, format string, filter model.Filter) (io.ReadCloser, error) {
+	query := filterQuery(filter)
+	query.Set("format", format)
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/tasks/export", scoped: true, query: query})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ImportOptions configure ImportTasks
+type ImportOptions struct {
+	// DryRun only validates the tasks
+	DryRun bool
+	// Mapping renames source columns or keys to task fields
+	Mapping map[string]string
+}
+
+// ImportTasks creates tasks from a csv or ndjson body, rows failing validation are reported in the response.
+// Imports aren't retried, since the body is read once.
+func (c *Client) ImportTasks(ctx context.Context, format string, body io.Reader, opts ImportOptions) (ImportResult, error) {
+	query := url.Values{"format": {format}}
+	if opts.DryRun {
+		query.Set("dry_run", "true")
+	}
+	for source, target := range opts.Mapping {
+		query.Add("map", source+":"+target)
+	}
+	contentType := "application/x-ndjson"
+	if format == FormatCSV {
+		contentType = "text/csv"
+	}
+
+	var response ImportResult
+	err := c.do(ctx, request{
+		method: http.MethodPost, path: "/tasks/import", scoped: true, query: query, rawBody: body, contentType: contentType,
+	}, &response)
+	return response, err
+}
+
+// GetSubtasks returns the task with its subtasks down to the depth, 0 uses the default depth of the server
+func (c *Client) GetSubtasks(ctx context.Context, taskId TaskID, depth int) (TaskTree, error) {
+	query := url.Values{}
+	if depth > 0 {
+		query.Set("depth", strconv.Itoa(depth))
+	}
+	var response TaskTree
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(taskId) + "/subtasks", scoped: true, query: query}, &response)
+	return response, err
+}
+
+// GetDependencyOrder lists the blockers of the task in an order they can be done in, the task is the last one
+func (c *Client) GetDependencyOrder(ctx context.Context, taskId TaskID) (TaskList, error) {
+	var response TaskList
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(taskId) + "/dependencies/order", scoped: true}, &response)
+	return response, err
+}
+
+func (c *Client) AddBlocker(ctx context.Context, taskId, blockerId TaskID) (Task, error) {
+	var response Task
+	err := c.do(ctx, request{method: http.MethodPut, path: blockerPath(taskId, blockerId), scoped: true}, &response)
+	return response, err
+}
+
+func (c *Client) RemoveBlocker(ctx context.Context, taskId, blockerId TaskID) (Task, error) {
+	var response Task
+	err := c.do(ctx, request{method: http.MethodDelete, path: blockerPath(taskId, blockerId), scoped: true}, &response)
+	return response, err
+}
+
+// GetTaskHistory returns a page of the changes of the task, oldest first
+func (c *Client) GetTaskHistory(ctx context.Context, taskId TaskID, page model.Page) (dto.GetAuditResponse, error) {
+	query := url.Values{}
+	addPage(query, page)
+	var response dto.GetAuditResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: taskPath(taskId) + "/history", scoped: true, query: query}, &response)
+	return response, err
+}
+
+func taskPath(taskId TaskID) string {
+	return "/tasks/" + url.PathEscape(string(taskId))
+}
+
+func blockerPath(taskId, blockerId TaskID) string {
+	return taskPath(taskId) + "/blockers/" + url.PathEscape(string(blockerId))
+}
+
+// filterQuery encodes the filter as the query parameters of GET /tasks
+func filterQuery(filter model.Filter) url.Values {
+	query := url.Values{}
+	if filter.Status != "" {
+		query.Set("status", string(filter.Status))
+	}
+	if filter.AssigneeID != model.NoUser {
+		query.Set("assignee", strconv.Itoa(filter.AssigneeID))
+	}
+	if filter.Priority != "" {
+		query.Set("priority", string(filter.Priority))
+	}
+	if filter.Overdue {
+		query.Set("overdue", "true")
+	}
+	if !filter.DueBefore.IsZero() {
+		query.Set("due_before", filter.DueBefore.Format(time.RFC3339))
+	}
+	if !filter.DueAfter.IsZero() {
+		query.Set("due_after", filter.DueAfter.Format(time.RFC3339))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		query.Set("created_before", filter.CreatedBefore.Format(time.RFC3339Nano))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		query.Set("created_after", filter.CreatedAfter.Format(time.RFC3339Nano))
+	}
+	if len(filter.Tags) > 0 {
+		query.Set("tags", strings.Join(filter.Tags, ","))
+	}
+	if filter.TagMatch != "" {
+		query.Set("tag_match", string(filter.TagMatch))
+	}
+	if filter.SortBy != "" || filter.Descending {
+		sortBy := filter.SortBy
+		if sortBy == "" {
+			sortBy = model.SortById
+		}
+		if filter.Descending {
+			query.Set("sort", fmt.Sprintf("-%v", sortBy))
+		} else {
+			query.Set("sort", string(sortBy))
+		}
+	}
+	return query
+}
+
+// addPage adds limit and offset parameters, a zero limit uses the default of the server
+func addPage(query url.Values, page model.Page) {
+	if page.Limit != 0 {
+		query.Set("limit", strconv.Itoa(page.Limit))
+	}
+	query.Set("offset", strconv.Itoa(page.Offset))
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"time"
+)
+
+// Types of the requests, they are aliases of the types the server decodes, so callers outside of the module can
+// name them. Responses are the dto types too, their fields are reachable without naming them, except for
+// the ones referring to tasks, whose ids are strings, see TaskID.
+type (
+	Filter = model.Filter
+	Page   = model.Page
+
+	TaskStatus   = model.TaskStatus
+	TaskPriority = model.TaskPriority
+	TagMatch     = model.TagMatch
+	SortField    = model.SortField
+
+	TaskRef             = dto.TaskRef
+	PostTaskRequest     = dto.PostTaskRequest
+	PatchTaskRequest    = dto.PatchTaskRequest
+	PostTagRequest      = dto.PostTagRequest
+	PutTagRequest       = dto.PutTagRequest
+	PostCommentRequest  = dto.PostCommentRequest
+	PutCommentRequest   = dto.PutCommentRequest
+	PostWebhookRequest  = dto.PostWebhookRequest
+	PutWebhookRequest   = dto.PutWebhookRequest
+	PostProjectRequest  = dto.PostProjectRequest
+	PatchProjectRequest = dto.PatchProjectRequest
+	PostUserRequest     = dto.PostUserRequest
+	PutUserRequest      = dto.PutUserRequest
+	GraphQLRequest      = dto.GraphQLRequest
+	SocketRequest       = dto.SocketRequest
+)
+
+const (
+	StatusCreated    = model.Created
+	StatusInProgress = model.InProgress
+	StatusDone       = model.Done
+
+	PriorityLow    = model.PriorityLow
+	PriorityMedium = model.PriorityMedium
+	PriorityHigh   = model.PriorityHigh
+	PriorityUrgent = model.PriorityUrgent
+
+	TagMatchAny = model.TagMatchAny
+	TagMatchAll = model.TagMatchAll
+)
+
+// TaskID identifies a task. Servers giving tasks public ids send them as strings, others send ids as numbers,
+// both are decoded to a string, so tasks are referred to the same way by either server.
+type TaskID string
+
+func (id *TaskID) UnmarshalJSON(data []byte) error {
+	var ref dto.TaskRef
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return fmt.Errorf("invalid task id %s: %w", data, err)
+	}
+	if ref.PublicID != "" {
+		*id = TaskID(ref.PublicID)
+	} else {
+		*id = TaskID(fmt.Sprint(ref.Id))
+	}
+	return nil
+}
+
+// Ref refers to the task in PostTaskRequest and PatchTaskRequest
+func (id TaskID) Ref() *TaskRef {
+	return &TaskRef{PublicID: string(id)}
+}
+
+// Task is a task in responses of the server. ProjectID is set in lists, CommentCount and Overdue
+// by the single task calls, so they are omitted when empty like the server does in lists.
+type Task struct {
+	ID           TaskID            `json:"id"`
+	ProjectID    int               `json:"project_id,omitempty"`
+	Status       TaskStatus        `json:"status"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Priority     TaskPriority      `json:"priority"`
+	AssigneeID   int               `json:"assignee_id,omitempty"`
+	ReporterID   int               `json:"reporter_id,omitempty"`
+	TagIDs       []int             `json:"tag_ids,omitempty"`
+	ParentID     *TaskID           `json:"parent_id,omitempty"`
+	BlockedBy    []TaskID          `json:"blocked_by,omitempty"`
+	CommentCount int               `json:"comment_count,omitempty"`
+	DueAt        time.Time         `json:"due_at,omitzero"`
+	Overdue      bool              `json:"overdue,omitempty"`
+	Recurrence   *model.Recurrence `json:"recurrence,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	StartedAt    time.Time         `json:"started_at,omitzero"`
+	CompletedAt  time.Time         `json:"completed_at,omitzero"`
+}
+
+// TaskList is a list of tasks, Total, Limit and Offset are set for pages
+type TaskList struct {
+	Amount int    `json:"amount"`
+	Tasks  []Task `json:"tasks"`
+	Total  int    `json:"total,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+}
+
+// TaskTree is a task with its subtasks
+type TaskTree struct {
+	Task
+	Subtasks []TaskTree `json:"subtasks"`
+}
+
+// TaskChangeEvent is a change of a task, Task is the state after the change, for deleted tasks the last one before it
+type TaskChangeEvent struct {
+	ID        int                  `json:"id"`
+	Type      model.TaskChangeType `json:"type"`
+	ProjectID int                  `json:"project_id"`
+	At        time.Time            `json:"at"`
+	Task      Task                 `json:"task"`
+}
+
+// ImportResult reports an import, Ids are the ids of the created tasks
+type ImportResult struct {
+	DryRun   bool                 `json:"dry_run"`
+	Total    int                  `json:"total"`
+	Imported int                  `json:"imported"`
+	Failed   int                  `json:"failed"`
+	Ids      []TaskID             `json:"ids"`
+	Errors   []dto.ImportRowError `json:"errors"`
+}
+
+// Comment is a comment in responses of the server
+type Comment struct {
+	Id        int       `json:"id"`
+	TaskID    TaskID    `json:"task_id"`
+	AuthorID  int       `json:"author_id,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	EditedAt  time.Time `json:"edited_at,omitzero"`
+}
+
+// CommentList is a page of the comments of a task, Total counts all of them
+type CommentList struct {
+	Amount   int       `json:"amount"`
+	Total    int       `json:"total"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+	Comments []Comment `json:"comments"`
+}
+
+// AuditFilter selects audit entries, zero fields match everything
+type AuditFilter struct {
+	ProjectID int
+	TaskID    TaskID
+	ActorID   int
+	Action    model.AuditAction
+	// From and To bound the time of the entries, both are inclusive
+	From time.Time
+	To   time.Time
+}
+
+// AuditEntry is an entry of the audit log in responses of the server
+type AuditEntry struct {
+	Seq       int                 `json:"seq"`
+	ProjectID int                 `json:"project_id"`
+	TaskID    TaskID              `json:"task_id"`
+	Action    model.AuditAction   `json:"action"`
+	Actor     string              `json:"actor,omitempty"`
+	ActorID   int                 `json:"actor_id,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+	At        time.Time           `json:"at"`
+	Changes   []model.FieldChange `json:"changes"`
+	PrevHash  string              `json:"prev_hash"`
+	Hash      string              `json:"hash"`
+}
+
+// AuditList is a page of audit entries, Total counts all the matching ones
+type AuditList struct {
+	Amount  int          `json:"amount"`
+	Total   int          `json:"total"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+	Entries []AuditEntry `json:"entries"`
+}
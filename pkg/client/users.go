@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"strconv"
+)
+
+func (c *Client) ListUsers(ctx context.Context) (dto.GetAllUsersResponse, error) {
+	var response dto.GetAllUsersResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/users"}, &response)
+	return response, err
+}
+
+func (c *Client) GetUser(ctx context.Context, userId int) (dto.GetUserByIdResponse, error) {
+	var response dto.GetUserByIdResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: userPath(userId)}, &response)
+	return response, err
+}
+
+// CreateUser returns the id of the created user
+func (c *Client) CreateUser(ctx context.Context, user dto.PostUserRequest) (int, error) {
+	var id int
+	err := c.do(ctx, request{method: http.MethodPost, path: "/users", body: user}, &id)
+	return id, err
+}
+
+func (c *Client) ReplaceUser(ctx context.Context, userId int, user dto.PutUserRequest) (dto.GetUserByIdResponse, error) {
+	var response dto.GetUserByIdResponse
+	err := c.do(ctx, request{method: http.MethodPut, path: userPath(userId), body: user}, &response)
+	return response, err
+}
+
+func (c *Client) DeleteUser(ctx context.Context, userId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: userPath(userId)}, nil)
+}
+
+// ListUserTasks returns the tasks assigned to the user in the default project
+func (c *Client) ListUserTasks(ctx context.Context, userId int) (dto.GetAllTasksResponse, error) {
+	var response dto.GetAllTasksResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: userPath(userId) + "/tasks"}, &response)
+	return response, err
+}
+
+func userPath(userId int) string {
+	return "/users/" + strconv.Itoa(userId)
+}
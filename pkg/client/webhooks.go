@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"ivanjabrony/test_lo/internal/model"
+	"ivanjabrony/test_lo/internal/model/dto"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (c *Client) ListWebhooks(ctx context.Context) (dto.GetAllWebhooksResponse, error) {
+	var response dto.GetAllWebhooksResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/webhooks", scoped: true}, &response)
+	return response, err
+}
+
+func (c *Client) GetWebhook(ctx context.Context, webhookId int) (dto.GetWebhookByIdResponse, error) {
+	var response dto.GetWebhookByIdResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: webhookPath(webhookId), scoped: true}, &response)
+	return response, err
+}
+
+// CreateWebhook returns the id of the webhook with its secret, the secret is only shown once
+func (c *Client) CreateWebhook(ctx context.Context, webhook dto.PostWebhookRequest) (dto.PostWebhookResponse, error) {
+	var response dto.PostWebhookResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/webhooks", scoped: true, body: webhook}, &response)
+	return response, err
+}
+
+func (c *Client) ReplaceWebhook(ctx context.Context, webhookId int, webhook dto.PutWebhookRequest) (dto.GetWebhookByIdResponse, error) {
+	var response dto.GetWebhookByIdResponse
+	err := c.do(ctx, request{method: http.MethodPut, path: webhookPath(webhookId), scoped: true, body: webhook}, &response)
+	return response, err
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, webhookId int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: webhookPath(webhookId), scoped: true}, nil)
+}
+
+// ListDeliveries returns a page of the delivery attempts of the webhook, newest first
+func (c *Client) ListDeliveries(ctx context.Context, webhookId int, page model.Page) (dto.GetWebhookDeliveriesResponse, error) {
+	query := url.Values{}
+	addPage(query, page)
+	var response dto.GetWebhookDeliveriesResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: webhookPath(webhookId) + "/deliveries", scoped: true, query: query}, &response)
+	return response, err
+}
+
+func (c *Client) ListDeadLetters(ctx context.Context, webhookId int) (dto.GetDeadLettersResponse, error) {
+	var response dto.GetDeadLettersResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: webhookPath(webhookId) + "/dead-letters", scoped: true}, &response)
+	return response, err
+}
+
+// Redeliver queues a dead letter for delivery again, it isn't retried since a retry could queue it twice
+func (c *Client) Redeliver(ctx context.Context, webhookId int, deliveryId string) error {
+	path := webhookPath(webhookId) + "/dead-letters/" + url.PathEscape(deliveryId) + "/redeliver"
+	return c.do(ctx, request{method: http.MethodPost, path: path, scoped: true}, nil)
+}
+
+func webhookPath(webhookId int) string {
+	return "/webhooks/" + strconv.Itoa(webhookId)
+}